@@ -4,4 +4,12 @@ import "github.com/ethereum/go-ethereum/common"
 
 var (
 	ArkivProcessorAddress = common.HexToAddress("0x00000000000000000000000000000061726B6976")
+
+	// ArkivCapabilitiesAddress is the address of the precompile answering which Arkiv
+	// features are active, "arkivcap" in ASCII.
+	ArkivCapabilitiesAddress = common.HexToAddress("0x00000000000000000000000061726B6976636170")
+
+	// ArkivContentHashAddress is the address of the precompile returning the content
+	// hash of an entity, "arkivhash" in ASCII.
+	ArkivContentHashAddress = common.HexToAddress("0x000000000000000000000061726B697668617368")
 )
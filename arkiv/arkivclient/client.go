@@ -0,0 +1,419 @@
+// Package arkivclient provides a client for the arkiv RPC namespace.
+package arkivclient
+
+import (
+	"context"
+	"errors"
+	"math/big"
+
+	sqlitestore "github.com/Arkiv-Network/sqlite-bitmap-store"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/arkiv/rpctypes"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// Client defines typed wrappers for the arkiv RPC namespace.
+type Client struct {
+	c *rpc.Client
+}
+
+// Dial connects a client to the given URL.
+func Dial(rawurl string) (*Client, error) {
+	return DialContext(context.Background(), rawurl)
+}
+
+// DialContext connects a client to the given URL with context.
+func DialContext(ctx context.Context, rawurl string) (*Client, error) {
+	c, err := rpc.DialContext(ctx, rawurl)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(c), nil
+}
+
+// NewClient creates a client that uses the given RPC client.
+func NewClient(c *rpc.Client) *Client {
+	return &Client{c}
+}
+
+// Close closes the underlying RPC connection.
+func (ac *Client) Close() {
+	ac.c.Close()
+}
+
+// Client gets the underlying RPC client.
+func (ac *Client) Client() *rpc.Client {
+	return ac.c
+}
+
+// Query runs a query against the store. A nil options queries the last indexed block
+// with the default options of the store.
+func (ac *Client) Query(ctx context.Context, query string, options *rpctypes.QueryOptions) (*rpctypes.QueryResponse, error) {
+	var result rpctypes.QueryResponse
+	if err := ac.c.CallContext(ctx, &result, "arkiv_query", query, options); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// QueryCount returns the number of entities matching the query, without reading them.
+func (ac *Client) QueryCount(ctx context.Context, query string, options *sqlitestore.Options) (*rpctypes.QueryCount, error) {
+	var result rpctypes.QueryCount
+	if err := ac.c.CallContext(ctx, &result, "arkiv_queryCount", query, options); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetEntity returns the entity with the given key at the given block, or at the head
+// if atBlock is nil. It returns ethereum.NotFound if the entity isn't live at that
+// block.
+func (ac *Client) GetEntity(ctx context.Context, key common.Hash, atBlock *uint64) (*rpctypes.Entity, error) {
+	var block *hexutil.Uint64
+	if atBlock != nil {
+		block = (*hexutil.Uint64)(atBlock)
+	}
+	var result rpctypes.Entity
+	if err := ac.c.CallContext(ctx, &result, "arkiv_getEntity", key, block); err != nil {
+		var rpcErr rpc.Error
+		if errors.As(err, &rpcErr) && rpcErr.ErrorCode() == rpctypes.ErrCodeNotFound {
+			return nil, ethereum.NotFound
+		}
+		return nil, err
+	}
+	return &result, nil
+}
+
+// QueryDiff returns how the result of a query changed between two blocks.
+func (ac *Client) QueryDiff(ctx context.Context, query string, fromBlock, toBlock uint64, options *sqlitestore.Options) (*rpctypes.QueryDiff, error) {
+	var result rpctypes.QueryDiff
+	if err := ac.c.CallContext(ctx, &result, "arkiv_queryDiff", query, fromBlock, toBlock, options); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetEntityCount returns the number of live entities.
+func (ac *Client) GetEntityCount(ctx context.Context) (uint64, error) {
+	var result uint64
+	err := ac.c.CallContext(ctx, &result, "arkiv_getEntityCount")
+	return result, err
+}
+
+// GetNumberOfUsedSlots returns the number of state slots used by the entities.
+func (ac *Client) GetNumberOfUsedSlots(ctx context.Context) (*big.Int, error) {
+	var result hexutil.Big
+	if err := ac.c.CallContext(ctx, &result, "arkiv_getNumberOfUsedSlots"); err != nil {
+		return nil, err
+	}
+	return (*big.Int)(&result), nil
+}
+
+// GetUsedSlotsByOwner returns the number of state slots used by the entities of the
+// owner.
+func (ac *Client) GetUsedSlotsByOwner(ctx context.Context, owner common.Address) (*big.Int, error) {
+	var result hexutil.Big
+	if err := ac.c.CallContext(ctx, &result, "arkiv_getUsedSlotsByOwner", owner); err != nil {
+		return nil, err
+	}
+	return (*big.Int)(&result), nil
+}
+
+// GetEntityMetaData returns the status of an entity in the state of the processor.
+func (ac *Client) GetEntityMetaData(ctx context.Context, key common.Hash) (*rpctypes.EntityMetaData, error) {
+	var result rpctypes.EntityMetaData
+	if err := ac.c.CallContext(ctx, &result, "arkiv_getEntityMetaData", key); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ComputeEntityKey returns the key of the entity created by the create at opIndex of
+// the transaction txHash with the payload, before the transaction is mined.
+func (ac *Client) ComputeEntityKey(ctx context.Context, txHash common.Hash, opIndex uint64, payload []byte) (common.Hash, error) {
+	var result common.Hash
+	err := ac.c.CallContext(ctx, &result, "arkiv_computeEntityKey", txHash, hexutil.Uint64(opIndex), hexutil.Bytes(payload))
+	return result, err
+}
+
+// GetEntityExpiry returns when an entity expires.
+func (ac *Client) GetEntityExpiry(ctx context.Context, key common.Hash) (*rpctypes.EntityExpiry, error) {
+	var result rpctypes.EntityExpiry
+	if err := ac.c.CallContext(ctx, &result, "arkiv_getEntityExpiry", key); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetBlockTiming returns the current block and the time since its parent.
+func (ac *Client) GetBlockTiming(ctx context.Context) (*rpctypes.BlockTiming, error) {
+	var result rpctypes.BlockTiming
+	if err := ac.c.CallContext(ctx, &result, "arkiv_getBlockTiming"); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// SyncStatus returns the progress of the indexer.
+func (ac *Client) SyncStatus(ctx context.Context) (*rpctypes.SyncStatus, error) {
+	var result rpctypes.SyncStatus
+	if err := ac.c.CallContext(ctx, &result, "arkiv_syncStatus"); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// SelfCheck runs the checks of the wiring of the Arkiv subsystem of the node.
+func (ac *Client) SelfCheck(ctx context.Context) (*rpctypes.SelfCheck, error) {
+	var result rpctypes.SelfCheck
+	if err := ac.c.CallContext(ctx, &result, "arkiv_selfCheck"); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetLimits returns the limits and gas pricing enforced on Arkiv transactions and
+// requests at the head.
+func (ac *Client) GetLimits(ctx context.Context) (*rpctypes.Limits, error) {
+	var result rpctypes.Limits
+	if err := ac.c.CallContext(ctx, &result, "arkiv_getLimits"); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Capabilities returns the features of the Arkiv processor active at a block, at the
+// head if block is nil.
+func (ac *Client) Capabilities(ctx context.Context, block *uint64) (*rpctypes.Capabilities, error) {
+	var result rpctypes.Capabilities
+	if err := ac.c.CallContext(ctx, &result, "arkiv_capabilities", (*hexutil.Uint64)(block)); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ShadowEnforcementStats returns the checks of the rules of the processor before their
+// forks, see --arkiv.shadow-enforcement.
+func (ac *Client) ShadowEnforcementStats(ctx context.Context) (*rpctypes.ShadowEnforcementStats, error) {
+	var result rpctypes.ShadowEnforcementStats
+	if err := ac.c.CallContext(ctx, &result, "arkiv_shadowEnforcementStats"); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// VerifyContentHashes checks that the entities with the keys of the pairs hold payloads
+// hashing to the hashes of the pairs at the block, the current block if atBlock is nil.
+// The node checks up to 10000 pairs per call, SubscribeContentHashVerification takes
+// larger jobs.
+func (ac *Client) VerifyContentHashes(ctx context.Context, pairs []rpctypes.ContentHashPair, atBlock *uint64) (*rpctypes.ContentHashVerification, error) {
+	var result rpctypes.ContentHashVerification
+	if err := ac.c.CallContext(ctx, &result, "arkiv_verifyContentHashes", pairs, (*hexutil.Uint64)(atBlock)); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// StorageChallenge challenges the node to prove it stores the payloads of the entities
+// with the keys, at most 1000: the response for an entity is the keccak256 hash of the
+// nonce followed by its payload, see the storagechallenge package to verify them.
+func (ac *Client) StorageChallenge(ctx context.Context, keys []common.Hash, nonce []byte) (*rpctypes.StorageChallenge, error) {
+	var result rpctypes.StorageChallenge
+	if err := ac.c.CallContext(ctx, &result, "arkiv_storageChallenge", keys, hexutil.Bytes(nonce)); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetIngestLatency returns how long the transaction submitted to the node took to be
+// included in a block and indexed by the store, nil if the node doesn't track it.
+func (ac *Client) GetIngestLatency(ctx context.Context, txHash common.Hash) (*rpctypes.IngestLatency, error) {
+	var result *rpctypes.IngestLatency
+	if err := ac.c.CallContext(ctx, &result, "arkiv_getIngestLatency", txHash); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ResolveAlias returns the entity the alias with the name points to at a block, the
+// current block if atBlock is nil. It fails with rpctypes.ErrCodeNotFound if the name
+// isn't registered.
+func (ac *Client) ResolveAlias(ctx context.Context, name string, atBlock *uint64) (*rpctypes.AliasResolution, error) {
+	var result rpctypes.AliasResolution
+	if err := ac.c.CallContext(ctx, &result, "arkiv_resolveAlias", name, (*hexutil.Uint64)(atBlock)); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetEntitiesOfOwner returns a page of the entities of the owner at a block, ordered by
+// key, with the blocks they expire at. A page with a cursor is followed by another one,
+// returned when the cursor is set in the options.
+func (ac *Client) GetEntitiesOfOwner(ctx context.Context, owner common.Address, options *rpctypes.OwnerEntitiesOptions) (*rpctypes.OwnerEntities, error) {
+	var result rpctypes.OwnerEntities
+	if err := ac.c.CallContext(ctx, &result, "arkiv_getEntitiesOfOwner", owner, options); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetOwnerEntitiesByExpiry returns a page of the entities of the owner at a block, the
+// soonest expiring first, with the number of blocks left until they expire. A page with
+// a cursor is followed by another one, returned when the cursor is set in the options.
+func (ac *Client) GetOwnerEntitiesByExpiry(ctx context.Context, owner common.Address, options *rpctypes.OwnerEntitiesByExpiryOptions) (*rpctypes.OwnerEntitiesByExpiry, error) {
+	var result rpctypes.OwnerEntitiesByExpiry
+	if err := ac.c.CallContext(ctx, &result, "arkiv_getOwnerEntitiesByExpiry", owner, options); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetEntitiesByOwnerClass returns a page of the entities at a block whose owner is of
+// the class, one of rpctypes.OwnerClassZeroAddress, OwnerClassContract and
+// OwnerClassEOA, ordered by owner and key. A page with a cursor is followed by another
+// one, returned when the cursor is set in the options; a page can be short or empty.
+func (ac *Client) GetEntitiesByOwnerClass(ctx context.Context, class string, options *rpctypes.OwnerClassOptions) (*rpctypes.OwnerClassEntities, error) {
+	var result rpctypes.OwnerClassEntities
+	if err := ac.c.CallContext(ctx, &result, "arkiv_getEntitiesByOwnerClass", class, options); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetEntitiesToExpire returns the keys of the entities expiring from fromBlock to
+// toBlock, both included, grouped by the block they expire at, only the entities of
+// the owner if it's not nil. The range covers up to 10000 blocks.
+func (ac *Client) GetEntitiesToExpire(ctx context.Context, fromBlock, toBlock uint64, owner *common.Address) (*rpctypes.EntitiesToExpire, error) {
+	var result rpctypes.EntitiesToExpire
+	if err := ac.c.CallContext(ctx, &result, "arkiv_getEntitiesToExpire", hexutil.Uint64(fromBlock), hexutil.Uint64(toBlock), owner); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// SampleEntities draws a sample of n of the entities live at a block with the seed,
+// the same on every node. The node draws up to 10000 entities per call.
+func (ac *Client) SampleEntities(ctx context.Context, n uint64, seed []byte, options *rpctypes.SampleOptions) (*rpctypes.EntitySample, error) {
+	var result rpctypes.EntitySample
+	if err := ac.c.CallContext(ctx, &result, "arkiv_sampleEntities", n, hexutil.Bytes(seed), options); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetProcessorLogs returns a page of the logs of the processor between two blocks,
+// both included.
+func (ac *Client) GetProcessorLogs(ctx context.Context, fromBlock, toBlock uint64, options *rpctypes.ProcessorLogsOptions) (*rpctypes.ProcessorLogs, error) {
+	var result rpctypes.ProcessorLogs
+	if err := ac.c.CallContext(ctx, &result, "arkiv_getProcessorLogs", hexutil.Uint64(fromBlock), hexutil.Uint64(toBlock), options); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetOwnerUsageReport returns the usage of the storage by an owner between two
+// blocks, both included.
+func (ac *Client) GetOwnerUsageReport(ctx context.Context, owner common.Address, fromBlock, toBlock uint64) (*rpctypes.OwnerUsageReport, error) {
+	var result rpctypes.OwnerUsageReport
+	if err := ac.c.CallContext(ctx, &result, "arkiv_getOwnerUsageReport", owner, hexutil.Uint64(fromBlock), hexutil.Uint64(toBlock)); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// SimulateTransaction runs the Arkiv transaction on top of the state of the current
+// block without writing it, and returns its logs, or the error it fails with, and the
+// slots of the processor it changes with IncludeStateDiff.
+func (ac *Client) SimulateTransaction(ctx context.Context, args rpctypes.SimulateTransactionArgs) (*rpctypes.SimulationResult, error) {
+	var result rpctypes.SimulationResult
+	if err := ac.c.CallContext(ctx, &result, "arkiv_simulateTransaction", args); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// EstimateStorageGas runs the Arkiv transaction on top of the state of the current
+// block without writing it, and returns the gas it uses broken down by operation and
+// the slots of the processor it writes, or the error it fails with.
+func (ac *Client) EstimateStorageGas(ctx context.Context, args rpctypes.EstimateStorageGasArgs) (*rpctypes.StorageGasEstimate, error) {
+	var result rpctypes.StorageGasEstimate
+	if err := ac.c.CallContext(ctx, &result, "arkiv_estimateStorageGas", args); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// SendSignedOperation has the relayer of the node send an Arkiv operation signed as
+// EIP-712 typed data, see storagetx.ArkivOperation. The relayer is the sender of the
+// transaction and the owner of the entities it creates.
+func (ac *Client) SendSignedOperation(ctx context.Context, typedData apitypes.TypedData, signature []byte) (*rpctypes.RelayedOperation, error) {
+	var result rpctypes.RelayedOperation
+	args := rpctypes.SendSignedOperationArgs{TypedData: typedData, Signature: signature}
+	if err := ac.c.CallContext(ctx, &result, "arkiv_sendSignedOperation", args); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// SetEventsCheckpoint moves the last block ingested by the store. The method is only
+// served on the authenticated endpoint, the client has to be dialed with the JWT
+// secret of the node.
+func (ac *Client) SetEventsCheckpoint(ctx context.Context, block uint64, force bool) (*rpctypes.EventsCheckpoint, error) {
+	var result rpctypes.EventsCheckpoint
+	if err := ac.c.CallContext(ctx, &result, "arkiv_setEventsCheckpoint", hexutil.Uint64(block), force); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ReinjectDeadLetter ingests the batch of a file of the dead-letter queue of the
+// store. The method is only served on the authenticated endpoint, like
+// SetEventsCheckpoint.
+func (ac *Client) ReinjectDeadLetter(ctx context.Context, file string) (*rpctypes.DeadLetter, error) {
+	var result rpctypes.DeadLetter
+	if err := ac.c.CallContext(ctx, &result, "arkiv_reinjectDeadLetter", file); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Warmup runs the warmup queries of the node against its head and returns how long
+// each one took. The method is only served on the authenticated endpoint, like
+// SetEventsCheckpoint.
+func (ac *Client) Warmup(ctx context.Context) (*rpctypes.WarmupReport, error) {
+	var result rpctypes.WarmupReport
+	if err := ac.c.CallContext(ctx, &result, "arkiv_warmup"); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// SubscribeContentHashVerification verifies the pairs like VerifyContentHashes, without
+// a limit on their number. The results are sent to the channel in order, in chunks
+// carrying the offset of their first pair, all of them against the same block.
+// Subscriptions need a WebSocket or IPC connection.
+func (ac *Client) SubscribeContentHashVerification(ctx context.Context, pairs []rpctypes.ContentHashPair, atBlock *uint64, ch chan<- *rpctypes.ContentHashVerification) (ethereum.Subscription, error) {
+	sub, err := ac.c.Subscribe(ctx, "arkiv", ch, "contentHashVerification", pairs, (*hexutil.Uint64)(atBlock))
+	if err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// SubscribeEntityEvents subscribes to the lifecycle events of the entities matching the
+// filter, the events of the blocks appended to the canonical chain after the call. A
+// nil filter matches all the entities. Subscriptions need a WebSocket or IPC
+// connection.
+func (ac *Client) SubscribeEntityEvents(ctx context.Context, filter *rpctypes.EntityEventFilter, ch chan<- *rpctypes.EntityEvent) (ethereum.Subscription, error) {
+	sub, err := ac.c.Subscribe(ctx, "arkiv", ch, "entityEvents", filter)
+	if err != nil {
+		// Defensively prefer returning nil interface explicitly on error-path, instead
+		// of letting default golang behavior wrap it with non-nil interface that stores
+		// nil concrete type value.
+		return nil, err
+	}
+	return sub, nil
+}
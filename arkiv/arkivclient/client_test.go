@@ -0,0 +1,335 @@
+package arkivclient
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sqlitestore "github.com/Arkiv-Network/sqlite-bitmap-store"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/arkiv/compression"
+	"github.com/ethereum/go-ethereum/arkiv/rpctypes"
+	"github.com/ethereum/go-ethereum/arkiv/storagechallenge"
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/arkiv/testutil"
+	"github.com/ethereum/go-ethereum/arkiv/webhook"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient(t *testing.T) {
+	if testing.Short() {
+		t.Skip("starts a dev mode node")
+	}
+
+	gethPath, cleanupCompiled, err := testutil.CompileGeth("../../cmd/geth")
+	require.NoError(t, err)
+	defer cleanupCompiled()
+
+	ctx := context.Background()
+
+	world, err := testutil.NewWorld(ctx, gethPath)
+	require.NoError(t, err)
+	defer world.Shutdown()
+
+	client := NewClient(world.GethInstance.RPCClient)
+
+	receipt, err := world.CreateEntity(ctx, 100, []byte("hello arkiv"), []storagetx.StringAnnotation{
+		{Key: "kind", Value: "client"},
+	}, nil)
+	require.NoError(t, err)
+	key := receipt.Logs[0].Topics[1]
+	owner := world.FundedAccount.Address
+	block := receipt.BlockNumber.Uint64()
+	require.NoError(t, world.WaitForStore(ctx, block))
+
+	t.Run("Query", func(t *testing.T) {
+		response, err := client.Query(ctx, `kind = "client"`, &rpctypes.QueryOptions{
+			Options: sqlitestore.Options{
+				AtBlock:     &block,
+				IncludeData: &sqlitestore.IncludeData{Key: true, Payload: true},
+			},
+		})
+		require.NoError(t, err)
+		entities, err := response.Entities()
+		require.NoError(t, err)
+		require.Len(t, entities, 1)
+		require.Equal(t, key, *entities[0].Key)
+		require.Equal(t, []byte("hello arkiv"), []byte(entities[0].Value))
+	})
+
+	t.Run("GetEntity", func(t *testing.T) {
+		entity, err := client.GetEntity(ctx, key, nil)
+		require.NoError(t, err)
+		require.Equal(t, key, entity.Key)
+		require.Equal(t, owner, entity.Owner)
+		require.Equal(t, block+100, uint64(entity.ExpiresAtBlock))
+		require.Equal(t, map[string]string{"kind": "client"}, entity.StringAttributes)
+		require.Equal(t, []byte("hello arkiv"), []byte(entity.Payload))
+
+		entity, err = client.GetEntity(ctx, key, &block)
+		require.NoError(t, err)
+		require.Equal(t, block, uint64(entity.Block))
+
+		_, err = client.GetEntity(ctx, common.HexToHash("0x01"), nil)
+		require.ErrorIs(t, err, ethereum.NotFound)
+	})
+
+	t.Run("QueryDiff", func(t *testing.T) {
+		diff, err := client.QueryDiff(ctx, `kind = "client"`, block-1, block, nil)
+		require.NoError(t, err)
+		require.Equal(t, []common.Hash{key}, diff.Added)
+		require.Empty(t, diff.Removed)
+	})
+
+	t.Run("GetEntityCount", func(t *testing.T) {
+		count, err := client.GetEntityCount(ctx)
+		require.NoError(t, err)
+		require.NotZero(t, count)
+	})
+
+	t.Run("GetNumberOfUsedSlots", func(t *testing.T) {
+		slots, err := client.GetNumberOfUsedSlots(ctx)
+		require.NoError(t, err)
+		require.Positive(t, slots.Sign())
+	})
+
+	t.Run("GetUsedSlotsByOwner", func(t *testing.T) {
+		// The owner slots fork is not active in dev mode
+		slots, err := client.GetUsedSlotsByOwner(ctx, owner)
+		require.NoError(t, err)
+		require.Zero(t, slots.Sign())
+	})
+
+	t.Run("GetEntityMetaData", func(t *testing.T) {
+		metaData, err := client.GetEntityMetaData(ctx, key)
+		require.NoError(t, err)
+		require.Equal(t, rpctypes.EntityStatusLive, metaData.Status)
+		require.Equal(t, owner, *metaData.Owner)
+		require.Equal(t, block+100, uint64(*metaData.ExpiresAtBlock))
+	})
+
+	t.Run("ComputeEntityKey", func(t *testing.T) {
+		computed, err := client.ComputeEntityKey(ctx, receipt.TxHash, 0, []byte("hello arkiv"))
+		require.NoError(t, err)
+		require.Equal(t, key, computed)
+	})
+
+	t.Run("GetEntityExpiry", func(t *testing.T) {
+		expiry, err := client.GetEntityExpiry(ctx, key)
+		require.NoError(t, err)
+		require.Equal(t, rpctypes.EntityStatusLive, expiry.Status)
+		require.Equal(t, block+100, uint64(*expiry.ExpiresAtBlock))
+		require.NotNil(t, expiry.BlocksRemaining)
+	})
+
+	t.Run("GetBlockTiming", func(t *testing.T) {
+		timing, err := client.GetBlockTiming(ctx)
+		require.NoError(t, err)
+		require.GreaterOrEqual(t, uint64(timing.CurrentBlock), block)
+	})
+
+	t.Run("SyncStatus", func(t *testing.T) {
+		status, err := client.SyncStatus(ctx)
+		require.NoError(t, err)
+		require.GreaterOrEqual(t, uint64(status.LastBlock), block)
+		require.False(t, status.ReadOnly)
+	})
+
+	t.Run("SelfCheck", func(t *testing.T) {
+		report, err := client.SelfCheck(ctx)
+		require.NoError(t, err)
+		require.True(t, report.OK, "%+v", report.Findings)
+		require.Len(t, report.Findings, 5)
+	})
+
+	t.Run("GetLimits", func(t *testing.T) {
+		limits, err := client.GetLimits(ctx)
+		require.NoError(t, err)
+		require.NotZero(t, limits.MaxAnnotationValueSize)
+		require.Equal(t, hexutil.Uint64(block), limits.Block)
+		require.NotEmpty(t, limits.Consensus)
+		require.NotEmpty(t, limits.Node)
+	})
+
+	t.Run("ShadowEnforcementStats", func(t *testing.T) {
+		stats, err := client.ShadowEnforcementStats(ctx)
+		require.NoError(t, err)
+		require.False(t, stats.Enabled)
+		require.Empty(t, stats.Rules)
+	})
+
+	t.Run("Capabilities", func(t *testing.T) {
+		capabilities, err := client.Capabilities(ctx, &block)
+		require.NoError(t, err)
+		require.Equal(t, hexutil.Uint64(block), capabilities.Block)
+		require.Len(t, capabilities.Features, len(params.ArkivFeatures()))
+		require.Empty(t, capabilities.DisabledMethods)
+	})
+
+	t.Run("GetProcessorLogs", func(t *testing.T) {
+		logs, err := client.GetProcessorLogs(ctx, block, block, &rpctypes.ProcessorLogsOptions{
+			Owner: &owner,
+			Kinds: []string{webhook.KindCreated},
+		})
+		require.NoError(t, err)
+		require.Len(t, logs.Logs, 1)
+		require.Equal(t, key, logs.Logs[0].Key)
+		require.Nil(t, logs.Cursor)
+	})
+
+	t.Run("GetOwnerUsageReport", func(t *testing.T) {
+		report, err := client.GetOwnerUsageReport(ctx, owner, block, block)
+		require.NoError(t, err)
+		require.Equal(t, uint64(1), report.Creates)
+		require.Equal(t, uint64(len("hello arkiv")), report.PayloadBytes)
+	})
+
+	t.Run("SubscribeEntityEvents", func(t *testing.T) {
+		// The node is dialed over HTTP
+		_, err := client.SubscribeEntityEvents(ctx, &rpctypes.EntityEventFilter{Keys: []common.Hash{key}}, make(chan *rpctypes.EntityEvent))
+		require.ErrorIs(t, err, rpc.ErrNotificationsUnsupported)
+	})
+
+	t.Run("VerifyContentHashes", func(t *testing.T) {
+		verification, err := client.VerifyContentHashes(ctx, []rpctypes.ContentHashPair{
+			{Key: key, Hash: crypto.Keccak256Hash([]byte("hello arkiv"))},
+			{Key: key, Hash: common.Hash{}},
+		}, &block)
+		require.NoError(t, err)
+		require.Equal(t, hexutil.Uint64(block), verification.Block)
+		require.Equal(t, rpctypes.ContentHashMatch, verification.Results[0].Status)
+		require.Equal(t, rpctypes.ContentHashMismatch, verification.Results[1].Status)
+
+		// The node is dialed over HTTP
+		_, err = client.SubscribeContentHashVerification(ctx, nil, nil, make(chan *rpctypes.ContentHashVerification))
+		require.ErrorIs(t, err, rpc.ErrNotificationsUnsupported)
+	})
+
+	t.Run("StorageChallenge", func(t *testing.T) {
+		nonce, err := storagechallenge.NewNonce()
+		require.NoError(t, err)
+		challenge, err := client.StorageChallenge(ctx, []common.Hash{key}, nonce)
+		require.NoError(t, err)
+		response := storagechallenge.PayloadResponse(nonce, []byte("hello arkiv"))
+		require.Equal(t, []rpctypes.StorageChallengeResponse{{Key: key, Response: &response}}, challenge.Responses)
+	})
+
+	t.Run("GetIngestLatency", func(t *testing.T) {
+		// The store records the block before the node sees the ingestion return
+		var latency *rpctypes.IngestLatency
+		require.Eventually(t, func() bool {
+			latency, err = client.GetIngestLatency(ctx, receipt.TxHash)
+			return err == nil && latency != nil && latency.IndexedAt != nil
+		}, 5*time.Second, 50*time.Millisecond)
+		require.Equal(t, block, uint64(*latency.Block))
+		require.NotZero(t, latency.SubmittedAt)
+		require.NotNil(t, latency.IncludedAt)
+		require.NotNil(t, latency.SubmitToInclusion)
+		require.NotNil(t, latency.InclusionToIndexed)
+		require.GreaterOrEqual(t, *latency.IndexedAt, *latency.IncludedAt)
+
+		// The transactions that weren't submitted to the node aren't tracked
+		latency, err = client.GetIngestLatency(ctx, common.HexToHash("0x1"))
+		require.NoError(t, err)
+		require.Nil(t, latency)
+	})
+
+	t.Run("ResolveAlias", func(t *testing.T) {
+		var rpcErr rpc.Error
+		_, err := client.ResolveAlias(ctx, "unregistered", &block)
+		require.ErrorAs(t, err, &rpcErr)
+		require.Equal(t, rpctypes.ErrCodeNotFound, rpcErr.ErrorCode())
+	})
+
+	t.Run("GetEntitiesOfOwner", func(t *testing.T) {
+		page, err := client.GetEntitiesOfOwner(ctx, owner, &rpctypes.OwnerEntitiesOptions{AtBlock: (*hexutil.Uint64)(&block)})
+		require.NoError(t, err)
+		require.Equal(t, []rpctypes.OwnerEntity{{Key: key, ExpiresAtBlock: hexutil.Uint64(block + 100)}}, page.Entities)
+		require.Nil(t, page.Cursor)
+	})
+
+	t.Run("GetOwnerEntitiesByExpiry", func(t *testing.T) {
+		page, err := client.GetOwnerEntitiesByExpiry(ctx, owner, &rpctypes.OwnerEntitiesByExpiryOptions{AtBlock: (*hexutil.Uint64)(&block)})
+		require.NoError(t, err)
+		require.Equal(t, []rpctypes.ExpiringEntity{{Key: key, ExpiresAtBlock: hexutil.Uint64(block + 100), BlocksRemaining: 100}}, page.Entities)
+		require.Nil(t, page.Cursor)
+	})
+
+	t.Run("GetEntitiesToExpire", func(t *testing.T) {
+		result, err := client.GetEntitiesToExpire(ctx, block+100, block+100, &owner)
+		require.NoError(t, err)
+		require.Equal(t, []rpctypes.ExpiringBlock{{Block: hexutil.Uint64(block + 100), Keys: []common.Hash{key}}}, result.Blocks)
+	})
+
+	t.Run("QueryCount", func(t *testing.T) {
+		count, err := client.QueryCount(ctx, `kind = "client"`, &sqlitestore.Options{AtBlock: &block})
+		require.NoError(t, err)
+		require.Equal(t, uint64(1), count.Count)
+		require.GreaterOrEqual(t, uint64(count.Block), block)
+	})
+
+	t.Run("SampleEntities", func(t *testing.T) {
+		sample, err := client.SampleEntities(ctx, 10, []byte("seed"), &rpctypes.SampleOptions{AtBlock: (*hexutil.Uint64)(&block), IncludeMetaData: true})
+		require.NoError(t, err)
+		require.Equal(t, hexutil.Uint64(block), sample.Block)
+		require.Equal(t, uint64(len(sample.Entities)), sample.Population)
+		require.NotEmpty(t, sample.Entities)
+		for _, entity := range sample.Entities {
+			require.Equal(t, rpctypes.EntityStatusLive, entity.MetaData.Status)
+		}
+	})
+
+	t.Run("SimulateTransaction", func(t *testing.T) {
+		data, err := rlp.EncodeToBytes(&storagetx.ArkivTransaction{Extend: []storagetx.ExtendBTL{{EntityKey: key, NumberOfBlocks: 10}}})
+		require.NoError(t, err)
+		result, err := client.SimulateTransaction(ctx, rpctypes.SimulateTransactionArgs{
+			From:             owner,
+			Data:             compression.MustBrotliCompress(data),
+			IncludeStateDiff: true,
+		})
+		require.NoError(t, err)
+		require.True(t, result.Success, result.Error)
+		require.Len(t, result.Logs, 1)
+		require.NotEmpty(t, result.StateDiff)
+	})
+
+	t.Run("EstimateStorageGas", func(t *testing.T) {
+		estimate, err := client.EstimateStorageGas(ctx, rpctypes.EstimateStorageGasArgs{
+			From:        owner,
+			Transaction: storagetx.ArkivTransaction{Extend: []storagetx.ExtendBTL{{EntityKey: key, NumberOfBlocks: 10}}},
+		})
+		require.NoError(t, err)
+		require.True(t, estimate.Success, estimate.Error)
+		require.NotZero(t, estimate.Gas)
+		require.Equal(t, []storagetx.OperationGas{{Operation: "extend", Index: 0, Gas: 0}}, estimate.Operations)
+	})
+
+	t.Run("SendSignedOperation", func(t *testing.T) {
+		op := &storagetx.ArkivOperation{
+			Transaction: storagetx.ArkivTransaction{Extend: []storagetx.ExtendBTL{{EntityKey: key, NumberOfBlocks: 10}}},
+			Deadline:    uint64(time.Now().Unix()) + 60,
+		}
+		chainID, err := world.GethInstance.ETHClient.ChainID(ctx)
+		require.NoError(t, err)
+		hash, err := op.Hash(chainID)
+		require.NoError(t, err)
+		signature, err := crypto.Sign(hash[:], world.FundedAccount.PrivateKey)
+		require.NoError(t, err)
+
+		// The dev node has no relayer account
+		_, err = client.SendSignedOperation(ctx, op.TypedData(chainID), signature)
+		require.ErrorContains(t, err, "no Arkiv relayer account configured")
+	})
+
+	t.Run("SetEventsCheckpoint", func(t *testing.T) {
+		// The method is only served on the authenticated endpoint
+		_, err := client.SetEventsCheckpoint(ctx, block, false)
+		require.ErrorContains(t, err, "does not exist")
+	})
+}
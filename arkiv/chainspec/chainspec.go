@@ -0,0 +1,121 @@
+// Package chainspec gathers the consensus-critical constants of the Arkiv processor:
+// the addresses of the processor and of its capabilities and content hash precompiles,
+// the topics of its logs and the salts its storage slots are derived from. Changing
+// any of them forks the chain, they are pinned by the fixture in testdata.
+package chainspec
+
+import (
+	"bytes"
+
+	"github.com/ethereum/go-ethereum/arkiv/address"
+	"github.com/ethereum/go-ethereum/arkiv/logs"
+	"github.com/ethereum/go-ethereum/arkiv/storageaccounting"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity/entityexpiration"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/keyset"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// Topics are the topics of the logs emitted by the processor, see arkiv/logs.
+type Topics struct {
+	EntityCreated                   common.Hash `json:"entityCreated"`
+	EntityUpdated                   common.Hash `json:"entityUpdated"`
+	EntityExpired                   common.Hash `json:"entityExpired"`
+	EntityDeleted                   common.Hash `json:"entityDeleted"`
+	EntityBTLExtended               common.Hash `json:"entityBTLExtended"`
+	EntityOwnerChanged              common.Hash `json:"entityOwnerChanged"`
+	EntityOwnershipTransferProposed common.Hash `json:"entityOwnershipTransferProposed"`
+	EntityOwnershipTransferAccepted common.Hash `json:"entityOwnershipTransferAccepted"`
+	EntityOwnershipTransferLapsed   common.Hash `json:"entityOwnershipTransferLapsed"`
+	AliasRegistered                 common.Hash `json:"aliasRegistered"`
+	AliasTransferred                common.Hash `json:"aliasTransferred"`
+	AliasExpired                    common.Hash `json:"aliasExpired"`
+	CreateDeduplicated              common.Hash `json:"createDeduplicated"`
+	EntityBTLReduced                common.Hash `json:"entityBTLReduced"`
+}
+
+// Salts are the salts the storage slots of the processor are derived from.
+type Salts struct {
+	// EntityMetaData salts the slot of the metadata of an entity.
+	EntityMetaData hexutil.Bytes `json:"entityMetaData"`
+	// BlockExpiration salts the set of the entities expiring at a block.
+	BlockExpiration hexutil.Bytes `json:"blockExpiration"`
+	// Tombstone salts the slot of the tombstone of a removed entity.
+	Tombstone hexutil.Bytes `json:"tombstone"`
+	// TombstoneSweep salts the set of the tombstones swept at a block.
+	TombstoneSweep hexutil.Bytes `json:"tombstoneSweep"`
+	// PendingOwner salts the slot of the pending owner of an entity.
+	PendingOwner hexutil.Bytes `json:"pendingOwner"`
+	// PendingOwnerLapse salts the set of the pending owners lapsing at a block.
+	PendingOwnerLapse hexutil.Bytes `json:"pendingOwnerLapse"`
+	// KeysetMap prefixes the index of the elements of every set.
+	KeysetMap hexutil.Bytes `json:"keysetMap"`
+	// OwnerUsedSlots salts the slot counting the slots used by the entities of an owner.
+	OwnerUsedSlots hexutil.Bytes `json:"ownerUsedSlots"`
+	// Alias salts the slot of the key of the entity an alias points to.
+	Alias hexutil.Bytes `json:"alias"`
+	// AliasOwner salts the slot of the owner and the expiry of an alias.
+	AliasOwner hexutil.Bytes `json:"aliasOwner"`
+	// AliasExpiration salts the set of the aliases expiring at a block.
+	AliasExpiration hexutil.Bytes `json:"aliasExpiration"`
+	// Idempotency salts the slot of the entity created with an idempotency key.
+	Idempotency hexutil.Bytes `json:"idempotency"`
+	// IdempotencyExpiration salts the set of the idempotency keys expiring at a block.
+	IdempotencyExpiration hexutil.Bytes `json:"idempotencyExpiration"`
+}
+
+// Spec is the registry of the consensus-critical constants of the processor.
+type Spec struct {
+	ProcessorAddress common.Address `json:"processorAddress"`
+	// CapabilitiesAddress is the address of the capabilities precompile.
+	CapabilitiesAddress common.Address `json:"capabilitiesAddress"`
+	// ContentHashAddress is the address of the content hash precompile.
+	ContentHashAddress common.Address `json:"contentHashAddress"`
+	// UsedSlotsKey is the slot counting the slots used by the processor.
+	UsedSlotsKey common.Hash `json:"usedSlotsKey"`
+	Topics       Topics      `json:"topics"`
+	Salts        Salts       `json:"salts"`
+}
+
+// Arkiv returns the constants of the processor. The salts are copies, modifying them
+// doesn't affect the processor.
+func Arkiv() Spec {
+	return Spec{
+		ProcessorAddress:    address.ArkivProcessorAddress,
+		CapabilitiesAddress: address.ArkivCapabilitiesAddress,
+		ContentHashAddress:  address.ArkivContentHashAddress,
+		UsedSlotsKey:        storageaccounting.UsedSlotsKey,
+		Topics: Topics{
+			EntityCreated:                   logs.ArkivEntityCreated,
+			EntityUpdated:                   logs.ArkivEntityUpdated,
+			EntityExpired:                   logs.ArkivEntityExpired,
+			EntityDeleted:                   logs.ArkivEntityDeleted,
+			EntityBTLExtended:               logs.ArkivEntityBTLExtended,
+			EntityOwnerChanged:              logs.ArkivEntityOwnerChanged,
+			EntityOwnershipTransferProposed: logs.ArkivEntityOwnershipTransferProposed,
+			EntityOwnershipTransferAccepted: logs.ArkivEntityOwnershipTransferAccepted,
+			EntityOwnershipTransferLapsed:   logs.ArkivEntityOwnershipTransferLapsed,
+			AliasRegistered:                 logs.ArkivAliasRegistered,
+			AliasTransferred:                logs.ArkivAliasTransferred,
+			AliasExpired:                    logs.ArkivAliasExpired,
+			CreateDeduplicated:              logs.ArkivCreateDeduplicated,
+			EntityBTLReduced:                logs.ArkivEntityBTLReduced,
+		},
+		Salts: Salts{
+			EntityMetaData:        bytes.Clone(entity.EntityMetaDataSalt),
+			BlockExpiration:       bytes.Clone(entityexpiration.BlockExpirationSalt),
+			Tombstone:             bytes.Clone(entity.TombstoneSalt),
+			TombstoneSweep:        bytes.Clone(entity.TombstoneSweepSalt),
+			PendingOwner:          bytes.Clone(entity.PendingOwnerSalt),
+			PendingOwnerLapse:     bytes.Clone(entity.PendingOwnerLapseSalt),
+			KeysetMap:             bytes.Clone(keyset.MapKeyPrefix),
+			OwnerUsedSlots:        bytes.Clone(storageaccounting.OwnerUsedSlotsSalt),
+			Alias:                 bytes.Clone(entity.AliasSalt),
+			AliasOwner:            bytes.Clone(entity.AliasOwnerSalt),
+			AliasExpiration:       bytes.Clone(entity.AliasExpirationSalt),
+			Idempotency:           bytes.Clone(entity.IdempotencySalt),
+			IdempotencyExpiration: bytes.Clone(entity.IdempotencyExpirationSalt),
+		},
+	}
+}
@@ -0,0 +1,189 @@
+package chainspec_test
+
+import (
+	"encoding/json"
+	"flag"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"math"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/arkiv/address"
+	"github.com/ethereum/go-ethereum/arkiv/chainspec"
+	"github.com/ethereum/go-ethereum/arkiv/storageaccounting"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity/entityexpiration"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+var writeFixtureFlag = flag.Bool("write-fixture", false, "Overwrite the chain-spec fixture in testdata/")
+
+const fixtureFile = "testdata/chainspec.json"
+
+var (
+	sampleKey   = common.HexToHash("0x5a3c6e1f0b9d24875ac3e0f1d2b4a6c8e0f1a2b3c4d5e6f708192a3b4c5d6e7f")
+	sampleOwner = common.HexToAddress("0x1111111111111111111111111111111111111111")
+	sampleNext  = common.HexToAddress("0x2222222222222222222222222222222222222222")
+)
+
+// recordingState is a state of the processor recording the slots written to it.
+type recordingState map[common.Hash]common.Hash
+
+func (s recordingState) GetState(addr common.Address, key common.Hash) common.Hash {
+	if addr != address.ArkivProcessorAddress {
+		panic("unexpected address " + addr.Hex())
+	}
+	return s[key]
+}
+
+func (s recordingState) SetState(addr common.Address, key common.Hash, value common.Hash) common.Hash {
+	if addr != address.ArkivProcessorAddress {
+		panic("unexpected address " + addr.Hex())
+	}
+	prev := s[key]
+	s[key] = value
+	return prev
+}
+
+// metaDataEncoding is the encoding of an EntityMetaData in its slot.
+type metaDataEncoding struct {
+	Owner          common.Address `json:"owner"`
+	ExpiresAtBlock uint64         `json:"expiresAtBlock"`
+	Slot           common.Hash    `json:"slot"`
+}
+
+// fixture holds the constants of the chain spec, the slots written by the operations
+// of the processor on the sample key, and the encoding of the entity metadata.
+type fixture struct {
+	Spec     chainspec.Spec                         `json:"spec"`
+	Slots    map[string]map[common.Hash]common.Hash `json:"slots"`
+	MetaData []metaDataEncoding                     `json:"metaData"`
+}
+
+func record(t *testing.T, write func(access recordingState) error) map[common.Hash]common.Hash {
+	t.Helper()
+
+	access := recordingState{}
+	require.NoError(t, write(access))
+	return access
+}
+
+func buildFixture(t *testing.T) fixture {
+	t.Helper()
+
+	slots := map[string]map[common.Hash]common.Hash{
+		"storeEntity": record(t, func(access recordingState) error {
+			return entity.Store(access, sampleKey, sampleOwner, entity.EntityMetaData{Owner: sampleOwner, ExpiresAtBlock: 100}, nil, false)
+		}),
+		"storeEntityWithContentHash": record(t, func(access recordingState) error {
+			return entity.Store(access, sampleKey, sampleOwner, entity.EntityMetaData{Owner: sampleOwner, ExpiresAtBlock: 100}, []byte("sample"), true)
+		}),
+		"storeTombstone": record(t, func(access recordingState) error {
+			return entity.StoreTombstone(access, sampleKey, entity.Tombstone{Reason: entity.TombstoneExpired, Block: 100}, 400)
+		}),
+		"storePendingOwner": record(t, func(access recordingState) error {
+			return entity.StorePendingOwner(access, sampleKey, entity.PendingOwner{Owner: sampleNext, LapsesAtBlock: 150})
+		}),
+		"storeAlias": record(t, func(access recordingState) error {
+			return entity.StoreAlias(access, entity.AliasNameHash("sample"), entity.Alias{Key: sampleKey, Owner: sampleOwner, ExpiresAtBlock: 200})
+		}),
+		"storeIdempotencyKey": record(t, func(access recordingState) error {
+			return entity.StoreIdempotencyKey(access, entity.IdempotencyHash(sampleOwner, common.Hash{1}), sampleKey, 300)
+		}),
+		"countUsedSlots": record(t, func(access recordingState) error {
+			counter := storageaccounting.NewSlotUsageCounter(access)
+			counter.SetState(address.ArkivProcessorAddress, sampleKey, common.Hash{1})
+			counter.UpdateUsedSlotsForGolemBase()
+			return nil
+		}),
+		"countOwnerUsedSlots": record(t, func(access recordingState) error {
+			counter := storageaccounting.NewSlotUsageCounter(access)
+			counter.AddOwnerSlots(sampleOwner, 3)
+			counter.UpdateUsedSlotsForGolemBase()
+			return nil
+		}),
+	}
+	// The expiration sets are keyed by the minimal big-endian encoding of the block
+	for _, block := range []uint64{0, 1, 255, 256, math.MaxUint64} {
+		slots["expireAtBlock/"+strconv.FormatUint(block, 10)] = record(t, func(access recordingState) error {
+			return entityexpiration.AddToEntitiesToExpireAtBlock(access, block, sampleKey)
+		})
+	}
+
+	var metaData []metaDataEncoding
+	for _, md := range []entity.EntityMetaData{
+		{},
+		{ExpiresAtBlock: math.MaxUint64},
+		{Owner: sampleOwner, ExpiresAtBlock: 100},
+		{Owner: common.MaxAddress, ExpiresAtBlock: math.MaxUint64},
+	} {
+		metaData = append(metaData, metaDataEncoding{Owner: md.Owner, ExpiresAtBlock: md.ExpiresAtBlock, Slot: md.Marshal()})
+	}
+
+	return fixture{Spec: chainspec.Arkiv(), Slots: slots, MetaData: metaData}
+}
+
+// TestFixture pins the consensus-critical constants and slot formulas of the
+// processor. A change to any of them forks the chain: if it is intended, review the
+// diff of the fixture and overwrite it with -write-fixture.
+func TestFixture(t *testing.T) {
+	got, err := json.MarshalIndent(buildFixture(t), "", "  ")
+	require.NoError(t, err)
+	got = append(got, '\n')
+
+	if *writeFixtureFlag {
+		require.NoError(t, os.WriteFile(fixtureFile, got, 0o644))
+		return
+	}
+
+	want, err := os.ReadFile(fixtureFile)
+	require.NoError(t, err)
+	require.Equal(t, string(want), string(got), "consensus constants changed, review the change and run the test with -write-fixture")
+}
+
+func TestMetaDataRoundTrip(t *testing.T) {
+	for _, encoded := range buildFixture(t).MetaData {
+		md := entity.EntityMetaData{}
+		md.Unmarshal(encoded.Slot)
+		require.Equal(t, entity.EntityMetaData{Owner: encoded.Owner, ExpiresAtBlock: encoded.ExpiresAtBlock}, md)
+	}
+}
+
+// TestTopicsComplete checks that every topic declared in arkiv/logs is in the spec.
+func TestTopicsComplete(t *testing.T) {
+	file, err := parser.ParseFile(token.NewFileSet(), "../logs/arkiv.go", nil, 0)
+	require.NoError(t, err)
+
+	var declared []string
+	ast.Inspect(file, func(node ast.Node) bool {
+		if spec, ok := node.(*ast.ValueSpec); ok {
+			for _, name := range spec.Names {
+				declared = append(declared, strings.TrimPrefix(name.Name, "Arkiv"))
+			}
+		}
+		return true
+	})
+
+	var fields []string
+	topics := reflect.TypeOf(chainspec.Topics{})
+	for i := range topics.NumField() {
+		fields = append(fields, topics.Field(i).Name)
+	}
+
+	slices.Sort(declared)
+	slices.Sort(fields)
+	require.Equal(t, declared, fields)
+}
+
+func TestSaltsAreCopies(t *testing.T) {
+	spec := chainspec.Arkiv()
+	spec.Salts.EntityMetaData[0] = 'X'
+	require.Equal(t, []byte("arkivEntityMetaData"), entity.EntityMetaDataSalt)
+}
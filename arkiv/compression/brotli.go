@@ -2,6 +2,7 @@ package compression
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 
@@ -39,6 +40,9 @@ func MustBrotliCompress(data []byte) []byte {
 	return compressed
 }
 
+// ErrDecompressedTooLarge is returned for the data decompressing past the limit.
+var ErrDecompressedTooLarge = errors.New("decompressed data is too large")
+
 func BrotliDecompress(data []byte) ([]byte, error) {
 	if len(data) == 0 {
 		return nil, nil
@@ -46,3 +50,34 @@ func BrotliDecompress(data []byte) ([]byte, error) {
 	reader := brotli.NewReader(bytes.NewReader(data))
 	return io.ReadAll(reader)
 }
+
+// BrotliDecompressLimited decompresses the data like BrotliDecompress, reading at most
+// limit bytes: data decompressing to more fails with ErrDecompressedTooLarge, without
+// holding more than limit bytes in memory.
+func BrotliDecompressLimited(data []byte, limit uint64) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	return ReadAllLimited(brotli.NewReader(bytes.NewReader(data)), limit)
+}
+
+// ReadAllLimited reads the reader until EOF like io.ReadAll, reading at most limit
+// bytes: a reader with more fails with ErrDecompressedTooLarge.
+func ReadAllLimited(reader io.Reader, limit uint64) ([]byte, error) {
+	decompressed, err := io.ReadAll(io.LimitReader(reader, int64(limit)))
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(decompressed)) == limit {
+		// Anything left past the limit is too much
+		var next [1]byte
+		n, err := io.ReadFull(reader, next[:])
+		if n > 0 {
+			return nil, fmt.Errorf("%w: more than %d bytes", ErrDecompressedTooLarge, limit)
+		}
+		if err != io.EOF {
+			return nil, err
+		}
+	}
+	return decompressed, nil
+}
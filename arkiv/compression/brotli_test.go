@@ -0,0 +1,30 @@
+package compression
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBrotliDecompressLimited(t *testing.T) {
+	data := bytes.Repeat([]byte{0}, 1024)
+	compressed := MustBrotliCompress(data)
+
+	for _, limit := range []uint64{1024, 4096} {
+		decompressed, err := BrotliDecompressLimited(compressed, limit)
+		require.NoError(t, err)
+		require.Equal(t, data, decompressed)
+	}
+
+	// Data decompressing past the limit fails
+	_, err := BrotliDecompressLimited(compressed, 1023)
+	require.ErrorIs(t, err, ErrDecompressedTooLarge)
+
+	_, err = BrotliDecompressLimited([]byte("not brotli"), 1024)
+	require.Error(t, err)
+
+	decompressed, err := BrotliDecompressLimited(nil, 1024)
+	require.NoError(t, err)
+	require.Empty(t, decompressed)
+}
@@ -0,0 +1,120 @@
+package compression
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// The codecs of the calldata of the Arkiv transactions, their id prefixes the
+// compressed calldata. No brotli stream starts with them, their window bits are
+// invalid, so the calldata compressed with raw brotli before the prefix existed is
+// still told apart.
+const (
+	// TxCodecBrotli prefixes the brotli compressed calldata.
+	TxCodecBrotli byte = 0x11
+	// TxCodecZstd prefixes the zstd compressed calldata.
+	TxCodecZstd byte = 0x91
+)
+
+// ZstdMaxWindow is the largest window of the zstd frames of the calldata, the frames
+// declaring a larger one fail to decompress on every node.
+const ZstdMaxWindow = 8 << 20
+
+// TxCodecNames are the names of the codecs of the calldata.
+var TxCodecNames = map[byte]string{
+	TxCodecBrotli: "br",
+	TxCodecZstd:   "zstd",
+}
+
+// ZstdCompress compresses the data with zstd.
+func ZstdCompress(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	encoder, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedBestCompression), zstd.WithEncoderConcurrency(1), zstd.WithWindowSize(ZstdMaxWindow))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create zstd compressor: %w", err)
+	}
+	defer encoder.Close()
+	return encoder.EncodeAll(data, nil), nil
+}
+
+// Compress compresses the data with the codec and prefixes it with the id of the
+// codec.
+func Compress(codec byte, data []byte) ([]byte, error) {
+	var (
+		compressed []byte
+		err        error
+	)
+	switch codec {
+	case TxCodecBrotli:
+		compressed, err = BrotliCompress(data)
+	case TxCodecZstd:
+		compressed, err = ZstdCompress(data)
+	default:
+		return nil, fmt.Errorf("unknown codec %#x", codec)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{codec}, compressed...), nil
+}
+
+// MustCompress is Compress panicking on errors.
+func MustCompress(codec byte, data []byte) []byte {
+	compressed, err := Compress(codec, data)
+	if err != nil {
+		panic(fmt.Errorf("failed to compress data: %w", err))
+	}
+	return compressed
+}
+
+// NewReader returns a reader decompressing the data with the codec of its prefix,
+// or as raw brotli without one.
+func NewReader(data []byte) (io.ReadCloser, error) {
+	if len(data) > 0 {
+		switch data[0] {
+		case TxCodecBrotli:
+			return io.NopCloser(brotli.NewReader(bytes.NewReader(data[1:]))), nil
+		case TxCodecZstd:
+			decoder, err := zstd.NewReader(bytes.NewReader(data[1:]), zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxWindow(ZstdMaxWindow))
+			if err != nil {
+				return nil, fmt.Errorf("failed to create zstd decompressor: %w", err)
+			}
+			return decoder.IOReadCloser(), nil
+		}
+	}
+	return io.NopCloser(brotli.NewReader(bytes.NewReader(data))), nil
+}
+
+// Decompress decompresses the data with the codec of its prefix, or as raw brotli
+// without one.
+func Decompress(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	reader, err := NewReader(data)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// DecompressLimited decompresses the data like Decompress, reading at most limit
+// bytes: data decompressing to more fails with ErrDecompressedTooLarge.
+func DecompressLimited(data []byte, limit uint64) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	reader, err := NewReader(data)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return ReadAllLimited(reader, limit)
+}
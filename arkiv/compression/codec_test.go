@@ -0,0 +1,45 @@
+package compression
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompress(t *testing.T) {
+	data := bytes.Repeat([]byte("hello arkiv "), 100)
+	for codec := range TxCodecNames {
+		compressed, err := Compress(codec, data)
+		require.NoError(t, err)
+		require.Equal(t, codec, compressed[0])
+
+		decompressed, err := Decompress(compressed)
+		require.NoError(t, err, TxCodecNames[codec])
+		require.Equal(t, data, decompressed)
+
+		_, err = DecompressLimited(compressed, uint64(len(data)-1))
+		require.ErrorIs(t, err, ErrDecompressedTooLarge)
+	}
+	_, err := Compress(0x01, data)
+	require.ErrorContains(t, err, "unknown codec 0x1")
+}
+
+func TestDecompress_RawBrotli(t *testing.T) {
+	data := bytes.Repeat([]byte("hello arkiv "), 100)
+	decompressed, err := Decompress(MustBrotliCompress(data))
+	require.NoError(t, err)
+	require.Equal(t, data, decompressed)
+
+	// No brotli stream starts with the id of a codec
+	for codec := range TxCodecNames {
+		_, err := BrotliDecompress([]byte{codec, 0, 0, 0})
+		require.Error(t, err)
+	}
+
+	decompressed, err = Decompress(nil)
+	require.NoError(t, err)
+	require.Empty(t, decompressed)
+	_, err = Decompress([]byte{TxCodecZstd, 1, 2, 3})
+	require.Error(t, err)
+}
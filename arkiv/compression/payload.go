@@ -0,0 +1,81 @@
+package compression
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/andybalholm/brotli"
+)
+
+// The codecs of the payloads the store keeps compressed, their id prefixes the payloads.
+const (
+	// CodecIdentity is the codec of the payloads kept as they are, because
+	// compressing doesn't make them smaller.
+	CodecIdentity byte = 0
+	// CodecBrotli is the codec of the brotli compressed payloads.
+	CodecBrotli byte = 1
+)
+
+// CodecNames are the names of the codecs, those of the HTTP content codings.
+var CodecNames = map[byte]string{
+	CodecIdentity: "identity",
+	CodecBrotli:   "br",
+}
+
+var errEmptyPayload = errors.New("encoded payload has no codec")
+
+// EncodePayload prefixes the payload with the id of its codec, brotli compressing it
+// unless that doesn't make it smaller.
+func EncodePayload(payload []byte) ([]byte, error) {
+	compressed, err := BrotliCompress(payload)
+	if err != nil {
+		return nil, err
+	}
+	if len(compressed) < len(payload) {
+		return append([]byte{CodecBrotli}, compressed...), nil
+	}
+	return append([]byte{CodecIdentity}, payload...), nil
+}
+
+// DecodePayload splits an encoded payload into the id of its codec and its bytes.
+func DecodePayload(encoded []byte) (byte, []byte, error) {
+	if len(encoded) == 0 {
+		return 0, nil, errEmptyPayload
+	}
+	codec := encoded[0]
+	if _, ok := CodecNames[codec]; !ok {
+		return 0, nil, fmt.Errorf("unknown payload codec %d", codec)
+	}
+	return codec, encoded[1:], nil
+}
+
+// DecompressPayload returns an encoded payload as it was written.
+func DecompressPayload(encoded []byte) ([]byte, error) {
+	codec, data, err := DecodePayload(encoded)
+	if err != nil {
+		return nil, err
+	}
+	if codec == CodecIdentity {
+		return bytes.Clone(data), nil
+	}
+	payload, err := BrotliDecompress(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress payload: %w", err)
+	}
+	return payload, nil
+}
+
+// NewPayloadReader returns a reader of an encoded payload as it was written, which
+// decompresses it as it's read instead of all at once.
+func NewPayloadReader(encoded []byte) (io.Reader, error) {
+	codec, data, err := DecodePayload(encoded)
+	if err != nil {
+		return nil, err
+	}
+	if codec == CodecIdentity {
+		return bytes.NewReader(data), nil
+	}
+	return brotli.NewReader(bytes.NewReader(data)), nil
+}
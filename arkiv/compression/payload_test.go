@@ -0,0 +1,56 @@
+package compression
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodePayload(t *testing.T) {
+	payload := bytes.Repeat([]byte("hello arkiv "), 100)
+	encoded, err := EncodePayload(payload)
+	require.NoError(t, err)
+	require.Equal(t, CodecBrotli, encoded[0])
+	require.Less(t, len(encoded), len(payload))
+
+	decoded, err := DecompressPayload(encoded)
+	require.NoError(t, err)
+	require.Equal(t, payload, decoded)
+}
+
+func TestEncodePayload_Incompressible(t *testing.T) {
+	for _, payload := range [][]byte{nil, []byte("x")} {
+		encoded, err := EncodePayload(payload)
+		require.NoError(t, err)
+		require.Equal(t, append([]byte{CodecIdentity}, payload...), encoded)
+
+		decoded, err := DecompressPayload(encoded)
+		require.NoError(t, err)
+		require.Equal(t, len(payload), len(decoded))
+	}
+}
+
+func TestDecodePayload_Invalid(t *testing.T) {
+	_, _, err := DecodePayload(nil)
+	require.ErrorIs(t, err, errEmptyPayload)
+
+	_, _, err = DecodePayload([]byte{7, 1, 2})
+	require.ErrorContains(t, err, "unknown payload codec 7")
+}
+
+func TestNewPayloadReader(t *testing.T) {
+	for _, payload := range [][]byte{bytes.Repeat([]byte("hello arkiv "), 100), []byte("x")} {
+		encoded, err := EncodePayload(payload)
+		require.NoError(t, err)
+		r, err := NewPayloadReader(encoded)
+		require.NoError(t, err)
+		decoded, err := io.ReadAll(r)
+		require.NoError(t, err)
+		require.Equal(t, payload, decoded)
+	}
+
+	_, err := NewPayloadReader(nil)
+	require.ErrorIs(t, err, errEmptyPayload)
+}
@@ -1,7 +1,6 @@
 package golembase_test
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -9,9 +8,8 @@ import (
 	"math"
 	"math/big"
 	"os"
-	"os/exec"
-	"path/filepath"
 	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 	"testing"
@@ -20,6 +18,7 @@ import (
 	sqlitestore "github.com/Arkiv-Network/sqlite-bitmap-store"
 	"github.com/cucumber/godog"
 	"github.com/cucumber/godog/colors"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/arkiv/address"
 	"github.com/ethereum/go-ethereum/arkiv/compression"
 	arkivlogs "github.com/ethereum/go-ethereum/arkiv/logs"
@@ -53,33 +52,11 @@ func init() {
 	}
 }
 
-func compileGeth() (string, func(), error) {
-	td, err := os.MkdirTemp("", "arkiv-cucumber-compiled-geth-")
-	if err != nil {
-		panic(fmt.Errorf("failed to create temp dir: %w", err))
-	}
-
-	gethBinaryPath := filepath.Join(td, "geth")
-
-	cmd := exec.Command("go", "build", "-o", gethBinaryPath, "../cmd/geth")
-	out := &bytes.Buffer{}
-	cmd.Stdout = out
-	cmd.Stderr = out
-	err = cmd.Run()
-	if err != nil {
-		return "", func() {}, fmt.Errorf("failed to compile geth: %w\n%s", err, out.String())
-	}
-
-	return gethBinaryPath, func() {
-		os.RemoveAll(td)
-	}, nil
-}
-
 func TestMain(m *testing.M) {
 	pflag.Parse()
 	opts.Paths = pflag.Args()
 
-	gethPath, cleanupCompiled, err := compileGeth()
+	gethPath, cleanupCompiled, err := testutil.CompileGeth("../cmd/geth")
 	if err != nil {
 		log.Fatal(fmt.Errorf("failed to compile geth: %w", err))
 	}
@@ -234,6 +211,35 @@ func InitializeScenario(ctx *godog.ScenarioContext) {
 	ctx.Step(`^I submit a storage transaction with (\d+) operations$`, iSubmitAStorageTransactionWithOperations)
 	ctx.Step(`^last error should mention "([^"]*)"$`, lastErrorShouldMention)
 
+	ctx.Step(`^I submit a storage transaction creating an entity$`, iSubmitAStorageTransactionCreatingAnEntity)
+	ctx.Step(`^I submit a storage transaction creating an entity with gas limit (\d+)$`, iSubmitAStorageTransactionCreatingAnEntityWithGasLimit)
+	ctx.Step(`^I submit a storage transaction creating an entity with fee caps (\d+) and (\d+) gwei$`, iSubmitAStorageTransactionCreatingAnEntityWithFeeCaps)
+	ctx.Step(`^I submit a storage transaction creating an entity signed by the second account$`, iSubmitAStorageTransactionCreatingAnEntitySignedByTheSecondAccount)
+	ctx.Step(`^I submit a storage transaction creating an entity with an invalid codec$`, iSubmitAStorageTransactionCreatingAnEntityWithAnInvalidCodec)
+	ctx.Step(`^the submitted transaction should be mined successfully$`, theSubmittedTransactionShouldBeMinedSuccessfully)
+	ctx.Step(`^the submitted transaction should have gas limit (\d+)$`, theSubmittedTransactionShouldHaveGasLimit)
+	ctx.Step(`^the submitted transaction should have fee caps (\d+) and (\d+) gwei$`, theSubmittedTransactionShouldHaveFeeCaps)
+	ctx.Step(`^the submitted transaction should be sent by the second account$`, theSubmittedTransactionShouldBeSentByTheSecondAccount)
+
+	ctx.Step(`^I remember the entity as "([^"]*)"$`, iRememberTheEntityAs)
+	ctx.Step(`^I remember the current block$`, iRememberTheCurrentBlock)
+	ctx.Step(`^I update the entity "([^"]*)"$`, iUpdateTheNamedEntity)
+	ctx.Step(`^I create an entity remembered as "([^"]*)"$`, iCreateAnEntityRememberedAs)
+	ctx.Step(`^I diff the query "([^"]*)" between the remembered block and the current block$`, iDiffTheQueryBetweenTheRememberedBlockAndTheCurrentBlock)
+	ctx.Step(`^the diff should list "([^"]*)" as (added|removed|changed)$`, theDiffShouldListAs)
+
+	ctx.Step(`^I submit a storage transaction creating an entity encrypted with "([^"]*)"$`, iSubmitAStorageTransactionCreatingAnEntityEncryptedWith)
+	ctx.Step(`^the entity should be returned with encryption scheme "([^"]*)"$`, theEntityShouldBeReturnedWithEncryptionScheme)
+	ctx.Step(`^the entity should be returned without encryption info$`, theEntityShouldBeReturnedWithoutEncryptionInfo)
+
+	ctx.Step(`^I read the Arkiv metrics$`, iReadTheArkivMetrics)
+	ctx.Step(`^the Arkiv metric "([^"]*)" should be reported$`, theArkivMetricShouldBeReported)
+	ctx.Step(`^the Arkiv metric "([^"]*)" should increase$`, theArkivMetricShouldIncrease)
+
+	ctx.Step(`^I create a "([^"]*)" entity of kind "([^"]*)" remembered as "([^"]*)" with payload$`, iCreateAnEntityOfKindRememberedAsWithPayload)
+	ctx.Step(`^I search for entities matching the text "([^"]*)" with the query$`, iSearchForEntitiesMatchingTheTextWithTheQuery)
+	ctx.Step(`^the search results should be "([^"]*)"$`, theSearchResultsShouldBe)
+
 }
 
 func iSearchForEntitiesWithTheInvalidQuery(ctx context.Context, query *godog.DocString) error {
@@ -1593,56 +1599,14 @@ func iSubmitATransactionToUpdateTheEntityByNonowner(ctx context.Context) error {
 func thereAreTwoEntitiesThatWillExpireInTheNextBlock(ctx context.Context) error {
 	w := testutil.GetWorld(ctx)
 
-	receipt1, err := w.CreateEntity(
-		ctx,
-		2,
-		[]byte("test payload"),
-		[]storagetx.StringAnnotation{
-			{
-				Key:   "test_key",
-				Value: "test_value",
-			},
-		},
-		[]storagetx.NumericAnnotation{
-			{
-				Key:   "test_number",
-				Value: 42,
-			},
-		},
-	)
-
-	if err != nil {
-		return fmt.Errorf("failed to create first entity: %w", err)
-	}
-
-	key1 := receipt1.Logs[0].Topics[1]
-	w.CreatedEntityKey = key1
-
-	receipt2, err := w.CreateEntity(
-		ctx,
-		1,
-		[]byte("test payload2"),
-		[]storagetx.StringAnnotation{
-			{
-				Key:   "test_key",
-				Value: "test_value2",
-			},
-		},
-		[]storagetx.NumericAnnotation{
-			{
-				Key:   "test_number",
-				Value: 42,
-			},
-		},
-	)
-
+	owner, keys, err := w.NewOwnerWithEntities(ctx, 2, 1)
 	if err != nil {
-		return fmt.Errorf("failed to create first entity: %w", err)
+		return fmt.Errorf("failed to create entities: %w", err)
 	}
 
-	key2 := receipt2.Logs[0].Topics[1]
-
-	w.SecondCreatedEntityKey = key2
+	w.EntityOwner = owner
+	w.CreatedEntityKey = keys[0]
+	w.SecondCreatedEntityKey = keys[1]
 
 	return nil
 }
@@ -1658,7 +1622,7 @@ func theExpiredEntitiesShouldBeDeleted(ctx context.Context) error {
 		ctx,
 		&arkivEntities,
 		"arkiv_query",
-		fmt.Sprintf(`$owner = %s`, w.FundedAccount.Address),
+		fmt.Sprintf(`$owner = %s`, w.EntityOwner.Address),
 	); err != nil {
 		return fmt.Errorf("failed to get entities of owner: %w", err)
 	}
@@ -2572,7 +2536,7 @@ func iSubmitAStorageTransactionWithOperations(ctx context.Context, count int) er
 		})
 	}
 
-	err := w.SubmitStorageTransaction(
+	_, err := w.SubmitStorageTransaction(
 		ctx,
 		storageTx,
 	)
@@ -2593,3 +2557,510 @@ func lastErrorShouldMention(ctx context.Context, mention string) error {
 	}
 	return nil
 }
+
+func iRememberTheEntityAs(ctx context.Context, name string) error {
+	w := testutil.GetWorld(ctx)
+	w.NamedEntityKeys[name] = w.CreatedEntityKey
+	return nil
+}
+
+func iRememberTheCurrentBlock(ctx context.Context) error {
+	w := testutil.GetWorld(ctx)
+
+	block, err := w.GethInstance.ETHClient.BlockNumber(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get block number: %w", err)
+	}
+
+	w.RememberedBlock = block
+
+	return nil
+}
+
+func iUpdateTheNamedEntity(ctx context.Context, name string) error {
+	w := testutil.GetWorld(ctx)
+
+	key, ok := w.NamedEntityKeys[name]
+	if !ok {
+		return fmt.Errorf("unknown entity %q", name)
+	}
+
+	_, err := w.UpdateEntity(
+		ctx,
+		key,
+		100,
+		[]byte("updated payload"),
+		[]storagetx.StringAnnotation{
+			{
+				Key:   "updated_key",
+				Value: "updated_value",
+			},
+		},
+		[]storagetx.NumericAnnotation{},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update entity: %w", err)
+	}
+
+	return nil
+}
+
+func iCreateAnEntityRememberedAs(ctx context.Context, name string) error {
+	err := iHaveCreatedAnEntity(ctx)
+	if err != nil {
+		return err
+	}
+
+	return iRememberTheEntityAs(ctx, name)
+}
+
+type queryDiff struct {
+	Added     []common.Hash `json:"added"`
+	Removed   []common.Hash `json:"removed"`
+	Changed   []common.Hash `json:"changed"`
+	Truncated bool          `json:"truncated"`
+}
+
+func iDiffTheQueryBetweenTheRememberedBlockAndTheCurrentBlock(ctx context.Context, query string) error {
+	w := testutil.GetWorld(ctx)
+
+	block, err := w.GethInstance.ETHClient.BlockNumber(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get block number: %w", err)
+	}
+
+	err = w.GethInstance.RPCClient.CallContext(
+		ctx,
+		&w.LastQueryDiff,
+		"arkiv_queryDiff",
+		query,
+		w.RememberedBlock,
+		block,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to diff query: %w", err)
+	}
+
+	return nil
+}
+
+func theDiffShouldListAs(ctx context.Context, name, kind string) error {
+	w := testutil.GetWorld(ctx)
+
+	key, ok := w.NamedEntityKeys[name]
+	if !ok {
+		return fmt.Errorf("unknown entity %q", name)
+	}
+
+	diff := queryDiff{}
+	err := json.Unmarshal(w.LastQueryDiff, &diff)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal query diff: %w", err)
+	}
+
+	keys := map[string][]common.Hash{
+		"added":   diff.Added,
+		"removed": diff.Removed,
+		"changed": diff.Changed,
+	}[kind]
+
+	if !slices.Contains(keys, key) {
+		return fmt.Errorf("expected %s (%s) to be %s, got %s", name, key.Hex(), kind, string(w.LastQueryDiff))
+	}
+
+	return nil
+}
+
+func createEntityStorageTransaction() *storagetx.ArkivTransaction {
+	return &storagetx.ArkivTransaction{
+		Create: []storagetx.ArkivCreate{
+			{
+				BTL:         100,
+				ContentType: "application/octet-stream",
+				Payload:     []byte("test payload"),
+				StringAnnotations: []storagetx.StringAnnotation{
+					{
+						Key:   "test_key",
+						Value: "test_value",
+					},
+				},
+			},
+		},
+	}
+}
+
+func submitCreateEntityStorageTransaction(ctx context.Context, opts ...testutil.SubmitOption) error {
+	w := testutil.GetWorld(ctx)
+
+	txHash, err := w.SubmitStorageTransaction(ctx, createEntityStorageTransaction(), opts...)
+	w.LastSubmittedTx = txHash
+	w.LastError = err
+
+	return nil
+}
+
+func iSubmitAStorageTransactionCreatingAnEntity(ctx context.Context) error {
+	return submitCreateEntityStorageTransaction(ctx)
+}
+
+func iSubmitAStorageTransactionCreatingAnEntityWithGasLimit(ctx context.Context, gas int) error {
+	return submitCreateEntityStorageTransaction(ctx, testutil.WithGasLimit(uint64(gas)))
+}
+
+func iSubmitAStorageTransactionCreatingAnEntityWithFeeCaps(ctx context.Context, tip, fee int) error {
+	return submitCreateEntityStorageTransaction(
+		ctx,
+		testutil.WithFeeCaps(
+			new(big.Int).Mul(big.NewInt(int64(tip)), big.NewInt(1e9)),
+			new(big.Int).Mul(big.NewInt(int64(fee)), big.NewInt(1e9)),
+		),
+	)
+}
+
+func iSubmitAStorageTransactionCreatingAnEntitySignedByTheSecondAccount(ctx context.Context) error {
+	w := testutil.GetWorld(ctx)
+	return submitCreateEntityStorageTransaction(ctx, testutil.WithSigner(w.SecondFundedAccount))
+}
+
+func iSubmitAStorageTransactionCreatingAnEntityWithAnInvalidCodec(ctx context.Context) error {
+	return submitCreateEntityStorageTransaction(
+		ctx,
+		testutil.WithCodec(func(data []byte) ([]byte, error) {
+			return data, nil
+		}),
+	)
+}
+
+func theSubmittedTransactionShouldBeMinedSuccessfully(ctx context.Context) error {
+	w := testutil.GetWorld(ctx)
+
+	if w.LastError != nil {
+		return fmt.Errorf("failed to submit transaction: %w", w.LastError)
+	}
+
+	receipt, err := bind.WaitMinedHash(ctx, w.GethInstance.ETHClient, w.LastSubmittedTx)
+	if err != nil {
+		return fmt.Errorf("failed to wait for transaction: %w", err)
+	}
+
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return fmt.Errorf("transaction failed")
+	}
+
+	w.LastReceipt = receipt
+
+	return nil
+}
+
+func submittedTransaction(ctx context.Context) (*types.Transaction, error) {
+	w := testutil.GetWorld(ctx)
+
+	tx, _, err := w.GethInstance.ETHClient.TransactionByHash(ctx, w.LastSubmittedTx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction: %w", err)
+	}
+
+	return tx, nil
+}
+
+func theSubmittedTransactionShouldHaveGasLimit(ctx context.Context, gas int) error {
+	tx, err := submittedTransaction(ctx)
+	if err != nil {
+		return err
+	}
+
+	if tx.Gas() != uint64(gas) {
+		return fmt.Errorf("expected gas limit %d, got %d", gas, tx.Gas())
+	}
+
+	return nil
+}
+
+func theSubmittedTransactionShouldHaveFeeCaps(ctx context.Context, tip, fee int) error {
+	tx, err := submittedTransaction(ctx)
+	if err != nil {
+		return err
+	}
+
+	expectedTip := new(big.Int).Mul(big.NewInt(int64(tip)), big.NewInt(1e9))
+	expectedFee := new(big.Int).Mul(big.NewInt(int64(fee)), big.NewInt(1e9))
+
+	if tx.GasTipCap().Cmp(expectedTip) != 0 || tx.GasFeeCap().Cmp(expectedFee) != 0 {
+		return fmt.Errorf("expected fee caps %s/%s, got %s/%s", expectedTip, expectedFee, tx.GasTipCap(), tx.GasFeeCap())
+	}
+
+	return nil
+}
+
+func theSubmittedTransactionShouldBeSentByTheSecondAccount(ctx context.Context) error {
+	w := testutil.GetWorld(ctx)
+
+	tx, err := submittedTransaction(ctx)
+	if err != nil {
+		return err
+	}
+
+	from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return fmt.Errorf("failed to get sender: %w", err)
+	}
+
+	if from != w.SecondFundedAccount.Address {
+		return fmt.Errorf("expected sender %s, got %s", w.SecondFundedAccount.Address.Hex(), from.Hex())
+	}
+
+	return nil
+}
+
+func iReadTheArkivMetrics(ctx context.Context) error {
+	w := testutil.GetWorld(ctx)
+
+	values, err := w.GethInstance.Metrics(ctx)
+	if err != nil {
+		return err
+	}
+
+	w.LastMetrics = values
+
+	return nil
+}
+
+func theArkivMetricShouldBeReported(ctx context.Context, name string) error {
+	w := testutil.GetWorld(ctx)
+
+	for {
+		values, err := w.GethInstance.Metrics(ctx)
+		if err != nil {
+			return err
+		}
+
+		if _, ok := values[name]; ok {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("metric %s was not reported", name)
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+}
+
+func theArkivMetricShouldIncrease(ctx context.Context, name string) error {
+	w := testutil.GetWorld(ctx)
+
+	previous := w.LastMetrics[name]
+
+	// size and row counts are collected on a timer, so wait for the next collection
+	for {
+		values, err := w.GethInstance.Metrics(ctx)
+		if err != nil {
+			return err
+		}
+
+		if values[name] > previous {
+			w.LastMetrics = values
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("metric %s did not increase from %v, got %v", name, previous, values[name])
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+}
+
+func iSubmitAStorageTransactionCreatingAnEntityEncryptedWith(ctx context.Context, scheme string) error {
+	w := testutil.GetWorld(ctx)
+
+	storageTx := createEntityStorageTransaction()
+	storageTx.Version = storagetx.TransactionVersionEncryption
+	storageTx.Create[0].Encryption = &storagetx.EncryptionInfo{
+		Scheme: scheme,
+		KeyID:  "key-1",
+		Nonce:  []byte{1, 2, 3},
+	}
+
+	txHash, err := w.SubmitStorageTransaction(ctx, storageTx)
+	w.LastSubmittedTx = txHash
+	w.LastError = err
+
+	return nil
+}
+
+// createdEntityAttributes queries the entity created by the last receipt and returns its string attributes
+func createdEntityAttributes(ctx context.Context) (map[string]string, error) {
+	w := testutil.GetWorld(ctx)
+
+	if w.LastReceipt == nil || len(w.LastReceipt.Logs) == 0 {
+		return nil, fmt.Errorf("no logs found in receipt")
+	}
+
+	key := w.LastReceipt.Logs[0].Topics[1]
+
+	res := sqlitestore.QueryResponse{}
+	err := w.GethInstance.RPCClient.CallContext(
+		ctx,
+		&res,
+		"arkiv_query",
+		fmt.Sprintf("$key = %s", key.Hex()),
+		sqlitestore.Options{
+			IncludeData: &sqlitestore.IncludeData{
+				Key:                 true,
+				Attributes:          true,
+				SyntheticAttributes: true,
+			},
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query entity: %w", err)
+	}
+
+	if len(res.Data) != 1 {
+		return nil, fmt.Errorf("expected 1 entity, got %d", len(res.Data))
+	}
+
+	ed := sqlitestore.EntityData{}
+	err = json.Unmarshal(res.Data[0], &ed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal entity data: %w", err)
+	}
+
+	return ed.StringAttributes, nil
+}
+
+func theEntityShouldBeReturnedWithEncryptionScheme(ctx context.Context, scheme string) error {
+	attributes, err := createdEntityAttributes(ctx)
+	if err != nil {
+		return err
+	}
+
+	expected := map[string]string{
+		storagetx.EncryptionSchemeAttribute: scheme,
+		storagetx.EncryptionKeyIDAttribute:  "key-1",
+		storagetx.EncryptionNonceAttribute:  "0x010203",
+	}
+
+	for k, v := range expected {
+		if attributes[k] != v {
+			return fmt.Errorf("expected attribute %s to be %q, got %q", k, v, attributes[k])
+		}
+	}
+
+	return nil
+}
+
+func theEntityShouldBeReturnedWithoutEncryptionInfo(ctx context.Context) error {
+	attributes, err := createdEntityAttributes(ctx)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := attributes[storagetx.EncryptionSchemeAttribute]; ok {
+		return fmt.Errorf("expected no encryption info, got scheme %q", attributes[storagetx.EncryptionSchemeAttribute])
+	}
+
+	return nil
+}
+
+func iCreateAnEntityOfKindRememberedAsWithPayload(ctx context.Context, contentType, kind, name string, payload *godog.DocString) error {
+	w := testutil.GetWorld(ctx)
+
+	storageTx := &storagetx.ArkivTransaction{
+		Create: []storagetx.ArkivCreate{
+			{
+				BTL:         100,
+				ContentType: contentType,
+				Payload:     []byte(payload.Content),
+				StringAnnotations: []storagetx.StringAnnotation{
+					{
+						Key:   "kind",
+						Value: kind,
+					},
+				},
+			},
+		},
+	}
+
+	txHash, err := w.SubmitStorageTransaction(ctx, storageTx)
+	if err != nil {
+		return fmt.Errorf("failed to submit transaction: %w", err)
+	}
+
+	receipt, err := bind.WaitMinedHash(ctx, w.GethInstance.ETHClient, txHash)
+	if err != nil {
+		return fmt.Errorf("failed to wait for transaction: %w", err)
+	}
+
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return fmt.Errorf("transaction failed")
+	}
+
+	w.LastReceipt = receipt
+	w.CreatedEntityKey = receipt.Logs[0].Topics[1]
+
+	return iRememberTheEntityAs(ctx, name)
+}
+
+func iSearchForEntitiesMatchingTheTextWithTheQuery(ctx context.Context, text string, queryDoc *godog.DocString) error {
+	w := testutil.GetWorld(ctx)
+
+	res := sqlitestore.QueryResponse{}
+	err := w.GethInstance.RPCClient.CallContext(
+		ctx,
+		&res,
+		"arkiv_query",
+		queryDoc.Content,
+		map[string]any{
+			"text": text,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to query entities: %w", err)
+	}
+
+	edList := []sqlitestore.EntityData{}
+	for _, d := range res.Data {
+		ed := sqlitestore.EntityData{}
+		err = json.Unmarshal(d, &ed)
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal entity data: %w", err)
+		}
+		edList = append(edList, ed)
+	}
+
+	w.ArkivSearchResult = edList
+
+	return nil
+}
+
+func theSearchResultsShouldBe(ctx context.Context, names string) error {
+	w := testutil.GetWorld(ctx)
+
+	expected := []common.Hash{}
+	for _, name := range strings.Split(names, ",") {
+		key, ok := w.NamedEntityKeys[strings.TrimSpace(name)]
+		if !ok {
+			return fmt.Errorf("unknown entity %q", name)
+		}
+		expected = append(expected, key)
+	}
+
+	found := []common.Hash{}
+	for _, ed := range w.ArkivSearchResult {
+		if ed.Key == nil {
+			return fmt.Errorf("search result without key")
+		}
+		found = append(found, *ed.Key)
+	}
+
+	slices.SortFunc(expected, func(a, b common.Hash) int { return a.Cmp(b) })
+	slices.SortFunc(found, func(a, b common.Hash) int { return a.Cmp(b) })
+
+	if !slices.Equal(expected, found) {
+		return fmt.Errorf("expected %v, found %v", expected, found)
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package dbevents
+
+import (
+	"iter"
+
+	arkivevents "github.com/Arkiv-Network/arkiv-events"
+	"github.com/Arkiv-Network/arkiv-events/events"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Block is the Arkiv events of a block with what events.Block can't carry: the hash
+// of the block and of its parent, so that the consumers can detect the reorgs, its
+// time, the hashes of its transactions, and the summary of its operations.
+type Block struct {
+	events.Block
+
+	Hash       common.Hash
+	ParentHash common.Hash
+	Time       uint64
+	// TxHashes are the hashes of the transactions of the block, by TxIndex.
+	TxHashes []common.Hash
+	Summary  BlockSummary
+}
+
+// newBlock returns the events of the block decoded by blockToEvents with the data of
+// the block and the summary of its operations.
+func newBlock(block *types.Block, decoded *events.Block, summary BlockSummary) Block {
+	transactions := block.Transactions()
+	txHashes := make([]common.Hash, len(transactions))
+	for i, tx := range transactions {
+		txHashes[i] = tx.Hash()
+	}
+	return Block{
+		Block:      *decoded,
+		Hash:       block.Hash(),
+		ParentHash: block.ParentHash(),
+		Time:       block.Time(),
+		TxHashes:   txHashes,
+		Summary:    summary,
+	}
+}
+
+// TxHash returns the hash of the transaction of the operation of the block.
+func (b *Block) TxHash(operation events.Operation) common.Hash {
+	if operation.TxIndex >= uint64(len(b.TxHashes)) {
+		return common.Hash{}
+	}
+	return b.TxHashes[operation.TxIndex]
+}
+
+// BlockBatch is a batch of blocks of the events of the chain.
+type BlockBatch struct {
+	Blocks []Block
+}
+
+// BatchOrError is a batch of blocks, or the error the iterator stopped at.
+type BatchOrError struct {
+	Batch BlockBatch
+	Error error
+}
+
+// BatchIterator iterates over the batches of the events of the chain with the data of
+// their blocks, see NewChainBatchIterator.
+type BatchIterator iter.Seq[BatchOrError]
+
+// Events returns the iterator over the events of the batches without the data of their
+// blocks, the form the store ingests.
+func (it BatchIterator) Events() arkivevents.BatchIterator {
+	return func(yield func(arkivevents.BatchOrError) bool) {
+		for batch := range it {
+			result := arkivevents.BatchOrError{Error: batch.Error}
+			for _, block := range batch.Batch.Blocks {
+				result.Batch.Blocks = append(result.Batch.Blocks, block.Block)
+			}
+			if !yield(result) {
+				return
+			}
+		}
+	}
+}
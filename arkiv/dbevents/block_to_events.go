@@ -1,17 +1,34 @@
 package dbevents
 
 import (
+	"errors"
 	"fmt"
+	"maps"
+	"math/big"
 
 	"github.com/Arkiv-Network/arkiv-events/events"
 	"github.com/ethereum/go-ethereum/arkiv/address"
 	"github.com/ethereum/go-ethereum/arkiv/logs"
 	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
 )
 
-func blockToEvents(rawBlock *types.Block, rawReceipts []*types.Receipt) (*events.Block, error) {
+// ErrCreatedEntityLogs is the error of the receipts whose ArkivEntityCreated and
+// ArkivCreateDeduplicated logs don't match the create operations of their transaction,
+// one log per create in order.
+var ErrCreatedEntityLogs = errors.New("created entity logs don't match the create operations")
+
+// blockToEvents returns the events of the Arkiv operations of the block, and the
+// operations of its successful transactions it couldn't map to events.
+//
+// The operations of a transaction are returned in the order the processor applies
+// them, see canonicalOpIndexes, and OpIndex is the position of the operation in that
+// order. The expirations have the index of their log in the receipt of their
+// transaction instead.
+func blockToEvents(rawBlock *types.Block, rawReceipts []*types.Receipt) (*events.Block, []UnknownOperations, error) {
 
 	bl := &events.Block{
 		Number:     rawBlock.NumberU64(),
@@ -19,27 +36,38 @@ func blockToEvents(rawBlock *types.Block, rawReceipts []*types.Receipt) (*events
 	}
 
 	if len(rawReceipts) == 0 {
-		return bl, nil
+		return bl, nil, nil
 	}
 
-	firstReceipt := rawReceipts[0]
-
-	for opIndex, log := range firstReceipt.Logs {
-		if len(log.Topics) == 0 {
+	var unknown []UnknownOperations
+	for i, receipt := range rawReceipts {
+		if receipt.Status != types.ReceiptStatusSuccessful {
 			continue
 		}
-		if log.Topics[0] == logs.ArkivEntityExpired && len(log.Data) >= 32 {
-			entityKey := common.BytesToHash(log.Data[:32])
-			expire := events.OPExpire(entityKey.Bytes())
-			bl.Operations = append(bl.Operations, events.Operation{
-				TxIndex: 0,
-				OpIndex: uint64(opIndex),
-				Expire:  &expire,
-			})
+		kinds := map[string]uint64{}
+		unknownLogs(receipt, kinds)
+
+		transaction := rawBlock.Transactions()[i]
+		if to := transaction.To(); to != nil && *to == address.ArkivProcessorAddress {
+			if _, err := storagetx.UnpackArkivTransaction(transaction.Data()); err != nil {
+				// Skip the transactions this version can't decode, such as the
+				// transactions of a newer version, rather than stalling the pipeline
+				maps.Copy(kinds, undecodableOperations(transaction.Data()))
+			}
+		}
+		if len(kinds) > 0 {
+			unknown = append(unknown, UnknownOperations{TxIndex: uint64(i), TxHash: transaction.Hash(), Kinds: kinds})
 		}
 	}
 
 	for i, transaction := range rawBlock.Transactions() {
+		receipt := rawReceipts[i]
+
+		// The expirations are taken from the logs of whichever transaction ran the
+		// housekeeping, they are attributed to the enclosing block, the block number
+		// field of the housekeeping logs is not trusted.
+		bl.Operations = append(bl.Operations, expirations(uint64(i), receipt)...)
+
 		transactionTo := transaction.To()
 		if transactionTo == nil {
 			continue
@@ -49,123 +77,309 @@ func blockToEvents(rawBlock *types.Block, rawReceipts []*types.Receipt) (*events
 			continue
 		}
 
-		receipt := rawReceipts[i]
-
 		if receipt.Status != types.ReceiptStatusSuccessful {
 			continue
 		}
 
 		atx, err := storagetx.UnpackArkivTransaction(transaction.Data())
 		if err != nil {
-			return nil, fmt.Errorf("failed to unpack arkiv transaction: %w", err)
+			// Counted as unknown operations
+			continue
 		}
 
 		signer := types.LatestSignerForChainID(transaction.ChainId())
 		from, err := signer.Sender(transaction)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get sender from transaction: %w", err)
+			return nil, nil, fmt.Errorf("failed to get sender from transaction: %w", err)
 		}
 
-		createdEntities := createdEntities(receipt)
+		createdEntities, err := createdEntities(receipt)
+		if err != nil {
+			return nil, nil, fmt.Errorf("transaction %s: %w", transaction.Hash(), err)
+		}
+		if len(createdEntities) > len(atx.Create) {
+			return nil, nil, fmt.Errorf("transaction %s: %w: %d logs for %d creates", transaction.Hash(), ErrCreatedEntityLogs, len(createdEntities), len(atx.Create))
+		}
+		opIndexes := canonicalOpIndexes(atx)
 
 		for opIndex, create := range atx.Create {
-			createdEntityKey := createdEntities[0]
-			createdEntities = createdEntities[1:]
+			if opIndex >= len(createdEntities) {
+				return nil, nil, fmt.Errorf("transaction %s: operation %d: %w: no log for the create", transaction.Hash(), opIndexes.create+uint64(opIndex), ErrCreatedEntityLogs)
+			}
+			if createdEntities[opIndex].deduplicated {
+				// The entity was created by an earlier transaction
+				continue
+			}
+			createdEntityKey := createdEntities[opIndex].key
 
 			bl.Operations = append(bl.Operations, events.Operation{
 				TxIndex: uint64(i),
-				OpIndex: uint64(opIndex),
+				OpIndex: opIndexes.create + uint64(opIndex),
 				Create: &events.OPCreate{
 					Key:               createdEntityKey,
 					ContentType:       create.ContentType,
 					BTL:               create.BTL,
 					Owner:             from,
 					Content:           create.Payload,
-					StringAttributes:  stringAnnotationsToMap(create.StringAnnotations),
+					StringAttributes:  withExternalContent(stringAnnotationsToMap(create.StringAnnotations, create.NumericAnnotations, create.Encryption), atx.ExternalContentOf(false, opIndex)),
 					NumericAttributes: numericAnnotationsToMap(create.NumericAnnotations),
 				},
 			})
 		}
 
+		for opIndex, delete := range atx.Delete {
+			event := events.OPDelete(delete)
+
+			bl.Operations = append(bl.Operations, events.Operation{
+				TxIndex: uint64(i),
+				OpIndex: opIndexes.delete + uint64(opIndex),
+				Delete:  &event,
+			})
+		}
+
 		for opIndex, update := range atx.Update {
 
 			bl.Operations = append(bl.Operations, events.Operation{
 				TxIndex: uint64(i),
-				OpIndex: uint64(opIndex),
+				OpIndex: opIndexes.update + uint64(opIndex),
 				Update: &events.OPUpdate{
 					Key:               update.EntityKey,
 					ContentType:       update.ContentType,
 					BTL:               update.BTL,
 					Owner:             from,
 					Content:           update.Payload,
-					StringAttributes:  stringAnnotationsToMap(update.StringAnnotations),
+					StringAttributes:  withExternalContent(stringAnnotationsToMap(update.StringAnnotations, update.NumericAnnotations, update.Encryption), atx.ExternalContentOf(true, opIndex)),
 					NumericAttributes: numericAnnotationsToMap(update.NumericAnnotations),
 				},
 			})
 		}
 
-		for opIndex, extendBTL := range atx.Extend {
+		// The extensions are taken from the logs, their new expiry depends on the
+		// state.
+		for opIndex, extend := range expiryChanges(bl.Number, receipt, logs.ArkivEntityBTLExtended) {
 
 			bl.Operations = append(bl.Operations, events.Operation{
-				TxIndex: uint64(i),
-				OpIndex: uint64(opIndex),
-				ExtendBTL: &events.OPExtendBTL{
-					Key: extendBTL.EntityKey,
-					BTL: extendBTL.NumberOfBlocks,
-				},
+				TxIndex:   uint64(i),
+				OpIndex:   opIndexes.extend + uint64(opIndex),
+				ExtendBTL: extend,
 			})
 
 		}
-		for opIndex, changeOwner := range atx.ChangeOwner {
+		// Ownership changes are taken from the logs, a transfer pending acceptance
+		// doesn't change the owner while an acceptance does.
+		for _, change := range ownerChanges(receipt) {
 
 			bl.Operations = append(bl.Operations, events.Operation{
-				TxIndex: uint64(i),
-				OpIndex: uint64(opIndex),
-				ChangeOwner: &events.OPChangeOwner{
-					Key:   changeOwner.EntityKey,
-					Owner: changeOwner.NewOwner,
-				},
+				TxIndex:     uint64(i),
+				OpIndex:     opIndexes.changeOwner + change.index,
+				ChangeOwner: change.change,
 			})
 
 		}
-		for opIndex, delete := range atx.Delete {
-			event := events.OPDelete(delete)
+		// So are the BTL reductions, an extension event setting an earlier expiry.
+		for opIndex, reduce := range expiryChanges(bl.Number, receipt, logs.ArkivEntityBTLReduced) {
 
 			bl.Operations = append(bl.Operations, events.Operation{
-				TxIndex: uint64(i),
+				TxIndex:   uint64(i),
+				OpIndex:   opIndexes.reduceBTL + uint64(opIndex),
+				ExtendBTL: reduce,
+			})
+
+		}
+
+	}
+
+	return bl, unknown, nil
+}
+
+// expirations returns the expirations of the entities logged in the receipt of the
+// transaction at txIndex, numbered after their log.
+func expirations(txIndex uint64, r *types.Receipt) []events.Operation {
+	operations := []events.Operation{}
+	for opIndex, log := range r.Logs {
+		if log.Address != address.ArkivProcessorAddress || len(log.Topics) == 0 {
+			continue
+		}
+		if log.Topics[0] == logs.ArkivEntityExpired && len(log.Data) >= 32 {
+			expire := events.OPExpire(common.BytesToHash(log.Data[:32]).Bytes())
+			operations = append(operations, events.Operation{
+				TxIndex: txIndex,
 				OpIndex: uint64(opIndex),
-				Delete:  &event,
+				Expire:  &expire,
 			})
 		}
+	}
+	return operations
+}
+
+// createdEntity is the outcome of a create logged in a receipt: the key of the entity
+// it created, or of the entity created earlier with the same idempotency key if it was
+// deduplicated.
+type createdEntity struct {
+	key          common.Hash
+	deduplicated bool
+}
+
+// createdEntities returns the outcomes of the creates of the receipt, in the order of
+// their logs. The logs of other contracts and the anonymous logs are ignored.
+func createdEntities(r *types.Receipt) ([]createdEntity, error) {
+	entities := []createdEntity{}
+	for _, log := range r.Logs {
+		if log.Address != address.ArkivProcessorAddress || len(log.Topics) == 0 {
+			continue
+		}
+		if log.Topics[0] != logs.ArkivEntityCreated && log.Topics[0] != logs.ArkivCreateDeduplicated {
+			continue
+		}
+		if len(log.Topics) < 2 {
+			return nil, fmt.Errorf("log %d: %w: the log has no entity key", log.Index, ErrCreatedEntityLogs)
+		}
+		entities = append(entities, createdEntity{key: log.Topics[1], deduplicated: log.Topics[0] == logs.ArkivCreateDeduplicated})
+	}
+	return entities, nil
+}
+
+// opIndexes are the indexes of the first operation of every kind of a transaction.
+type opIndexes struct {
+	create, delete, update, extend, changeOwner, acceptOwnership, reduceBTL uint64
+}
+
+// canonicalOpIndexes returns where the operations of every kind of the transaction
+// start in its canonical order. The calldata groups the operations by kind, the
+// canonical order is the order the processor applies them: the creates, the deletes,
+// the updates, the extends, the ownership changes, the entities of the batched
+// ownership changes, the acceptances, the alias operations and the BTL reductions,
+// every kind in the order of the calldata. OpIndex is the position of an operation in
+// that order whether it's returned as an event or not, such as a proposed transfer, so
+// it's stable across the versions of the decoder.
+func canonicalOpIndexes(atx *storagetx.ArkivTransaction) opIndexes {
+	var indexes opIndexes
+	indexes.delete = indexes.create + uint64(len(atx.Create))
+	indexes.update = indexes.delete + uint64(len(atx.Delete))
+	indexes.extend = indexes.update + uint64(len(atx.Update))
+	indexes.changeOwner = indexes.extend + uint64(len(atx.Extend))
+	indexes.acceptOwnership = indexes.changeOwner + uint64(len(atx.ChangeOwner)+batchedOwnerChanges(atx))
+	indexes.reduceBTL = indexes.acceptOwnership + uint64(len(atx.AcceptOwnership)+len(atx.RegisterAlias)+len(atx.TransferAlias))
+	return indexes
+}
 
+// batchedOwnerChanges returns the number of entities of the batched ownership changes of
+// the transaction, each of them is an operation of the canonical order.
+func batchedOwnerChanges(atx *storagetx.ArkivTransaction) int {
+	n := 0
+	for _, batch := range atx.ChangeOwnerBatch {
+		n += len(batch.EntityKeys)
 	}
+	return n
+}
+
+// ownerChange is an ownership change logged in a receipt, with the position of its
+// operation among the ownership changes and the acceptances of the transaction, and the
+// owner of the entity before the change.
+type ownerChange struct {
+	index    uint64
+	change   *events.OPChangeOwner
+	previous common.Address
+}
 
-	return bl, nil
+// ownerChanges returns the ownership changes of the entities logged in the receipt, in
+// the order they were applied. Every ownership change and every entity of a batched
+// one logs a proposed transfer or a change of owner, and every acceptance an accepted
+// transfer and a change of owner.
+func ownerChanges(r *types.Receipt) []ownerChange {
+	changes := []ownerChange{}
+	index := uint64(0)
+	for _, log := range r.Logs {
+		if len(log.Topics) < 4 {
+			continue
+		}
+		switch log.Topics[0] {
+		case logs.ArkivEntityOwnershipTransferProposed:
+			index++
+		case logs.ArkivEntityOwnerChanged:
+			changes = append(changes, ownerChange{
+				index: index,
+				change: &events.OPChangeOwner{
+					Key:   log.Topics[1],
+					Owner: common.BytesToAddress(log.Topics[3].Bytes()),
+				},
+				previous: common.BytesToAddress(log.Topics[2].Bytes()),
+			})
+			index++
+		}
+	}
+	return changes
 }
 
-func createdEntities(r *types.Receipt) []common.Hash {
-	entities := []common.Hash{}
+// expiryChanges returns the changes of expiry of the entities logged with the topic in
+// the receipt of a transaction of the block, in the order they were applied. The BTL
+// of an extension event is counted from the block whatever moved the expiry, it's
+// the new expiry from the log minus the block number.
+func expiryChanges(blockNumber uint64, r *types.Receipt, topic common.Hash) []*events.OPExtendBTL {
+	changes := []*events.OPExtendBTL{}
 	for _, log := range r.Logs {
-		if log.Topics[0] == logs.ArkivEntityCreated {
-			entityKey := log.Topics[1]
-			entities = append(entities, entityKey)
+		if log.Address != address.ArkivProcessorAddress || len(log.Topics) < 2 || log.Topics[0] != topic || len(log.Data) < 64 {
+			continue
 		}
+		expiresAtBlock := new(big.Int).SetBytes(log.Data[32:64]).Uint64()
+		changes = append(changes, &events.OPExtendBTL{
+			Key: log.Topics[1],
+			BTL: expiresAtBlock - blockNumber,
+		})
 	}
-	return entities
+	return changes
 }
 
-func stringAnnotationsToMap(annotations []storagetx.StringAnnotation) map[string]string {
+// stringAnnotationsToMap returns the string attributes of an entity, including the synthetic
+// attributes carrying the type of its typed numeric annotations and its encryption metadata.
+func stringAnnotationsToMap(
+	annotations []storagetx.StringAnnotation,
+	numericAnnotations []storagetx.NumericAnnotation,
+	encryption *storagetx.EncryptionInfo,
+) map[string]string {
 	annotationsMap := make(map[string]string)
 	for _, annotation := range annotations {
 		annotationsMap[annotation.Key] = annotation.Value
+		addAnnotationKeyPrefixes(annotationsMap, annotation.Key)
+	}
+	for _, annotation := range numericAnnotations {
+		addAnnotationKeyPrefixes(annotationsMap, annotation.Key)
+		if annotation.Type == storagetx.NumericTypeUint64 {
+			continue
+		}
+		annotationsMap[storagetx.NumericTypeAttributePrefix+annotation.Key] = annotation.TypeName()
+	}
+	if encryption != nil {
+		annotationsMap[storagetx.EncryptionSchemeAttribute] = encryption.Scheme
+		annotationsMap[storagetx.EncryptionKeyIDAttribute] = encryption.KeyID
+		annotationsMap[storagetx.EncryptionNonceAttribute] = hexutil.Encode(encryption.Nonce)
 	}
 	return annotationsMap
 }
 
+// withExternalContent adds the synthetic attributes of the external content of an
+// operation to its string attributes, the payload is fetched later by
+// ResolveExternalContent.
+func withExternalContent(annotationsMap map[string]string, content *storagetx.ArkivExternalContent) map[string]string {
+	if content != nil {
+		maps.Copy(annotationsMap, content.Attributes())
+	}
+	return annotationsMap
+}
+
+// addAnnotationKeyPrefixes indexes the prefixes of a hierarchical annotation key.
+func addAnnotationKeyPrefixes(annotationsMap map[string]string, key string) {
+	for _, prefix := range entity.AnnotationKeyPrefixes(key) {
+		annotationsMap[storagetx.AnnotationKeyPrefixAttributePrefix+prefix] = storagetx.AnnotationKeyPrefixAttributeValue
+	}
+}
+
+// numericAnnotationsToMap returns the numeric attributes of an entity, indexed so that
+// signed and fixed-point values order properly in range queries.
 func numericAnnotationsToMap(annotations []storagetx.NumericAnnotation) map[string]uint64 {
 	annotationsMap := make(map[string]uint64)
 	for _, annotation := range annotations {
-		annotationsMap[annotation.Key] = annotation.Value
+		annotationsMap[annotation.Key] = annotation.IndexValue()
 	}
 	return annotationsMap
 }
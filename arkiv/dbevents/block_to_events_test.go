@@ -0,0 +1,457 @@
+package dbevents
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/Arkiv-Network/arkiv-events/events"
+	"github.com/ethereum/go-ethereum/arkiv/address"
+	"github.com/ethereum/go-ethereum/arkiv/logs"
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func ownershipLog(topic common.Hash, key common.Hash, oldOwner, newOwner common.Address) *types.Log {
+	return &types.Log{
+		Address: address.ArkivProcessorAddress,
+		Topics:  []common.Hash{topic, key, common.BytesToHash(oldOwner[:]), common.BytesToHash(newOwner[:])},
+	}
+}
+
+func expiryLog(topic common.Hash, key common.Hash, owner common.Address, oldExpiry, newExpiry uint64) *types.Log {
+	data := make([]byte, 64)
+	new(big.Int).SetUint64(oldExpiry).FillBytes(data[:32])
+	new(big.Int).SetUint64(newExpiry).FillBytes(data[32:])
+	return &types.Log{
+		Address: address.ArkivProcessorAddress,
+		Topics:  []common.Hash{topic, key, common.BytesToHash(owner[:])},
+		Data:    data,
+	}
+}
+
+func TestOwnerChanges(t *testing.T) {
+	proposed := common.HexToHash("0x1")
+	accepted := common.HexToHash("0x2")
+	immediate := common.HexToHash("0x3")
+	owner, newOwner := common.HexToAddress("0xa"), common.HexToAddress("0xb")
+
+	receipt := &types.Receipt{Logs: []*types.Log{
+		ownershipLog(logs.ArkivEntityOwnershipTransferProposed, proposed, owner, newOwner),
+		ownershipLog(logs.ArkivEntityOwnerChanged, immediate, owner, newOwner),
+		ownershipLog(logs.ArkivEntityOwnershipTransferAccepted, accepted, owner, newOwner),
+		ownershipLog(logs.ArkivEntityOwnerChanged, accepted, owner, newOwner),
+	}}
+
+	// Only the transfers taking effect change the owner, the proposed one keeps its
+	// position among the ownership operations
+	require.Equal(t, []ownerChange{
+		{index: 1, change: &events.OPChangeOwner{Key: immediate, Owner: newOwner}, previous: owner},
+		{index: 2, change: &events.OPChangeOwner{Key: accepted, Owner: newOwner}, previous: owner},
+	}, ownerChanges(receipt))
+}
+
+func TestBlockToEvents_CanonicalOrder(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	tx, err := types.SignTx(arkivTx(t, &storagetx.ArkivTransaction{
+		Version: storagetx.TransactionVersionOwnership,
+		Create:  []storagetx.ArkivCreate{{BTL: 10, ContentType: "text/plain", Payload: []byte("first")}},
+		Update: []storagetx.ArkivUpdate{
+			{EntityKey: common.HexToHash("0x2"), BTL: 10, ContentType: "text/plain"},
+			{EntityKey: common.HexToHash("0x3"), BTL: 10, ContentType: "text/plain"},
+		},
+		Delete: []common.Hash{common.HexToHash("0x4")},
+		Extend: []storagetx.ExtendBTL{{EntityKey: common.HexToHash("0x5"), NumberOfBlocks: 10}},
+		ChangeOwner: []storagetx.ArkivChangeOwner{
+			{EntityKey: common.HexToHash("0x6"), NewOwner: common.HexToAddress("0xb")},
+			{EntityKey: common.HexToHash("0x7"), NewOwner: common.HexToAddress("0xb"), Immediate: true},
+		},
+		AcceptOwnership: []common.Hash{common.HexToHash("0x8")},
+	}), types.LatestSignerForChainID(big.NewInt(1)), key)
+	require.NoError(t, err)
+
+	created := common.HexToHash("0x1")
+	receipt := &types.Receipt{Status: types.ReceiptStatusSuccessful, Logs: []*types.Log{
+		{Address: address.ArkivProcessorAddress, Topics: []common.Hash{logs.ArkivEntityCreated, created, common.BytesToHash(sender[:])}},
+		expiryLog(logs.ArkivEntityBTLExtended, common.HexToHash("0x5"), sender, 20, 30),
+		ownershipLog(logs.ArkivEntityOwnershipTransferProposed, common.HexToHash("0x6"), sender, common.HexToAddress("0xb")),
+		ownershipLog(logs.ArkivEntityOwnerChanged, common.HexToHash("0x7"), sender, common.HexToAddress("0xb")),
+		ownershipLog(logs.ArkivEntityOwnershipTransferAccepted, common.HexToHash("0x8"), common.HexToAddress("0xc"), sender),
+		ownershipLog(logs.ArkivEntityOwnerChanged, common.HexToHash("0x8"), common.HexToAddress("0xc"), sender),
+	}}
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(7)}).WithBody(types.Body{
+		Transactions: []*types.Transaction{tx},
+	})
+
+	decoded, _, err := blockToEvents(block, []*types.Receipt{receipt})
+	require.NoError(t, err)
+
+	// The operations are numbered in the order the processor applies them, the
+	// proposed transfer at index 5 isn't an event but keeps its index
+	type indexed struct {
+		opIndex uint64
+		key     common.Hash
+	}
+	got := []indexed{}
+	for _, operation := range decoded.Operations {
+		var key common.Hash
+		switch {
+		case operation.Create != nil:
+			key = operation.Create.Key
+		case operation.Delete != nil:
+			key = common.Hash(*operation.Delete)
+		case operation.Update != nil:
+			key = operation.Update.Key
+		case operation.ExtendBTL != nil:
+			key = operation.ExtendBTL.Key
+		case operation.ChangeOwner != nil:
+			key = operation.ChangeOwner.Key
+		}
+		got = append(got, indexed{operation.OpIndex, key})
+	}
+	require.Equal(t, []indexed{
+		{0, created},
+		{1, common.HexToHash("0x4")},
+		{2, common.HexToHash("0x2")},
+		{3, common.HexToHash("0x3")},
+		{4, common.HexToHash("0x5")},
+		{6, common.HexToHash("0x7")},
+		{7, common.HexToHash("0x8")},
+	}, got)
+
+	// The pending view numbers the operations of a transaction the same way
+	pending, err := PendingTransactionToEvents(tx, 0, sender, true, 7, func(common.Hash) (uint64, error) { return 20, nil })
+	require.NoError(t, err)
+	require.Len(t, pending, len(decoded.Operations))
+	for i := range pending {
+		require.Equal(t, decoded.Operations[i].OpIndex, pending[i].OpIndex)
+	}
+}
+
+func TestBlockToEvents_ReduceBTL(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	reduced := common.HexToHash("0x2")
+	tx, err := types.SignTx(arkivTx(t, &storagetx.ArkivTransaction{
+		Version:       storagetx.TransactionVersionReduceBTL,
+		Extend:        []storagetx.ExtendBTL{{EntityKey: common.HexToHash("0x1"), NumberOfBlocks: 10}},
+		RegisterAlias: []storagetx.ArkivRegisterAlias{{Name: "alias", EntityKey: common.HexToHash("0x1"), BTL: 10}},
+		ReduceBTL:     []storagetx.ArkivReduceBTL{{EntityKey: reduced, NumberOfBlocks: 80}},
+	}), types.LatestSignerForChainID(big.NewInt(1)), key)
+	require.NoError(t, err)
+
+	receipt := &types.Receipt{Status: types.ReceiptStatusSuccessful, Logs: []*types.Log{
+		expiryLog(logs.ArkivEntityBTLExtended, common.HexToHash("0x1"), sender, 50, 60),
+		expiryLog(logs.ArkivEntityBTLReduced, reduced, sender, 100, 20),
+	}}
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(7)}).WithBody(types.Body{
+		Transactions: []*types.Transaction{tx},
+	})
+
+	decoded, unknown, err := blockToEvents(block, []*types.Receipt{receipt})
+	require.NoError(t, err)
+	require.Empty(t, unknown)
+
+	// The extension and the reduction both carry the BTL from the block to the expiry
+	// they set, the reduction comes after the extend and the alias registration
+	require.Equal(t, []events.Operation{
+		{OpIndex: 0, ExtendBTL: &events.OPExtendBTL{Key: common.HexToHash("0x1"), BTL: 53}},
+		{OpIndex: 2, ExtendBTL: &events.OPExtendBTL{Key: reduced, BTL: 13}},
+	}, decoded.Operations)
+}
+
+func TestBlockToEvents_ChangeOwnerBatch(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	newOwner := common.HexToAddress("0xb")
+	tx, err := types.SignTx(arkivTx(t, &storagetx.ArkivTransaction{
+		Version:     storagetx.TransactionVersionChangeOwnerBatch,
+		ChangeOwner: []storagetx.ArkivChangeOwner{{EntityKey: common.HexToHash("0x1"), NewOwner: newOwner, Immediate: true}},
+		ChangeOwnerBatch: []storagetx.ArkivChangeOwnerBatch{
+			{EntityKeys: []common.Hash{common.HexToHash("0x2"), common.HexToHash("0x3")}, NewOwner: newOwner, Immediate: true},
+		},
+		AcceptOwnership: []common.Hash{common.HexToHash("0x4")},
+	}), types.LatestSignerForChainID(big.NewInt(1)), key)
+	require.NoError(t, err)
+
+	receipt := &types.Receipt{Status: types.ReceiptStatusSuccessful, Logs: []*types.Log{
+		ownershipLog(logs.ArkivEntityOwnerChanged, common.HexToHash("0x1"), sender, newOwner),
+		ownershipLog(logs.ArkivEntityOwnerChanged, common.HexToHash("0x2"), sender, newOwner),
+		ownershipLog(logs.ArkivEntityOwnerChanged, common.HexToHash("0x3"), sender, newOwner),
+		ownershipLog(logs.ArkivEntityOwnershipTransferAccepted, common.HexToHash("0x4"), common.HexToAddress("0xc"), sender),
+		ownershipLog(logs.ArkivEntityOwnerChanged, common.HexToHash("0x4"), common.HexToAddress("0xc"), sender),
+	}}
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(7)}).WithBody(types.Body{
+		Transactions: []*types.Transaction{tx},
+	})
+
+	decoded, unknown, err := blockToEvents(block, []*types.Receipt{receipt})
+	require.NoError(t, err)
+	require.Empty(t, unknown)
+
+	// Every entity of the batch is an ownership change of its own, numbered after the
+	// ownership changes and before the acceptances
+	require.Equal(t, []events.Operation{
+		{OpIndex: 0, ChangeOwner: &events.OPChangeOwner{Key: common.HexToHash("0x1"), Owner: newOwner}},
+		{OpIndex: 1, ChangeOwner: &events.OPChangeOwner{Key: common.HexToHash("0x2"), Owner: newOwner}},
+		{OpIndex: 2, ChangeOwner: &events.OPChangeOwner{Key: common.HexToHash("0x3"), Owner: newOwner}},
+		{OpIndex: 3, ChangeOwner: &events.OPChangeOwner{Key: common.HexToHash("0x4"), Owner: sender}},
+	}, decoded.Operations)
+
+	// The pending view numbers the operations of a transaction the same way
+	pending, err := PendingTransactionToEvents(tx, 0, sender, true, 7, nil)
+	require.NoError(t, err)
+	require.Equal(t, decoded.Operations, pending)
+}
+
+func TestBlockOwnerChanges(t *testing.T) {
+	keyA, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	keyB, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	a, b := crypto.PubkeyToAddress(keyA.PublicKey), crypto.PubkeyToAddress(keyB.PublicKey)
+	c := common.HexToAddress("0xc")
+	entity := common.HexToHash("0x1")
+	signer := types.LatestSignerForChainID(big.NewInt(1))
+
+	// A gives the entity to B, which gives it to C in the same block
+	change := func(key *ecdsa.PrivateKey, newOwner common.Address) *types.Transaction {
+		tx, err := types.SignTx(arkivTx(t, &storagetx.ArkivTransaction{
+			Version:     storagetx.TransactionVersionOwnership,
+			ChangeOwner: []storagetx.ArkivChangeOwner{{EntityKey: entity, NewOwner: newOwner, Immediate: true}},
+		}), signer, key)
+		require.NoError(t, err)
+		return tx
+	}
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(7)}).WithBody(types.Body{
+		Transactions: []*types.Transaction{change(keyA, b), change(keyB, c)},
+	})
+	receipts := []*types.Receipt{
+		{Status: types.ReceiptStatusSuccessful, Logs: []*types.Log{ownershipLog(logs.ArkivEntityOwnerChanged, entity, a, b)}},
+		{Status: types.ReceiptStatusSuccessful, Logs: []*types.Log{ownershipLog(logs.ArkivEntityOwnerChanged, entity, b, c)}},
+	}
+
+	// Every change has the owner the change before it left
+	require.Equal(t, []OwnerChange{
+		{TxIndex: 0, OPChangeOwner: events.OPChangeOwner{Key: entity, Owner: b}, PreviousOwner: a},
+		{TxIndex: 1, OPChangeOwner: events.OPChangeOwner{Key: entity, Owner: c}, PreviousOwner: b},
+	}, BlockOwnerChanges(block, receipts))
+
+	// The changes are the ones BlockToEvents decodes
+	decoded, _, err := BlockToEvents(block, receipts)
+	require.NoError(t, err)
+	require.Len(t, decoded.Operations, 2)
+	for i, change := range BlockOwnerChanges(block, receipts) {
+		require.Equal(t, decoded.Operations[i].TxIndex, change.TxIndex)
+		require.Equal(t, decoded.Operations[i].OpIndex, change.OpIndex)
+		require.Equal(t, *decoded.Operations[i].ChangeOwner, change.OPChangeOwner)
+	}
+}
+
+func TestAnnotationKeyPrefixes(t *testing.T) {
+	attributes := stringAnnotationsToMap(
+		[]storagetx.StringAnnotation{{Key: "invoice.customer.region", Value: "eu"}},
+		[]storagetx.NumericAnnotation{{Key: "invoice.total", Value: 10}},
+		nil,
+	)
+
+	// Dotted keys pass through unchanged, their prefixes are indexed
+	require.Equal(t, map[string]string{
+		"invoice.customer.region":  "eu",
+		"$prefix_invoice":          storagetx.AnnotationKeyPrefixAttributeValue,
+		"$prefix_invoice.customer": storagetx.AnnotationKeyPrefixAttributeValue,
+	}, attributes)
+	require.Equal(t, map[string]uint64{"invoice.total": 10}, numericAnnotationsToMap([]storagetx.NumericAnnotation{{Key: "invoice.total", Value: 10}}))
+}
+
+// createBlock returns a block with a transaction creating two entities, and the
+// ArkivEntityCreated logs of its entities.
+func createBlock(t testing.TB, number int64) (*types.Block, []*types.Log) {
+	t.Helper()
+
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	tx, err := types.SignTx(arkivTx(t, &storagetx.ArkivTransaction{
+		Create: []storagetx.ArkivCreate{
+			{BTL: 10, ContentType: "text/plain", Payload: []byte("first")},
+			{BTL: 10, ContentType: "text/plain", Payload: []byte("second")},
+		},
+	}), types.LatestSignerForChainID(big.NewInt(1)), key)
+	require.NoError(t, err)
+
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(number)}).WithBody(types.Body{
+		Transactions: []*types.Transaction{tx},
+	})
+	created := []*types.Log{}
+	for _, entity := range []common.Hash{common.HexToHash("0x1"), common.HexToHash("0x2")} {
+		created = append(created, &types.Log{
+			Address: address.ArkivProcessorAddress,
+			Topics:  []common.Hash{logs.ArkivEntityCreated, entity, common.BytesToHash(sender[:])},
+		})
+	}
+	return block, created
+}
+
+func TestBlockToEvents_CreatedEntityLogs(t *testing.T) {
+	block, created := createBlock(t, 7)
+	txHash := block.Transactions()[0].Hash().Hex()
+
+	t.Run("anonymous logs", func(t *testing.T) {
+		receipt := &types.Receipt{Status: types.ReceiptStatusSuccessful, Logs: []*types.Log{
+			{Address: address.ArkivProcessorAddress},
+			created[0],
+			{Address: common.HexToAddress("0xc"), Topics: []common.Hash{logs.ArkivEntityCreated}},
+			created[1],
+		}}
+		decoded, _, err := blockToEvents(block, []*types.Receipt{receipt})
+		require.NoError(t, err)
+		require.Len(t, decoded.Operations, 2)
+		require.Equal(t, common.HexToHash("0x1"), decoded.Operations[0].Create.Key)
+		require.Equal(t, common.HexToHash("0x2"), decoded.Operations[1].Create.Key)
+	})
+
+	t.Run("missing logs", func(t *testing.T) {
+		receipt := &types.Receipt{Status: types.ReceiptStatusSuccessful, Logs: created[:1]}
+		_, _, err := blockToEvents(block, []*types.Receipt{receipt})
+		require.ErrorIs(t, err, ErrCreatedEntityLogs)
+		require.ErrorContains(t, err, txHash)
+		require.ErrorContains(t, err, "operation 1")
+	})
+
+	t.Run("extra logs", func(t *testing.T) {
+		extra := &types.Log{
+			Address: address.ArkivProcessorAddress,
+			Topics:  []common.Hash{logs.ArkivEntityCreated, common.HexToHash("0x3")},
+		}
+		receipt := &types.Receipt{Status: types.ReceiptStatusSuccessful, Logs: append(created[:2:2], extra)}
+		_, _, err := blockToEvents(block, []*types.Receipt{receipt})
+		require.ErrorIs(t, err, ErrCreatedEntityLogs)
+		require.ErrorContains(t, err, txHash)
+		require.ErrorContains(t, err, "3 logs for 2 creates")
+	})
+
+	t.Run("deduplicated create", func(t *testing.T) {
+		deduplicated := &types.Log{
+			Address: address.ArkivProcessorAddress,
+			Topics:  []common.Hash{logs.ArkivCreateDeduplicated, common.HexToHash("0x3"), created[0].Topics[2]},
+		}
+		receipt := &types.Receipt{Status: types.ReceiptStatusSuccessful, Logs: []*types.Log{deduplicated, created[1]}}
+		decoded, _, err := blockToEvents(block, []*types.Receipt{receipt})
+		require.NoError(t, err)
+
+		// The first create was a retry, only the second one creates an entity
+		require.Len(t, decoded.Operations, 1)
+		require.Equal(t, uint64(1), decoded.Operations[0].OpIndex)
+		require.Equal(t, common.HexToHash("0x2"), decoded.Operations[0].Create.Key)
+	})
+
+	t.Run("log without entity key", func(t *testing.T) {
+		receipt := &types.Receipt{Status: types.ReceiptStatusSuccessful, Logs: []*types.Log{
+			created[0],
+			{Address: address.ArkivProcessorAddress, Topics: []common.Hash{logs.ArkivEntityCreated}, Index: 1},
+		}}
+		_, _, err := blockToEvents(block, []*types.Receipt{receipt})
+		require.ErrorIs(t, err, ErrCreatedEntityLogs)
+		require.ErrorContains(t, err, txHash)
+		require.ErrorContains(t, err, "log 1")
+	})
+}
+
+func TestBlockToEvents_Expirations(t *testing.T) {
+	expiredLog := func(logAddress common.Address, key common.Hash) *types.Log {
+		return &types.Log{Address: logAddress, Topics: []common.Hash{logs.ArkivEntityExpired}, Data: key.Bytes()}
+	}
+	transactions := []*types.Transaction{}
+	for nonce := range uint64(3) {
+		transactions = append(transactions, types.NewTx(&types.LegacyTx{Nonce: nonce, To: &common.Address{0xa}}))
+	}
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(7)}).WithBody(types.Body{Transactions: transactions})
+
+	// The housekeeping logs are on the third receipt, next to the logs of another
+	// contract with the same topic
+	receipts := []*types.Receipt{
+		{Status: types.ReceiptStatusSuccessful},
+		{Status: types.ReceiptStatusSuccessful},
+		{Status: types.ReceiptStatusSuccessful, Logs: []*types.Log{
+			expiredLog(common.HexToAddress("0xc"), common.HexToHash("0x1")),
+			expiredLog(address.ArkivProcessorAddress, common.HexToHash("0x2")),
+			expiredLog(address.ArkivProcessorAddress, common.HexToHash("0x3")),
+		}},
+	}
+
+	decoded, _, err := blockToEvents(block, receipts)
+	require.NoError(t, err)
+	expired := func(key common.Hash) *events.OPExpire {
+		expire := events.OPExpire(key)
+		return &expire
+	}
+	require.Equal(t, []events.Operation{
+		{TxIndex: 2, OpIndex: 1, Expire: expired(common.HexToHash("0x2"))},
+		{TxIndex: 2, OpIndex: 2, Expire: expired(common.HexToHash("0x3"))},
+	}, decoded.Operations)
+}
+
+func TestBlockToEvents_RepeatedKeys(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	signer := types.LatestSignerForChainID(big.NewInt(1))
+	deleted, updated := common.HexToHash("0x1"), common.HexToHash("0x2")
+	idempotencyKey := common.HexToHash("0xabc")
+
+	// A retried create resolving to an entity the transaction then deletes
+	createDelete, err := types.SignTx(arkivTx(t, &storagetx.ArkivTransaction{
+		Version: storagetx.TransactionVersionIdempotency,
+		Create:  []storagetx.ArkivCreate{{BTL: 10, ContentType: "text/plain", Payload: []byte("retried"), IdempotencyKey: &idempotencyKey}},
+		Delete:  []common.Hash{deleted},
+	}), signer, key)
+	require.NoError(t, err)
+	// Two updates of an entity, then an extension of it
+	updates, err := types.SignTx(arkivTx(t, &storagetx.ArkivTransaction{
+		Update: []storagetx.ArkivUpdate{
+			{EntityKey: updated, BTL: 10, ContentType: "text/plain", Payload: []byte("first")},
+			{EntityKey: updated, BTL: 20, ContentType: "text/plain", Payload: []byte("second")},
+		},
+		Extend: []storagetx.ExtendBTL{{EntityKey: updated, NumberOfBlocks: 5}},
+	}), signer, key)
+	require.NoError(t, err)
+
+	owner := common.BytesToHash(sender[:])
+	receipts := []*types.Receipt{
+		{Status: types.ReceiptStatusSuccessful, Logs: []*types.Log{
+			{Address: address.ArkivProcessorAddress, Topics: []common.Hash{logs.ArkivCreateDeduplicated, deleted, owner}, Data: idempotencyKey.Bytes()},
+			{Address: address.ArkivProcessorAddress, Topics: []common.Hash{logs.ArkivEntityDeleted, deleted, owner}},
+		}},
+		{Status: types.ReceiptStatusSuccessful, Logs: []*types.Log{
+			expiryLog(logs.ArkivEntityBTLExtended, updated, sender, 27, 32),
+		}},
+	}
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(7)}).WithBody(types.Body{
+		Transactions: []*types.Transaction{createDelete, updates},
+	})
+
+	decoded, unknown, err := blockToEvents(block, receipts)
+	require.NoError(t, err)
+	require.Empty(t, unknown)
+
+	// The deduplicated create has no event, the delete keeps its position after it
+	require.Len(t, decoded.Operations, 4)
+	deleteOp := events.OPDelete(deleted)
+	require.Equal(t, events.Operation{OpIndex: 1, Delete: &deleteOp}, decoded.Operations[0])
+
+	// The operations on the same key follow in the order they're applied, so the store
+	// ends with the second payload and the extended expiry
+	require.Equal(t, []byte("first"), decoded.Operations[1].Update.Content)
+	require.Equal(t, uint64(0), decoded.Operations[1].OpIndex)
+	require.Equal(t, []byte("second"), decoded.Operations[2].Update.Content)
+	require.Equal(t, uint64(1), decoded.Operations[2].OpIndex)
+	require.Equal(t, events.Operation{TxIndex: 1, OpIndex: 2, ExtendBTL: &events.OPExtendBTL{Key: updated, BTL: 25}}, decoded.Operations[3])
+}
@@ -1,9 +1,12 @@
 package dbevents
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"sync"
+	"time"
 
-	arkivevents "github.com/Arkiv-Network/arkiv-events"
 	"github.com/Arkiv-Network/arkiv-events/events"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/rawdb"
@@ -13,47 +16,303 @@ import (
 	"github.com/ethereum/go-ethereum/params"
 )
 
-func NewChainBatchIterator(db ethdb.Database, lastBlock uint64) (
-	arkivevents.BatchIterator,
-	func(cc *params.ChainConfig, block *types.Block) error,
+// PrunedGap is a range of blocks whose receipts were pruned from the database
+// and that could therefore not be indexed.
+type PrunedGap struct {
+	From uint64 `json:"from"`
+	To   uint64 `json:"to"`
+}
+
+// SyncStatus describes the progress of the chain batch iterator.
+type SyncStatus struct {
+	// LastBlock is the last block handed to the consumer of the iterator.
+	LastBlock uint64 `json:"lastBlock"`
+	// HeadBlock is the latest head the iterator was notified about.
+	HeadBlock uint64 `json:"headBlock"`
+	// EarliestIndexableBlock is the first block with receipts available, 0 if nothing is pruned.
+	EarliestIndexableBlock uint64 `json:"earliestIndexableBlock"`
+	// Stalled is set when the iterator can't make progress because the next block is pruned.
+	Stalled bool `json:"stalled"`
+	// PrunedGap is the range of blocks that were skipped because their receipts were pruned.
+	PrunedGap *PrunedGap `json:"prunedGap,omitempty"`
+	// UnknownOperations is the number of operations skipped since the start because the
+	// pipeline couldn't map them to events, see UnknownOperations.
+	UnknownOperations uint64 `json:"unknownOperations"`
+	// FirstUnknownOperationBlock is the first block with skipped operations.
+	FirstUnknownOperationBlock *uint64 `json:"firstUnknownOperationBlock,omitempty"`
+	// DeadLetterBatches is the number of batches in the dead-letter queue, see Ingester.
+	DeadLetterBatches uint64 `json:"deadLetterBatches"`
+	// Heartbeat is the last time the iterator made progress, see Watchdog.
+	Heartbeat time.Time `json:"heartbeat"`
+	// HeadsSinceHeartbeat is the number of heads notified since the heartbeat.
+	HeadsSinceHeartbeat uint64 `json:"headsSinceHeartbeat"`
+	// IteratorRestarts is the number of times the iterator was restarted from the
+	// checkpoint after a panic.
+	IteratorRestarts uint64 `json:"iteratorRestarts"`
+}
+
+// SyncStatusTracker keeps the sync status of the chain batch iterator.
+type SyncStatusTracker struct {
+	mu     sync.Mutex
+	status SyncStatus
+}
+
+// Status returns a copy of the current sync status.
+func (t *SyncStatusTracker) Status() SyncStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	status := t.status
+	if status.PrunedGap != nil {
+		gap := *status.PrunedGap
+		status.PrunedGap = &gap
+	}
+	if status.FirstUnknownOperationBlock != nil {
+		first := *status.FirstUnknownOperationBlock
+		status.FirstUnknownOperationBlock = &first
+	}
+	return status
+}
+
+func (t *SyncStatusTracker) update(fn func(status *SyncStatus)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fn(&t.status)
+}
+
+// addUnknownOperations accounts the operations of the block the pipeline skipped.
+func (t *SyncStatusTracker) addUnknownOperations(blockNumber uint64, unknown []UnknownOperations) {
+	if len(unknown) == 0 {
+		return
+	}
+
+	count := uint64(0)
+	for _, u := range unknown {
+		log.Error("Arkiv skipped operations it can't map to events, the store may diverge from the chain",
+			"block", blockNumber, "tx", u.TxHash, "index", u.TxIndex, "kinds", u.Kinds)
+		count += u.Count()
+	}
+	unknownOperationsCounter.Inc(int64(count))
+
+	t.update(func(status *SyncStatus) {
+		status.UnknownOperations += count
+		if status.FirstUnknownOperationBlock == nil {
+			status.FirstUnknownOperationBlock = &blockNumber
+		}
+	})
+}
+
+// DefaultBatchSize is the default maximum number of blocks in a batch.
+const DefaultBatchSize = 100
+
+// ErrIncompleteBatch is the error of the batches the iterator couldn't read all the
+// blocks of: a block of the canonical chain is missing from the database, or the chain
+// was reorganized while the batch was read.
+var ErrIncompleteBatch = errors.New("incomplete batch")
+
+type chainBatchIteratorConfig struct {
+	batchSize uint64
+	// checkpoint writes the last block of every batch consumed to the database.
+	checkpoint bool
+	ctx        context.Context
+	// concurrency is the number of blocks read from the database at once.
+	concurrency int
+	// usedSlots reads the used slots counter for the slot deltas of the summaries.
+	usedSlots UsedSlotsReader
+	// convert converts the blocks to events, blockToEvents.
+	convert blockConverter
+	// restartDelay, maxRestartDelay and maxRestarts bound the restarts after a
+	// panic, see iteratorRestartDelay.
+	restartDelay    time.Duration
+	maxRestartDelay time.Duration
+	maxRestarts     int
+}
+
+// ChainBatchIteratorOption configures the iterator returned by NewChainBatchIterator.
+type ChainBatchIteratorOption func(*chainBatchIteratorConfig)
+
+// WithBatchSize sets the maximum number of blocks in a batch, DefaultBatchSize if it's
+// 0. Larger batches catch up with a backlog faster at the cost of more memory.
+func WithBatchSize(size uint64) ChainBatchIteratorOption {
+	return func(c *chainBatchIteratorConfig) {
+		if size > 0 {
+			c.batchSize = size
+		}
+	}
+}
+
+// WithCheckpoint writes the last block of every batch to the database once the consumer
+// returns from it, see NewChainBatchIteratorFromCheckpoint.
+func WithCheckpoint() ChainBatchIteratorOption {
+	return func(c *chainBatchIteratorConfig) {
+		c.checkpoint = true
+	}
+}
+
+// WithConcurrency sets the number of blocks read from the database at once,
+// DefaultConcurrency if it's 0. The blocks of a batch are read ahead of the iterator,
+// the next ones while the consumer works on it, and yielded in order.
+func WithConcurrency(concurrency int) ChainBatchIteratorOption {
+	return func(c *chainBatchIteratorConfig) {
+		if concurrency > 0 {
+			c.concurrency = concurrency
+		}
+	}
+}
+
+// WithContext ends the iterator once the context is canceled. The batch the consumer
+// holds is finished, with its checkpoint, but no other batch is read.
+func WithContext(ctx context.Context) ChainBatchIteratorOption {
+	return func(c *chainBatchIteratorConfig) {
+		c.ctx = ctx
+	}
+}
+
+// WithUsedSlots reads the slot deltas of the summaries of the blocks from the used
+// slots counter of their states, and cross-checks the deltas of every batch with the
+// counter at its boundaries. The blocks whose state is pruned have no slot delta.
+func WithUsedSlots(read UsedSlotsReader) ChainBatchIteratorOption {
+	return func(c *chainBatchIteratorConfig) {
+		c.usedSlots = read
+	}
+}
+
+// prunedHorizon returns the first block whose receipts are still kept in the database,
+// or 0 if the database was not pruned.
+func prunedHorizon(db ethdb.Database) uint64 {
+	tail, err := db.Tail()
+	if err != nil {
+		return 0
+	}
+	return tail
+}
+
+// NewChainBatchIteratorFromCheckpoint returns an iterator like NewChainBatchIterator,
+// starting after the checkpoint of the database, from the start of the chain without
+// one. The iterator writes the last block of a batch as the checkpoint once the
+// consumer returns from it: a consumer stopped in the middle of a batch gets the batch
+// again on restart.
+func NewChainBatchIteratorFromCheckpoint(db ethdb.Database, hooks *Hooks, skipPruned bool, options ...ChainBatchIteratorOption) (
+	BatchIterator,
+	*SyncStatusTracker,
 ) {
+	var lastBlock uint64
+	if checkpoint := rawdb.ReadArkivEventsCheckpoint(db); checkpoint != nil {
+		lastBlock = checkpoint.Number
+	}
+	return NewChainBatchIterator(db, hooks, lastBlock, skipPruned, append(options, WithCheckpoint())...)
+}
+
+// NewChainBatchIterator returns an iterator over the Arkiv events of the canonical chain
+// starting after lastBlock. The iterator registers on the hooks to be notified about
+// new heads.
+// If skipPruned is set, blocks whose receipts were pruned are skipped instead of
+// stalling the iterator at the pruned boundary.
+// Every block of a batch is read once, by its canonical hash. If a block is missing,
+// or the blocks of the batch don't form a chain, the iterator yields an
+// ErrIncompleteBatch error and stops.
+// The blocks carry their hash, the hash of their parent, their time, the hashes of
+// their transactions and the summary of their operations, BatchIterator.Events drops
+// them for the store.
+func NewChainBatchIterator(db ethdb.Database, hooks *Hooks, lastBlock uint64, skipPruned bool, options ...ChainBatchIteratorOption) (
+	BatchIterator,
+	*SyncStatusTracker,
+) {
+	config := chainBatchIteratorConfig{
+		batchSize:       DefaultBatchSize,
+		concurrency:     DefaultConcurrency,
+		ctx:             context.Background(),
+		convert:         blockToEvents,
+		restartDelay:    iteratorRestartDelay,
+		maxRestartDelay: iteratorMaxRestartDelay,
+		maxRestarts:     iteratorMaxRestarts,
+	}
+	for _, option := range options {
+		option(&config)
+	}
 
 	cond := sync.NewCond(&sync.Mutex{})
 	var block *types.Block
+	context.AfterFunc(config.ctx, func() {
+		cond.L.Lock()
+		cond.Broadcast()
+		cond.L.Unlock()
+	})
 
 	var chainConfig *params.ChainConfig
 
-	onNewHead := func(cc *params.ChainConfig, bl *types.Block) error {
+	tracker := &SyncStatusTracker{
+		status: SyncStatus{
+			LastBlock: lastBlock,
+			Heartbeat: time.Now(),
+		},
+	}
+
+	var prunedErrorLogged bool
+
+	hooks.RegisterBlockHook("store", func(bl *types.Block, _ *events.Block) error {
 		cond.L.Lock()
 		block = bl
-		chainConfig = cc
+		chainConfig = hooks.ChainConfig()
 		cond.Signal()
 		cond.L.Unlock()
+		tracker.update(func(status *SyncStatus) {
+			status.HeadBlock = bl.NumberU64()
+			status.HeadsSinceHeartbeat++
+		})
 		log.Info("Arkiv new head", "number", bl.Number, "hash", bl.Hash())
 		return nil
-	}
-
-	batchIterator := arkivevents.BatchIterator(
-		func(yield func(arkivevents.BatchOrError) bool) {
-
-			for {
-
-				batch := arkivevents.BatchOrError{
-					Batch: events.BlockBatch{},
+	})
+
+	batchIterator := BatchIterator(
+		func(yield func(BatchOrError) bool) {
+
+			prefetch := newPrefetcher(db, config.usedSlots, config.convert, config.concurrency)
+			slots := &slotCheck{db: db, read: config.usedSlots}
+			var cfg *params.ChainConfig
+			// consumed is the last block of the last batch the consumer returned from
+			consumed := lastBlock
+			// restarts is the number of panics in a row at the checkpoint restartedAt
+			var restarts int
+			var restartedAt uint64
+			for config.ctx.Err() == nil {
+				tracker.heartbeat()
+
+				var newBlockNumber uint64
+				// The head the batch is read up to, read again after a panic
+				var head *types.Block
+				// The block before the batch, a skipped pruned gap moves lastBlock
+				before := lastBlock
+				batch := BatchOrError{
+					Batch: BlockBatch{},
 					Error: nil,
 				}
 
-				func() {
+				recovered := func() (recovered *iteratorPanic) {
+					defer func() {
+						if r := recover(); r != nil {
+							recovered = newIteratorPanic(r)
+						}
+					}()
+
 					cond.L.Lock()
 
-					for block == nil {
+					for block == nil && config.ctx.Err() == nil {
 						cond.Wait()
 					}
-					newBlockNumber := block.NumberU64()
+					if block == nil {
+						// The iterator was stopped
+						cond.L.Unlock()
+						return
+					}
+					head = block
+					newBlockNumber = block.NumberU64()
+					cfg = chainConfig
 
 					block = nil
 
 					cond.L.Unlock()
+					tracker.heartbeat()
 
 					log.Info("Arkiv new head", "number", newBlockNumber)
 
@@ -61,62 +320,182 @@ func NewChainBatchIterator(db ethdb.Database, lastBlock uint64) (
 						return
 					}
 
-					batchSize := min(100, (newBlockNumber - lastBlock))
-
-					log.Info("Arkiv reading batch", "size", batchSize)
+					log.Info("Arkiv reading batch", "size", min(config.batchSize, newBlockNumber-lastBlock))
+					prefetch.prefetch(lastBlock+1, min(lastBlock+config.batchSize, newBlockNumber), cfg)
 
-					for i := range batchSize {
+					var parent common.Hash
+					for blockNumber := lastBlock + 1; blockNumber <= newBlockNumber && uint64(len(batch.Batch.Blocks)) < config.batchSize; blockNumber++ {
 
-						blockNumber := lastBlock + i + 1
 						log.Info("Arkiv reading block", "number", blockNumber)
 
-						hash := rawdb.ReadCanonicalHash(db, blockNumber)
+						loaded := prefetch.load(blockNumber, cfg)
+						hash := loaded.hash
 						if hash == (common.Hash{}) {
-							log.Warn("Canonical hash not found", "number", blockNumber)
+							batch.Error = fmt.Errorf("%w: canonical hash of block %d not found", ErrIncompleteBatch, blockNumber)
 							return
 						}
-						bl := rawdb.ReadBlock(db, hash, blockNumber)
-
-						receiepts := rawdb.ReadReceipts(db, hash, bl.NumberU64(), bl.Time(), chainConfig)
 
-						if receiepts == nil {
-							log.Warn("receipts not found for block", "number", blockNumber, "hash", hash)
+						header := loaded.header
+						if header == nil {
+							batch.Error = fmt.Errorf("%w: header of block %d (%s) not found", ErrIncompleteBatch, blockNumber, hash)
 							return
 						}
-
-						block := rawdb.ReadBlock(db, bl.Hash(), bl.NumberU64())
-						if block == nil {
-							log.Warn("block not found for block", "number", blockNumber, "hash", hash)
+						// The blocks are read by hash, a reorg while reading breaks the chain
+						if len(batch.Batch.Blocks) > 0 && header.ParentHash != parent {
+							batch.Error = fmt.Errorf("%w: block %d (%s) doesn't follow the block before it, the chain was reorganized", ErrIncompleteBatch, blockNumber, hash)
 							return
 						}
 
-						batchBlock, err := blockToEvents(block, receiepts)
-						if err != nil {
-							log.Error("failed to convert block to events", "number", blockNumber, "hash", hash, "error", err)
+						if loaded.receipts == nil {
+							horizon := prunedHorizon(db)
+							if blockNumber >= horizon {
+								batch.Error = fmt.Errorf("%w: receipts of block %d (%s) not found", ErrIncompleteBatch, blockNumber, hash)
+								return
+							}
+
+							// The receipts of the block were pruned, they will never show up.
+							// Pruned blocks are always at the start of a batch, as the iterator
+							// can't get past them unless they are skipped.
+							if !prunedErrorLogged {
+								log.Error(
+									"Arkiv can't index blocks with pruned receipts, restart with --arkiv.skip-pruned to skip them",
+									"first", blockNumber,
+									"earliestIndexable", horizon,
+								)
+								prunedErrorLogged = true
+							}
+
+							tracker.update(func(status *SyncStatus) {
+								status.EarliestIndexableBlock = horizon
+								status.Stalled = !skipPruned
+							})
+
+							if !skipPruned {
+								return
+							}
+
+							log.Warn("Arkiv skipping blocks with pruned receipts", "from", blockNumber, "to", horizon-1)
+
+							tracker.update(func(status *SyncStatus) {
+								if status.PrunedGap == nil {
+									status.PrunedGap = &PrunedGap{From: blockNumber}
+								}
+								status.PrunedGap.To = horizon - 1
+								status.LastBlock = horizon - 1
+							})
+
+							lastBlock = horizon - 1
+							blockNumber = lastBlock
+							prefetch.discard(horizon)
+							prefetch.prefetch(horizon, min(lastBlock+config.batchSize, newBlockNumber), cfg)
+							continue
+						}
+
+						if loaded.block == nil {
+							batch.Error = fmt.Errorf("%w: body of block %d (%s) not found", ErrIncompleteBatch, blockNumber, hash)
+							return
+						}
+						if loaded.err != nil {
+							batch.Error = fmt.Errorf("failed to convert block %d (%s) to events: %w", blockNumber, hash, loaded.err)
 							return
 						}
+						tracker.addUnknownOperations(blockNumber, loaded.unknown)
 
-						batch.Batch.Blocks = append(batch.Batch.Blocks, *batchBlock)
+						batch.Batch.Blocks = append(batch.Batch.Blocks, newBlock(loaded.block, loaded.events, loaded.summary))
+						parent = hash
 
 					}
-
+					return nil
 				}()
 
+				if recovered != nil {
+					// The batch is dropped, the iterator restarts from the checkpoint
+					lastBlock = consumed
+					if config.checkpoint {
+						if checkpoint := rawdb.ReadArkivEventsCheckpoint(db); checkpoint != nil {
+							lastBlock = checkpoint.Number
+						}
+					}
+					iteratorPanicsCounter.Inc(1)
+					if restarts == 0 || restartedAt != lastBlock {
+						restarts, restartedAt = 0, lastBlock
+					}
+					if restarts >= config.maxRestarts {
+						err := fmt.Errorf("%w: panicked %d times in a row at checkpoint %d: %v", ErrIteratorPanicked, restarts+1, lastBlock, recovered.value)
+						log.Error("Arkiv events iterator keeps panicking at the checkpoint, halting the ingestion",
+							"panic", recovered.value, "checkpoint", lastBlock, "restarts", restarts, "stack", string(recovered.stack))
+						tracker.update(func(status *SyncStatus) {
+							status.LastBlock = lastBlock
+							status.Stalled = true
+						})
+						yield(BatchOrError{Error: err})
+						return
+					}
+					restarts++
+					delay := restartBackoff(restarts, config.restartDelay, config.maxRestartDelay)
+					log.Error("Arkiv events iterator panicked, restarting it from the checkpoint",
+						"panic", recovered.value, "checkpoint", lastBlock, "restarts", restarts, "delay", delay, "stack", string(recovered.stack))
+					tracker.update(func(status *SyncStatus) {
+						status.LastBlock = lastBlock
+						status.IteratorRestarts++
+					})
+					prefetch = newPrefetcher(db, config.usedSlots, config.convert, config.concurrency)
+
+					select {
+					case <-config.ctx.Done():
+						return
+					case <-time.After(delay):
+					}
+					// The head is read again unless a newer one arrived
+					cond.L.Lock()
+					if block == nil {
+						block = head
+					}
+					cond.L.Unlock()
+					continue
+				}
+
+				if batch.Error != nil {
+					log.Error("Arkiv failed to read a batch, halting the ingestion", "error", batch.Error)
+					tracker.update(func(status *SyncStatus) {
+						status.Stalled = true
+					})
+					yield(BatchOrError{Error: batch.Error})
+					return
+				}
+
 				if len(batch.Batch.Blocks) == 0 {
 					continue
 				}
+				if config.ctx.Err() != nil {
+					// The batch read while the iterator was stopped is left for the next run
+					return
+				}
 
 				log.Info("yielding batch", "from", batch.Batch.Blocks[0].Number, "to", batch.Batch.Blocks[len(batch.Batch.Blocks)-1].Number)
 
 				lastBlock = batch.Batch.Blocks[len(batch.Batch.Blocks)-1].Number
+				slots.check(before, batch.Batch.Blocks)
+				// The next blocks are read while the consumer works on the batch
+				prefetch.prefetch(lastBlock+1, min(lastBlock+config.batchSize, newBlockNumber), cfg)
+
+				tracker.update(func(status *SyncStatus) {
+					status.LastBlock = lastBlock
+					status.Stalled = false
+				})
 
 				if !yield(batch) {
 					return
 				}
+				consumed = lastBlock
+				if config.checkpoint {
+					last := batch.Batch.Blocks[len(batch.Batch.Blocks)-1]
+					rawdb.WriteArkivEventsCheckpoint(db, rawdb.ArkivEventsCheckpoint{Number: last.Number, Hash: last.Hash})
+				}
 			}
 
 		},
 	)
 
-	return batchIterator, onNewHead
+	return batchIterator, tracker
 }
@@ -0,0 +1,609 @@
+package dbevents
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	arkivevents "github.com/Arkiv-Network/arkiv-events"
+	"github.com/Arkiv-Network/arkiv-events/events"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+// prunedDB is a database whose ancient tail is set to simulate pruned receipts.
+type prunedDB struct {
+	ethdb.Database
+	tail uint64
+}
+
+func (db *prunedDB) Tail() (uint64, error) {
+	return db.tail, nil
+}
+
+// newPrunedDB writes a canonical chain of the given length to a memory database,
+// without receipts for the blocks below tail.
+func newPrunedDB(t *testing.T, length uint64, tail uint64) (*prunedDB, []*types.Block) {
+	t.Helper()
+
+	db := &prunedDB{Database: rawdb.NewMemoryDatabase(), tail: tail}
+	blocks := make([]*types.Block, 0, length+1)
+
+	parent := common.Hash{}
+	for number := uint64(0); number <= length; number++ {
+		header := &types.Header{
+			ParentHash: parent,
+			Number:     new(big.Int).SetUint64(number),
+			Time:       number,
+			Difficulty: big.NewInt(0),
+		}
+		block := types.NewBlockWithHeader(header)
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteCanonicalHash(db, block.Hash(), number)
+		if number >= tail {
+			rawdb.WriteReceipts(db, block.Hash(), number, types.Receipts{})
+		}
+		blocks = append(blocks, block)
+		parent = block.Hash()
+	}
+
+	return db, blocks
+}
+
+// startIterator runs the iterator in the background and sends the batches it yields on the returned channel.
+func startIterator(batchIterator BatchIterator) <-chan BatchOrError {
+	batches := make(chan BatchOrError)
+	go func() {
+		for batch := range batchIterator {
+			batches <- batch
+		}
+	}()
+	return batches
+}
+
+func nextBatch(t *testing.T, batches <-chan BatchOrError) BatchOrError {
+	t.Helper()
+
+	select {
+	case batch := <-batches:
+		return batch
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a batch")
+		return BatchOrError{}
+	}
+}
+
+func TestChainBatchIterator_StallsOnPrunedReceipts(t *testing.T) {
+	db, blocks := newPrunedDB(t, 10, 5)
+
+	hooks := NewHooks(db, 0)
+	batchIterator, tracker := NewChainBatchIterator(db, hooks, 0, false)
+	startIterator(batchIterator)
+
+	require.NoError(t, hooks.OnNewBlock(params.TestChainConfig, blocks[10]))
+
+	require.Eventually(t, func() bool {
+		return tracker.Status().Stalled
+	}, 5*time.Second, 10*time.Millisecond)
+
+	status := tracker.Status()
+	require.Equal(t, uint64(0), status.LastBlock)
+	require.Equal(t, uint64(10), status.HeadBlock)
+	require.Equal(t, uint64(5), status.EarliestIndexableBlock)
+	require.Nil(t, status.PrunedGap)
+}
+
+func TestChainBatchIterator_SkipsPrunedReceipts(t *testing.T) {
+	db, blocks := newPrunedDB(t, 10, 5)
+
+	hooks := NewHooks(db, 0)
+	batchIterator, tracker := NewChainBatchIterator(db, hooks, 0, true)
+	batches := startIterator(batchIterator)
+
+	require.NoError(t, hooks.OnNewBlock(params.TestChainConfig, blocks[10]))
+
+	batch := nextBatch(t, batches)
+	require.NoError(t, batch.Error)
+	require.Len(t, batch.Batch.Blocks, 6)
+	require.Equal(t, uint64(5), batch.Batch.Blocks[0].Number)
+	require.Equal(t, uint64(10), batch.Batch.Blocks[5].Number)
+
+	status := tracker.Status()
+	require.False(t, status.Stalled)
+	require.Equal(t, uint64(10), status.LastBlock)
+	require.Equal(t, uint64(5), status.EarliestIndexableBlock)
+	require.Equal(t, &PrunedGap{From: 1, To: 4}, status.PrunedGap)
+}
+
+func TestChainBatchIterator_NoPruning(t *testing.T) {
+	db, blocks := newPrunedDB(t, 3, 0)
+
+	hooks := NewHooks(db, 0)
+	batchIterator, tracker := NewChainBatchIterator(db, hooks, 0, false)
+	batches := startIterator(batchIterator)
+
+	require.NoError(t, hooks.OnNewBlock(params.TestChainConfig, blocks[3]))
+
+	batch := nextBatch(t, batches)
+	require.Len(t, batch.Batch.Blocks, 3)
+	require.Equal(t, uint64(1), batch.Batch.Blocks[0].Number)
+
+	status := tracker.Status()
+	require.Equal(t, uint64(3), status.LastBlock)
+	require.Equal(t, uint64(0), status.EarliestIndexableBlock)
+	require.Nil(t, status.PrunedGap)
+}
+
+func TestChainBatchIterator_BatchSize(t *testing.T) {
+	db, blocks := newPrunedDB(t, 5, 0)
+
+	hooks := NewHooks(db, 0)
+	batchIterator, tracker := NewChainBatchIterator(db, hooks, 0, false, WithBatchSize(2))
+	batches := startIterator(batchIterator)
+
+	require.NoError(t, hooks.OnNewBlock(params.TestChainConfig, blocks[5]))
+
+	batch := nextBatch(t, batches)
+	require.NoError(t, batch.Error)
+	require.Len(t, batch.Batch.Blocks, 2)
+	require.Equal(t, uint64(1), batch.Batch.Blocks[0].Number)
+	require.Equal(t, uint64(2), tracker.Status().LastBlock)
+
+	// The next head picks up where the batch stopped
+	require.NoError(t, hooks.OnNewBlock(params.TestChainConfig, blocks[5]))
+
+	batch = nextBatch(t, batches)
+	require.NoError(t, batch.Error)
+	require.Len(t, batch.Batch.Blocks, 2)
+	require.Equal(t, uint64(3), batch.Batch.Blocks[0].Number)
+}
+
+func TestChainBatchIterator_MissingBlock(t *testing.T) {
+	for name, remove := range map[string]func(db ethdb.KeyValueWriter, block *types.Block){
+		"body": func(db ethdb.KeyValueWriter, block *types.Block) {
+			rawdb.DeleteBody(db, block.Hash(), block.NumberU64())
+		},
+		"receipts": func(db ethdb.KeyValueWriter, block *types.Block) {
+			rawdb.DeleteReceipts(db, block.Hash(), block.NumberU64())
+		},
+		"canonical hash": func(db ethdb.KeyValueWriter, block *types.Block) {
+			rawdb.DeleteCanonicalHash(db, block.NumberU64())
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			db, blocks := newPrunedDB(t, 5, 0)
+			remove(db, blocks[3])
+
+			hooks := NewHooks(db, 0)
+			batchIterator, tracker := NewChainBatchIterator(db, hooks, 0, false)
+			batches := startIterator(batchIterator)
+
+			require.NoError(t, hooks.OnNewBlock(params.TestChainConfig, blocks[5]))
+
+			// Blocks 1 and 2 aren't yielded without block 3
+			batch := nextBatch(t, batches)
+			require.ErrorIs(t, batch.Error, ErrIncompleteBatch)
+			require.ErrorContains(t, batch.Error, "block 3")
+			require.Empty(t, batch.Batch.Blocks)
+
+			status := tracker.Status()
+			require.True(t, status.Stalled)
+			require.Equal(t, uint64(0), status.LastBlock)
+		})
+	}
+}
+
+func TestChainBatchIterator_Reorg(t *testing.T) {
+	db, blocks := newPrunedDB(t, 3, 0)
+
+	// Block 2 of another chain became canonical while block 3 still builds on ours
+	header := types.CopyHeader(blocks[2].Header())
+	header.Extra = []byte("reorg")
+	reorged := types.NewBlockWithHeader(header)
+	rawdb.WriteBlock(db, reorged)
+	rawdb.WriteReceipts(db, reorged.Hash(), 2, types.Receipts{})
+	rawdb.WriteCanonicalHash(db, reorged.Hash(), 2)
+
+	hooks := NewHooks(db, 0)
+	batchIterator, _ := NewChainBatchIterator(db, hooks, 0, false)
+	batches := startIterator(batchIterator)
+
+	require.NoError(t, hooks.OnNewBlock(params.TestChainConfig, blocks[3]))
+
+	batch := nextBatch(t, batches)
+	require.ErrorIs(t, batch.Error, ErrIncompleteBatch)
+	require.ErrorContains(t, batch.Error, "block 3")
+}
+
+func TestChainBatchIterator_MalformedReceipt(t *testing.T) {
+	db, blocks := newPrunedDB(t, 0, 0)
+
+	// The receipt of block 1 misses the log of its second create
+	block, created := createBlock(t, 1)
+	header := block.Header()
+	header.ParentHash = blocks[0].Hash()
+	header.Difficulty = big.NewInt(0)
+	block = types.NewBlockWithHeader(header).WithBody(*block.Body())
+	rawdb.WriteBlock(db, block)
+	rawdb.WriteCanonicalHash(db, block.Hash(), 1)
+	rawdb.WriteReceipts(db, block.Hash(), 1, types.Receipts{
+		{Status: types.ReceiptStatusSuccessful, Logs: created[:1]},
+	})
+
+	hooks := NewHooks(db, 0)
+	batchIterator, tracker := NewChainBatchIterator(db, hooks, 0, false)
+	batches := startIterator(batchIterator)
+
+	require.NoError(t, hooks.OnNewBlock(params.TestChainConfig, block))
+
+	// The iterator halts on the error instead of panicking
+	batch := nextBatch(t, batches)
+	require.ErrorIs(t, batch.Error, ErrCreatedEntityLogs)
+	require.ErrorContains(t, batch.Error, "block 1")
+	require.True(t, tracker.Status().Stalled)
+}
+
+func TestChainBatchIterator_BlockData(t *testing.T) {
+	db, blocks := newPrunedDB(t, 0, 0)
+
+	block, created := createBlock(t, 1)
+	header := block.Header()
+	header.ParentHash = blocks[0].Hash()
+	header.Difficulty = big.NewInt(0)
+	header.Time = 12
+	block = types.NewBlockWithHeader(header).WithBody(*block.Body())
+	rawdb.WriteBlock(db, block)
+	rawdb.WriteCanonicalHash(db, block.Hash(), 1)
+	rawdb.WriteReceipts(db, block.Hash(), 1, types.Receipts{
+		{Status: types.ReceiptStatusSuccessful, Logs: created},
+	})
+
+	hooks := NewHooks(db, 0)
+	batchIterator, _ := NewChainBatchIterator(db, hooks, 0, false)
+	batches := startIterator(batchIterator)
+
+	require.NoError(t, hooks.OnNewBlock(params.TestChainConfig, block))
+
+	batch := nextBatch(t, batches)
+	require.NoError(t, batch.Error)
+	require.Len(t, batch.Batch.Blocks, 1)
+	got := batch.Batch.Blocks[0]
+	require.Equal(t, uint64(1), got.Number)
+	require.Equal(t, block.Hash(), got.Hash)
+	require.Equal(t, blocks[0].Hash(), got.ParentHash)
+	require.Equal(t, uint64(12), got.Time)
+	require.Equal(t, []common.Hash{block.Transactions()[0].Hash()}, got.TxHashes)
+	require.Len(t, got.Operations, 2)
+	for _, operation := range got.Operations {
+		require.Equal(t, block.Transactions()[0].Hash(), got.TxHash(operation))
+	}
+}
+
+func TestBatchIterator_Events(t *testing.T) {
+	decoded := events.Block{Number: 3, Operations: []events.Operation{{TxIndex: 0}}}
+	iterator := BatchIterator(func(yield func(BatchOrError) bool) {
+		yield(BatchOrError{Batch: BlockBatch{Blocks: []Block{{Block: decoded, Hash: common.HexToHash("0x3")}}}})
+	})
+
+	var batches []arkivevents.BatchOrError
+	for batch := range iterator.Events() {
+		batches = append(batches, batch)
+	}
+	require.Equal(t, []arkivevents.BatchOrError{{Batch: events.BlockBatch{Blocks: []events.Block{decoded}}}}, batches)
+}
+
+func TestChainBatchIterator_Checkpoint(t *testing.T) {
+	db, blocks := newPrunedDB(t, 5, 0)
+
+	hooks := NewHooks(db, 0)
+	batchIterator, _ := NewChainBatchIteratorFromCheckpoint(db, hooks, false, WithBatchSize(2))
+	consumed := make(chan *rawdb.ArkivEventsCheckpoint)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for batch := range batchIterator {
+			// The consumer stops in the middle of the second batch
+			if batch.Error != nil || batch.Batch.Blocks[0].Number == 3 {
+				return
+			}
+			// The checkpoint isn't written before the consumer is done with the batch
+			consumed <- rawdb.ReadArkivEventsCheckpoint(db)
+		}
+	}()
+
+	require.NoError(t, hooks.OnNewBlock(params.TestChainConfig, blocks[5]))
+	select {
+	case checkpoint := <-consumed:
+		require.Nil(t, checkpoint)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a batch")
+	}
+	require.NoError(t, hooks.OnNewBlock(params.TestChainConfig, blocks[5]))
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the consumer to stop")
+	}
+	require.Equal(t, &rawdb.ArkivEventsCheckpoint{Number: 2, Hash: blocks[2].Hash()}, rawdb.ReadArkivEventsCheckpoint(db))
+
+	// On restart the batch the consumer didn't finish is replayed
+	hooks = NewHooks(db, 0)
+	batchIterator, tracker := NewChainBatchIteratorFromCheckpoint(db, hooks, false, WithBatchSize(2))
+	require.Equal(t, uint64(2), tracker.Status().LastBlock)
+	batches := startIterator(batchIterator)
+
+	require.NoError(t, hooks.OnNewBlock(params.TestChainConfig, blocks[5]))
+	batch := nextBatch(t, batches)
+	require.NoError(t, batch.Error)
+	require.Equal(t, uint64(3), batch.Batch.Blocks[0].Number)
+	require.Equal(t, uint64(4), batch.Batch.Blocks[1].Number)
+}
+
+func TestChainBatchIterator_Context(t *testing.T) {
+	db, _ := newPrunedDB(t, 3, 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	batchIterator, _ := NewChainBatchIterator(db, NewHooks(db, 0), 0, false, WithContext(ctx))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range batchIterator {
+			t.Error("unexpected batch")
+		}
+	}()
+
+	// The iterator waiting for a head ends
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the iterator didn't end")
+	}
+}
+
+// newArkivChainDB writes a canonical chain of the given length to a memory database,
+// every block after the genesis with a transaction creating two entities.
+func newArkivChainDB(t testing.TB, length uint64) (ethdb.Database, []*types.Block) {
+	t.Helper()
+
+	db := rawdb.NewMemoryDatabase()
+	genesis := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(0), Difficulty: big.NewInt(0)})
+	rawdb.WriteBlock(db, genesis)
+	rawdb.WriteCanonicalHash(db, genesis.Hash(), 0)
+	rawdb.WriteReceipts(db, genesis.Hash(), 0, types.Receipts{})
+	blocks := []*types.Block{genesis}
+
+	for number := uint64(1); number <= length; number++ {
+		block, created := createBlock(t, int64(number))
+		header := block.Header()
+		header.ParentHash = blocks[number-1].Hash()
+		header.Difficulty = big.NewInt(0)
+		block = types.NewBlockWithHeader(header).WithBody(*block.Body())
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteCanonicalHash(db, block.Hash(), number)
+		rawdb.WriteReceipts(db, block.Hash(), number, types.Receipts{
+			{Status: types.ReceiptStatusSuccessful, Logs: created},
+		})
+		blocks = append(blocks, block)
+	}
+	return db, blocks
+}
+
+func TestChainBatchIterator_Concurrency(t *testing.T) {
+	db, blocks := newArkivChainDB(t, 50)
+
+	for _, concurrency := range []int{1, 8} {
+		hooks := NewHooks(db, 0)
+		batchIterator, _ := NewChainBatchIterator(db, hooks, 0, false, WithBatchSize(7), WithConcurrency(concurrency))
+		batches := startIterator(batchIterator)
+
+		// The blocks come out in order whatever order they are read in
+		next := uint64(1)
+		for next <= 50 {
+			require.NoError(t, hooks.OnNewBlock(params.TestChainConfig, blocks[50]))
+			batch := nextBatch(t, batches)
+			require.NoError(t, batch.Error)
+			for _, block := range batch.Batch.Blocks {
+				require.Equal(t, next, block.Number, "concurrency %d", concurrency)
+				require.Equal(t, blocks[next].Hash(), block.Hash, "concurrency %d", concurrency)
+				require.Len(t, block.Operations, 2, "concurrency %d", concurrency)
+				next++
+			}
+		}
+	}
+}
+
+func TestChainBatchIterator_PrefetchedReorg(t *testing.T) {
+	db, blocks := newPrunedDB(t, 4, 0)
+
+	hooks := NewHooks(db, 0)
+	batchIterator, _ := NewChainBatchIterator(db, hooks, 0, false, WithBatchSize(2))
+	batches := make(chan BatchOrError)
+	reorged := make([]*types.Block, 0, 2)
+	go func() {
+		for batch := range batchIterator {
+			if len(reorged) == 0 {
+				// Blocks 3 and 4 of another chain become canonical while they are prefetched
+				parent := blocks[2].Hash()
+				for number := uint64(3); number <= 4; number++ {
+					header := types.CopyHeader(blocks[number].Header())
+					header.ParentHash = parent
+					header.Extra = []byte("reorg")
+					block := types.NewBlockWithHeader(header)
+					rawdb.WriteBlock(db, block)
+					rawdb.WriteReceipts(db, block.Hash(), number, types.Receipts{})
+					rawdb.WriteCanonicalHash(db, block.Hash(), number)
+					reorged = append(reorged, block)
+					parent = block.Hash()
+				}
+			}
+			batches <- batch
+		}
+	}()
+
+	require.NoError(t, hooks.OnNewBlock(params.TestChainConfig, blocks[4]))
+	batch := nextBatch(t, batches)
+	require.NoError(t, batch.Error)
+	require.Equal(t, uint64(2), batch.Batch.Blocks[1].Number)
+
+	// The next batch has the blocks of the canonical chain, not the prefetched ones
+	require.NoError(t, hooks.OnNewBlock(params.TestChainConfig, reorged[1]))
+	batch = nextBatch(t, batches)
+	require.NoError(t, batch.Error)
+	require.Len(t, batch.Batch.Blocks, 2)
+	require.Equal(t, reorged[0].Hash(), batch.Batch.Blocks[0].Hash)
+	require.Equal(t, reorged[1].Hash(), batch.Batch.Blocks[1].Hash)
+}
+
+// withConvert converts the blocks to events with convert instead of blockToEvents.
+func withConvert(convert blockConverter) ChainBatchIteratorOption {
+	return func(c *chainBatchIteratorConfig) {
+		c.convert = convert
+	}
+}
+
+func TestChainBatchIterator_RecoversPanic(t *testing.T) {
+	db, blocks := newArkivChainDB(t, 6)
+
+	// The conversion of block 3 panics once
+	var panicked atomic.Bool
+	convert := func(block *types.Block, receipts []*types.Receipt) (*events.Block, []UnknownOperations, error) {
+		if block.NumberU64() == 3 && panicked.CompareAndSwap(false, true) {
+			panic("conversion failed")
+		}
+		return blockToEvents(block, receipts)
+	}
+
+	hooks := NewHooks(db, 0)
+	batchIterator, tracker := NewChainBatchIteratorFromCheckpoint(db, hooks, false, WithBatchSize(2), withConvert(convert))
+	batches := startIterator(batchIterator)
+
+	require.NoError(t, hooks.OnNewBlock(params.TestChainConfig, blocks[6]))
+	batch := nextBatch(t, batches)
+	require.NoError(t, batch.Error)
+	require.Equal(t, uint64(1), batch.Batch.Blocks[0].Number)
+
+	// The iterator restarts from the checkpoint and reads the head again by itself
+	require.NoError(t, hooks.OnNewBlock(params.TestChainConfig, blocks[6]))
+	batch = nextBatch(t, batches)
+	require.NoError(t, batch.Error)
+	require.True(t, panicked.Load())
+	require.Len(t, batch.Batch.Blocks, 2)
+	require.Equal(t, uint64(3), batch.Batch.Blocks[0].Number)
+	require.Equal(t, blocks[3].Hash(), batch.Batch.Blocks[0].Hash)
+	require.Len(t, batch.Batch.Blocks[0].Operations, 2)
+
+	status := tracker.Status()
+	require.Equal(t, uint64(1), status.IteratorRestarts)
+	require.Equal(t, uint64(4), status.LastBlock)
+}
+
+// withRestartBackoff bounds the restarts of the iterator after a panic with delay,
+// maxDelay and maxRestarts instead of the defaults.
+func withRestartBackoff(delay, maxDelay time.Duration, maxRestarts int) ChainBatchIteratorOption {
+	return func(c *chainBatchIteratorConfig) {
+		c.restartDelay = delay
+		c.maxRestartDelay = maxDelay
+		c.maxRestarts = maxRestarts
+	}
+}
+
+func TestChainBatchIterator_HaltsOnRepeatedPanic(t *testing.T) {
+	db, blocks := newArkivChainDB(t, 6)
+
+	// The conversion of block 3 always panics
+	var panics atomic.Int64
+	convert := func(block *types.Block, receipts []*types.Receipt) (*events.Block, []UnknownOperations, error) {
+		if block.NumberU64() == 3 {
+			panics.Add(1)
+			panic("conversion failed")
+		}
+		return blockToEvents(block, receipts)
+	}
+
+	hooks := NewHooks(db, 0)
+	batchIterator, tracker := NewChainBatchIteratorFromCheckpoint(db, hooks, false, WithBatchSize(2), withConvert(convert), withRestartBackoff(time.Millisecond, 4*time.Millisecond, 3))
+	batches := startIterator(batchIterator)
+
+	require.NoError(t, hooks.OnNewBlock(params.TestChainConfig, blocks[6]))
+	batch := nextBatch(t, batches)
+	require.NoError(t, batch.Error)
+	require.Equal(t, uint64(2), batch.Batch.Blocks[1].Number)
+
+	// The iterator restarts from the checkpoint 3 times, then halts on the panic
+	require.NoError(t, hooks.OnNewBlock(params.TestChainConfig, blocks[6]))
+	batch = nextBatch(t, batches)
+	require.ErrorIs(t, batch.Error, ErrIteratorPanicked)
+	require.ErrorContains(t, batch.Error, "checkpoint 2")
+	require.ErrorContains(t, batch.Error, "conversion failed")
+	require.Equal(t, int64(4), panics.Load())
+
+	status := tracker.Status()
+	require.Equal(t, uint64(3), status.IteratorRestarts)
+	require.Equal(t, uint64(2), status.LastBlock)
+	require.True(t, status.Stalled)
+}
+
+func TestRestartBackoff(t *testing.T) {
+	for restarts, want := range []time.Duration{time.Second, time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second} {
+		require.Equal(t, want, restartBackoff(restarts, time.Second, 10*time.Second), "restarts %d", restarts)
+	}
+	require.Equal(t, iteratorMaxRestartDelay, restartBackoff(1000, iteratorRestartDelay, iteratorMaxRestartDelay))
+}
+
+func TestWatchdog(t *testing.T) {
+	db, blocks := newPrunedDB(t, 3, 0)
+
+	// The iterator is never read, like a deadlocked one
+	hooks := NewHooks(db, 0)
+	_, tracker := NewChainBatchIterator(db, hooks, 0, false)
+	require.False(t, tracker.Status().Heartbeat.IsZero())
+
+	watchdog := NewWatchdog(tracker, 3, time.Hour)
+	for _, block := range blocks[1:] {
+		require.False(t, watchdog.check(time.Now()))
+		require.NoError(t, hooks.OnNewBlock(params.TestChainConfig, block))
+	}
+	require.Equal(t, uint64(3), tracker.Status().HeadsSinceHeartbeat)
+	require.True(t, watchdog.check(time.Now()))
+
+	// The alert clears once the iterator makes progress
+	tracker.heartbeat()
+	require.False(t, watchdog.check(time.Now()))
+}
+
+func BenchmarkChainBatchIterator(b *testing.B) {
+	const length = 3000
+	db, blocks := newArkivChainDB(b, length)
+
+	for _, concurrency := range []int{1, 4, 8} {
+		b.Run(fmt.Sprintf("concurrency %d", concurrency), func(b *testing.B) {
+			for b.Loop() {
+				hooks := NewHooks(db, 0)
+				batchIterator, _ := NewChainBatchIterator(db, hooks, 0, false, WithConcurrency(concurrency))
+				if err := hooks.OnNewBlock(params.TestChainConfig, blocks[length]); err != nil {
+					b.Fatal(err)
+				}
+				for batch := range batchIterator {
+					if batch.Error != nil {
+						b.Fatal(batch.Error)
+					}
+					if batch.Batch.Blocks[len(batch.Batch.Blocks)-1].Number == length {
+						break
+					}
+					// A head per batch
+					if err := hooks.OnNewBlock(params.TestChainConfig, blocks[length]); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
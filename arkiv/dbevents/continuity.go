@@ -0,0 +1,71 @@
+package dbevents
+
+import (
+	"fmt"
+
+	arkivevents "github.com/Arkiv-Network/arkiv-events"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// BlockGapError is returned when a batch of events doesn't continue from the last
+// block ingested by the store.
+type BlockGapError struct {
+	Expected uint64
+	Received uint64
+}
+
+func (e *BlockGapError) Error() string {
+	return fmt.Sprintf(
+		"events of block %d received, expected block %d: rebuild the Arkiv store or move its checkpoint with arkiv_setEventsCheckpoint and restart",
+		e.Received,
+		e.Expected,
+	)
+}
+
+// VerifyContinuity returns an iterator yielding the batches of the iterator as long as
+// their blocks are contiguous and the first one follows the last block ingested, as
+// returned by lastBlock before every batch. The blocks skipped because their receipts
+// were pruned, as reported by the tracker, are not a gap. Otherwise the iterator yields
+// a BlockGapError and stops, which halts the ingestion.
+func VerifyContinuity(iterator arkivevents.BatchIterator, lastBlock func() (uint64, error), tracker *SyncStatusTracker) arkivevents.BatchIterator {
+	return func(yield func(arkivevents.BatchOrError) bool) {
+		for batch := range iterator {
+			if batch.Error == nil {
+				if err := verifyBatch(batch, lastBlock, tracker); err != nil {
+					log.Error("Arkiv events are not contiguous, halting the ingestion", "error", err)
+					tracker.update(func(status *SyncStatus) {
+						status.Stalled = true
+					})
+					yield(arkivevents.BatchOrError{Error: err})
+					return
+				}
+			}
+			if !yield(batch) {
+				return
+			}
+		}
+	}
+}
+
+func verifyBatch(batch arkivevents.BatchOrError, lastBlock func() (uint64, error), tracker *SyncStatusTracker) error {
+	blocks := batch.Batch.Blocks
+	if len(blocks) == 0 {
+		return nil
+	}
+	last, err := lastBlock()
+	if err != nil {
+		return fmt.Errorf("failed to get the last ingested block: %w", err)
+	}
+
+	expected := last + 1
+	if gap := tracker.Status().PrunedGap; gap != nil && gap.From == expected && gap.To+1 == blocks[0].Number {
+		expected = blocks[0].Number
+	}
+	for _, block := range blocks {
+		if block.Number != expected {
+			return &BlockGapError{Expected: expected, Received: block.Number}
+		}
+		expected++
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package dbevents
+
+import (
+	"testing"
+
+	arkivevents "github.com/Arkiv-Network/arkiv-events"
+	"github.com/Arkiv-Network/arkiv-events/events"
+	"github.com/stretchr/testify/require"
+)
+
+func batchOf(numbers ...uint64) arkivevents.BatchOrError {
+	batch := arkivevents.BatchOrError{}
+	for _, number := range numbers {
+		batch.Batch.Blocks = append(batch.Batch.Blocks, events.Block{Number: number})
+	}
+	return batch
+}
+
+// verifyBatches runs the batches through VerifyContinuity, the store ingesting every
+// batch it yields, and returns them.
+func verifyBatches(tracker *SyncStatusTracker, last uint64, batches ...arkivevents.BatchOrError) []arkivevents.BatchOrError {
+	iterator := func(yield func(arkivevents.BatchOrError) bool) {
+		for _, batch := range batches {
+			if !yield(batch) {
+				return
+			}
+		}
+	}
+	var yielded []arkivevents.BatchOrError
+	for batch := range VerifyContinuity(iterator, func() (uint64, error) { return last, nil }, tracker) {
+		yielded = append(yielded, batch)
+		if batch.Error == nil {
+			last = batch.Batch.Blocks[len(batch.Batch.Blocks)-1].Number
+		}
+	}
+	return yielded
+}
+
+func TestVerifyContinuity(t *testing.T) {
+	yielded := verifyBatches(&SyncStatusTracker{}, 4, batchOf(5, 6), batchOf(7))
+	require.Equal(t, []arkivevents.BatchOrError{batchOf(5, 6), batchOf(7)}, yielded)
+}
+
+func TestVerifyContinuity_GapBetweenBatches(t *testing.T) {
+	tracker := &SyncStatusTracker{}
+	yielded := verifyBatches(tracker, 4, batchOf(5, 6), batchOf(9, 10), batchOf(11))
+
+	// The ingestion halts at the gap
+	require.Len(t, yielded, 2)
+	require.Equal(t, batchOf(5, 6), yielded[0])
+	var gapErr *BlockGapError
+	require.ErrorAs(t, yielded[1].Error, &gapErr)
+	require.Equal(t, &BlockGapError{Expected: 7, Received: 9}, gapErr)
+	require.ErrorContains(t, gapErr, "events of block 9 received, expected block 7")
+	require.ErrorContains(t, gapErr, "arkiv_setEventsCheckpoint")
+	require.True(t, tracker.Status().Stalled)
+}
+
+func TestVerifyContinuity_GapWithinBatch(t *testing.T) {
+	yielded := verifyBatches(&SyncStatusTracker{}, 4, batchOf(5, 7))
+
+	require.Len(t, yielded, 1)
+	require.Equal(t, &BlockGapError{Expected: 6, Received: 7}, yielded[0].Error)
+}
+
+func TestVerifyContinuity_Replay(t *testing.T) {
+	// A batch starting before the checkpoint, after it was moved forward
+	yielded := verifyBatches(&SyncStatusTracker{}, 10, batchOf(5, 6))
+
+	require.Len(t, yielded, 1)
+	require.Equal(t, &BlockGapError{Expected: 11, Received: 5}, yielded[0].Error)
+}
+
+func TestVerifyContinuity_PrunedGap(t *testing.T) {
+	// The blocks skipped with --arkiv.skip-pruned are not a gap
+	tracker := &SyncStatusTracker{status: SyncStatus{PrunedGap: &PrunedGap{From: 1, To: 4}}}
+	yielded := verifyBatches(tracker, 0, batchOf(5, 6), batchOf(7))
+
+	require.Equal(t, []arkivevents.BatchOrError{batchOf(5, 6), batchOf(7)}, yielded)
+	require.False(t, tracker.Status().Stalled)
+}
@@ -0,0 +1,206 @@
+package dbevents
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	arkivevents "github.com/Arkiv-Network/arkiv-events"
+	"github.com/Arkiv-Network/arkiv-events/events"
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+const (
+	// DefaultContentResolveAttempts is the default number of times the payload of an
+	// entity stored off chain is fetched before it's left pending.
+	DefaultContentResolveAttempts = 3
+
+	// DefaultContentResolveTimeout is the default time a fetch of a payload stored off
+	// chain can take.
+	DefaultContentResolveTimeout = 10 * time.Second
+)
+
+var (
+	// resolvedContentCounter counts the payloads stored off chain fetched and matching
+	// their commitment.
+	resolvedContentCounter = metrics.NewRegisteredCounter("arkiv/content/resolved", nil)
+	// unresolvedContentCounter counts the payloads stored off chain that couldn't be
+	// fetched and were left pending.
+	unresolvedContentCounter = metrics.NewRegisteredCounter("arkiv/content/unresolved", nil)
+	// invalidContentCounter counts the payloads stored off chain not matching their
+	// commitment.
+	invalidContentCounter = metrics.NewRegisteredCounter("arkiv/content/invalid", nil)
+)
+
+// ContentResolver fetches the payload of an entity stored off chain.
+type ContentResolver interface {
+	Resolve(ctx context.Context, content *storagetx.ArkivExternalContent) ([]byte, error)
+}
+
+// HTTPContentResolver fetches the payloads stored off chain from a data availability
+// service at GET <base URL>/<pointer>. The pointer is escaped as a single path
+// segment, the resolver only ever fetches from the configured service.
+type HTTPContentResolver struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewHTTPContentResolver returns the resolver of the service at the base URL, given
+// the time a fetch can take.
+func NewHTTPContentResolver(baseURL string, timeout time.Duration) (*HTTPContentResolver, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid content resolver URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid content resolver URL %q: the scheme must be http or https", baseURL)
+	}
+	if timeout <= 0 {
+		timeout = DefaultContentResolveTimeout
+	}
+	return &HTTPContentResolver{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// Resolve fetches the payload of the content, reading one byte past its size at most
+// so that Verify rejects longer payloads.
+func (r *HTTPContentResolver) Resolve(ctx context.Context, content *storagetx.ArkivExternalContent) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.baseURL+"/"+url.PathEscape(content.Pointer), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("content service returned %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, int64(content.Size)+1))
+}
+
+// ResolveExternalContent fetches the payloads stored off chain of the creates and
+// updates of the batches with the resolver, before they're indexed. A payload matching
+// its commitment becomes the content of the operation and its status is
+// storagetx.ContentStatusResolved, a payload not matching it is dropped and its status
+// is storagetx.ContentStatusInvalid. A payload that can't be fetched after the attempts,
+// each taking timeout at most and waiting backoff between them, is left pending: the
+// ingestion never halts on the data availability service. The fetches stop with the
+// context, the iterator then ends like the chain batch iterator does rather than
+// yield the batch being resolved with its payloads pending. The operations are
+// copied, the batches of the iterator aren't modified.
+func ResolveExternalContent(ctx context.Context, iterator arkivevents.BatchIterator, resolver ContentResolver, attempts int, timeout time.Duration, backoff time.Duration) arkivevents.BatchIterator {
+	if attempts <= 0 {
+		attempts = DefaultContentResolveAttempts
+	}
+	if timeout <= 0 {
+		timeout = DefaultContentResolveTimeout
+	}
+	return func(yield func(arkivevents.BatchOrError) bool) {
+		for batch := range iterator {
+			if batch.Error == nil {
+				blocks, err := resolveBlocks(ctx, batch.Batch.Blocks, resolver, attempts, timeout, backoff)
+				if err != nil {
+					log.Debug("Stopped resolving Arkiv content stored off chain", "error", err)
+					return
+				}
+				batch.Batch.Blocks = blocks
+			}
+			if !yield(batch) {
+				return
+			}
+		}
+	}
+}
+
+func resolveBlocks(ctx context.Context, blocks []events.Block, resolver ContentResolver, attempts int, timeout time.Duration, backoff time.Duration) ([]events.Block, error) {
+	blocks = slices.Clone(blocks)
+	for i := range blocks {
+		block := &blocks[i]
+		block.Operations = slices.Clone(block.Operations)
+		for j := range block.Operations {
+			operation := &block.Operations[j]
+			switch {
+			case operation.Create != nil && isPendingContent(operation.Create.StringAttributes):
+				create := *operation.Create
+				content, attributes, err := resolveContent(ctx, resolver, attempts, timeout, backoff, block.Number, create.StringAttributes)
+				if err != nil {
+					return nil, err
+				}
+				create.Content, create.StringAttributes = content, attributes
+				operation.Create = &create
+			case operation.Update != nil && isPendingContent(operation.Update.StringAttributes):
+				update := *operation.Update
+				content, attributes, err := resolveContent(ctx, resolver, attempts, timeout, backoff, block.Number, update.StringAttributes)
+				if err != nil {
+					return nil, err
+				}
+				update.Content, update.StringAttributes = content, attributes
+				operation.Update = &update
+			}
+		}
+	}
+	return blocks, nil
+}
+
+func isPendingContent(attributes map[string]string) bool {
+	return attributes[storagetx.ContentStatusAttribute] == storagetx.ContentStatusPending
+}
+
+// resolveContent returns the content of the operation with the attributes and its
+// attributes with the status of the content, or the error of the context if it's done
+// before the content is resolved.
+func resolveContent(ctx context.Context, resolver ContentResolver, attempts int, timeout time.Duration, backoff time.Duration, number uint64, attributes map[string]string) ([]byte, map[string]string, error) {
+	content, _ := storagetx.ExternalContentFromAttributes(attributes)
+	attributes = maps.Clone(attributes)
+
+	var err error
+	for attempt := range attempts {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, nil, ctx.Err()
+			case <-time.After(backoff):
+			}
+		}
+		var payload []byte
+		if payload, err = resolveAttempt(ctx, resolver, timeout, content); err == nil {
+			err = content.Verify(payload)
+		}
+		switch {
+		case err == nil:
+			resolvedContentCounter.Inc(1)
+			attributes[storagetx.ContentStatusAttribute] = storagetx.ContentStatusResolved
+			return payload, attributes, nil
+		case errors.Is(err, storagetx.ErrContentMismatch):
+			log.Warn("Arkiv content stored off chain doesn't match its commitment", "number", number, "pointer", content.Pointer, "error", err)
+			invalidContentCounter.Inc(1)
+			attributes[storagetx.ContentStatusAttribute] = storagetx.ContentStatusInvalid
+			return nil, attributes, nil
+		case ctx.Err() != nil:
+			return nil, nil, ctx.Err()
+		}
+	}
+	log.Warn("Failed to fetch Arkiv content stored off chain, leaving it pending", "number", number, "pointer", content.Pointer, "attempts", attempts, "error", err)
+	unresolvedContentCounter.Inc(1)
+	return nil, attributes, nil
+}
+
+// resolveAttempt fetches the payload of the content once, giving up after timeout.
+func resolveAttempt(ctx context.Context, resolver ContentResolver, timeout time.Duration, content *storagetx.ArkivExternalContent) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return resolver.Resolve(ctx, content)
+}
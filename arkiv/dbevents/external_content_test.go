@@ -0,0 +1,184 @@
+package dbevents
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	arkivevents "github.com/Arkiv-Network/arkiv-events"
+	"github.com/Arkiv-Network/arkiv-events/events"
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func externalContent(payload []byte, pointer string) storagetx.ArkivExternalContent {
+	return storagetx.ArkivExternalContent{
+		Type:       storagetx.ContentCommitmentKeccak256,
+		Commitment: crypto.Keccak256Hash(payload),
+		Size:       uint64(len(payload)),
+		Pointer:    pointer,
+	}
+}
+
+func TestPendingTransactionToEvents_ExternalContent(t *testing.T) {
+	content := externalContent([]byte("stored off chain"), "blobs/1")
+	tx := arkivTx(t, &storagetx.ArkivTransaction{
+		Version: storagetx.TransactionVersionExternalContent,
+		Create: []storagetx.ArkivCreate{
+			{BTL: 10, ContentType: "text/plain", Payload: []byte("inline")},
+			{BTL: 10, ContentType: "text/plain", StringAnnotations: []storagetx.StringAnnotation{{Key: "kind", Value: "blob"}}},
+		},
+		ExternalContent: []storagetx.ArkivExternalContent{{Index: 1, Type: content.Type, Commitment: content.Commitment, Size: content.Size, Pointer: content.Pointer}},
+	})
+
+	operations, err := PendingTransactionToEvents(tx, 0, common.HexToAddress("0xa"), true, 7, nil)
+	require.NoError(t, err)
+	require.Len(t, operations, 2)
+	require.NotContains(t, operations[0].Create.StringAttributes, storagetx.ContentStatusAttribute)
+
+	// The external content is described by synthetic attributes, pending until resolved
+	attributes := operations[1].Create.StringAttributes
+	require.Empty(t, operations[1].Create.Content)
+	require.Equal(t, "blob", attributes["kind"])
+	require.Equal(t, storagetx.ContentStatusPending, attributes[storagetx.ContentStatusAttribute])
+	require.Equal(t, content.Commitment.Hex(), attributes[storagetx.ContentCommitmentAttribute])
+	require.Equal(t, "keccak256", attributes[storagetx.ContentCommitmentTypeAttribute])
+	require.Equal(t, "16", attributes[storagetx.ContentSizeAttribute])
+	require.Equal(t, "blobs/1", attributes[storagetx.ContentPointerAttribute])
+}
+
+func TestResolveExternalContent(t *testing.T) {
+	payloads := map[string][]byte{
+		"/blobs%2F1": []byte("stored off chain"),
+		"/blobs%2F2": []byte("stored off chain, corrupted"),
+	}
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		payload, ok := payloads[r.URL.EscapedPath()]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(payload)
+	}))
+	defer server.Close()
+
+	resolver, err := NewHTTPContentResolver(server.URL+"/", 0)
+	require.NoError(t, err)
+
+	resolved := externalContent([]byte("stored off chain"), "blobs/1")
+	corrupted := externalContent([]byte("stored off chain, as committed"), "blobs/2")
+	missing := externalContent([]byte("never stored"), "blobs/3")
+	block := events.Block{Number: 1, Operations: []events.Operation{
+		{Create: &events.OPCreate{Key: common.HexToHash("0x01"), StringAttributes: resolved.Attributes()}},
+		{Update: &events.OPUpdate{Key: common.HexToHash("0x02"), StringAttributes: corrupted.Attributes()}},
+		{Create: &events.OPCreate{Key: common.HexToHash("0x03"), StringAttributes: missing.Attributes()}},
+		{Create: &events.OPCreate{Key: common.HexToHash("0x04"), Content: []byte("inline")}},
+	}}
+	iterator := func(yield func(arkivevents.BatchOrError) bool) {
+		yield(arkivevents.BatchOrError{Batch: events.BlockBatch{Blocks: []events.Block{block}}})
+	}
+
+	var yielded []arkivevents.BatchOrError
+	for batch := range ResolveExternalContent(context.Background(), iterator, resolver, 2, 0, 0) {
+		yielded = append(yielded, batch)
+	}
+	require.Len(t, yielded, 1)
+	operations := yielded[0].Batch.Blocks[0].Operations
+	require.Len(t, operations, 4)
+
+	require.Equal(t, []byte("stored off chain"), operations[0].Create.Content)
+	require.Equal(t, storagetx.ContentStatusResolved, operations[0].Create.StringAttributes[storagetx.ContentStatusAttribute])
+
+	// The content not matching its commitment is dropped, and isn't fetched again
+	require.Empty(t, operations[1].Update.Content)
+	require.Equal(t, storagetx.ContentStatusInvalid, operations[1].Update.StringAttributes[storagetx.ContentStatusAttribute])
+
+	// The content that can't be fetched stays pending after the attempts
+	require.Empty(t, operations[2].Create.Content)
+	require.Equal(t, storagetx.ContentStatusPending, operations[2].Create.StringAttributes[storagetx.ContentStatusAttribute])
+	require.Equal(t, int32(4), requests.Load())
+
+	require.Equal(t, block.Operations[3], operations[3])
+
+	// The operations of the iterator are left as they are
+	require.Empty(t, block.Operations[0].Create.Content)
+	require.Equal(t, storagetx.ContentStatusPending, block.Operations[0].Create.StringAttributes[storagetx.ContentStatusAttribute])
+}
+
+// blockingResolver is a resolver whose fetches only return when their context is done.
+type blockingResolver struct {
+	calls atomic.Int32
+}
+
+func (r *blockingResolver) Resolve(ctx context.Context, content *storagetx.ArkivExternalContent) ([]byte, error) {
+	r.calls.Add(1)
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestResolveExternalContent_AttemptTimeout(t *testing.T) {
+	missing := externalContent([]byte("never stored"), "blobs/1")
+	block := events.Block{Number: 1, Operations: []events.Operation{
+		{Create: &events.OPCreate{Key: common.HexToHash("0x01"), StringAttributes: missing.Attributes()}},
+	}}
+	iterator := func(yield func(arkivevents.BatchOrError) bool) {
+		yield(arkivevents.BatchOrError{Batch: events.BlockBatch{Blocks: []events.Block{block}}})
+	}
+
+	// Every attempt gives up after the timeout, the content is left pending
+	resolver := &blockingResolver{}
+	var yielded []arkivevents.BatchOrError
+	for batch := range ResolveExternalContent(context.Background(), iterator, resolver, 2, 10*time.Millisecond, 0) {
+		yielded = append(yielded, batch)
+	}
+	require.Len(t, yielded, 1)
+	require.NoError(t, yielded[0].Error)
+	require.Equal(t, storagetx.ContentStatusPending, yielded[0].Batch.Blocks[0].Operations[0].Create.StringAttributes[storagetx.ContentStatusAttribute])
+	require.Equal(t, int32(2), resolver.calls.Load())
+}
+
+func TestResolveExternalContent_Canceled(t *testing.T) {
+	missing := externalContent([]byte("never stored"), "blobs/1")
+	block := events.Block{Number: 1, Operations: []events.Operation{
+		{Create: &events.OPCreate{Key: common.HexToHash("0x01"), StringAttributes: missing.Attributes()}},
+	}}
+	iterator := func(yield func(arkivevents.BatchOrError) bool) {
+		for range 2 {
+			if !yield(arkivevents.BatchOrError{Batch: events.BlockBatch{Blocks: []events.Block{block}}}) {
+				return
+			}
+		}
+	}
+
+	// The cancellation interrupts the fetch without waiting for the timeout or the
+	// backoff, the iterator ends without yielding the batch with its content pending
+	ctx, cancel := context.WithCancel(context.Background())
+	resolver := &blockingResolver{}
+	go func() {
+		for resolver.calls.Load() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		cancel()
+	}()
+
+	var yielded []arkivevents.BatchOrError
+	start := time.Now()
+	for batch := range ResolveExternalContent(ctx, iterator, resolver, 3, time.Hour, time.Hour) {
+		yielded = append(yielded, batch)
+	}
+	require.Less(t, time.Since(start), time.Minute)
+	require.Empty(t, yielded)
+	require.Equal(t, int32(1), resolver.calls.Load())
+}
+
+func TestNewHTTPContentResolver(t *testing.T) {
+	_, err := NewHTTPContentResolver("file:///tmp", 0)
+	require.ErrorContains(t, err, "the scheme must be http or https")
+}
@@ -0,0 +1,109 @@
+package dbevents
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	arkivevents "github.com/Arkiv-Network/arkiv-events"
+	"github.com/Arkiv-Network/arkiv-events/events"
+	"github.com/ethereum/go-ethereum/arkiv/address"
+	"github.com/ethereum/go-ethereum/arkiv/genesis"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// GenesisBlock returns the creates of the Arkiv entities seeded by the genesis
+// allocation at block 0, after checking the genesis manifest against the allocation.
+// It returns nil if the database has no genesis state specification to check, which
+// is only allowed without a manifest.
+func GenesisBlock(db ethdb.Database) (*events.Block, error) {
+	hash := rawdb.ReadCanonicalHash(db, 0)
+	if hash == (common.Hash{}) {
+		return nil, errors.New("genesis block not found")
+	}
+
+	var manifest *genesis.Manifest
+	if blob := rawdb.ReadArkivGenesisManifest(db, hash); blob != nil {
+		manifest = &genesis.Manifest{}
+		if err := json.Unmarshal(blob, manifest); err != nil {
+			return nil, fmt.Errorf("failed to decode Arkiv genesis manifest: %w", err)
+		}
+	}
+
+	spec := rawdb.ReadGenesisStateSpec(db, hash)
+	if spec == nil {
+		if manifest != nil {
+			return nil, errors.New("genesis state specification not found to check the Arkiv genesis manifest")
+		}
+		log.Warn("Genesis state specification not found, Arkiv entities seeded by the genesis can't be indexed")
+		return nil, nil
+	}
+	var alloc types.GenesisAlloc
+	if err := json.Unmarshal(spec, &alloc); err != nil {
+		return nil, fmt.Errorf("failed to decode genesis state specification: %w", err)
+	}
+	if err := manifest.Validate(alloc[address.ArkivProcessorAddress].Storage); err != nil {
+		return nil, err
+	}
+	return manifest.Block(), nil
+}
+
+// WithGenesis returns an iterator yielding the batches of the iterator, with the
+// creates of the genesis block, as returned by genesis when the first batch arrives,
+// ahead of the operations of block 1. The store takes block 0 for its empty state and
+// skips its events, so the entities of the genesis are indexed with block 1, their BTL
+// shortened to keep their expiration block. If genesis fails, or the first batch
+// doesn't start at block 1, the iterator yields the error and stops, which halts the
+// ingestion.
+func WithGenesis(iterator arkivevents.BatchIterator, genesis func() (*events.Block, error)) arkivevents.BatchIterator {
+	return func(yield func(arkivevents.BatchOrError) bool) {
+		pending := true
+		for batch := range iterator {
+			if pending && batch.Error == nil && len(batch.Batch.Blocks) > 0 {
+				pending = false
+				if err := prependGenesis(&batch.Batch.Blocks[0], genesis); err != nil {
+					log.Error("Failed to index the Arkiv entities of the genesis, halting the ingestion", "error", err)
+					yield(arkivevents.BatchOrError{Error: err})
+					return
+				}
+			}
+			if !yield(batch) {
+				return
+			}
+		}
+	}
+}
+
+func prependGenesis(first *events.Block, genesis func() (*events.Block, error)) error {
+	block, err := genesis()
+	if err != nil {
+		return err
+	}
+	if block == nil || len(block.Operations) == 0 {
+		return nil
+	}
+	if first.Number != 1 {
+		return fmt.Errorf("events of block %d received before the Arkiv entities of the genesis", first.Number)
+	}
+
+	first.Operations = append(GenesisOperations(block), first.Operations...)
+	log.Info("Indexing the Arkiv entities of the genesis", "entities", len(block.Operations))
+	return nil
+}
+
+// GenesisOperations returns the creates of the genesis block the way the store indexes
+// them with block 1, their BTL shortened to keep their expiration block.
+func GenesisOperations(block *events.Block) []events.Operation {
+	operations := make([]events.Operation, 0, len(block.Operations))
+	for _, operation := range block.Operations {
+		create := *operation.Create
+		create.BTL--
+		operation.Create = &create
+		operations = append(operations, operation)
+	}
+	return operations
+}
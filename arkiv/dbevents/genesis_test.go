@@ -0,0 +1,120 @@
+package dbevents
+
+import (
+	"errors"
+	"testing"
+
+	arkivevents "github.com/Arkiv-Network/arkiv-events"
+	"github.com/Arkiv-Network/arkiv-events/events"
+	"github.com/ethereum/go-ethereum/arkiv/address"
+	"github.com/ethereum/go-ethereum/arkiv/genesis"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/triedb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenesisBlock(t *testing.T) {
+	manifest := &genesis.Manifest{Entities: []genesis.Entity{{
+		Key:            common.HexToHash("0x01"),
+		Owner:          common.HexToAddress("0xaa"),
+		ExpiresAtBlock: 10,
+		ContentType:    "text/plain",
+		Payload:        []byte("hello"),
+	}}}
+	storage, err := manifest.Storage()
+	require.NoError(t, err)
+
+	db := rawdb.NewMemoryDatabase()
+	gspec := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc:  types.GenesisAlloc{address.ArkivProcessorAddress: {Balance: common.Big0, Storage: storage}},
+		Arkiv:  manifest,
+	}
+	_, err = gspec.Commit(db, triedb.NewDatabase(db, nil))
+	require.NoError(t, err)
+
+	block, err := GenesisBlock(db)
+	require.NoError(t, err)
+	require.Equal(t, manifest.Block(), block)
+
+	// The allocation doesn't match the manifest
+	gspec.Arkiv = &genesis.Manifest{}
+	_, err = gspec.Commit(rawdb.NewMemoryDatabase(), triedb.NewDatabase(rawdb.NewMemoryDatabase(), nil))
+	require.ErrorIs(t, err, genesis.ErrUndeclaredEntities)
+
+	// Entities seeded without a manifest can't be indexed
+	db = rawdb.NewMemoryDatabase()
+	gspec.Arkiv = nil
+	_, err = gspec.Commit(db, triedb.NewDatabase(db, nil))
+	require.NoError(t, err)
+	_, err = GenesisBlock(db)
+	require.ErrorIs(t, err, genesis.ErrUndeclaredEntities)
+}
+
+func TestWithGenesis(t *testing.T) {
+	genesisBlock := &events.Block{Operations: []events.Operation{
+		{Create: &events.OPCreate{Key: common.HexToHash("0x01"), BTL: 10}},
+	}}
+	block1 := events.Block{Number: 1, Operations: []events.Operation{
+		{TxIndex: 1, Create: &events.OPCreate{Key: common.HexToHash("0x02"), BTL: 5}},
+	}}
+	iterator := func(yield func(arkivevents.BatchOrError) bool) {
+		batches := []arkivevents.BatchOrError{
+			{Batch: events.BlockBatch{Blocks: []events.Block{block1, {Number: 2}}}},
+			batchOf(3),
+		}
+		for _, batch := range batches {
+			if !yield(batch) {
+				return
+			}
+		}
+	}
+
+	var yielded []arkivevents.BatchOrError
+	for batch := range WithGenesis(iterator, func() (*events.Block, error) { return genesisBlock, nil }) {
+		yielded = append(yielded, batch)
+	}
+
+	require.Len(t, yielded, 2)
+	first := yielded[0].Batch.Blocks[0]
+	require.Len(t, first.Operations, 2)
+	// The genesis entity still expires at block 10
+	require.Equal(t, common.HexToHash("0x01"), first.Operations[0].Create.Key)
+	require.Equal(t, uint64(9), first.Operations[0].Create.BTL)
+	require.Equal(t, uint64(10), genesisBlock.Operations[0].Create.BTL)
+	require.Equal(t, common.HexToHash("0x02"), first.Operations[1].Create.Key)
+	require.Equal(t, batchOf(3), yielded[1])
+}
+
+func TestWithGenesis_Halts(t *testing.T) {
+	iterator := func(yield func(arkivevents.BatchOrError) bool) {
+		yield(batchOf(5))
+	}
+	genesisBlock := &events.Block{Operations: []events.Operation{{Create: &events.OPCreate{BTL: 10}}}}
+
+	var yielded []arkivevents.BatchOrError
+	for batch := range WithGenesis(iterator, func() (*events.Block, error) { return genesisBlock, nil }) {
+		yielded = append(yielded, batch)
+	}
+	require.Len(t, yielded, 1)
+	require.ErrorContains(t, yielded[0].Error, "events of block 5 received before the Arkiv entities of the genesis")
+
+	failure := errors.New("invalid manifest")
+	yielded = nil
+	for batch := range WithGenesis(iterator, func() (*events.Block, error) { return nil, failure }) {
+		yielded = append(yielded, batch)
+	}
+	require.Len(t, yielded, 1)
+	require.ErrorIs(t, yielded[0].Error, failure)
+
+	// Nothing seeded, the blocks go through
+	yielded = nil
+	for batch := range WithGenesis(iterator, func() (*events.Block, error) { return &events.Block{}, nil }) {
+		yielded = append(yielded, batch)
+	}
+	require.Equal(t, []arkivevents.BatchOrError{batchOf(5)}, yielded)
+}
@@ -0,0 +1,186 @@
+package dbevents
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Arkiv-Network/arkiv-events/events"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// DefaultHookBudget is the default time the import of a block waits for the hooks.
+const DefaultHookBudget = 100 * time.Millisecond
+
+var (
+	// hookFailuresCounter counts the hook calls that returned an error or panicked.
+	hookFailuresCounter = metrics.NewRegisteredCounter("arkiv/hooks/failures", nil)
+	// hookTimeoutsCounter counts the hook calls that exceeded the budget.
+	hookTimeoutsCounter = metrics.NewRegisteredCounter("arkiv/hooks/timeouts", nil)
+	// hookSkippedCounter counts the blocks a hook missed because it was still busy
+	// with a previous block.
+	hookSkippedCounter = metrics.NewRegisteredCounter("arkiv/hooks/skipped", nil)
+)
+
+// BlockHook is called with a block and its Arkiv operations, ops is nil if the
+// operations of the block couldn't be decoded.
+type BlockHook func(block *types.Block, ops *events.Block) error
+
+type hook struct {
+	name string
+	fn   BlockHook
+	busy atomic.Bool
+}
+
+// Hooks calls the hooks registered on it when a block joins or leaves the canonical
+// chain, it implements core.BlockHooks.
+//
+// The hooks of a block run concurrently, and the import of the block waits for them
+// for the budget at most. A hook that fails, panics or exceeds the budget is logged
+// and counted, it never fails or stalls the import. A hook exceeding the budget keeps
+// running in the background and misses the blocks coming until it returns.
+type Hooks struct {
+	db     ethdb.Database
+	budget time.Duration
+
+	mu           sync.RWMutex
+	blockHooks   []*hook
+	removedHooks []*hook
+
+	chainConfig      atomic.Pointer[params.ChainConfig]
+	perKindOpIndexes atomic.Bool
+}
+
+// NewHooks returns the hooks of the chain in the database, given the budget of the
+// hooks of a block.
+func NewHooks(db ethdb.Database, budget time.Duration) *Hooks {
+	if budget <= 0 {
+		budget = DefaultHookBudget
+	}
+	return &Hooks{db: db, budget: budget}
+}
+
+// RegisterBlockHook registers a hook called after every block becoming canonical.
+func (h *Hooks) RegisterBlockHook(name string, fn BlockHook) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.blockHooks = append(h.blockHooks, &hook{name: name, fn: fn})
+}
+
+// RegisterReorgHook registers a hook called with every block a reorg removes from the
+// canonical chain, newest first, before the blocks of the new chain are added.
+func (h *Hooks) RegisterReorgHook(name string, fn BlockHook) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.removedHooks = append(h.removedHooks, &hook{name: name, fn: fn})
+}
+
+// SetPerKindOpIndexes makes the hooks receive the operations in the order and with the
+// numbering of the releases before the canonical order, see PerKindOpIndexes.
+func (h *Hooks) SetPerKindOpIndexes(enabled bool) {
+	h.perKindOpIndexes.Store(enabled)
+}
+
+// ChainConfig returns the chain config of the last call, nil before the first one.
+func (h *Hooks) ChainConfig() *params.ChainConfig {
+	return h.chainConfig.Load()
+}
+
+func (h *Hooks) OnNewBlock(chainConfig *params.ChainConfig, block *types.Block) error {
+	h.mu.RLock()
+	hooks := h.blockHooks
+	h.mu.RUnlock()
+	return h.call(chainConfig, block, hooks)
+}
+
+func (h *Hooks) OnRemovedBlock(chainConfig *params.ChainConfig, block *types.Block) error {
+	h.mu.RLock()
+	hooks := h.removedHooks
+	h.mu.RUnlock()
+	return h.call(chainConfig, block, hooks)
+}
+
+func (h *Hooks) call(chainConfig *params.ChainConfig, block *types.Block, hooks []*hook) error {
+	h.chainConfig.Store(chainConfig)
+	if len(hooks) == 0 {
+		return nil
+	}
+	ops := h.operations(chainConfig, block)
+
+	deadline := time.NewTimer(h.budget)
+	defer deadline.Stop()
+
+	done := make(chan *hook, len(hooks))
+	running := map[*hook]struct{}{}
+	for _, hk := range hooks {
+		if !hk.busy.CompareAndSwap(false, true) {
+			log.Warn("Arkiv hook still busy with a previous block, skipping block", "hook", hk.name, "number", block.NumberU64())
+			hookSkippedCounter.Inc(1)
+			continue
+		}
+		running[hk] = struct{}{}
+		go func() {
+			defer func() {
+				hk.busy.Store(false)
+				done <- hk
+			}()
+			if err := callHook(hk.fn, block, ops); err != nil {
+				log.Error("Arkiv hook failed", "hook", hk.name, "number", block.NumberU64(), "hash", block.Hash(), "error", err)
+				hookFailuresCounter.Inc(1)
+			}
+		}()
+	}
+
+	for len(running) > 0 {
+		select {
+		case hk := <-done:
+			delete(running, hk)
+		case <-deadline.C:
+			names := make([]string, 0, len(running))
+			for hk := range maps.Keys(running) {
+				names = append(names, hk.name)
+			}
+			slices.Sort(names)
+			log.Warn("Arkiv hooks exceeded their budget", "hooks", names, "number", block.NumberU64(), "budget", h.budget)
+			hookTimeoutsCounter.Inc(int64(len(running)))
+			return nil
+		}
+	}
+	return nil
+}
+
+// operations decodes the Arkiv operations of the block, nil if they can't be.
+func (h *Hooks) operations(chainConfig *params.ChainConfig, block *types.Block) *events.Block {
+	receipts := rawdb.ReadReceipts(h.db, block.Hash(), block.NumberU64(), block.Time(), chainConfig)
+	if receipts == nil && len(block.Transactions()) > 0 {
+		log.Warn("Arkiv hooks can't read the receipts of the block", "number", block.NumberU64(), "hash", block.Hash())
+		return nil
+	}
+	ops, _, err := blockToEvents(block, receipts)
+	if err != nil {
+		log.Warn("Arkiv hooks can't decode the block", "number", block.NumberU64(), "hash", block.Hash(), "error", err)
+		return nil
+	}
+	if h.perKindOpIndexes.Load() {
+		ops.Operations = perKindOperations(ops.Operations)
+	}
+	return ops
+}
+
+// callHook calls the hook, turning a panic into an error.
+func callHook(fn BlockHook, block *types.Block, ops *events.Block) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("hook panicked: %v", r)
+		}
+	}()
+	return fn(block, ops)
+}
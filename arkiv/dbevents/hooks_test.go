@@ -0,0 +1,106 @@
+package dbevents
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Arkiv-Network/arkiv-events/events"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHooks_RecoversFromPanic(t *testing.T) {
+	db, blocks := newPrunedDB(t, 2, 0)
+	hooks := NewHooks(db, time.Second)
+
+	var called atomic.Uint64
+	hooks.RegisterBlockHook("panics", func(*types.Block, *events.Block) error {
+		panic("boom")
+	})
+	hooks.RegisterBlockHook("fails", func(*types.Block, *events.Block) error {
+		return errors.New("failed")
+	})
+	hooks.RegisterBlockHook("works", func(block *types.Block, ops *events.Block) error {
+		require.NotNil(t, ops)
+		require.Equal(t, block.NumberU64(), ops.Number)
+		called.Add(1)
+		return nil
+	})
+
+	failures := hookFailuresCounter.Snapshot().Count()
+	for _, block := range blocks[1:] {
+		require.NoError(t, hooks.OnNewBlock(params.TestChainConfig, block))
+	}
+
+	require.Equal(t, uint64(2), called.Load())
+	require.Equal(t, failures+4, hookFailuresCounter.Snapshot().Count())
+}
+
+func TestHooks_EnforcesBudget(t *testing.T) {
+	db, blocks := newPrunedDB(t, 3, 0)
+	budget := 50 * time.Millisecond
+	hooks := NewHooks(db, budget)
+
+	release := make(chan struct{})
+	var slow, fast []uint64
+	hooks.RegisterBlockHook("slow", func(block *types.Block, _ *events.Block) error {
+		slow = append(slow, block.NumberU64())
+		if block.NumberU64() == 1 {
+			<-release
+		}
+		return nil
+	})
+	hooks.RegisterBlockHook("fast", func(block *types.Block, _ *events.Block) error {
+		fast = append(fast, block.NumberU64())
+		return nil
+	})
+
+	timeouts := hookTimeoutsCounter.Snapshot().Count()
+	skipped := hookSkippedCounter.Snapshot().Count()
+
+	// The slow hook doesn't hold the import of the block past the budget
+	start := time.Now()
+	require.NoError(t, hooks.OnNewBlock(params.TestChainConfig, blocks[1]))
+	require.Less(t, time.Since(start), budget+time.Second)
+	require.Equal(t, timeouts+1, hookTimeoutsCounter.Snapshot().Count())
+
+	// While it's busy, the slow hook misses the blocks and the others don't wait for it
+	start = time.Now()
+	require.NoError(t, hooks.OnNewBlock(params.TestChainConfig, blocks[2]))
+	require.Less(t, time.Since(start), budget)
+	require.Equal(t, skipped+1, hookSkippedCounter.Snapshot().Count())
+
+	close(release)
+	require.Eventually(t, func() bool {
+		return !hooks.blockHooks[0].busy.Load()
+	}, 5*time.Second, time.Millisecond)
+
+	require.NoError(t, hooks.OnNewBlock(params.TestChainConfig, blocks[3]))
+	require.Equal(t, []uint64{1, 3}, slow)
+	require.Equal(t, []uint64{1, 2, 3}, fast)
+}
+
+func TestHooks_Reorg(t *testing.T) {
+	db, blocks := newPrunedDB(t, 2, 0)
+	hooks := NewHooks(db, time.Second)
+
+	var added, removed []uint64
+	hooks.RegisterBlockHook("added", func(block *types.Block, _ *events.Block) error {
+		added = append(added, block.NumberU64())
+		return nil
+	})
+	hooks.RegisterReorgHook("removed", func(block *types.Block, _ *events.Block) error {
+		removed = append(removed, block.NumberU64())
+		return nil
+	})
+
+	require.NoError(t, hooks.OnNewBlock(params.TestChainConfig, blocks[1]))
+	require.NoError(t, hooks.OnRemovedBlock(params.TestChainConfig, blocks[2]))
+
+	require.Equal(t, []uint64{1}, added)
+	require.Equal(t, []uint64{2}, removed)
+	require.Equal(t, params.TestChainConfig, hooks.ChainConfig())
+}
@@ -0,0 +1,387 @@
+package dbevents
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	arkivevents "github.com/Arkiv-Network/arkiv-events"
+	"github.com/Arkiv-Network/arkiv-events/events"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+const (
+	// DefaultIngestRetries is the default number of retries of a batch the store
+	// failed to ingest before it's written to the dead-letter queue.
+	DefaultIngestRetries = 5
+	// DefaultIngestMinBackoff is the default delay before the first retry of a batch.
+	DefaultIngestMinBackoff = time.Second
+	// DefaultIngestMaxBackoff is the default upper bound of the delay between retries.
+	DefaultIngestMaxBackoff = time.Minute
+
+	deadLetterSuffix = ".json"
+)
+
+var (
+	ingestRetriesCounter = metrics.NewRegisteredCounter("arkiv/ingest/retries", nil)
+	// deadLetterGauge is the number of batches in the dead-letter queue.
+	deadLetterGauge = metrics.NewRegisteredGauge("arkiv/ingest/deadletter", nil)
+)
+
+var (
+	// ErrDeadLetterNotFound is returned when re-injecting a batch that isn't in the
+	// dead-letter queue.
+	ErrDeadLetterNotFound = errors.New("dead letter not found")
+	// ErrDeadLetterSkipped is returned when re-injecting a batch the ingestion skipped,
+	// the store is past its blocks.
+	ErrDeadLetterSkipped = errors.New("the batch was skipped, the store is past its blocks")
+	// ErrDeadLetterGap is returned when re-injecting a batch that doesn't continue from
+	// the last block ingested by the store.
+	ErrDeadLetterGap = errors.New("the batch doesn't continue from the last ingested block")
+)
+
+// IngestFunc ingests a batch in the store, atomically: a batch that failed leaves the
+// store unchanged, or ingested in part in a way a retry completes.
+type IngestFunc func(ctx context.Context, batch events.BlockBatch) error
+
+// IngesterConfig configures the retries and the dead-letter queue of an Ingester.
+type IngesterConfig struct {
+	// Dir is the directory the dead letters are written to, they are only kept in
+	// memory if it's empty.
+	Dir string
+	// Retries is the number of retries of a batch after its first attempt.
+	Retries int
+	// MinBackoff and MaxBackoff bound the delay between the attempts of a batch.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// SkipPoison advances the ingestion past a batch written to the dead-letter queue
+	// instead of halting it until the batch is re-injected.
+	SkipPoison bool
+}
+
+// withDefaults returns the config with the defaults applied to the unset fields.
+func (c IngesterConfig) withDefaults() IngesterConfig {
+	if c.Retries <= 0 {
+		c.Retries = DefaultIngestRetries
+	}
+	if c.MinBackoff <= 0 {
+		c.MinBackoff = DefaultIngestMinBackoff
+	}
+	if c.MaxBackoff < c.MinBackoff {
+		c.MaxBackoff = max(DefaultIngestMaxBackoff, c.MinBackoff)
+	}
+	return c
+}
+
+// DeadLetter is a batch the store failed to ingest, as written to the dead-letter
+// queue. The file is self-describing: it holds the events of the batch along with the
+// error of its last attempt.
+type DeadLetter struct {
+	// Name is the name of the file of the dead letter in the dead-letter directory.
+	Name      string    `json:"-"`
+	FromBlock uint64    `json:"fromBlock"`
+	ToBlock   uint64    `json:"toBlock"`
+	Error     string    `json:"error"`
+	Attempts  int       `json:"attempts"`
+	Time      time.Time `json:"time"`
+	// Skipped is set if the ingestion advanced past the batch, see
+	// IngesterConfig.SkipPoison.
+	Skipped bool              `json:"skipped"`
+	Batch   events.BlockBatch `json:"batch"`
+}
+
+// Ingester feeds the store with the batches of an iterator. A batch the store fails to
+// ingest is retried with exponential backoff, and written to the dead-letter queue once
+// the retries are exhausted. The ingestion then either halts until the batch is
+// re-injected, or skips it.
+type Ingester struct {
+	config    IngesterConfig
+	ingest    IngestFunc
+	lastBlock func() (uint64, error)
+	tracker   *SyncStatusTracker
+
+	mu sync.Mutex
+	// letters holds the dead letters if they are kept in memory.
+	letters map[string]*DeadLetter
+	// halted is the dead letter the ingestion waits for, resumed is closed once it's
+	// re-injected.
+	halted  string
+	resumed chan struct{}
+}
+
+// NewIngester returns an ingester of the batches in the store. The dead letters left in
+// the directory by a previous run count in the depth of the queue.
+func NewIngester(config IngesterConfig, ingest IngestFunc, lastBlock func() (uint64, error), tracker *SyncStatusTracker) (*Ingester, error) {
+	i := &Ingester{
+		config:    config.withDefaults(),
+		ingest:    ingest,
+		lastBlock: lastBlock,
+		tracker:   tracker,
+	}
+	if i.config.Dir == "" {
+		i.letters = make(map[string]*DeadLetter)
+	} else if err := os.MkdirAll(i.config.Dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create the dead-letter directory: %w", err)
+	}
+	if err := i.updateDepth(); err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+// Follow ingests the batches of the iterator until it ends, it yields an error or the
+// context is canceled.
+func (i *Ingester) Follow(ctx context.Context, iterator arkivevents.BatchIterator) error {
+	for batch := range iterator {
+		if batch.Error != nil {
+			return fmt.Errorf("failed to follow events: %w", batch.Error)
+		}
+		if len(batch.Batch.Blocks) == 0 {
+			continue
+		}
+		attempts, err := i.ingestWithRetries(ctx, batch.Batch)
+		if err == nil {
+			continue
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		letter := &DeadLetter{
+			FromBlock: batch.Batch.Blocks[0].Number,
+			ToBlock:   batch.Batch.Blocks[len(batch.Batch.Blocks)-1].Number,
+			Error:     err.Error(),
+			Attempts:  attempts,
+			Time:      time.Now().UTC(),
+			Skipped:   i.config.SkipPoison,
+			Batch:     batch.Batch,
+		}
+		if err := i.write(letter); err != nil {
+			return err
+		}
+
+		if i.config.SkipPoison {
+			log.Error("Arkiv failed to ingest a batch, skipping it, the store diverges from the chain",
+				"from", letter.FromBlock, "to", letter.ToBlock, "deadLetter", letter.Name, "err", err)
+			// The store records the last block of the empty blocks, moving past the batch
+			if err := i.ingest(ctx, emptyBlocks(batch.Batch)); err != nil {
+				return fmt.Errorf("failed to skip blocks %d-%d: %w", letter.FromBlock, letter.ToBlock, err)
+			}
+			continue
+		}
+
+		log.Error("Arkiv failed to ingest a batch, halting the ingestion until it's re-injected with arkiv_reinjectDeadLetter",
+			"from", letter.FromBlock, "to", letter.ToBlock, "deadLetter", letter.Name, "err", err)
+		if err := i.wait(ctx, letter.Name); err != nil {
+			return err
+		}
+		log.Info("Arkiv resumed the ingestion", "deadLetter", letter.Name)
+	}
+	return nil
+}
+
+// ingestWithRetries ingests the batch, retrying with exponential backoff, and returns
+// the number of attempts and the error of the last one.
+func (i *Ingester) ingestWithRetries(ctx context.Context, batch events.BlockBatch) (int, error) {
+	backoff := i.config.MinBackoff
+	for attempt := 1; ; attempt++ {
+		err := i.ingest(ctx, batch)
+		if err == nil || attempt > i.config.Retries {
+			return attempt, err
+		}
+		ingestRetriesCounter.Inc(1)
+		log.Warn("Arkiv failed to ingest a batch, retrying", "from", batch.Blocks[0].Number, "to", batch.Blocks[len(batch.Blocks)-1].Number,
+			"attempt", attempt, "backoff", backoff, "err", err)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return attempt, ctx.Err()
+		case <-timer.C:
+		}
+		backoff = min(backoff*2, i.config.MaxBackoff)
+	}
+}
+
+// wait halts the ingestion until the dead letter is re-injected.
+func (i *Ingester) wait(ctx context.Context, name string) error {
+	i.mu.Lock()
+	i.halted = name
+	i.resumed = make(chan struct{})
+	resumed := i.resumed
+	i.mu.Unlock()
+
+	i.tracker.update(func(status *SyncStatus) {
+		status.Stalled = true
+	})
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-resumed:
+	}
+	i.tracker.update(func(status *SyncStatus) {
+		status.Stalled = false
+	})
+	return nil
+}
+
+// Reinject ingests the batch of the dead letter once the issue that made the store
+// fail is fixed, and removes it from the queue. An ingestion halted on the batch
+// resumes. A batch the store has already ingested is only removed.
+func (i *Ingester) Reinject(ctx context.Context, name string) (*DeadLetter, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	letter, err := i.read(name)
+	if err != nil {
+		return nil, err
+	}
+	if letter.Skipped {
+		return nil, fmt.Errorf("%w: blocks %d-%d, rebuild the store to index them", ErrDeadLetterSkipped, letter.FromBlock, letter.ToBlock)
+	}
+	last, err := i.lastBlock()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last block from store: %w", err)
+	}
+	switch {
+	case last >= letter.ToBlock:
+		log.Info("Arkiv dead letter already ingested, removing it", "deadLetter", name, "lastBlock", last)
+	case last+1 != letter.FromBlock:
+		return nil, fmt.Errorf("%w: blocks %d-%d, last ingested block is %d", ErrDeadLetterGap, letter.FromBlock, letter.ToBlock, last)
+	default:
+		if err := i.ingest(ctx, letter.Batch); err != nil {
+			return nil, fmt.Errorf("failed to ingest blocks %d-%d: %w", letter.FromBlock, letter.ToBlock, err)
+		}
+		log.Info("Arkiv re-injected a dead letter", "deadLetter", name, "from", letter.FromBlock, "to", letter.ToBlock)
+	}
+
+	if err := i.remove(name); err != nil {
+		return nil, err
+	}
+	if i.halted == name {
+		close(i.resumed)
+		i.halted, i.resumed = "", nil
+	}
+	return letter, nil
+}
+
+// names returns the names of the dead letters.
+func (i *Ingester) names() ([]string, error) {
+	if i.letters != nil {
+		return slices.Collect(maps.Keys(i.letters)), nil
+	}
+	entries, err := os.ReadDir(i.config.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the dead-letter directory: %w", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), deadLetterSuffix) {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+// write adds the dead letter to the queue, naming it after its blocks.
+func (i *Ingester) write(letter *DeadLetter) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	letter.Name = fmt.Sprintf("blocks-%d-%d-%d%s", letter.FromBlock, letter.ToBlock, letter.Time.UnixNano(), deadLetterSuffix)
+	if i.letters != nil {
+		i.letters[letter.Name] = letter
+		return i.updateDepth()
+	}
+
+	data, err := json.MarshalIndent(letter, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode dead letter: %w", err)
+	}
+	path := filepath.Join(i.config.Dir, letter.Name)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write dead letter: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write dead letter: %w", err)
+	}
+	return i.updateDepth()
+}
+
+// read returns the dead letter with the name.
+func (i *Ingester) read(name string) (*DeadLetter, error) {
+	if name == "" || filepath.Base(name) != name || !strings.HasSuffix(name, deadLetterSuffix) {
+		return nil, fmt.Errorf("%w: %q", ErrDeadLetterNotFound, name)
+	}
+	if i.letters != nil {
+		letter, ok := i.letters[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrDeadLetterNotFound, name)
+		}
+		return letter, nil
+	}
+
+	data, err := os.ReadFile(filepath.Join(i.config.Dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrDeadLetterNotFound, name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dead letter: %w", err)
+	}
+	letter := &DeadLetter{Name: name}
+	if err := json.Unmarshal(data, letter); err != nil {
+		return nil, fmt.Errorf("failed to decode dead letter %s: %w", name, err)
+	}
+	return letter, nil
+}
+
+// remove removes the dead letter from the queue.
+func (i *Ingester) remove(name string) error {
+	if i.letters != nil {
+		delete(i.letters, name)
+	} else if err := os.Remove(filepath.Join(i.config.Dir, name)); err != nil {
+		return fmt.Errorf("failed to remove dead letter: %w", err)
+	}
+	return i.updateDepth()
+}
+
+// updateDepth reports the depth of the queue in the metrics and the sync status.
+func (i *Ingester) updateDepth() error {
+	names, err := i.names()
+	if err != nil {
+		return err
+	}
+	deadLetterGauge.Update(int64(len(names)))
+	i.tracker.update(func(status *SyncStatus) {
+		status.DeadLetterBatches = uint64(len(names))
+	})
+	return nil
+}
+
+// emptyBlocks returns the blocks of the batch without their operations.
+func emptyBlocks(batch events.BlockBatch) events.BlockBatch {
+	empty := events.BlockBatch{Blocks: make([]events.Block, len(batch.Blocks))}
+	for n, block := range batch.Blocks {
+		empty.Blocks[n] = events.Block{Number: block.Number, Operations: []events.Operation{}}
+	}
+	return empty
+}
+
+// SingleBatch returns an iterator yielding the batch only.
+func SingleBatch(batch events.BlockBatch) arkivevents.BatchIterator {
+	return func(yield func(arkivevents.BatchOrError) bool) {
+		yield(arkivevents.BatchOrError{Batch: batch})
+	}
+}
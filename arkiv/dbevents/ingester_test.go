@@ -0,0 +1,269 @@
+package dbevents
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	arkivevents "github.com/Arkiv-Network/arkiv-events"
+	"github.com/Arkiv-Network/arkiv-events/events"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeStore records the batches it ingests, failing the batches fail returns an error
+// for.
+type fakeStore struct {
+	mu      sync.Mutex
+	last    uint64
+	batches []events.BlockBatch
+	fail    func(batch events.BlockBatch) error
+}
+
+func (s *fakeStore) ingest(_ context.Context, batch events.BlockBatch) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fail != nil {
+		if err := s.fail(batch); err != nil {
+			return err
+		}
+	}
+	s.batches = append(s.batches, batch)
+	s.last = batch.Blocks[len(batch.Blocks)-1].Number
+	return nil
+}
+
+func (s *fakeStore) lastBlock() (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.last, nil
+}
+
+func (s *fakeStore) setFail(fail func(batch events.BlockBatch) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fail = fail
+}
+
+// ingested returns the block numbers of the batches ingested by the store.
+func (s *fakeStore) ingested() [][]uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var numbers [][]uint64
+	for _, batch := range s.batches {
+		var batchNumbers []uint64
+		for _, block := range batch.Blocks {
+			batchNumbers = append(batchNumbers, block.Number)
+		}
+		numbers = append(numbers, batchNumbers)
+	}
+	return numbers
+}
+
+func iteratorOf(batches ...arkivevents.BatchOrError) arkivevents.BatchIterator {
+	return func(yield func(arkivevents.BatchOrError) bool) {
+		for _, batch := range batches {
+			if !yield(batch) {
+				return
+			}
+		}
+	}
+}
+
+func newTestIngester(t *testing.T, store *fakeStore, tracker *SyncStatusTracker, dir string, skipPoison bool) *Ingester {
+	t.Helper()
+	ingester, err := NewIngester(IngesterConfig{
+		Dir:        dir,
+		Retries:    2,
+		MinBackoff: time.Millisecond,
+		MaxBackoff: 2 * time.Millisecond,
+		SkipPoison: skipPoison,
+	}, store.ingest, store.lastBlock, tracker)
+	require.NoError(t, err)
+	return ingester
+}
+
+// poisoned returns a batch of the block with an operation the store fails on.
+func poisoned(number uint64) arkivevents.BatchOrError {
+	batch := batchOf(number)
+	key := events.OPDelete{0x01}
+	batch.Batch.Blocks[0].Operations = []events.Operation{{Delete: &key}}
+	return batch
+}
+
+// failBlock fails the batches with operations in the block.
+func failBlock(number uint64, err error) func(batch events.BlockBatch) error {
+	return func(batch events.BlockBatch) error {
+		for _, block := range batch.Blocks {
+			if block.Number == number && len(block.Operations) > 0 {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func deadLetters(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+func TestIngester_TransientError(t *testing.T) {
+	// The store is locked for the first two attempts of the second batch
+	store := &fakeStore{last: 4}
+	failures := 0
+	store.fail = func(batch events.BlockBatch) error {
+		if batch.Blocks[0].Number == 7 && failures < 2 {
+			failures++
+			return errors.New("database is locked")
+		}
+		return nil
+	}
+	tracker := &SyncStatusTracker{}
+	dir := t.TempDir()
+	ingester := newTestIngester(t, store, tracker, dir, false)
+
+	err := ingester.Follow(context.Background(), iteratorOf(batchOf(5, 6), batchOf(7), batchOf(8)))
+	require.NoError(t, err)
+	require.Equal(t, [][]uint64{{5, 6}, {7}, {8}}, store.ingested())
+	require.Empty(t, deadLetters(t, dir))
+	require.Zero(t, tracker.Status().DeadLetterBatches)
+}
+
+func TestIngester_SkipPoison(t *testing.T) {
+	store := &fakeStore{last: 4}
+	store.fail = failBlock(7, errors.New("UNIQUE constraint failed"))
+	tracker := &SyncStatusTracker{}
+	dir := t.TempDir()
+	ingester := newTestIngester(t, store, tracker, dir, true)
+
+	err := ingester.Follow(context.Background(), iteratorOf(batchOf(5, 6), poisoned(7), batchOf(8)))
+	require.NoError(t, err)
+
+	// The store moved past the batch with an empty block
+	require.Equal(t, [][]uint64{{5, 6}, {7}, {8}}, store.ingested())
+	require.Empty(t, store.batches[1].Blocks[0].Operations)
+	require.Equal(t, uint64(1), tracker.Status().DeadLetterBatches)
+	require.False(t, tracker.Status().Stalled)
+
+	names := deadLetters(t, dir)
+	require.Len(t, names, 1)
+	data, err := os.ReadFile(filepath.Join(dir, names[0]))
+	require.NoError(t, err)
+	var letter DeadLetter
+	require.NoError(t, json.Unmarshal(data, &letter))
+	require.Equal(t, uint64(7), letter.FromBlock)
+	require.Equal(t, uint64(7), letter.ToBlock)
+	require.Equal(t, "UNIQUE constraint failed", letter.Error)
+	require.Equal(t, 3, letter.Attempts)
+	require.True(t, letter.Skipped)
+	require.Equal(t, poisoned(7).Batch, letter.Batch)
+
+	// The operations of a skipped batch are lost
+	_, err = ingester.Reinject(context.Background(), names[0])
+	require.ErrorIs(t, err, ErrDeadLetterSkipped)
+	require.Len(t, deadLetters(t, dir), 1)
+}
+
+func TestIngester_PermanentErrorHaltsUntilReinjected(t *testing.T) {
+	store := &fakeStore{last: 4}
+	store.setFail(failBlock(7, errors.New("database or disk is full")))
+	tracker := &SyncStatusTracker{}
+	dir := t.TempDir()
+	ingester := newTestIngester(t, store, tracker, dir, false)
+
+	done := make(chan error)
+	go func() {
+		done <- ingester.Follow(context.Background(), iteratorOf(batchOf(5, 6), poisoned(7), batchOf(8)))
+	}()
+
+	require.Eventually(t, func() bool {
+		status := tracker.Status()
+		return status.Stalled && status.DeadLetterBatches == 1
+	}, 5*time.Second, time.Millisecond)
+	require.Equal(t, [][]uint64{{5, 6}}, store.ingested())
+	names := deadLetters(t, dir)
+	require.Len(t, names, 1)
+
+	// The batch keeps failing until the disk is freed
+	_, err := ingester.Reinject(context.Background(), names[0])
+	require.ErrorContains(t, err, "database or disk is full")
+	require.True(t, tracker.Status().Stalled)
+
+	_, err = ingester.Reinject(context.Background(), "../"+names[0])
+	require.ErrorIs(t, err, ErrDeadLetterNotFound)
+
+	store.setFail(nil)
+	letter, err := ingester.Reinject(context.Background(), names[0])
+	require.NoError(t, err)
+	require.Equal(t, names[0], letter.Name)
+	require.Equal(t, uint64(7), letter.FromBlock)
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the ingestion didn't resume")
+	}
+	require.Equal(t, [][]uint64{{5, 6}, {7}, {8}}, store.ingested())
+	require.Empty(t, deadLetters(t, dir))
+	status := tracker.Status()
+	require.False(t, status.Stalled)
+	require.Zero(t, status.DeadLetterBatches)
+}
+
+func TestIngester_Restart(t *testing.T) {
+	store := &fakeStore{last: 4}
+	store.setFail(failBlock(5, errors.New("database or disk is full")))
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	ingester := newTestIngester(t, store, &SyncStatusTracker{}, dir, false)
+	done := make(chan error)
+	go func() {
+		done <- ingester.Follow(ctx, iteratorOf(poisoned(5)))
+	}()
+	require.Eventually(t, func() bool { return len(deadLetters(t, dir)) == 1 }, 5*time.Second, time.Millisecond)
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+	name := deadLetters(t, dir)[0]
+
+	// The dead letters of the previous run count in the depth of the queue
+	tracker := &SyncStatusTracker{}
+	ingester = newTestIngester(t, store, tracker, dir, false)
+	require.Equal(t, uint64(1), tracker.Status().DeadLetterBatches)
+
+	// A batch that doesn't continue from the store can't be re-injected
+	store.setFail(nil)
+	store.last = 2
+	_, err := ingester.Reinject(context.Background(), name)
+	require.ErrorIs(t, err, ErrDeadLetterGap)
+
+	// The restarted ingestion ingested the batch again, its dead letter is removed
+	store.last = 6
+	_, err = ingester.Reinject(context.Background(), name)
+	require.NoError(t, err)
+	require.Empty(t, store.ingested())
+	require.Empty(t, deadLetters(t, dir))
+	require.Zero(t, tracker.Status().DeadLetterBatches)
+}
+
+func TestIngester_InMemory(t *testing.T) {
+	store := &fakeStore{last: 4}
+	store.setFail(failBlock(5, errors.New("UNIQUE constraint failed")))
+	tracker := &SyncStatusTracker{}
+	ingester := newTestIngester(t, store, tracker, "", true)
+
+	require.NoError(t, ingester.Follow(context.Background(), iteratorOf(poisoned(5))))
+	require.Equal(t, uint64(1), tracker.Status().DeadLetterBatches)
+	require.Equal(t, [][]uint64{{5}}, store.ingested())
+}
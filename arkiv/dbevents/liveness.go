@@ -0,0 +1,140 @@
+package dbevents
+
+import (
+	"context"
+	"errors"
+	"runtime/debug"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+const (
+	// DefaultStuckHeads is the default number of heads the chain batch iterator can
+	// be notified about without making progress before the watchdog raises an alert.
+	DefaultStuckHeads = 32
+
+	// DefaultWatchdogInterval is the default interval between the checks of the
+	// watchdog.
+	DefaultWatchdogInterval = 10 * time.Second
+
+	// iteratorRestartDelay is the wait before the iterator restarts after a panic, a
+	// panic on every block doesn't spin. It doubles with every restart at the same
+	// checkpoint, up to iteratorMaxRestartDelay.
+	iteratorRestartDelay    = time.Second
+	iteratorMaxRestartDelay = time.Minute
+
+	// iteratorMaxRestarts is the number of restarts at the same checkpoint after which
+	// the iterator halts on the panic, it's deterministic and won't go away.
+	iteratorMaxRestarts = 8
+)
+
+// ErrIteratorPanicked is the error the chain batch iterator halts with once it
+// panicked iteratorMaxRestarts times in a row at the same checkpoint.
+var ErrIteratorPanicked = errors.New("events iterator keeps panicking")
+
+var (
+	// iteratorPanicsCounter counts the panics of the chain batch iterator recovered
+	// with a restart.
+	iteratorPanicsCounter = metrics.NewRegisteredCounter("arkiv/iterator/panics", nil)
+	// iteratorStuckGauge is 1 while the watchdog considers the iterator stuck.
+	iteratorStuckGauge = metrics.NewRegisteredGauge("arkiv/iterator/stuck", nil)
+	// iteratorHeartbeatAgeGauge is the age of the heartbeat of the iterator in
+	// milliseconds at the last check of the watchdog.
+	iteratorHeartbeatAgeGauge = metrics.NewRegisteredGauge("arkiv/iterator/heartbeat/age", nil)
+)
+
+// iteratorPanic is a panic recovered while the chain batch iterator read a batch, with
+// the stack of the goroutine that panicked.
+type iteratorPanic struct {
+	value any
+	stack []byte
+}
+
+// newIteratorPanic returns the recovered panic, a panic raised again with the one of a
+// prefetch keeps its stack.
+func newIteratorPanic(recovered any) *iteratorPanic {
+	if p, ok := recovered.(*iteratorPanic); ok {
+		return p
+	}
+	return &iteratorPanic{value: recovered, stack: debug.Stack()}
+}
+
+// restartBackoff returns the wait before the restart of the iterator after its
+// restarts-th panic in a row at the same checkpoint: delay doubled with every restart,
+// up to maxDelay.
+func restartBackoff(restarts int, delay, maxDelay time.Duration) time.Duration {
+	for i := 1; i < restarts && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxDelay)
+}
+
+// heartbeat records that the iterator made progress.
+func (t *SyncStatusTracker) heartbeat() {
+	t.update(func(status *SyncStatus) {
+		status.Heartbeat = time.Now()
+		status.HeadsSinceHeartbeat = 0
+	})
+}
+
+// Watchdog raises an alert when the chain batch iterator stops making progress while
+// heads keep arriving: its goroutine is deadlocked, or the consumer is stuck on a
+// batch. The new heads are notified whatever the iterator does, so the store would
+// otherwise fall behind silently. An iterator waiting for a head has nothing to do and
+// isn't stuck.
+type Watchdog struct {
+	tracker  *SyncStatusTracker
+	maxHeads uint64
+	interval time.Duration
+	stuck    bool
+}
+
+// NewWatchdog returns a watchdog of the iterator tracked by tracker, considered stuck
+// once it was notified about maxHeads heads since its heartbeat, DefaultStuckHeads if
+// it's 0. It checks the iterator every interval, DefaultWatchdogInterval if it's 0.
+func NewWatchdog(tracker *SyncStatusTracker, maxHeads uint64, interval time.Duration) *Watchdog {
+	if maxHeads == 0 {
+		maxHeads = DefaultStuckHeads
+	}
+	if interval == 0 {
+		interval = DefaultWatchdogInterval
+	}
+	return &Watchdog{tracker: tracker, maxHeads: maxHeads, interval: interval}
+}
+
+// Run checks the iterator until the context is canceled.
+func (w *Watchdog) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			w.check(now)
+		}
+	}
+}
+
+// check reports whether the iterator is stuck at the time, logging when it gets stuck
+// and when it makes progress again.
+func (w *Watchdog) check(now time.Time) bool {
+	status := w.tracker.Status()
+	age := now.Sub(status.Heartbeat)
+	iteratorHeartbeatAgeGauge.Update(age.Milliseconds())
+
+	stuck := status.HeadsSinceHeartbeat >= w.maxHeads
+	switch {
+	case stuck && !w.stuck:
+		log.Error("Arkiv events iterator made no progress, queries are going stale",
+			"heads", status.HeadsSinceHeartbeat, "heartbeat", age, "lastBlock", status.LastBlock, "headBlock", status.HeadBlock)
+		iteratorStuckGauge.Update(1)
+	case !stuck && w.stuck:
+		log.Info("Arkiv events iterator is making progress again", "lastBlock", status.LastBlock)
+		iteratorStuckGauge.Update(0)
+	}
+	w.stuck = stuck
+	return stuck
+}
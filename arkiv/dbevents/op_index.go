@@ -0,0 +1,79 @@
+package dbevents
+
+import (
+	"cmp"
+	"slices"
+
+	arkivevents "github.com/Arkiv-Network/arkiv-events"
+	"github.com/Arkiv-Network/arkiv-events/events"
+)
+
+// PerKindOpIndexes returns the operations of the batches the way the releases before
+// the canonical order did, for the consumers that haven't migrated yet: the operations
+// of a transaction grouped in the order creates, updates, extends, ownership changes
+// and deletes, and numbered from 0 for every kind. The operations are copied, the
+// batches of the iterator aren't modified. The per-kind numbering will be removed in
+// the next release, see canonicalOpIndexes for the canonical order.
+func PerKindOpIndexes(iterator arkivevents.BatchIterator) arkivevents.BatchIterator {
+	return func(yield func(arkivevents.BatchOrError) bool) {
+		for batch := range iterator {
+			if batch.Error == nil {
+				blocks := slices.Clone(batch.Batch.Blocks)
+				for i := range blocks {
+					blocks[i].Operations = perKindOperations(blocks[i].Operations)
+				}
+				batch.Batch.Blocks = blocks
+			}
+			if !yield(batch) {
+				return
+			}
+		}
+	}
+}
+
+// perKindOperations returns a copy of the operations of a block in the per-kind order
+// and numbering, see PerKindOpIndexes.
+func perKindOperations(operations []events.Operation) []events.Operation {
+	operations = slices.Clone(operations)
+	slices.SortStableFunc(operations, func(a, b events.Operation) int {
+		if c := cmp.Compare(a.TxIndex, b.TxIndex); c != 0 {
+			return c
+		}
+		return cmp.Compare(perKindRank(a), perKindRank(b))
+	})
+
+	type txKind struct {
+		txIndex uint64
+		rank    int
+	}
+	next := map[txKind]uint64{}
+	for i := range operations {
+		operation := &operations[i]
+		if operation.Expire != nil {
+			// Expirations are numbered after their log, in both orders
+			continue
+		}
+		kind := txKind{operation.TxIndex, perKindRank(*operation)}
+		operation.OpIndex = next[kind]
+		next[kind]++
+	}
+	return operations
+}
+
+// perKindRank is the position of the kind of the operation in the per-kind order.
+func perKindRank(operation events.Operation) int {
+	switch {
+	case operation.Expire != nil:
+		return 0
+	case operation.Create != nil:
+		return 1
+	case operation.Update != nil:
+		return 2
+	case operation.ExtendBTL != nil:
+		return 3
+	case operation.ChangeOwner != nil:
+		return 4
+	default:
+		return 5
+	}
+}
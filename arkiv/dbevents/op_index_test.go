@@ -0,0 +1,51 @@
+package dbevents
+
+import (
+	"testing"
+
+	arkivevents "github.com/Arkiv-Network/arkiv-events"
+	"github.com/Arkiv-Network/arkiv-events/events"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPerKindOpIndexes(t *testing.T) {
+	expire := events.OPExpire(common.HexToHash("0x9").Bytes())
+	deleted := events.OPDelete(common.HexToHash("0x4"))
+	block := events.Block{Number: 7, Operations: []events.Operation{
+		{TxIndex: 0, OpIndex: 3, Expire: &expire},
+		{TxIndex: 1, OpIndex: 0, Create: &events.OPCreate{Key: common.HexToHash("0x1")}},
+		{TxIndex: 1, OpIndex: 1, Delete: &deleted},
+		{TxIndex: 1, OpIndex: 2, Update: &events.OPUpdate{Key: common.HexToHash("0x2")}},
+		{TxIndex: 1, OpIndex: 3, Update: &events.OPUpdate{Key: common.HexToHash("0x3")}},
+		{TxIndex: 1, OpIndex: 4, ExtendBTL: &events.OPExtendBTL{Key: common.HexToHash("0x5")}},
+		{TxIndex: 1, OpIndex: 6, ChangeOwner: &events.OPChangeOwner{Key: common.HexToHash("0x7")}},
+		{TxIndex: 2, OpIndex: 0, Delete: &deleted},
+	}}
+	iterator := func(yield func(arkivevents.BatchOrError) bool) {
+		yield(arkivevents.BatchOrError{Batch: events.BlockBatch{Blocks: []events.Block{block}}})
+	}
+
+	var yielded []arkivevents.BatchOrError
+	for batch := range PerKindOpIndexes(iterator) {
+		yielded = append(yielded, batch)
+	}
+	require.Len(t, yielded, 1)
+
+	// The operations of a transaction are grouped by kind and numbered per kind, the
+	// expirations keep their index
+	require.Equal(t, []events.Operation{
+		{TxIndex: 0, OpIndex: 3, Expire: &expire},
+		{TxIndex: 1, OpIndex: 0, Create: &events.OPCreate{Key: common.HexToHash("0x1")}},
+		{TxIndex: 1, OpIndex: 0, Update: &events.OPUpdate{Key: common.HexToHash("0x2")}},
+		{TxIndex: 1, OpIndex: 1, Update: &events.OPUpdate{Key: common.HexToHash("0x3")}},
+		{TxIndex: 1, OpIndex: 0, ExtendBTL: &events.OPExtendBTL{Key: common.HexToHash("0x5")}},
+		{TxIndex: 1, OpIndex: 0, ChangeOwner: &events.OPChangeOwner{Key: common.HexToHash("0x7")}},
+		{TxIndex: 1, OpIndex: 0, Delete: &deleted},
+		{TxIndex: 2, OpIndex: 0, Delete: &deleted},
+	}, yielded[0].Batch.Blocks[0].Operations)
+
+	// The operations of the iterator are left as they are
+	require.Equal(t, uint64(1), block.Operations[2].OpIndex)
+	require.NotNil(t, block.Operations[2].Delete)
+}
@@ -0,0 +1,184 @@
+package dbevents
+
+import (
+	"fmt"
+
+	"github.com/Arkiv-Network/arkiv-events/events"
+	"github.com/ethereum/go-ethereum/arkiv/address"
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// BlockToEvents decodes the Arkiv operations of a block and its receipts the way they
+// are fed to the store, see UnknownOperations for the operations it skips.
+func BlockToEvents(block *types.Block, receipts []*types.Receipt) (*events.Block, []UnknownOperations, error) {
+	return blockToEvents(block, receipts)
+}
+
+// OwnerChange is an ownership change of a block with the owner of the entity before
+// the change, which events.OPChangeOwner doesn't carry.
+type OwnerChange struct {
+	TxIndex uint64
+	OpIndex uint64
+	events.OPChangeOwner
+	PreviousOwner common.Address
+}
+
+// BlockOwnerChanges returns the ownership changes of a block, numbered like the
+// operations of BlockToEvents, with their previous owners. The ArkivEntityOwnerChanged
+// log carries the previous owner as an indexed topic, so several changes of the same
+// entity in the block each get the owner the previous change left.
+func BlockOwnerChanges(block *types.Block, receipts []*types.Receipt) []OwnerChange {
+	changes := []OwnerChange{}
+	for i, transaction := range block.Transactions() {
+		if i >= len(receipts) || receipts[i].Status != types.ReceiptStatusSuccessful {
+			continue
+		}
+		if to := transaction.To(); to == nil || *to != address.ArkivProcessorAddress {
+			continue
+		}
+		atx, err := storagetx.UnpackArkivTransaction(transaction.Data())
+		if err != nil {
+			// Skipped by BlockToEvents too
+			continue
+		}
+		opIndexes := canonicalOpIndexes(atx)
+		for _, change := range ownerChanges(receipts[i]) {
+			changes = append(changes, OwnerChange{
+				TxIndex:       uint64(i),
+				OpIndex:       opIndexes.changeOwner + change.index,
+				OPChangeOwner: *change.change,
+				PreviousOwner: change.previous,
+			})
+		}
+	}
+	return changes
+}
+
+// PendingTransactionToEvents decodes the Arkiv operations of a transaction of the
+// sender that isn't mined yet, assuming it succeeds. Without a receipt the keys of the
+// created entities are derived from the transaction, and ownership changes are only
+// decoded if they take effect right away: immediate changes, acceptances, and any
+// change if twoStepTransfers is false. The transaction is decoded as included in the
+// block blockNumber: the BTL of an extension is counted from it, like in BlockToEvents,
+// and expiresAt returns the expiry the extended entities have before the transaction.
+// It fails if expiresAt does, the transaction fails when it's executed then. BTL
+// reductions aren't decoded, the processor may refuse them depending on the state. The
+// operations are numbered like the operations of a mined transaction, see
+// canonicalOpIndexes.
+func PendingTransactionToEvents(tx *types.Transaction, txIndex uint64, sender common.Address, twoStepTransfers bool, blockNumber uint64, expiresAt func(common.Hash) (uint64, error)) ([]events.Operation, error) {
+	if to := tx.To(); to == nil || *to != address.ArkivProcessorAddress {
+		return nil, nil
+	}
+
+	atx, err := storagetx.UnpackArkivTransaction(tx.Data())
+	if err != nil {
+		return nil, fmt.Errorf("failed to unpack arkiv transaction: %w", err)
+	}
+
+	// The expiries the operations of the transaction set, and the entities it deletes
+	expiries := map[common.Hash]uint64{}
+	deleted := map[common.Hash]bool{}
+
+	operations := []events.Operation{}
+	opIndexes := canonicalOpIndexes(atx)
+	for opIndex, create := range atx.Create {
+		key := entity.DeriveEntityKey(tx.Hash(), opIndex, create.Payload)
+		expiries[key] = blockNumber + create.BTL
+		operations = append(operations, events.Operation{
+			TxIndex: txIndex,
+			OpIndex: opIndexes.create + uint64(opIndex),
+			Create: &events.OPCreate{
+				Key:               key,
+				ContentType:       create.ContentType,
+				BTL:               create.BTL,
+				Owner:             sender,
+				Content:           create.Payload,
+				StringAttributes:  withExternalContent(stringAnnotationsToMap(create.StringAnnotations, create.NumericAnnotations, create.Encryption), atx.ExternalContentOf(false, opIndex)),
+				NumericAttributes: numericAnnotationsToMap(create.NumericAnnotations),
+			},
+		})
+	}
+	for opIndex, key := range atx.Delete {
+		delete(expiries, key)
+		deleted[key] = true
+		event := events.OPDelete(key)
+		operations = append(operations, events.Operation{
+			TxIndex: txIndex,
+			OpIndex: opIndexes.delete + uint64(opIndex),
+			Delete:  &event,
+		})
+	}
+	for opIndex, update := range atx.Update {
+		expiries[update.EntityKey] = blockNumber + update.BTL
+		operations = append(operations, events.Operation{
+			TxIndex: txIndex,
+			OpIndex: opIndexes.update + uint64(opIndex),
+			Update: &events.OPUpdate{
+				Key:               update.EntityKey,
+				ContentType:       update.ContentType,
+				BTL:               update.BTL,
+				Owner:             sender,
+				Content:           update.Payload,
+				StringAttributes:  withExternalContent(stringAnnotationsToMap(update.StringAnnotations, update.NumericAnnotations, update.Encryption), atx.ExternalContentOf(true, opIndex)),
+				NumericAttributes: numericAnnotationsToMap(update.NumericAnnotations),
+			},
+		})
+	}
+	for opIndex, extendBTL := range atx.Extend {
+		key := extendBTL.EntityKey
+		if deleted[key] {
+			return nil, fmt.Errorf("failed to extend BTL of entity %s: deleted by the transaction", key.Hex())
+		}
+		expiry, ok := expiries[key]
+		if !ok {
+			expiry, err = expiresAt(key)
+			if err != nil {
+				return nil, fmt.Errorf("failed to extend BTL of entity %s: %w", key.Hex(), err)
+			}
+		}
+		expiries[key] = expiry + extendBTL.NumberOfBlocks
+		operations = append(operations, events.Operation{
+			TxIndex: txIndex,
+			OpIndex: opIndexes.extend + uint64(opIndex),
+			ExtendBTL: &events.OPExtendBTL{
+				Key: key,
+				BTL: expiries[key] - blockNumber,
+			},
+		})
+	}
+	for opIndex, changeOwner := range atx.ChangeOwner {
+		if twoStepTransfers && !changeOwner.Immediate {
+			continue
+		}
+		operations = append(operations, events.Operation{
+			TxIndex:     txIndex,
+			OpIndex:     opIndexes.changeOwner + uint64(opIndex),
+			ChangeOwner: &events.OPChangeOwner{Key: changeOwner.EntityKey, Owner: changeOwner.NewOwner},
+		})
+	}
+	opIndex := opIndexes.changeOwner + uint64(len(atx.ChangeOwner))
+	for _, batch := range atx.ChangeOwnerBatch {
+		for _, key := range batch.EntityKeys {
+			if !twoStepTransfers || batch.Immediate {
+				operations = append(operations, events.Operation{
+					TxIndex:     txIndex,
+					OpIndex:     opIndex,
+					ChangeOwner: &events.OPChangeOwner{Key: key, Owner: batch.NewOwner},
+				})
+			}
+			opIndex++
+		}
+	}
+	for opIndex, key := range atx.AcceptOwnership {
+		operations = append(operations, events.Operation{
+			TxIndex:     txIndex,
+			OpIndex:     opIndexes.acceptOwnership + uint64(opIndex),
+			ChangeOwner: &events.OPChangeOwner{Key: key, Owner: sender},
+		})
+	}
+
+	return operations, nil
+}
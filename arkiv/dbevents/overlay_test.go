@@ -0,0 +1,118 @@
+package dbevents
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/Arkiv-Network/arkiv-events/events"
+	"github.com/ethereum/go-ethereum/arkiv/address"
+	"github.com/ethereum/go-ethereum/arkiv/compression"
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/stretchr/testify/require"
+)
+
+func arkivTx(t testing.TB, atx *storagetx.ArkivTransaction) *types.Transaction {
+	t.Helper()
+
+	data, err := rlp.EncodeToBytes(atx)
+	require.NoError(t, err)
+	return types.NewTx(&types.DynamicFeeTx{
+		To:   &address.ArkivProcessorAddress,
+		Data: compression.MustBrotliCompress(data),
+	})
+}
+
+func TestPendingTransactionToEvents(t *testing.T) {
+	sender := common.HexToAddress("0xa")
+	key, other := common.HexToHash("0x1"), common.HexToHash("0x2")
+	tx := arkivTx(t, &storagetx.ArkivTransaction{
+		Version: storagetx.TransactionVersionOwnership,
+		Create: []storagetx.ArkivCreate{
+			{BTL: 10, ContentType: "text/plain", Payload: []byte("first")},
+			{BTL: 10, ContentType: "text/plain", Payload: []byte("second")},
+		},
+		ChangeOwner: []storagetx.ArkivChangeOwner{
+			{EntityKey: key, NewOwner: common.HexToAddress("0xb")},
+			{EntityKey: other, NewOwner: common.HexToAddress("0xc"), Immediate: true},
+		},
+		AcceptOwnership: []common.Hash{common.HexToHash("0x3")},
+		Delete:          []common.Hash{key},
+	})
+
+	operations, err := PendingTransactionToEvents(tx, 2, sender, true, 7, nil)
+	require.NoError(t, err)
+	require.Len(t, operations, 5)
+
+	// The keys of the created entities are derived the way the processor derives them
+	for i, payload := range []string{"first", "second"} {
+		require.Equal(t, crypto.Keccak256Hash(tx.Hash().Bytes(), []byte(payload), common.LeftPadBytes(big.NewInt(int64(i)).Bytes(), 32)), operations[i].Create.Key)
+		require.Equal(t, sender, operations[i].Create.Owner)
+		require.Equal(t, uint64(2), operations[i].TxIndex)
+	}
+
+	// The deletes come after the creates, like the processor applies them
+	require.Equal(t, key, common.Hash(*operations[2].Delete))
+	require.Equal(t, uint64(2), operations[2].OpIndex)
+
+	// A proposed transfer doesn't change the owner, an immediate one and an acceptance do
+	require.Equal(t, other, operations[3].ChangeOwner.Key)
+	require.Equal(t, uint64(4), operations[3].OpIndex)
+	require.Equal(t, common.HexToHash("0x3"), operations[4].ChangeOwner.Key)
+	require.Equal(t, sender, operations[4].ChangeOwner.Owner)
+	require.Equal(t, uint64(5), operations[4].OpIndex)
+
+	operations, err = PendingTransactionToEvents(tx, 2, sender, false, 7, nil)
+	require.NoError(t, err)
+	require.Len(t, operations, 6)
+	require.Equal(t, key, operations[3].ChangeOwner.Key)
+	require.Equal(t, uint64(3), operations[3].OpIndex)
+
+	// Other transactions carry no operations
+	operations, err = PendingTransactionToEvents(types.NewTx(&types.DynamicFeeTx{To: &sender}), 0, sender, true, 7, nil)
+	require.NoError(t, err)
+	require.Empty(t, operations)
+}
+
+func TestPendingTransactionToEvents_Extend(t *testing.T) {
+	sender := common.HexToAddress("0xa")
+	updated, stored, deleted := common.HexToHash("0x1"), common.HexToHash("0x2"), common.HexToHash("0x3")
+	expiresAt := func(key common.Hash) (uint64, error) {
+		if key == stored {
+			return 30, nil
+		}
+		return 0, fmt.Errorf("entity %s not found", key.Hex())
+	}
+
+	// The extensions count from the expiry the update sets and from the stored one
+	tx := arkivTx(t, &storagetx.ArkivTransaction{
+		Update: []storagetx.ArkivUpdate{{EntityKey: updated, BTL: 10, ContentType: "text/plain"}},
+		Extend: []storagetx.ExtendBTL{
+			{EntityKey: updated, NumberOfBlocks: 5},
+			{EntityKey: stored, NumberOfBlocks: 5},
+			{EntityKey: updated, NumberOfBlocks: 5},
+		},
+	})
+	operations, err := PendingTransactionToEvents(tx, 0, sender, true, 7, expiresAt)
+	require.NoError(t, err)
+	require.Equal(t, []events.Operation{
+		{OpIndex: 1, ExtendBTL: &events.OPExtendBTL{Key: updated, BTL: 15}},
+		{OpIndex: 2, ExtendBTL: &events.OPExtendBTL{Key: stored, BTL: 28}},
+		{OpIndex: 3, ExtendBTL: &events.OPExtendBTL{Key: updated, BTL: 20}},
+	}, operations[1:])
+
+	// An extension of an entity that isn't found or that the transaction deletes fails
+	_, err = PendingTransactionToEvents(arkivTx(t, &storagetx.ArkivTransaction{
+		Extend: []storagetx.ExtendBTL{{EntityKey: deleted, NumberOfBlocks: 5}},
+	}), 0, sender, true, 7, expiresAt)
+	require.ErrorContains(t, err, "not found")
+	_, err = PendingTransactionToEvents(arkivTx(t, &storagetx.ArkivTransaction{
+		Delete: []common.Hash{stored},
+		Extend: []storagetx.ExtendBTL{{EntityKey: stored, NumberOfBlocks: 5}},
+	}), 0, sender, true, 7, expiresAt)
+	require.ErrorContains(t, err, "deleted by the transaction")
+}
@@ -0,0 +1,64 @@
+package dbevents
+
+import (
+	"fmt"
+	"slices"
+
+	arkivevents "github.com/Arkiv-Network/arkiv-events"
+	"github.com/Arkiv-Network/arkiv-events/events"
+	"github.com/ethereum/go-ethereum/arkiv/compression"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// CompressPayloads encodes the payloads of the creates and updates of the batches with
+// compression.EncodePayload, for a store that keeps them compressed. The operations
+// are copied, the batches of the iterator aren't modified. A payload that fails to
+// compress halts the ingestion.
+func CompressPayloads(iterator arkivevents.BatchIterator) arkivevents.BatchIterator {
+	return func(yield func(arkivevents.BatchOrError) bool) {
+		for batch := range iterator {
+			if batch.Error == nil {
+				blocks, err := compressBlocks(batch.Batch.Blocks)
+				if err != nil {
+					log.Error("Failed to compress the Arkiv payloads, halting the ingestion", "error", err)
+					yield(arkivevents.BatchOrError{Error: err})
+					return
+				}
+				batch.Batch.Blocks = blocks
+			}
+			if !yield(batch) {
+				return
+			}
+		}
+	}
+}
+
+func compressBlocks(blocks []events.Block) ([]events.Block, error) {
+	blocks = slices.Clone(blocks)
+	for i := range blocks {
+		block := &blocks[i]
+		block.Operations = slices.Clone(block.Operations)
+		for j := range block.Operations {
+			operation := &block.Operations[j]
+			switch {
+			case operation.Create != nil:
+				create := *operation.Create
+				content, err := compression.EncodePayload(create.Content)
+				if err != nil {
+					return nil, fmt.Errorf("failed to compress the payload of %s at block %d: %w", create.Key.Hex(), block.Number, err)
+				}
+				create.Content = content
+				operation.Create = &create
+			case operation.Update != nil:
+				update := *operation.Update
+				content, err := compression.EncodePayload(update.Content)
+				if err != nil {
+					return nil, fmt.Errorf("failed to compress the payload of %s at block %d: %w", update.Key.Hex(), block.Number, err)
+				}
+				update.Content = content
+				operation.Update = &update
+			}
+		}
+	}
+	return blocks, nil
+}
@@ -0,0 +1,43 @@
+package dbevents
+
+import (
+	"bytes"
+	"testing"
+
+	arkivevents "github.com/Arkiv-Network/arkiv-events"
+	"github.com/Arkiv-Network/arkiv-events/events"
+	"github.com/ethereum/go-ethereum/arkiv/compression"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompressPayloads(t *testing.T) {
+	payload := bytes.Repeat([]byte("arkiv "), 100)
+	block := events.Block{Number: 1, Operations: []events.Operation{
+		{Create: &events.OPCreate{Key: common.HexToHash("0x01"), Content: payload}},
+		{Update: &events.OPUpdate{Key: common.HexToHash("0x01"), Content: []byte("x")}},
+		{Delete: &events.OPDelete{}},
+	}}
+	iterator := func(yield func(arkivevents.BatchOrError) bool) {
+		yield(arkivevents.BatchOrError{Batch: events.BlockBatch{Blocks: []events.Block{block}}})
+	}
+
+	var yielded []arkivevents.BatchOrError
+	for batch := range CompressPayloads(iterator) {
+		yielded = append(yielded, batch)
+	}
+	require.Len(t, yielded, 1)
+	operations := yielded[0].Batch.Blocks[0].Operations
+	require.Len(t, operations, 3)
+
+	require.Equal(t, compression.CodecBrotli, operations[0].Create.Content[0])
+	decoded, err := compression.DecompressPayload(operations[0].Create.Content)
+	require.NoError(t, err)
+	require.Equal(t, payload, decoded)
+	require.Equal(t, []byte{compression.CodecIdentity, 'x'}, operations[1].Update.Content)
+	require.Equal(t, block.Operations[2], operations[2])
+
+	// The operations of the iterator are left as they are
+	require.Equal(t, payload, block.Operations[0].Create.Content)
+	require.Equal(t, []byte("x"), block.Operations[1].Update.Content)
+}
@@ -0,0 +1,157 @@
+package dbevents
+
+import (
+	"github.com/Arkiv-Network/arkiv-events/events"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// DefaultConcurrency is the default number of blocks the chain batch iterator loads at
+// once.
+const DefaultConcurrency = 4
+
+// loadedBlock is a block of the canonical chain read from the database and converted to
+// events. The parts of the block from the first one missing from the database are nil.
+type loadedBlock struct {
+	hash     common.Hash
+	header   *types.Header
+	receipts types.Receipts
+	block    *types.Block
+
+	events  *events.Block
+	summary BlockSummary
+	unknown []UnknownOperations
+	// err is the error of the conversion to events.
+	err error
+	// panicked is the panic recovered while the block was prefetched, load raises it
+	// again on the goroutine of the iterator.
+	panicked *iteratorPanic
+}
+
+// blockConverter converts a block and its receipts to events, see blockToEvents.
+type blockConverter func(*types.Block, []*types.Receipt) (*events.Block, []UnknownOperations, error)
+
+// complete reports whether all the parts of the block were found.
+func (b *loadedBlock) complete() bool {
+	return b.block != nil
+}
+
+// loadBlock reads the canonical block with the number, converts it to events with
+// convert and sums them up, with the slot delta read by read if it's set.
+func loadBlock(db ethdb.Reader, number uint64, config *params.ChainConfig, read UsedSlotsReader, convert blockConverter) *loadedBlock {
+	loaded := &loadedBlock{hash: rawdb.ReadCanonicalHash(db, number)}
+	if loaded.hash == (common.Hash{}) {
+		return loaded
+	}
+	if loaded.header = rawdb.ReadHeader(db, loaded.hash, number); loaded.header == nil {
+		return loaded
+	}
+	if loaded.receipts = rawdb.ReadReceipts(db, loaded.hash, number, loaded.header.Time, config); loaded.receipts == nil {
+		// The receipts pruned by the Arkiv retention policy have no Arkiv logs
+		if !rawdb.HasArkivPrunedReceipts(db, loaded.hash, number) {
+			return loaded
+		}
+		loaded.receipts = types.Receipts{}
+	}
+	body := rawdb.ReadBody(db, loaded.hash, number)
+	if body == nil {
+		return loaded
+	}
+	loaded.block = types.NewBlockWithHeader(loaded.header).WithBody(*body)
+	loaded.events, loaded.unknown, loaded.err = convert(loaded.block, loaded.receipts)
+	if loaded.err == nil {
+		loaded.summary = summarize(loaded.events)
+		loaded.summary.SlotDelta = slotDelta(db, read, loaded.header)
+	}
+	return loaded
+}
+
+// prefetcher loads the blocks of the chain ahead of the iterator, at most concurrency
+// at once, in the order of their numbers. It's used by a single goroutine.
+type prefetcher struct {
+	db      ethdb.Database
+	read    UsedSlotsReader
+	convert blockConverter
+	workers chan struct{}
+	pending map[uint64]chan *loadedBlock
+}
+
+func newPrefetcher(db ethdb.Database, read UsedSlotsReader, convert blockConverter, concurrency int) *prefetcher {
+	return &prefetcher{
+		db:      db,
+		read:    read,
+		convert: convert,
+		workers: make(chan struct{}, max(concurrency, 1)),
+		pending: make(map[uint64]chan *loadedBlock),
+	}
+}
+
+// prefetch starts loading the blocks from first to last not loaded yet, without
+// waiting for them.
+func (p *prefetcher) prefetch(first, last uint64, config *params.ChainConfig) {
+	var numbers []uint64
+	var results []chan *loadedBlock
+	for number := first; number <= last; number++ {
+		if _, ok := p.pending[number]; ok {
+			continue
+		}
+		result := make(chan *loadedBlock, 1)
+		p.pending[number] = result
+		numbers, results = append(numbers, number), append(results, result)
+	}
+	if len(numbers) == 0 {
+		return
+	}
+	go func() {
+		for i, number := range numbers {
+			p.workers <- struct{}{}
+			go func() {
+				defer func() { <-p.workers }()
+				results[i] <- p.loadAhead(number, config)
+			}()
+		}
+	}()
+}
+
+// loadAhead loads the block with the number on a goroutine of the prefetcher. A panic
+// is recovered, its goroutine would otherwise crash the node, and returned with the
+// block.
+func (p *prefetcher) loadAhead(number uint64, config *params.ChainConfig) (loaded *loadedBlock) {
+	defer func() {
+		if r := recover(); r != nil {
+			loaded = &loadedBlock{panicked: newIteratorPanic(r)}
+		}
+	}()
+	return loadBlock(p.db, number, config, p.read, p.convert)
+}
+
+// load returns the block with the number, waiting for it if it's being prefetched. A
+// prefetched block that is no longer canonical or was incomplete is read again, a
+// panic of its prefetch is raised again.
+func (p *prefetcher) load(number uint64, config *params.ChainConfig) *loadedBlock {
+	result, ok := p.pending[number]
+	if !ok {
+		return loadBlock(p.db, number, config, p.read, p.convert)
+	}
+	delete(p.pending, number)
+	loaded := <-result
+	if loaded.panicked != nil {
+		panic(loaded.panicked)
+	}
+	if !loaded.complete() || loaded.hash != rawdb.ReadCanonicalHash(p.db, number) {
+		return loadBlock(p.db, number, config, p.read, p.convert)
+	}
+	return loaded
+}
+
+// discard drops the blocks prefetched before the number.
+func (p *prefetcher) discard(number uint64) {
+	for n := range p.pending {
+		if n < number {
+			delete(p.pending, n)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package dbevents
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/arkiv/address"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+var (
+	// prunedReceiptsCounter counts the blocks whose receipts were pruned.
+	prunedReceiptsCounter = metrics.NewRegisteredCounter("arkiv/receipts/pruned/blocks", nil)
+	// prunedReceiptsBytesCounter counts the bytes of the pruned receipts.
+	prunedReceiptsBytesCounter = metrics.NewRegisteredCounter("arkiv/receipts/pruned/bytes", nil)
+)
+
+// PrunedReceipts are the blocks a call to PruneReceipts went through and the receipts
+// it pruned.
+type PrunedReceipts struct {
+	From, To uint64
+	Blocks   uint64
+	Bytes    uint64
+}
+
+// PruneReceipts drops the receipts of the blocks without logs of the Arkiv processor,
+// once the checkpoint of the events iterator is retention blocks past them. The blocks
+// with Arkiv logs keep their receipts. The pruning resumes after the last block of the
+// previous call and goes through at most limit blocks.
+//
+// The freezer is append-only: the receipts are pruned before the blocks are frozen, an
+// empty list is frozen in their place. The blocks already frozen are skipped.
+func PruneReceipts(db ethdb.Database, retention uint64, limit uint64) (*PrunedReceipts, error) {
+	checkpoint := rawdb.ReadArkivEventsCheckpoint(db)
+	if checkpoint == nil || checkpoint.Number <= retention {
+		return nil, nil
+	}
+	result := &PrunedReceipts{
+		From: rawdb.ReadArkivReceiptsPrunedTo(db) + 1,
+		To:   checkpoint.Number - retention,
+	}
+	if result.From > result.To {
+		return nil, nil
+	}
+	result.To = min(result.To, result.From+limit-1)
+	frozen, _ := db.Ancients()
+
+	batch := db.NewBatch()
+	for number := max(result.From, frozen); number <= result.To; number++ {
+		hash := rawdb.ReadCanonicalHash(db, number)
+		data := rawdb.ReadReceiptsRLP(db, hash, number)
+		if len(data) <= len(rlp.EmptyList) {
+			continue
+		}
+		receipts := rawdb.ReadRawReceipts(db, hash, number)
+		if receipts == nil || hasProcessorLogs(receipts) {
+			continue
+		}
+		rawdb.PruneArkivReceipts(batch, hash, number)
+		result.Blocks++
+		result.Bytes += uint64(len(data) - len(rlp.EmptyList))
+
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return nil, fmt.Errorf("failed to write pruned receipts: %w", err)
+			}
+			batch.Reset()
+		}
+	}
+	rawdb.WriteArkivReceiptsPrunedTo(batch, result.To)
+	if err := batch.Write(); err != nil {
+		return nil, fmt.Errorf("failed to write pruned receipts: %w", err)
+	}
+
+	prunedReceiptsCounter.Inc(int64(result.Blocks))
+	prunedReceiptsBytesCounter.Inc(int64(result.Bytes))
+	return result, nil
+}
+
+// hasProcessorLogs reports whether the receipts have logs of the Arkiv processor.
+func hasProcessorLogs(receipts types.Receipts) bool {
+	for _, receipt := range receipts {
+		for _, log := range receipt.Logs {
+			if log.Address == address.ArkivProcessorAddress {
+				return true
+			}
+		}
+	}
+	return false
+}
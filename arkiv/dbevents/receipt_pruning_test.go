@@ -0,0 +1,92 @@
+package dbevents
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+// newPruningChainDB returns a chain of 8 blocks where blocks 2, 4 and 6 have no Arkiv logs.
+func newPruningChainDB(t *testing.T) (*prunedDB, []*types.Block) {
+	t.Helper()
+
+	db, blocks := newArkivChainDB(t, 8)
+	for number := uint64(2); number <= 6; number += 2 {
+		rawdb.WriteReceipts(db, blocks[number].Hash(), number, types.Receipts{
+			{Status: types.ReceiptStatusSuccessful, Logs: []*types.Log{{Address: common.HexToAddress("0xc")}}},
+		})
+	}
+	return &prunedDB{Database: db}, blocks
+}
+
+func TestPruneReceipts(t *testing.T) {
+	db, blocks := newPruningChainDB(t)
+
+	// Nothing is pruned before the events pipeline goes past the retention
+	pruned, err := PruneReceipts(db, 2, 100)
+	require.NoError(t, err)
+	require.Nil(t, pruned)
+	rawdb.WriteArkivEventsCheckpoint(db, rawdb.ArkivEventsCheckpoint{Number: 2, Hash: blocks[2].Hash()})
+	pruned, err = PruneReceipts(db, 2, 100)
+	require.NoError(t, err)
+	require.Nil(t, pruned)
+
+	rawdb.WriteArkivEventsCheckpoint(db, rawdb.ArkivEventsCheckpoint{Number: 7, Hash: blocks[7].Hash()})
+	pruned, err = PruneReceipts(db, 2, 100)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), pruned.From)
+	require.Equal(t, uint64(5), pruned.To)
+	require.Equal(t, uint64(2), pruned.Blocks)
+	require.Positive(t, pruned.Bytes)
+
+	for number := uint64(1); number <= 8; number++ {
+		hash := blocks[number].Hash()
+		wantPruned := number == 2 || number == 4
+		require.Equal(t, wantPruned, rawdb.HasArkivPrunedReceipts(db, hash, number), "block %d", number)
+		if wantPruned {
+			require.Empty(t, rawdb.ReadRawReceipts(db, hash, number), "block %d", number)
+		} else {
+			require.Len(t, rawdb.ReadRawReceipts(db, hash, number), 1, "block %d", number)
+		}
+	}
+
+	// The next pass resumes after the last one, at most limit blocks at once
+	rawdb.WriteArkivEventsCheckpoint(db, rawdb.ArkivEventsCheckpoint{Number: 10})
+	pruned, err = PruneReceipts(db, 2, 2)
+	require.NoError(t, err)
+	require.Equal(t, &PrunedReceipts{From: 6, To: 7, Blocks: 1, Bytes: pruned.Bytes}, pruned)
+	require.True(t, rawdb.HasArkivPrunedReceipts(db, blocks[6].Hash(), 6))
+}
+
+func TestChainBatchIterator_ArkivPrunedReceipts(t *testing.T) {
+	db, blocks := newPruningChainDB(t)
+	rawdb.WriteArkivEventsCheckpoint(db, rawdb.ArkivEventsCheckpoint{Number: 8, Hash: blocks[8].Hash()})
+	_, err := PruneReceipts(db, 1, 100)
+	require.NoError(t, err)
+
+	// The blocks pruned by the Arkiv retention policy are indexed without operations
+	hooks := NewHooks(db, 0)
+	batchIterator, status := NewChainBatchIterator(db, hooks, 0, false)
+	batches := startIterator(batchIterator)
+	require.NoError(t, hooks.OnNewBlock(params.TestChainConfig, blocks[8]))
+
+	next := uint64(1)
+	for next <= 8 {
+		batch := nextBatch(t, batches)
+		require.NoError(t, batch.Error)
+		for _, block := range batch.Batch.Blocks {
+			require.Equal(t, next, block.Number)
+			if next%2 == 0 && next < 8 {
+				require.Empty(t, block.Operations, "block %d", next)
+			} else {
+				require.Len(t, block.Operations, 2, "block %d", next)
+			}
+			next++
+		}
+	}
+	require.Nil(t, status.Status().PrunedGap)
+}
@@ -0,0 +1,161 @@
+package dbevents
+
+import (
+	"github.com/Arkiv-Network/arkiv-events/events"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// slotMismatchCounter counts the batches whose slot deltas disagree with the used
+// slots counter of the chain.
+var slotMismatchCounter = metrics.NewRegisteredCounter("arkiv/ingest/slotmismatch", nil)
+
+// The kinds of the operations counted by BlockSummary. The BTL reductions are
+// extensions, like in the events.
+const (
+	OperationCreate      = "create"
+	OperationUpdate      = "update"
+	OperationDelete      = "delete"
+	OperationExtendBTL   = "extendBTL"
+	OperationChangeOwner = "changeOwner"
+	OperationExpire      = "expire"
+)
+
+// BlockSummary sums up the Arkiv operations of a block, so that the consumers keeping
+// counters don't have to recompute them from the operations.
+type BlockSummary struct {
+	// EntityDelta is the number of entities the block created minus the number it
+	// deleted or expired.
+	EntityDelta int64 `json:"entityDelta"`
+	// SlotDelta is the change of the slots used by the processor over the block, read
+	// from the state of the block and of its parent. It's nil without a UsedSlotsReader
+	// or if either state isn't available.
+	SlotDelta *int64 `json:"slotDelta,omitempty"`
+	// Operations is the number of operations of the block by kind.
+	Operations map[string]uint64 `json:"operations"`
+}
+
+// summarize returns the summary of the operations of a block, without its slot delta.
+func summarize(block *events.Block) BlockSummary {
+	summary := BlockSummary{Operations: map[string]uint64{}}
+	for _, operation := range block.Operations {
+		switch {
+		case operation.Create != nil:
+			summary.EntityDelta++
+			summary.Operations[OperationCreate]++
+		case operation.Update != nil:
+			summary.Operations[OperationUpdate]++
+		case operation.Delete != nil:
+			summary.EntityDelta--
+			summary.Operations[OperationDelete]++
+		case operation.ExtendBTL != nil:
+			summary.Operations[OperationExtendBTL]++
+		case operation.ChangeOwner != nil:
+			summary.Operations[OperationChangeOwner]++
+		case operation.Expire != nil:
+			summary.EntityDelta--
+			summary.Operations[OperationExpire]++
+		}
+	}
+	return summary
+}
+
+// UsedSlotsReader returns the number of slots used by the processor in the state of
+// the block with the header, see storageaccounting.GetNumberOfUsedSlots.
+type UsedSlotsReader func(header *types.Header) (uint64, error)
+
+// slotDelta returns the change of the used slots over the block with the header, nil if
+// the state of the block or of its parent can't be read.
+func slotDelta(db ethdb.Reader, read UsedSlotsReader, header *types.Header) *int64 {
+	if read == nil || header.Number.Uint64() == 0 {
+		return nil
+	}
+	parent := rawdb.ReadHeader(db, header.ParentHash, header.Number.Uint64()-1)
+	if parent == nil {
+		return nil
+	}
+	before, err := read(parent)
+	if err != nil {
+		return nil
+	}
+	after, err := read(header)
+	if err != nil {
+		return nil
+	}
+	delta := int64(after) - int64(before)
+	return &delta
+}
+
+// slotCheck cross-checks the slot deltas of the batches with the used slots counter of
+// the chain at their boundaries: the counter at the last block of a batch has to be
+// the counter at the last block of the batch before plus the deltas of the blocks in
+// between. A mismatch means the batches missed a change of the counter, such as the
+// blocks of a skipped pruned gap, and is logged and counted.
+type slotCheck struct {
+	db   ethdb.Reader
+	read UsedSlotsReader
+
+	// boundary is the counter at the last block of the previous batch, nil until it's
+	// read.
+	boundary *uint64
+}
+
+// usedSlots reads the counter at the canonical block with the number.
+func (c *slotCheck) usedSlots(number uint64) (uint64, bool) {
+	hash := rawdb.ReadCanonicalHash(c.db, number)
+	if hash == (common.Hash{}) {
+		return 0, false
+	}
+	header := rawdb.ReadHeader(c.db, hash, number)
+	if header == nil {
+		return 0, false
+	}
+	slots, err := c.read(header)
+	if err != nil {
+		return 0, false
+	}
+	return slots, true
+}
+
+// check cross-checks the blocks of the batch following the block with the number
+// parent, and reports whether they agree with the counter. The batches that can't be
+// checked, because a state isn't available, agree.
+func (c *slotCheck) check(parent uint64, blocks []Block) bool {
+	if c.read == nil || len(blocks) == 0 {
+		return true
+	}
+	if c.boundary == nil {
+		if slots, ok := c.usedSlots(parent); ok {
+			c.boundary = &slots
+		}
+	}
+	last := blocks[len(blocks)-1].Number
+	slots, ok := c.usedSlots(last)
+	if !ok {
+		c.boundary = nil
+		return true
+	}
+	defer func() { c.boundary = &slots }()
+
+	if c.boundary == nil {
+		return true
+	}
+	expected := int64(*c.boundary)
+	for _, block := range blocks {
+		if block.Summary.SlotDelta == nil {
+			return true
+		}
+		expected += *block.Summary.SlotDelta
+	}
+	if expected != int64(slots) {
+		log.Warn("Arkiv slot deltas disagree with the used slots of the chain",
+			"from", blocks[0].Number, "to", last, "expected", expected, "used", slots)
+		slotMismatchCounter.Inc(1)
+		return false
+	}
+	return true
+}
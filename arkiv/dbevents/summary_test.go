@@ -0,0 +1,124 @@
+package dbevents
+
+import (
+	"testing"
+
+	"github.com/Arkiv-Network/arkiv-events/events"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummarize(t *testing.T) {
+	key := common.HexToHash("0x1")
+	expire := events.OPExpire(key)
+	deleted := events.OPDelete(key)
+	block := &events.Block{
+		Number: 1,
+		Operations: []events.Operation{
+			{Create: &events.OPCreate{Key: key}},
+			{Create: &events.OPCreate{Key: key}},
+			{Create: &events.OPCreate{Key: key}},
+			{Update: &events.OPUpdate{Key: key}},
+			{Delete: &deleted},
+			{ExtendBTL: &events.OPExtendBTL{Key: key}},
+			{ChangeOwner: &events.OPChangeOwner{Key: key}},
+			{Expire: &expire},
+			{Expire: &expire},
+		},
+	}
+
+	summary := summarize(block)
+	require.Equal(t, int64(0), summary.EntityDelta)
+	require.Nil(t, summary.SlotDelta)
+	require.Equal(t, map[string]uint64{
+		OperationCreate:      3,
+		OperationUpdate:      1,
+		OperationDelete:      1,
+		OperationExtendBTL:   1,
+		OperationChangeOwner: 1,
+		OperationExpire:      2,
+	}, summary.Operations)
+
+	// A block without operations has an empty summary
+	require.Equal(t, BlockSummary{Operations: map[string]uint64{}}, summarize(&events.Block{Number: 2}))
+}
+
+// usedSlots reads a used slots counter growing by 3 slots a block.
+func usedSlots(header *types.Header) (uint64, error) {
+	return 3 * header.Number.Uint64(), nil
+}
+
+func TestChainBatchIterator_SlotDeltas(t *testing.T) {
+	db, blocks := newPrunedDB(t, 5, 0)
+
+	hooks := NewHooks(db, 0)
+	batchIterator, _ := NewChainBatchIterator(db, hooks, 0, false, WithBatchSize(3), WithUsedSlots(usedSlots))
+	batches := startIterator(batchIterator)
+	mismatches := slotMismatchCounter.Snapshot().Count()
+
+	require.NoError(t, hooks.OnNewBlock(params.TestChainConfig, blocks[5]))
+	for _, size := range []int{3, 2} {
+		batch := nextBatch(t, batches)
+		require.NoError(t, batch.Error)
+		require.Len(t, batch.Batch.Blocks, size)
+		for _, block := range batch.Batch.Blocks {
+			require.Equal(t, int64(3), *block.Summary.SlotDelta, block.Number)
+			require.Equal(t, map[string]uint64{}, block.Summary.Operations)
+		}
+		if size == 3 {
+			require.NoError(t, hooks.OnNewBlock(params.TestChainConfig, blocks[5]))
+		}
+	}
+	require.Equal(t, mismatches, slotMismatchCounter.Snapshot().Count())
+
+	// Without a reader the blocks have no slot delta
+	batchIterator, _ = NewChainBatchIterator(db, hooks, 0, false)
+	batches = startIterator(batchIterator)
+	require.NoError(t, hooks.OnNewBlock(params.TestChainConfig, blocks[5]))
+	batch := nextBatch(t, batches)
+	require.NoError(t, batch.Error)
+	require.Nil(t, batch.Batch.Blocks[0].Summary.SlotDelta)
+}
+
+func TestChainBatchIterator_SlotMismatch(t *testing.T) {
+	// The blocks 1 to 4 are pruned and skipped, the slots they used are missing from
+	// the deltas of the batch
+	db, blocks := newPrunedDB(t, 10, 5)
+
+	hooks := NewHooks(db, 0)
+	batchIterator, _ := NewChainBatchIterator(db, hooks, 0, true, WithUsedSlots(usedSlots))
+	batches := startIterator(batchIterator)
+	mismatches := slotMismatchCounter.Snapshot().Count()
+
+	require.NoError(t, hooks.OnNewBlock(params.TestChainConfig, blocks[10]))
+	batch := nextBatch(t, batches)
+	require.NoError(t, batch.Error)
+	require.Equal(t, uint64(5), batch.Batch.Blocks[0].Number)
+	require.Equal(t, mismatches+1, slotMismatchCounter.Snapshot().Count())
+}
+
+func TestSlotCheck(t *testing.T) {
+	db, _ := newPrunedDB(t, 3, 0)
+	delta := func(d int64) *int64 { return &d }
+	block := func(number uint64, slotDelta *int64) Block {
+		return Block{Block: events.Block{Number: number}, Summary: BlockSummary{SlotDelta: slotDelta}}
+	}
+	mismatches := slotMismatchCounter.Snapshot().Count()
+
+	check := &slotCheck{db: db, read: usedSlots}
+	require.True(t, check.check(0, []Block{block(1, delta(3)), block(2, delta(3))}))
+	require.Equal(t, uint64(6), *check.boundary)
+
+	// An artificial mismatch: the block claims more slots than the counter shows
+	require.False(t, check.check(2, []Block{block(3, delta(4))}))
+	require.Equal(t, mismatches+1, slotMismatchCounter.Snapshot().Count())
+	// The next batch is checked from the counter of the chain
+	require.Equal(t, uint64(9), *check.boundary)
+
+	// The batches with a block without slot delta aren't checked
+	check = &slotCheck{db: db, read: usedSlots}
+	require.True(t, check.check(0, []Block{block(1, delta(3)), block(2, nil), block(3, delta(100))}))
+	require.Equal(t, mismatches+1, slotMismatchCounter.Snapshot().Count())
+}
@@ -0,0 +1,125 @@
+package dbevents
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	arkivevents "github.com/Arkiv-Network/arkiv-events"
+	"github.com/Arkiv-Network/arkiv-events/events"
+)
+
+var (
+	// ErrEventsNotStarted is returned by a tick sent before the store started reading
+	// the events.
+	ErrEventsNotStarted = errors.New("the store didn't start reading the Arkiv events")
+	// ErrEventsStopped is returned by a tick sent after the ingestion halted.
+	ErrEventsStopped = errors.New("the ingestion of the Arkiv events halted")
+)
+
+// Ticker sends no-op batches through the events pipeline to check the store is reading
+// it. A tick is a batch of a single empty block numbered like the last block ingested
+// by the store, which the store skips like any block it already ingested.
+type Ticker struct {
+	ticks   chan chan error
+	started chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+}
+
+// NewTicker returns a ticker, its pipeline is set up by Wrap.
+func NewTicker() *Ticker {
+	return &Ticker{
+		ticks:   make(chan chan error),
+		started: make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+}
+
+// Wrap returns an iterator yielding the batches of the iterator, and a tick whenever
+// one is sent. The ticks are yielded between the batches, once the store processed the
+// previous batch, at the last block returned by lastBlock. It must be the last wrapper
+// of the pipeline, the other wrappers don't expect the ticks. The iterator can only be
+// read once.
+func (t *Ticker) Wrap(iterator arkivevents.BatchIterator, lastBlock func() (uint64, error)) arkivevents.BatchIterator {
+	return func(yield func(arkivevents.BatchOrError) bool) {
+		t.once.Do(func() { close(t.started) })
+		defer close(t.stopped)
+
+		// The batches are read in a goroutine to wait for them and for the ticks at
+		// once. Every batch waits for the store to process it, like when the store reads
+		// the iterator directly, the wrappers of the pipeline rely on it.
+		type handoff struct {
+			batch arkivevents.BatchOrError
+			done  chan bool
+		}
+		batches := make(chan handoff)
+		quit := make(chan struct{})
+		defer close(quit)
+		go func() {
+			defer close(batches)
+			for batch := range iterator {
+				h := handoff{batch: batch, done: make(chan bool)}
+				select {
+				case batches <- h:
+				case <-quit:
+					return
+				}
+				if !<-h.done {
+					return
+				}
+			}
+		}()
+
+		for {
+			select {
+			case h, ok := <-batches:
+				if !ok {
+					return
+				}
+				more := yield(h.batch)
+				h.done <- more
+				if !more {
+					return
+				}
+			case reply := <-t.ticks:
+				last, err := lastBlock()
+				if err != nil {
+					reply <- err
+					continue
+				}
+				if !yield(arkivevents.BatchOrError{Batch: events.BlockBatch{Blocks: []events.Block{{Number: last}}}}) {
+					reply <- ErrEventsStopped
+					return
+				}
+				reply <- nil
+			}
+		}
+	}
+}
+
+// Tick sends a tick through the pipeline and waits for the store to process it. It
+// returns ErrEventsNotStarted or ErrEventsStopped if the store isn't reading the
+// pipeline, or the error of the context if the store is busy until it's done.
+func (t *Ticker) Tick(ctx context.Context) error {
+	select {
+	case <-t.started:
+	case <-ctx.Done():
+		return ErrEventsNotStarted
+	}
+
+	reply := make(chan error, 1)
+	select {
+	case t.ticks <- reply:
+	case <-t.stopped:
+		return ErrEventsStopped
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case err := <-reply:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,95 @@
+package dbevents
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	arkivevents "github.com/Arkiv-Network/arkiv-events"
+	"github.com/stretchr/testify/require"
+)
+
+// followTicker reads the pipeline of the ticker over the batches sent to the channel
+// like the store does, and returns the channel of the batches it read.
+func followTicker(ticker *Ticker, batches <-chan arkivevents.BatchOrError) <-chan arkivevents.BatchOrError {
+	iterator := func(yield func(arkivevents.BatchOrError) bool) {
+		for batch := range batches {
+			if !yield(batch) {
+				return
+			}
+		}
+	}
+	last := uint64(4)
+	read := make(chan arkivevents.BatchOrError, 10)
+	go func() {
+		defer close(read)
+		for batch := range ticker.Wrap(iterator, func() (uint64, error) { return last, nil }) {
+			last = batch.Batch.Blocks[len(batch.Batch.Blocks)-1].Number
+			read <- batch
+		}
+	}()
+	return read
+}
+
+func TestTicker(t *testing.T) {
+	ticker := NewTicker()
+	batches := make(chan arkivevents.BatchOrError)
+	read := followTicker(ticker, batches)
+
+	batches <- batchOf(5, 6)
+	require.Equal(t, batchOf(5, 6), <-read)
+
+	// The tick is at the last block ingested
+	require.NoError(t, ticker.Tick(context.Background()))
+	require.Equal(t, batchOf(6), <-read)
+
+	batches <- batchOf(7)
+	require.Equal(t, batchOf(7), <-read)
+
+	close(batches)
+	_, ok := <-read
+	require.False(t, ok)
+	require.ErrorIs(t, ticker.Tick(context.Background()), ErrEventsStopped)
+}
+
+func TestTicker_NotStarted(t *testing.T) {
+	ticker := NewTicker()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, ticker.Tick(ctx), ErrEventsNotStarted)
+}
+
+func TestTicker_Busy(t *testing.T) {
+	ticker := NewTicker()
+	batches := make(chan arkivevents.BatchOrError)
+	iterator := func(yield func(arkivevents.BatchOrError) bool) {
+		for batch := range batches {
+			if !yield(batch) {
+				return
+			}
+		}
+	}
+	processing := make(chan struct{})
+	release := make(chan struct{})
+	go func() {
+		for range ticker.Wrap(iterator, func() (uint64, error) { return 0, nil }) {
+			processing <- struct{}{}
+			<-release
+		}
+	}()
+	defer close(batches)
+
+	// The tick waits for the batch being processed
+	batches <- batchOf(1)
+	<-processing
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, ticker.Tick(ctx), context.DeadlineExceeded)
+
+	release <- struct{}{}
+	done := make(chan error)
+	go func() { done <- ticker.Tick(context.Background()) }()
+	<-processing
+	release <- struct{}{}
+	require.NoError(t, <-done)
+}
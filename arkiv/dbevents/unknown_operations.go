@@ -0,0 +1,130 @@
+package dbevents
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/andybalholm/brotli"
+	"github.com/ethereum/go-ethereum/arkiv/address"
+	"github.com/ethereum/go-ethereum/arkiv/logs"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// unknownOperationsCounter counts the Arkiv operations the pipeline couldn't map to
+// events.
+var unknownOperationsCounter = metrics.NewRegisteredCounter("arkiv/ingest/unknown", nil)
+
+const (
+	// UnknownOperationTransaction is the kind of a transaction whose operations couldn't
+	// be told apart, because its calldata isn't an RLP list.
+	UnknownOperationTransaction = "transaction"
+
+	// UnknownOperationLogPrefix prefixes the kind of the logs of the processor with an
+	// unknown topic, followed by the topic.
+	UnknownOperationLogPrefix = "log:"
+)
+
+// UnknownOperations are the operations of a successful transaction the pipeline
+// couldn't map to events, counted by kind: the operations of a transaction it
+// couldn't decode, such as one of a newer version, by the field of the transaction
+// holding them, and the logs of the processor with an unknown topic.
+type UnknownOperations struct {
+	TxIndex uint64            `json:"txIndex"`
+	TxHash  common.Hash       `json:"txHash"`
+	Kinds   map[string]uint64 `json:"kinds"`
+}
+
+// Count returns the number of unknown operations of the transaction.
+func (u *UnknownOperations) Count() uint64 {
+	count := uint64(0)
+	for _, n := range u.Kinds {
+		count += n
+	}
+	return count
+}
+
+// maxUndecodableSize is the maximum size of the decompressed calldata of a transaction
+// whose operations are counted.
+const maxUndecodableSize = 20 * 1024 * 1024
+
+// operationFields names the fields of an Arkiv transaction holding operations, by
+// position, the version field is not an operation.
+var operationFields = []string{"create", "update", "delete", "extend", "changeOwner", "", "acceptOwnership", "registerAlias", "transferAlias", "reduceBTL", "changeOwnerBatch"}
+
+// knownLogs are the topics of the logs of the processor the pipeline either maps to
+// events or knows not to change the store, like the logs of the aliases, which the
+// store doesn't hold.
+var knownLogs = map[common.Hash]bool{
+	logs.ArkivEntityCreated:                   true,
+	logs.ArkivEntityUpdated:                   true,
+	logs.ArkivEntityExpired:                   true,
+	logs.ArkivEntityDeleted:                   true,
+	logs.ArkivEntityBTLExtended:               true,
+	logs.ArkivEntityOwnerChanged:              true,
+	logs.ArkivEntityOwnershipTransferProposed: true,
+	logs.ArkivEntityOwnershipTransferAccepted: true,
+	logs.ArkivEntityOwnershipTransferLapsed:   true,
+	logs.ArkivAliasRegistered:                 true,
+	logs.ArkivAliasTransferred:                true,
+	logs.ArkivAliasExpired:                    true,
+	logs.ArkivCreateDeduplicated:              true,
+	logs.ArkivEntityBTLReduced:                true,
+}
+
+// undecodableOperations counts the operations of the calldata of a transaction the
+// pipeline couldn't decode by the field holding them. The fields past the known ones
+// are named after their position.
+func undecodableOperations(calldata []byte) map[string]uint64 {
+	kinds := map[string]uint64{}
+
+	data, err := io.ReadAll(io.LimitReader(brotli.NewReader(bytes.NewReader(calldata)), maxUndecodableSize))
+	if err != nil {
+		kinds[UnknownOperationTransaction] = 1
+		return kinds
+	}
+	content, _, err := rlp.SplitList(data)
+	if err != nil {
+		kinds[UnknownOperationTransaction] = 1
+		return kinds
+	}
+
+	for position := 0; len(content) > 0; position++ {
+		kind, value, rest, err := rlp.Split(content)
+		if err != nil {
+			break
+		}
+		content = rest
+
+		name := fmt.Sprintf("field%d", position)
+		if position < len(operationFields) {
+			name = operationFields[position]
+		}
+		if kind != rlp.List || name == "" {
+			continue
+		}
+		count, err := rlp.CountValues(value)
+		if err != nil || count == 0 {
+			continue
+		}
+		kinds[name] += uint64(count)
+	}
+
+	if len(kinds) == 0 {
+		kinds[UnknownOperationTransaction] = 1
+	}
+	return kinds
+}
+
+// unknownLogs counts the logs of the processor in the receipt with an unknown topic.
+func unknownLogs(receipt *types.Receipt, kinds map[string]uint64) {
+	for _, log := range receipt.Logs {
+		if log.Address != address.ArkivProcessorAddress || len(log.Topics) == 0 || knownLogs[log.Topics[0]] {
+			continue
+		}
+		kinds[UnknownOperationLogPrefix+log.Topics[0].Hex()]++
+	}
+}
@@ -0,0 +1,88 @@
+package dbevents
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/arkiv/address"
+	"github.com/ethereum/go-ethereum/arkiv/compression"
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnknownOperations(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	signer := types.LatestSignerForChainID(big.NewInt(1))
+	sign := func(tx *types.Transaction) *types.Transaction {
+		signed, err := types.SignTx(tx, signer, key)
+		require.NoError(t, err)
+		return signed
+	}
+
+	// A transaction of a newer version
+	newer := sign(arkivTx(t, &storagetx.ArkivTransaction{
+		Version: storagetx.CurrentTransactionVersion + 1,
+		Create: []storagetx.ArkivCreate{
+			{BTL: 10, ContentType: "text/plain", Payload: []byte("first")},
+			{BTL: 10, ContentType: "text/plain", Payload: []byte("second")},
+		},
+	}))
+
+	// A transaction with a field this version doesn't know
+	empty := []any{}
+	data, err := rlp.EncodeToBytes([]any{
+		empty, empty, []common.Hash{common.HexToHash("0x1")}, empty, empty,
+		uint64(storagetx.CurrentTransactionVersion), empty, empty, empty, empty,
+		empty, [][]byte{{1}, {2}, {3}},
+	})
+	require.NoError(t, err)
+	extraField := sign(types.NewTx(&types.DynamicFeeTx{
+		Nonce: 1,
+		To:    &address.ArkivProcessorAddress,
+		Data:  compression.MustBrotliCompress(data),
+	}))
+
+	// A transaction the pipeline decodes, whose receipt has a log it doesn't know
+	known := sign(arkivTx(t, &storagetx.ArkivTransaction{
+		Version: storagetx.CurrentTransactionVersion,
+		Delete:  []common.Hash{common.HexToHash("0x2")},
+	}))
+	unknownTopic := common.HexToHash("0xff")
+
+	// A failed transaction doesn't change the store
+	failed := sign(types.NewTx(&types.DynamicFeeTx{
+		Nonce: 2,
+		To:    &address.ArkivProcessorAddress,
+		Data:  []byte("not an arkiv transaction"),
+	}))
+
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(7)}).WithBody(types.Body{
+		Transactions: []*types.Transaction{newer, extraField, known, failed},
+	})
+	receipts := []*types.Receipt{
+		{Status: types.ReceiptStatusSuccessful},
+		{Status: types.ReceiptStatusSuccessful},
+		{Status: types.ReceiptStatusSuccessful, Logs: []*types.Log{
+			{Address: address.ArkivProcessorAddress, Topics: []common.Hash{unknownTopic}},
+		}},
+		{Status: types.ReceiptStatusFailed},
+	}
+
+	decoded, unknown, err := blockToEvents(block, receipts)
+	require.NoError(t, err)
+	require.Equal(t, []UnknownOperations{
+		{TxIndex: 0, TxHash: newer.Hash(), Kinds: map[string]uint64{"create": 2}},
+		{TxIndex: 1, TxHash: extraField.Hash(), Kinds: map[string]uint64{"delete": 1, "field11": 3}},
+		{TxIndex: 2, TxHash: known.Hash(), Kinds: map[string]uint64{UnknownOperationLogPrefix + unknownTopic.Hex(): 1}},
+	}, unknown)
+	require.Equal(t, uint64(4), unknown[1].Count())
+
+	// The undecodable transactions are skipped rather than failing the block
+	require.Len(t, decoded.Operations, 1)
+	require.NotNil(t, decoded.Operations[0].Delete)
+}
@@ -0,0 +1,14 @@
+Feature: Encryption info
+
+  Scenario: Encryption info is returned with the entity
+    When I submit a storage transaction creating an entity encrypted with "aes-256-gcm"
+    Then the submitted transaction should be mined successfully
+    And the entity should be returned with encryption scheme "aes-256-gcm"
+
+  Scenario: Plaintext entities carry no encryption info
+    Given I have created an entity
+    Then the entity should be returned without encryption info
+
+  Scenario: Unknown encryption schemes are rejected
+    When I submit a storage transaction creating an entity encrypted with "rot13"
+    Then last error should mention "is not allowed"
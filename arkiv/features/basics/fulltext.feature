@@ -0,0 +1,44 @@
+Feature: Full-text search
+
+  Background:
+    Given I create a "application/json" entity of kind "person" remembered as "alice" with payload
+      """
+      {"name": "Alice Smith", "city": "Prague"}
+      """
+    And I create a "text/plain" entity of kind "person" remembered as "bob" with payload
+      """
+      Bob Jones lives in Prague
+      """
+    And I create a "application/json" entity of kind "city" remembered as "prague" with payload
+      """
+      {"name": "Prague", "country": "Czechia"}
+      """
+
+  Scenario: Text matches are combined with annotation predicates
+    When I search for entities matching the text "prague" with the query
+      """
+      kind = "person"
+      """
+    Then the search results should be "alice, bob"
+
+  Scenario: Text matches are substrings of the payload
+    When I search for entities matching the text "rag" with the query
+      """
+      $all
+      """
+    Then the search results should be "alice, bob, prague"
+
+  Scenario: The keys of JSON payloads are not indexed
+    When I search for entities matching the text "city" with the query
+      """
+      $all
+      """
+    Then I should find 0 entities
+
+  Scenario: Binary payloads are not indexed
+    Given I have created an entity
+    When I search for entities matching the text "payload" with the query
+      """
+      $all
+      """
+    Then I should find 0 entities
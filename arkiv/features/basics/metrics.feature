@@ -0,0 +1,16 @@
+Feature: Metrics
+
+  Scenario: Query latency is reported by query shape
+    Given I have created an entity
+    And I read the Arkiv metrics
+    When I search for all entities
+    Then the Arkiv metric "arkiv_query_latency_fullscan_count" should increase
+
+  Scenario: Store metrics move after an ingested block
+    Given I have created an entity
+    And I read the Arkiv metrics
+    When I create an entity remembered as "second"
+    Then the Arkiv metric "arkiv_store_entities" should increase
+    And the Arkiv metric "arkiv_store_size" should be reported
+    And the Arkiv metric "arkiv_ingest_lag_blocks" should be reported
+    And the Arkiv metric "arkiv_ingest_lag_seconds" should be reported
@@ -0,0 +1,14 @@
+Feature: Query diff
+
+  Scenario: Diff of a query between two blocks
+    Given I have created an entity
+    And I remember the entity as "updated"
+    And there is an entity that will expire in the next block
+    And I remember the entity as "expired"
+    And I remember the current block
+    When I update the entity "updated"
+    And I create an entity remembered as "added"
+    And I diff the query "$all" between the remembered block and the current block
+    Then the diff should list "added" as added
+    And the diff should list "expired" as removed
+    And the diff should list "updated" as changed
@@ -0,0 +1,24 @@
+Feature: Submitting storage transactions
+
+  Scenario: Gas is estimated by default
+    When I submit a storage transaction creating an entity
+    Then the submitted transaction should be mined successfully
+
+  Scenario: Explicit gas limit
+    When I submit a storage transaction creating an entity with gas limit 3000000
+    Then the submitted transaction should be mined successfully
+    And the submitted transaction should have gas limit 3000000
+
+  Scenario: Explicit fee caps
+    When I submit a storage transaction creating an entity with fee caps 2 and 20 gwei
+    Then the submitted transaction should be mined successfully
+    And the submitted transaction should have fee caps 2 and 20 gwei
+
+  Scenario: Signing with another account
+    When I submit a storage transaction creating an entity signed by the second account
+    Then the submitted transaction should be mined successfully
+    And the submitted transaction should be sent by the second account
+
+  Scenario: Custom codec
+    When I submit a storage transaction creating an entity with an invalid codec
+    Then last error should mention "failed to decompress arkiv transaction data"
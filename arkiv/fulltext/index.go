@@ -0,0 +1,336 @@
+// Package fulltext maintains an optional full-text index of the payloads of Arkiv
+// entities with text content types, backed by SQLite FTS5.
+//
+// The index lives next to the Arkiv store and is populated at ingest time, from the
+// same batches of events as the store. It only indexes the latest version of every
+// entity, so text matches are always evaluated against the current payloads.
+//
+// FTS5 is only available when the sqlite driver is built with the `sqlite_fts5` tag.
+package fulltext
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"iter"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/arkiv/limits"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const (
+	// DefaultMaxPayloadSize is the default size limit of the indexed payloads in bytes.
+	DefaultMaxPayloadSize = 64 * 1024
+
+	// MaxMatches is the maximum number of entities a text match can select.
+	MaxMatches = limits.MaxTextMatches
+)
+
+// DefaultContentTypes are the content types indexed by default.
+var DefaultContentTypes = []string{"text/*", "application/json"}
+
+// ErrUnavailable is returned by Open if the sqlite driver was built without FTS5.
+var ErrUnavailable = errors.New("full-text search requires the sqlite driver to be built with the sqlite_fts5 tag")
+
+// ErrTooManyMatches is returned by Match if the text matches more than MaxMatches entities.
+var ErrTooManyMatches = fmt.Errorf("search text matches more than %d entities", MaxMatches)
+
+// Config is the indexing policy of the full-text index.
+type Config struct {
+	// ContentTypes are the content types whose payloads are indexed, either media types
+	// or types with a wildcard subtype, e.g. `text/*`.
+	ContentTypes []string
+	// MaxPayloadSize is the size limit of the indexed payloads in bytes. Larger payloads
+	// are not indexed.
+	MaxPayloadSize uint64
+}
+
+// policy returns the canonical form of the config stored in the index, so that a
+// change of the policy can be detected.
+func (c Config) policy() string {
+	contentTypes := make([]string, len(c.ContentTypes))
+	for i, contentType := range c.ContentTypes {
+		contentTypes[i] = strings.ToLower(strings.TrimSpace(contentType))
+	}
+	slices.Sort(contentTypes)
+	return strings.Join(slices.Compact(contentTypes), ",") + ";" + strconv.FormatUint(c.MaxPayloadSize, 10)
+}
+
+// Entity is the indexed content of an entity.
+type Entity struct {
+	Key         common.Hash
+	ContentType string
+	Payload     []byte
+}
+
+// Stats describes the size of the index.
+type Stats struct {
+	Entities int64
+	Size     int64
+}
+
+const schema = `
+CREATE TABLE IF NOT EXISTS fulltext_meta (
+	key TEXT PRIMARY KEY,
+	value TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS fulltext_entities (
+	id INTEGER PRIMARY KEY,
+	entity_key BLOB NOT NULL UNIQUE
+);
+CREATE VIRTUAL TABLE IF NOT EXISTS fulltext_payloads USING fts5(content, tokenize = 'trigram');
+`
+
+const (
+	metaPolicy    = "policy"
+	metaLastBlock = "last_block"
+)
+
+// Index is the full-text index of the entity payloads.
+type Index struct {
+	db     *sql.DB
+	config Config
+}
+
+// Open opens or creates the full-text index at path.
+func Open(path string, config Config) (*Index, error) {
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?_journal_mode=WAL&_busy_timeout=5000", path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open full-text index: %w", err)
+	}
+
+	// A single connection serializes the writes of the ingestion with the reads of
+	// the queries, and keeps in-memory indexes on a single database.
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec(schema)
+	if err != nil {
+		db.Close()
+		if strings.Contains(err.Error(), "no such module: fts5") {
+			return nil, ErrUnavailable
+		}
+		return nil, fmt.Errorf("failed to create full-text index: %w", err)
+	}
+
+	return &Index{
+		db:     db,
+		config: config,
+	}, nil
+}
+
+// Close closes the index.
+func (i *Index) Close() error {
+	return i.db.Close()
+}
+
+// Indexable reports whether a payload with the content type and size is indexed.
+func (i *Index) Indexable(contentType string, size int) bool {
+	return uint64(size) <= i.config.MaxPayloadSize && matchContentType(i.config.ContentTypes, contentType)
+}
+
+// MaxPayloadSize returns the size limit of the indexed payloads in bytes.
+func (i *Index) MaxPayloadSize() uint64 {
+	return i.config.MaxPayloadSize
+}
+
+func (i *Index) meta(ctx context.Context, key string) (string, error) {
+	var value string
+	err := i.db.QueryRowContext(ctx, "SELECT value FROM fulltext_meta WHERE key = ?", key).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", key, err)
+	}
+	return value, nil
+}
+
+func setMeta(ctx context.Context, tx *sql.Tx, key string, value string) error {
+	_, err := tx.ExecContext(ctx, "INSERT INTO fulltext_meta (key, value) VALUES (?, ?) ON CONFLICT (key) DO UPDATE SET value = excluded.value", key, value)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", key, err)
+	}
+	return nil
+}
+
+// NeedsReindex reports whether the index has to be rebuilt before following the
+// events after lastBlock, because the indexing policy changed since it was built
+// or because it's not in sync with the store.
+func (i *Index) NeedsReindex(ctx context.Context, lastBlock uint64) (bool, error) {
+	policy, err := i.meta(ctx, metaPolicy)
+	if err != nil {
+		return false, err
+	}
+	if policy != i.config.policy() {
+		return true, nil
+	}
+
+	indexed, err := i.meta(ctx, metaLastBlock)
+	if err != nil {
+		return false, err
+	}
+	return indexed != strconv.FormatUint(lastBlock, 10), nil
+}
+
+// Reindex replaces the content of the index with the entities, which must be the
+// entities of the store at lastBlock.
+func (i *Index) Reindex(ctx context.Context, entities iter.Seq2[Entity, error], lastBlock uint64) error {
+	tx, err := i.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, table := range []string{"fulltext_entities", "fulltext_payloads"} {
+		_, err = tx.ExecContext(ctx, "DELETE FROM "+table)
+		if err != nil {
+			return fmt.Errorf("failed to clear %s: %w", table, err)
+		}
+	}
+
+	indexed := 0
+	for entity, err := range entities {
+		if err != nil {
+			return fmt.Errorf("failed to read entities: %w", err)
+		}
+		if !i.Indexable(entity.ContentType, len(entity.Payload)) {
+			continue
+		}
+		err = put(ctx, tx, entity)
+		if err != nil {
+			return err
+		}
+		indexed++
+	}
+
+	err = setMeta(ctx, tx, metaPolicy, i.config.policy())
+	if err != nil {
+		return err
+	}
+
+	err = setMeta(ctx, tx, metaLastBlock, strconv.FormatUint(lastBlock, 10))
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit reindex: %w", err)
+	}
+
+	log.Info("Arkiv full-text index rebuilt", "entities", indexed, "block", lastBlock)
+
+	return nil
+}
+
+// put adds the entity to the index, replacing its previous payload.
+func put(ctx context.Context, tx *sql.Tx, entity Entity) error {
+	var id int64
+	err := tx.QueryRowContext(
+		ctx,
+		"INSERT INTO fulltext_entities (entity_key) VALUES (?) ON CONFLICT (entity_key) DO UPDATE SET entity_key = excluded.entity_key RETURNING id",
+		entity.Key.Bytes(),
+	).Scan(&id)
+	if err != nil {
+		return fmt.Errorf("failed to index entity %s: %w", entity.Key.Hex(), err)
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM fulltext_payloads WHERE rowid = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to index entity %s: %w", entity.Key.Hex(), err)
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO fulltext_payloads (rowid, content) VALUES (?, ?)", id, ExtractText(entity.ContentType, entity.Payload))
+	if err != nil {
+		return fmt.Errorf("failed to index entity %s: %w", entity.Key.Hex(), err)
+	}
+
+	return nil
+}
+
+// remove removes the entity from the index, if it's indexed.
+func remove(ctx context.Context, tx *sql.Tx, key common.Hash) error {
+	var id int64
+	err := tx.QueryRowContext(ctx, "DELETE FROM fulltext_entities WHERE entity_key = ? RETURNING id", key.Bytes()).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to remove entity %s: %w", key.Hex(), err)
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM fulltext_payloads WHERE rowid = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to remove entity %s: %w", key.Hex(), err)
+	}
+
+	return nil
+}
+
+// Match returns the keys of the entities whose payload contains all the terms of the text.
+func (i *Index) Match(ctx context.Context, text string) ([]common.Hash, error) {
+	expression, err := matchExpression(text)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := i.db.QueryContext(
+		ctx,
+		`SELECT e.entity_key FROM fulltext_payloads p
+		JOIN fulltext_entities e ON e.id = p.rowid
+		WHERE fulltext_payloads MATCH ?
+		ORDER BY e.entity_key
+		LIMIT ?`,
+		expression,
+		MaxMatches+1,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to match text: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []common.Hash{}
+	for rows.Next() {
+		var key []byte
+		err = rows.Scan(&key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan entity key: %w", err)
+		}
+		keys = append(keys, common.BytesToHash(key))
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("failed to match text: %w", err)
+	}
+
+	if len(keys) > MaxMatches {
+		return nil, ErrTooManyMatches
+	}
+
+	return keys, nil
+}
+
+// Stats returns the number of indexed entities and the size of the index in bytes.
+func (i *Index) Stats(ctx context.Context) (Stats, error) {
+	stats := Stats{}
+
+	err := i.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM fulltext_entities").Scan(&stats.Entities)
+	if err != nil {
+		return stats, fmt.Errorf("failed to count indexed entities: %w", err)
+	}
+
+	err = i.db.QueryRowContext(ctx, "SELECT page_count * page_size FROM pragma_page_count(), pragma_page_size()").Scan(&stats.Size)
+	if err != nil {
+		return stats, fmt.Errorf("failed to get the size of the index: %w", err)
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,199 @@
+package fulltext
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Arkiv-Network/arkiv-events/events"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+var testConfig = Config{
+	ContentTypes:   DefaultContentTypes,
+	MaxPayloadSize: 1024,
+}
+
+func openIndex(t *testing.T, path string, config Config) *Index {
+	t.Helper()
+	index, err := Open(path, config)
+	if errors.Is(err, ErrUnavailable) {
+		t.Skip("sqlite driver built without the sqlite_fts5 tag")
+	}
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		index.Close()
+	})
+	return index
+}
+
+func createOp(key common.Hash, contentType string, payload string) events.Operation {
+	return events.Operation{
+		Create: &events.OPCreate{
+			Key:         key,
+			ContentType: contentType,
+			Content:     []byte(payload),
+		},
+	}
+}
+
+func applyOps(t *testing.T, index *Index, number uint64, ops ...events.Operation) {
+	t.Helper()
+	err := index.ApplyBatch(context.Background(), events.BlockBatch{
+		Blocks: []events.Block{{Number: number, Operations: ops}},
+	})
+	require.NoError(t, err)
+}
+
+func requireMatches(t *testing.T, index *Index, text string, expected ...common.Hash) {
+	t.Helper()
+	keys, err := index.Match(context.Background(), text)
+	require.NoError(t, err)
+	if len(expected) == 0 {
+		expected = []common.Hash{}
+	}
+	require.Equal(t, expected, keys, "text %q", text)
+}
+
+func TestExtractText_JSONValues(t *testing.T) {
+	payload := `{"name": "Alice Smith", "tags": ["red", "blue"], "age": 42, "address": {"city": "Prague"}, "active": true}`
+	require.Equal(t, "Prague\n42\nAlice Smith\nred\nblue", ExtractText("application/json", []byte(payload)))
+	require.Equal(t, "Prague", ExtractText("application/ld+json; charset=utf-8", []byte(`{"city": "Prague"}`)))
+
+	// Invalid JSON and other content types are indexed as they are
+	require.Equal(t, `{"name": "Alice`, ExtractText("application/json", []byte(`{"name": "Alice`)))
+	require.Equal(t, payload, ExtractText("text/plain", []byte(payload)))
+}
+
+func TestMatchExpression(t *testing.T) {
+	expression, err := matchExpression(`  alice "smith"  `)
+	require.NoError(t, err)
+	require.Equal(t, `"alice" AND """smith"""`, expression)
+
+	_, err = matchExpression(" ")
+	require.ErrorContains(t, err, "search text is empty")
+
+	_, err = matchExpression("alice al")
+	require.ErrorContains(t, err, `search term "al" is too short`)
+}
+
+func TestIndex_JSONValuesAreIndexed(t *testing.T) {
+	index := openIndex(t, ":memory:", testConfig)
+
+	key := common.HexToHash("0x01")
+	applyOps(t, index, 1, createOp(key, "application/json", `{"name": "Alice Smith", "city": "Prague"}`))
+
+	requireMatches(t, index, "alice", key)
+	requireMatches(t, index, "ALICE smith", key)
+	requireMatches(t, index, "rag", key)
+	requireMatches(t, index, "alice berlin")
+
+	// The keys of the document are not indexed
+	requireMatches(t, index, "name")
+	requireMatches(t, index, "city")
+}
+
+func TestIndex_Policy(t *testing.T) {
+	index := openIndex(t, ":memory:", testConfig)
+
+	text := common.HexToHash("0x01")
+	binary := common.HexToHash("0x02")
+	oversized := common.HexToHash("0x03")
+	applyOps(
+		t, index, 1,
+		createOp(text, "text/plain; charset=utf-8", "hello world"),
+		createOp(binary, "application/octet-stream", "hello world"),
+		createOp(oversized, "text/plain", "hello world "+strings.Repeat("a", 1024)),
+	)
+	requireMatches(t, index, "hello", text)
+
+	// Updating the payload to an oversized one removes it from the index
+	applyOps(t, index, 2, events.Operation{
+		Update: &events.OPUpdate{
+			Key:         text,
+			ContentType: "text/plain",
+			Content:     []byte("hello world " + strings.Repeat("a", 1024)),
+		},
+	})
+	requireMatches(t, index, "hello")
+
+	stats, err := index.Stats(context.Background())
+	require.NoError(t, err)
+	require.Zero(t, stats.Entities)
+	require.Positive(t, stats.Size)
+}
+
+func TestIndex_DeleteAndExpire(t *testing.T) {
+	index := openIndex(t, ":memory:", testConfig)
+
+	deleted := common.HexToHash("0x01")
+	expired := common.HexToHash("0x02")
+	kept := common.HexToHash("0x03")
+	applyOps(
+		t, index, 1,
+		createOp(deleted, "text/plain", "hello world"),
+		createOp(expired, "text/plain", "hello world"),
+		createOp(kept, "text/plain", "hello world"),
+	)
+	requireMatches(t, index, "world", deleted, expired, kept)
+
+	del := events.OPDelete(deleted)
+	exp := events.OPExpire(expired)
+	applyOps(t, index, 2, events.Operation{Delete: &del}, events.Operation{Expire: &exp})
+	requireMatches(t, index, "world", kept)
+}
+
+func entitiesOf(entities ...Entity) iter.Seq2[Entity, error] {
+	return func(yield func(Entity, error) bool) {
+		for _, entity := range entities {
+			if !yield(entity, nil) {
+				return
+			}
+		}
+	}
+}
+
+func TestIndex_ReindexWhenPolicyChanges(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "fulltext.db")
+
+	index := openIndex(t, path, testConfig)
+
+	needsReindex, err := index.NeedsReindex(ctx, 0)
+	require.NoError(t, err)
+	require.True(t, needsReindex)
+
+	key := common.HexToHash("0x01")
+	entities := entitiesOf(Entity{Key: key, ContentType: "application/xml", Payload: []byte("<name>Alice</name>")})
+	require.NoError(t, index.Reindex(ctx, entities, 5))
+
+	needsReindex, err = index.NeedsReindex(ctx, 5)
+	require.NoError(t, err)
+	require.False(t, needsReindex)
+	requireMatches(t, index, "alice")
+
+	// The index is behind the store
+	needsReindex, err = index.NeedsReindex(ctx, 6)
+	require.NoError(t, err)
+	require.True(t, needsReindex)
+	require.NoError(t, index.Close())
+
+	// The same policy in a different order doesn't require a reindex
+	index = openIndex(t, path, Config{ContentTypes: []string{"application/json", "text/*"}, MaxPayloadSize: 1024})
+	needsReindex, err = index.NeedsReindex(ctx, 5)
+	require.NoError(t, err)
+	require.False(t, needsReindex)
+	require.NoError(t, index.Close())
+
+	index = openIndex(t, path, Config{ContentTypes: []string{"application/xml"}, MaxPayloadSize: 1024})
+	needsReindex, err = index.NeedsReindex(ctx, 5)
+	require.NoError(t, err)
+	require.True(t, needsReindex)
+
+	require.NoError(t, index.Reindex(ctx, entities, 5))
+	requireMatches(t, index, "alice", key)
+}
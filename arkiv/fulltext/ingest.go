@@ -0,0 +1,96 @@
+package fulltext
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	arkivevents "github.com/Arkiv-Network/arkiv-events"
+	"github.com/Arkiv-Network/arkiv-events/events"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// ApplyBatch updates the index with the operations of a batch of blocks.
+// Created and updated entities are indexed if their content type and size match the
+// policy, the other ones are removed from the index together with the deleted and
+// expired entities.
+func (i *Index) ApplyBatch(ctx context.Context, batch events.BlockBatch) error {
+	if len(batch.Blocks) == 0 {
+		return nil
+	}
+
+	tx, err := i.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	upsert := func(key common.Hash, contentType string, payload []byte) error {
+		if !i.Indexable(contentType, len(payload)) {
+			return remove(ctx, tx, key)
+		}
+		return put(ctx, tx, Entity{Key: key, ContentType: contentType, Payload: payload})
+	}
+
+	for _, block := range batch.Blocks {
+		for _, op := range block.Operations {
+			switch {
+			case op.Create != nil:
+				err = upsert(op.Create.Key, op.Create.ContentType, op.Create.Content)
+			case op.Update != nil:
+				err = upsert(op.Update.Key, op.Update.ContentType, op.Update.Content)
+			case op.Delete != nil:
+				err = remove(ctx, tx, common.Hash(*op.Delete))
+			case op.Expire != nil:
+				err = remove(ctx, tx, common.Hash(*op.Expire))
+			}
+			if err != nil {
+				return fmt.Errorf("block %d: %w", block.Number, err)
+			}
+		}
+	}
+
+	lastBlock := batch.Blocks[len(batch.Blocks)-1].Number
+	err = setMeta(ctx, tx, metaLastBlock, strconv.FormatUint(lastBlock, 10))
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit batch: %w", err)
+	}
+
+	return nil
+}
+
+// Wrap returns an iterator yielding the batches of the iterator after applying them to
+// the index. If a batch can't be applied, the index is marked as stale and is no longer
+// updated until it's rebuilt on the next start. The batches are yielded regardless.
+func (i *Index) Wrap(iterator arkivevents.BatchIterator) arkivevents.BatchIterator {
+	return func(yield func(arkivevents.BatchOrError) bool) {
+		stale := false
+		for batch := range iterator {
+			if batch.Error == nil && !stale {
+				err := i.ApplyBatch(context.Background(), batch.Batch)
+				if err != nil {
+					log.Error("Failed to update the Arkiv full-text index, it will be rebuilt on restart", "error", err)
+					i.markStale()
+					stale = true
+				}
+			}
+			if !yield(batch) {
+				return
+			}
+		}
+	}
+}
+
+// markStale forgets the last indexed block, which forces a reindex on the next start.
+func (i *Index) markStale() {
+	_, err := i.db.Exec("DELETE FROM fulltext_meta WHERE key = ?", metaLastBlock)
+	if err != nil {
+		log.Error("Failed to mark the Arkiv full-text index as stale", "error", err)
+	}
+}
@@ -0,0 +1,124 @@
+package fulltext
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"mime"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// MinTermLength is the minimum length of a search term in characters. The index is
+// built from trigrams, so shorter terms can't be matched.
+const MinTermLength = 3
+
+// mediaType returns the lower case media type of a content type, without its parameters.
+func mediaType(contentType string) string {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mt, _, _ = strings.Cut(contentType, ";")
+	}
+	return strings.ToLower(strings.TrimSpace(mt))
+}
+
+// matchContentType reports whether the content type matches one of the patterns.
+// A pattern is either a media type or a type followed by a wildcard subtype, e.g. `text/*`.
+func matchContentType(patterns []string, contentType string) bool {
+	mt := mediaType(contentType)
+	if mt == "" {
+		return false
+	}
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if prefix, ok := strings.CutSuffix(pattern, "/*"); ok {
+			if strings.HasPrefix(mt, prefix+"/") {
+				return true
+			}
+			continue
+		}
+		if mt == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// isJSON reports whether the content type is JSON, including structured syntax
+// suffixes like `application/ld+json`.
+func isJSON(contentType string) bool {
+	mt := mediaType(contentType)
+	return mt == "application/json" || strings.HasSuffix(mt, "+json")
+}
+
+// ExtractText returns the text of a payload that is indexed.
+// For JSON payloads only the string and number values are indexed, one per line,
+// so that the keys and the syntax of the document don't match searches.
+// Payloads that are not valid JSON are indexed as plain text.
+func ExtractText(contentType string, payload []byte) string {
+	if isJSON(contentType) {
+		values, err := jsonValues(payload)
+		if err == nil {
+			return strings.Join(values, "\n")
+		}
+	}
+	if utf8.Valid(payload) {
+		return string(payload)
+	}
+	return strings.ToValidUTF8(string(payload), " ")
+}
+
+func jsonValues(payload []byte) ([]string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+
+	var document any
+	err := decoder.Decode(&document)
+	if err != nil {
+		return nil, err
+	}
+
+	values := []string{}
+	var walk func(v any)
+	walk = func(v any) {
+		switch v := v.(type) {
+		case string:
+			values = append(values, v)
+		case json.Number:
+			values = append(values, v.String())
+		case []any:
+			for _, e := range v {
+				walk(e)
+			}
+		case map[string]any:
+			for _, key := range slices.Sorted(maps.Keys(v)) {
+				walk(v[key])
+			}
+		}
+	}
+	walk(document)
+
+	return values, nil
+}
+
+// matchExpression turns a search text into an FTS5 match expression that requires all
+// the whitespace separated terms of the text to appear in the payload.
+// Every term is quoted, so the text can't use the FTS5 query syntax.
+func matchExpression(text string) (string, error) {
+	terms := strings.Fields(text)
+	if len(terms) == 0 {
+		return "", fmt.Errorf("search text is empty")
+	}
+
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		if utf8.RuneCountInString(term) < MinTermLength {
+			return "", fmt.Errorf("search term %q is too short, terms must be at least %d characters long", term, MinTermLength)
+		}
+		quoted[i] = `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+	}
+
+	return strings.Join(quoted, " AND "), nil
+}
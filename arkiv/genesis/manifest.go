@@ -0,0 +1,186 @@
+// Package genesis describes the Arkiv entities seeded by the genesis allocation.
+//
+// The state only holds the metadata and the expiration index of the entities, so the
+// arkiv section of the genesis JSON lists them with their payloads and attributes. The
+// manifest is checked against the storage of the processor in the allocation, and the
+// events pipeline indexes its entities before the first block.
+package genesis
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"math/big"
+	"slices"
+
+	"github.com/Arkiv-Network/arkiv-events/events"
+	"github.com/ethereum/go-ethereum/arkiv/address"
+	"github.com/ethereum/go-ethereum/arkiv/storageaccounting"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Manifest is the arkiv section of the genesis JSON.
+type Manifest struct {
+	Entities []Entity `json:"entities"`
+}
+
+// Entity is an entity seeded by the genesis allocation.
+type Entity struct {
+	Key               common.Hash       `json:"key"`
+	Owner             common.Address    `json:"owner"`
+	ExpiresAtBlock    uint64            `json:"expiresAtBlock"`
+	ContentType       string            `json:"contentType"`
+	Payload           hexutil.Bytes     `json:"payload"`
+	StringAttributes  map[string]string `json:"stringAttributes,omitempty"`
+	NumericAttributes map[string]uint64 `json:"numericAttributes,omitempty"`
+	// ContentHash is the keccak256 hash of the payload. It is optional and lets the
+	// payload be checked against the hash the entity was published with.
+	ContentHash *common.Hash `json:"contentHash,omitempty"`
+}
+
+// ErrUndeclaredEntities is returned when the storage of the processor holds entities
+// the manifest doesn't declare. The store can't index them without their payloads.
+var ErrUndeclaredEntities = errors.New("genesis allocation holds Arkiv entities missing from the manifest")
+
+// slots is an in-memory storage of the processor.
+type slots map[common.Hash]common.Hash
+
+func (s slots) GetState(_ common.Address, key common.Hash) common.Hash {
+	return s[key]
+}
+
+func (s slots) SetState(_ common.Address, key common.Hash, value common.Hash) common.Hash {
+	prev := s[key]
+	if value == (common.Hash{}) {
+		delete(s, key)
+	} else {
+		s[key] = value
+	}
+	return prev
+}
+
+// Storage returns the storage of the processor holding the entities of the manifest,
+// to seed them in the genesis allocation.
+func (m *Manifest) Storage() (map[common.Hash]common.Hash, error) {
+	storage := slots{}
+	if m == nil {
+		return storage, nil
+	}
+	for i, e := range m.Entities {
+		if err := e.validate(); err != nil {
+			return nil, fmt.Errorf("genesis entity %d: %w", i, err)
+		}
+		err := entity.Store(storage, e.Key, e.Owner, entity.EntityMetaData{Owner: e.Owner, ExpiresAtBlock: e.ExpiresAtBlock}, e.Payload, false)
+		if err != nil {
+			return nil, fmt.Errorf("genesis entity %s: %w", e.Key.Hex(), err)
+		}
+	}
+	if len(storage) > 0 {
+		storage[storageaccounting.UsedSlotsKey] = common.BigToHash(big.NewInt(int64(len(storage))))
+	}
+	return storage, nil
+}
+
+// Validate checks the manifest against the storage of the processor in the genesis
+// allocation, which must be the storage of its entities. The slot usage counter is
+// optional. A nil manifest declares no entities.
+func (m *Manifest) Validate(storage map[common.Hash]common.Hash) error {
+	expected, err := m.Storage()
+	if err != nil {
+		return err
+	}
+	delete(expected, storageaccounting.UsedSlotsKey)
+
+	state := slots{}
+	for k, v := range storage {
+		if k != storageaccounting.UsedSlotsKey {
+			state.SetState(address.ArkivProcessorAddress, k, v)
+		}
+	}
+
+	if m != nil {
+		for _, e := range m.Entities {
+			if !entity.Exists(state, e.Key) {
+				return fmt.Errorf("genesis entity %s is not in the allocation", e.Key.Hex())
+			}
+			emd, err := entity.GetEntityMetaData(state, e.Key)
+			if err != nil {
+				return err
+			}
+			if emd.Owner != e.Owner || emd.ExpiresAtBlock != e.ExpiresAtBlock {
+				return fmt.Errorf("genesis entity %s is owned by %s and expires at block %d in the allocation, not by %s at block %d",
+					e.Key.Hex(), emd.Owner.Hex(), emd.ExpiresAtBlock, e.Owner.Hex(), e.ExpiresAtBlock)
+			}
+		}
+	}
+	for k, v := range state {
+		if expected[k] != v {
+			return fmt.Errorf("%w: slot %s", ErrUndeclaredEntities, k.Hex())
+		}
+	}
+	for k := range expected {
+		if _, ok := state[k]; !ok {
+			return fmt.Errorf("genesis allocation lacks the processor slot %s of the manifest", k.Hex())
+		}
+	}
+
+	if used, ok := storage[storageaccounting.UsedSlotsKey]; ok && used.Big().Uint64() != uint64(len(expected)) {
+		return fmt.Errorf("genesis allocation counts %d used slots, the manifest uses %d", used.Big().Uint64(), len(expected))
+	}
+	return nil
+}
+
+func (e *Entity) validate() error {
+	if e.ExpiresAtBlock == 0 {
+		return errors.New("expiresAtBlock must be after the genesis block")
+	}
+	if e.ContentHash != nil {
+		if hash := crypto.Keccak256Hash(e.Payload); hash != *e.ContentHash {
+			return fmt.Errorf("payload of %s hashes to %s, not to its content hash %s", e.Key.Hex(), hash.Hex(), e.ContentHash.Hex())
+		}
+	}
+	for k := range e.StringAttributes {
+		if !entity.AnnotationIdentRegexCompiled.MatchString(k) {
+			return fmt.Errorf("invalid string attribute key %q of %s", k, e.Key.Hex())
+		}
+	}
+	for k := range e.NumericAttributes {
+		if !entity.AnnotationIdentRegexCompiled.MatchString(k) {
+			return fmt.Errorf("invalid numeric attribute key %q of %s", k, e.Key.Hex())
+		}
+	}
+	return nil
+}
+
+// Block returns the creates of the entities of the manifest at block 0, in the order
+// of the manifest.
+func (m *Manifest) Block() *events.Block {
+	block := &events.Block{Number: 0}
+	if m == nil {
+		return block
+	}
+	for i, e := range m.Entities {
+		block.Operations = append(block.Operations, events.Operation{
+			OpIndex: uint64(i),
+			Create: &events.OPCreate{
+				Key:               e.Key,
+				ContentType:       e.ContentType,
+				BTL:               e.ExpiresAtBlock,
+				Owner:             e.Owner,
+				Content:           slices.Clone(e.Payload),
+				StringAttributes:  cloneOrEmpty(e.StringAttributes),
+				NumericAttributes: cloneOrEmpty(e.NumericAttributes),
+			},
+		})
+	}
+	return block
+}
+
+func cloneOrEmpty[V any](m map[string]V) map[string]V {
+	c := make(map[string]V, len(m))
+	maps.Copy(c, m)
+	return c
+}
@@ -0,0 +1,100 @@
+package genesis
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/arkiv/storageaccounting"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func testManifest() *Manifest {
+	hash := crypto.Keccak256Hash([]byte("hello"))
+	return &Manifest{Entities: []Entity{
+		{
+			Key:               common.HexToHash("0x01"),
+			Owner:             common.HexToAddress("0xaa"),
+			ExpiresAtBlock:    100,
+			ContentType:       "text/plain",
+			Payload:           []byte("hello"),
+			StringAttributes:  map[string]string{"kind": "greeting"},
+			NumericAttributes: map[string]uint64{"version": 1},
+			ContentHash:       &hash,
+		},
+		{
+			Key:            common.HexToHash("0x02"),
+			Owner:          common.HexToAddress("0xbb"),
+			ExpiresAtBlock: 100,
+			ContentType:    "application/octet-stream",
+			Payload:        []byte{1, 2, 3},
+		},
+	}}
+}
+
+func TestManifest_Validate(t *testing.T) {
+	manifest := testManifest()
+	storage, err := manifest.Storage()
+	require.NoError(t, err)
+	require.NoError(t, manifest.Validate(storage))
+
+	// The slot usage counter is optional
+	delete(storage, storageaccounting.UsedSlotsKey)
+	require.NoError(t, manifest.Validate(storage))
+
+	// Nothing declared, nothing seeded
+	require.NoError(t, (*Manifest)(nil).Validate(nil))
+	require.NoError(t, (*Manifest)(nil).Validate(map[common.Hash]common.Hash{storageaccounting.UsedSlotsKey: {}}))
+}
+
+func TestManifest_ValidateUndeclared(t *testing.T) {
+	manifest := testManifest()
+	storage, err := manifest.Storage()
+	require.NoError(t, err)
+
+	manifest.Entities = manifest.Entities[:1]
+	require.ErrorIs(t, manifest.Validate(storage), ErrUndeclaredEntities)
+	require.ErrorIs(t, (*Manifest)(nil).Validate(storage), ErrUndeclaredEntities)
+}
+
+func TestManifest_ValidateMismatch(t *testing.T) {
+	storage, err := testManifest().Storage()
+	require.NoError(t, err)
+
+	manifest := testManifest()
+	manifest.Entities[1].Owner = common.HexToAddress("0xcc")
+	require.ErrorContains(t, manifest.Validate(storage), "is owned by 0x00000000000000000000000000000000000000bb")
+
+	manifest = testManifest()
+	manifest.Entities = append(manifest.Entities, Entity{Key: common.HexToHash("0x03"), ExpiresAtBlock: 10})
+	require.ErrorContains(t, manifest.Validate(storage), "is not in the allocation")
+
+	manifest = testManifest()
+	manifest.Entities[0].Payload = []byte("bye")
+	require.ErrorContains(t, manifest.Validate(storage), "not to its content hash")
+
+	manifest = testManifest()
+	manifest.Entities[0].ExpiresAtBlock = 0
+	require.ErrorContains(t, manifest.Validate(storage), "expiresAtBlock must be after the genesis block")
+
+	manifest = testManifest()
+	storage[storageaccounting.UsedSlotsKey] = common.BigToHash(common.Big1)
+	require.ErrorContains(t, manifest.Validate(storage), "counts 1 used slots")
+}
+
+func TestManifest_Block(t *testing.T) {
+	block := testManifest().Block()
+	require.Equal(t, uint64(0), block.Number)
+	require.Len(t, block.Operations, 2)
+
+	create := block.Operations[0].Create
+	require.Equal(t, common.HexToHash("0x01"), create.Key)
+	require.Equal(t, uint64(100), create.BTL)
+	require.Equal(t, []byte("hello"), create.Content)
+	require.Equal(t, map[string]string{"kind": "greeting"}, create.StringAttributes)
+
+	// The store adds the synthetic attributes to the maps of the creates
+	require.NotNil(t, block.Operations[1].Create.StringAttributes)
+	require.NotNil(t, block.Operations[1].Create.NumericAttributes)
+	require.Equal(t, uint64(1), block.Operations[1].OpIndex)
+}
@@ -2,7 +2,6 @@ package housekeepingtx
 
 import (
 	"fmt"
-	"slices"
 
 	"github.com/ethereum/go-ethereum/arkiv/address"
 	arkivlogs "github.com/ethereum/go-ethereum/arkiv/logs"
@@ -21,7 +20,28 @@ func addressToHash(a common.Address) common.Hash {
 	return h
 }
 
-func ExecuteTransaction(blockNumber uint64, txHash common.Hash, db vm.StateDB) (_ []*types.Log, err error) {
+// LogAppender receives the logs of the housekeeping transaction as they are emitted,
+// the StateDB of the transaction is one.
+type LogAppender interface {
+	AddLog(log *types.Log)
+}
+
+// LogAppenderFunc is a function used as a LogAppender.
+type LogAppenderFunc func(log *types.Log)
+
+// AddLog calls f with the log.
+func (f LogAppenderFunc) AddLog(log *types.Log) {
+	f(log)
+}
+
+// ExecuteTransaction expires the entities whose BTL ends at the block with the rules of
+// the chain, see Rules. If the tombstone retention is not 0, expired entities leave a
+// tombstone and the tombstones whose retention ends at the block are swept. If the
+// transfer window is not 0, the ownership transfers not accepted within the window
+// lapse at the block. The logs are passed to the appender as the entities are expired,
+// they aren't kept by the transaction, so a block expiring many entities doesn't hold
+// them twice.
+func ExecuteTransaction(blockNumber uint64, txHash common.Hash, rules Rules, db vm.StateDB, logs LogAppender) (err error) {
 
 	// create the golem base storage processor address if it doesn't exist
 	// this is needed to be able to use the state access interface
@@ -31,8 +51,6 @@ func ExecuteTransaction(blockNumber uint64, txHash common.Hash, db vm.StateDB) (
 		db.SetNonce(address.ArkivProcessorAddress, 1, tracing.NonceChangeNewContract)
 	}
 
-	logs := []*types.Log{}
-
 	st := storageaccounting.NewSlotUsageCounter(db)
 
 	defer func() {
@@ -43,14 +61,37 @@ func ExecuteTransaction(blockNumber uint64, txHash common.Hash, db vm.StateDB) (
 
 	deleteEntity := func(toDelete common.Hash) error {
 
+		if rules.OwnerSlots {
+			owner, slots := entity.UsedSlots(st, toDelete)
+			st.AddOwnerSlots(owner, -int64(slots))
+		}
+
 		owner, err := entity.Delete(st, toDelete)
 		if err != nil {
 			return fmt.Errorf("failed to delete entity: %w", err)
 		}
 
-		// create the log for the created entity
-		logs = append(
-			logs,
+		if rules.TransferWindow > 0 {
+			err = entity.DeletePendingOwner(st, toDelete)
+			if err != nil {
+				return fmt.Errorf("failed to delete pending owner: %w", err)
+			}
+		}
+
+		if rules.TombstoneRetention > 0 {
+			err = entity.StoreTombstone(
+				st,
+				toDelete,
+				entity.Tombstone{Reason: entity.TombstoneExpired, Block: blockNumber},
+				blockNumber+rules.TombstoneRetention,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to store tombstone: %w", err)
+			}
+		}
+
+		// create the log for the expired entity
+		logs.AddLog(
 			&types.Log{
 				Address: common.Address(address.ArkivProcessorAddress),
 				Topics: []common.Hash{
@@ -66,14 +107,89 @@ func ExecuteTransaction(blockNumber uint64, txHash common.Hash, db vm.StateDB) (
 		return nil
 	}
 
-	toDelete := slices.Collect(entityexpiration.IteratorOfEntitiesToExpireAtBlock(st, blockNumber))
+	if rules.TombstoneRetention > 0 {
+		entity.SweepTombstones(st, blockNumber)
+	}
+
+	// the keys are collected before deleting the entities, which removes them from the
+	// bucket being iterated
+	toDelete := make([]common.Hash, 0, entityexpiration.SizeOfEntitiesToExpireAtBlock(st, blockNumber))
+	for key := range entityexpiration.IteratorOfEntitiesToExpireAtBlock(st, blockNumber) {
+		toDelete = append(toDelete, key)
+	}
 
 	for _, key := range toDelete {
 		err := deleteEntity(key)
 		if err != nil {
-			return nil, fmt.Errorf("failed to delete entity %s: %w", key.Hex(), err)
+			return fmt.Errorf("failed to delete entity %s: %w", key.Hex(), err)
 		}
 	}
 
-	return logs, nil
+	if rules.TransferWindow > 0 {
+		lapsedOwners := entity.LapsePendingOwners(st, blockNumber)
+		if rules.OwnerSlots {
+			keys := make([]common.Hash, len(lapsedOwners))
+			for i, lapsed := range lapsedOwners {
+				keys[i] = lapsed.Key
+			}
+			mds, _ := entity.GetEntityMetaDataBatch(st, keys)
+			for _, md := range mds {
+				if md != nil {
+					st.AddOwnerSlots(md.Owner, -entity.PendingOwnerUsedSlots)
+				}
+			}
+		}
+		for _, lapsed := range lapsedOwners {
+			logs.AddLog(
+				&types.Log{
+					Address: common.Address(address.ArkivProcessorAddress),
+					Topics: []common.Hash{
+						arkivlogs.ArkivEntityOwnershipTransferLapsed,
+						lapsed.Key,
+						addressToHash(lapsed.Owner),
+					},
+					Data:        []byte{},
+					BlockNumber: blockNumber,
+				},
+			)
+		}
+	}
+
+	if rules.Aliases {
+		for _, expired := range entity.ExpireAliases(st, blockNumber) {
+			if rules.OwnerSlots {
+				st.AddOwnerSlots(expired.Owner, -entity.AliasUsedSlots)
+			}
+			logs.AddLog(
+				&types.Log{
+					Address: common.Address(address.ArkivProcessorAddress),
+					Topics: []common.Hash{
+						arkivlogs.ArkivAliasExpired,
+						expired.NameHash,
+						addressToHash(expired.Owner),
+					},
+					Data:        expired.Key.Bytes(),
+					BlockNumber: blockNumber,
+				},
+			)
+		}
+	}
+
+	if rules.Idempotency {
+		entity.ExpireIdempotencyKeys(st, blockNumber)
+	}
+
+	return nil
+}
+
+// L1AttributesDepositor is the sender of the L1 attributes deposit, the first
+// transaction of every block.
+var L1AttributesDepositor = common.HexToAddress("0xDeaDDEaDDeAdDeAdDEAdDEaddeAddEAdDEAd0001")
+
+// RunsIn returns whether the housekeeping runs in a deposit from the sender to the
+// recipient. Before the housekeeping order fork it runs in every deposit, from the fork
+// only in the L1 attributes deposit, so that it expires the entities of the block
+// before all its other transactions whatever the deposits of the block.
+func RunsIn(from common.Address, to common.Address, ordered bool) bool {
+	return !ordered || (from == L1AttributesDepositor && to == types.L1BlockAddr)
 }
@@ -0,0 +1,35 @@
+package housekeepingtx
+
+import "github.com/ethereum/go-ethereum/params"
+
+// Rules are the parameters of the chain the housekeeping of a block is run with, built
+// once per block with RulesAt.
+type Rules struct {
+	// TombstoneRetention is the number of blocks the tombstone of an expired entity is
+	// kept, expired entities leave no tombstone if it's 0.
+	TombstoneRetention uint64
+	// TransferWindow is the number of blocks the new owner of an entity has to accept
+	// an ownership change, no change is pending if it's 0.
+	TransferWindow uint64
+
+	// OwnerSlots counts the slots used by the entities and the aliases of each owner.
+	OwnerSlots bool
+	// Aliases expires the aliases whose BTL ends at the block.
+	Aliases bool
+	// Idempotency forgets the idempotency keys whose TTL ends at the block.
+	Idempotency bool
+	// Ordered runs the housekeeping in the L1 attributes deposit only, see RunsIn.
+	Ordered bool
+}
+
+// RulesAt returns the rules of the housekeeping of the blocks applied at time.
+func RulesAt(config *params.ChainConfig, time uint64) Rules {
+	return Rules{
+		TombstoneRetention: config.ArkivTombstoneRetentionAt(time),
+		TransferWindow:     config.ArkivOwnershipTransferWindowAt(time),
+		OwnerSlots:         config.IsArkivOwnerSlots(time),
+		Aliases:            config.IsArkivAliases(time),
+		Idempotency:        config.IsArkivIdempotency(time),
+		Ordered:            config.IsArkivHousekeepingOrder(time),
+	}
+}
@@ -0,0 +1,27 @@
+package housekeepingtx
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRulesAt(t *testing.T) {
+	activation := uint64(100)
+	config := &params.ChainConfig{ArkivTombstonesTime: &activation, ArkivAliasesTime: &activation, ArkivHousekeepingOrderTime: &activation}
+
+	before := RulesAt(config, 99)
+	require.Zero(t, before.TombstoneRetention)
+	require.False(t, before.Aliases)
+	require.False(t, before.Ordered)
+
+	after := RulesAt(config, 100)
+	require.Equal(t, params.DefaultArkivTombstoneRetention, after.TombstoneRetention)
+	require.True(t, after.Aliases)
+	require.True(t, after.Ordered)
+	require.False(t, after.Idempotency)
+
+	// Before every fork the housekeeping only expires the entities
+	require.Equal(t, Rules{}, RulesAt(&params.ChainConfig{}, 0))
+}
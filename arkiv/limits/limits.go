@@ -0,0 +1,200 @@
+// Package limits is the registry of the limits enforced on the Arkiv transactions and
+// requests. The code enforcing a limit reads its value here, arkiv_getLimits reports
+// the registry, so the reported values are the enforced ones.
+package limits
+
+import "github.com/ethereum/go-ethereum/params"
+
+// Version is the version of the document of the limits returned by arkiv_getLimits.
+// It's bumped when the meaning of an existing limit changes, new limits are appended.
+const Version = 1
+
+// Scope is who sets a limit.
+type Scope string
+
+const (
+	// Consensus limits are enforced by every node processing the chain, changing them
+	// forks the chain.
+	Consensus Scope = "consensus"
+	// Node limits are the policy of the node serving the request, other nodes may
+	// enforce different ones.
+	Node Scope = "node"
+)
+
+// The consensus limits of the Arkiv transactions.
+const (
+	// MaxDecompressedSize is the number of bytes of the calldata of a transaction read
+	// after decompression, the rest is ignored and fails the decoding.
+	MaxDecompressedSize = 20 * 1024 * 1024
+
+	// MaxOperations is the maximum number of operations of a transaction.
+	MaxOperations = 1000
+
+	// MaxContentTypeLength is the maximum length of the content type of an entity in
+	// bytes.
+	MaxContentTypeLength = 128
+
+	// MaxAnnotationKeyDepth is the maximum number of segments of an annotation key.
+	MaxAnnotationKeyDepth = 8
+
+	// MaxFixedPointDecimals is the maximum number of decimals of a fixed-point
+	// annotation.
+	MaxFixedPointDecimals = 18
+
+	// MaxEncryptionSchemeLength is the maximum length of the encryption scheme
+	// identifier in bytes.
+	MaxEncryptionSchemeLength = 64
+
+	// MaxEncryptionKeyIDLength is the maximum length of the encryption key id in bytes.
+	MaxEncryptionKeyIDLength = 256
+
+	// MaxEncryptionNonceLength is the maximum length of the encryption nonce in bytes.
+	MaxEncryptionNonceLength = 64
+
+	// MaxAliasNameLength is the maximum length of the name of an alias in bytes.
+	MaxAliasNameLength = 64
+
+	// MaxContentPointerLength is the maximum length of the pointer to a payload stored
+	// off chain in bytes.
+	MaxContentPointerLength = 512
+)
+
+// The fixed node limits of the arkiv RPC.
+const (
+	// MaxQueryDiffBlocks is the largest distance between the blocks QueryDiff compares.
+	MaxQueryDiffBlocks = 43_200
+
+	// MaxQueryDiffEntries is the maximum number of keys returned by QueryDiff.
+	MaxQueryDiffEntries = 10_000
+
+	// MaxProcessorLogsScanBlocks is the largest number of blocks a call to
+	// GetProcessorLogs scans.
+	MaxProcessorLogsScanBlocks = 43_200
+
+	// MaxProcessorLogsLimit is the largest number of logs GetProcessorLogs returns.
+	MaxProcessorLogsLimit = 10_000
+
+	// MaxUsageReportBlocks is the largest range of blocks a usage report covers.
+	MaxUsageReportBlocks = 43_200
+
+	// MaxRewindBlocks is how far before a block the creation of an entity is looked
+	// for to rebuild the entity at the block.
+	MaxRewindBlocks = 43_200
+
+	// MaxTextMatches is the maximum number of entities a text match can select.
+	MaxTextMatches = 10_000
+
+	// MaxContentHashPairs is the largest number of pairs a call to VerifyContentHashes
+	// checks, and of the results of a notification of its subscription.
+	MaxContentHashPairs = 10_000
+
+	// MaxSampleEntities is the largest sample of entities SampleEntities draws.
+	MaxSampleEntities = 10_000
+
+	// MaxOwnerEntitiesPerPage is the largest page of GetEntitiesOfOwner.
+	MaxOwnerEntitiesPerPage = 1_000
+
+	// MaxEntitiesToExpireBlocks is the largest range of blocks GetEntitiesToExpire
+	// walks.
+	MaxEntitiesToExpireBlocks = 10_000
+
+	// MaxOrderedQueryEntities is the largest number of entities a query ordered by an
+	// annotation can select.
+	MaxOrderedQueryEntities = 100_000
+
+	// MaxChallengeKeys is the largest number of entities a storage challenge covers.
+	MaxChallengeKeys = 1_000
+	// MaxOwnerClassReads is the largest number of owners whose code a call to
+	// GetEntitiesByOwnerClass reads from the state.
+	MaxOwnerClassReads = 1_000
+
+	// MaxSimulationOffset is the largest number of blocks SimulateTransaction runs the
+	// housekeeping of before the transaction.
+	MaxSimulationOffset = 1_000
+)
+
+// Limit is a limit enforced on the Arkiv transactions or requests.
+type Limit struct {
+	Name  string
+	Scope Scope
+	// Unit is what the value counts: bytes, blocks, entities...
+	Unit  string
+	Value uint64
+	// Feature is the fork the limit activates with, nil if it's always enforced.
+	Feature *params.ArkivFeature
+}
+
+var (
+	gasSchedule     = params.ArkivFeatureGasSchedule
+	typedNumerics   = params.ArkivFeatureTypedNumerics
+	encryption      = params.ArkivFeatureEncryption
+	dottedKeys      = params.ArkivFeatureDottedKeys
+	tombstones      = params.ArkivFeatureTombstones
+	twoStepTransfer = params.ArkivFeatureTwoStepTransfer
+	aliases         = params.ArkivFeatureAliases
+	maxBTL          = params.ArkivFeatureMaxBTL
+	idempotency     = params.ArkivFeatureIdempotency
+	payloadLimits   = params.ArkivFeaturePayloadLimits
+	externalContent = params.ArkivFeatureExternalContent
+)
+
+// ConsensusLimits returns the consensus limits of the chain at time. The values of the
+// fork-gated limits are the ones of the chain config at time, or at the activation of
+// their fork if it's later, 0 if their fork isn't scheduled.
+func ConsensusLimits(config *params.ChainConfig, time uint64) []Limit {
+	at := func(feature params.ArkivFeature) uint64 {
+		if spec, ok := params.ArkivFeatureByID(feature); ok {
+			if activation := spec.Activation(config); activation != nil && *activation > time {
+				return *activation
+			}
+		}
+		return time
+	}
+	// The payload limits fork turns the decoding limits into chain parameters
+	maxDecompressedSize, maxOperations := uint64(MaxDecompressedSize), uint64(MaxOperations)
+	if config.IsArkivPayloadLimits(time) {
+		maxDecompressedSize, maxOperations = config.ArkivMaxDecompressedSizeAt(time), config.ArkivMaxOperationsAt(time)
+	}
+	return []Limit{
+		{Name: "maxDecompressedSize", Scope: Consensus, Unit: "bytes", Value: maxDecompressedSize},
+		{Name: "maxOperations", Scope: Consensus, Unit: "operations", Value: maxOperations},
+		{Name: "maxContentTypeLength", Scope: Consensus, Unit: "bytes", Value: MaxContentTypeLength},
+		{Name: "maxAnnotationValueSize", Scope: Consensus, Unit: "bytes", Value: config.ArkivMaxAnnotationValueSizeAt(at(gasSchedule)), Feature: &gasSchedule},
+		{Name: "annotationValueGasThreshold", Scope: Consensus, Unit: "bytes", Value: config.ArkivAnnotationValueGasThresholdAt(at(gasSchedule)), Feature: &gasSchedule},
+		{Name: "annotationValueGasPerByte", Scope: Consensus, Unit: "gas", Value: config.ArkivAnnotationValueGasPerByteAt(at(gasSchedule)), Feature: &gasSchedule},
+		{Name: "maxAnnotationKeyDepth", Scope: Consensus, Unit: "segments", Value: MaxAnnotationKeyDepth, Feature: &dottedKeys},
+		{Name: "maxFixedPointDecimals", Scope: Consensus, Unit: "decimals", Value: MaxFixedPointDecimals, Feature: &typedNumerics},
+		{Name: "maxEncryptionSchemeLength", Scope: Consensus, Unit: "bytes", Value: MaxEncryptionSchemeLength, Feature: &encryption},
+		{Name: "maxEncryptionKeyIDLength", Scope: Consensus, Unit: "bytes", Value: MaxEncryptionKeyIDLength, Feature: &encryption},
+		{Name: "maxEncryptionNonceLength", Scope: Consensus, Unit: "bytes", Value: MaxEncryptionNonceLength, Feature: &encryption},
+		{Name: "tombstoneRetention", Scope: Consensus, Unit: "blocks", Value: config.ArkivTombstoneRetentionAt(at(tombstones)), Feature: &tombstones},
+		{Name: "ownershipTransferWindow", Scope: Consensus, Unit: "blocks", Value: config.ArkivOwnershipTransferWindowAt(at(twoStepTransfer)), Feature: &twoStepTransfer},
+		{Name: "maxAliasNameLength", Scope: Consensus, Unit: "bytes", Value: MaxAliasNameLength, Feature: &aliases},
+		{Name: "maxBTL", Scope: Consensus, Unit: "blocks", Value: config.ArkivMaxBTLAt(at(maxBTL)), Feature: &maxBTL},
+		{Name: "idempotencyTTL", Scope: Consensus, Unit: "blocks", Value: config.ArkivIdempotencyTTLAt(at(idempotency)), Feature: &idempotency},
+		{Name: "maxPayloadSize", Scope: Consensus, Unit: "bytes", Value: config.ArkivMaxPayloadSizeAt(at(payloadLimits)), Feature: &payloadLimits},
+		{Name: "maxContentPointerLength", Scope: Consensus, Unit: "bytes", Value: MaxContentPointerLength, Feature: &externalContent},
+	}
+}
+
+// NodeLimits returns the fixed node limits of the arkiv RPC, the limits set by the
+// configuration of the node are added by the RPC.
+func NodeLimits() []Limit {
+	return []Limit{
+		{Name: "maxQueryDiffBlocks", Scope: Node, Unit: "blocks", Value: MaxQueryDiffBlocks},
+		{Name: "maxQueryDiffEntries", Scope: Node, Unit: "entities", Value: MaxQueryDiffEntries},
+		{Name: "maxProcessorLogsScanBlocks", Scope: Node, Unit: "blocks", Value: MaxProcessorLogsScanBlocks},
+		{Name: "maxProcessorLogsLimit", Scope: Node, Unit: "logs", Value: MaxProcessorLogsLimit},
+		{Name: "maxUsageReportBlocks", Scope: Node, Unit: "blocks", Value: MaxUsageReportBlocks},
+		{Name: "maxRewindBlocks", Scope: Node, Unit: "blocks", Value: MaxRewindBlocks},
+		{Name: "maxTextMatches", Scope: Node, Unit: "entities", Value: MaxTextMatches},
+		{Name: "maxContentHashPairs", Scope: Node, Unit: "pairs", Value: MaxContentHashPairs},
+		{Name: "maxSampleEntities", Scope: Node, Unit: "entities", Value: MaxSampleEntities},
+		{Name: "maxOwnerEntitiesPerPage", Scope: Node, Unit: "entities", Value: MaxOwnerEntitiesPerPage},
+		{Name: "maxEntitiesToExpireBlocks", Scope: Node, Unit: "blocks", Value: MaxEntitiesToExpireBlocks},
+		{Name: "maxOrderedQueryEntities", Scope: Node, Unit: "entities", Value: MaxOrderedQueryEntities},
+		{Name: "maxChallengeKeys", Scope: Node, Unit: "entities", Value: MaxChallengeKeys},
+		{Name: "maxOwnerClassReads", Scope: Node, Unit: "accounts", Value: MaxOwnerClassReads},
+		{Name: "maxSimulationOffset", Scope: Node, Unit: "blocks", Value: MaxSimulationOffset},
+	}
+}
@@ -0,0 +1,74 @@
+package limits
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsensusLimits_ForkGated(t *testing.T) {
+	activation := uint64(100)
+	config := &params.ChainConfig{ArkivTombstonesTime: &activation, ArkivTombstoneRetention: 50}
+	retention := func(config *params.ChainConfig, time uint64) Limit {
+		for _, limit := range ConsensusLimits(config, time) {
+			if limit.Name == "tombstoneRetention" {
+				return limit
+			}
+		}
+		t.Fatal("tombstoneRetention not found")
+		return Limit{}
+	}
+
+	// Before the fork the limit is the one it activates with
+	require.Equal(t, uint64(50), retention(config, 10).Value)
+	require.Equal(t, uint64(50), retention(config, 200).Value)
+	require.Equal(t, params.ArkivFeatureTombstones, *retention(config, 10).Feature)
+
+	// The limits of the forks that aren't scheduled are 0
+	require.Zero(t, retention(&params.ChainConfig{}, 10).Value)
+}
+
+func TestConsensusLimits_GasSchedule(t *testing.T) {
+	activation := uint64(100)
+	config := &params.ChainConfig{ArkivGasScheduleTime: &activation, ArkivAnnotationValueGasPerByte: 16}
+	values := make(map[string]Limit)
+	for _, limit := range ConsensusLimits(config, 10) {
+		values[limit.Name] = limit
+	}
+
+	require.Equal(t, params.DefaultArkivMaxAnnotationValueSize, values["maxAnnotationValueSize"].Value)
+	require.Equal(t, params.DefaultArkivAnnotationValueGasThreshold, values["annotationValueGasThreshold"].Value)
+	require.Equal(t, uint64(16), values["annotationValueGasPerByte"].Value)
+	require.Equal(t, params.ArkivFeatureGasSchedule, *values["annotationValueGasPerByte"].Feature)
+}
+
+func TestConsensusLimits_PayloadLimits(t *testing.T) {
+	// The defaults of the chain parameters are the limits before the fork
+	require.Equal(t, uint64(MaxDecompressedSize), params.DefaultArkivMaxDecompressedSize)
+	require.Equal(t, uint64(MaxOperations), params.DefaultArkivMaxOperations)
+
+	activation := uint64(100)
+	config := &params.ChainConfig{ArkivPayloadLimitsTime: &activation, ArkivMaxPayloadSize: 4096, ArkivMaxOperations: 10}
+	values := func(time uint64) map[string]uint64 {
+		values := make(map[string]uint64)
+		for _, limit := range ConsensusLimits(config, time) {
+			values[limit.Name] = limit.Value
+		}
+		return values
+	}
+
+	before, after := values(10), values(100)
+	require.Equal(t, uint64(MaxOperations), before["maxOperations"])
+	require.Equal(t, uint64(10), after["maxOperations"])
+	require.Equal(t, uint64(MaxDecompressedSize), after["maxDecompressedSize"])
+	require.Equal(t, uint64(4096), before["maxPayloadSize"])
+	require.Equal(t, uint64(4096), after["maxPayloadSize"])
+}
+
+func TestNodeLimits(t *testing.T) {
+	for _, limit := range NodeLimits() {
+		require.Equal(t, Node, limit.Scope)
+		require.Nil(t, limit.Feature)
+	}
+}
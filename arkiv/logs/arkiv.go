@@ -22,6 +22,46 @@ var ArkivEntityDeleted = crypto.Keccak256Hash([]byte("ArkivEntityDeleted(uint256
 // Parameters: entityKey (indexed), ownerAddress(indexed), oldExpirationBlock, newExpirationBlock, cost (wei)
 var ArkivEntityBTLExtended = crypto.Keccak256Hash([]byte("ArkivEntityBTLExtended(uint256,address,uint256,uint256,uint256)"))
 
+// ArkivEntityBTLReduced is the event signature for the owner bringing the expiry of an
+// entity forward.
+// Parameters: entityKey (indexed), ownerAddress(indexed), oldExpirationBlock, newExpirationBlock
+var ArkivEntityBTLReduced = crypto.Keccak256Hash([]byte("ArkivEntityBTLReduced(uint256,address,uint256,uint256)"))
+
 // ArkivEntityOwnerChanged is the event signature for changing the owner of an entity.
 // Parameters: entityKey (indexed), oldOwnerAddress(indexed), newOwnerAddress(indexed)
 var ArkivEntityOwnerChanged = crypto.Keccak256Hash([]byte("ArkivEntityOwnerChanged(uint256,address,address)"))
+
+// ArkivEntityOwnershipTransferProposed is the event signature for proposing to transfer
+// an entity to a new owner.
+// Parameters: entityKey (indexed), ownerAddress(indexed), newOwnerAddress(indexed), lapseBlock
+var ArkivEntityOwnershipTransferProposed = crypto.Keccak256Hash([]byte("ArkivEntityOwnershipTransferProposed(uint256,address,address,uint256)"))
+
+// ArkivEntityOwnershipTransferAccepted is the event signature for the new owner accepting
+// the transfer of an entity, it is followed by an ArkivEntityOwnerChanged log.
+// Parameters: entityKey (indexed), oldOwnerAddress(indexed), newOwnerAddress(indexed)
+var ArkivEntityOwnershipTransferAccepted = crypto.Keccak256Hash([]byte("ArkivEntityOwnershipTransferAccepted(uint256,address,address)"))
+
+// ArkivEntityOwnershipTransferLapsed is the event signature for a transfer of an entity
+// the new owner didn't accept in time.
+// Parameters: entityKey (indexed), newOwnerAddress(indexed)
+var ArkivEntityOwnershipTransferLapsed = crypto.Keccak256Hash([]byte("ArkivEntityOwnershipTransferLapsed(uint256,address)"))
+
+// ArkivAliasRegistered is the event signature for registering, repointing or renewing an
+// alias. The name follows the two words of the data.
+// Parameters: nameHash (indexed), ownerAddress(indexed), entityKey, expirationBlock, name
+var ArkivAliasRegistered = crypto.Keccak256Hash([]byte("ArkivAliasRegistered(bytes32,address,uint256,uint256,string)"))
+
+// ArkivAliasTransferred is the event signature for giving an alias to a new owner. The
+// data is the name.
+// Parameters: nameHash (indexed), oldOwnerAddress(indexed), newOwnerAddress(indexed), name
+var ArkivAliasTransferred = crypto.Keccak256Hash([]byte("ArkivAliasTransferred(bytes32,address,address,string)"))
+
+// ArkivAliasExpired is the event signature for alias expiration logs.
+// Parameters: nameHash (indexed), ownerAddress(indexed), entityKey
+var ArkivAliasExpired = crypto.Keccak256Hash([]byte("ArkivAliasExpired(bytes32,address,uint256)"))
+
+// ArkivCreateDeduplicated is the event signature for the creates skipped because the
+// sender created an entity with the same idempotency key within its TTL. The data is
+// the idempotency key.
+// Parameters: entityKey (indexed), ownerAddress(indexed), idempotencyKey
+var ArkivCreateDeduplicated = crypto.Keccak256Hash([]byte("ArkivCreateDeduplicated(uint256,address,bytes32)"))
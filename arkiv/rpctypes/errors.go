@@ -0,0 +1,44 @@
+package rpctypes
+
+import "github.com/ethereum/go-ethereum/common/hexutil"
+
+// The JSON-RPC error codes of the arkiv namespace. A missing entity has the code of a
+// missing resource of EIP-1474, the other failures clients can tell apart have codes
+// from -32101 to -32199, clear of the codes of the RPC server. Other errors have the
+// generic code -32000.
+const (
+	// ErrCodeNotFound is returned for the entities that aren't live at the block, the
+	// data of the error is their EntityMetaData, and for the names no alias is
+	// registered under, without data.
+	ErrCodeNotFound = -32001
+
+	// ErrCodeNotIndexed is returned for the blocks the store can't answer for: blocks
+	// it hasn't indexed yet and blocks too far before the last block it indexed. The
+	// data of the error is a NotIndexedErrorData.
+	ErrCodeNotIndexed = -32101
+
+	// ErrCodeStoreBusy is returned for the requests the store didn't answer in time,
+	// they can be retried.
+	ErrCodeStoreBusy = -32102
+
+	// ErrCodeConcurrencyLimit is returned for the queries rejected because the running
+	// queries hold the memory budget, they can be retried once they're done.
+	ErrCodeConcurrencyLimit = -32103
+
+	// ErrCodeValidation is returned for the invalid requests: malformed queries,
+	// cursors or options, block ranges out of order or over their limit, and queries
+	// over the limits of the node.
+	ErrCodeValidation = -32104
+
+	// ErrCodeMethodDisabled is returned by the methods the operator of the node
+	// disabled, see the DisabledMethods of Capabilities.
+	ErrCodeMethodDisabled = -32105
+)
+
+// NotIndexedErrorData is the data of the ErrCodeNotIndexed errors.
+type NotIndexedErrorData struct {
+	// Block is the block of the request.
+	Block hexutil.Uint64 `json:"block"`
+	// LastIndexedBlock is the last block the store indexed.
+	LastIndexedBlock hexutil.Uint64 `json:"lastIndexedBlock"`
+}
@@ -0,0 +1,834 @@
+// Package rpctypes holds the request and response types of the arkiv RPC namespace,
+// shared by the node serving it and the clients calling it.
+package rpctypes
+
+import (
+	"encoding/json"
+	"fmt"
+
+	sqlitestore "github.com/Arkiv-Network/sqlite-bitmap-store"
+	"github.com/ethereum/go-ethereum/arkiv/statediff"
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// EntityProvenance tells where an entity returned by a query with a pending view
+// comes from.
+type EntityProvenance string
+
+const (
+	// EntityProvenanceIndexed is an entity read from the store.
+	EntityProvenanceIndexed EntityProvenance = "indexed"
+	// EntityProvenanceHeadOverlay is an entity changed by a mined block the store
+	// hasn't indexed yet.
+	EntityProvenanceHeadOverlay EntityProvenance = "headOverlay"
+	// EntityProvenancePending is an entity changed by a transaction of the sender that
+	// is still in the transaction pool.
+	EntityProvenancePending EntityProvenance = "pending"
+)
+
+// PendingView overlays the entities changed by the mined blocks the store hasn't
+// indexed yet on the results of a query, and the entities changed by the pooled
+// transactions of Sender if it's set. The overlay is computed for the query and never
+// written to the store.
+type PendingView struct {
+	Sender *common.Address `json:"sender,omitempty"`
+}
+
+// QueryOptions are the options of a query. On top of the options of the store, the
+// results can be restricted to the entities whose payload contains all the terms of
+// Text, if the full-text index is enabled, and to the entities with an annotation key
+// under KeyPrefix, such as invoice.customer.* for invoice.customer.region.
+// PendingView overlays the entities the store hasn't indexed yet on the results.
+//
+// Queries estimated to select most of the live entities are rejected unless
+// AllowFullScan is set. IncludeStats adds the estimate to the response.
+//
+// AcceptEncoding names the codecs the payloads can be returned compressed with, see
+// EntityData. Only the payloads the store keeps compressed are returned compressed.
+type QueryOptions struct {
+	sqlitestore.Options
+	Text           string       `json:"text,omitempty"`
+	KeyPrefix      string       `json:"keyPrefix,omitempty"`
+	PendingView    *PendingView `json:"pendingView,omitempty"`
+	AllowFullScan  bool         `json:"allowFullScan,omitempty"`
+	IncludeStats   bool         `json:"includeStats,omitempty"`
+	AcceptEncoding []string     `json:"acceptEncoding,omitempty"`
+	// TimeoutMs bounds the time the query runs in the store, in milliseconds. It can
+	// only shorten the timeout of the node.
+	TimeoutMs uint64 `json:"timeoutMs,omitempty"`
+	// OrderBy orders the results by a numeric annotation instead of newest first.
+	OrderBy *QueryOrder `json:"orderBy,omitempty"`
+}
+
+// The directions of QueryOrder.
+const (
+	QueryOrderAscending  = "asc"
+	QueryOrderDescending = "desc"
+)
+
+// QueryOrder orders the results of a query by the numeric annotation Key, in the
+// Direction, ascending by default. The entities without the annotation come last, and
+// the entities with the same value newest first.
+type QueryOrder struct {
+	Key       string `json:"key"`
+	Direction string `json:"direction,omitempty"`
+}
+
+// QueryDiff describes how the result of a query changed between two blocks. Cursor is
+// set when the diff is truncated, passing it back in the options returns the next keys.
+type QueryDiff struct {
+	FromBlock hexutil.Uint64 `json:"fromBlock"`
+	ToBlock   hexutil.Uint64 `json:"toBlock"`
+	Added     []common.Hash  `json:"added"`
+	Removed   []common.Hash  `json:"removed"`
+	Changed   []common.Hash  `json:"changed"`
+	Truncated bool           `json:"truncated"`
+	Cursor    string         `json:"cursor,omitempty"`
+}
+
+// The statuses of an entity.
+const (
+	EntityStatusLive    = "live"
+	EntityStatusDeleted = "deleted"
+	EntityStatusExpired = "expired"
+	// EntityStatusUnknown is the status of the entities that never existed, or whose
+	// tombstone was swept or predates the tombstones fork.
+	EntityStatusUnknown = "unknown"
+)
+
+// EntityMetaData is the status of an entity in the state of the Arkiv processor.
+type EntityMetaData struct {
+	Status string `json:"status"`
+	// Owner and ExpiresAtBlock are set for live entities.
+	Owner          *common.Address `json:"owner,omitempty"`
+	ExpiresAtBlock *hexutil.Uint64 `json:"expiresAtBlock,omitempty"`
+	// PendingOwner is set for live entities whose transfer awaits acceptance.
+	PendingOwner *PendingOwner `json:"pendingOwner,omitempty"`
+	// Block is the block a deleted or expired entity was removed at.
+	Block *hexutil.Uint64 `json:"block,omitempty"`
+}
+
+// PendingOwner is the owner a live entity is being transferred to, and the block the
+// transfer lapses at unless the owner accepts it.
+type PendingOwner struct {
+	Owner         common.Address `json:"owner"`
+	LapsesAtBlock hexutil.Uint64 `json:"lapsesAtBlock"`
+}
+
+// ExpiryEstimate is an estimate of the wall-clock time of an expiry, assuming the
+// next blocks come at the average interval of the last Window blocks.
+type ExpiryEstimate struct {
+	// ExpiresAt is the estimated unix time of the expiry block.
+	ExpiresAt hexutil.Uint64 `json:"expiresAt"`
+	// AverageBlockInterval is the average interval between the blocks of the window
+	// in milliseconds.
+	AverageBlockInterval uint64 `json:"averageBlockIntervalMs"`
+	// Window is the number of blocks the average is computed over, fewer than the
+	// configured window close to genesis.
+	Window hexutil.Uint64 `json:"window"`
+}
+
+// EntityExpiry describes when a live entity expires. Deleted and expired entities only
+// have the status and the block of the removal, like in EntityMetaData.
+type EntityExpiry struct {
+	Status          string          `json:"status"`
+	ExpiresAtBlock  *hexutil.Uint64 `json:"expiresAtBlock,omitempty"`
+	BlocksRemaining *hexutil.Uint64 `json:"blocksRemaining,omitempty"`
+	Estimate        *ExpiryEstimate `json:"estimate,omitempty"`
+	Block           *hexutil.Uint64 `json:"block,omitempty"`
+}
+
+// BlockTiming describes the current block and the time since its parent.
+type BlockTiming struct {
+	CurrentBlock     hexutil.Uint64 `json:"currentBlock"`
+	CurrentBlockTime hexutil.Uint64 `json:"currentBlockTime"`
+	BlockDuration    hexutil.Uint64 `json:"duration"`
+}
+
+// IngestLatency is how long a transaction submitted to the node took to be included in
+// a block and indexed by the store. The times are unix milliseconds and the latencies
+// milliseconds, the steps not reached yet are omitted.
+type IngestLatency struct {
+	TxHash             common.Hash     `json:"txHash"`
+	Block              *hexutil.Uint64 `json:"block,omitempty"`
+	SubmittedAt        hexutil.Uint64  `json:"submittedAt"`
+	IncludedAt         *hexutil.Uint64 `json:"includedAt,omitempty"`
+	IndexedAt          *hexutil.Uint64 `json:"indexedAt,omitempty"`
+	SubmitToInclusion  *hexutil.Uint64 `json:"submitToInclusion,omitempty"`
+	InclusionToIndexed *hexutil.Uint64 `json:"inclusionToIndexed,omitempty"`
+}
+
+// PrunedGap is a range of blocks that could not be indexed because their receipts
+// were pruned.
+type PrunedGap struct {
+	From hexutil.Uint64 `json:"from"`
+	To   hexutil.Uint64 `json:"to"`
+}
+
+// SyncStatus describes the progress of the Arkiv indexer, see dbevents.SyncStatus.
+type SyncStatus struct {
+	LastBlock              hexutil.Uint64 `json:"lastBlock"`
+	HeadBlock              hexutil.Uint64 `json:"headBlock"`
+	EarliestIndexableBlock hexutil.Uint64 `json:"earliestIndexableBlock"`
+	Stalled                bool           `json:"stalled"`
+	PrunedGap              *PrunedGap     `json:"prunedGap,omitempty"`
+	// UnknownOperations is the number of operations included in blocks that the
+	// indexer skipped because it couldn't decode them, the store may diverge from the
+	// chain if it's not zero.
+	UnknownOperations          hexutil.Uint64  `json:"unknownOperations"`
+	FirstUnknownOperationBlock *hexutil.Uint64 `json:"firstUnknownOperationBlock,omitempty"`
+	// DeadLetterBatches is the number of batches the store failed to ingest, written
+	// to the dead-letter queue.
+	DeadLetterBatches uint64 `json:"deadLetterBatches"`
+	// HeartbeatAge is the time in milliseconds since the events iterator last made
+	// progress, HeadsSinceHeartbeat the number of heads notified since.
+	HeartbeatAge        hexutil.Uint64 `json:"heartbeatAge"`
+	HeadsSinceHeartbeat hexutil.Uint64 `json:"headsSinceHeartbeat"`
+	// IteratorRestarts is the number of times the events iterator was restarted from
+	// its checkpoint after a panic.
+	IteratorRestarts uint64 `json:"iteratorRestarts"`
+	// ReadOnly is whether the node is a read replica, see --arkiv.readonly.
+	ReadOnly bool `json:"readOnly"`
+	// Ready is whether the node is ready to serve queries. With --arkiv.warmup.gate it
+	// is false until the warmup of the query caches run at startup completes.
+	Ready bool `json:"ready"`
+	// Warmup is the last warmup of the query caches, if any ran.
+	Warmup *WarmupReport `json:"warmup,omitempty"`
+}
+
+// Limits describes the limits and gas pricing enforced on Arkiv transactions and
+// requests at a block, see arkiv/limits.
+type Limits struct {
+	// Version is the version of the document, see limits.Version.
+	Version uint64         `json:"version"`
+	Block   hexutil.Uint64 `json:"block"`
+	Time    hexutil.Uint64 `json:"time"`
+	// Consensus are the limits enforced by every node, Node the policy of this node.
+	Consensus []Limit `json:"consensus"`
+	Node      []Limit `json:"node"`
+
+	MaxAnnotationValueSize      uint64         `json:"maxAnnotationValueSize"`
+	AnnotationValueGasThreshold uint64         `json:"annotationValueGasThreshold"`
+	AnnotationValueGasPerByte   hexutil.Uint64 `json:"annotationValueGasPerByte"`
+}
+
+// Limit is a limit of the registry of arkiv/limits.
+type Limit struct {
+	Name  string `json:"name"`
+	Unit  string `json:"unit"`
+	Value uint64 `json:"value"`
+	// ForkGated is whether the limit activates with a fork, Feature is the name of the
+	// fork and ActivationTime its time, unset if it isn't scheduled.
+	ForkGated      bool            `json:"forkGated"`
+	Feature        string          `json:"feature,omitempty"`
+	ActivationTime *hexutil.Uint64 `json:"activationTime,omitempty"`
+}
+
+// QueryEstimate is the estimated size of the result of a query, computed before the
+// query is executed.
+type QueryEstimate struct {
+	// Entities is an upper bound of the number of entities matching the query.
+	Entities uint64 `json:"entities"`
+	// LiveEntities is the number of entities in the store.
+	LiveEntities uint64 `json:"liveEntities"`
+	// FullScan is set if the query selects more entities than a query can select
+	// without allowing a full scan.
+	FullScan bool `json:"fullScan"`
+}
+
+// QueryCount is the number of entities matching a query in the store, which had
+// indexed Block when they were counted.
+type QueryCount struct {
+	Count uint64         `json:"count"`
+	Block hexutil.Uint64 `json:"block"`
+}
+
+// QueryStats are the statistics of a query returned if they were requested.
+type QueryStats struct {
+	Estimate *QueryEstimate `json:"estimate"`
+	Memory   *QueryMemory   `json:"memory,omitempty"`
+}
+
+// QueryResponse is the response of a query, with the statistics of the query if
+// they were requested. Queries with a pending view report where every returned
+// entity comes from in Provenance.
+type QueryResponse struct {
+	*sqlitestore.QueryResponse
+	Stats      *QueryStats        `json:"stats,omitempty"`
+	Provenance []EntityProvenance `json:"provenance,omitempty"`
+}
+
+// Entities decodes the entities of the response.
+func (r *QueryResponse) Entities() ([]EntityData, error) {
+	if r.QueryResponse == nil {
+		return nil, nil
+	}
+	entities := make([]EntityData, 0, len(r.Data))
+	for _, data := range r.Data {
+		var ed EntityData
+		if err := json.Unmarshal(data, &ed); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal entity data: %w", err)
+		}
+		entities = append(entities, ed)
+	}
+	return entities, nil
+}
+
+// QueryMemory is the memory accounted to a query.
+type QueryMemory struct {
+	// Estimated is the working set reserved before the query ran.
+	Estimated uint64 `json:"estimated"`
+	// Peak is the working set of the query once its results are materialized.
+	Peak uint64 `json:"peak"`
+}
+
+// EntityData is an entity in the results of a query: the fields returned by the store,
+// and the codec of the payload when it's served compressed.
+type EntityData struct {
+	sqlitestore.EntityData
+	// ValueEncoding is the codec of the value, only set when it's compressed.
+	ValueEncoding string `json:"valueEncoding,omitempty"`
+}
+
+// OwnerUsageReport is the usage of the Arkiv storage by an owner over a range of
+// blocks, both included.
+type OwnerUsageReport struct {
+	Owner     common.Address `json:"owner"`
+	FromBlock hexutil.Uint64 `json:"fromBlock"`
+	ToBlock   hexutil.Uint64 `json:"toBlock"`
+	// Creates, Updates and Deletes are the numbers of operations on the entities of
+	// the owner, expirations aren't counted as deletes.
+	Creates uint64 `json:"creates"`
+	Updates uint64 `json:"updates"`
+	Deletes uint64 `json:"deletes"`
+	// PayloadBytes is the size of the payloads written by the creates and updates.
+	PayloadBytes uint64 `json:"payloadBytes"`
+	// SlotBlocks is the sum over the blocks of the range of the state slots held by
+	// the live entities of the owner.
+	SlotBlocks hexutil.Uint64 `json:"slotBlocks"`
+	// GasUsed is the gas used by the Arkiv transactions sent by the owner, the failed
+	// ones included.
+	GasUsed hexutil.Uint64 `json:"gasUsed"`
+}
+
+// ProcessorLogsOptions are the options of GetProcessorLogs.
+type ProcessorLogsOptions struct {
+	// Owner only matches the logs with the owner in their third topic, the previous
+	// owner for the ownership changes.
+	Owner *common.Address `json:"owner,omitempty"`
+	// Kinds only matches the logs of the kinds, all of them if empty.
+	Kinds []string `json:"kinds,omitempty"`
+	// Cursor resumes from the cursor of the previous page.
+	Cursor string `json:"cursor,omitempty"`
+	// Limit is the largest number of logs returned, 1000 by default and 10000 at most.
+	Limit uint64 `json:"limit,omitempty"`
+	// BucketSize returns the number of logs of every kind in buckets of that number of
+	// blocks, starting at fromBlock, instead of the logs.
+	BucketSize uint64 `json:"bucketSize,omitempty"`
+}
+
+// ProcessorLog is a decoded log of the processor.
+type ProcessorLog struct {
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	TxHash      common.Hash    `json:"transactionHash"`
+	TxIndex     hexutil.Uint   `json:"transactionIndex"`
+	LogIndex    hexutil.Uint   `json:"logIndex"`
+	Kind        string         `json:"kind"`
+	Key         common.Hash    `json:"key"`
+	Owner       common.Address `json:"owner"`
+	// NewOwner is the owner the entity is given to by the ownership changes.
+	NewOwner *common.Address `json:"newOwner,omitempty"`
+	Data     hexutil.Bytes   `json:"data"`
+}
+
+// ProcessorLogBucket counts the logs of every kind over a range of blocks, both ends
+// included.
+type ProcessorLogBucket struct {
+	FromBlock hexutil.Uint64    `json:"fromBlock"`
+	ToBlock   hexutil.Uint64    `json:"toBlock"`
+	Counts    map[string]uint64 `json:"counts"`
+}
+
+// ProcessorLogs is a page of the logs of the processor.
+type ProcessorLogs struct {
+	Logs    []ProcessorLog       `json:"logs,omitempty"`
+	Buckets []ProcessorLogBucket `json:"buckets,omitempty"`
+	// Cursor is set if the range has more logs, to pass to the next call.
+	Cursor *string `json:"cursor,omitempty"`
+	// Path is how the logs were found, index or bloom.
+	Path string `json:"path"`
+	// BlocksScanned is the number of blocks searched by the call.
+	BlocksScanned uint64 `json:"blocksScanned"`
+}
+
+// EventsCheckpoint is a move of the last block ingested by the store.
+type EventsCheckpoint struct {
+	Previous hexutil.Uint64 `json:"previous"`
+	Block    hexutil.Uint64 `json:"block"`
+}
+
+// WarmupQuery is a query run by a warmup of the query caches, its results discarded.
+type WarmupQuery struct {
+	Query string `json:"query"`
+	// Source is where the query comes from: config for the warmup file, popular for
+	// the most popular queries of the node.
+	Source     string `json:"source"`
+	DurationMs uint64 `json:"durationMs"`
+	Error      string `json:"error,omitempty"`
+}
+
+// WarmupReport is a warmup of the query caches, run against the block.
+type WarmupReport struct {
+	Block   hexutil.Uint64 `json:"block"`
+	Done    bool           `json:"done"`
+	Queries []WarmupQuery  `json:"queries"`
+}
+
+// The sources of the queries of a warmup.
+const (
+	WarmupSourceConfig  = "config"
+	WarmupSourcePopular = "popular"
+)
+
+// DeadLetter is a batch the store failed to ingest, re-injected from the dead-letter
+// queue.
+type DeadLetter struct {
+	File      string         `json:"file"`
+	FromBlock hexutil.Uint64 `json:"fromBlock"`
+	ToBlock   hexutil.Uint64 `json:"toBlock"`
+	// Error is the error of the last attempt to ingest the batch.
+	Error    string `json:"error"`
+	Attempts uint64 `json:"attempts"`
+}
+
+// The statuses of the findings of a self-check.
+const (
+	SelfCheckOK      = "ok"
+	SelfCheckWarning = "warning"
+	SelfCheckError   = "error"
+)
+
+// SelfCheckFinding is the outcome of one check of the wiring of the Arkiv subsystem.
+type SelfCheckFinding struct {
+	Check   string `json:"check"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// SelfCheck is the report of a self-check, OK is set when no check failed.
+type SelfCheck struct {
+	OK       bool               `json:"ok"`
+	Findings []SelfCheckFinding `json:"findings"`
+}
+
+// Entity is a live entity at a block, its owner and expiry read from the state of the
+// Arkiv processor.
+type Entity struct {
+	Key               common.Hash       `json:"key"`
+	Owner             common.Address    `json:"owner"`
+	ExpiresAtBlock    hexutil.Uint64    `json:"expiresAtBlock"`
+	ContentType       string            `json:"contentType"`
+	StringAttributes  map[string]string `json:"stringAttributes"`
+	NumericAttributes map[string]uint64 `json:"numericAttributes"`
+	Payload           hexutil.Bytes     `json:"payload"`
+	// Block is the block the entity was read at.
+	Block hexutil.Uint64 `json:"block"`
+}
+
+// The kinds of entity events.
+const (
+	EntityEventCreated      = "created"
+	EntityEventUpdated      = "updated"
+	EntityEventDeleted      = "deleted"
+	EntityEventExpired      = "expired"
+	EntityEventExtended     = "extended"
+	EntityEventOwnerChanged = "ownerChanged"
+)
+
+// EntityEventFilter selects the events of an entity events subscription, an empty
+// list matches everything. An ownership change matches both the previous and the new
+// owner.
+type EntityEventFilter struct {
+	Keys   []common.Hash    `json:"keys,omitempty"`
+	Owners []common.Address `json:"owners,omitempty"`
+}
+
+// EntityEvent is a change of the lifecycle of an entity, an operation of a canonical
+// block. The events of a block are sent in the order of their operations.
+type EntityEvent struct {
+	Kind  string         `json:"kind"`
+	Key   common.Hash    `json:"key"`
+	Owner common.Address `json:"owner"`
+	// PreviousOwner is the owner before an ownership change.
+	PreviousOwner *common.Address `json:"previousOwner,omitempty"`
+	// ExpiresAtBlock is the expiration block of a created, updated or extended entity.
+	ExpiresAtBlock *hexutil.Uint64 `json:"expiresAtBlock,omitempty"`
+	BlockNumber    hexutil.Uint64  `json:"blockNumber"`
+	BlockHash      common.Hash     `json:"blockHash"`
+	TxIndex        hexutil.Uint64  `json:"txIndex"`
+	OpIndex        hexutil.Uint64  `json:"opIndex"`
+}
+
+// Capability is a feature of the Arkiv processor, as answered by the capabilities
+// precompile.
+type Capability struct {
+	// ID is the id of the feature passed to supportsFeature(bytes4).
+	ID     hexutil.Bytes `json:"id"`
+	Name   string        `json:"name"`
+	Active bool          `json:"active"`
+	// ActivationTime is the time the feature activates at, unset if it never does.
+	ActivationTime *hexutil.Uint64 `json:"activationTime,omitempty"`
+}
+
+// ShadowRule are the statistics of a rule of the processor checked before its fork, see
+// --arkiv.shadow-enforcement.
+type ShadowRule struct {
+	// ID and Name are the id and the name of the feature the rule activates with.
+	ID         hexutil.Bytes `json:"id"`
+	Name       string        `json:"name"`
+	Evaluated  uint64        `json:"evaluated"`
+	Violations uint64        `json:"violations"`
+}
+
+// ShadowViolation is a transaction that violated a rule before its fork.
+type ShadowViolation struct {
+	Rule   string         `json:"rule"`
+	Block  hexutil.Uint64 `json:"block"`
+	TxHash common.Hash    `json:"txHash"`
+	Error  string         `json:"error"`
+}
+
+// ShadowEnforcementStats are the checks of the rules of the processor before their
+// forks since the node started, with the last violations, the oldest first.
+type ShadowEnforcementStats struct {
+	Enabled bool              `json:"enabled"`
+	Rules   []ShadowRule      `json:"rules"`
+	Recent  []ShadowViolation `json:"recent"`
+}
+
+// ContentHashPair is the key of an entity and the keccak256 hash of the payload it's
+// expected to hold, encoded as a [key, hash] array.
+type ContentHashPair struct {
+	Key  common.Hash
+	Hash common.Hash
+}
+
+func (p ContentHashPair) MarshalJSON() ([]byte, error) {
+	return json.Marshal([2]common.Hash{p.Key, p.Hash})
+}
+
+func (p *ContentHashPair) UnmarshalJSON(data []byte) error {
+	var pair []common.Hash
+	if err := json.Unmarshal(data, &pair); err != nil {
+		return err
+	}
+	if len(pair) != 2 {
+		return fmt.Errorf("content hash pair has %d elements, expected a key and a hash", len(pair))
+	}
+	p.Key, p.Hash = pair[0], pair[1]
+	return nil
+}
+
+// The statuses of a verified content hash.
+const (
+	ContentHashMatch    = "match"
+	ContentHashMismatch = "mismatch"
+	// ContentHashUnknown is the status of a live entity whose payload the node can't
+	// read at the block, such as one missing from the store.
+	ContentHashUnknown = "unknownHash"
+	// ContentHashEntityMissing is the status of a key that doesn't hold a live entity
+	// at the block.
+	ContentHashEntityMissing = "entityMissing"
+)
+
+// ContentHashResult is the verification of a content hash pair.
+type ContentHashResult struct {
+	Key    common.Hash `json:"key"`
+	Status string      `json:"status"`
+	// ContentHash is the hash of the payload of the entity when it's a mismatch.
+	ContentHash *common.Hash `json:"contentHash,omitempty"`
+}
+
+// ContentHashVerification are the results of content hash pairs verified against the
+// state at a block, in the order of the pairs. Offset is the index of the pair of the
+// first result, for the notifications of the subscription.
+type ContentHashVerification struct {
+	Block   hexutil.Uint64      `json:"block"`
+	Offset  uint64              `json:"offset"`
+	Results []ContentHashResult `json:"results"`
+}
+
+// StorageChallengeResponse is the response of a node to the storage challenge of an
+// entity, the keccak256 hash of the nonce followed by the payload the node stores. It's
+// nil if the node doesn't store the entity.
+type StorageChallengeResponse struct {
+	Key      common.Hash  `json:"key"`
+	Response *common.Hash `json:"response"`
+}
+
+// StorageChallenge are the responses of a node to a storage challenge, in the order of
+// the keys, computed from the entities of its store at Block, the last block it
+// indexed.
+type StorageChallenge struct {
+	Block     hexutil.Uint64             `json:"block"`
+	Nonce     hexutil.Bytes              `json:"nonce"`
+	Responses []StorageChallengeResponse `json:"responses"`
+}
+
+// AliasResolution is the entity an alias points to at Block, with the owner and the
+// expiry of the alias. EntityLive is false if the entity was deleted or expired while
+// the alias is still registered.
+type AliasResolution struct {
+	Name           string         `json:"name"`
+	NameHash       common.Hash    `json:"nameHash"`
+	Key            common.Hash    `json:"key"`
+	Owner          common.Address `json:"owner"`
+	ExpiresAtBlock hexutil.Uint64 `json:"expiresAtBlock"`
+	Block          hexutil.Uint64 `json:"block"`
+	EntityLive     bool           `json:"entityLive"`
+}
+
+// SampleOptions are the options of SampleEntities.
+type SampleOptions struct {
+	// AtBlock is the block the entities are sampled at, the head if it's nil.
+	AtBlock *hexutil.Uint64 `json:"atBlock,omitempty"`
+	// IncludeMetaData adds the metadata of the entities to the sample.
+	IncludeMetaData bool `json:"includeMetaData,omitempty"`
+}
+
+// SampledEntity is an entity of a sample, with its metadata if it was requested.
+type SampledEntity struct {
+	Key      common.Hash     `json:"key"`
+	MetaData *EntityMetaData `json:"metaData,omitempty"`
+}
+
+// EntitySample is a sample of the entities live at a block, drawn with a seed out of
+// Population entities. The entities are ordered by their rank, the keccak256 hash of
+// the seed followed by their key.
+type EntitySample struct {
+	Block      hexutil.Uint64  `json:"block"`
+	Seed       hexutil.Bytes   `json:"seed"`
+	Population uint64          `json:"population"`
+	Entities   []SampledEntity `json:"entities"`
+}
+
+// OwnerEntitiesOptions are the options of GetEntitiesOfOwner.
+type OwnerEntitiesOptions struct {
+	// AtBlock is the block the entities are listed at, the head if it's nil.
+	AtBlock *hexutil.Uint64 `json:"atBlock,omitempty"`
+	// ResultsPerPage is the size of a page, the largest page if it's 0.
+	ResultsPerPage uint64 `json:"resultsPerPage,omitempty"`
+	// Cursor is the cursor of the previous page, the listing starts after its key.
+	Cursor *common.Hash `json:"cursor,omitempty"`
+}
+
+// OwnerEntity is a live entity of an owner and the block it expires at.
+type OwnerEntity struct {
+	Key            common.Hash    `json:"key"`
+	ExpiresAtBlock hexutil.Uint64 `json:"expiresAtBlock"`
+}
+
+// OwnerEntities is a page of the entities of an owner at a block, ordered by key.
+// Cursor is set when more entities are left, passing it back in the options returns
+// the next page.
+type OwnerEntities struct {
+	Owner    common.Address `json:"owner"`
+	Block    hexutil.Uint64 `json:"block"`
+	Entities []OwnerEntity  `json:"entities"`
+	Cursor   *common.Hash   `json:"cursor,omitempty"`
+}
+
+// OwnerEntitiesByExpiryOptions are the options of GetOwnerEntitiesByExpiry.
+type OwnerEntitiesByExpiryOptions struct {
+	// AtBlock is the block the entities are listed at, the head if it's nil.
+	AtBlock *hexutil.Uint64 `json:"atBlock,omitempty"`
+	// ResultsPerPage is the size of a page, the largest page if it's 0.
+	ResultsPerPage uint64 `json:"resultsPerPage,omitempty"`
+	// Cursor is the cursor of the previous page, the listing starts after its entity.
+	Cursor *ExpiryCursor `json:"cursor,omitempty"`
+}
+
+// ExpiryCursor is the position of an entity in a listing ordered by expiration: the
+// entities expiring at the same block are ordered by key.
+type ExpiryCursor struct {
+	ExpiresAtBlock hexutil.Uint64 `json:"expiresAtBlock"`
+	Key            common.Hash    `json:"key"`
+}
+
+// ExpiringEntity is a live entity of an owner, the block it expires at and the number
+// of blocks left until then from the block of the listing.
+type ExpiringEntity struct {
+	Key             common.Hash    `json:"key"`
+	ExpiresAtBlock  hexutil.Uint64 `json:"expiresAtBlock"`
+	BlocksRemaining hexutil.Uint64 `json:"blocksRemaining"`
+}
+
+// OwnerEntitiesByExpiry is a page of the entities of an owner at a block, the soonest
+// expiring first. Cursor is set when more entities are left, passing it back in the
+// options returns the next page.
+type OwnerEntitiesByExpiry struct {
+	Owner    common.Address   `json:"owner"`
+	Block    hexutil.Uint64   `json:"block"`
+	Entities []ExpiringEntity `json:"entities"`
+	Cursor   *ExpiryCursor    `json:"cursor,omitempty"`
+}
+
+// The classes of the owners of GetEntitiesByOwnerClass.
+const (
+	OwnerClassZeroAddress = "zeroAddress"
+	// OwnerClassContract are the addresses with code, the accounts delegating their
+	// code aside.
+	OwnerClassContract = "contract"
+	// OwnerClassEOA are the addresses without code or delegating it, the zero address
+	// aside.
+	OwnerClassEOA = "eoa"
+)
+
+// OwnerClassOptions are the options of GetEntitiesByOwnerClass.
+type OwnerClassOptions struct {
+	// AtBlock is the block the entities are listed at, the head if it's nil. The
+	// block of the cursor is used when it's set.
+	AtBlock *hexutil.Uint64 `json:"atBlock,omitempty"`
+	// ResultsPerPage is the size of a page, the largest page if it's 0.
+	ResultsPerPage uint64 `json:"resultsPerPage,omitempty"`
+	// Cursor is the cursor of the previous page, the listing starts after it.
+	Cursor *OwnerClassCursor `json:"cursor,omitempty"`
+}
+
+// OwnerClassCursor is the progress of a listing by owner class through the owners,
+// ordered by address, pinned to the block of its first page. The listing resumes after
+// the entity Key of Owner, or after Owner if Key is nil.
+type OwnerClassCursor struct {
+	Block hexutil.Uint64 `json:"block"`
+	Owner common.Address `json:"owner"`
+	Key   *common.Hash   `json:"key,omitempty"`
+}
+
+// OwnerClassEntity is a live entity, its owner and the block it expires at.
+type OwnerClassEntity struct {
+	Key            common.Hash    `json:"key"`
+	Owner          common.Address `json:"owner"`
+	ExpiresAtBlock hexutil.Uint64 `json:"expiresAtBlock"`
+}
+
+// OwnerClassEntities is a page of the entities whose owner is of a class at a block,
+// ordered by owner and by key. Cursor is set when owners are left, passing it back in
+// the options returns the next page; a page can be short, or empty, when the node
+// reached its limit of owners to classify.
+type OwnerClassEntities struct {
+	Class    string             `json:"class"`
+	Block    hexutil.Uint64     `json:"block"`
+	Entities []OwnerClassEntity `json:"entities"`
+	Cursor   *OwnerClassCursor  `json:"cursor,omitempty"`
+}
+
+// ExpiringBlock is a block and the keys of the entities expiring at it.
+type ExpiringBlock struct {
+	Block hexutil.Uint64 `json:"block"`
+	Keys  []common.Hash  `json:"keys"`
+}
+
+// EntitiesToExpire are the entities expiring in a range of blocks, in the state of the
+// current block, grouped by the block they expire at. Blocks no entity expires at are
+// left out. Owner is set when the entities are filtered by owner.
+type EntitiesToExpire struct {
+	Block     hexutil.Uint64  `json:"block"`
+	FromBlock hexutil.Uint64  `json:"fromBlock"`
+	ToBlock   hexutil.Uint64  `json:"toBlock"`
+	Owner     *common.Address `json:"owner,omitempty"`
+	Blocks    []ExpiringBlock `json:"blocks"`
+}
+
+// Capabilities is the set of the features of the Arkiv processor at a block.
+type Capabilities struct {
+	Block    hexutil.Uint64 `json:"block"`
+	Time     hexutil.Uint64 `json:"time"`
+	Features []Capability   `json:"features"`
+	// DisabledMethods are the methods of the arkiv namespace the operator of the node
+	// disabled, without the arkiv_ prefix. They fail with ErrCodeMethodDisabled.
+	DisabledMethods []string `json:"disabledMethods"`
+}
+
+// SimulateTransactionArgs is an Arkiv transaction to simulate.
+type SimulateTransactionArgs struct {
+	From common.Address `json:"from"`
+	// Data is the calldata of the transaction to the processor.
+	Data hexutil.Bytes `json:"data"`
+	// IncludeStateDiff adds the slots of the processor written by the transaction to
+	// the result.
+	IncludeStateDiff bool `json:"includeStateDiff,omitempty"`
+	// TargetBlockOffset is the number of blocks after the next one the transaction
+	// lands in. The housekeeping of the blocks in between is run first.
+	TargetBlockOffset hexutil.Uint64 `json:"targetBlockOffset,omitempty"`
+}
+
+// SimulationResult is the outcome of an Arkiv transaction simulated on top of the state
+// of Block, in TargetBlock. Error is set if the transaction fails, the logs and the
+// state diff are only set if it succeeds.
+type SimulationResult struct {
+	Block       hexutil.Uint64 `json:"block"`
+	TargetBlock hexutil.Uint64 `json:"targetBlock"`
+	Success     bool           `json:"success"`
+	Error       string         `json:"error,omitempty"`
+	Logs        []*types.Log   `json:"logs"`
+	// ExpiredEntities are the entities the transaction refers to that the housekeeping
+	// run before it expires.
+	ExpiredEntities []common.Hash `json:"expiredEntities,omitempty"`
+	// StateDiff are the slots of the processor whose value the transaction changes, in
+	// the order they are first written, set with IncludeStateDiff.
+	StateDiff []statediff.DecodedSlot `json:"stateDiff,omitempty"`
+}
+
+// EstimateStorageGasArgs is an Arkiv transaction to estimate the gas of.
+type EstimateStorageGasArgs struct {
+	From common.Address `json:"from"`
+	// Transaction is the transaction to the processor, compressed with brotli into
+	// the calldata.
+	Transaction storagetx.ArkivTransaction `json:"transaction"`
+}
+
+// StorageGasEstimate is the gas of an Arkiv transaction run on top of the state of
+// Block. Gas is the gas the transaction uses, IntrinsicGas plus ArkivGas, at least
+// FloorDataGas, which is 0 before Prague. Error is set if the transaction fails, the
+// slots are only counted if it succeeds.
+type StorageGasEstimate struct {
+	Block        hexutil.Uint64 `json:"block"`
+	Success      bool           `json:"success"`
+	Error        string         `json:"error,omitempty"`
+	Gas          hexutil.Uint64 `json:"gas"`
+	CalldataSize hexutil.Uint64 `json:"calldataSize"`
+	IntrinsicGas hexutil.Uint64 `json:"intrinsicGas"`
+	FloorDataGas hexutil.Uint64 `json:"floorDataGas"`
+	ArkivGas     hexutil.Uint64 `json:"arkivGas"`
+	// Operations breaks ArkivGas down by operation.
+	Operations []storagetx.OperationGas `json:"operations"`
+	// CreatedSlots, ModifiedSlots and ClearedSlots are the slots of the processor
+	// the transaction writes from empty, from a value to another and to empty.
+	CreatedSlots  hexutil.Uint64 `json:"createdSlots"`
+	ModifiedSlots hexutil.Uint64 `json:"modifiedSlots"`
+	ClearedSlots  hexutil.Uint64 `json:"clearedSlots"`
+}
+
+// SendSignedOperationArgs is an Arkiv operation signed as EIP-712 typed data by its
+// signer, see storagetx.ArkivOperation, for the relayer of the node to send.
+type SendSignedOperationArgs struct {
+	TypedData apitypes.TypedData `json:"typedData"`
+	// Signature is the 65 bytes signature of the typed data, V being 0 or 1, or 27
+	// or 28.
+	Signature hexutil.Bytes `json:"signature"`
+}
+
+// RelayedOperation is an Arkiv operation sent by the relayer of the node. Hash is the
+// EIP-712 hash of the operation and TransactionHash the hash of the transaction sent
+// by Relayer with Nonce.
+type RelayedOperation struct {
+	Hash            common.Hash    `json:"hash"`
+	Signer          common.Address `json:"signer"`
+	Relayer         common.Address `json:"relayer"`
+	TransactionHash common.Hash    `json:"transactionHash"`
+	Nonce           hexutil.Uint64 `json:"nonce"`
+}
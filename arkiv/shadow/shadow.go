@@ -0,0 +1,127 @@
+// Package shadow measures the traffic a consensus rule of the Arkiv processor would
+// reject before the fork activating it. Until its fork activates, a rule is checked on
+// every transaction it applies to and its violations are recorded, without affecting
+// the execution. The rules are checked by Enforce, with the code enforcing them once
+// their fork is active.
+package shadow
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// DefaultRecentViolations is the default number of the violations a recorder keeps.
+const DefaultRecentViolations = 256
+
+// Violation is a transaction that violated a rule before its fork.
+type Violation struct {
+	Feature params.ArkivFeature
+	Block   uint64
+	TxHash  common.Hash
+	Error   string
+}
+
+// RuleStats are the number of the transactions a rule was checked on before its fork,
+// and of the ones that violated it.
+type RuleStats struct {
+	Feature    params.ArkivFeature
+	Evaluated  uint64
+	Violations uint64
+}
+
+// Recorder records the checks of the rules before their forks. A transaction executed
+// twice, such as in a reorg, is counted twice.
+type Recorder struct {
+	mu     sync.Mutex
+	stats  map[params.ArkivFeature]*RuleStats
+	recent []Violation // ring buffer of the last violations
+	next   int
+	full   bool
+}
+
+// NewRecorder returns a recorder keeping the last size violations, at least one.
+func NewRecorder(size int) *Recorder {
+	return &Recorder{
+		stats:  map[params.ArkivFeature]*RuleStats{},
+		recent: make([]Violation, max(size, 1)),
+	}
+}
+
+// Enforce checks the rule of the feature on a transaction of the block: once the
+// feature is active at time it returns the error of check, before that it records the
+// result of check with the recorder and returns nil. Without a recorder the rule isn't
+// checked before its fork.
+func Enforce(recorder *Recorder, config *params.ChainConfig, feature params.ArkivFeature, time uint64, block uint64, txHash common.Hash, check func() error) error {
+	if config.IsArkivFeature(feature, time) {
+		return check()
+	}
+	if recorder != nil {
+		recorder.record(feature, block, txHash, check())
+	}
+	return nil
+}
+
+func (r *Recorder) record(feature params.ArkivFeature, block uint64, txHash common.Hash, err error) {
+	name := metricName(feature)
+	metrics.GetOrRegisterCounter("arkiv/shadow/"+name+"/evaluated", nil).Inc(1)
+	if err != nil {
+		metrics.GetOrRegisterCounter("arkiv/shadow/"+name+"/violations", nil).Inc(1)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats := r.stats[feature]
+	if stats == nil {
+		stats = &RuleStats{Feature: feature}
+		r.stats[feature] = stats
+	}
+	stats.Evaluated++
+	if err == nil {
+		return
+	}
+	stats.Violations++
+	r.recent[r.next] = Violation{Feature: feature, Block: block, TxHash: txHash, Error: err.Error()}
+	r.next = (r.next + 1) % len(r.recent)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// Stats returns the statistics of the rules checked so far, in the order of the
+// registry of the features.
+func (r *Recorder) Stats() []RuleStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var stats []RuleStats
+	for _, feature := range params.ArkivFeatures() {
+		if s, ok := r.stats[feature.ID]; ok {
+			stats = append(stats, *s)
+		}
+	}
+	return stats
+}
+
+// Recent returns the last violations, the oldest first.
+func (r *Recorder) Recent() []Violation {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.full {
+		return append([]Violation{}, r.recent[:r.next]...)
+	}
+	return append(append([]Violation{}, r.recent[r.next:]...), r.recent[:r.next]...)
+}
+
+// metricName returns the name of the feature in the metrics, without the arkiv prefix.
+func metricName(feature params.ArkivFeature) string {
+	if spec, ok := params.ArkivFeatureByID(feature); ok {
+		return strings.TrimPrefix(spec.Name, "arkiv.")
+	}
+	return common.Bytes2Hex(feature[:])
+}
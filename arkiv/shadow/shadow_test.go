@@ -0,0 +1,65 @@
+package shadow
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+func gasScheduleConfig(active bool) *params.ChainConfig {
+	activation := uint64(100)
+	if active {
+		activation = 0
+	}
+	return &params.ChainConfig{ArkivGasScheduleTime: &activation}
+}
+
+func TestEnforce_SameClassification(t *testing.T) {
+	recorder := NewRecorder(DefaultRecentViolations)
+	for i, checkErr := range []error{nil, errors.New("invalid"), nil, errors.New("still invalid")} {
+		check := func() error { return checkErr }
+		txHash := common.Hash{byte(i)}
+
+		live := Enforce(recorder, gasScheduleConfig(true), params.ArkivFeatureGasSchedule, 50, uint64(i), txHash, check)
+		require.Equal(t, checkErr, live)
+
+		recent := len(recorder.Recent())
+		shadowed := Enforce(recorder, gasScheduleConfig(false), params.ArkivFeatureGasSchedule, 50, uint64(i), txHash, check)
+		require.NoError(t, shadowed)
+		require.Equal(t, live != nil, len(recorder.Recent()) > recent)
+	}
+
+	require.Equal(t, []RuleStats{{Feature: params.ArkivFeatureGasSchedule, Evaluated: 4, Violations: 2}}, recorder.Stats())
+	require.Equal(t, []Violation{
+		{Feature: params.ArkivFeatureGasSchedule, Block: 1, TxHash: common.Hash{1}, Error: "invalid"},
+		{Feature: params.ArkivFeatureGasSchedule, Block: 3, TxHash: common.Hash{3}, Error: "still invalid"},
+	}, recorder.Recent())
+}
+
+func TestEnforce_WithoutRecorder(t *testing.T) {
+	checked := false
+	err := Enforce(nil, gasScheduleConfig(false), params.ArkivFeatureGasSchedule, 50, 1, common.Hash{}, func() error {
+		checked = true
+		return errors.New("invalid")
+	})
+	require.NoError(t, err)
+	require.False(t, checked)
+}
+
+func TestRecorder_RecentBounded(t *testing.T) {
+	recorder := NewRecorder(3)
+	for i := range 5 {
+		recorder.record(params.ArkivFeatureGasSchedule, uint64(i), common.Hash{byte(i)}, fmt.Errorf("violation %d", i))
+	}
+
+	var blocks []uint64
+	for _, violation := range recorder.Recent() {
+		blocks = append(blocks, violation.Block)
+	}
+	require.Equal(t, []uint64{2, 3, 4}, blocks)
+	require.Equal(t, []RuleStats{{Feature: params.ArkivFeatureGasSchedule, Evaluated: 5, Violations: 5}}, recorder.Stats())
+}
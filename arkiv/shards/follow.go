@@ -0,0 +1,175 @@
+package shards
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	arkivevents "github.com/Arkiv-Network/arkiv-events"
+	"github.com/Arkiv-Network/arkiv-events/events"
+	sqlitestore "github.com/Arkiv-Network/sqlite-bitmap-store"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// keyLookupBatchSize is the number of keys looked up in a shard per query, it's the
+// largest page of the store.
+const keyLookupBatchSize = int(sqlitestore.QueryResultCountLimit)
+
+// FollowEvents ingests the batches of the iterator in all the shards, every shard
+// receiving the operations of its entities in the blocks after its checkpoint. The
+// iterator must start after the last block ingested by all the shards, as returned by
+// GetLastBlock. A batch is ingested by all the shards before the next one is read.
+func (r *Router) FollowEvents(ctx context.Context, iterator arkivevents.BatchIterator) error {
+	if len(r.shards) == 1 {
+		return r.shards[0].Store.FollowEvents(ctx, iterator)
+	}
+	for batch := range iterator {
+		if batch.Error != nil {
+			return fmt.Errorf("failed to follow events: %w", batch.Error)
+		}
+		if err := r.ingest(ctx, batch.Batch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ingest splits the batch over the shards and ingests it.
+func (r *Router) ingest(ctx context.Context, batch events.BlockBatch) error {
+	if len(batch.Blocks) == 0 {
+		return nil
+	}
+	checkpoints := make([]uint64, len(r.shards))
+	for i, shard := range r.shards {
+		last, err := shard.Store.GetLastBlock(ctx)
+		if err != nil {
+			return fmt.Errorf("shard %s: failed to get last block: %w", shard.Name, err)
+		}
+		checkpoints[i] = last
+	}
+
+	owners, err := r.owners(ctx, batch, checkpoints)
+	if err != nil {
+		return err
+	}
+
+	for i, shard := range r.shards {
+		var blocks []events.Block
+		for _, block := range batch.Blocks {
+			if block.Number <= checkpoints[i] {
+				continue
+			}
+			operations := []events.Operation{}
+			for _, operation := range block.Operations {
+				if owner, ok := owners[operationKey(operation)]; ok && owner == i {
+					operations = append(operations, operation)
+				}
+			}
+			blocks = append(blocks, events.Block{Number: block.Number, Operations: operations})
+		}
+		if len(blocks) == 0 {
+			continue
+		}
+		single := func(yield func(arkivevents.BatchOrError) bool) {
+			yield(arkivevents.BatchOrError{Batch: events.BlockBatch{Blocks: blocks}})
+		}
+		if err := shard.Store.FollowEvents(ctx, single); err != nil {
+			return fmt.Errorf("shard %s: %w", shard.Name, err)
+		}
+	}
+	return nil
+}
+
+// owners returns the shard of the entity of every operation of the batch. The entities
+// created in the batch are assigned by the rules, the others are looked up in the
+// shards ingesting the batch. The entities not found belong to shards that already
+// ingested the batch.
+func (r *Router) owners(ctx context.Context, batch events.BlockBatch, checkpoints []uint64) (map[common.Hash]int, error) {
+	owners := map[common.Hash]int{}
+	unknown := map[common.Hash]struct{}{}
+	for _, block := range batch.Blocks {
+		for _, operation := range block.Operations {
+			key := operationKey(operation)
+			if _, ok := owners[key]; ok {
+				continue
+			}
+			if create := operation.Create; create != nil {
+				namespace := create.StringAttributes[NamespaceAttribute]
+				owners[key], _ = r.route(scope{namespace: &namespace, creator: &create.Owner})
+				continue
+			}
+			unknown[key] = struct{}{}
+		}
+	}
+	for key := range owners {
+		delete(unknown, key)
+	}
+
+	last := batch.Blocks[len(batch.Blocks)-1].Number
+	for i, shard := range r.shards {
+		if len(unknown) == 0 {
+			break
+		}
+		if checkpoints[i] >= last {
+			continue
+		}
+		found, err := lookupKeys(ctx, shard.Store, slices.Collect(maps.Keys(unknown)))
+		if err != nil {
+			return nil, fmt.Errorf("shard %s: failed to look up entities: %w", shard.Name, err)
+		}
+		for _, key := range found {
+			owners[key] = i
+			delete(unknown, key)
+		}
+	}
+	return owners, nil
+}
+
+// lookupKeys returns the keys of the entities of the store among the keys.
+func lookupKeys(ctx context.Context, store *sqlitestore.SQLiteStore, keys []common.Hash) ([]common.Hash, error) {
+	var found []common.Hash
+	perPage := uint64(keyLookupBatchSize)
+	for chunk := range slices.Chunk(keys, keyLookupBatchSize) {
+		hexKeys := make([]string, len(chunk))
+		for i, key := range chunk {
+			hexKeys[i] = key.Hex()
+		}
+		response, err := store.QueryEntities(ctx, fmt.Sprintf("$key IN (%s)", strings.Join(hexKeys, " ")), &sqlitestore.Options{
+			IncludeData:    &sqlitestore.IncludeData{Key: true},
+			ResultsPerPage: &perPage,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, data := range response.Data {
+			var entity sqlitestore.EntityData
+			if err := json.Unmarshal(data, &entity); err != nil {
+				return nil, err
+			}
+			found = append(found, *entity.Key)
+		}
+	}
+	return found, nil
+}
+
+// operationKey returns the key of the entity of the operation.
+func operationKey(operation events.Operation) common.Hash {
+	switch {
+	case operation.Create != nil:
+		return operation.Create.Key
+	case operation.Update != nil:
+		return operation.Update.Key
+	case operation.Delete != nil:
+		return common.Hash(*operation.Delete)
+	case operation.Expire != nil:
+		return common.Hash(*operation.Expire)
+	case operation.ExtendBTL != nil:
+		return operation.ExtendBTL.Key
+	case operation.ChangeOwner != nil:
+		return operation.ChangeOwner.Key
+	}
+	return common.Hash{}
+}
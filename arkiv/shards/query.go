@@ -0,0 +1,276 @@
+package shards
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+
+	sqlitestore "github.com/Arkiv-Network/sqlite-bitmap-store"
+	"github.com/Arkiv-Network/sqlite-bitmap-store/query"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// errShardCursorMismatch is returned for a cursor of a query sent to all the shards
+// created with other shards.
+var errShardCursorMismatch = errors.New("cursor was created for different shards")
+
+// Route returns the shards holding the entities the query can select: the shard of the
+// entities of its namespace or creator if it names them in every alternative, all the
+// shards otherwise.
+func (r *Router) Route(req string) []*Shard {
+	if len(r.shards) == 1 {
+		return r.shards
+	}
+	ast, err := query.Parse(req)
+	if err != nil || ast.Expr == nil {
+		return r.shards
+	}
+	target := -1
+	for _, and := range ast.Expr.Or.Terms {
+		var s scope
+		for _, term := range and.Terms {
+			equality := term.Assign
+			if equality == nil || equality.IsNot || equality.Value.String == nil {
+				continue
+			}
+			value := *equality.Value.String
+			switch equality.Var {
+			case NamespaceAttribute:
+				s.namespace = &value
+			case query.CreatorAttributeKey:
+				if common.IsHexAddress(value) {
+					creator := common.HexToAddress(value)
+					s.creator = &creator
+				}
+			}
+		}
+		shard, ok := r.route(s)
+		if !ok || (target != -1 && shard != target) {
+			return r.shards
+		}
+		target = shard
+	}
+	return r.shards[target : target+1]
+}
+
+// Stores returns the stores of the shards the query is routed to.
+func (r *Router) Stores(req string) []*sqlitestore.SQLiteStore {
+	var stores []*sqlitestore.SQLiteStore
+	for _, shard := range r.Route(req) {
+		stores = append(stores, shard.Store)
+	}
+	return stores
+}
+
+// QueryEntities runs the query in the shards it's routed to. The entities of several
+// shards are merged in the order of the store, newest first. The cursor of a query
+// sent to a single shard is the cursor of its store, otherwise it holds the cursor of
+// every shard.
+func (r *Router) QueryEntities(ctx context.Context, req string, options *sqlitestore.Options) (*sqlitestore.QueryResponse, error) {
+	shards := r.Route(req)
+	if len(shards) == 1 {
+		return shards[0].Store.QueryEntities(ctx, req, options)
+	}
+	if options == nil {
+		options = &sqlitestore.Options{}
+	}
+
+	cursors, err := decodeShardCursor(options.Cursor, len(shards))
+	if err != nil {
+		return nil, err
+	}
+	perPage := options.GetResultsPerPage()
+	include := options.GetIncludeData()
+	// The $sequence of the entities orders the pages of the shards
+	sequenced := include
+	sequenced.CreatedAtBlock = true
+	sequenced.TransactionIndexInBlock = true
+	sequenced.OperationIndexInTransaction = true
+
+	pages := make([][]sequencedEntity, len(shards))
+	more := make([]*string, len(shards))
+	var blockNumber uint64
+	for i, shard := range shards {
+		if cursors[i] == shardCursorDone {
+			continue
+		}
+		shardOptions := &sqlitestore.Options{
+			AtBlock:        options.AtBlock,
+			IncludeData:    &sequenced,
+			ResultsPerPage: &perPage,
+			Cursor:         cursors[i].storeCursor(),
+		}
+		response, err := shard.Store.QueryEntities(ctx, req, shardOptions)
+		if err != nil {
+			return nil, fmt.Errorf("shard %s: %w", shard.Name, err)
+		}
+		blockNumber = max(blockNumber, response.BlockNumber)
+		more[i] = response.Cursor
+		for _, data := range response.Data {
+			var entity sqlitestore.EntityData
+			if err := json.Unmarshal(data, &entity); err != nil {
+				return nil, fmt.Errorf("shard %s: %w", shard.Name, err)
+			}
+			pages[i] = append(pages[i], sequencedEntity{entity: entity, sequence: entitySequence(entity)})
+		}
+	}
+
+	// Merge the pages, every page is ordered newest first
+	response := &sqlitestore.QueryResponse{Data: []json.RawMessage{}, BlockNumber: blockNumber}
+	taken := make([]int, len(shards))
+	for uint64(len(response.Data)) < perPage {
+		next := -1
+		for i, page := range pages {
+			if taken[i] < len(page) && (next == -1 || page[taken[i]].sequence > pages[next][taken[next]].sequence) {
+				next = i
+			}
+		}
+		if next == -1 {
+			break
+		}
+		entity := pages[next][taken[next]].entity
+		stripSequence(&entity, include)
+		data, err := json.Marshal(entity)
+		if err != nil {
+			return nil, err
+		}
+		response.Data = append(response.Data, data)
+		taken[next]++
+	}
+
+	// Every shard continues after the last entity taken from it
+	finished := true
+	for i, shard := range shards {
+		switch {
+		case cursors[i] == shardCursorDone:
+			continue
+		case taken[i] == len(pages[i]) && more[i] == nil:
+			cursors[i] = shardCursorDone
+		case taken[i] == 0:
+		case taken[i] == len(pages[i]):
+			cursors[i], err = parseShardCursor(*more[i])
+		default:
+			cursors[i], err = positionAfter(ctx, shard.Store, req, options.AtBlock, cursors[i], taken[i])
+		}
+		if err != nil {
+			return nil, fmt.Errorf("shard %s: %w", shard.Name, err)
+		}
+		if cursors[i] != shardCursorDone {
+			finished = false
+		}
+	}
+	if !finished {
+		cursor := encodeShardCursor(cursors)
+		response.Cursor = &cursor
+	}
+	return response, nil
+}
+
+// sequencedEntity is an entity of the page of a shard with its $sequence.
+type sequencedEntity struct {
+	entity   sqlitestore.EntityData
+	sequence uint64
+}
+
+// entitySequence returns the $sequence of the entity, the position of its creation on
+// the chain.
+func entitySequence(entity sqlitestore.EntityData) uint64 {
+	var block, tx, op uint64
+	if entity.CreatedAtBlock != nil {
+		block = *entity.CreatedAtBlock
+	}
+	if entity.TransactionIndexInBlock != nil {
+		tx = *entity.TransactionIndexInBlock
+	}
+	if entity.OperationIndexInTransaction != nil {
+		op = *entity.OperationIndexInTransaction
+	}
+	return block<<32 | tx<<16 | op
+}
+
+// stripSequence removes the fields read to order the entities that weren't requested.
+func stripSequence(entity *sqlitestore.EntityData, include sqlitestore.IncludeData) {
+	if !include.CreatedAtBlock {
+		entity.CreatedAtBlock = nil
+	}
+	if !include.TransactionIndexInBlock {
+		entity.TransactionIndexInBlock = nil
+	}
+	if !include.OperationIndexInTransaction {
+		entity.OperationIndexInTransaction = nil
+	}
+}
+
+// positionAfter returns the cursor of the store after the first entities of the page
+// at the cursor.
+func positionAfter(ctx context.Context, store *sqlitestore.SQLiteStore, req string, atBlock *uint64, cursor shardCursor, entities int) (shardCursor, error) {
+	perPage := uint64(entities)
+	response, err := store.QueryEntities(ctx, req, &sqlitestore.Options{
+		AtBlock:        atBlock,
+		IncludeData:    &sqlitestore.IncludeData{},
+		ResultsPerPage: &perPage,
+		Cursor:         cursor.storeCursor(),
+	})
+	if err != nil {
+		return 0, err
+	}
+	if response.Cursor == nil {
+		return shardCursorDone, nil
+	}
+	return parseShardCursor(*response.Cursor)
+}
+
+// shardCursor is the position of a shard in a query sent to all the shards: the cursor
+// of its store, shardCursorStart before its first page or shardCursorDone after its
+// last page.
+type shardCursor uint64
+
+const (
+	shardCursorStart shardCursor = 0
+	shardCursorDone  shardCursor = math.MaxUint64
+)
+
+func (c shardCursor) storeCursor() string {
+	if c == shardCursorStart {
+		return ""
+	}
+	return hexutil.EncodeUint64(uint64(c))
+}
+
+func parseShardCursor(s string) (shardCursor, error) {
+	cursor, err := hexutil.DecodeUint64(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid store cursor %q: %w", s, err)
+	}
+	return shardCursor(cursor), nil
+}
+
+func encodeShardCursor(cursors []shardCursor) string {
+	b := make([]byte, 8*len(cursors))
+	for i, cursor := range cursors {
+		binary.BigEndian.PutUint64(b[8*i:], uint64(cursor))
+	}
+	return hexutil.Encode(b)
+}
+
+func decodeShardCursor(s string, shards int) ([]shardCursor, error) {
+	cursors := make([]shardCursor, shards)
+	if s == "" {
+		return cursors, nil
+	}
+	b, err := hexutil.Decode(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor %q", s)
+	}
+	if len(b) != 8*shards {
+		return nil, errShardCursorMismatch
+	}
+	for i := range cursors {
+		cursors[i] = shardCursor(binary.BigEndian.Uint64(b[8*i:]))
+	}
+	return cursors, nil
+}
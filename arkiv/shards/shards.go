@@ -0,0 +1,215 @@
+// Package shards splits the Arkiv entities over several stores.
+//
+// Every shard is a store in its own sqlite file with its own checkpoint. An entity is
+// assigned to a shard when it's created, by the first rule matching its namespace
+// attribute or the address of its creator, and stays there for its whole life; the
+// entities no rule matches are kept in the default shard, the store the node uses
+// without sharding. All the shards follow a single stream of events, every shard
+// ingesting the operations of its entities.
+//
+// A query selecting the entities of a single shard, like `namespace = "x"`, is sent to
+// that shard, any other query is sent to all the shards and their results are merged
+// newest first. Moving entities between shards isn't supported: a shard has to be
+// added before the entities matching its rule are created.
+package shards
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	sqlitestore "github.com/Arkiv-Network/sqlite-bitmap-store"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// NamespaceAttribute is the string attribute matched by the namespace rules.
+const NamespaceAttribute = "namespace"
+
+// DefaultShard is the name of the shard of the entities no rule matches.
+const DefaultShard = "default"
+
+var (
+	shardNameRegexp   = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+	ownerPrefixRegexp = regexp.MustCompile(`^[0-9a-f]{1,40}$`)
+)
+
+// Rule assigns the entities to a shard by their namespace or by the address of their
+// creator. Exactly one of Namespace and OwnerPrefix is set.
+type Rule struct {
+	// Shard is the name of the shard.
+	Shard string
+	// Namespace is the value of the namespace attribute of the entities of the shard.
+	Namespace string
+	// OwnerPrefix is the prefix of the address of the creator of the entities of the
+	// shard, lower case and without 0x.
+	OwnerPrefix string
+}
+
+// ParseRule parses a rule in the form `<shard>=namespace:<value>` or
+// `<shard>=owner:<0x address prefix>`.
+func ParseRule(s string) (Rule, error) {
+	shard, spec, ok := strings.Cut(s, "=")
+	if !ok {
+		return Rule{}, fmt.Errorf("invalid shard rule %q, expected <shard>=namespace:<value> or <shard>=owner:<0x prefix>", s)
+	}
+	if !shardNameRegexp.MatchString(shard) || shard == DefaultShard {
+		return Rule{}, fmt.Errorf("invalid shard name %q", shard)
+	}
+	kind, value, _ := strings.Cut(spec, ":")
+	switch kind {
+	case "namespace":
+		if value == "" {
+			return Rule{}, fmt.Errorf("shard %s has an empty namespace", shard)
+		}
+		return Rule{Shard: shard, Namespace: value}, nil
+	case "owner":
+		prefix, ok := strings.CutPrefix(strings.ToLower(value), "0x")
+		if !ok || !ownerPrefixRegexp.MatchString(prefix) {
+			return Rule{}, fmt.Errorf("invalid owner prefix %q of shard %s", value, shard)
+		}
+		return Rule{Shard: shard, OwnerPrefix: prefix}, nil
+	default:
+		return Rule{}, fmt.Errorf("invalid shard rule %q, expected <shard>=namespace:<value> or <shard>=owner:<0x prefix>", s)
+	}
+}
+
+// ParseRules parses the rules, in the order they are matched.
+func ParseRules(specs []string) ([]Rule, error) {
+	rules := make([]Rule, 0, len(specs))
+	for _, spec := range specs {
+		rule, err := ParseRule(spec)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// Shard is one of the stores of a router.
+type Shard struct {
+	Name  string
+	Path  string
+	Store *sqlitestore.SQLiteStore
+}
+
+// Router reads and writes the entities of the shards of a store.
+type Router struct {
+	rules []Rule
+	// shards holds the default shard first, then the shards of the rules by name.
+	shards []*Shard
+	// index is the index in shards of the shard of every rule.
+	index []int
+}
+
+// ShardPath returns the path of the file of a shard next to the file of the default
+// shard.
+func ShardPath(path string, shard string) string {
+	return path + "-shard-" + shard
+}
+
+// New returns a router over the store of the default shard, in the file at path, and
+// the shards of the rules opened with open. Several rules can share a shard. A shard
+// opened for the first time is empty and ingests the events from the first block,
+// without affecting the other shards.
+func New(store *sqlitestore.SQLiteStore, path string, rules []Rule, open func(path string) (*sqlitestore.SQLiteStore, error)) (*Router, error) {
+	r := &Router{
+		rules:  rules,
+		shards: []*Shard{{Name: DefaultShard, Path: path, Store: store}},
+	}
+	if len(rules) > 0 && path == ":memory:" {
+		return nil, errors.New("the Arkiv store can't be sharded in memory")
+	}
+	byName := map[string]int{}
+	for _, rule := range rules {
+		i, ok := byName[rule.Shard]
+		if !ok {
+			shardPath := ShardPath(path, rule.Shard)
+			shardStore, err := open(shardPath)
+			if err != nil {
+				r.Close()
+				return nil, fmt.Errorf("failed to open shard %s: %w", rule.Shard, err)
+			}
+			i = len(r.shards)
+			byName[rule.Shard] = i
+			r.shards = append(r.shards, &Shard{Name: rule.Shard, Path: shardPath, Store: shardStore})
+		}
+		r.index = append(r.index, i)
+	}
+	return r, nil
+}
+
+// Shards returns the shards, the default shard first.
+func (r *Router) Shards() []*Shard {
+	return r.shards
+}
+
+// Close closes the stores of the shards other than the default one, which is owned by
+// the caller of New.
+func (r *Router) Close() error {
+	var errs []error
+	for _, shard := range r.shards[1:] {
+		errs = append(errs, shard.Store.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// GetLastBlock returns the last block ingested by all the shards.
+func (r *Router) GetLastBlock(ctx context.Context) (uint64, error) {
+	var last uint64
+	for i, shard := range r.shards {
+		block, err := shard.Store.GetLastBlock(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("shard %s: %w", shard.Name, err)
+		}
+		if i == 0 || block < last {
+			last = block
+		}
+	}
+	return last, nil
+}
+
+// GetNumberOfEntities returns the number of entities of all the shards.
+func (r *Router) GetNumberOfEntities(ctx context.Context) (uint64, error) {
+	var total uint64
+	for _, shard := range r.shards {
+		entities, err := shard.Store.GetNumberOfEntities(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("shard %s: %w", shard.Name, err)
+		}
+		total += entities
+	}
+	return total, nil
+}
+
+// scope is what's known of the namespace and the creator of an entity, nil if unknown.
+type scope struct {
+	namespace *string
+	creator   *common.Address
+}
+
+// route returns the index of the shard of the entities in the scope, false if the
+// scope doesn't tell the rule they match.
+func (r *Router) route(s scope) (int, bool) {
+	for i, rule := range r.rules {
+		switch {
+		case rule.Namespace != "":
+			if s.namespace == nil {
+				return 0, false
+			}
+			if *s.namespace == rule.Namespace {
+				return r.index[i], true
+			}
+		default:
+			if s.creator == nil {
+				return 0, false
+			}
+			if strings.HasPrefix(strings.ToLower(s.creator.Hex()[2:]), rule.OwnerPrefix) {
+				return r.index[i], true
+			}
+		}
+	}
+	return 0, true
+}
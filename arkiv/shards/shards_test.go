@@ -0,0 +1,307 @@
+package shards
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	arkivevents "github.com/Arkiv-Network/arkiv-events"
+	"github.com/Arkiv-Network/arkiv-events/events"
+	sqlitestore "github.com/Arkiv-Network/sqlite-bitmap-store"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	alice = common.HexToAddress("0xab00000000000000000000000000000000000001")
+	bob   = common.HexToAddress("0xcd00000000000000000000000000000000000002")
+)
+
+func openStore(t *testing.T, path string) *sqlitestore.SQLiteStore {
+	t.Helper()
+	store, err := sqlitestore.NewSQLiteStore(slog.New(slog.DiscardHandler), path, 1)
+	require.NoError(t, err)
+	return store
+}
+
+// openRouter opens a router over the store files in the directory, closed when the
+// test ends.
+func openRouter(t *testing.T, dir string, specs ...string) *Router {
+	t.Helper()
+	rules, err := ParseRules(specs)
+	require.NoError(t, err)
+	path := filepath.Join(dir, "arkiv.db")
+	store := openStore(t, path)
+	router, err := New(store, path, rules, func(path string) (*sqlitestore.SQLiteStore, error) {
+		return sqlitestore.NewSQLiteStore(slog.New(slog.DiscardHandler), path, 1)
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		router.Close()
+		store.Close()
+	})
+	return router
+}
+
+// closeRouter closes the stores of all the shards of the router.
+func closeRouter(router *Router) {
+	router.Close()
+	router.Shards()[0].Store.Close()
+}
+
+func create(key byte, owner common.Address, namespace string, txIndex uint64) events.Operation {
+	attributes := map[string]string{}
+	if namespace != "" {
+		attributes[NamespaceAttribute] = namespace
+	}
+	return events.Operation{TxIndex: txIndex, Create: &events.OPCreate{
+		Key:               common.Hash{key},
+		ContentType:       "text/plain",
+		BTL:               100,
+		Owner:             owner,
+		Content:           []byte{key},
+		StringAttributes:  attributes,
+		NumericAttributes: map[string]uint64{},
+	}}
+}
+
+func update(key byte, owner common.Address, namespace string) events.Operation {
+	return events.Operation{Update: &events.OPUpdate{
+		Key:               common.Hash{key},
+		ContentType:       "text/plain",
+		BTL:               100,
+		Owner:             owner,
+		Content:           []byte{key, key},
+		StringAttributes:  map[string]string{NamespaceAttribute: namespace},
+		NumericAttributes: map[string]uint64{},
+	}}
+}
+
+func remove(key byte) events.Operation {
+	deleted := events.OPDelete(common.Hash{key})
+	return events.Operation{Delete: &deleted}
+}
+
+// history is the chain of the tests:
+//   - block 1: 0x01 in namespace a, 0x02 of alice, 0x03 in namespace b
+//   - block 2: 0x04 in namespace a, 0x05 of bob, 0x01 is updated into namespace b
+//   - block 3: 0x02 is deleted, 0x06 in namespace c
+var history = []events.Block{
+	{Number: 1, Operations: []events.Operation{create(0x01, bob, "a", 0), create(0x02, alice, "", 1), create(0x03, bob, "b", 2)}},
+	{Number: 2, Operations: []events.Operation{create(0x04, bob, "a", 0), create(0x05, bob, "", 1), update(0x01, bob, "b")}},
+	{Number: 3, Operations: []events.Operation{remove(0x02), create(0x06, bob, "c", 0)}},
+}
+
+// follow ingests the blocks of the history from the block after the last block of the
+// router, one batch per block.
+func follow(t *testing.T, router *Router, blocks []events.Block) {
+	t.Helper()
+	last, err := router.GetLastBlock(context.Background())
+	require.NoError(t, err)
+	iterator := func(yield func(arkivevents.BatchOrError) bool) {
+		for _, block := range blocks {
+			if block.Number > last && !yield(arkivevents.BatchOrError{Batch: events.BlockBatch{Blocks: []events.Block{block}}}) {
+				return
+			}
+		}
+	}
+	require.NoError(t, router.FollowEvents(context.Background(), iterator))
+}
+
+// shardKeys returns the keys of the entities of the shard.
+func shardKeys(t *testing.T, shard *Shard) []common.Hash {
+	t.Helper()
+	response, err := shard.Store.QueryEntities(context.Background(), "$all", &sqlitestore.Options{IncludeData: &sqlitestore.IncludeData{Key: true}})
+	require.NoError(t, err)
+	return responseKeys(t, response)
+}
+
+func responseKeys(t *testing.T, response *sqlitestore.QueryResponse) []common.Hash {
+	t.Helper()
+	keys := []common.Hash{}
+	for _, data := range response.Data {
+		var entity sqlitestore.EntityData
+		require.NoError(t, json.Unmarshal(data, &entity))
+		keys = append(keys, *entity.Key)
+	}
+	return keys
+}
+
+func hashes(keys ...byte) []common.Hash {
+	hashes := []common.Hash{}
+	for _, key := range keys {
+		hashes = append(hashes, common.Hash{key})
+	}
+	return hashes
+}
+
+func TestParseRule(t *testing.T) {
+	rule, err := ParseRule("apps=namespace:my-app")
+	require.NoError(t, err)
+	require.Equal(t, Rule{Shard: "apps", Namespace: "my-app"}, rule)
+
+	rule, err = ParseRule("alice=owner:0xAB")
+	require.NoError(t, err)
+	require.Equal(t, Rule{Shard: "alice", OwnerPrefix: "ab"}, rule)
+
+	for _, spec := range []string{"apps", "apps=label:x", "apps=namespace:", "default=namespace:x", "Apps=namespace:x", "alice=owner:ab", "alice=owner:0xzz", "alice=owner:0x"} {
+		_, err := ParseRule(spec)
+		require.Error(t, err, spec)
+	}
+}
+
+func TestRouter_Follow(t *testing.T) {
+	router := openRouter(t, t.TempDir(), "a=namespace:a", "alice=owner:0xab")
+	follow(t, router, history)
+
+	shards := router.Shards()
+	require.Equal(t, []string{"default", "a", "alice"}, []string{shards[0].Name, shards[1].Name, shards[2].Name})
+	// 0x01 stays in the shard of namespace a after it moved to namespace b
+	require.Equal(t, hashes(0x06, 0x05, 0x03), shardKeys(t, shards[0]))
+	require.Equal(t, hashes(0x04, 0x01), shardKeys(t, shards[1]))
+	require.Empty(t, shardKeys(t, shards[2]))
+
+	for _, shard := range shards {
+		last, err := shard.Store.GetLastBlock(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, uint64(3), last, shard.Name)
+	}
+	entities, err := router.GetNumberOfEntities(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, uint64(5), entities)
+}
+
+func TestRouter_Route(t *testing.T) {
+	router := openRouter(t, t.TempDir(), "a=namespace:a", "alice=owner:0xab", "b=namespace:b")
+	shards := router.Shards()
+	for query, expected := range map[string][]*Shard{
+		`namespace = "a"`:                                  shards[1:2],
+		`namespace = "a" && kind = "x"`:                    shards[1:2],
+		`namespace = "a" || (namespace = "a" && kind = 1)`: shards[1:2],
+		// An entity of namespace b created by alice is in the shard of alice
+		`namespace = "b"`: shards,
+		`namespace = "b" && $creator = 0xcd00000000000000000000000000000000000002`: shards[3:4],
+		`namespace = "b" && $creator = 0xAB00000000000000000000000000000000000001`: shards[2:3],
+		`namespace = "z" && $creator = 0xcd00000000000000000000000000000000000002`: shards[0:1],
+		`namespace = "a" || namespace = "c"`:                                       shards,
+		`namespace != "a"`:                                                         shards,
+		`$all`:                                                                     shards,
+		`invalid query (`:                                                          shards,
+	} {
+		require.Equal(t, expected, router.Route(query), query)
+	}
+}
+
+func TestRouter_QueryEntities(t *testing.T) {
+	router := openRouter(t, t.TempDir(), "a=namespace:a", "alice=owner:0xab")
+	follow(t, router, history)
+	ctx := context.Background()
+
+	// A scoped query reads a single shard with the cursors of its store
+	perPage := uint64(1)
+	response, err := router.QueryEntities(ctx, `namespace = "a"`, &sqlitestore.Options{
+		IncludeData:    &sqlitestore.IncludeData{Key: true},
+		ResultsPerPage: &perPage,
+	})
+	require.NoError(t, err)
+	require.Equal(t, hashes(0x04), responseKeys(t, response))
+	require.NotNil(t, response.Cursor)
+	direct, err := router.Shards()[1].Store.QueryEntities(ctx, `namespace = "a"`, &sqlitestore.Options{
+		IncludeData:    &sqlitestore.IncludeData{Key: true},
+		ResultsPerPage: &perPage,
+	})
+	require.NoError(t, err)
+	require.Equal(t, direct, response)
+
+	// Other queries are merged newest first over all the shards
+	for _, perPage := range []uint64{1, 2, 3, 4, 100} {
+		var keys []common.Hash
+		cursor := ""
+		for {
+			response, err := router.QueryEntities(ctx, "$all", &sqlitestore.Options{
+				IncludeData:    &sqlitestore.IncludeData{Key: true},
+				ResultsPerPage: &perPage,
+				Cursor:         cursor,
+			})
+			require.NoError(t, err)
+			require.LessOrEqual(t, len(response.Data), int(perPage))
+			keys = append(keys, responseKeys(t, response)...)
+			if response.Cursor == nil {
+				break
+			}
+			cursor = *response.Cursor
+		}
+		require.Equal(t, hashes(0x06, 0x05, 0x04, 0x03, 0x01), keys, "%d per page", perPage)
+	}
+
+	// The fields read to merge the pages are only returned if requested
+	response, err = router.QueryEntities(ctx, `namespace = "b"`, &sqlitestore.Options{IncludeData: &sqlitestore.IncludeData{Key: true}})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"key":"`+common.Hash{0x03}.Hex()+`"}`, string(response.Data[0]))
+	response, err = router.QueryEntities(ctx, `namespace = "b"`, &sqlitestore.Options{IncludeData: &sqlitestore.IncludeData{Key: true, CreatedAtBlock: true}})
+	require.NoError(t, err)
+	require.Equal(t, hashes(0x03, 0x01), responseKeys(t, response))
+	require.JSONEq(t, `{"key":"`+common.Hash{0x03}.Hex()+`","createdAtBlock":1}`, string(response.Data[0]))
+
+	_, err = router.QueryEntities(ctx, "$all", &sqlitestore.Options{Cursor: "0x01"})
+	require.ErrorIs(t, err, errShardCursorMismatch)
+}
+
+func TestRouter_NewShard(t *testing.T) {
+	dir := t.TempDir()
+	router := openRouter(t, dir, "a=namespace:a")
+	follow(t, router, history[:2])
+	closeRouter(router)
+
+	// The shard of namespace c, added before its first entity, ingests the history from
+	// the start while the other shards wait for it
+	router = openRouter(t, dir, "a=namespace:a", "c=namespace:c")
+	last, err := router.GetLastBlock(context.Background())
+	require.NoError(t, err)
+	require.Zero(t, last)
+	shards := router.Shards()
+	before := [][]common.Hash{shardKeys(t, shards[0]), shardKeys(t, shards[1])}
+
+	follow(t, router, history[:2])
+	for _, shard := range shards {
+		last, err := shard.Store.GetLastBlock(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, uint64(2), last, shard.Name)
+	}
+	require.Equal(t, before, [][]common.Hash{shardKeys(t, shards[0]), shardKeys(t, shards[1])}, "the other shards are untouched")
+	require.Empty(t, shardKeys(t, shards[2]))
+
+	follow(t, router, history)
+	require.Equal(t, hashes(0x05, 0x03), shardKeys(t, shards[0]))
+	require.Equal(t, hashes(0x04, 0x01), shardKeys(t, shards[1]))
+	require.Equal(t, hashes(0x06), shardKeys(t, shards[2]))
+}
+
+func TestRouter_RebuildShard(t *testing.T) {
+	dir := t.TempDir()
+	router := openRouter(t, dir, "a=namespace:a", "alice=owner:0xab")
+	follow(t, router, history)
+	closeRouter(router)
+
+	// The shard of namespace a is rebuilt from the start
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		err := os.Remove(ShardPath(filepath.Join(dir, "arkiv.db"), "a") + suffix)
+		if !os.IsNotExist(err) {
+			require.NoError(t, err)
+		}
+	}
+	router = openRouter(t, dir, "a=namespace:a", "alice=owner:0xab")
+	require.Empty(t, shardKeys(t, router.Shards()[1]))
+	follow(t, router, history)
+
+	shards := router.Shards()
+	require.Equal(t, hashes(0x06, 0x05, 0x03), shardKeys(t, shards[0]))
+	require.Equal(t, hashes(0x04, 0x01), shardKeys(t, shards[1]))
+	require.Empty(t, shardKeys(t, shards[2]))
+	entities, err := router.GetNumberOfEntities(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, uint64(5), entities)
+}
@@ -0,0 +1,111 @@
+//go:build soak
+
+// Package soak runs a dev mode node for many blocks under a random load of storage
+// transactions and checks that the state of the processor, the store and a replay of
+// the logs agree on the live entities at the end of the run.
+//
+// Run it with `go test -tags soak -timeout 0 ./arkiv/soak/`, the length and the load
+// of the run are configured with the ARKIV_SOAK_* environment variables, see
+// testutil.SoakConfig.
+package soak
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/arkiv/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSoak(t *testing.T) {
+	config, err := testutil.SoakConfigFromEnv()
+	require.NoError(t, err)
+	t.Logf("soak config: %+v", config)
+
+	gethPath, cleanupCompiled, err := testutil.CompileGeth("../../cmd/geth")
+	require.NoError(t, err)
+	defer cleanupCompiled()
+
+	ctx := context.Background()
+
+	world, err := testutil.NewWorld(ctx, gethPath)
+	require.NoError(t, err)
+	defer world.Shutdown()
+
+	fail := func(format string, args ...any) {
+		t.Helper()
+		t.Fatal(world.AddLogsToTestError(fmt.Errorf(format, args...)))
+	}
+
+	accounts, err := world.FundAccounts(ctx, config.Workers, testutil.EthToWei(1000))
+	if err != nil {
+		fail("failed to fund accounts: %w", err)
+	}
+
+	startBlock, err := world.GethInstance.ETHClient.BlockNumber(ctx)
+	if err != nil {
+		fail("failed to get block number: %w", err)
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, config.Duration)
+	defer cancel()
+
+	generator := testutil.NewSoakGenerator(config)
+	stats, err := world.RunSoakWorkers(runCtx, generator, accounts, startBlock+config.Blocks)
+	if err != nil {
+		fail("soak run failed: %w", err)
+	}
+
+	block, err := world.GethInstance.ETHClient.BlockNumber(ctx)
+	if err != nil {
+		fail("failed to get block number: %w", err)
+	}
+	t.Logf("soak run ended at block %d: %s", block, stats)
+
+	checkCtx, cancelCheck := context.WithTimeout(ctx, time.Minute)
+	defer cancelCheck()
+
+	err = world.WaitForStore(checkCtx, block)
+	if err != nil {
+		fail("store didn't catch up: %w", err)
+	}
+
+	replay, created, err := world.ReplaySnapshot(checkCtx, block)
+	if err != nil {
+		fail("failed to replay logs: %w", err)
+	}
+
+	processor, err := world.ProcessorSnapshot(checkCtx, created, block)
+	if err != nil {
+		fail("failed to read processor state: %w", err)
+	}
+
+	store, err := world.StoreSnapshot(checkCtx, block)
+	if err != nil {
+		fail("failed to query store: %w", err)
+	}
+
+	t.Logf("entities created: %d, live at block %d: %d", len(created), block, len(replay))
+
+	// The store keeps the payloads compressed, compressing never makes them larger
+	rawSize, servedSize, err := world.StorePayloadSizes(checkCtx, block)
+	if err != nil {
+		fail("failed to query the payloads: %w", err)
+	}
+	t.Logf("payloads of the live entities: %d bytes, %d bytes compressed", rawSize, servedSize)
+	if servedSize > rawSize {
+		fail("compressed payloads take %d bytes, more than the %d bytes of the payloads", servedSize, rawSize)
+	}
+
+	divergences := testutil.CompareSnapshots(map[string]testutil.EntitySnapshot{
+		"processor": processor,
+		"store":     store,
+		"replay":    replay,
+	})
+	if len(divergences) > 0 {
+		fail("%d entities diverge at block %d:\n%s", len(divergences), block, strings.Join(divergences, "\n"))
+	}
+}
@@ -0,0 +1,277 @@
+package statediff
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/arkiv/storageaccounting"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity/entityexpiration"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/keyset"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/holiman/uint256"
+)
+
+// The kinds of the slots of the processor.
+const (
+	KindEntityMetaData       = "entityMetaData"
+	KindEntityContentHash    = "entityContentHash"
+	KindTombstone            = "tombstone"
+	KindPendingOwner         = "pendingOwner"
+	KindEntitiesToExpire     = "entitiesToExpire"
+	KindTombstonesToSweep    = "tombstonesToSweep"
+	KindPendingOwnersToLapse = "pendingOwnersToLapse"
+	KindUsedSlots            = "usedSlots"
+	KindOwnerUsedSlots       = "ownerUsedSlots"
+	KindAlias                = "alias"
+	KindAliasOwner           = "aliasOwner"
+	KindAliasesToExpire      = "aliasesToExpire"
+	KindIdempotencyKey       = "idempotencyKey"
+	KindIdempotencyToExpire  = "idempotencyKeysToExpire"
+	// KindUnknown is the kind of the slots that couldn't be derived from the hints.
+	KindUnknown = "unknown"
+)
+
+// The parts of the sets of entities keyed by block, see keyset.
+const (
+	// PartSize is the slot holding the size of the set.
+	PartSize = "size"
+	// PartElement is the slot holding the element of the set at a position.
+	PartElement = "element"
+	// PartIndex is the slot holding the position of an entity in the set, plus one.
+	PartIndex = "index"
+)
+
+// maxSetSize bounds the positions of the elements of the sets recognized by Decode.
+const maxSetSize = 1 << 32
+
+// DecodedSlot is a written slot with the structure of the processor it belongs to and
+// its decoded values.
+type DecodedSlot struct {
+	Slot
+	Kind string `json:"kind"`
+	// Key is the entity of the metadata, tombstone or pending owner, the name hash of
+	// the alias, the hash of the idempotency key, or the entity, the alias or the
+	// idempotency key whose position in a set the slot holds.
+	Key *common.Hash `json:"key,omitempty"`
+	// Owner is the owner whose used slots the slot counts.
+	Owner *common.Address `json:"owner,omitempty"`
+	// Block is the block of the set the slot belongs to.
+	Block *hexutil.Uint64 `json:"block,omitempty"`
+	// Part is the part of the set the slot holds, and Position the position of the
+	// element it holds.
+	Part     string  `json:"part,omitempty"`
+	Position *uint64 `json:"position,omitempty"`
+	// OldValue and NewValue are the decoded values of the slot, nil if it's empty.
+	OldValue any `json:"oldValue"`
+	NewValue any `json:"newValue"`
+}
+
+// MetaData is the decoded value of a slot of KindEntityMetaData.
+type MetaData struct {
+	Owner          common.Address `json:"owner"`
+	ExpiresAtBlock hexutil.Uint64 `json:"expiresAtBlock"`
+}
+
+// Tombstone is the decoded value of a slot of KindTombstone.
+type Tombstone struct {
+	Reason string         `json:"reason"`
+	Block  hexutil.Uint64 `json:"block"`
+}
+
+// PendingOwner is the decoded value of a slot of KindPendingOwner.
+type PendingOwner struct {
+	Owner         common.Address `json:"owner"`
+	LapsesAtBlock hexutil.Uint64 `json:"lapsesAtBlock"`
+}
+
+// AliasOwner is the decoded value of a slot of KindAliasOwner.
+type AliasOwner struct {
+	Owner          common.Address `json:"owner"`
+	ExpiresAtBlock hexutil.Uint64 `json:"expiresAtBlock"`
+}
+
+// Hints are the entity keys, alias name hashes and idempotency hashes, owners and
+// blocks the slots are derived from, on top of the ones the written slots hold.
+type Hints struct {
+	Keys   []common.Hash
+	Owners []common.Address
+	Blocks []uint64
+}
+
+// slotInfo describes a slot derived from the hints.
+type slotInfo struct {
+	kind   string
+	key    *common.Hash
+	owner  *common.Address
+	block  *hexutil.Uint64
+	part   string
+	decode func(common.Hash) any
+}
+
+// set is a set of entities keyed by block.
+type set struct {
+	key   common.Hash
+	kind  string
+	block uint64
+}
+
+// Decode decodes the slots written by a transaction. The slots are hashed, so they are
+// recognized by deriving the slots of the entities, owners and blocks of the hints and
+// of the ones held by the written slots: the entities of the elements of the sets,
+// the owners of the metadata and the expiration blocks. The slots that can't be
+// derived are of KindUnknown.
+func Decode(slots []Slot, hints Hints) []DecodedSlot {
+	known := map[common.Hash]slotInfo{}
+
+	keys := map[common.Hash]struct{}{}
+	for _, key := range hints.Keys {
+		keys[key] = struct{}{}
+	}
+	for _, slot := range slots {
+		for _, value := range []common.Hash{slot.Old, slot.New} {
+			if value != (common.Hash{}) {
+				keys[value] = struct{}{}
+			}
+		}
+	}
+	for key := range keys {
+		known[crypto.Keccak256Hash(entity.EntityMetaDataSalt, key[:])] = slotInfo{kind: KindEntityMetaData, key: &key, decode: decodeMetaData}
+		known[entity.ContentHashSlot(key)] = slotInfo{kind: KindEntityContentHash, key: &key, decode: decodeHash}
+		known[crypto.Keccak256Hash(entity.TombstoneSalt, key[:])] = slotInfo{kind: KindTombstone, key: &key, decode: decodeTombstone}
+		known[crypto.Keccak256Hash(entity.PendingOwnerSalt, key[:])] = slotInfo{kind: KindPendingOwner, key: &key, decode: decodePendingOwner}
+		known[crypto.Keccak256Hash(entity.AliasSalt, key[:])] = slotInfo{kind: KindAlias, key: &key, decode: decodeHash}
+		known[crypto.Keccak256Hash(entity.AliasOwnerSalt, key[:])] = slotInfo{kind: KindAliasOwner, key: &key, decode: decodeAliasOwner}
+		known[crypto.Keccak256Hash(entity.IdempotencySalt, key[:])] = slotInfo{kind: KindIdempotencyKey, key: &key, decode: decodeHash}
+	}
+
+	// The owners and the blocks held by the slots of the entities
+	owners := map[common.Address]struct{}{}
+	for _, owner := range hints.Owners {
+		owners[owner] = struct{}{}
+	}
+	blocks := map[uint64]struct{}{}
+	for _, block := range hints.Blocks {
+		blocks[block] = struct{}{}
+	}
+	for _, slot := range slots {
+		info, ok := known[slot.Slot]
+		if !ok {
+			continue
+		}
+		for _, value := range []common.Hash{slot.Old, slot.New} {
+			if value == (common.Hash{}) {
+				continue
+			}
+			switch decoded := info.decode(value).(type) {
+			case *MetaData:
+				owners[decoded.Owner] = struct{}{}
+				blocks[uint64(decoded.ExpiresAtBlock)] = struct{}{}
+			case *PendingOwner:
+				owners[decoded.Owner] = struct{}{}
+				blocks[uint64(decoded.LapsesAtBlock)] = struct{}{}
+			case *AliasOwner:
+				owners[decoded.Owner] = struct{}{}
+				blocks[uint64(decoded.ExpiresAtBlock)] = struct{}{}
+			}
+		}
+	}
+
+	known[storageaccounting.UsedSlotsKey] = slotInfo{kind: KindUsedSlots, decode: decodeCounter}
+	for owner := range owners {
+		known[storageaccounting.OwnerUsedSlotsKey(owner)] = slotInfo{kind: KindOwnerUsedSlots, owner: &owner, decode: decodeCounter}
+	}
+
+	var sets []set
+	for block := range blocks {
+		number := new(big.Int).SetUint64(block).Bytes()
+		sets = append(sets,
+			set{key: crypto.Keccak256Hash(entityexpiration.BlockExpirationSalt, number), kind: KindEntitiesToExpire, block: block},
+			set{key: crypto.Keccak256Hash(entity.TombstoneSweepSalt, number), kind: KindTombstonesToSweep, block: block},
+			set{key: crypto.Keccak256Hash(entity.PendingOwnerLapseSalt, number), kind: KindPendingOwnersToLapse, block: block},
+			set{key: crypto.Keccak256Hash(entity.AliasExpirationSalt, number), kind: KindAliasesToExpire, block: block},
+			set{key: crypto.Keccak256Hash(entity.IdempotencyExpirationSalt, number), kind: KindIdempotencyToExpire, block: block},
+		)
+	}
+	for _, s := range sets {
+		block := hexutil.Uint64(s.block)
+		known[s.key] = slotInfo{kind: s.kind, block: &block, part: PartSize, decode: decodeCounter}
+		for key := range keys {
+			known[crypto.Keccak256Hash(keyset.MapKeyPrefix, s.key[:], key[:])] = slotInfo{kind: s.kind, key: &key, block: &block, part: PartIndex, decode: decodeCounter}
+		}
+	}
+
+	decoded := make([]DecodedSlot, 0, len(slots))
+	for _, slot := range slots {
+		info, ok := known[slot.Slot]
+		var position *uint64
+		if !ok {
+			info, position = element(sets, slot.Slot)
+		}
+		d := DecodedSlot{
+			Slot:     slot,
+			Kind:     info.kind,
+			Key:      info.key,
+			Owner:    info.owner,
+			Block:    info.block,
+			Part:     info.part,
+			Position: position,
+		}
+		if slot.Old != (common.Hash{}) {
+			d.OldValue = info.decode(slot.Old)
+		}
+		if slot.New != (common.Hash{}) {
+			d.NewValue = info.decode(slot.New)
+		}
+		decoded = append(decoded, d)
+	}
+	return decoded
+}
+
+// element returns the set whose element the slot holds, and its position. The elements
+// of a set are stored in the slots following the one of its size.
+func element(sets []set, slot common.Hash) (slotInfo, *uint64) {
+	for _, s := range sets {
+		offset := new(uint256.Int).Sub(new(uint256.Int).SetBytes32(slot[:]), new(uint256.Int).SetBytes32(s.key[:]))
+		if offset.IsZero() || !offset.IsUint64() || offset.Uint64() > maxSetSize {
+			continue
+		}
+		block, position := hexutil.Uint64(s.block), offset.Uint64()-1
+		return slotInfo{kind: s.kind, block: &block, part: PartElement, decode: decodeHash}, &position
+	}
+	return slotInfo{kind: KindUnknown, decode: decodeHash}, nil
+}
+
+func decodeMetaData(value common.Hash) any {
+	var md entity.EntityMetaData
+	md.Unmarshal(value)
+	return &MetaData{Owner: md.Owner, ExpiresAtBlock: hexutil.Uint64(md.ExpiresAtBlock)}
+}
+
+func decodeTombstone(value common.Hash) any {
+	var tombstone entity.Tombstone
+	tombstone.Unmarshal(value)
+	return &Tombstone{Reason: tombstone.Reason.String(), Block: hexutil.Uint64(tombstone.Block)}
+}
+
+func decodePendingOwner(value common.Hash) any {
+	var pending entity.PendingOwner
+	pending.Unmarshal(value)
+	return &PendingOwner{Owner: pending.Owner, LapsesAtBlock: hexutil.Uint64(pending.LapsesAtBlock)}
+}
+
+func decodeAliasOwner(value common.Hash) any {
+	return &AliasOwner{
+		Owner:          common.BytesToAddress(value[:20]),
+		ExpiresAtBlock: hexutil.Uint64(new(uint256.Int).SetBytes(value[24:]).Uint64()),
+	}
+}
+
+func decodeCounter(value common.Hash) any {
+	return new(uint256.Int).SetBytes32(value[:]).Uint64()
+}
+
+func decodeHash(value common.Hash) any {
+	return value
+}
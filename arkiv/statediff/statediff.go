@@ -0,0 +1,60 @@
+// Package statediff records the slots of the Arkiv processor written by a transaction
+// and decodes them into the structures of the processor they belong to: the metadata,
+// content hashes, tombstones and pending owners of the entities, the sets of entities keyed by block
+// and the counters of used slots.
+package statediff
+
+import (
+	"github.com/ethereum/go-ethereum/arkiv/address"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Slot is a slot of the processor written through a Recorder, with its value before
+// the first write and after the last one.
+type Slot struct {
+	Slot common.Hash `json:"slot"`
+	Old  common.Hash `json:"old"`
+	New  common.Hash `json:"new"`
+}
+
+// Recorder is a StateAccess recording the slots of the processor written through it.
+type Recorder struct {
+	access storageutil.StateAccess
+	slots  []Slot
+	index  map[common.Hash]int
+}
+
+// NewRecorder returns a recorder writing through to access.
+func NewRecorder(access storageutil.StateAccess) *Recorder {
+	return &Recorder{access: access, index: map[common.Hash]int{}}
+}
+
+func (r *Recorder) GetState(addr common.Address, key common.Hash) common.Hash {
+	return r.access.GetState(addr, key)
+}
+
+func (r *Recorder) SetState(addr common.Address, key common.Hash, value common.Hash) common.Hash {
+	prev := r.access.SetState(addr, key, value)
+	if addr != address.ArkivProcessorAddress {
+		return prev
+	}
+	if i, ok := r.index[key]; ok {
+		r.slots[i].New = value
+	} else {
+		r.index[key] = len(r.slots)
+		r.slots = append(r.slots, Slot{Slot: key, Old: prev, New: value})
+	}
+	return prev
+}
+
+// Slots returns the slots whose value changed, in the order they were first written.
+func (r *Recorder) Slots() []Slot {
+	slots := []Slot{}
+	for _, slot := range r.slots {
+		if slot.Old != slot.New {
+			slots = append(slots, slot)
+		}
+	}
+	return slots
+}
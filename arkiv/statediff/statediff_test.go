@@ -0,0 +1,137 @@
+package statediff_test
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/arkiv/address"
+	"github.com/ethereum/go-ethereum/arkiv/statediff"
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+var writeGoldenFlag = flag.Bool("write-golden", false, "Overwrite the golden state diffs in testdata/")
+
+const goldenFile = "testdata/statediff.json"
+
+const (
+	tombstoneRetention = 50
+	transferWindow     = 20
+	idempotencyTTL     = 30
+)
+
+var sender = common.HexToAddress("0x1111111111111111111111111111111111111111")
+
+// memoryState is a state of the processor held in memory.
+type memoryState map[common.Hash]common.Hash
+
+func (s memoryState) GetState(addr common.Address, key common.Hash) common.Hash {
+	return s[key]
+}
+
+func (s memoryState) SetState(addr common.Address, key common.Hash, value common.Hash) common.Hash {
+	prev := s[key]
+	s[key] = value
+	return prev
+}
+
+// execute runs the transaction at the block and returns its logs and the decoded state
+// diff.
+func execute(t *testing.T, state memoryState, block uint64, tx *storagetx.ArkivTransaction) ([]*types.Log, []statediff.DecodedSlot) {
+	t.Helper()
+
+	recorder := statediff.NewRecorder(state)
+	rules := storagetx.Rules{
+		TombstoneRetention: tombstoneRetention,
+		TransferWindow:     transferWindow,
+		IdempotencyTTL:     idempotencyTTL,
+		OwnerSlots:         true,
+		ContentHash:        true,
+		Aliases:            true,
+		ReduceBTL:          true,
+		ChangeOwnerBatch:   true,
+	}
+	logs, err := tx.Execute(block, common.BigToHash(common.Big1), 0, sender, rules, recorder)
+	require.NoError(t, err)
+
+	hints := statediff.Hints{
+		Owners: []common.Address{sender},
+		Blocks: []uint64{block, block + tombstoneRetention, block + transferWindow, block + idempotencyTTL},
+	}
+	for _, log := range logs {
+		hints.Keys = append(hints.Keys, log.Topics[1])
+	}
+	for _, create := range tx.Create {
+		if create.IdempotencyKey != nil {
+			hints.Keys = append(hints.Keys, entity.IdempotencyHash(sender, *create.IdempotencyKey))
+		}
+	}
+	return logs, statediff.Decode(recorder.Slots(), hints)
+}
+
+func TestRecorder(t *testing.T) {
+	state := memoryState{}
+	recorder := statediff.NewRecorder(state)
+
+	// Only the net changes of the slots of the processor are recorded
+	recorder.SetState(address.ArkivProcessorAddress, common.Hash{1}, common.Hash{1})
+	recorder.SetState(address.ArkivProcessorAddress, common.Hash{1}, common.Hash{2})
+	recorder.SetState(address.ArkivProcessorAddress, common.Hash{2}, common.Hash{1})
+	recorder.SetState(address.ArkivProcessorAddress, common.Hash{2}, common.Hash{})
+	recorder.SetState(common.HexToAddress("0x2"), common.Hash{3}, common.Hash{1})
+	require.Equal(t, []statediff.Slot{{Slot: common.Hash{1}, New: common.Hash{2}}}, recorder.Slots())
+	require.Equal(t, common.Hash{2}, state[common.Hash{1}])
+}
+
+// TestGolden pins the decoded state diffs of a create, an extend, an alias registration,
+// a create with an idempotency key and a delete. If the layout of the processor
+// changes, review the diff of the golden file and overwrite it with -write-golden.
+func TestGolden(t *testing.T) {
+	state := memoryState{}
+	diffs := map[string][]statediff.DecodedSlot{}
+
+	logs, diff := execute(t, state, 10, &storagetx.ArkivTransaction{
+		Create: []storagetx.ArkivCreate{{BTL: 100, ContentType: "text/plain", Payload: []byte("hello")}},
+	})
+	diffs["create"] = diff
+	key := logs[0].Topics[1]
+
+	_, diffs["extend"] = execute(t, state, 11, &storagetx.ArkivTransaction{
+		Extend: []storagetx.ExtendBTL{{EntityKey: key, NumberOfBlocks: 50}},
+	})
+	_, diffs["registerAlias"] = execute(t, state, 11, &storagetx.ArkivTransaction{
+		RegisterAlias: []storagetx.ArkivRegisterAlias{{Name: "hello", EntityKey: key, BTL: 30}},
+	})
+	_, diffs["createIdempotent"] = execute(t, state, 11, &storagetx.ArkivTransaction{
+		Create: []storagetx.ArkivCreate{{BTL: 100, ContentType: "text/plain", Payload: []byte("retried"), IdempotencyKey: &common.Hash{1}}},
+	})
+	_, diffs["delete"] = execute(t, state, 12, &storagetx.ArkivTransaction{
+		Delete: []common.Hash{key},
+	})
+
+	for name, diff := range diffs {
+		require.NotEmpty(t, diff, name)
+		for _, slot := range diff {
+			require.NotEqual(t, statediff.KindUnknown, slot.Kind, "%s: slot %s", name, slot.Slot.Slot)
+		}
+	}
+
+	got, err := json.MarshalIndent(diffs, "", "  ")
+	require.NoError(t, err)
+	got = append(got, '\n')
+
+	if *writeGoldenFlag {
+		require.NoError(t, os.MkdirAll("testdata", 0o755))
+		require.NoError(t, os.WriteFile(goldenFile, got, 0o644))
+		return
+	}
+
+	want, err := os.ReadFile(goldenFile)
+	require.NoError(t, err)
+	require.Equal(t, string(want), string(got), "state diffs changed, review the change and run the test with -write-golden")
+}
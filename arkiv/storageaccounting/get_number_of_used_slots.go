@@ -3,6 +3,7 @@ package storageaccounting
 import (
 	"github.com/ethereum/go-ethereum/arkiv/address"
 	"github.com/ethereum/go-ethereum/arkiv/storageutil"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/holiman/uint256"
 )
 
@@ -13,3 +14,12 @@ func GetNumberOfUsedSlots(db storageutil.StateAccess) *uint256.Int {
 
 	return counter
 }
+
+// GetNumberOfUsedSlotsByOwner returns the number of slots used by the entities of the
+// owner, counted since the owner slots fork.
+func GetNumberOfUsedSlotsByOwner(db storageutil.StateAccess, owner common.Address) *uint256.Int {
+	counter := uint256.NewInt(0)
+	counter.SetBytes32(db.GetState(address.ArkivProcessorAddress, OwnerUsedSlotsKey(owner)).Bytes())
+
+	return counter
+}
@@ -10,18 +10,36 @@ import (
 
 var UsedSlotsKey = crypto.Keccak256Hash([]byte("arkivUsedSlots"))
 
+// OwnerUsedSlotsSalt is the salt of the slot counting the slots used by the entities of
+// an owner.
+var OwnerUsedSlotsSalt = []byte("arkivOwnerUsedSlots")
+
+// OwnerUsedSlotsKey returns the slot counting the slots used by the entities of the owner.
+func OwnerUsedSlotsKey(owner common.Address) common.Hash {
+	return crypto.Keccak256Hash(OwnerUsedSlotsSalt, owner[:])
+}
+
 type SlotUsageCounter struct {
-	UsedSlots   map[common.Address]*uint256.Int
+	UsedSlots map[common.Address]*uint256.Int
+	// OwnerSlots are the changes of the slots used by the entities of each owner.
+	OwnerSlots  map[common.Address]int64
 	stateAccess storageutil.StateAccess
 }
 
 func NewSlotUsageCounter(stateAccess storageutil.StateAccess) *SlotUsageCounter {
 	return &SlotUsageCounter{
 		UsedSlots:   make(map[common.Address]*uint256.Int),
+		OwnerSlots:  make(map[common.Address]int64),
 		stateAccess: stateAccess,
 	}
 }
 
+// AddOwnerSlots adds delta to the slots used by the entities of the owner. The change
+// is stored by UpdateUsedSlotsForGolemBase.
+func (c *SlotUsageCounter) AddOwnerSlots(owner common.Address, delta int64) {
+	c.OwnerSlots[owner] += delta
+}
+
 func (c *SlotUsageCounter) GetState(address common.Address, key common.Hash) common.Hash {
 	return c.stateAccess.GetState(address, key)
 }
@@ -65,4 +83,24 @@ func (c *SlotUsageCounter) UpdateUsedSlotsForGolemBase() {
 
 	c.stateAccess.SetState(address.ArkivProcessorAddress, UsedSlotsKey, storedSlotsCounter.Bytes32())
 	counter.SetUint64(0)
+
+	// The counters of the owners start from zero at the owner slots fork, the removal
+	// of an entity stored before it doesn't take them below zero
+	for owner, delta := range c.OwnerSlots {
+		if delta == 0 {
+			continue
+		}
+		key := OwnerUsedSlotsKey(owner)
+		stored := new(uint256.Int).SetBytes32(c.stateAccess.GetState(address.ArkivProcessorAddress, key).Bytes())
+		switch {
+		case delta > 0:
+			stored.AddUint64(stored, uint64(delta))
+		case stored.CmpUint64(uint64(-delta)) < 0:
+			stored.Clear()
+		default:
+			stored.SubUint64(stored, uint64(-delta))
+		}
+		c.stateAccess.SetState(address.ArkivProcessorAddress, key, stored.Bytes32())
+	}
+	clear(c.OwnerSlots)
 }
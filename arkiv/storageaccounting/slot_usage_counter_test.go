@@ -266,3 +266,25 @@ func TestSlotUsageCounter_SetState_MultipleAddresses(t *testing.T) {
 	require.Equal(t, uint256.NewInt(0), counter.UsedSlots[address1])
 	require.Equal(t, uint256.NewInt(1), counter.UsedSlots[address2])
 }
+
+func TestSlotUsageCounter_OwnerSlots(t *testing.T) {
+	mockAccess := newMockStateAccess()
+	counter := NewSlotUsageCounter(mockAccess)
+
+	owner := common.HexToAddress("0x1234")
+	other := common.HexToAddress("0x5678")
+
+	counter.AddOwnerSlots(owner, 6)
+	counter.AddOwnerSlots(other, 3)
+	counter.AddOwnerSlots(other, -3)
+	counter.UpdateUsedSlotsForGolemBase()
+
+	require.Equal(t, uint256.NewInt(6), GetNumberOfUsedSlotsByOwner(mockAccess, owner))
+	require.True(t, GetNumberOfUsedSlotsByOwner(mockAccess, other).IsZero())
+	require.Empty(t, counter.OwnerSlots)
+
+	// Removing the entities created before the fork doesn't take the count below zero
+	counter.AddOwnerSlots(owner, -9)
+	counter.UpdateUsedSlotsForGolemBase()
+	require.True(t, GetNumberOfUsedSlotsByOwner(mockAccess, owner).IsZero())
+}
@@ -0,0 +1,104 @@
+// Package storagechallenge computes and verifies the responses to the storage
+// challenges of arkiv_storageChallenge, which prove a replica holds the payloads of
+// entities and not only their metadata. The response for an entity is the keccak256
+// hash of the nonce of the challenge followed by the payload: a fresh nonce makes the
+// response impossible to compute without reading the payload.
+package storagechallenge
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// The lengths of the nonces of the challenges, in bytes.
+const (
+	MinNonceLength = 16
+	MaxNonceLength = 64
+	NonceLength    = 32
+)
+
+// The statuses of the entities of a verified challenge.
+const (
+	// StatusOK is the status of an entity whose payload the replica holds.
+	StatusOK = "ok"
+	// StatusMissing is the status of an entity the replica doesn't hold.
+	StatusMissing = "missing"
+	// StatusCorrupted is the status of an entity the replica holds with another
+	// payload.
+	StatusCorrupted = "corrupted"
+	// StatusUnknown is the status of an entity the verifier has no expected response
+	// for.
+	StatusUnknown = "unknown"
+)
+
+// NewNonce returns a random nonce of NonceLength bytes.
+func NewNonce() ([]byte, error) {
+	nonce := make([]byte, NonceLength)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return nonce, nil
+}
+
+// CheckNonce returns an error if the length of the nonce is out of bounds.
+func CheckNonce(nonce []byte) error {
+	if len(nonce) < MinNonceLength || len(nonce) > MaxNonceLength {
+		return fmt.Errorf("nonce of %d bytes, expected %d to %d", len(nonce), MinNonceLength, MaxNonceLength)
+	}
+	return nil
+}
+
+// Response returns the response to the challenge with the nonce for the payload read
+// from r. The payload is streamed through the hash, it's never held in memory.
+func Response(nonce []byte, r io.Reader) (common.Hash, error) {
+	hasher := crypto.NewKeccakState()
+	hasher.Write(nonce)
+	if _, err := io.Copy(hasher, r); err != nil {
+		return common.Hash{}, fmt.Errorf("failed to read payload: %w", err)
+	}
+	var response common.Hash
+	hasher.Read(response[:])
+	return response, nil
+}
+
+// PayloadResponse returns the response to the challenge with the nonce for the payload.
+func PayloadResponse(nonce, payload []byte) common.Hash {
+	return crypto.Keccak256Hash(nonce, payload)
+}
+
+// Result is the status of an entity of a verified challenge.
+type Result struct {
+	Key    common.Hash `json:"key"`
+	Status string      `json:"status"`
+}
+
+// Verify checks the responses of a replica against the expected ones, both in the order
+// of the keys of the challenge. A nil response is an entity the replica doesn't hold, a
+// nil expected response one the verifier can't check. Verifiers holding the payloads
+// compute the expected responses with Response. Verifiers holding only the content
+// hashes of the payloads can't, since the response is bound to the nonce: they take
+// the responses of a node whose payloads they checked against the hashes instead.
+func Verify(keys []common.Hash, expected, responses []*common.Hash) ([]Result, error) {
+	if len(expected) != len(keys) || len(responses) != len(keys) {
+		return nil, fmt.Errorf("%d keys with %d expected responses and %d responses", len(keys), len(expected), len(responses))
+	}
+	results := make([]Result, len(keys))
+	for i, key := range keys {
+		results[i].Key = key
+		switch {
+		case expected[i] == nil:
+			results[i].Status = StatusUnknown
+		case responses[i] == nil:
+			results[i].Status = StatusMissing
+		case *responses[i] != *expected[i]:
+			results[i].Status = StatusCorrupted
+		default:
+			results[i].Status = StatusOK
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,61 @@
+package storagechallenge
+
+import (
+	"bytes"
+	"testing"
+	"testing/iotest"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponse(t *testing.T) {
+	nonce := bytes.Repeat([]byte{1}, NonceLength)
+	payload := bytes.Repeat([]byte("hello arkiv "), 10_000)
+
+	// Streaming the payload in small reads hashes the same bytes
+	response, err := Response(nonce, iotest.OneByteReader(bytes.NewReader(payload)))
+	require.NoError(t, err)
+	require.Equal(t, PayloadResponse(nonce, payload), response)
+
+	// The response is bound to the nonce
+	require.NotEqual(t, response, PayloadResponse(bytes.Repeat([]byte{2}, NonceLength), payload))
+
+	_, err = Response(nonce, iotest.ErrReader(iotest.ErrTimeout))
+	require.ErrorIs(t, err, iotest.ErrTimeout)
+}
+
+func TestCheckNonce(t *testing.T) {
+	nonce, err := NewNonce()
+	require.NoError(t, err)
+	require.Len(t, nonce, NonceLength)
+	require.NoError(t, CheckNonce(nonce))
+	require.NoError(t, CheckNonce(make([]byte, MinNonceLength)))
+	require.Error(t, CheckNonce(make([]byte, MinNonceLength-1)))
+	require.Error(t, CheckNonce(make([]byte, MaxNonceLength+1)))
+}
+
+func TestVerify(t *testing.T) {
+	nonce, err := NewNonce()
+	require.NoError(t, err)
+	keys := []common.Hash{{1}, {2}, {3}, {4}}
+	payload := func(s string) *common.Hash {
+		response := PayloadResponse(nonce, []byte(s))
+		return &response
+	}
+
+	results, err := Verify(keys,
+		[]*common.Hash{payload("a"), payload("b"), payload("c"), nil},
+		[]*common.Hash{payload("a"), nil, payload("corrupted"), payload("d")},
+	)
+	require.NoError(t, err)
+	require.Equal(t, []Result{
+		{Key: keys[0], Status: StatusOK},
+		{Key: keys[1], Status: StatusMissing},
+		{Key: keys[2], Status: StatusCorrupted},
+		{Key: keys[3], Status: StatusUnknown},
+	}, results)
+
+	_, err = Verify(keys, make([]*common.Hash, 4), make([]*common.Hash, 3))
+	require.Error(t, err)
+}
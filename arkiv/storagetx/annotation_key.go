@@ -0,0 +1,103 @@
+package storagetx
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity"
+)
+
+const (
+	// AnnotationKeyPrefixAttributePrefix prefixes the synthetic string attributes that
+	// index the prefixes of hierarchical annotation keys, so that the entities with a
+	// key under a prefix are found with a single bitmap lookup.
+	AnnotationKeyPrefixAttributePrefix = "$prefix_"
+
+	// AnnotationKeyPrefixAttributeValue is the value of the prefix attributes.
+	AnnotationKeyPrefixAttributeValue = "1"
+)
+
+// validateAnnotationKey checks an annotation key of the transaction. Transactions of a
+// version before TransactionVersionDottedKeys can only carry single identifiers.
+func (tx *ArkivTransaction) validateAnnotationKey(key string) error {
+	if key == "" {
+		return fmt.Errorf("annotation key is empty")
+	}
+	if tx.Version >= TransactionVersionDottedKeys {
+		return entity.ValidateAnnotationKey(key)
+	}
+	if !entity.AnnotationIdentRegexCompiled.MatchString(key) {
+		return fmt.Errorf("invalid annotation identifier (must match `%s`): %s",
+			entity.AnnotationIdentRegexCompiled.String(),
+			key,
+		)
+	}
+	return nil
+}
+
+// validateFlatKeys rejects the dotted annotation keys if the limits only accept flat
+// ones, the rule before the dotted keys fork. The format of the keys is checked by
+// Validate.
+func (tx *ArkivTransaction) validateFlatKeys(unpackLimits UnpackLimits) error {
+	if !unpackLimits.FlatKeys {
+		return nil
+	}
+
+	check := func(op string, i int, stringAnnotations []StringAnnotation, numericAnnotations []NumericAnnotation) error {
+		for _, annotation := range stringAnnotations {
+			if strings.Contains(annotation.Key, ".") {
+				return fmt.Errorf("%s[%d] string annotation key %q: dotted annotation keys are not active", op, i, annotation.Key)
+			}
+		}
+		for _, annotation := range numericAnnotations {
+			if strings.Contains(annotation.Key, ".") {
+				return fmt.Errorf("%s[%d] numeric annotation key %q: dotted annotation keys are not active", op, i, annotation.Key)
+			}
+		}
+		return nil
+	}
+
+	for i, create := range tx.Create {
+		err := check("create", i, create.StringAnnotations, create.NumericAnnotations)
+		if err != nil {
+			return err
+		}
+	}
+
+	for i, update := range tx.Update {
+		err := check("update", i, update.StringAnnotations, update.NumericAnnotations)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateAnnotationKeys checks the annotation keys of the operation, named like
+// create[0] in the errors. The keys must be valid, which keeps them out of the
+// namespace of the meta-annotations such as $owner, and unique among the string and
+// among the numeric annotations of the operation.
+func (tx *ArkivTransaction) validateAnnotationKeys(op string, stringAnnotations []StringAnnotation, numericAnnotations []NumericAnnotation) error {
+	seen := make(map[string]bool, len(stringAnnotations))
+	for _, annotation := range stringAnnotations {
+		if err := tx.validateAnnotationKey(annotation.Key); err != nil {
+			return fmt.Errorf("%s string annotation key %q: %w", op, annotation.Key, err)
+		}
+		if seen[annotation.Key] {
+			return fmt.Errorf("%s string annotation key %s is duplicated", op, annotation.Key)
+		}
+		seen[annotation.Key] = true
+	}
+	seen = make(map[string]bool, len(numericAnnotations))
+	for _, annotation := range numericAnnotations {
+		if err := tx.validateAnnotationKey(annotation.Key); err != nil {
+			return fmt.Errorf("%s numeric annotation key %q: %w", op, annotation.Key, err)
+		}
+		if seen[annotation.Key] {
+			return fmt.Errorf("%s numeric annotation key %s is duplicated", op, annotation.Key)
+		}
+		seen[annotation.Key] = true
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package storagetx
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+func createWithAnnotationKey(version uint64, key string) *ArkivTransaction {
+	return &ArkivTransaction{
+		Create: []ArkivCreate{
+			{
+				BTL:                100,
+				ContentType:        "text/plain",
+				Payload:            []byte("payload"),
+				StringAnnotations:  []StringAnnotation{{Key: key, Value: "eu"}},
+				NumericAnnotations: []NumericAnnotation{{Key: key, Value: 1}},
+			},
+		},
+		Version: version,
+	}
+}
+
+func TestValidate_DottedAnnotationKeys(t *testing.T) {
+	tx := createWithAnnotationKey(TransactionVersionDottedKeys, "invoice.customer.region")
+	require.NoError(t, tx.Validate())
+
+	// Dotted keys pass through unchanged
+	unpacked, err := UnpackArkivTransaction(packTransaction(t, tx))
+	require.NoError(t, err)
+	require.Equal(t, "invoice.customer.region", unpacked.Create[0].StringAnnotations[0].Key)
+
+	// Earlier versions only carry single identifiers
+	require.ErrorContains(t, createWithAnnotationKey(TransactionVersionOwnership, "invoice.customer").Validate(), "invalid annotation identifier")
+	require.NoError(t, createWithAnnotationKey(TransactionVersionOwnership, "invoice").Validate())
+}
+
+func TestUnpack_DottedAnnotationKeysRequireFork(t *testing.T) {
+	flat := UnpackLimitsAt(&params.ChainConfig{ArkivTypedNumericsTime: new(uint64)}, 0)
+
+	_, err := UnpackArkivTransactionWithLimits(packTransaction(t, createWithAnnotationKey(TransactionVersionDottedKeys, "invoice.customer")), flat)
+	require.ErrorContains(t, err, `create[0] string annotation key "invoice.customer": dotted annotation keys are not active`)
+
+	_, err = UnpackArkivTransactionWithLimits(packTransaction(t, createWithAnnotationKey(TransactionVersionDottedKeys, "invoice")), flat)
+	require.NoError(t, err)
+}
+
+func TestValidate_AnnotationKeyMaxDepth(t *testing.T) {
+	segments := make([]string, entity.MaxAnnotationKeyDepth)
+	for i := range segments {
+		segments[i] = "a"
+	}
+	require.NoError(t, createWithAnnotationKey(TransactionVersionDottedKeys, strings.Join(segments, ".")).Validate())
+
+	segments = append(segments, "a")
+	require.ErrorContains(t, createWithAnnotationKey(TransactionVersionDottedKeys, strings.Join(segments, ".")).Validate(), "segments")
+}
+
+func TestValidate_InvalidAnnotationKeySegments(t *testing.T) {
+	for _, key := range []string{
+		"invoice.",
+		".invoice",
+		"invoice..customer",
+		"invoice.$owner",
+		"$owner.invoice",
+		"invoice.1st",
+		"invoice.customer-region",
+	} {
+		require.ErrorContains(t, createWithAnnotationKey(TransactionVersionDottedKeys, key).Validate(), "invalid annotation key segment", key)
+	}
+}
+
+func TestValidate_ReservedAnnotationKeys(t *testing.T) {
+	// The keys of the meta-annotations and hex strings can't be set by operations
+	for _, key := range []string{"$owner", "$key", "0x1234", ""} {
+		require.ErrorContains(t, createWithAnnotationKey(TransactionVersionDottedKeys, key).Validate(), fmt.Sprintf("create[0] string annotation key %q", key))
+		require.ErrorContains(t, createWithAnnotationKey(TransactionVersionOwnership, key).Validate(), fmt.Sprintf("create[0] string annotation key %q", key))
+	}
+	require.ErrorContains(t, createWithAnnotationKey(TransactionVersionDottedKeys, "").Validate(), "annotation key is empty")
+}
+
+func TestValidate_AnnotationKeysOfOperation(t *testing.T) {
+	update := func(stringKeys []string, numericKeys []string) *ArkivTransaction {
+		tx := &ArkivTransaction{
+			Version: TransactionVersionDottedKeys,
+			Update: []ArkivUpdate{
+				{EntityKey: common.HexToHash("0x1"), BTL: 100, ContentType: "text/plain"},
+				{EntityKey: common.HexToHash("0x2"), BTL: 100, ContentType: "text/plain"},
+			},
+		}
+		for _, key := range stringKeys {
+			tx.Update[1].StringAnnotations = append(tx.Update[1].StringAnnotations, StringAnnotation{Key: key, Value: "v"})
+		}
+		for _, key := range numericKeys {
+			tx.Update[1].NumericAnnotations = append(tx.Update[1].NumericAnnotations, NumericAnnotation{Key: key, Value: 1})
+		}
+		return tx
+	}
+
+	// A key can be both a string and a numeric annotation
+	require.NoError(t, update([]string{"region"}, []string{"region"}).Validate())
+
+	require.EqualError(t, update([]string{"region", "region"}, nil).Validate(), "update[1] string annotation key region is duplicated")
+	require.EqualError(t, update(nil, []string{"total", "total"}).Validate(), "update[1] numeric annotation key total is duplicated")
+	require.ErrorContains(t, update(nil, []string{"$expiration"}).Validate(), `update[1] numeric annotation key "$expiration"`)
+}
@@ -2,19 +2,20 @@ package storagetx
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
-	"math/big"
 
 	"github.com/andybalholm/brotli"
 	"github.com/ethereum/go-ethereum/arkiv/address"
+	"github.com/ethereum/go-ethereum/arkiv/compression"
+	"github.com/ethereum/go-ethereum/arkiv/limits"
 	arkivlogs "github.com/ethereum/go-ethereum/arkiv/logs"
 	"github.com/ethereum/go-ethereum/arkiv/storageaccounting"
 	"github.com/ethereum/go-ethereum/arkiv/storageutil"
 	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/holiman/uint256"
@@ -29,30 +30,107 @@ import (
 //   - Create: adds new entities to the storage layer. Each entity has a BTL (number of blocks), a payload and a list of annotations. The Key of the entity is derived from the payload content, the transaction hash where the entity was created and the index of the create operation in the transaction.
 //   - Update: updates existing entities. Each entity has a key, a BTL (number of blocks), a payload and a list of annotations. If the entity does not exist, the operation fails, failing the whole transaction.
 //   - Delete: removes entities from the storage layer. If the entity does not exist, the operation fails, failing back the whole transaction.
+//   - ChangeOwner: proposes to transfer entities to a new owner. Once two-step transfers are active the new owner has to accept the transfer with AcceptOwnership, unless the operation is Immediate.
+//   - AcceptOwnership: makes the sender the owner of entities whose transfer to the sender is pending.
+//   - RegisterAlias: points a name to an entity for a number of blocks. A free name is registered to the sender, the owner of a name can repoint and renew it.
+//   - TransferAlias: gives a name to a new owner.
+//   - ReduceBTL: brings the expiry of entities of the sender forward, so that they expire early.
+//   - ChangeOwnerBatch: transfers several entities to the same new owner, like one ChangeOwner per entity. The sender must own all of them.
 //
 // The transaction is atomic, meaning that all operations are applied or none are.
+// Conditions guard creates, updates and extensions: they're checked right before their
+// operation, against the state left by the operations run before it, and the whole
+// transaction fails if one doesn't hold, see ArkivCondition.
 //
 // Annotations are key-value pairs where the key is a string and the value is either a string or a number.
 // The key-value pairs are used to build indexes and to query the storage layer.
 // Same key can have both string and numeric annotation, but not multiple values of the same type.
+//
+// Version selects the encoding of the transaction, see CurrentTransactionVersion.
 type ArkivTransaction struct {
 	Create      []ArkivCreate      `json:"create"`
 	Update      []ArkivUpdate      `json:"update"`
 	Delete      []common.Hash      `json:"delete"`
 	Extend      []ExtendBTL        `json:"extend"`
 	ChangeOwner []ArkivChangeOwner `json:"changeOwner"`
+	Version     uint64             `json:"version" rlp:"optional"`
+
+	AcceptOwnership []common.Hash        `json:"acceptOwnership" rlp:"optional"`
+	RegisterAlias   []ArkivRegisterAlias `json:"registerAlias" rlp:"optional"`
+	TransferAlias   []ArkivTransferAlias `json:"transferAlias" rlp:"optional"`
+	ReduceBTL       []ArkivReduceBTL     `json:"reduceBTL" rlp:"optional"`
+
+	ChangeOwnerBatch []ArkivChangeOwnerBatch `json:"changeOwnerBatch" rlp:"optional"`
+
+	ExternalContent []ArkivExternalContent `json:"externalContent" rlp:"optional"`
+
+	Conditions []ArkivCondition `json:"conditions" rlp:"optional"`
 }
 
+const (
+	// TransactionVersionTypedNumerics is the first transaction version that can carry
+	// numeric annotations with a type other than NumericTypeUint64.
+	TransactionVersionTypedNumerics = 1
+
+	// TransactionVersionEncryption is the first transaction version that can carry
+	// encryption metadata on create and update operations.
+	TransactionVersionEncryption = 2
+
+	// TransactionVersionOwnership is the first transaction version that can carry
+	// immediate ownership changes and ownership acceptances.
+	TransactionVersionOwnership = 3
+
+	// TransactionVersionDottedKeys is the first transaction version that can carry
+	// hierarchical annotation keys, see entity.ValidateAnnotationKey.
+	TransactionVersionDottedKeys = 4
+
+	// TransactionVersionAliases is the first transaction version that can carry alias
+	// registrations and transfers.
+	TransactionVersionAliases = 5
+
+	// TransactionVersionIdempotency is the first transaction version that can carry
+	// idempotency keys on create operations.
+	TransactionVersionIdempotency = 6
+
+	// TransactionVersionReduceBTL is the first transaction version that can carry BTL
+	// reductions.
+	TransactionVersionReduceBTL = 7
+
+	// TransactionVersionChangeOwnerBatch is the first transaction version that can
+	// carry batched ownership changes.
+	TransactionVersionChangeOwnerBatch = 8
+
+	// TransactionVersionExternalContent is the first transaction version that can carry
+	// the commitments to payloads stored off chain.
+	TransactionVersionExternalContent = 9
+
+	// TransactionVersionConditions is the first transaction version that can carry
+	// conditions on its operations.
+	TransactionVersionConditions = 10
+
+	// CurrentTransactionVersion is the latest supported transaction version.
+	CurrentTransactionVersion = TransactionVersionConditions
+)
+
 type ExtendBTL struct {
 	EntityKey      common.Hash `json:"entityKey"`
 	NumberOfBlocks uint64      `json:"numberOfBlocks"`
 }
 
+// numberOfOperations returns the number of operations of the transaction, every entity
+// of a batch counts as an operation.
+func (tx *ArkivTransaction) numberOfOperations() int {
+	n := len(tx.Create) + len(tx.Update) + len(tx.Delete) + len(tx.Extend) + len(tx.ChangeOwner) + len(tx.AcceptOwnership) + len(tx.RegisterAlias) + len(tx.TransferAlias) + len(tx.ReduceBTL)
+	for _, batch := range tx.ChangeOwnerBatch {
+		n += len(batch.EntityKeys)
+	}
+	return n
+}
+
 func (tx *ArkivTransaction) Validate() error {
 
-	numberOfOperations := len(tx.Create) + len(tx.Update) + len(tx.Delete) + len(tx.Extend) + len(tx.ChangeOwner)
-	if numberOfOperations > 1000 {
-		return fmt.Errorf("number of operations is greater than 1000")
+	if tx.numberOfOperations() > limits.MaxOperations {
+		return fmt.Errorf("number of operations is greater than %d", limits.MaxOperations)
 	}
 
 	for i, create := range tx.Create {
@@ -60,43 +138,17 @@ func (tx *ArkivTransaction) Validate() error {
 			return fmt.Errorf("create BTL is 0")
 		}
 
-		seenStringAnnotations := make(map[string]bool)
-		seenNumericAnnotations := make(map[string]bool)
-
 		if create.ContentType == "" {
 			return fmt.Errorf("create[%d] contentType is empty", i)
 		}
 
-		if len(create.ContentType) > 128 {
+		if len(create.ContentType) > limits.MaxContentTypeLength {
 			return fmt.Errorf("create[%d] contentType is too long", i)
 		}
 
 		// Validate the annotation identifiers
-		for _, annotation := range create.StringAnnotations {
-			if !entity.AnnotationIdentRegexCompiled.MatchString(annotation.Key) {
-				return fmt.Errorf("invalid annotation identifier (must match `%s`): %s",
-					entity.AnnotationIdentRegexCompiled.String(),
-					annotation.Key,
-				)
-			}
-			if seenStringAnnotations[annotation.Key] {
-				return fmt.Errorf("create[%d] string annotation key %s is duplicated", i, annotation.Key)
-			}
-
-			seenStringAnnotations[annotation.Key] = true
-
-		}
-		for _, annotation := range create.NumericAnnotations {
-			if !entity.AnnotationIdentRegexCompiled.MatchString(annotation.Key) {
-				return fmt.Errorf("invalid annotation identifier (must match `%s`): %s",
-					entity.AnnotationIdentRegexCompiled.String(),
-					annotation.Key,
-				)
-			}
-			if seenNumericAnnotations[annotation.Key] {
-				return fmt.Errorf("create[%d] numeric annotation key %s is duplicated", i, annotation.Key)
-			}
-			seenNumericAnnotations[annotation.Key] = true
+		if err := tx.validateAnnotationKeys(fmt.Sprintf("create[%d]", i), create.StringAnnotations, create.NumericAnnotations); err != nil {
+			return err
 		}
 
 	}
@@ -110,36 +162,12 @@ func (tx *ArkivTransaction) Validate() error {
 			return fmt.Errorf("update[%d] contentType is empty", i)
 		}
 
-		if len(update.ContentType) > 128 {
+		if len(update.ContentType) > limits.MaxContentTypeLength {
 			return fmt.Errorf("update[%d] contentType is too long", i)
 		}
 
-		seenStringAnnotations := make(map[string]bool)
-		seenNumericAnnotations := make(map[string]bool)
-
-		for _, annotation := range update.StringAnnotations {
-			if !entity.AnnotationIdentRegexCompiled.MatchString(annotation.Key) {
-				return fmt.Errorf("invalid annotation identifier (must match `%s`): %s",
-					entity.AnnotationIdentRegexCompiled.String(),
-					annotation.Key,
-				)
-			}
-			if seenStringAnnotations[annotation.Key] {
-				return fmt.Errorf("update[%d] string annotation key %s is duplicated", i, annotation.Key)
-			}
-			seenStringAnnotations[annotation.Key] = true
-		}
-		for _, annotation := range update.NumericAnnotations {
-			if !entity.AnnotationIdentRegexCompiled.MatchString(annotation.Key) {
-				return fmt.Errorf("invalid annotation identifier (must match `%s`): %s",
-					entity.AnnotationIdentRegexCompiled.String(),
-					annotation.Key,
-				)
-			}
-			if seenNumericAnnotations[annotation.Key] {
-				return fmt.Errorf("update[%d] numeric annotation key %s is duplicated", i, annotation.Key)
-			}
-			seenNumericAnnotations[annotation.Key] = true
+		if err := tx.validateAnnotationKeys(fmt.Sprintf("update[%d]", i), update.StringAnnotations, update.NumericAnnotations); err != nil {
+			return err
 		}
 
 	}
@@ -150,18 +178,60 @@ func (tx *ArkivTransaction) Validate() error {
 		}
 	}
 
+	for i, register := range tx.RegisterAlias {
+		if register.BTL == 0 {
+			return fmt.Errorf("registerAlias[%d] BTL is 0", i)
+		}
+		if err := entity.ValidateAliasName(register.Name); err != nil {
+			return fmt.Errorf("registerAlias[%d]: %w", i, err)
+		}
+	}
+
+	for i, transfer := range tx.TransferAlias {
+		if err := entity.ValidateAliasName(transfer.Name); err != nil {
+			return fmt.Errorf("transferAlias[%d]: %w", i, err)
+		}
+	}
+
+	for i, reduce := range tx.ReduceBTL {
+		if reduce.NumberOfBlocks == 0 {
+			return fmt.Errorf("reduceBTL[%d] number of blocks is 0", i)
+		}
+	}
+
+	for i, batch := range tx.ChangeOwnerBatch {
+		if len(batch.EntityKeys) == 0 {
+			return fmt.Errorf("changeOwnerBatch[%d] has no entity keys", i)
+		}
+		seen := make(map[common.Hash]bool, len(batch.EntityKeys))
+		for _, key := range batch.EntityKeys {
+			if seen[key] {
+				return fmt.Errorf("changeOwnerBatch[%d] repeats entity %s", i, key.Hex())
+			}
+			seen[key] = true
+		}
+	}
+
 	return nil
 
 }
 
+// ArkivCreate creates an entity. A create carrying the IdempotencyKey of an entity its
+// sender created within the idempotency TTL is skipped, so that retrying a submission
+// doesn't create the entity twice. Encryption is decoded as nil from an empty list, so
+// that IdempotencyKey can follow a create without encryption.
 type ArkivCreate struct {
 	BTL                uint64              `json:"btl"`
 	ContentType        string              `json:"contentType"`
 	Payload            []byte              `json:"payload"`
 	StringAnnotations  []StringAnnotation  `json:"stringAnnotations"`
 	NumericAnnotations []NumericAnnotation `json:"numericAnnotations"`
+	Encryption         *EncryptionInfo     `json:"encryption,omitempty" rlp:"optional,nil"`
+	IdempotencyKey     *common.Hash        `json:"idempotencyKey,omitempty" rlp:"optional"`
 }
 
+// ArkivUpdate replaces the content, the annotations and the BTL of an entity.
+// Encryption is decoded as nil from an empty list.
 type ArkivUpdate struct {
 	EntityKey          common.Hash         `json:"entityKey"`
 	ContentType        string              `json:"contentType"`
@@ -169,6 +239,7 @@ type ArkivUpdate struct {
 	Payload            []byte              `json:"payload"`
 	StringAnnotations  []StringAnnotation  `json:"stringAnnotations"`
 	NumericAnnotations []NumericAnnotation `json:"numericAnnotations"`
+	Encryption         *EncryptionInfo     `json:"encryption,omitempty" rlp:"optional,nil"`
 }
 
 type StringAnnotation struct {
@@ -176,14 +247,54 @@ type StringAnnotation struct {
 	Value string `json:"value"`
 }
 
+// NumericAnnotation is a numeric key-value pair. Type selects how Value is interpreted,
+// see NumericType. Decimals is only used by fixed-point annotations.
 type NumericAnnotation struct {
-	Key   string `json:"key"`
-	Value uint64 `json:"value"`
+	Key      string      `json:"key"`
+	Value    uint64      `json:"value"`
+	Type     NumericType `json:"type" rlp:"optional"`
+	Decimals uint8       `json:"decimals" rlp:"optional"`
 }
 
+// ArkivChangeOwner transfers an entity to NewOwner. Once two-step transfers are active
+// the transfer is pending until NewOwner accepts it, Immediate keeps the legacy
+// behaviour of changing the owner right away.
 type ArkivChangeOwner struct {
 	EntityKey common.Hash    `json:"entityKey"`
 	NewOwner  common.Address `json:"newOwner"`
+	Immediate bool           `json:"immediate" rlp:"optional"`
+}
+
+// ArkivRegisterAlias points the alias Name to the entity EntityKey for BTL blocks. A
+// free name is registered to the sender, a name registered to the sender is repointed
+// and expires BTL blocks after the registration instead.
+type ArkivRegisterAlias struct {
+	Name      string      `json:"name"`
+	EntityKey common.Hash `json:"entityKey"`
+	BTL       uint64      `json:"btl"`
+}
+
+// ArkivTransferAlias gives the alias Name to NewOwner, keeping its entity and expiry.
+type ArkivTransferAlias struct {
+	Name     string         `json:"name"`
+	NewOwner common.Address `json:"newOwner"`
+}
+
+// ArkivReduceBTL brings the expiry of the entity EntityKey forward by NumberOfBlocks
+// blocks. Only the owner can reduce the BTL of an entity, and the entity must still
+// expire after the block of the transaction.
+type ArkivReduceBTL struct {
+	EntityKey      common.Hash `json:"entityKey"`
+	NumberOfBlocks uint64      `json:"numberOfBlocks"`
+}
+
+// ArkivChangeOwnerBatch transfers the entities EntityKeys to NewOwner, each like an
+// ArkivChangeOwner. The sender must own every entity of the batch, which is checked
+// before any of them is transferred.
+type ArkivChangeOwnerBatch struct {
+	EntityKeys []common.Hash  `json:"entityKeys"`
+	NewOwner   common.Address `json:"newOwner"`
+	Immediate  bool           `json:"immediate" rlp:"optional"`
 }
 
 func addressToHash(a common.Address) common.Hash {
@@ -192,7 +303,9 @@ func addressToHash(a common.Address) common.Hash {
 	return h
 }
 
-func (tx *ArkivTransaction) Run(blockNumber uint64, txHash common.Hash, txIx int, sender common.Address, access storageutil.StateAccess) (_ []*types.Log, err error) {
+// Run applies the operations of the transaction to the state with the rules of the
+// chain, see Rules.
+func (tx *ArkivTransaction) Run(blockNumber uint64, txHash common.Hash, txIx int, sender common.Address, rules Rules, access storageutil.StateAccess) (_ []*types.Log, err error) {
 
 	defer func() {
 		if err != nil {
@@ -209,7 +322,7 @@ func (tx *ArkivTransaction) Run(blockNumber uint64, txHash common.Hash, txIx int
 
 	storeEntity := func(key common.Hash, ap *entity.EntityMetaData, payload []byte, emitLogs bool) error {
 
-		err := entity.Store(access, key, sender, *ap, payload)
+		err := entity.Store(access, key, sender, *ap, payload, rules.ContentHash)
 		if err != nil {
 			return fmt.Errorf("failed to store entity: %w", err)
 		}
@@ -247,23 +360,42 @@ func (tx *ArkivTransaction) Run(blockNumber uint64, txHash common.Hash, txIx int
 
 	for opIx, create := range tx.Create {
 
-		// Convert i to a big integer and pad to 32 bytes
-		bigI := big.NewInt(int64(opIx))
-		paddedI := common.LeftPadBytes(bigI.Bytes(), 32)
+		key := entity.DeriveEntityKey(txHash, opIx, create.Payload)
+
+		if err := tx.checkConditions(access, ConditionOperationCreate, opIx, key); err != nil {
+			return nil, fmt.Errorf("failed to create entity %s: %w", key.Hex(), err)
+		}
+		if create.IdempotencyKey != nil && rules.IdempotencyTTL == 0 {
+			return nil, fmt.Errorf("failed to create entity %s: idempotency keys are not active", key.Hex())
+		}
+		if deduplicated := deduplicatedCreate(access, blockNumber, sender, create); deduplicated != nil {
+			logs = append(logs, deduplicated)
+			continue
+		}
 
-		key := crypto.Keccak256Hash(txHash.Bytes(), create.Payload, paddedI)
+		expiresAtBlock, err := entity.ExpiresAt(blockNumber, blockNumber, create.BTL, rules.MaxBTL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create entity %s: %w", key.Hex(), err)
+		}
 
 		ap := &entity.EntityMetaData{
 			Owner:          sender,
-			ExpiresAtBlock: blockNumber + create.BTL,
+			ExpiresAtBlock: expiresAtBlock,
 		}
 
-		err := storeEntity(key, ap, create.Payload, true)
+		err = storeEntity(key, ap, create.Payload, true)
 
 		if err != nil {
 			return nil, err
 		}
 
+		if create.IdempotencyKey != nil {
+			err = entity.StoreIdempotencyKey(access, entity.IdempotencyHash(sender, *create.IdempotencyKey), key, blockNumber+rules.IdempotencyTTL)
+			if err != nil {
+				return nil, fmt.Errorf("failed to store the idempotency key of entity %s: %w", key.Hex(), err)
+			}
+		}
+
 	}
 
 	deleteEntity := func(toDelete common.Hash, emitLogs bool) error {
@@ -273,6 +405,28 @@ func (tx *ArkivTransaction) Run(blockNumber uint64, txHash common.Hash, txIx int
 			return fmt.Errorf("failed to delete entity: %w", err)
 		}
 
+		// Updates delete the previous version of the entity, only actual deletions
+		// leave a tombstone.
+		// A removed entity can't be accepted anymore, updates keep the pending owner.
+		if emitLogs && rules.TransferWindow > 0 {
+			err = entity.DeletePendingOwner(access, toDelete)
+			if err != nil {
+				return fmt.Errorf("failed to delete pending owner: %w", err)
+			}
+		}
+
+		if emitLogs && rules.TombstoneRetention > 0 {
+			err = entity.StoreTombstone(
+				access,
+				toDelete,
+				entity.Tombstone{Reason: entity.TombstoneDeleted, Block: blockNumber},
+				blockNumber+rules.TombstoneRetention,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to store tombstone: %w", err)
+			}
+		}
+
 		if emitLogs {
 
 			// create the log for the created entity
@@ -312,7 +466,11 @@ func (tx *ArkivTransaction) Run(blockNumber uint64, txHash common.Hash, txIx int
 		}
 	}
 
-	for _, update := range tx.Update {
+	for opIx, update := range tx.Update {
+
+		if err := tx.checkConditions(access, ConditionOperationUpdate, opIx, update.EntityKey); err != nil {
+			return nil, fmt.Errorf("failed to update entity %s: %w", update.EntityKey.Hex(), err)
+		}
 
 		oldMetaData, err := entity.GetEntityMetaData(access, update.EntityKey)
 		if err != nil {
@@ -323,6 +481,11 @@ func (tx *ArkivTransaction) Run(blockNumber uint64, txHash common.Hash, txIx int
 			return nil, fmt.Errorf("failed to update entity %s: %s is not the owner", update.EntityKey.Hex(), sender.Hex())
 		}
 
+		expiresAtBlock, err := entity.ExpiresAt(blockNumber, blockNumber, update.BTL, rules.MaxBTL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update entity %s: %w", update.EntityKey.Hex(), err)
+		}
+
 		err = deleteEntity(update.EntityKey, false)
 		if err != nil {
 			return nil, err
@@ -330,7 +493,7 @@ func (tx *ArkivTransaction) Run(blockNumber uint64, txHash common.Hash, txIx int
 
 		ap := &entity.EntityMetaData{
 			Owner:          oldMetaData.Owner,
-			ExpiresAtBlock: blockNumber + update.BTL,
+			ExpiresAtBlock: expiresAtBlock,
 		}
 
 		err = storeEntity(update.EntityKey, ap, update.Payload, false)
@@ -366,8 +529,17 @@ func (tx *ArkivTransaction) Run(blockNumber uint64, txHash common.Hash, txIx int
 
 	}
 
-	for _, extend := range tx.Extend {
-		oldExpiresAtBlock, owner, err := entity.ExtendBTL(access, extend.EntityKey, extend.NumberOfBlocks)
+	for opIx, extend := range tx.Extend {
+		if err := tx.checkConditions(access, ConditionOperationExtend, opIx, extend.EntityKey); err != nil {
+			return nil, fmt.Errorf("failed to extend BTL of entity %s: %w", extend.EntityKey.Hex(), err)
+		}
+
+		oldExpiresAtBlock, owner, err := entity.ExtendBTL(access, extend.EntityKey, extend.NumberOfBlocks, blockNumber, rules.MaxBTL)
+		if errors.Is(err, entity.ErrEntityNotFound) {
+			if expired := expiredEntityError(access, extend.EntityKey, blockNumber); expired != nil {
+				err = expired
+			}
+		}
 		if err != nil {
 			return nil, fmt.Errorf("failed to extend BTL of entity %s: %w", extend.EntityKey.Hex(), err)
 		}
@@ -398,22 +570,154 @@ func (tx *ArkivTransaction) Run(blockNumber uint64, txHash common.Hash, txIx int
 		)
 	}
 
-	for _, changeOwner := range tx.ChangeOwner {
-		md, err := entity.GetEntityMetaData(access, changeOwner.EntityKey)
+	for _, change := range tx.ChangeOwner {
+		changeLog, err := changeOwner(access, sender, change.EntityKey, change.NewOwner, change.Immediate, blockNumber, rules.TransferWindow)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get entity meta data for change owner %s: %w", changeOwner.EntityKey.Hex(), err)
+			return nil, err
 		}
+		logs = append(logs, changeLog)
+	}
 
-		if md.Owner != sender {
-			return nil, fmt.Errorf("failed to change owner of entity %s: %s is not the owner", changeOwner.EntityKey.Hex(), sender.Hex())
+	if len(tx.ChangeOwnerBatch) > 0 && !rules.ChangeOwnerBatch {
+		return nil, fmt.Errorf("failed to change owner of entity batch: batched ownership changes are not active")
+	}
+
+	for i, batch := range tx.ChangeOwnerBatch {
+		// The sender must own the whole batch before any entity is transferred
+		for _, key := range batch.EntityKeys {
+			md, err := entity.GetEntityMetaData(access, key)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get entity meta data for change owner batch %d %s: %w", i, key.Hex(), err)
+			}
+			if md.Owner != sender {
+				return nil, fmt.Errorf("failed to change owner of entity batch %d: %s is not the owner of %s", i, sender.Hex(), key.Hex())
+			}
+		}
+		for _, key := range batch.EntityKeys {
+			changeLog, err := changeOwner(access, sender, key, batch.NewOwner, batch.Immediate, blockNumber, rules.TransferWindow)
+			if err != nil {
+				return nil, err
+			}
+			logs = append(logs, changeLog)
+		}
+	}
+
+	if len(tx.AcceptOwnership) > 0 && rules.TransferWindow == 0 {
+		return nil, fmt.Errorf("failed to accept ownership: two-step ownership transfers are not active")
+	}
+
+	for _, key := range tx.AcceptOwnership {
+		md, err := entity.GetEntityMetaData(access, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get entity meta data for accept ownership %s: %w", key.Hex(), err)
+		}
+
+		pending := entity.GetPendingOwner(access, key)
+		if pending == nil || pending.Owner != sender {
+			return nil, fmt.Errorf("failed to accept ownership of entity %s: no transfer to %s is pending", key.Hex(), sender.Hex())
+		}
+
+		err = entity.DeletePendingOwner(access, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to delete pending owner for accept ownership %s: %w", key.Hex(), err)
 		}
 
 		oldOwner := md.Owner
 
-		md.Owner = changeOwner.NewOwner
-		err = entity.StoreEntityMetaData(access, changeOwner.EntityKey, *md)
+		md.Owner = sender
+		err = entity.StoreEntityMetaData(access, key, *md)
 		if err != nil {
-			return nil, fmt.Errorf("failed to store entity meta data for change owner %s: %w", changeOwner.EntityKey.Hex(), err)
+			return nil, fmt.Errorf("failed to store entity meta data for accept ownership %s: %w", key.Hex(), err)
+		}
+
+		for _, topic := range []common.Hash{arkivlogs.ArkivEntityOwnershipTransferAccepted, arkivlogs.ArkivEntityOwnerChanged} {
+			logs = append(
+				logs,
+				&types.Log{
+					Address: common.Address(address.ArkivProcessorAddress),
+					Topics: []common.Hash{
+						topic,
+						key,
+						addressToHash(oldOwner),
+						addressToHash(md.Owner),
+					},
+					Data:        []byte{},
+					BlockNumber: blockNumber,
+				},
+			)
+		}
+	}
+
+	if len(tx.RegisterAlias)+len(tx.TransferAlias) > 0 && !rules.Aliases {
+		return nil, fmt.Errorf("failed to apply alias operations: aliases are not active")
+	}
+
+	for _, register := range tx.RegisterAlias {
+		if !entity.Exists(access, register.EntityKey) {
+			return nil, fmt.Errorf("failed to register alias %q: entity %s doesn't exist", register.Name, register.EntityKey.Hex())
+		}
+
+		nameHash := entity.AliasNameHash(register.Name)
+		if existing := entity.GetAlias(access, nameHash); existing != nil && existing.Owner != sender {
+			return nil, fmt.Errorf("failed to register alias %q: %s is not the owner", register.Name, sender.Hex())
+		}
+
+		expiresAtBlock, err := entity.ExpiresAt(blockNumber, blockNumber, register.BTL, rules.MaxBTL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to register alias %q: %w", register.Name, err)
+		}
+
+		alias := entity.Alias{
+			Key:            register.EntityKey,
+			Owner:          sender,
+			ExpiresAtBlock: expiresAtBlock,
+		}
+		err = entity.StoreAlias(access, nameHash, alias)
+		if err != nil {
+			return nil, fmt.Errorf("failed to store alias %q: %w", register.Name, err)
+		}
+
+		data := make([]byte, 64, 64+len(register.Name))
+		copy(data[:32], alias.Key[:])
+		uint256.NewInt(alias.ExpiresAtBlock).PutUint256(data[32:64])
+		data = append(data, register.Name...)
+
+		logs = append(
+			logs,
+			&types.Log{
+				Address: common.Address(address.ArkivProcessorAddress),
+				Topics: []common.Hash{
+					arkivlogs.ArkivAliasRegistered,
+					nameHash,
+					addressToHash(alias.Owner),
+				},
+				Data:        data,
+				BlockNumber: blockNumber,
+			},
+		)
+	}
+
+	for _, transfer := range tx.TransferAlias {
+		nameHash := entity.AliasNameHash(transfer.Name)
+		alias := entity.GetAlias(access, nameHash)
+		if alias == nil {
+			return nil, fmt.Errorf("failed to transfer alias %q: alias is not registered", transfer.Name)
+		}
+
+		if alias.Owner != sender {
+			return nil, fmt.Errorf("failed to transfer alias %q: %s is not the owner", transfer.Name, sender.Hex())
+		}
+
+		err = validateNewOwner(transfer.NewOwner)
+		if err != nil {
+			return nil, fmt.Errorf("failed to transfer alias %q: %w", transfer.Name, err)
+		}
+
+		oldOwner := alias.Owner
+		alias.Owner = transfer.NewOwner
+		err = entity.StoreAlias(access, nameHash, *alias)
+		if err != nil {
+			return nil, fmt.Errorf("failed to store alias %q: %w", transfer.Name, err)
 		}
 
 		logs = append(
@@ -421,12 +725,55 @@ func (tx *ArkivTransaction) Run(blockNumber uint64, txHash common.Hash, txIx int
 			&types.Log{
 				Address: common.Address(address.ArkivProcessorAddress),
 				Topics: []common.Hash{
-					arkivlogs.ArkivEntityOwnerChanged,
-					changeOwner.EntityKey,
+					arkivlogs.ArkivAliasTransferred,
+					nameHash,
 					addressToHash(oldOwner),
-					addressToHash(md.Owner),
+					addressToHash(alias.Owner),
 				},
-				Data:        []byte{},
+				Data:        []byte(transfer.Name),
+				BlockNumber: blockNumber,
+			},
+		)
+	}
+
+	if len(tx.ReduceBTL) > 0 && !rules.ReduceBTL {
+		return nil, fmt.Errorf("failed to reduce BTL: reducing the BTL is not active")
+	}
+
+	for _, reduce := range tx.ReduceBTL {
+		md, err := entity.GetEntityMetaData(access, reduce.EntityKey)
+		if errors.Is(err, entity.ErrEntityNotFound) {
+			if expired := expiredEntityError(access, reduce.EntityKey, blockNumber); expired != nil {
+				err = expired
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get entity meta data for reduce BTL %s: %w", reduce.EntityKey.Hex(), err)
+		}
+
+		if md.Owner != sender {
+			return nil, fmt.Errorf("failed to reduce BTL of entity %s: %s is not the owner", reduce.EntityKey.Hex(), sender.Hex())
+		}
+
+		oldExpiresAtBlock, owner, err := entity.ReduceBTL(access, reduce.EntityKey, reduce.NumberOfBlocks, blockNumber)
+		if err != nil {
+			return nil, fmt.Errorf("failed to reduce BTL of entity %s: %w", reduce.EntityKey.Hex(), err)
+		}
+
+		data := make([]byte, 64)
+		uint256.NewInt(oldExpiresAtBlock).PutUint256(data[:32])
+		uint256.NewInt(oldExpiresAtBlock - reduce.NumberOfBlocks).PutUint256(data[32:])
+
+		logs = append(
+			logs,
+			&types.Log{
+				Address: common.Address(address.ArkivProcessorAddress),
+				Topics: []common.Hash{
+					arkivlogs.ArkivEntityBTLReduced,
+					reduce.EntityKey,
+					addressToHash(owner),
+				},
+				Data:        data,
 				BlockNumber: blockNumber,
 			},
 		)
@@ -435,13 +782,39 @@ func (tx *ArkivTransaction) Run(blockNumber uint64, txHash common.Hash, txIx int
 	return logs, nil
 }
 
-const maxCompressedSize = 1024 * 1024 * 20 // 20MB
-
+// UnpackArkivTransaction decodes a transaction with the zero UnpackLimits, the limits
+// before the payload limits fork. It's meant for the transactions already applied
+// successfully, the others are decoded with the limits of the chain, see
+// UnpackArkivTransactionWithLimits.
 func UnpackArkivTransaction(compressed []byte) (*ArkivTransaction, error) {
-	reader := brotli.NewReader(bytes.NewReader(compressed))
-	lr := io.LimitReader(reader, maxCompressedSize)
+	return UnpackArkivTransactionWithLimits(compressed, UnpackLimits{})
+}
 
-	d, err := io.ReadAll(lr)
+// UnpackArkivTransactionWithLimits decompresses and decodes a transaction, and checks
+// it against the limits. The limits only depend on the chain, so a transaction
+// breaking them fails the same way on every node.
+func UnpackArkivTransactionWithLimits(compressed []byte, unpackLimits UnpackLimits) (*ArkivTransaction, error) {
+	var reader io.ReadCloser
+	var err error
+	if unpackLimits.RawBrotli {
+		// Before the codecs fork the calldata has no codec prefix
+		reader = io.NopCloser(brotli.NewReader(bytes.NewReader(compressed)))
+	} else {
+		reader, err = compression.NewReader(compressed)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read compressed storage transaction: %w", err)
+		}
+	}
+	defer reader.Close()
+
+	var d []byte
+	if unpackLimits.MaxDecompressedSize == 0 {
+		// Before the payload limits fork the bytes past the limit are ignored, which
+		// fails the decoding
+		d, err = io.ReadAll(io.LimitReader(reader, limits.MaxDecompressedSize))
+	} else {
+		d, err = compression.ReadAllLimited(reader, unpackLimits.MaxDecompressedSize)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to read compressed storage transaction: %w", err)
 	}
@@ -452,24 +825,118 @@ func UnpackArkivTransaction(compressed []byte) (*ArkivTransaction, error) {
 		return nil, fmt.Errorf("failed to decode storage transaction: %w", err)
 	}
 
+	if tx.Version > CurrentTransactionVersion {
+		return nil, fmt.Errorf("unsupported transaction version %d (max %d)", tx.Version, CurrentTransactionVersion)
+	}
+
+	err = tx.validateUnpackLimits(unpackLimits)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.validateAnnotationValueSizes(unpackLimits)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.validateUntypedNumerics(unpackLimits)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.validateUnencrypted(unpackLimits)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.validateFlatKeys(unpackLimits)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.validateNumericAnnotations()
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.validateEncryption()
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.validateOwnership()
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.validateAliases()
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.validateIdempotencyKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.validateReduceBTL()
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.validateChangeOwnerBatch()
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.validateExternalContent(unpackLimits)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.validateConditions(unpackLimits)
+	if err != nil {
+		return nil, err
+	}
+
 	return tx, nil
 }
 
-func ExecuteArkivTransaction(compressed []byte, blockNumber uint64, txHash common.Hash, txIx int, sender common.Address, access storageutil.StateAccess) ([]*types.Log, error) {
+// ExecuteArkivTransaction unpacks the compressed transaction with the limits of the
+// rules and executes it.
+func ExecuteArkivTransaction(compressed []byte, rules Rules, blockNumber uint64, txHash common.Hash, txIx int, sender common.Address, access storageutil.StateAccess) ([]*types.Log, error) {
 
-	tx, err := UnpackArkivTransaction(compressed)
+	tx, err := UnpackArkivTransactionWithLimits(compressed, rules.Unpack)
 	if err != nil {
 		return nil, fmt.Errorf("failed to unpack arkiv transaction: %w", err)
 	}
 
+	return tx.Execute(blockNumber, txHash, txIx, sender, rules, access)
+}
+
+// Execute runs the unpacked transaction and updates the number of used slots of the Arkiv processor.
+// If the rules count the slots of the owners, it also updates the number of slots used by
+// the entities and the aliases of each owner.
+func (tx *ArkivTransaction) Execute(blockNumber uint64, txHash common.Hash, txIx int, sender common.Address, rules Rules, access storageutil.StateAccess) ([]*types.Log, error) {
+
 	st := storageaccounting.NewSlotUsageCounter(access)
 
-	logs, err := tx.Run(blockNumber, txHash, txIx, sender, st)
+	var usedSlots, usedAliasSlots map[common.Hash]ownedSlots
+	if rules.OwnerSlots {
+		usedSlots = usedSlotsOf(st, tx.entityKeys(txHash), entity.UsedSlots)
+		usedAliasSlots = usedSlotsOf(st, tx.aliasNameHashes(), entity.UsedAliasSlots)
+	}
+
+	logs, err := tx.Run(blockNumber, txHash, txIx, sender, rules, st)
 	if err != nil {
 		log.Error("Failed to run storage transaction", "error", err)
 		return nil, fmt.Errorf("failed to run storage transaction: %w", err)
 	}
 
+	if rules.OwnerSlots {
+		attributeUsedSlots(st, usedSlots, entity.UsedSlots)
+		attributeUsedSlots(st, usedAliasSlots, entity.UsedAliasSlots)
+	}
 	st.UpdateUsedSlotsForGolemBase()
 
 	return logs, nil
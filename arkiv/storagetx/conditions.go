@@ -0,0 +1,153 @@
+package storagetx
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/arkiv/limits"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// The operations a condition can guard, see ArkivCondition.
+const (
+	ConditionOperationCreate uint8 = 1
+	ConditionOperationUpdate uint8 = 2
+	ConditionOperationExtend uint8 = 3
+)
+
+// ErrConditionFailed is returned for a transaction whose operation has a condition
+// that doesn't hold, which reverts the whole transaction.
+var ErrConditionFailed = errors.New("condition failed")
+
+// ArkivCondition is a precondition of the create, update or extension at Index of the
+// transaction, checked against the metadata of the entity EntityKey right before the
+// operation is applied. The zero EntityKey is the entity of the update or extension,
+// a condition of a create names the entity it checks.
+//
+// RequireAbsent requires the entity not to exist, it's deleted or has expired. The
+// other fields require it to exist and, when they aren't zero, to be owned by
+// RequireOwner, to expire after the block RequireExpiresAfter and to expire at the
+// block RequireExpiresAtMost or before.
+type ArkivCondition struct {
+	Operation            uint8          `json:"operation"`
+	Index                uint64         `json:"index"`
+	EntityKey            common.Hash    `json:"entityKey"`
+	RequireAbsent        bool           `json:"requireAbsent"`
+	RequireOwner         common.Address `json:"requireOwner"`
+	RequireExpiresAfter  uint64         `json:"requireExpiresAfter"`
+	RequireExpiresAtMost uint64         `json:"requireExpiresAtMost"`
+}
+
+// OperationName returns the name of the field of ArkivTransaction holding the guarded
+// operation.
+func (c *ArkivCondition) OperationName() string {
+	switch c.Operation {
+	case ConditionOperationCreate:
+		return "create"
+	case ConditionOperationUpdate:
+		return "update"
+	case ConditionOperationExtend:
+		return "extend"
+	}
+	return "unknown"
+}
+
+// Check returns an error wrapping ErrConditionFailed if the condition doesn't hold for
+// the entity key in the state.
+func (c *ArkivCondition) Check(access storageutil.StateAccess, key common.Hash) error {
+	md, err := entity.GetEntityMetaData(access, key)
+	if err != nil && !errors.Is(err, entity.ErrEntityNotFound) {
+		return err
+	}
+	if c.RequireAbsent {
+		if md != nil {
+			return fmt.Errorf("%w: entity %s exists", ErrConditionFailed, key.Hex())
+		}
+		return nil
+	}
+	if md == nil {
+		return fmt.Errorf("%w: entity %s doesn't exist", ErrConditionFailed, key.Hex())
+	}
+	if c.RequireOwner != (common.Address{}) && md.Owner != c.RequireOwner {
+		return fmt.Errorf("%w: entity %s is owned by %s, not %s", ErrConditionFailed, key.Hex(), md.Owner.Hex(), c.RequireOwner.Hex())
+	}
+	if md.ExpiresAtBlock <= c.RequireExpiresAfter {
+		return fmt.Errorf("%w: entity %s expires at block %d, not after %d", ErrConditionFailed, key.Hex(), md.ExpiresAtBlock, c.RequireExpiresAfter)
+	}
+	if c.RequireExpiresAtMost != 0 && md.ExpiresAtBlock > c.RequireExpiresAtMost {
+		return fmt.Errorf("%w: entity %s expires at block %d, after %d", ErrConditionFailed, key.Hex(), md.ExpiresAtBlock, c.RequireExpiresAtMost)
+	}
+	return nil
+}
+
+// checkConditions checks the conditions of the operation at index, whose entity is
+// key, in their order. The state is the one left by the operations run before.
+func (tx *ArkivTransaction) checkConditions(access storageutil.StateAccess, operation uint8, index int, key common.Hash) error {
+	for i := range tx.Conditions {
+		condition := &tx.Conditions[i]
+		if condition.Operation != operation || condition.Index != uint64(index) {
+			continue
+		}
+		entityKey := condition.EntityKey
+		if entityKey == (common.Hash{}) {
+			entityKey = key
+		}
+		if err := condition.Check(access, entityKey); err != nil {
+			return fmt.Errorf("condition[%d] of %s[%d]: %w", i, condition.OperationName(), index, err)
+		}
+	}
+	return nil
+}
+
+// validateConditions checks the format of the conditions: they're only carried by
+// transactions of a version supporting them once the fork is active, they point to an
+// operation of the transaction and require something. A condition of a create names
+// the entity it checks, and RequireAbsent excludes the other requirements.
+func (tx *ArkivTransaction) validateConditions(unpackLimits UnpackLimits) error {
+	if len(tx.Conditions) == 0 {
+		return nil
+	}
+	if unpackLimits.Unconditional {
+		return errors.New("conditions are not active")
+	}
+	if tx.Version < TransactionVersionConditions {
+		return fmt.Errorf("conditions require transaction version %d", TransactionVersionConditions)
+	}
+	if len(tx.Conditions) > limits.MaxOperations {
+		return fmt.Errorf("number of conditions is greater than %d", limits.MaxOperations)
+	}
+
+	for i, condition := range tx.Conditions {
+		var n int
+		switch condition.Operation {
+		case ConditionOperationCreate:
+			n = len(tx.Create)
+		case ConditionOperationUpdate:
+			n = len(tx.Update)
+		case ConditionOperationExtend:
+			n = len(tx.Extend)
+		default:
+			return fmt.Errorf("conditions[%d] has unknown operation %d", i, condition.Operation)
+		}
+		if condition.Index >= uint64(n) {
+			return fmt.Errorf("conditions[%d] points to no operation", i)
+		}
+		if condition.Operation == ConditionOperationCreate && condition.EntityKey == (common.Hash{}) {
+			return fmt.Errorf("conditions[%d] of a create names no entity", i)
+		}
+
+		requirements := condition.RequireOwner != (common.Address{}) || condition.RequireExpiresAfter != 0 || condition.RequireExpiresAtMost != 0
+		if condition.RequireAbsent && requirements {
+			return fmt.Errorf("conditions[%d] requires an absent entity with an owner or an expiry", i)
+		}
+		if !condition.RequireAbsent && !requirements {
+			return fmt.Errorf("conditions[%d] requires nothing", i)
+		}
+		if condition.RequireExpiresAtMost != 0 && condition.RequireExpiresAtMost <= condition.RequireExpiresAfter {
+			return fmt.Errorf("conditions[%d] requires an expiry after %d and at most %d", i, condition.RequireExpiresAfter, condition.RequireExpiresAtMost)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package storagetx
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func conditionalTransaction(conditions ...ArkivCondition) *ArkivTransaction {
+	return &ArkivTransaction{
+		Version: TransactionVersionConditions,
+		Create:  []ArkivCreate{{BTL: 100, ContentType: "text/plain", Payload: []byte("created")}},
+		Update: []ArkivUpdate{{
+			EntityKey:   common.HexToHash("0x01"),
+			BTL:         100,
+			ContentType: "text/plain",
+		}},
+		Extend:     []ExtendBTL{{EntityKey: common.HexToHash("0x02"), NumberOfBlocks: 10}},
+		Conditions: conditions,
+	}
+}
+
+func TestUnpack_Conditions(t *testing.T) {
+	tx := conditionalTransaction(
+		ArkivCondition{Operation: ConditionOperationCreate, EntityKey: common.HexToHash("0x03"), RequireAbsent: true},
+		ArkivCondition{Operation: ConditionOperationUpdate, RequireOwner: common.HexToAddress("0x1")},
+		ArkivCondition{Operation: ConditionOperationExtend, RequireExpiresAfter: 10, RequireExpiresAtMost: 20},
+	)
+
+	unpacked, err := UnpackArkivTransactionWithLimits(packTransaction(t, tx), UnpackLimits{})
+	require.NoError(t, err)
+	require.Equal(t, tx.Conditions, unpacked.Conditions)
+
+	// Before the conditions fork the conditions are rejected
+	_, err = UnpackArkivTransactionWithLimits(packTransaction(t, tx), UnpackLimits{Unconditional: true})
+	require.ErrorContains(t, err, "conditions are not active")
+	_, err = UnpackArkivTransactionWithLimits(packTransaction(t, conditionalTransaction()), UnpackLimits{Unconditional: true})
+	require.NoError(t, err)
+}
+
+func TestUnpack_ConditionsFormat(t *testing.T) {
+	for name, test := range map[string]struct {
+		condition ArkivCondition
+		err       string
+	}{
+		"unknown operation": {
+			condition: ArkivCondition{Operation: 4, RequireAbsent: true},
+			err:       "conditions[0] has unknown operation 4",
+		},
+		"no operation": {
+			condition: ArkivCondition{Operation: ConditionOperationExtend, Index: 1, RequireAbsent: true},
+			err:       "conditions[0] points to no operation",
+		},
+		"create without entity": {
+			condition: ArkivCondition{Operation: ConditionOperationCreate, RequireAbsent: true},
+			err:       "conditions[0] of a create names no entity",
+		},
+		"absent with owner": {
+			condition: ArkivCondition{Operation: ConditionOperationUpdate, RequireAbsent: true, RequireOwner: common.HexToAddress("0x1")},
+			err:       "requires an absent entity with an owner or an expiry",
+		},
+		"nothing": {
+			condition: ArkivCondition{Operation: ConditionOperationUpdate},
+			err:       "conditions[0] requires nothing",
+		},
+		"empty expiry range": {
+			condition: ArkivCondition{Operation: ConditionOperationExtend, RequireExpiresAfter: 20, RequireExpiresAtMost: 20},
+			err:       "requires an expiry after 20 and at most 20",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := UnpackArkivTransactionWithLimits(packTransaction(t, conditionalTransaction(test.condition)), UnpackLimits{})
+			require.ErrorContains(t, err, test.err)
+		})
+	}
+
+	tx := conditionalTransaction(ArkivCondition{Operation: ConditionOperationUpdate, RequireAbsent: true})
+	tx.Version = TransactionVersionExternalContent
+	_, err := UnpackArkivTransactionWithLimits(packTransaction(t, tx), UnpackLimits{})
+	require.ErrorContains(t, err, "conditions require transaction version 10")
+}
@@ -0,0 +1,145 @@
+package storagetx
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/ethereum/go-ethereum/arkiv/limits"
+)
+
+const (
+	// MaxEncryptionSchemeLength is the maximum length of the encryption scheme identifier in bytes.
+	MaxEncryptionSchemeLength = limits.MaxEncryptionSchemeLength
+
+	// MaxEncryptionKeyIDLength is the maximum length of the encryption key id in bytes.
+	MaxEncryptionKeyIDLength = limits.MaxEncryptionKeyIDLength
+
+	// MaxEncryptionNonceLength is the maximum length of the encryption nonce in bytes.
+	MaxEncryptionNonceLength = limits.MaxEncryptionNonceLength
+
+	// Synthetic string attributes carrying the encryption metadata of an entity in the
+	// events and query results. Annotation keys can't start with `$`, so the attributes
+	// can't collide with user annotations.
+	EncryptionSchemeAttribute = "$encryption_scheme"
+	EncryptionKeyIDAttribute  = "$encryption_key_id"
+	EncryptionNonceAttribute  = "$encryption_nonce"
+)
+
+// DefaultEncryptionSchemes is the default list of encryption schemes accepted by the transaction pool.
+var DefaultEncryptionSchemes = []string{
+	"aes-256-gcm",
+	"chacha20-poly1305",
+	"xchacha20-poly1305",
+	"age",
+}
+
+// EncryptionInfo describes how the payload of an entity is encrypted. The node carries
+// this metadata so that readers know how to decrypt the payload, it doesn't enforce it.
+type EncryptionInfo struct {
+	Scheme string `json:"scheme"`
+	KeyID  string `json:"keyId"`
+	Nonce  []byte `json:"nonce"`
+}
+
+func (e *EncryptionInfo) validate() error {
+	if e.Scheme == "" {
+		return fmt.Errorf("encryption scheme is empty")
+	}
+	if len(e.Scheme) > MaxEncryptionSchemeLength {
+		return fmt.Errorf("encryption scheme is too long: %d bytes (max %d)", len(e.Scheme), MaxEncryptionSchemeLength)
+	}
+	if len(e.KeyID) > MaxEncryptionKeyIDLength {
+		return fmt.Errorf("encryption key id is too long: %d bytes (max %d)", len(e.KeyID), MaxEncryptionKeyIDLength)
+	}
+	if len(e.Nonce) > MaxEncryptionNonceLength {
+		return fmt.Errorf("encryption nonce is too long: %d bytes (max %d)", len(e.Nonce), MaxEncryptionNonceLength)
+	}
+	return nil
+}
+
+func (tx *ArkivTransaction) validateEncryption() error {
+	check := func(op string, i int, encryption *EncryptionInfo) error {
+		if encryption == nil {
+			return nil
+		}
+		if tx.Version < TransactionVersionEncryption {
+			return fmt.Errorf("%s[%d] encryption info requires transaction version %d", op, i, TransactionVersionEncryption)
+		}
+		err := encryption.validate()
+		if err != nil {
+			return fmt.Errorf("%s[%d] %w", op, i, err)
+		}
+		return nil
+	}
+
+	for i, create := range tx.Create {
+		err := check("create", i, create.Encryption)
+		if err != nil {
+			return err
+		}
+	}
+
+	for i, update := range tx.Update {
+		err := check("update", i, update.Encryption)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateUnencrypted rejects the payloads carrying encryption info if the limits don't
+// accept them, the rule before the encryption fork.
+func (tx *ArkivTransaction) validateUnencrypted(unpackLimits UnpackLimits) error {
+	if !unpackLimits.Unencrypted {
+		return nil
+	}
+
+	for i, create := range tx.Create {
+		if create.Encryption != nil {
+			return fmt.Errorf("create[%d] encryption is not active", i)
+		}
+	}
+
+	for i, update := range tx.Update {
+		if update.Encryption != nil {
+			return fmt.Errorf("update[%d] encryption is not active", i)
+		}
+	}
+
+	return nil
+}
+
+// ValidateEncryptionSchemes checks that all the encryption schemes used by the transaction
+// are in the allowed list. An empty list allows any scheme.
+// The check is a node policy applied when transactions are accepted, it's not part of
+// the execution of the transaction.
+func (tx *ArkivTransaction) ValidateEncryptionSchemes(allowed []string) error {
+	if len(allowed) == 0 {
+		return nil
+	}
+
+	check := func(op string, i int, encryption *EncryptionInfo) error {
+		if encryption != nil && !slices.Contains(allowed, encryption.Scheme) {
+			return fmt.Errorf("%s[%d] encryption scheme %q is not allowed", op, i, encryption.Scheme)
+		}
+		return nil
+	}
+
+	for i, create := range tx.Create {
+		err := check("create", i, create.Encryption)
+		if err != nil {
+			return err
+		}
+	}
+
+	for i, update := range tx.Update {
+		err := check("update", i, update.Encryption)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
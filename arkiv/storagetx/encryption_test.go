@@ -0,0 +1,108 @@
+package storagetx
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/stretchr/testify/require"
+)
+
+func encryptedCreate(version uint64, encryption *EncryptionInfo) *ArkivTransaction {
+	return &ArkivTransaction{
+		Create: []ArkivCreate{
+			{
+				BTL:         100,
+				ContentType: "application/octet-stream",
+				Payload:     []byte("ciphertext"),
+				Encryption:  encryption,
+			},
+		},
+		Version: version,
+	}
+}
+
+func TestUnpack_EncryptionRoundTrip(t *testing.T) {
+	encryption := &EncryptionInfo{Scheme: "aes-256-gcm", KeyID: "key-1", Nonce: []byte{1, 2, 3}}
+	tx := encryptedCreate(TransactionVersionEncryption, encryption)
+	tx.Update = []ArkivUpdate{
+		{
+			EntityKey:   common.HexToHash("0x01"),
+			BTL:         100,
+			ContentType: "application/octet-stream",
+			Payload:     []byte("ciphertext"),
+			Encryption:  encryption,
+		},
+	}
+
+	unpacked, err := UnpackArkivTransaction(packTransaction(t, tx))
+	require.NoError(t, err)
+	require.Equal(t, encryption, unpacked.Create[0].Encryption)
+	require.Equal(t, encryption, unpacked.Update[0].Encryption)
+}
+
+func TestUnpack_PlaintextHasNoEncryption(t *testing.T) {
+	unpacked, err := UnpackArkivTransaction(packTransaction(t, encryptedCreate(TransactionVersionEncryption, nil)))
+	require.NoError(t, err)
+	require.Nil(t, unpacked.Create[0].Encryption)
+}
+
+func TestDecode_UpdateEmptyEncryptionIsNil(t *testing.T) {
+	// an update encoding its encryption as an empty list is plaintext
+	data, err := rlp.EncodeToBytes([]any{common.HexToHash("0x01"), "text/plain", uint64(100), []byte("payload"), []any{}, []any{}, []any{}})
+	require.NoError(t, err)
+
+	var update ArkivUpdate
+	require.NoError(t, rlp.DecodeBytes(data, &update))
+	require.Nil(t, update.Encryption)
+}
+
+func TestUnpack_EncryptionRequiresFork(t *testing.T) {
+	encryption := &EncryptionInfo{Scheme: "aes-256-gcm"}
+	unencrypted := UnpackLimitsAt(&params.ChainConfig{ArkivTypedNumericsTime: new(uint64)}, 0)
+
+	_, err := UnpackArkivTransactionWithLimits(packTransaction(t, encryptedCreate(TransactionVersionEncryption, encryption)), unencrypted)
+	require.ErrorContains(t, err, "create[0] encryption is not active")
+
+	_, err = UnpackArkivTransactionWithLimits(packTransaction(t, encryptedCreate(TransactionVersionEncryption, nil)), unencrypted)
+	require.NoError(t, err)
+}
+
+func TestUnpack_EncryptionRequiresVersion(t *testing.T) {
+	encryption := &EncryptionInfo{Scheme: "aes-256-gcm"}
+	_, err := UnpackArkivTransaction(packTransaction(t, encryptedCreate(TransactionVersionTypedNumerics, encryption)))
+	require.ErrorContains(t, err, "create[0] encryption info requires transaction version 2")
+}
+
+func TestUnpack_EncryptionLengthCaps(t *testing.T) {
+	_, err := UnpackArkivTransaction(packTransaction(t, encryptedCreate(TransactionVersionEncryption, &EncryptionInfo{})))
+	require.ErrorContains(t, err, "encryption scheme is empty")
+
+	_, err = UnpackArkivTransaction(packTransaction(t, encryptedCreate(TransactionVersionEncryption, &EncryptionInfo{
+		Scheme: strings.Repeat("a", MaxEncryptionSchemeLength+1),
+	})))
+	require.ErrorContains(t, err, "encryption scheme is too long")
+
+	_, err = UnpackArkivTransaction(packTransaction(t, encryptedCreate(TransactionVersionEncryption, &EncryptionInfo{
+		Scheme: "age",
+		KeyID:  strings.Repeat("a", MaxEncryptionKeyIDLength+1),
+	})))
+	require.ErrorContains(t, err, "encryption key id is too long")
+
+	_, err = UnpackArkivTransaction(packTransaction(t, encryptedCreate(TransactionVersionEncryption, &EncryptionInfo{
+		Scheme: "age",
+		Nonce:  make([]byte, MaxEncryptionNonceLength+1),
+	})))
+	require.ErrorContains(t, err, "encryption nonce is too long")
+}
+
+func TestValidateEncryptionSchemes(t *testing.T) {
+	tx := encryptedCreate(TransactionVersionEncryption, &EncryptionInfo{Scheme: "rot13"})
+
+	require.ErrorContains(t, tx.ValidateEncryptionSchemes(DefaultEncryptionSchemes), `create[0] encryption scheme "rot13" is not allowed`)
+	require.NoError(t, tx.ValidateEncryptionSchemes([]string{"rot13"}))
+	require.NoError(t, tx.ValidateEncryptionSchemes(nil))
+	require.NoError(t, encryptedCreate(TransactionVersionEncryption, nil).ValidateEncryptionSchemes(DefaultEncryptionSchemes))
+}
@@ -0,0 +1,33 @@
+package storagetx
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/arkiv/storageutil"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ErrEntityExpired is returned for the extension of an entity that has expired. The
+// housekeeping of a block runs before its transactions, so an entity expiring at a
+// block can only be extended up to the block before.
+var ErrEntityExpired = errors.New("entity expired")
+
+// expiredEntityError returns ErrEntityExpired if the tombstone of the missing entity
+// tells it expired, nil otherwise.
+func expiredEntityError(access storageutil.StateAccess, key common.Hash, blockNumber uint64) error {
+	tombstone := entity.GetTombstone(access, key)
+	if tombstone == nil || tombstone.Reason != entity.TombstoneExpired {
+		return nil
+	}
+	if tombstone.Block == blockNumber {
+		return fmt.Errorf(
+			"%w at block %d, the housekeeping of a block runs before its transactions, extend it at block %d at the latest",
+			ErrEntityExpired,
+			blockNumber,
+			blockNumber-1,
+		)
+	}
+	return fmt.Errorf("%w at block %d", ErrEntityExpired, tombstone.Block)
+}
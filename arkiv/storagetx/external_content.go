@@ -0,0 +1,191 @@
+package storagetx
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"strconv"
+	"unicode"
+
+	"github.com/ethereum/go-ethereum/arkiv/limits"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	// MaxContentPointerLength is the maximum length of the pointer to a payload stored
+	// off chain in bytes.
+	MaxContentPointerLength = limits.MaxContentPointerLength
+
+	// ContentCommitmentKeccak256 and ContentCommitmentSHA256 are the types of the
+	// commitment of external content, the hash of the payload.
+	ContentCommitmentKeccak256 uint8 = 1
+	ContentCommitmentSHA256    uint8 = 2
+
+	// Synthetic string attributes carrying the external content of an entity in the
+	// events and query results. ContentStatusAttribute is the state of the payload,
+	// ContentStatusPending until the node fetched and verified it.
+	ContentCommitmentAttribute     = "$content_commitment"
+	ContentCommitmentTypeAttribute = "$content_commitment_type"
+	ContentSizeAttribute           = "$content_size"
+	ContentPointerAttribute        = "$content_pointer"
+	ContentStatusAttribute         = "$content_status"
+
+	// The values of ContentStatusAttribute: the payload wasn't fetched yet, it was
+	// fetched and matches the commitment, or it doesn't match the commitment.
+	ContentStatusPending  = "pending"
+	ContentStatusResolved = "resolved"
+	ContentStatusInvalid  = "invalid"
+)
+
+// ErrContentMismatch is returned by ArkivExternalContent.Verify for a payload that
+// doesn't match the commitment.
+var ErrContentMismatch = errors.New("content doesn't match the commitment")
+
+// ArkivExternalContent is the commitment to the payload of an operation of the
+// transaction, stored off chain: the create at Index, or the update at Index if Update
+// is set, carries no payload, the nodes fetch the Size bytes of the payload from
+// Pointer and check them against Commitment. The chain only checks the format of the
+// commitment, the payload never goes through consensus.
+type ArkivExternalContent struct {
+	Update     bool        `json:"update"`
+	Index      uint64      `json:"index"`
+	Type       uint8       `json:"type"`
+	Commitment common.Hash `json:"commitment"`
+	Size       uint64      `json:"size"`
+	Pointer    string      `json:"pointer"`
+}
+
+// CommitmentTypeName returns the name of the type of the commitment.
+func (c *ArkivExternalContent) CommitmentTypeName() string {
+	switch c.Type {
+	case ContentCommitmentKeccak256:
+		return "keccak256"
+	case ContentCommitmentSHA256:
+		return "sha256"
+	}
+	return "unknown"
+}
+
+// Verify checks that the payload is the one of the commitment.
+func (c *ArkivExternalContent) Verify(payload []byte) error {
+	if uint64(len(payload)) != c.Size {
+		return fmt.Errorf("%w: %d bytes, committed to %d", ErrContentMismatch, len(payload), c.Size)
+	}
+	var hash common.Hash
+	switch c.Type {
+	case ContentCommitmentKeccak256:
+		hash = crypto.Keccak256Hash(payload)
+	case ContentCommitmentSHA256:
+		hash = sha256.Sum256(payload)
+	default:
+		return fmt.Errorf("unknown commitment type %d", c.Type)
+	}
+	if hash != c.Commitment {
+		return fmt.Errorf("%w: %s hash %s, committed to %s", ErrContentMismatch, c.CommitmentTypeName(), hash.Hex(), c.Commitment.Hex())
+	}
+	return nil
+}
+
+// Attributes returns the synthetic attributes of the external content, with the
+// pending status.
+func (c *ArkivExternalContent) Attributes() map[string]string {
+	return map[string]string{
+		ContentCommitmentAttribute:     c.Commitment.Hex(),
+		ContentCommitmentTypeAttribute: c.CommitmentTypeName(),
+		ContentSizeAttribute:           strconv.FormatUint(c.Size, 10),
+		ContentPointerAttribute:        c.Pointer,
+		ContentStatusAttribute:         ContentStatusPending,
+	}
+}
+
+// ExternalContentFromAttributes returns the external content described by the
+// synthetic attributes of an entity, false if the entity has no external content.
+// Update and Index aren't set.
+func ExternalContentFromAttributes(attributes map[string]string) (*ArkivExternalContent, bool) {
+	if _, ok := attributes[ContentStatusAttribute]; !ok {
+		return nil, false
+	}
+	content := &ArkivExternalContent{
+		Commitment: common.HexToHash(attributes[ContentCommitmentAttribute]),
+		Pointer:    attributes[ContentPointerAttribute],
+	}
+	switch attributes[ContentCommitmentTypeAttribute] {
+	case "keccak256":
+		content.Type = ContentCommitmentKeccak256
+	case "sha256":
+		content.Type = ContentCommitmentSHA256
+	}
+	content.Size, _ = strconv.ParseUint(attributes[ContentSizeAttribute], 10, 64)
+	return content, true
+}
+
+// ExternalContentOf returns the external content of the create at index, or of the
+// update at index if update is set, nil if its payload is in the transaction.
+func (tx *ArkivTransaction) ExternalContentOf(update bool, index int) *ArkivExternalContent {
+	for i := range tx.ExternalContent {
+		if content := &tx.ExternalContent[i]; content.Update == update && content.Index == uint64(index) {
+			return content
+		}
+	}
+	return nil
+}
+
+// validateExternalContent checks the format of the external content: it's only carried
+// by transactions of a version supporting it once the fork is active, it points to a
+// create or an update without payload, once, and its commitment has a known type and
+// a pointer of printable characters.
+func (tx *ArkivTransaction) validateExternalContent(unpackLimits UnpackLimits) error {
+	if len(tx.ExternalContent) == 0 {
+		return nil
+	}
+	if unpackLimits.InlineContent {
+		return errors.New("external content is not active")
+	}
+	if tx.Version < TransactionVersionExternalContent {
+		return fmt.Errorf("externalContent requires transaction version %d", TransactionVersionExternalContent)
+	}
+
+	type operation struct {
+		update bool
+		index  uint64
+	}
+	seen := make(map[operation]bool, len(tx.ExternalContent))
+	for i, content := range tx.ExternalContent {
+		var payload []byte
+		switch {
+		case content.Update && content.Index < uint64(len(tx.Update)):
+			payload = tx.Update[content.Index].Payload
+		case !content.Update && content.Index < uint64(len(tx.Create)):
+			payload = tx.Create[content.Index].Payload
+		default:
+			return fmt.Errorf("externalContent[%d] points to no operation", i)
+		}
+		op := operation{content.Update, content.Index}
+		if seen[op] {
+			return fmt.Errorf("externalContent[%d] repeats the content of an operation", i)
+		}
+		seen[op] = true
+
+		if len(payload) > 0 {
+			return fmt.Errorf("externalContent[%d] points to an operation carrying a payload", i)
+		}
+		if content.Type != ContentCommitmentKeccak256 && content.Type != ContentCommitmentSHA256 {
+			return fmt.Errorf("externalContent[%d] has unknown commitment type %d", i, content.Type)
+		}
+		if unpackLimits.MaxPayloadSize > 0 && content.Size > unpackLimits.MaxPayloadSize {
+			return fmt.Errorf("externalContent[%d] payload is too large: %d bytes (max %d)", i, content.Size, unpackLimits.MaxPayloadSize)
+		}
+		if content.Pointer == "" {
+			return fmt.Errorf("externalContent[%d] pointer is empty", i)
+		}
+		if len(content.Pointer) > MaxContentPointerLength {
+			return fmt.Errorf("externalContent[%d] pointer is too long: %d bytes (max %d)", i, len(content.Pointer), MaxContentPointerLength)
+		}
+		if pos := bytes.IndexFunc([]byte(content.Pointer), func(r rune) bool { return r > unicode.MaxASCII || !unicode.IsPrint(r) || r == ' ' }); pos >= 0 {
+			return fmt.Errorf("externalContent[%d] pointer has a character that isn't printable ASCII at %d", i, pos)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package storagetx
+
+import (
+	"crypto/sha256"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func externalContentTransaction(contents ...ArkivExternalContent) *ArkivTransaction {
+	return &ArkivTransaction{
+		Version: TransactionVersionExternalContent,
+		Create:  []ArkivCreate{{BTL: 100, ContentType: "text/plain"}},
+		Update: []ArkivUpdate{{
+			EntityKey:   common.HexToHash("0x01"),
+			BTL:         100,
+			ContentType: "text/plain",
+		}},
+		ExternalContent: contents,
+	}
+}
+
+func keccakContent(payload []byte) ArkivExternalContent {
+	return ArkivExternalContent{
+		Type:       ContentCommitmentKeccak256,
+		Commitment: crypto.Keccak256Hash(payload),
+		Size:       uint64(len(payload)),
+		Pointer:    "blobs/0x01",
+	}
+}
+
+func TestUnpack_ExternalContent(t *testing.T) {
+	created, updated := keccakContent([]byte("created")), keccakContent([]byte("updated"))
+	updated.Update = true
+	tx := externalContentTransaction(created, updated)
+
+	unpacked, err := UnpackArkivTransactionWithLimits(packTransaction(t, tx), UnpackLimits{})
+	require.NoError(t, err)
+	require.Equal(t, tx.ExternalContent, unpacked.ExternalContent)
+	require.Equal(t, &unpacked.ExternalContent[0], unpacked.ExternalContentOf(false, 0))
+	require.Equal(t, &unpacked.ExternalContent[1], unpacked.ExternalContentOf(true, 0))
+	require.Nil(t, unpacked.ExternalContentOf(false, 1))
+
+	// Before the external content fork the commitments are rejected
+	_, err = UnpackArkivTransactionWithLimits(packTransaction(t, tx), UnpackLimits{InlineContent: true})
+	require.ErrorContains(t, err, "external content is not active")
+
+	// The transactions without external content decode the same
+	_, err = UnpackArkivTransactionWithLimits(packTransaction(t, externalContentTransaction()), UnpackLimits{InlineContent: true})
+	require.NoError(t, err)
+}
+
+func TestUnpack_ExternalContentFormat(t *testing.T) {
+	content := keccakContent([]byte("payload"))
+	with := func(change func(*ArkivExternalContent)) ArkivExternalContent {
+		c := content
+		change(&c)
+		return c
+	}
+
+	for name, test := range map[string]struct {
+		tx  *ArkivTransaction
+		err string
+	}{
+		"version": {
+			tx:  &ArkivTransaction{Version: TransactionVersionChangeOwnerBatch, Create: []ArkivCreate{{BTL: 1, ContentType: "text/plain"}}, ExternalContent: []ArkivExternalContent{content}},
+			err: "externalContent requires transaction version 9",
+		},
+		"no operation": {
+			tx:  externalContentTransaction(with(func(c *ArkivExternalContent) { c.Index = 1 })),
+			err: "externalContent[0] points to no operation",
+		},
+		"repeated": {
+			tx:  externalContentTransaction(content, content),
+			err: "externalContent[1] repeats the content of an operation",
+		},
+		"unknown type": {
+			tx:  externalContentTransaction(with(func(c *ArkivExternalContent) { c.Type = 3 })),
+			err: "unknown commitment type 3",
+		},
+		"empty pointer": {
+			tx:  externalContentTransaction(with(func(c *ArkivExternalContent) { c.Pointer = "" })),
+			err: "pointer is empty",
+		},
+		"long pointer": {
+			tx:  externalContentTransaction(with(func(c *ArkivExternalContent) { c.Pointer = strings.Repeat("a", MaxContentPointerLength+1) })),
+			err: "pointer is too long",
+		},
+		"unprintable pointer": {
+			tx:  externalContentTransaction(with(func(c *ArkivExternalContent) { c.Pointer = "blobs/\n" })),
+			err: "isn't printable ASCII at 6",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := UnpackArkivTransactionWithLimits(packTransaction(t, test.tx), UnpackLimits{})
+			require.ErrorContains(t, err, test.err)
+		})
+	}
+
+	// The operation carries no payload of its own
+	tx := externalContentTransaction(content)
+	tx.Create[0].Payload = []byte("payload")
+	_, err := UnpackArkivTransactionWithLimits(packTransaction(t, tx), UnpackLimits{})
+	require.ErrorContains(t, err, "points to an operation carrying a payload")
+
+	// The committed size is capped like the payloads
+	tx = externalContentTransaction(with(func(c *ArkivExternalContent) { c.Size = 17 }))
+	_, err = UnpackArkivTransactionWithLimits(packTransaction(t, tx), UnpackLimits{MaxPayloadSize: 16})
+	require.ErrorContains(t, err, "externalContent[0] payload is too large")
+}
+
+func TestExternalContent_Verify(t *testing.T) {
+	payload := []byte("payload")
+	content := keccakContent(payload)
+	require.NoError(t, content.Verify(payload))
+	require.ErrorIs(t, content.Verify([]byte("pay1oad")), ErrContentMismatch)
+	require.ErrorIs(t, content.Verify([]byte("payload!")), ErrContentMismatch)
+
+	content.Type, content.Commitment = ContentCommitmentSHA256, sha256.Sum256(payload)
+	require.NoError(t, content.Verify(payload))
+
+	// The attributes describe the content
+	decoded, ok := ExternalContentFromAttributes(content.Attributes())
+	require.True(t, ok)
+	require.Equal(t, &content, decoded)
+	_, ok = ExternalContentFromAttributes(map[string]string{"kind": "inline"})
+	require.False(t, ok)
+}
@@ -27,59 +27,207 @@ func (obj *ArkivTransaction) EncodeRLP(_w io.Writer) error {
 			_tmp9 := w.List()
 			w.WriteString(_tmp8.Key)
 			w.WriteUint64(_tmp8.Value)
+			_tmp10 := _tmp8.Type != 0
+			_tmp11 := _tmp8.Decimals != 0
+			if _tmp10 || _tmp11 {
+				w.WriteUint64(uint64(_tmp8.Type))
+			}
+			if _tmp11 {
+				w.WriteUint64(uint64(_tmp8.Decimals))
+			}
 			w.ListEnd(_tmp9)
 		}
 		w.ListEnd(_tmp7)
+		_tmp12 := _tmp2.Encryption != nil
+		_tmp13 := _tmp2.IdempotencyKey != nil
+		if _tmp12 || _tmp13 {
+			if _tmp2.Encryption == nil {
+				w.Write([]byte{0xC0})
+			} else {
+				_tmp14 := w.List()
+				w.WriteString(_tmp2.Encryption.Scheme)
+				w.WriteString(_tmp2.Encryption.KeyID)
+				w.WriteBytes(_tmp2.Encryption.Nonce)
+				w.ListEnd(_tmp14)
+			}
+		}
+		if _tmp13 {
+			if _tmp2.IdempotencyKey == nil {
+				w.Write([]byte{0x80})
+			} else {
+				w.WriteBytes(_tmp2.IdempotencyKey[:])
+			}
+		}
 		w.ListEnd(_tmp3)
 	}
 	w.ListEnd(_tmp1)
-	_tmp10 := w.List()
-	for _, _tmp11 := range obj.Update {
-		_tmp12 := w.List()
-		w.WriteBytes(_tmp11.EntityKey[:])
-		w.WriteString(_tmp11.ContentType)
-		w.WriteUint64(_tmp11.BTL)
-		w.WriteBytes(_tmp11.Payload)
-		_tmp13 := w.List()
-		for _, _tmp14 := range _tmp11.StringAnnotations {
-			_tmp15 := w.List()
-			w.WriteString(_tmp14.Key)
-			w.WriteString(_tmp14.Value)
-			w.ListEnd(_tmp15)
+	_tmp15 := w.List()
+	for _, _tmp16 := range obj.Update {
+		_tmp17 := w.List()
+		w.WriteBytes(_tmp16.EntityKey[:])
+		w.WriteString(_tmp16.ContentType)
+		w.WriteUint64(_tmp16.BTL)
+		w.WriteBytes(_tmp16.Payload)
+		_tmp18 := w.List()
+		for _, _tmp19 := range _tmp16.StringAnnotations {
+			_tmp20 := w.List()
+			w.WriteString(_tmp19.Key)
+			w.WriteString(_tmp19.Value)
+			w.ListEnd(_tmp20)
+		}
+		w.ListEnd(_tmp18)
+		_tmp21 := w.List()
+		for _, _tmp22 := range _tmp16.NumericAnnotations {
+			_tmp23 := w.List()
+			w.WriteString(_tmp22.Key)
+			w.WriteUint64(_tmp22.Value)
+			_tmp24 := _tmp22.Type != 0
+			_tmp25 := _tmp22.Decimals != 0
+			if _tmp24 || _tmp25 {
+				w.WriteUint64(uint64(_tmp22.Type))
+			}
+			if _tmp25 {
+				w.WriteUint64(uint64(_tmp22.Decimals))
+			}
+			w.ListEnd(_tmp23)
 		}
-		w.ListEnd(_tmp13)
-		_tmp16 := w.List()
-		for _, _tmp17 := range _tmp11.NumericAnnotations {
-			_tmp18 := w.List()
-			w.WriteString(_tmp17.Key)
-			w.WriteUint64(_tmp17.Value)
-			w.ListEnd(_tmp18)
+		w.ListEnd(_tmp21)
+		_tmp26 := _tmp16.Encryption != nil
+		if _tmp26 {
+			if _tmp16.Encryption == nil {
+				w.Write([]byte{0xC0})
+			} else {
+				_tmp27 := w.List()
+				w.WriteString(_tmp16.Encryption.Scheme)
+				w.WriteString(_tmp16.Encryption.KeyID)
+				w.WriteBytes(_tmp16.Encryption.Nonce)
+				w.ListEnd(_tmp27)
+			}
+		}
+		w.ListEnd(_tmp17)
+	}
+	w.ListEnd(_tmp15)
+	_tmp28 := w.List()
+	for _, _tmp29 := range obj.Delete {
+		w.WriteBytes(_tmp29[:])
+	}
+	w.ListEnd(_tmp28)
+	_tmp30 := w.List()
+	for _, _tmp31 := range obj.Extend {
+		_tmp32 := w.List()
+		w.WriteBytes(_tmp31.EntityKey[:])
+		w.WriteUint64(_tmp31.NumberOfBlocks)
+		w.ListEnd(_tmp32)
+	}
+	w.ListEnd(_tmp30)
+	_tmp33 := w.List()
+	for _, _tmp34 := range obj.ChangeOwner {
+		_tmp35 := w.List()
+		w.WriteBytes(_tmp34.EntityKey[:])
+		w.WriteBytes(_tmp34.NewOwner[:])
+		_tmp36 := _tmp34.Immediate
+		if _tmp36 {
+			w.WriteBool(_tmp34.Immediate)
 		}
-		w.ListEnd(_tmp16)
-		w.ListEnd(_tmp12)
+		w.ListEnd(_tmp35)
 	}
-	w.ListEnd(_tmp10)
-	_tmp19 := w.List()
-	for _, _tmp20 := range obj.Delete {
-		w.WriteBytes(_tmp20[:])
+	w.ListEnd(_tmp33)
+	_tmp37 := obj.Version != 0
+	_tmp38 := len(obj.AcceptOwnership) > 0
+	_tmp39 := len(obj.RegisterAlias) > 0
+	_tmp40 := len(obj.TransferAlias) > 0
+	_tmp41 := len(obj.ReduceBTL) > 0
+	_tmp42 := len(obj.ChangeOwnerBatch) > 0
+	_tmp43 := len(obj.ExternalContent) > 0
+	_tmp44 := len(obj.Conditions) > 0
+	if _tmp37 || _tmp38 || _tmp39 || _tmp40 || _tmp41 || _tmp42 || _tmp43 || _tmp44 {
+		w.WriteUint64(obj.Version)
 	}
-	w.ListEnd(_tmp19)
-	_tmp21 := w.List()
-	for _, _tmp22 := range obj.Extend {
-		_tmp23 := w.List()
-		w.WriteBytes(_tmp22.EntityKey[:])
-		w.WriteUint64(_tmp22.NumberOfBlocks)
-		w.ListEnd(_tmp23)
+	if _tmp38 || _tmp39 || _tmp40 || _tmp41 || _tmp42 || _tmp43 || _tmp44 {
+		_tmp45 := w.List()
+		for _, _tmp46 := range obj.AcceptOwnership {
+			w.WriteBytes(_tmp46[:])
+		}
+		w.ListEnd(_tmp45)
+	}
+	if _tmp39 || _tmp40 || _tmp41 || _tmp42 || _tmp43 || _tmp44 {
+		_tmp47 := w.List()
+		for _, _tmp48 := range obj.RegisterAlias {
+			_tmp49 := w.List()
+			w.WriteString(_tmp48.Name)
+			w.WriteBytes(_tmp48.EntityKey[:])
+			w.WriteUint64(_tmp48.BTL)
+			w.ListEnd(_tmp49)
+		}
+		w.ListEnd(_tmp47)
 	}
-	w.ListEnd(_tmp21)
-	_tmp24 := w.List()
-	for _, _tmp25 := range obj.ChangeOwner {
-		_tmp26 := w.List()
-		w.WriteBytes(_tmp25.EntityKey[:])
-		w.WriteBytes(_tmp25.NewOwner[:])
-		w.ListEnd(_tmp26)
+	if _tmp40 || _tmp41 || _tmp42 || _tmp43 || _tmp44 {
+		_tmp50 := w.List()
+		for _, _tmp51 := range obj.TransferAlias {
+			_tmp52 := w.List()
+			w.WriteString(_tmp51.Name)
+			w.WriteBytes(_tmp51.NewOwner[:])
+			w.ListEnd(_tmp52)
+		}
+		w.ListEnd(_tmp50)
+	}
+	if _tmp41 || _tmp42 || _tmp43 || _tmp44 {
+		_tmp53 := w.List()
+		for _, _tmp54 := range obj.ReduceBTL {
+			_tmp55 := w.List()
+			w.WriteBytes(_tmp54.EntityKey[:])
+			w.WriteUint64(_tmp54.NumberOfBlocks)
+			w.ListEnd(_tmp55)
+		}
+		w.ListEnd(_tmp53)
+	}
+	if _tmp42 || _tmp43 || _tmp44 {
+		_tmp56 := w.List()
+		for _, _tmp57 := range obj.ChangeOwnerBatch {
+			_tmp58 := w.List()
+			_tmp59 := w.List()
+			for _, _tmp60 := range _tmp57.EntityKeys {
+				w.WriteBytes(_tmp60[:])
+			}
+			w.ListEnd(_tmp59)
+			w.WriteBytes(_tmp57.NewOwner[:])
+			_tmp61 := _tmp57.Immediate
+			if _tmp61 {
+				w.WriteBool(_tmp57.Immediate)
+			}
+			w.ListEnd(_tmp58)
+		}
+		w.ListEnd(_tmp56)
+	}
+	if _tmp43 || _tmp44 {
+		_tmp62 := w.List()
+		for _, _tmp63 := range obj.ExternalContent {
+			_tmp64 := w.List()
+			w.WriteBool(_tmp63.Update)
+			w.WriteUint64(_tmp63.Index)
+			w.WriteUint64(uint64(_tmp63.Type))
+			w.WriteBytes(_tmp63.Commitment[:])
+			w.WriteUint64(_tmp63.Size)
+			w.WriteString(_tmp63.Pointer)
+			w.ListEnd(_tmp64)
+		}
+		w.ListEnd(_tmp62)
+	}
+	if _tmp44 {
+		_tmp65 := w.List()
+		for _, _tmp66 := range obj.Conditions {
+			_tmp67 := w.List()
+			w.WriteUint64(uint64(_tmp66.Operation))
+			w.WriteUint64(_tmp66.Index)
+			w.WriteBytes(_tmp66.EntityKey[:])
+			w.WriteBool(_tmp66.RequireAbsent)
+			w.WriteBytes(_tmp66.RequireOwner[:])
+			w.WriteUint64(_tmp66.RequireExpiresAfter)
+			w.WriteUint64(_tmp66.RequireExpiresAtMost)
+			w.ListEnd(_tmp67)
+		}
+		w.ListEnd(_tmp65)
 	}
-	w.ListEnd(_tmp24)
 	w.ListEnd(_tmp0)
 	return w.Flush()
 }
@@ -0,0 +1,49 @@
+package storagetx
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/arkiv/address"
+	arkivlogs "github.com/ethereum/go-ethereum/arkiv/logs"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// validateIdempotencyKeys checks that idempotency keys are only carried by
+// transactions of a version supporting them.
+func (tx *ArkivTransaction) validateIdempotencyKeys() error {
+	if tx.Version >= TransactionVersionIdempotency {
+		return nil
+	}
+	for i, create := range tx.Create {
+		if create.IdempotencyKey != nil {
+			return fmt.Errorf("create[%d] idempotency key requires transaction version %d", i, TransactionVersionIdempotency)
+		}
+	}
+	return nil
+}
+
+// deduplicatedCreate returns the log of the create of the sender if it carries the
+// idempotency key of an entity the sender created within the TTL, nil if the create
+// has to be applied.
+func deduplicatedCreate(access storageutil.StateAccess, blockNumber uint64, sender common.Address, create ArkivCreate) *types.Log {
+	if create.IdempotencyKey == nil {
+		return nil
+	}
+	original, ok := entity.GetIdempotencyKey(access, entity.IdempotencyHash(sender, *create.IdempotencyKey))
+	if !ok {
+		return nil
+	}
+	return &types.Log{
+		Address: common.Address(address.ArkivProcessorAddress),
+		Topics: []common.Hash{
+			arkivlogs.ArkivCreateDeduplicated,
+			original,
+			addressToHash(sender),
+		},
+		Data:        create.IdempotencyKey.Bytes(),
+		BlockNumber: blockNumber,
+	}
+}
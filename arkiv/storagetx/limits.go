@@ -0,0 +1,192 @@
+package storagetx
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// GasSchedule is the gas the Arkiv transactions are charged on top of the intrinsic
+// gas. The zero schedule, the one before the gas schedule fork, charges nothing.
+type GasSchedule struct {
+	// AnnotationValueGasThreshold is the size of a string annotation value in bytes
+	// above which every additional byte is charged with AnnotationValueGasPerByte.
+	AnnotationValueGasThreshold uint64
+	// AnnotationValueGasPerByte is the gas charged for every byte of a string
+	// annotation value above AnnotationValueGasThreshold.
+	AnnotationValueGasPerByte uint64
+}
+
+// GasScheduleAt returns the gas schedule of the transactions applied at time.
+func GasScheduleAt(config *params.ChainConfig, time uint64) GasSchedule {
+	return GasSchedule{
+		AnnotationValueGasThreshold: config.ArkivAnnotationValueGasThresholdAt(time),
+		AnnotationValueGasPerByte:   config.ArkivAnnotationValueGasPerByteAt(time),
+	}
+}
+
+// Gas returns the gas charged by the Arkiv gas schedule on top of the intrinsic gas of the transaction.
+func (tx *ArkivTransaction) Gas(schedule GasSchedule) uint64 {
+	gas := uint64(0)
+	for _, op := range tx.OperationsGas(schedule) {
+		gas += op.Gas
+	}
+	return gas
+}
+
+// OperationGas is the gas an operation of a transaction is charged by the Arkiv gas
+// schedule. Operation is the field of ArkivTransaction holding it and Index its
+// position in the field.
+type OperationGas struct {
+	Operation string `json:"operation"`
+	Index     int    `json:"index"`
+	Gas       uint64 `json:"gas"`
+}
+
+// OperationsGas breaks Gas down by operation, in the order the operations are run.
+// Only the string annotation values of creates and updates are charged, the other
+// operations cost nothing on top of the intrinsic gas.
+func (tx *ArkivTransaction) OperationsGas(schedule GasSchedule) []OperationGas {
+	ops := []OperationGas{}
+	add := func(operation string, n int, gas func(i int) uint64) {
+		for i := range n {
+			ops = append(ops, OperationGas{Operation: operation, Index: i, Gas: gas(i)})
+		}
+	}
+	free := func(int) uint64 { return 0 }
+
+	add("create", len(tx.Create), func(i int) uint64 { return schedule.annotationValuesGas(tx.Create[i].StringAnnotations) })
+	add("delete", len(tx.Delete), free)
+	add("update", len(tx.Update), func(i int) uint64 { return schedule.annotationValuesGas(tx.Update[i].StringAnnotations) })
+	add("extend", len(tx.Extend), free)
+	add("changeOwner", len(tx.ChangeOwner), free)
+	add("changeOwnerBatch", len(tx.ChangeOwnerBatch), free)
+	add("acceptOwnership", len(tx.AcceptOwnership), free)
+	add("registerAlias", len(tx.RegisterAlias), free)
+	add("transferAlias", len(tx.TransferAlias), free)
+	add("reduceBTL", len(tx.ReduceBTL), free)
+	return ops
+}
+
+func (s GasSchedule) annotationValuesGas(annotations []StringAnnotation) uint64 {
+	gas := uint64(0)
+	for _, annotation := range annotations {
+		size := uint64(len(annotation.Value))
+		if size > s.AnnotationValueGasThreshold {
+			gas += (size - s.AnnotationValueGasThreshold) * s.AnnotationValueGasPerByte
+		}
+	}
+	return gas
+}
+
+// ValidateAnnotationValueSizes checks the string annotation values of the creates and
+// updates against maxSize, the check of the decoding from the gas schedule fork.
+func (tx *ArkivTransaction) ValidateAnnotationValueSizes(maxSize uint64) error {
+	return tx.validateAnnotationValueSizes(UnpackLimits{MaxAnnotationValueSize: maxSize})
+}
+
+// validateAnnotationValueSizes rejects the transaction if a string annotation value of
+// a create or an update is larger than the limits allow. The values aren't capped
+// before the gas schedule fork.
+func (tx *ArkivTransaction) validateAnnotationValueSizes(unpackLimits UnpackLimits) error {
+	if unpackLimits.MaxAnnotationValueSize == 0 {
+		return nil
+	}
+	maxSize := unpackLimits.MaxAnnotationValueSize
+
+	for i, create := range tx.Create {
+		for _, annotation := range create.StringAnnotations {
+			if uint64(len(annotation.Value)) > maxSize {
+				return fmt.Errorf("create[%d] string annotation %s value is too long: %d bytes (max %d)", i, annotation.Key, len(annotation.Value), maxSize)
+			}
+		}
+	}
+
+	for i, update := range tx.Update {
+		for _, annotation := range update.StringAnnotations {
+			if uint64(len(annotation.Value)) > maxSize {
+				return fmt.Errorf("update[%d] string annotation %s value is too long: %d bytes (max %d)", i, annotation.Key, len(annotation.Value), maxSize)
+			}
+		}
+	}
+
+	return nil
+}
+
+// UnpackLimits are the chain parameters limiting the decoding of a transaction. The
+// zero limits decode every transaction applied successfully: the calldata is truncated
+// at limits.MaxDecompressedSize as before the payload limits fork and may carry the
+// codec prefix of compression.NewReader, the payloads and the annotation values aren't
+// capped, only limits.MaxOperations applies and the typed numerics, the encryption
+// info, the dotted keys, the external content and the conditions are accepted.
+type UnpackLimits struct {
+	// MaxDecompressedSize is the largest decompressed calldata in bytes, a transaction
+	// decompressing to more fails.
+	MaxDecompressedSize uint64
+	// MaxPayloadSize is the largest payload of a create or an update in bytes.
+	MaxPayloadSize uint64
+	// MaxOperations is the largest number of operations of a transaction.
+	MaxOperations uint64
+	// MaxAnnotationValueSize is the largest string annotation value of a create or an
+	// update in bytes, the values aren't capped before the gas schedule fork.
+	MaxAnnotationValueSize uint64
+	// UntypedNumerics only accepts the plain uint64 numeric annotations, the rule
+	// before the typed numerics fork.
+	UntypedNumerics bool
+	// Unencrypted only accepts the payloads without encryption info, the rule before
+	// the encryption fork.
+	Unencrypted bool
+	// FlatKeys only accepts the single identifiers as annotation keys, the rule before
+	// the dotted keys fork.
+	FlatKeys bool
+	// RawBrotli only accepts the raw brotli calldata, without the codec prefix, the
+	// rule before the codecs fork.
+	RawBrotli bool
+	// InlineContent only accepts the payloads carried by the transaction, without
+	// external content, the rule before the external content fork.
+	InlineContent bool
+	// Unconditional only accepts the operations without conditions, the rule before
+	// the conditions fork.
+	Unconditional bool
+}
+
+// UnpackLimitsAt returns the limits of the decoding of the transactions applied at time.
+func UnpackLimitsAt(config *params.ChainConfig, time uint64) UnpackLimits {
+	return UnpackLimits{
+		MaxDecompressedSize:    config.ArkivMaxDecompressedSizeAt(time),
+		MaxPayloadSize:         config.ArkivMaxPayloadSizeAt(time),
+		MaxOperations:          config.ArkivMaxOperationsAt(time),
+		MaxAnnotationValueSize: config.ArkivMaxAnnotationValueSizeAt(time),
+		UntypedNumerics:        !config.IsArkivTypedNumerics(time),
+		Unencrypted:            !config.IsArkivEncryption(time),
+		FlatKeys:               !config.IsArkivDottedKeys(time),
+		RawBrotli:              !config.IsArkivCodecs(time),
+		InlineContent:          !config.IsArkivExternalContent(time),
+		Unconditional:          !config.IsArkivConditions(time),
+	}
+}
+
+// validateUnpackLimits checks the number of operations and the size of the payloads of
+// the decoded transaction against the limits.
+func (tx *ArkivTransaction) validateUnpackLimits(unpackLimits UnpackLimits) error {
+	if unpackLimits.MaxOperations > 0 {
+		if n := tx.numberOfOperations(); uint64(n) > unpackLimits.MaxOperations {
+			return fmt.Errorf("number of operations is greater than %d: %d operations", unpackLimits.MaxOperations, n)
+		}
+	}
+
+	if unpackLimits.MaxPayloadSize == 0 {
+		return nil
+	}
+	for i, create := range tx.Create {
+		if uint64(len(create.Payload)) > unpackLimits.MaxPayloadSize {
+			return fmt.Errorf("create[%d] payload is too large: %d bytes (max %d)", i, len(create.Payload), unpackLimits.MaxPayloadSize)
+		}
+	}
+	for i, update := range tx.Update {
+		if uint64(len(update.Payload)) > unpackLimits.MaxPayloadSize {
+			return fmt.Errorf("update[%d] payload is too large: %d bytes (max %d)", i, len(update.Payload), unpackLimits.MaxPayloadSize)
+		}
+	}
+	return nil
+}
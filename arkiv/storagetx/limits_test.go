@@ -0,0 +1,177 @@
+package storagetx
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/arkiv/compression"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/stretchr/testify/require"
+)
+
+func packTransaction(t *testing.T, tx *ArkivTransaction) []byte {
+	t.Helper()
+	data, err := rlp.EncodeToBytes(tx)
+	require.NoError(t, err)
+	return compression.MustBrotliCompress(data)
+}
+
+func createWithAnnotationValue(size int) *ArkivTransaction {
+	return &ArkivTransaction{
+		Create: []ArkivCreate{
+			{
+				BTL:         100,
+				ContentType: "text/plain",
+				Payload:     []byte("payload"),
+				StringAnnotations: []StringAnnotation{
+					{Key: "value", Value: strings.Repeat("a", size)},
+				},
+			},
+		},
+	}
+}
+
+var (
+	// testSchedule is the default gas schedule of the chain config.
+	testSchedule = GasScheduleAt(&params.ChainConfig{ArkivGasScheduleTime: new(uint64)}, 0)
+	threshold    = int(params.DefaultArkivAnnotationValueGasThreshold)
+	perByte      = params.DefaultArkivAnnotationValueGasPerByte
+
+	// testValueLimits caps the annotation values at the default of the chain config.
+	testValueLimits = UnpackLimits{MaxAnnotationValueSize: params.DefaultArkivMaxAnnotationValueSize}
+	maxValueSize    = int(params.DefaultArkivMaxAnnotationValueSize)
+)
+
+func TestGas_AtThreshold(t *testing.T) {
+	tx := createWithAnnotationValue(threshold)
+	require.Equal(t, uint64(0), tx.Gas(testSchedule))
+}
+
+func TestGas_AboveThreshold(t *testing.T) {
+	tx := createWithAnnotationValue(threshold + 1)
+	require.Equal(t, perByte, tx.Gas(testSchedule))
+
+	tx = createWithAnnotationValue(threshold + 100)
+	require.Equal(t, 100*perByte, tx.Gas(testSchedule))
+}
+
+func TestGas_BeforeFork(t *testing.T) {
+	// The zero schedule charges nothing
+	tx := createWithAnnotationValue(threshold + 100)
+	require.Zero(t, tx.Gas(GasSchedule{}))
+	require.Equal(t, GasSchedule{}, GasScheduleAt(&params.ChainConfig{}, 0))
+}
+
+func TestGas_UpdateAnnotations(t *testing.T) {
+	tx := &ArkivTransaction{
+		Update: []ArkivUpdate{
+			{
+				EntityKey:   common.HexToHash("0x01"),
+				BTL:         100,
+				ContentType: "text/plain",
+				StringAnnotations: []StringAnnotation{
+					{Key: "a", Value: strings.Repeat("a", threshold+2)},
+					{Key: "b", Value: strings.Repeat("b", threshold+3)},
+				},
+			},
+		},
+	}
+	require.Equal(t, 5*perByte, tx.Gas(testSchedule))
+}
+
+func TestGas_Operations(t *testing.T) {
+	tx := createWithAnnotationValue(threshold + 1)
+	tx.Delete = []common.Hash{common.HexToHash("0x01")}
+	tx.Update = []ArkivUpdate{{
+		EntityKey:         common.HexToHash("0x02"),
+		BTL:               100,
+		ContentType:       "text/plain",
+		StringAnnotations: []StringAnnotation{{Key: "a", Value: strings.Repeat("a", threshold+2)}},
+	}}
+
+	require.Equal(t, []OperationGas{
+		{Operation: "create", Index: 0, Gas: perByte},
+		{Operation: "delete", Index: 0, Gas: 0},
+		{Operation: "update", Index: 0, Gas: 2 * perByte},
+	}, tx.OperationsGas(testSchedule))
+	require.Equal(t, 3*perByte, tx.Gas(testSchedule))
+}
+
+func TestUnpack_AnnotationValueAtHardLimit(t *testing.T) {
+	_, err := UnpackArkivTransactionWithLimits(packTransaction(t, createWithAnnotationValue(maxValueSize)), testValueLimits)
+	require.NoError(t, err)
+}
+
+func TestUnpack_AnnotationValueAboveHardLimit(t *testing.T) {
+	_, err := UnpackArkivTransactionWithLimits(packTransaction(t, createWithAnnotationValue(maxValueSize+1)), testValueLimits)
+	require.ErrorContains(t, err, "create[0] string annotation value value is too long")
+
+	tx := &ArkivTransaction{
+		Update: []ArkivUpdate{
+			{
+				EntityKey:   common.HexToHash("0x01"),
+				BTL:         100,
+				ContentType: "text/plain",
+				StringAnnotations: []StringAnnotation{
+					{Key: "big", Value: strings.Repeat("a", maxValueSize+1)},
+				},
+			},
+		},
+	}
+	_, err = UnpackArkivTransactionWithLimits(packTransaction(t, tx), testValueLimits)
+	require.ErrorContains(t, err, "update[0] string annotation big value is too long")
+
+	// The values aren't capped before the gas schedule fork
+	_, err = UnpackArkivTransaction(packTransaction(t, tx))
+	require.NoError(t, err)
+}
+
+func TestUnpack_PayloadLimits(t *testing.T) {
+	unpackLimits := UnpackLimits{MaxDecompressedSize: 1024, MaxPayloadSize: 16, MaxOperations: 2}
+	create := func(payload int) ArkivCreate {
+		return ArkivCreate{BTL: 100, ContentType: "text/plain", Payload: make([]byte, payload)}
+	}
+
+	_, err := UnpackArkivTransactionWithLimits(packTransaction(t, &ArkivTransaction{Create: []ArkivCreate{create(16)}}), unpackLimits)
+	require.NoError(t, err)
+
+	_, err = UnpackArkivTransactionWithLimits(packTransaction(t, &ArkivTransaction{Create: []ArkivCreate{create(16), create(17)}}), unpackLimits)
+	require.ErrorContains(t, err, "create[1] payload is too large: 17 bytes (max 16)")
+
+	update := &ArkivTransaction{Update: []ArkivUpdate{{EntityKey: common.HexToHash("0x01"), BTL: 100, ContentType: "text/plain", Payload: make([]byte, 17)}}}
+	_, err = UnpackArkivTransactionWithLimits(packTransaction(t, update), unpackLimits)
+	require.ErrorContains(t, err, "update[0] payload is too large")
+
+	_, err = UnpackArkivTransactionWithLimits(packTransaction(t, &ArkivTransaction{Create: []ArkivCreate{create(0), create(0), create(0)}}), unpackLimits)
+	require.ErrorContains(t, err, "number of operations is greater than 2")
+
+	// The calldata decompressing past the limit fails rather than being truncated
+	_, err = UnpackArkivTransactionWithLimits(packTransaction(t, &ArkivTransaction{Create: []ArkivCreate{create(1000)}}), UnpackLimits{MaxDecompressedSize: 1024})
+	require.ErrorIs(t, err, compression.ErrDecompressedTooLarge)
+
+	// The zero limits are the ones before the fork
+	_, err = UnpackArkivTransactionWithLimits(packTransaction(t, &ArkivTransaction{Create: []ArkivCreate{create(1000)}}), UnpackLimits{})
+	require.NoError(t, err)
+}
+
+func TestUnpack_Codecs(t *testing.T) {
+	tx := &ArkivTransaction{Create: []ArkivCreate{{BTL: 100, ContentType: "text/plain", Payload: []byte("hello")}}}
+	data, err := rlp.EncodeToBytes(tx)
+	require.NoError(t, err)
+
+	for _, codec := range []byte{compression.TxCodecBrotli, compression.TxCodecZstd} {
+		compressed := compression.MustCompress(codec, data)
+		unpacked, err := UnpackArkivTransactionWithLimits(compressed, UnpackLimits{})
+		require.NoError(t, err, compression.TxCodecNames[codec])
+		require.Equal(t, tx.Create[0].Payload, unpacked.Create[0].Payload)
+
+		// Before the codecs fork only the raw brotli calldata decodes
+		_, err = UnpackArkivTransactionWithLimits(compressed, UnpackLimits{RawBrotli: true})
+		require.Error(t, err, compression.TxCodecNames[codec])
+	}
+
+	_, err = UnpackArkivTransactionWithLimits(compression.MustBrotliCompress(data), UnpackLimits{RawBrotli: true})
+	require.NoError(t, err)
+}
@@ -0,0 +1,193 @@
+package storagetx
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/arkiv/limits"
+)
+
+const (
+	// MaxFixedPointDecimals is the maximum number of decimals of a fixed-point annotation.
+	MaxFixedPointDecimals = limits.MaxFixedPointDecimals
+
+	// NumericTypeAttributePrefix prefixes the synthetic string attribute that carries the type
+	// of a typed numeric annotation in the events and query results, e.g. `$type_price = "fixed:2"`.
+	// Annotation keys can't start with `$`, so the attribute can't collide with user annotations.
+	NumericTypeAttributePrefix = "$type_"
+)
+
+// NumericType is the type of the value of a numeric annotation.
+type NumericType uint8
+
+const (
+	// NumericTypeUint64 is an unsigned 64 bit integer, the default.
+	NumericTypeUint64 NumericType = iota
+	// NumericTypeInt64 is a signed 64 bit integer stored as two's complement in Value.
+	NumericTypeInt64
+	// NumericTypeFixed is a signed fixed-point number. Value holds the two's complement of the
+	// number multiplied by 10^Decimals, e.g. -1.25 with 2 decimals is stored as -125.
+	NumericTypeFixed
+)
+
+func (t NumericType) String() string {
+	switch t {
+	case NumericTypeUint64:
+		return "uint64"
+	case NumericTypeInt64:
+		return "int64"
+	case NumericTypeFixed:
+		return "fixed"
+	default:
+		return fmt.Sprintf("unknown(%d)", uint8(t))
+	}
+}
+
+// EncodeSortableInt64 maps a signed value onto the unsigned range preserving its order,
+// math.MinInt64 maps to 0, -1 to 2^63-1, 0 to 2^63 and math.MaxInt64 to 2^64-1.
+// Clients use it to build range queries over signed and fixed-point annotations.
+func EncodeSortableInt64(v int64) uint64 {
+	return uint64(v) ^ (1 << 63)
+}
+
+// DecodeSortableInt64 is the inverse of EncodeSortableInt64.
+func DecodeSortableInt64(v uint64) int64 {
+	return int64(v ^ (1 << 63))
+}
+
+// Int64 returns the signed value of an int64 or fixed-point annotation.
+func (a NumericAnnotation) Int64() int64 {
+	return int64(a.Value)
+}
+
+// IndexValue returns the value under which the annotation is indexed in the store.
+// Unsigned values are indexed as is, signed and fixed-point values are indexed with
+// EncodeSortableInt64 so that range queries order them by their numeric value.
+// Fixed-point values only order properly against values with the same number of decimals.
+func (a NumericAnnotation) IndexValue() uint64 {
+	switch a.Type {
+	case NumericTypeInt64, NumericTypeFixed:
+		return EncodeSortableInt64(a.Int64())
+	default:
+		return a.Value
+	}
+}
+
+// TypeName returns the name of the annotation type as exposed in the events and query results,
+// fixed-point types include the number of decimals, e.g. "fixed:2".
+func (a NumericAnnotation) TypeName() string {
+	if a.Type == NumericTypeFixed {
+		return fmt.Sprintf("%s:%d", a.Type, a.Decimals)
+	}
+	return a.Type.String()
+}
+
+// FormatValue returns the decimal representation of the annotation value.
+func (a NumericAnnotation) FormatValue() string {
+	switch a.Type {
+	case NumericTypeInt64:
+		return strconv.FormatInt(a.Int64(), 10)
+	case NumericTypeFixed:
+		return formatFixed(a.Int64(), a.Decimals)
+	default:
+		return strconv.FormatUint(a.Value, 10)
+	}
+}
+
+func formatFixed(v int64, decimals uint8) string {
+	sign := ""
+	abs := uint64(v)
+	if v < 0 {
+		sign = "-"
+		abs = uint64(-v) // math.MinInt64 wraps to its own absolute value as uint64
+	}
+
+	digits := strconv.FormatUint(abs, 10)
+	if len(digits) <= int(decimals) {
+		digits = strings.Repeat("0", int(decimals)-len(digits)+1) + digits
+	}
+
+	point := len(digits) - int(decimals)
+	return sign + digits[:point] + "." + digits[point:]
+}
+
+func (a NumericAnnotation) validate() error {
+	switch a.Type {
+	case NumericTypeUint64, NumericTypeInt64:
+		if a.Decimals != 0 {
+			return fmt.Errorf("decimals are only allowed for fixed-point values, got %d for %s", a.Decimals, a.Type)
+		}
+	case NumericTypeFixed:
+		if a.Decimals == 0 || a.Decimals > MaxFixedPointDecimals {
+			return fmt.Errorf("fixed-point decimals must be between 1 and %d, got %d", MaxFixedPointDecimals, a.Decimals)
+		}
+	default:
+		return fmt.Errorf("unknown numeric type %d", uint8(a.Type))
+	}
+	return nil
+}
+
+func (tx *ArkivTransaction) validateNumericAnnotations() error {
+	check := func(op string, i int, annotations []NumericAnnotation) error {
+		for _, annotation := range annotations {
+			if tx.Version < TransactionVersionTypedNumerics && (annotation.Type != NumericTypeUint64 || annotation.Decimals != 0) {
+				return fmt.Errorf("%s[%d] numeric annotation %s: typed numeric annotations require transaction version %d", op, i, annotation.Key, TransactionVersionTypedNumerics)
+			}
+			err := annotation.validate()
+			if err != nil {
+				return fmt.Errorf("%s[%d] numeric annotation %s: %w", op, i, annotation.Key, err)
+			}
+		}
+		return nil
+	}
+
+	for i, create := range tx.Create {
+		err := check("create", i, create.NumericAnnotations)
+		if err != nil {
+			return err
+		}
+	}
+
+	for i, update := range tx.Update {
+		err := check("update", i, update.NumericAnnotations)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateUntypedNumerics rejects the typed numeric annotations if the limits don't
+// accept them, the rule before the typed numerics fork.
+func (tx *ArkivTransaction) validateUntypedNumerics(unpackLimits UnpackLimits) error {
+	if !unpackLimits.UntypedNumerics {
+		return nil
+	}
+
+	check := func(op string, i int, annotations []NumericAnnotation) error {
+		for _, annotation := range annotations {
+			if annotation.Type != NumericTypeUint64 || annotation.Decimals != 0 {
+				return fmt.Errorf("%s[%d] numeric annotation %s: typed numeric annotations are not active", op, i, annotation.Key)
+			}
+		}
+		return nil
+	}
+
+	for i, create := range tx.Create {
+		err := check("create", i, create.NumericAnnotations)
+		if err != nil {
+			return err
+		}
+	}
+
+	for i, update := range tx.Update {
+		err := check("update", i, update.NumericAnnotations)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
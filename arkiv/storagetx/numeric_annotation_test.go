@@ -0,0 +1,195 @@
+package storagetx
+
+import (
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/stretchr/testify/require"
+)
+
+func signed(v int64) NumericAnnotation {
+	return NumericAnnotation{Key: "n", Value: uint64(v), Type: NumericTypeInt64}
+}
+
+func fixed(v int64, decimals uint8) NumericAnnotation {
+	return NumericAnnotation{Key: "n", Value: uint64(v), Type: NumericTypeFixed, Decimals: decimals}
+}
+
+func createWithNumericAnnotations(version uint64, annotations ...NumericAnnotation) *ArkivTransaction {
+	return &ArkivTransaction{
+		Create: []ArkivCreate{
+			{
+				BTL:                100,
+				ContentType:        "text/plain",
+				Payload:            []byte("payload"),
+				NumericAnnotations: annotations,
+			},
+		},
+		Version: version,
+	}
+}
+
+func requireOrdered(t *testing.T, annotations []NumericAnnotation) {
+	t.Helper()
+	for i := 1; i < len(annotations); i++ {
+		require.Less(t, annotations[i-1].IndexValue(), annotations[i].IndexValue(),
+			"%s should be indexed before %s", annotations[i-1].FormatValue(), annotations[i].FormatValue())
+	}
+}
+
+func TestIndexValue_Int64OrderAroundZero(t *testing.T) {
+	requireOrdered(t, []NumericAnnotation{
+		signed(-1000),
+		signed(-2),
+		signed(-1),
+		signed(0),
+		signed(1),
+		signed(2),
+		signed(1000),
+	})
+}
+
+func TestIndexValue_Int64OrderAtSignBoundary(t *testing.T) {
+	requireOrdered(t, []NumericAnnotation{
+		signed(math.MinInt64),
+		signed(math.MinInt64 + 1),
+		signed(-1),
+		signed(0),
+		signed(math.MaxInt64 - 1),
+		signed(math.MaxInt64),
+	})
+
+	require.Equal(t, uint64(0), signed(math.MinInt64).IndexValue())
+	require.Equal(t, uint64(math.MaxInt64), signed(-1).IndexValue())
+	require.Equal(t, uint64(1<<63), signed(0).IndexValue())
+	require.Equal(t, uint64(math.MaxUint64), signed(math.MaxInt64).IndexValue())
+}
+
+func TestIndexValue_FixedOrder(t *testing.T) {
+	requireOrdered(t, []NumericAnnotation{
+		fixed(-150, 2),
+		fixed(-1, 2),
+		fixed(0, 2),
+		fixed(1, 2),
+		fixed(150, 2),
+	})
+}
+
+func TestIndexValue_Uint64Unchanged(t *testing.T) {
+	annotation := NumericAnnotation{Key: "n", Value: math.MaxUint64}
+	require.Equal(t, uint64(math.MaxUint64), annotation.IndexValue())
+}
+
+func TestSortableInt64_RoundTrip(t *testing.T) {
+	values := []int64{math.MinInt64, math.MinInt64 + 1, -1, 0, 1, math.MaxInt64}
+	encoded := make([]uint64, len(values))
+	for i, v := range values {
+		encoded[i] = EncodeSortableInt64(v)
+		require.Equal(t, v, DecodeSortableInt64(encoded[i]))
+	}
+	require.True(t, slices.IsSorted(encoded))
+}
+
+func TestFormatValue(t *testing.T) {
+	require.Equal(t, "18446744073709551615", NumericAnnotation{Value: math.MaxUint64}.FormatValue())
+	require.Equal(t, "-1", signed(-1).FormatValue())
+	require.Equal(t, "-1.25", fixed(-125, 2).FormatValue())
+	require.Equal(t, "0.05", fixed(5, 2).FormatValue())
+	require.Equal(t, "-0.05", fixed(-5, 2).FormatValue())
+	require.Equal(t, "0.00", fixed(0, 2).FormatValue())
+	require.Equal(t, "-9.223372036854775808", fixed(math.MinInt64, 18).FormatValue())
+	require.Equal(t, "fixed:2", fixed(0, 2).TypeName())
+	require.Equal(t, "int64", signed(0).TypeName())
+}
+
+func TestUnpack_TypedNumericAnnotations(t *testing.T) {
+	tx := createWithNumericAnnotations(TransactionVersionTypedNumerics, signed(-5), fixed(-125, 2))
+
+	unpacked, err := UnpackArkivTransaction(packTransaction(t, tx))
+	require.NoError(t, err)
+	require.Equal(t, tx.Create[0].NumericAnnotations, unpacked.Create[0].NumericAnnotations)
+	require.Equal(t, int64(-5), unpacked.Create[0].NumericAnnotations[0].Int64())
+}
+
+func TestUnpack_TypedNumericAnnotationsRequireVersion(t *testing.T) {
+	_, err := UnpackArkivTransaction(packTransaction(t, createWithNumericAnnotations(0, signed(-5))))
+	require.ErrorContains(t, err, "typed numeric annotations require transaction version 1")
+}
+
+func TestUnpack_TypedNumericAnnotationsRequireFork(t *testing.T) {
+	untyped := UnpackLimitsAt(&params.ChainConfig{}, 0)
+
+	data := packTransaction(t, createWithNumericAnnotations(TransactionVersionTypedNumerics, signed(-5)))
+	_, err := UnpackArkivTransactionWithLimits(data, untyped)
+	require.ErrorContains(t, err, "typed numeric annotations are not active")
+	_, err = UnpackArkivTransactionWithLimits(data, UnpackLimitsAt(&params.ChainConfig{ArkivTypedNumericsTime: new(uint64)}, 0))
+	require.NoError(t, err)
+
+	// the plain uint64 annotations are valid before the fork
+	data = packTransaction(t, createWithNumericAnnotations(TransactionVersionTypedNumerics, NumericAnnotation{Key: "n", Value: 5}))
+	_, err = UnpackArkivTransactionWithLimits(data, untyped)
+	require.NoError(t, err)
+}
+
+func TestUnpack_UnsupportedVersion(t *testing.T) {
+	_, err := UnpackArkivTransaction(packTransaction(t, createWithNumericAnnotations(CurrentTransactionVersion+1)))
+	require.ErrorContains(t, err, "unsupported transaction version")
+}
+
+func TestUnpack_InvalidNumericAnnotations(t *testing.T) {
+	_, err := UnpackArkivTransaction(packTransaction(t, createWithNumericAnnotations(TransactionVersionTypedNumerics, fixed(1, 0))))
+	require.ErrorContains(t, err, "fixed-point decimals must be between 1 and 18")
+
+	_, err = UnpackArkivTransaction(packTransaction(t, createWithNumericAnnotations(TransactionVersionTypedNumerics, fixed(1, MaxFixedPointDecimals+1))))
+	require.ErrorContains(t, err, "fixed-point decimals must be between 1 and 18")
+
+	withDecimals := signed(1)
+	withDecimals.Decimals = 2
+	_, err = UnpackArkivTransaction(packTransaction(t, createWithNumericAnnotations(TransactionVersionTypedNumerics, withDecimals)))
+	require.ErrorContains(t, err, "decimals are only allowed for fixed-point values")
+
+	unknown := NumericAnnotation{Key: "n", Type: NumericTypeFixed + 1}
+	_, err = UnpackArkivTransaction(packTransaction(t, createWithNumericAnnotations(TransactionVersionTypedNumerics, unknown)))
+	require.ErrorContains(t, err, "unknown numeric type 3")
+}
+
+func TestEncoding_Version0Unchanged(t *testing.T) {
+	// transactions without typed annotations encode exactly as before the typed annotations were added
+	type legacyNumericAnnotation struct {
+		Key   string
+		Value uint64
+	}
+	type legacyCreate struct {
+		BTL                uint64
+		ContentType        string
+		Payload            []byte
+		StringAnnotations  []StringAnnotation
+		NumericAnnotations []legacyNumericAnnotation
+	}
+	type legacyTransaction struct {
+		Create      []legacyCreate
+		Update      []ArkivUpdate
+		Delete      []struct{}
+		Extend      []ExtendBTL
+		ChangeOwner []ArkivChangeOwner
+	}
+
+	legacy, err := rlp.EncodeToBytes(&legacyTransaction{
+		Create: []legacyCreate{
+			{
+				BTL:                100,
+				ContentType:        "text/plain",
+				Payload:            []byte("payload"),
+				NumericAnnotations: []legacyNumericAnnotation{{Key: "n", Value: 42}},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	current, err := rlp.EncodeToBytes(createWithNumericAnnotations(0, NumericAnnotation{Key: "n", Value: 42}))
+	require.NoError(t, err)
+	require.Equal(t, legacy, current)
+}
@@ -0,0 +1,81 @@
+package storagetx
+
+import (
+	"github.com/ethereum/go-ethereum/arkiv/storageaccounting"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// entityKeys returns the keys of the entities the operations of the transaction apply to.
+func (tx *ArkivTransaction) entityKeys(txHash common.Hash) []common.Hash {
+	keys := make([]common.Hash, 0, len(tx.Create)+len(tx.Update)+len(tx.Delete)+len(tx.Extend)+len(tx.ChangeOwner)+len(tx.AcceptOwnership)+len(tx.ReduceBTL)+len(tx.ChangeOwnerBatch))
+	for opIx, create := range tx.Create {
+		keys = append(keys, entity.DeriveEntityKey(txHash, opIx, create.Payload))
+	}
+	for _, update := range tx.Update {
+		keys = append(keys, update.EntityKey)
+	}
+	keys = append(keys, tx.Delete...)
+	for _, extend := range tx.Extend {
+		keys = append(keys, extend.EntityKey)
+	}
+	for _, changeOwner := range tx.ChangeOwner {
+		keys = append(keys, changeOwner.EntityKey)
+	}
+	keys = append(keys, tx.AcceptOwnership...)
+	for _, reduce := range tx.ReduceBTL {
+		keys = append(keys, reduce.EntityKey)
+	}
+	for _, batch := range tx.ChangeOwnerBatch {
+		keys = append(keys, batch.EntityKeys...)
+	}
+	return keys
+}
+
+// aliasNameHashes returns the name hashes of the aliases the operations of the
+// transaction apply to.
+func (tx *ArkivTransaction) aliasNameHashes() []common.Hash {
+	nameHashes := make([]common.Hash, 0, len(tx.RegisterAlias)+len(tx.TransferAlias))
+	for _, register := range tx.RegisterAlias {
+		nameHashes = append(nameHashes, entity.AliasNameHash(register.Name))
+	}
+	for _, transfer := range tx.TransferAlias {
+		nameHashes = append(nameHashes, entity.AliasNameHash(transfer.Name))
+	}
+	return nameHashes
+}
+
+// ownedSlots are the owner of an entity or an alias and the number of slots it uses.
+type ownedSlots struct {
+	owner common.Address
+	slots uint64
+}
+
+// usedSlotsFunc returns the owner of the entity or the alias with the key and the
+// number of slots it uses, see entity.UsedSlots and entity.UsedAliasSlots.
+type usedSlotsFunc func(access entity.StateAccess, key common.Hash) (common.Address, uint64)
+
+// usedSlotsOf returns the owners of the entities or the aliases and the slots they use.
+func usedSlotsOf(access storageutil.StateAccess, keys []common.Hash, usedSlots usedSlotsFunc) map[common.Hash]ownedSlots {
+	used := make(map[common.Hash]ownedSlots, len(keys))
+	for _, key := range keys {
+		owner, slots := usedSlots(access, key)
+		used[key] = ownedSlots{owner: owner, slots: slots}
+	}
+	return used
+}
+
+// attributeUsedSlots moves the slots the entities or the aliases used before the
+// transaction out of the counters of their previous owners and the slots they use now
+// into the counters of their current owners.
+func attributeUsedSlots(st *storageaccounting.SlotUsageCounter, before map[common.Hash]ownedSlots, usedSlots usedSlotsFunc) {
+	for key, previous := range before {
+		if previous.slots > 0 {
+			st.AddOwnerSlots(previous.owner, -int64(previous.slots))
+		}
+		if owner, slots := usedSlots(st, key); slots > 0 {
+			st.AddOwnerSlots(owner, int64(slots))
+		}
+	}
+}
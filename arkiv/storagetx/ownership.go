@@ -0,0 +1,144 @@
+package storagetx
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/arkiv/address"
+	arkivlogs "github.com/ethereum/go-ethereum/arkiv/logs"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/holiman/uint256"
+)
+
+// validateOwnership checks that immediate ownership changes and acceptances are only
+// carried by transactions of a version supporting them.
+func (tx *ArkivTransaction) validateOwnership() error {
+	if tx.Version >= TransactionVersionOwnership {
+		return nil
+	}
+	for i, changeOwner := range tx.ChangeOwner {
+		if changeOwner.Immediate {
+			return fmt.Errorf("changeOwner[%d] immediate requires transaction version %d", i, TransactionVersionOwnership)
+		}
+	}
+	if len(tx.AcceptOwnership) > 0 {
+		return fmt.Errorf("acceptOwnership requires transaction version %d", TransactionVersionOwnership)
+	}
+	return nil
+}
+
+// validateNewOwner rejects transfers of entities to owners that can't use them.
+func validateNewOwner(newOwner common.Address) error {
+	switch newOwner {
+	case common.Address{}:
+		return fmt.Errorf("new owner is the zero address")
+	case address.ArkivProcessorAddress:
+		return fmt.Errorf("new owner is the Arkiv processor")
+	}
+	return nil
+}
+
+// changeOwner transfers the entity with the key from the sender to newOwner and returns
+// the log of the change. If transferWindow is not 0 and the change isn't immediate, the
+// transfer is proposed to newOwner instead, who has transferWindow blocks to accept it.
+func changeOwner(access storageutil.StateAccess, sender common.Address, key common.Hash, newOwner common.Address, immediate bool, blockNumber uint64, transferWindow uint64) (*types.Log, error) {
+	md, err := entity.GetEntityMetaData(access, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get entity meta data for change owner %s: %w", key.Hex(), err)
+	}
+
+	if md.Owner != sender {
+		return nil, fmt.Errorf("failed to change owner of entity %s: %s is not the owner", key.Hex(), sender.Hex())
+	}
+
+	oldOwner := md.Owner
+
+	if transferWindow > 0 {
+		err = validateNewOwner(newOwner)
+		if err != nil {
+			return nil, fmt.Errorf("failed to change owner of entity %s: %w", key.Hex(), err)
+		}
+
+		if !immediate {
+			pending := entity.PendingOwner{
+				Owner:         newOwner,
+				LapsesAtBlock: blockNumber + transferWindow,
+			}
+			err = entity.StorePendingOwner(access, key, pending)
+			if err != nil {
+				return nil, fmt.Errorf("failed to store pending owner for change owner %s: %w", key.Hex(), err)
+			}
+
+			data := make([]byte, 32)
+			uint256.NewInt(pending.LapsesAtBlock).PutUint256(data)
+
+			return &types.Log{
+				Address: common.Address(address.ArkivProcessorAddress),
+				Topics: []common.Hash{
+					arkivlogs.ArkivEntityOwnershipTransferProposed,
+					key,
+					addressToHash(oldOwner),
+					addressToHash(pending.Owner),
+				},
+				Data:        data,
+				BlockNumber: blockNumber,
+			}, nil
+		}
+
+		// An immediate change cancels the transfer proposed by the previous owner
+		err = entity.DeletePendingOwner(access, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to delete pending owner for change owner %s: %w", key.Hex(), err)
+		}
+	}
+
+	md.Owner = newOwner
+	err = entity.StoreEntityMetaData(access, key, *md)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store entity meta data for change owner %s: %w", key.Hex(), err)
+	}
+
+	return &types.Log{
+		Address: common.Address(address.ArkivProcessorAddress),
+		Topics: []common.Hash{
+			arkivlogs.ArkivEntityOwnerChanged,
+			key,
+			addressToHash(oldOwner),
+			addressToHash(md.Owner),
+		},
+		Data:        []byte{},
+		BlockNumber: blockNumber,
+	}, nil
+}
+
+// validateAliases checks that alias operations are only carried by transactions of a
+// version supporting them.
+func (tx *ArkivTransaction) validateAliases() error {
+	if tx.Version >= TransactionVersionAliases {
+		return nil
+	}
+	if len(tx.RegisterAlias) > 0 || len(tx.TransferAlias) > 0 {
+		return fmt.Errorf("alias operations require transaction version %d", TransactionVersionAliases)
+	}
+	return nil
+}
+
+// validateReduceBTL checks that BTL reductions are only carried by transactions of a
+// version supporting them.
+func (tx *ArkivTransaction) validateReduceBTL() error {
+	if tx.Version < TransactionVersionReduceBTL && len(tx.ReduceBTL) > 0 {
+		return fmt.Errorf("reduceBTL requires transaction version %d", TransactionVersionReduceBTL)
+	}
+	return nil
+}
+
+// validateChangeOwnerBatch checks that batched ownership changes are only carried by
+// transactions of a version supporting them.
+func (tx *ArkivTransaction) validateChangeOwnerBatch() error {
+	if tx.Version < TransactionVersionChangeOwnerBatch && len(tx.ChangeOwnerBatch) > 0 {
+		return fmt.Errorf("changeOwnerBatch requires transaction version %d", TransactionVersionChangeOwnerBatch)
+	}
+	return nil
+}
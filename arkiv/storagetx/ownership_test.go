@@ -0,0 +1,45 @@
+package storagetx
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/arkiv/limits"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnpack_ChangeOwnerBatchRoundTrip(t *testing.T) {
+	tx := &ArkivTransaction{
+		Version: TransactionVersionChangeOwnerBatch,
+		ChangeOwnerBatch: []ArkivChangeOwnerBatch{
+			{EntityKeys: []common.Hash{{0x01}, {0x02}}, NewOwner: common.Address{0x0a}},
+			{EntityKeys: []common.Hash{{0x03}}, NewOwner: common.Address{0x0b}, Immediate: true},
+		},
+	}
+	unpacked, err := UnpackArkivTransaction(packTransaction(t, tx))
+	require.NoError(t, err)
+	require.Equal(t, tx.ChangeOwnerBatch, unpacked.ChangeOwnerBatch)
+
+	// Older transaction versions can't carry the batches
+	tx.Version = TransactionVersionReduceBTL
+	_, err = UnpackArkivTransaction(packTransaction(t, tx))
+	require.ErrorContains(t, err, "changeOwnerBatch requires transaction version")
+}
+
+func TestValidate_ChangeOwnerBatch(t *testing.T) {
+	batch := func(keys ...common.Hash) *ArkivTransaction {
+		return &ArkivTransaction{ChangeOwnerBatch: []ArkivChangeOwnerBatch{{EntityKeys: keys, NewOwner: common.Address{0x0a}}}}
+	}
+
+	require.NoError(t, batch(common.Hash{0x01}, common.Hash{0x02}).Validate())
+	require.ErrorContains(t, batch().Validate(), "has no entity keys")
+	require.ErrorContains(t, batch(common.Hash{0x01}, common.Hash{0x01}).Validate(), "repeats entity")
+
+	// Every entity of a batch counts as an operation
+	keys := make([]common.Hash, limits.MaxOperations+1)
+	for i := range keys {
+		keys[i] = common.BigToHash(big.NewInt(int64(i)))
+	}
+	require.ErrorContains(t, batch(keys...).Validate(), "number of operations")
+}
@@ -0,0 +1,34 @@
+package storagetx
+
+import "github.com/ethereum/go-ethereum/common"
+
+// ReferencedEntityKeys returns the keys of the existing entities the transaction refers
+// to: the entities its operations apply to, the ones its aliases point to and the ones
+// its conditions check. The entities it creates are left out.
+func (tx *ArkivTransaction) ReferencedEntityKeys() []common.Hash {
+	var keys []common.Hash
+	for _, update := range tx.Update {
+		keys = append(keys, update.EntityKey)
+	}
+	keys = append(keys, tx.Delete...)
+	for _, extend := range tx.Extend {
+		keys = append(keys, extend.EntityKey)
+	}
+	for _, changeOwner := range tx.ChangeOwner {
+		keys = append(keys, changeOwner.EntityKey)
+	}
+	keys = append(keys, tx.AcceptOwnership...)
+	for _, register := range tx.RegisterAlias {
+		keys = append(keys, register.EntityKey)
+	}
+	for _, reduce := range tx.ReduceBTL {
+		keys = append(keys, reduce.EntityKey)
+	}
+	for _, batch := range tx.ChangeOwnerBatch {
+		keys = append(keys, batch.EntityKeys...)
+	}
+	for _, condition := range tx.Conditions {
+		keys = append(keys, condition.EntityKey)
+	}
+	return keys
+}
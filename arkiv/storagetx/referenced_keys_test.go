@@ -0,0 +1,26 @@
+package storagetx
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReferencedEntityKeys(t *testing.T) {
+	tx := &ArkivTransaction{
+		Create:           []ArkivCreate{{BTL: 10, Payload: []byte("new")}},
+		Update:           []ArkivUpdate{{EntityKey: common.Hash{0x01}}},
+		Delete:           []common.Hash{{0x02}},
+		Extend:           []ExtendBTL{{EntityKey: common.Hash{0x03}}},
+		ChangeOwner:      []ArkivChangeOwner{{EntityKey: common.Hash{0x04}}},
+		AcceptOwnership:  []common.Hash{{0x05}},
+		RegisterAlias:    []ArkivRegisterAlias{{Name: "alias", EntityKey: common.Hash{0x06}, BTL: 10}},
+		ReduceBTL:        []ArkivReduceBTL{{EntityKey: common.Hash{0x07}, NumberOfBlocks: 1}},
+		ChangeOwnerBatch: []ArkivChangeOwnerBatch{{EntityKeys: []common.Hash{{0x08}, {0x09}}}},
+		Conditions:       []ArkivCondition{{EntityKey: common.Hash{0x0a}}},
+	}
+	// The created entity is left out
+	require.Equal(t, []common.Hash{{0x01}, {0x02}, {0x03}, {0x04}, {0x05}, {0x06}, {0x07}, {0x08}, {0x09}, {0x0a}}, tx.ReferencedEntityKeys())
+	require.Empty(t, (&ArkivTransaction{}).ReferencedEntityKeys())
+}
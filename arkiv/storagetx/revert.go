@@ -0,0 +1,21 @@
+package storagetx
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/holiman/uint256"
+)
+
+// revertSelector is the selector of Error(string), the revert reason of Solidity.
+var revertSelector = crypto.Keccak256([]byte("Error(string)"))[:4]
+
+// RevertReason returns the message of the error ABI encoded as an Error(string) revert
+// reason, so that a transaction failing on it reverts like a contract call.
+func RevertReason(err error) []byte {
+	message := []byte(err.Error())
+	reason := make([]byte, 4+64, 4+64+(len(message)+31)/32*32)
+	copy(reason, revertSelector)
+	uint256.NewInt(32).PutUint256(reason[4:36])
+	uint256.NewInt(uint64(len(message))).PutUint256(reason[36:68])
+	return append(reason, common.RightPadBytes(message, (len(message)+31)/32*32)...)
+}
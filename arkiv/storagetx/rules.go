@@ -0,0 +1,53 @@
+package storagetx
+
+import "github.com/ethereum/go-ethereum/params"
+
+// Rules are the parameters of the chain the Arkiv transactions of a block are decoded,
+// charged and run with, built once per block with RulesAt.
+type Rules struct {
+	// Unpack are the limits of the decoding of the transactions.
+	Unpack UnpackLimits
+	// Gas is the gas schedule charged on top of the intrinsic gas.
+	Gas GasSchedule
+
+	// TombstoneRetention is the number of blocks the tombstone of a deleted entity is
+	// kept, deleted entities leave no tombstone if it's 0.
+	TombstoneRetention uint64
+	// TransferWindow is the number of blocks the new owner of an entity has to accept
+	// an ownership change, the changes are immediate if it's 0.
+	TransferWindow uint64
+	// MaxBTL is the largest number of blocks between the block and the expiry the
+	// operations set, the expiries aren't capped if it's 0.
+	MaxBTL uint64
+	// IdempotencyTTL is the number of blocks the idempotency keys of the creates are
+	// remembered, the creates can't carry keys if it's 0.
+	IdempotencyTTL uint64
+
+	// OwnerSlots counts the slots used by the entities and the aliases of each owner.
+	OwnerSlots bool
+	// ContentHash keeps the content hash of the payload of the stored entities.
+	ContentHash bool
+	// Aliases allows registering and transferring aliases.
+	Aliases bool
+	// ReduceBTL allows reducing the BTL of entities.
+	ReduceBTL bool
+	// ChangeOwnerBatch allows changing the owner of batches of entities.
+	ChangeOwnerBatch bool
+}
+
+// RulesAt returns the rules of the transactions applied at time.
+func RulesAt(config *params.ChainConfig, time uint64) Rules {
+	return Rules{
+		Unpack:             UnpackLimitsAt(config, time),
+		Gas:                GasScheduleAt(config, time),
+		TombstoneRetention: config.ArkivTombstoneRetentionAt(time),
+		TransferWindow:     config.ArkivOwnershipTransferWindowAt(time),
+		MaxBTL:             config.ArkivMaxBTLAt(time),
+		IdempotencyTTL:     config.ArkivIdempotencyTTLAt(time),
+		OwnerSlots:         config.IsArkivOwnerSlots(time),
+		ContentHash:        config.IsArkivContentHash(time),
+		Aliases:            config.IsArkivAliases(time),
+		ReduceBTL:          config.IsArkivReduceBTL(time),
+		ChangeOwnerBatch:   config.IsArkivChangeOwnerBatch(time),
+	}
+}
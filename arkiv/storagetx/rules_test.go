@@ -0,0 +1,27 @@
+package storagetx
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRulesAt(t *testing.T) {
+	activation := uint64(100)
+	config := &params.ChainConfig{ArkivTombstonesTime: &activation, ArkivAliasesTime: &activation, ArkivConditionsTime: &activation}
+
+	before := RulesAt(config, 99)
+	require.Zero(t, before.TombstoneRetention)
+	require.False(t, before.Aliases)
+	require.True(t, before.Unpack.Unconditional)
+
+	after := RulesAt(config, 100)
+	require.Equal(t, params.DefaultArkivTombstoneRetention, after.TombstoneRetention)
+	require.True(t, after.Aliases)
+	require.False(t, after.Unpack.Unconditional)
+	require.False(t, after.ReduceBTL)
+
+	// Before every fork only the decoding has rules
+	require.Equal(t, RulesAt(&params.ChainConfig{}, 0), Rules{Unpack: UnpackLimitsAt(&params.ChainConfig{}, 0)})
+}
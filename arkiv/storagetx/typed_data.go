@@ -0,0 +1,369 @@
+package storagetx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/arkiv/address"
+	"github.com/ethereum/go-ethereum/arkiv/compression"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+const (
+	// TypedDataName and TypedDataVersion are the name and the version of the EIP-712
+	// domain of the Arkiv operations, whose verifying contract is the processor.
+	TypedDataName    = "Arkiv"
+	TypedDataVersion = "1"
+
+	// TypedDataPrimaryType is the EIP-712 type of an ArkivOperation.
+	TypedDataPrimaryType = "ArkivOperation"
+)
+
+// ArkivOperation is an Arkiv transaction signed as EIP-712 typed data, so that wallets
+// show its operations instead of the compressed calldata, and relayed by another
+// account. Nonce tells apart the operations of a signer carrying the same transaction,
+// and Deadline is the time in seconds after which the operation can't be relayed.
+type ArkivOperation struct {
+	Transaction ArkivTransaction `json:"transaction"`
+	Nonce       uint64           `json:"nonce"`
+	Deadline    uint64           `json:"deadline"`
+}
+
+var (
+	typeHash        = reflect.TypeFor[common.Hash]()
+	typeAddress     = reflect.TypeFor[common.Address]()
+	typeOperation   = reflect.TypeFor[ArkivOperation]()
+	typedDataDomain = apitypes.Types{
+		"EIP712Domain": {
+			{Name: "name", Type: "string"},
+			{Name: "version", Type: "string"},
+			{Name: "chainId", Type: "uint256"},
+			{Name: "verifyingContract", Type: "address"},
+		},
+	}
+)
+
+// TypedDataDomain returns the EIP-712 domain of the Arkiv operations of the chain.
+func TypedDataDomain(chainID *big.Int) apitypes.TypedDataDomain {
+	return apitypes.TypedDataDomain{
+		Name:              TypedDataName,
+		Version:           TypedDataVersion,
+		ChainId:           (*math.HexOrDecimal256)(chainID),
+		VerifyingContract: address.ArkivProcessorAddress.Hex(),
+	}
+}
+
+// TypedDataTypes returns the EIP-712 types of an ArkivOperation. They follow the
+// structures of the transaction: every structure is a type named after it, whose
+// fields are named after their JSON names, in the order they are declared. The
+// unsigned integers keep their size, byte slices are bytes, hashes bytes32 and the
+// optional structures arrays of at most one element. An optional hash is bytes32, the
+// zero hash standing for none.
+func TypedDataTypes() apitypes.Types {
+	types := apitypes.Types{}
+	for name, fields := range typedDataDomain {
+		types[name] = fields
+	}
+	typedDataType(typeOperation, types)
+	return types
+}
+
+// typedDataType returns the EIP-712 type of the Go type, adding the structures it
+// refers to to types.
+func typedDataType(t reflect.Type, types apitypes.Types) string {
+	switch {
+	case t == typeHash:
+		return "bytes32"
+	case t == typeAddress:
+		return "address"
+	case t.Kind() == reflect.Pointer && t.Elem() == typeHash:
+		return "bytes32"
+	case t.Kind() == reflect.Pointer:
+		return typedDataType(t.Elem(), types) + "[]"
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		return "bytes"
+	case t.Kind() == reflect.Slice:
+		return typedDataType(t.Elem(), types) + "[]"
+	case t.Kind() == reflect.String:
+		return "string"
+	case t.Kind() == reflect.Bool:
+		return "bool"
+	case t.Kind() >= reflect.Uint8 && t.Kind() <= reflect.Uint64:
+		return "uint" + strconv.Itoa(t.Bits())
+	case t.Kind() == reflect.Struct:
+		if _, ok := types[t.Name()]; !ok {
+			types[t.Name()] = nil
+			fields := []apitypes.Type{}
+			for i := range t.NumField() {
+				field := t.Field(i)
+				fields = append(fields, apitypes.Type{Name: typedDataFieldName(field), Type: typedDataType(field.Type, types)})
+			}
+			types[t.Name()] = fields
+		}
+		return t.Name()
+	}
+	panic(fmt.Sprintf("no EIP-712 type for %s", t))
+}
+
+// typedDataFieldName returns the JSON name of the field.
+func typedDataFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// TypedData returns the operation as EIP-712 typed data of the chain. The integers of
+// the message are decimal strings and the bytes hex strings.
+func (op *ArkivOperation) TypedData(chainID *big.Int) apitypes.TypedData {
+	return apitypes.TypedData{
+		Types:       TypedDataTypes(),
+		PrimaryType: TypedDataPrimaryType,
+		Domain:      TypedDataDomain(chainID),
+		Message:     typedDataValue(reflect.ValueOf(op).Elem()).(map[string]interface{}),
+	}
+}
+
+// typedDataValue returns the value of the message of the typed data holding v.
+func typedDataValue(v reflect.Value) interface{} {
+	t := v.Type()
+	switch {
+	case t == typeHash || t == typeAddress:
+		return v.Interface().(interface{ Hex() string }).Hex()
+	case t.Kind() == reflect.Pointer && t.Elem() == typeHash:
+		if v.IsNil() {
+			return common.Hash{}.Hex()
+		}
+		return typedDataValue(v.Elem())
+	case t.Kind() == reflect.Pointer:
+		if v.IsNil() {
+			return []interface{}{}
+		}
+		return []interface{}{typedDataValue(v.Elem())}
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		return hexutil.Encode(v.Bytes())
+	case t.Kind() == reflect.Slice:
+		values := make([]interface{}, v.Len())
+		for i := range v.Len() {
+			values[i] = typedDataValue(v.Index(i))
+		}
+		return values
+	case t.Kind() == reflect.String:
+		return v.String()
+	case t.Kind() == reflect.Bool:
+		return v.Bool()
+	case t.Kind() >= reflect.Uint8 && t.Kind() <= reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	case t.Kind() == reflect.Struct:
+		fields := make(map[string]interface{}, t.NumField())
+		for i := range t.NumField() {
+			fields[typedDataFieldName(t.Field(i))] = typedDataValue(v.Field(i))
+		}
+		return fields
+	}
+	panic(fmt.Sprintf("no EIP-712 value for %s", t))
+}
+
+// decodeTypedDataValue sets v from the value of a message of typed data. The integers
+// can be decimal or hex strings, or JSON numbers up to 2^53.
+func decodeTypedDataValue(value interface{}, v reflect.Value, path string) error {
+	t := v.Type()
+	mismatch := func() error {
+		return fmt.Errorf("%s: invalid %s value %v", path, typedDataType(t, apitypes.Types{}), value)
+	}
+	switch {
+	case t == typeHash, t == typeAddress:
+		s, ok := value.(string)
+		if !ok {
+			return mismatch()
+		}
+		b, err := hexutil.Decode(s)
+		if err != nil || len(b) != t.Len() {
+			return mismatch()
+		}
+		reflect.Copy(v, reflect.ValueOf(b))
+	case t.Kind() == reflect.Pointer && t.Elem() == typeHash:
+		var hash common.Hash
+		if err := decodeTypedDataValue(value, reflect.ValueOf(&hash).Elem(), path); err != nil {
+			return err
+		}
+		if hash != (common.Hash{}) {
+			v.Set(reflect.ValueOf(&hash))
+		}
+	case t.Kind() == reflect.Pointer:
+		values, ok := value.([]interface{})
+		if !ok || len(values) > 1 {
+			return fmt.Errorf("%s: expected at most one %s", path, t.Elem().Name())
+		}
+		if len(values) == 1 {
+			v.Set(reflect.New(t.Elem()))
+			return decodeTypedDataValue(values[0], v.Elem(), path+"[0]")
+		}
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		s, ok := value.(string)
+		if !ok {
+			return mismatch()
+		}
+		b, err := hexutil.Decode(s)
+		if err != nil {
+			return mismatch()
+		}
+		v.SetBytes(b)
+	case t.Kind() == reflect.Slice:
+		values, ok := value.([]interface{})
+		if !ok {
+			return mismatch()
+		}
+		if len(values) == 0 {
+			return nil
+		}
+		v.Set(reflect.MakeSlice(t, len(values), len(values)))
+		for i, value := range values {
+			if err := decodeTypedDataValue(value, v.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case t.Kind() == reflect.String:
+		s, ok := value.(string)
+		if !ok {
+			return mismatch()
+		}
+		v.SetString(s)
+	case t.Kind() == reflect.Bool:
+		b, ok := value.(bool)
+		if !ok {
+			return mismatch()
+		}
+		v.SetBool(b)
+	case t.Kind() >= reflect.Uint8 && t.Kind() <= reflect.Uint64:
+		var n uint64
+		switch value := value.(type) {
+		case string:
+			var ok bool
+			if n, ok = math.ParseUint64(value); !ok {
+				return mismatch()
+			}
+		case float64:
+			if value < 0 || value > 1<<53 || value != float64(uint64(value)) {
+				return mismatch()
+			}
+			n = uint64(value)
+		case json.Number:
+			var err error
+			if n, err = strconv.ParseUint(value.String(), 10, 64); err != nil {
+				return mismatch()
+			}
+		default:
+			return mismatch()
+		}
+		if v.OverflowUint(n) {
+			return mismatch()
+		}
+		v.SetUint(n)
+	case t.Kind() == reflect.Struct:
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			return mismatch()
+		}
+		if len(fields) != t.NumField() {
+			return fmt.Errorf("%s: expected the %d fields of %s, got %d", path, t.NumField(), t.Name(), len(fields))
+		}
+		for i := range t.NumField() {
+			name := typedDataFieldName(t.Field(i))
+			field, ok := fields[name]
+			if !ok {
+				return fmt.Errorf("%s: missing field %s of %s", path, name, t.Name())
+			}
+			if err := decodeTypedDataValue(field, v.Field(i), path+"."+name); err != nil {
+				return err
+			}
+		}
+	default:
+		return mismatch()
+	}
+	return nil
+}
+
+// ArkivOperationFromTypedData decodes the operation of typed data, which must carry
+// the domain of the chain and the types of TypedDataTypes.
+func ArkivOperationFromTypedData(typedData apitypes.TypedData, chainID *big.Int) (*ArkivOperation, error) {
+	if typedData.PrimaryType != TypedDataPrimaryType {
+		return nil, fmt.Errorf("primary type is %q, not %s", typedData.PrimaryType, TypedDataPrimaryType)
+	}
+	domain := TypedDataDomain(chainID)
+	if typedData.Domain.Name != domain.Name || typedData.Domain.Version != domain.Version ||
+		typedData.Domain.ChainId == nil || (*big.Int)(typedData.Domain.ChainId).Cmp(chainID) != 0 ||
+		!strings.EqualFold(typedData.Domain.VerifyingContract, domain.VerifyingContract) || typedData.Domain.Salt != "" {
+		return nil, errors.New("typed data domain is not the Arkiv domain of the chain")
+	}
+	if !reflect.DeepEqual(typedData.Types, TypedDataTypes()) {
+		return nil, errors.New("typed data types are not the types of an Arkiv operation")
+	}
+
+	op := &ArkivOperation{}
+	if err := decodeTypedDataValue(map[string]interface{}(typedData.Message), reflect.ValueOf(op).Elem(), "message"); err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+// Hash returns the EIP-712 hash of the operation signed for the chain.
+func (op *ArkivOperation) Hash(chainID *big.Int) (common.Hash, error) {
+	hash, _, err := apitypes.TypedDataAndHash(op.TypedData(chainID))
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return common.BytesToHash(hash), nil
+}
+
+// VerifySignedOperation decodes the operation of the typed data and returns it with
+// the account that signed it. The signature is checked against the hash of the
+// operation as decoded, so a message holding anything the operation doesn't carry
+// fails to verify. The V of the signature can be 0 or 1, or 27 or 28 like wallets
+// produce.
+func VerifySignedOperation(typedData apitypes.TypedData, signature []byte, chainID *big.Int) (*ArkivOperation, common.Address, error) {
+	op, err := ArkivOperationFromTypedData(typedData, chainID)
+	if err != nil {
+		return nil, common.Address{}, err
+	}
+	hash, err := op.Hash(chainID)
+	if err != nil {
+		return nil, common.Address{}, err
+	}
+	if len(signature) != crypto.SignatureLength {
+		return nil, common.Address{}, fmt.Errorf("signature is %d bytes, not %d", len(signature), crypto.SignatureLength)
+	}
+	sig := common.CopyBytes(signature)
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+	pub, err := crypto.SigToPub(hash[:], sig)
+	if err != nil {
+		return nil, common.Address{}, fmt.Errorf("invalid signature: %w", err)
+	}
+	return op, crypto.PubkeyToAddress(*pub), nil
+}
+
+// Calldata returns the canonical calldata of the transaction of the operation, its
+// RLP encoding compressed with brotli, once validated.
+func (op *ArkivOperation) Calldata() ([]byte, error) {
+	if err := op.Transaction.Validate(); err != nil {
+		return nil, err
+	}
+	data, err := rlp.EncodeToBytes(&op.Transaction)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode the transaction: %w", err)
+	}
+	return compression.BrotliCompress(data)
+}
@@ -0,0 +1,213 @@
+package storagetx
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/stretchr/testify/require"
+)
+
+var typedDataChainID = big.NewInt(60138453033)
+
+func typedDataOperation() *ArkivOperation {
+	idempotencyKey := common.HexToHash("0x1d")
+	return &ArkivOperation{
+		Transaction: ArkivTransaction{
+			Version: TransactionVersionIdempotency,
+			Create: []ArkivCreate{{
+				BTL:                100,
+				ContentType:        "text/plain",
+				Payload:            []byte("hello"),
+				StringAnnotations:  []StringAnnotation{{Key: "kind", Value: "greeting"}},
+				NumericAnnotations: []NumericAnnotation{{Key: "score", Value: 7}},
+				Encryption:         &EncryptionInfo{Scheme: "age", KeyID: "key", Nonce: []byte{1, 2}},
+				IdempotencyKey:     &idempotencyKey,
+			}},
+			Delete: []common.Hash{common.HexToHash("0xde")},
+			ChangeOwner: []ArkivChangeOwner{{
+				EntityKey: common.HexToHash("0xc0"),
+				NewOwner:  common.HexToAddress("0x0b"),
+			}},
+		},
+		Nonce:    3,
+		Deadline: 1_700_000_000,
+	}
+}
+
+// signTypedData signs the typed data like a wallet, with a V of 27 or 28.
+func signTypedData(t *testing.T, typedData apitypes.TypedData) ([]byte, common.Address) {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	hash, _, err := apitypes.TypedDataAndHash(typedData)
+	require.NoError(t, err)
+	sig, err := crypto.Sign(hash, key)
+	require.NoError(t, err)
+	sig[crypto.RecoveryIDOffset] += 27
+	return sig, crypto.PubkeyToAddress(key.PublicKey)
+}
+
+// reorderJSON returns the JSON document with the keys of its objects in reverse order.
+func reorderJSON(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var value interface{}
+	require.NoError(t, json.Unmarshal(data, &value))
+	var encode func(value interface{}) string
+	encode = func(value interface{}) string {
+		switch value := value.(type) {
+		case map[string]interface{}:
+			keys := make([]string, 0, len(value))
+			for key := range value {
+				keys = append(keys, key)
+			}
+			out := "{"
+			for i := len(keys) - 1; i >= 0; i-- {
+				key, _ := json.Marshal(keys[i])
+				out += string(key) + ":" + encode(value[keys[i]])
+				if i > 0 {
+					out += ","
+				}
+			}
+			return out + "}"
+		case []interface{}:
+			out := "["
+			for i, element := range value {
+				if i > 0 {
+					out += ","
+				}
+				out += encode(element)
+			}
+			return out + "]"
+		default:
+			encoded, _ := json.Marshal(value)
+			return string(encoded)
+		}
+	}
+	return []byte(encode(value))
+}
+
+func TestTypedData_RoundTrip(t *testing.T) {
+	op := typedDataOperation()
+	typedData := op.TypedData(typedDataChainID)
+	sig, signer := signTypedData(t, typedData)
+
+	// The typed data goes through JSON like it does over RPC
+	data, err := json.Marshal(typedData)
+	require.NoError(t, err)
+	var decoded apitypes.TypedData
+	require.NoError(t, json.Unmarshal(data, &decoded))
+
+	verified, from, err := VerifySignedOperation(decoded, sig, typedDataChainID)
+	require.NoError(t, err)
+	require.Equal(t, signer, from)
+	require.Equal(t, op, verified)
+
+	// The calldata is the canonical encoding of the transaction
+	calldata, err := verified.Calldata()
+	require.NoError(t, err)
+	require.Equal(t, packTransaction(t, &op.Transaction), calldata)
+	unpacked, err := UnpackArkivTransaction(calldata)
+	require.NoError(t, err)
+	require.Equal(t, calldata, packTransaction(t, unpacked))
+}
+
+func TestTypedData_HashStableAcrossFieldOrder(t *testing.T) {
+	op := typedDataOperation()
+	hash, err := op.Hash(typedDataChainID)
+	require.NoError(t, err)
+	typedData := op.TypedData(typedDataChainID)
+	sig, signer := signTypedData(t, typedData)
+
+	data, err := json.Marshal(typedData)
+	require.NoError(t, err)
+	reordered := reorderJSON(t, data)
+	require.NotEqual(t, data, reordered)
+	var decoded apitypes.TypedData
+	require.NoError(t, json.Unmarshal(reordered, &decoded))
+
+	verified, from, err := VerifySignedOperation(decoded, sig, typedDataChainID)
+	require.NoError(t, err)
+	require.Equal(t, signer, from)
+	reorderedHash, err := verified.Hash(typedDataChainID)
+	require.NoError(t, err)
+	require.Equal(t, hash, reorderedHash)
+
+	// The hash of the generic EIP-712 encoder of the reordered document agrees
+	genericHash, _, err := apitypes.TypedDataAndHash(decoded)
+	require.NoError(t, err)
+	require.Equal(t, hash[:], genericHash)
+}
+
+func TestTypedData_NumbersAsJSONNumbers(t *testing.T) {
+	op := typedDataOperation()
+	typedData := op.TypedData(typedDataChainID)
+	sig, signer := signTypedData(t, typedData)
+
+	typedData.Message["nonce"] = float64(3)
+	typedData.Message["deadline"] = json.Number("1700000000")
+	_, from, err := VerifySignedOperation(typedData, sig, typedDataChainID)
+	require.NoError(t, err)
+	require.Equal(t, signer, from)
+}
+
+func TestTypedData_TamperedMessage(t *testing.T) {
+	typedData := typedDataOperation().TypedData(typedDataChainID)
+	sig, signer := signTypedData(t, typedData)
+
+	typedData.Message["nonce"] = "4"
+	_, from, err := VerifySignedOperation(typedData, sig, typedDataChainID)
+	require.NoError(t, err)
+	require.NotEqual(t, signer, from)
+}
+
+func TestTypedData_Rejected(t *testing.T) {
+	sig := make([]byte, crypto.SignatureLength)
+	for name, tamper := range map[string]func(*apitypes.TypedData){
+		"other chain":        func(td *apitypes.TypedData) { td.Domain = TypedDataDomain(big.NewInt(1)) },
+		"other contract":     func(td *apitypes.TypedData) { td.Domain.VerifyingContract = common.Address{}.Hex() },
+		"other primary type": func(td *apitypes.TypedData) { td.PrimaryType = "ArkivTransaction" },
+		"other types": func(td *apitypes.TypedData) {
+			td.Types["ArkivOperation"] = td.Types["ArkivOperation"][:2]
+		},
+		"unknown field": func(td *apitypes.TypedData) { td.Message["extra"] = "1" },
+		"missing field": func(td *apitypes.TypedData) { delete(td.Message, "deadline") },
+		"negative":      func(td *apitypes.TypedData) { td.Message["nonce"] = float64(-1) },
+		"overflow": func(td *apitypes.TypedData) {
+			td.Message["transaction"].(map[string]interface{})["create"].([]interface{})[0].(map[string]interface{})["numericAnnotations"].([]interface{})[0].(map[string]interface{})["decimals"] = "256"
+		},
+		"two encryptions": func(td *apitypes.TypedData) {
+			create := td.Message["transaction"].(map[string]interface{})["create"].([]interface{})[0].(map[string]interface{})
+			encryption := create["encryption"].([]interface{})
+			create["encryption"] = append(encryption, encryption[0])
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			typedData := typedDataOperation().TypedData(typedDataChainID)
+			tamper(&typedData)
+			_, _, err := VerifySignedOperation(typedData, sig, typedDataChainID)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestTypedData_Types(t *testing.T) {
+	types := TypedDataTypes()
+	require.Equal(t, []apitypes.Type{
+		{Name: "transaction", Type: "ArkivTransaction"},
+		{Name: "nonce", Type: "uint64"},
+		{Name: "deadline", Type: "uint64"},
+	}, types["ArkivOperation"])
+	require.Contains(t, types["ArkivCreate"], apitypes.Type{Name: "encryption", Type: "EncryptionInfo[]"})
+	require.Contains(t, types["ArkivCreate"], apitypes.Type{Name: "idempotencyKey", Type: "bytes32"})
+	require.Contains(t, types["NumericAnnotation"], apitypes.Type{Name: "type", Type: "uint8"})
+	require.Contains(t, types["ArkivTransaction"], apitypes.Type{Name: "delete", Type: "bytes32[]"})
+
+	// The domain and the types are valid EIP-712
+	typedData := typedDataOperation().TypedData(typedDataChainID)
+	_, _, err := apitypes.TypedDataAndHash(typedData)
+	require.NoError(t, err)
+}
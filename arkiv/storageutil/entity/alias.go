@@ -0,0 +1,152 @@
+package entity
+
+import (
+	"encoding/binary"
+	"fmt"
+	"regexp"
+
+	"github.com/ethereum/go-ethereum/arkiv/address"
+	"github.com/ethereum/go-ethereum/arkiv/limits"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/keyset"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/holiman/uint256"
+)
+
+var (
+	// AliasSalt is the salt of the slot holding the key of the entity an alias points to.
+	AliasSalt = []byte("arkivAlias")
+	// AliasOwnerSalt is the salt of the slot holding the owner and the expiry of an alias.
+	AliasOwnerSalt = []byte("arkivAliasOwner")
+	// AliasExpirationSalt is the salt of the set of the aliases expiring at a block.
+	AliasExpirationSalt = []byte("arkivAliasesToExpireAtBlock")
+)
+
+// MaxAliasNameLength is the maximum length of the name of an alias in bytes.
+const MaxAliasNameLength = limits.MaxAliasNameLength
+
+// AliasNameRegex is the expression the names of the aliases must match: lowercase
+// letters, digits, dots, dashes and underscores, starting with a letter or a digit.
+const AliasNameRegex = `^[a-z0-9][a-z0-9._-]*$`
+
+var aliasNameRegexCompiled = regexp.MustCompile(AliasNameRegex)
+
+// ValidateAliasName checks that the name is at most MaxAliasNameLength bytes long and
+// matches AliasNameRegex.
+func ValidateAliasName(name string) error {
+	if len(name) > MaxAliasNameLength {
+		return fmt.Errorf("alias name %q is too long (max %d bytes)", name, MaxAliasNameLength)
+	}
+	if !aliasNameRegexCompiled.MatchString(name) {
+		return fmt.Errorf("invalid alias name %q (must match `%s`)", name, AliasNameRegex)
+	}
+	return nil
+}
+
+// AliasNameHash returns the hash the slots of the alias with the name are derived from.
+func AliasNameHash(name string) common.Hash {
+	return crypto.Keccak256Hash([]byte(name))
+}
+
+// Alias is a name pointing to an entity. It belongs to the account that registered it,
+// or the one it was transferred to, and expires at ExpiresAtBlock whether the entity is
+// still live or not.
+type Alias struct {
+	Key            common.Hash    `json:"key"`
+	Owner          common.Address `json:"owner"`
+	ExpiresAtBlock uint64         `json:"expiresAtBlock"`
+}
+
+func aliasKey(nameHash common.Hash) common.Hash {
+	return crypto.Keccak256Hash(AliasSalt, nameHash[:])
+}
+
+func aliasOwnerKey(nameHash common.Hash) common.Hash {
+	return crypto.Keccak256Hash(AliasOwnerSalt, nameHash[:])
+}
+
+func aliasExpirationKey(blockNumber uint64) common.Hash {
+	return crypto.Keccak256Hash(AliasExpirationSalt, uint256.NewInt(blockNumber).Bytes())
+}
+
+func marshalAliasOwner(alias Alias) common.Hash {
+	bytes := [32]byte{}
+	copy(bytes[:20], alias.Owner[:])
+	binary.BigEndian.PutUint64(bytes[24:], alias.ExpiresAtBlock)
+	return bytes
+}
+
+// StoreAlias records the alias with the name hash and schedules it to expire,
+// replacing the alias already registered under the name if any.
+func StoreAlias(access StateAccess, nameHash common.Hash, alias Alias) error {
+	err := deleteAlias(access, nameHash)
+	if err != nil {
+		return err
+	}
+
+	access.SetState(address.ArkivProcessorAddress, aliasKey(nameHash), alias.Key)
+	access.SetState(address.ArkivProcessorAddress, aliasOwnerKey(nameHash), marshalAliasOwner(alias))
+
+	err = keyset.AddValue(access, aliasExpirationKey(alias.ExpiresAtBlock), nameHash)
+	if err != nil {
+		return fmt.Errorf("failed to add alias to the aliases to expire at block %d: %w", alias.ExpiresAtBlock, err)
+	}
+
+	return nil
+}
+
+// GetAlias returns the alias with the name hash, nil if no alias is registered under
+// the name.
+func GetAlias(access StateAccess, nameHash common.Hash) *Alias {
+	value := access.GetState(address.ArkivProcessorAddress, aliasOwnerKey(nameHash))
+	if value == (common.Hash{}) {
+		return nil
+	}
+
+	return &Alias{
+		Key:            access.GetState(address.ArkivProcessorAddress, aliasKey(nameHash)),
+		Owner:          common.BytesToAddress(value[:20]),
+		ExpiresAtBlock: binary.BigEndian.Uint64(value[24:]),
+	}
+}
+
+// deleteAlias removes the alias with the name hash, if any.
+func deleteAlias(access StateAccess, nameHash common.Hash) error {
+	alias := GetAlias(access, nameHash)
+	if alias == nil {
+		return nil
+	}
+
+	err := keyset.RemoveValue(access, aliasExpirationKey(alias.ExpiresAtBlock), nameHash)
+	if err != nil {
+		return fmt.Errorf("failed to remove alias from the aliases to expire at block %d: %w", alias.ExpiresAtBlock, err)
+	}
+
+	access.SetState(address.ArkivProcessorAddress, aliasKey(nameHash), common.Hash{})
+	access.SetState(address.ArkivProcessorAddress, aliasOwnerKey(nameHash), common.Hash{})
+	return nil
+}
+
+// ExpiredAlias is an alias removed by the housekeeping at the end of its BTL.
+type ExpiredAlias struct {
+	NameHash common.Hash
+	Alias
+}
+
+// ExpireAliases removes the aliases expiring at the block, freeing their slots, and
+// returns them. The entities they point to are left alone.
+func ExpireAliases(access StateAccess, blockNumber uint64) []ExpiredAlias {
+	expirationKey := aliasExpirationKey(blockNumber)
+
+	expired := []ExpiredAlias{}
+	for nameHash := range keyset.Iterate(access, expirationKey) {
+		if alias := GetAlias(access, nameHash); alias != nil {
+			expired = append(expired, ExpiredAlias{NameHash: nameHash, Alias: *alias})
+		}
+		access.SetState(address.ArkivProcessorAddress, aliasKey(nameHash), common.Hash{})
+		access.SetState(address.ArkivProcessorAddress, aliasOwnerKey(nameHash), common.Hash{})
+	}
+	keyset.Clear(access, expirationKey)
+
+	return expired
+}
@@ -0,0 +1,49 @@
+package entity
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/arkiv/limits"
+)
+
+const (
+	// AnnotationKeySeparator separates the segments of hierarchical annotation keys,
+	// such as invoice.customer.region.
+	AnnotationKeySeparator = "."
+
+	// MaxAnnotationKeyDepth is the maximum number of segments of an annotation key.
+	MaxAnnotationKeyDepth = limits.MaxAnnotationKeyDepth
+)
+
+// ValidateAnnotationKey checks that the key is a sequence of at most
+// MaxAnnotationKeyDepth segments separated by AnnotationKeySeparator, each matching
+// AnnotationIdentRegex.
+func ValidateAnnotationKey(key string) error {
+	segments := strings.Split(key, AnnotationKeySeparator)
+	if len(segments) > MaxAnnotationKeyDepth {
+		return fmt.Errorf("annotation key %s has %d segments (max %d)", key, len(segments), MaxAnnotationKeyDepth)
+	}
+	for _, segment := range segments {
+		if !AnnotationIdentRegexCompiled.MatchString(segment) {
+			return fmt.Errorf("invalid annotation key segment %q of %s (must match `%s`)",
+				segment,
+				key,
+				AnnotationIdentRegexCompiled.String(),
+			)
+		}
+	}
+	return nil
+}
+
+// AnnotationKeyPrefixes returns the proper prefixes of a hierarchical annotation key,
+// shortest first: invoice and invoice.customer for invoice.customer.region.
+func AnnotationKeyPrefixes(key string) []string {
+	var prefixes []string
+	for i := range len(key) {
+		if key[i] == AnnotationKeySeparator[0] {
+			prefixes = append(prefixes, key[:i])
+		}
+	}
+	return prefixes
+}
@@ -0,0 +1,329 @@
+package entity_test
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/arkiv/address"
+	"github.com/ethereum/go-ethereum/arkiv/housekeepingtx"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity/entityexpiration"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/triedb"
+	"github.com/stretchr/testify/require"
+)
+
+var writeBaselineFlag = flag.Bool("write-baseline", false, "Overwrite the benchmark baseline in testdata/")
+
+const baselineFile = "testdata/benchmark_baseline.json"
+
+// allocsTolerance is the fraction of allocations per operation above the baseline
+// tolerated by TestAllocationBudget, on top of allocsSlack allocations.
+const (
+	allocsTolerance = 0.1
+	allocsSlack     = 2
+)
+
+// budgetIterations is the benchtime of the benchmarks run by TestAllocationBudget.
+const budgetIterations = "200x"
+
+// benchBackends are the state databases the operations are benchmarked against: a
+// StateDB holding the entities in memory, and one reading them from the snapshot
+// after they were committed.
+var benchBackends = []string{"memory", "snapshot"}
+
+// newBenchState returns a StateDB of the backend on which setup has been applied.
+func newBenchState(tb testing.TB, backend string, setup func(statedb *state.StateDB)) *state.StateDB {
+	tb.Helper()
+
+	disk := rawdb.NewMemoryDatabase()
+	tdb := triedb.NewDatabase(disk, nil)
+	var snaps *snapshot.Tree
+	if backend == "snapshot" {
+		var err error
+		snaps, err = snapshot.New(snapshot.Config{CacheSize: 16}, disk, tdb, types.EmptyRootHash)
+		require.NoError(tb, err)
+	}
+	db := state.NewDatabase(tdb, snaps)
+
+	statedb, err := state.New(types.EmptyRootHash, db)
+	require.NoError(tb, err)
+	statedb.CreateAccount(address.ArkivProcessorAddress)
+	statedb.CreateContract(address.ArkivProcessorAddress)
+	statedb.SetNonce(address.ArkivProcessorAddress, 1, tracing.NonceChangeNewContract)
+	setup(statedb)
+	if backend == "memory" {
+		return statedb
+	}
+
+	root, err := statedb.Commit(0, true, false)
+	require.NoError(tb, err)
+	statedb, err = state.New(root, db)
+	require.NoError(tb, err)
+	return statedb
+}
+
+func benchKey(i int) common.Hash {
+	return crypto.Keccak256Hash(binary.BigEndian.AppendUint64(nil, uint64(i)))
+}
+
+// storeEntities stores the number of entities expiring at the block.
+func storeEntities(tb testing.TB, statedb *state.StateDB, n int, expiresAtBlock uint64) {
+	tb.Helper()
+	for i := range n {
+		err := entity.Store(statedb, benchKey(i), common.Address{}, entity.EntityMetaData{ExpiresAtBlock: expiresAtBlock}, nil, false)
+		require.NoError(tb, err)
+	}
+}
+
+// benchmarks are the benchmarks of the entity state operations, by name.
+func benchmarks(sweepSizes []int) map[string]func(b *testing.B) {
+	bms := map[string]func(b *testing.B){}
+	for _, backend := range benchBackends {
+		bms["Store/"+backend] = func(b *testing.B) { benchmarkStore(b, backend) }
+		bms["Delete/"+backend] = func(b *testing.B) { benchmarkDelete(b, backend) }
+		bms["ExtendBTL/"+backend] = func(b *testing.B) { benchmarkExtendBTL(b, backend) }
+		bms["GetEntityMetaData/"+backend] = func(b *testing.B) { benchmarkGetEntityMetaData(b, backend) }
+		bms["AddToEntitiesToExpireAtBlock/"+backend] = func(b *testing.B) { benchmarkAddToEntitiesToExpire(b, backend) }
+		bms["RemoveFromEntitiesToExpire/"+backend] = func(b *testing.B) { benchmarkRemoveFromEntitiesToExpire(b, backend) }
+		for _, size := range sweepSizes {
+			bms[fmt.Sprintf("HousekeepingSweep/%s/%d", backend, size)] = func(b *testing.B) { benchmarkHousekeepingSweep(b, backend, size) }
+		}
+	}
+	return bms
+}
+
+// sweepSizes are the sizes of the buckets of expiring entities swept by the
+// housekeeping benchmarks.
+var sweepSizes = []int{10, 1_000, 100_000}
+
+func BenchmarkEntityOperations(b *testing.B) {
+	bms := benchmarks(sweepSizes)
+	for _, name := range slices.Sorted(maps.Keys(bms)) {
+		b.Run(name, bms[name])
+	}
+}
+
+func benchmarkStore(b *testing.B, backend string) {
+	statedb := newBenchState(b, backend, func(*state.StateDB) {})
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := range b.N {
+		err := entity.Store(statedb, benchKey(i), common.Address{}, entity.EntityMetaData{ExpiresAtBlock: 100}, nil, false)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func benchmarkDelete(b *testing.B, backend string) {
+	statedb := newBenchState(b, backend, func(statedb *state.StateDB) { storeEntities(b, statedb, b.N, 100) })
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := range b.N {
+		if _, err := entity.Delete(statedb, benchKey(i)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func benchmarkExtendBTL(b *testing.B, backend string) {
+	statedb := newBenchState(b, backend, func(statedb *state.StateDB) { storeEntities(b, statedb, 1, 100) })
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		if _, _, err := entity.ExtendBTL(statedb, benchKey(0), 1, 1, 0); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func benchmarkGetEntityMetaData(b *testing.B, backend string) {
+	const entities = 1_000
+	statedb := newBenchState(b, backend, func(statedb *state.StateDB) { storeEntities(b, statedb, entities, 100) })
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := range b.N {
+		if _, err := entity.GetEntityMetaData(statedb, benchKey(i%entities)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkGetEntityMetaDataBatch compares looking up the metadata of 10k entities
+// one key at a time with looking it up in a single batch.
+func BenchmarkGetEntityMetaDataBatch(b *testing.B) {
+	const entities = 10_000
+	keys := make([]common.Hash, entities)
+	for i := range keys {
+		keys[i] = benchKey(i)
+	}
+	for _, backend := range benchBackends {
+		statedb := newBenchState(b, backend, func(statedb *state.StateDB) { storeEntities(b, statedb, entities, 100) })
+		b.Run("perKey/"+backend, func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				for _, key := range keys {
+					if _, err := entity.GetEntityMetaData(statedb, key); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+		b.Run("batch/"+backend, func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				_, errs := entity.GetEntityMetaDataBatch(statedb, keys)
+				if err := errors.Join(errs...); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func benchmarkAddToEntitiesToExpire(b *testing.B, backend string) {
+	statedb := newBenchState(b, backend, func(*state.StateDB) {})
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := range b.N {
+		if err := entityexpiration.AddToEntitiesToExpireAtBlock(statedb, 100, benchKey(i)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func benchmarkRemoveFromEntitiesToExpire(b *testing.B, backend string) {
+	statedb := newBenchState(b, backend, func(statedb *state.StateDB) {
+		for i := range b.N {
+			require.NoError(b, entityexpiration.AddToEntitiesToExpireAtBlock(statedb, 100, benchKey(i)))
+		}
+	})
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := range b.N {
+		if err := entityexpiration.RemoveFromEntitiesToExpire(statedb, 100, benchKey(i)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// benchmarkHousekeepingSweep measures the housekeeping transaction expiring a bucket
+// of the size, the state is reverted after each sweep.
+func benchmarkHousekeepingSweep(b *testing.B, backend string, size int) {
+	statedb := newBenchState(b, backend, func(statedb *state.StateDB) { storeEntities(b, statedb, size, 100) })
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		b.StopTimer()
+		snapshot := statedb.Snapshot()
+		b.StartTimer()
+
+		expired := 0
+		err := housekeepingtx.ExecuteTransaction(100, common.Hash{}, housekeepingtx.Rules{}, statedb, housekeepingtx.LogAppenderFunc(func(*types.Log) { expired++ }))
+		if err != nil {
+			b.Fatal(err)
+		}
+		if expired != size {
+			b.Fatalf("expired %d entities, want %d", expired, size)
+		}
+
+		b.StopTimer()
+		statedb.RevertToSnapshot(snapshot)
+		b.StartTimer()
+	}
+}
+
+// TestHousekeepingSweepStreamsLogs checks that the housekeeping transaction doesn't
+// keep the logs of the expired entities: streamed to an appender discarding them, the
+// sweep allocates less than when they are collected.
+func TestHousekeepingSweepStreamsLogs(t *testing.T) {
+	statedb := newBenchState(t, "memory", func(statedb *state.StateDB) { storeEntities(t, statedb, 1_000, 100) })
+	sweep := func(logs housekeepingtx.LogAppender) func() {
+		return func() {
+			snapshot := statedb.Snapshot()
+			require.NoError(t, housekeepingtx.ExecuteTransaction(100, common.Hash{}, housekeepingtx.Rules{}, statedb, logs))
+			statedb.RevertToSnapshot(snapshot)
+		}
+	}
+
+	var collected []*types.Log
+	collecting := testing.AllocsPerRun(5, sweep(housekeepingtx.LogAppenderFunc(func(log *types.Log) { collected = append(collected, log) })))
+	streaming := testing.AllocsPerRun(5, sweep(housekeepingtx.LogAppenderFunc(func(*types.Log) {})))
+	require.Less(t, streaming, collecting)
+}
+
+// benchmarkResult is the baseline of a benchmark. Only the allocations are checked,
+// the time is recorded for reference since it depends on the machine.
+type benchmarkResult struct {
+	NsPerOp     int64 `json:"nsPerOp"`
+	AllocsPerOp int64 `json:"allocsPerOp"`
+	BytesPerOp  int64 `json:"bytesPerOp"`
+}
+
+// TestAllocationBudget runs the benchmarks, except the sweep of the largest bucket,
+// and fails if an operation allocates more than its baseline. Run it with
+// -write-baseline to record the current numbers after an intended change.
+func TestAllocationBudget(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping benchmarks in short mode")
+	}
+
+	// A fixed number of iterations keeps the test short, the allocations per
+	// operation don't depend on it
+	benchtime := flag.Lookup("test.benchtime")
+	previous := benchtime.Value.String()
+	require.NoError(t, benchtime.Value.Set(budgetIterations))
+	defer benchtime.Value.Set(previous)
+
+	results := map[string]benchmarkResult{}
+	bms := benchmarks(sweepSizes[:len(sweepSizes)-1])
+	for _, name := range slices.Sorted(maps.Keys(bms)) {
+		r := testing.Benchmark(bms[name])
+		require.NotZero(t, r.N, "benchmark %s failed", name)
+		results[name] = benchmarkResult{
+			NsPerOp:     r.NsPerOp(),
+			AllocsPerOp: r.AllocsPerOp(),
+			BytesPerOp:  r.AllocedBytesPerOp(),
+		}
+	}
+
+	if *writeBaselineFlag {
+		data, err := json.MarshalIndent(results, "", "  ")
+		require.NoError(t, err)
+		require.NoError(t, os.MkdirAll(filepath.Dir(baselineFile), 0o755))
+		require.NoError(t, os.WriteFile(baselineFile, append(data, '\n'), 0o644))
+		return
+	}
+
+	data, err := os.ReadFile(baselineFile)
+	require.NoError(t, err)
+	var baseline map[string]benchmarkResult
+	require.NoError(t, json.Unmarshal(data, &baseline))
+
+	for name, result := range results {
+		expected, ok := baseline[name]
+		if !ok {
+			t.Errorf("%s: no baseline, run the test with -write-baseline", name)
+			continue
+		}
+		budget := int64(float64(expected.AllocsPerOp)*(1+allocsTolerance)) + allocsSlack
+		if result.AllocsPerOp > budget {
+			t.Errorf("%s: %d allocs/op exceed the budget of %d (baseline %d)", name, result.AllocsPerOp, budget, expected.AllocsPerOp)
+		}
+	}
+}
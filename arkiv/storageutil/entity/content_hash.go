@@ -0,0 +1,61 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/arkiv/address"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/holiman/uint256"
+)
+
+var (
+	// ErrNoContentHash is returned when verifying the content of an entity stored
+	// without a content hash, before the content hash fork.
+	ErrNoContentHash = errors.New("entity has no content hash")
+	// ErrContentMismatch is returned when a payload doesn't match the content hash of
+	// the entity.
+	ErrContentMismatch = errors.New("payload doesn't match the content hash of the entity")
+)
+
+// ContentHash returns the content hash of a payload, its keccak256 hash.
+func ContentHash(payload []byte) common.Hash {
+	return crypto.Keccak256Hash(payload)
+}
+
+// ContentHashSlot returns the slot holding the content hash of an entity, the slot
+// following the one of its metadata. The metadata keeps its single slot encoding, so
+// the entities stored before the content hash fork have an empty content hash slot.
+func ContentHashSlot(key common.Hash) common.Hash {
+	slot := crypto.Keccak256Hash(EntityMetaDataSalt, key[:])
+	return new(uint256.Int).AddUint64(new(uint256.Int).SetBytes32(slot[:]), 1).Bytes32()
+}
+
+// StoreContentHash stores the content hash of an entity.
+func StoreContentHash(access StateAccess, key common.Hash, hash common.Hash) {
+	access.SetState(address.ArkivProcessorAddress, ContentHashSlot(key), hash)
+}
+
+// GetContentHash returns the content hash of an entity, zero if the entity doesn't
+// exist or was stored without one.
+func GetContentHash(access StateAccess, key common.Hash) common.Hash {
+	return access.GetState(address.ArkivProcessorAddress, ContentHashSlot(key))
+}
+
+// VerifyEntityContent checks the payload against the content hash of the entity. It
+// fails with ErrNoContentHash if the entity was stored without one, and with
+// ErrContentMismatch if the payload doesn't match it.
+func VerifyEntityContent(access StateAccess, key common.Hash, payload []byte) error {
+	if _, err := GetEntityMetaData(access, key); err != nil {
+		return err
+	}
+	hash := GetContentHash(access, key)
+	if hash == (common.Hash{}) {
+		return fmt.Errorf("%w: %s", ErrNoContentHash, key.Hex())
+	}
+	if ContentHash(payload) != hash {
+		return fmt.Errorf("%w: %s", ErrContentMismatch, key.Hex())
+	}
+	return nil
+}
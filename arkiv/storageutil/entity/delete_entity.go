@@ -7,6 +7,8 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 )
 
+// Delete removes the entity with the key and returns its owner. It fails with an error
+// wrapping ErrEntityNotFound if the key doesn't hold an entity.
 func Delete(access StateAccess, toDelete common.Hash) (common.Address, error) {
 
 	md, err := GetEntityMetaData(access, toDelete)
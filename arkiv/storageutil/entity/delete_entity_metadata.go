@@ -7,8 +7,10 @@ import (
 	"github.com/ethereum/go-ethereum/crypto"
 )
 
+// DeleteEntityMetadata clears the metadata of an entity and its content hash.
 func DeleteEntityMetadata(access storageutil.StateAccess, key common.Hash) {
 
 	hash := crypto.Keccak256Hash(EntityMetaDataSalt, key[:])
 	access.SetState(address.ArkivProcessorAddress, hash, common.Hash{})
+	access.SetState(address.ArkivProcessorAddress, ContentHashSlot(key), common.Hash{})
 }
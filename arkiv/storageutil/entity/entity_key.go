@@ -0,0 +1,17 @@
+package entity
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// DeriveEntityKey returns the key of the entity created by the create at opIndex of
+// the Create operations of the transaction txHash, with the payload: the keccak256
+// hash of the transaction hash, the payload and the index left padded to 32 bytes.
+// The payload is the one of the calldata, empty for a payload stored off chain.
+func DeriveEntityKey(txHash common.Hash, opIndex int, payload []byte) common.Hash {
+	paddedIndex := common.LeftPadBytes(big.NewInt(int64(opIndex)).Bytes(), 32)
+	return crypto.Keccak256Hash(txHash.Bytes(), payload, paddedIndex)
+}
@@ -0,0 +1,14 @@
+package entityexpiration
+
+import (
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/keyset"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/holiman/uint256"
+)
+
+// SizeOfEntitiesToExpireAtBlock returns the number of the entities expiring at the block.
+func SizeOfEntitiesToExpireAtBlock(access StateAccess, blockNumber uint64) uint64 {
+	blockNumberBig := uint256.NewInt(blockNumber)
+	expiredEntityKey := crypto.Keccak256Hash(BlockExpirationSalt, blockNumberBig.Bytes())
+	return keyset.Size(access, expiredEntityKey).Uint64()
+}
@@ -1,6 +1,7 @@
 package entity
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/ethereum/go-ethereum/arkiv/storageutil"
@@ -8,16 +9,69 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 )
 
+// ErrBTLTooLarge is wrapped by the errors of the expiries past the maximum BTL or past
+// the largest block number.
+var ErrBTLTooLarge = errors.New("BTL too large")
+
+// BTLTooLargeError is the error of an expiry more than MaxBTL blocks after BlockNumber,
+// or past the largest block number if Overflow is set.
+type BTLTooLargeError struct {
+	BlockNumber    uint64
+	ExpiresAtBlock uint64
+	MaxBTL         uint64
+	Overflow       bool
+}
+
+func (e *BTLTooLargeError) Error() string {
+	if e.Overflow {
+		return "expiry overflows the largest block number"
+	}
+	return fmt.Sprintf("expiry at block %d is more than the maximum BTL of %d blocks after block %d", e.ExpiresAtBlock, e.MaxBTL, e.BlockNumber)
+}
+
+func (e *BTLTooLargeError) Unwrap() error {
+	return ErrBTLTooLarge
+}
+
+// ExpiresAt returns the block numberOfBlocks blocks after from, the expiry of an entity
+// or an alias set at blockNumber. If maxBTL is not 0, it fails with a *BTLTooLargeError
+// if the expiry overflows or is more than maxBTL blocks after blockNumber. Otherwise the
+// expiry isn't checked, as before the cap.
+func ExpiresAt(blockNumber, from, numberOfBlocks, maxBTL uint64) (uint64, error) {
+	expiresAtBlock := from + numberOfBlocks
+	if maxBTL == 0 {
+		return expiresAtBlock, nil
+	}
+	if expiresAtBlock < from {
+		return 0, &BTLTooLargeError{BlockNumber: blockNumber, MaxBTL: maxBTL, Overflow: true}
+	}
+	if expiresAtBlock > blockNumber && expiresAtBlock-blockNumber > maxBTL {
+		return 0, &BTLTooLargeError{BlockNumber: blockNumber, ExpiresAtBlock: expiresAtBlock, MaxBTL: maxBTL}
+	}
+	return expiresAtBlock, nil
+}
+
+// ExtendBTL extends the BTL of the entity with the key by the number of blocks at
+// blockNumber and returns its previous expiry and its owner. It fails with an error
+// wrapping ErrEntityNotFound if the key doesn't hold an entity, and with a
+// *BTLTooLargeError if the new expiry isn't allowed by maxBTL, see ExpiresAt.
 func ExtendBTL(
 	access storageutil.StateAccess,
 	entityKey common.Hash,
-	numberOfBlocks uint64) (uint64, common.Address, error) {
+	numberOfBlocks uint64,
+	blockNumber uint64,
+	maxBTL uint64) (uint64, common.Address, error) {
 
 	entity, err := GetEntityMetaData(access, entityKey)
 	if err != nil {
 		return 0, common.Address{}, err
 	}
 
+	expiresAtBlock, err := ExpiresAt(blockNumber, entity.ExpiresAtBlock, numberOfBlocks, maxBTL)
+	if err != nil {
+		return 0, common.Address{}, err
+	}
+
 	err = entityexpiration.RemoveFromEntitiesToExpire(access, entity.ExpiresAtBlock, entityKey)
 	if err != nil {
 		return 0, common.Address{}, fmt.Errorf("failed to remove from entities to expire at block %d: %w", entity.ExpiresAtBlock, err)
@@ -25,7 +79,7 @@ func ExtendBTL(
 
 	oldExpiresAtBlock := entity.ExpiresAtBlock
 
-	entity.ExpiresAtBlock += numberOfBlocks
+	entity.ExpiresAtBlock = expiresAtBlock
 
 	err = entityexpiration.AddToEntitiesToExpireAtBlock(access, entity.ExpiresAtBlock, entityKey)
 	if err != nil {
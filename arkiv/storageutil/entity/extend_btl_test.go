@@ -0,0 +1,50 @@
+package entity_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpiresAt(t *testing.T) {
+	// Without a cap the expiry isn't checked
+	expiresAtBlock, err := entity.ExpiresAt(10, math.MaxUint64, 2, 0)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), expiresAtBlock)
+
+	expiresAtBlock, err = entity.ExpiresAt(10, 10, 100, 100)
+	require.NoError(t, err)
+	require.Equal(t, uint64(110), expiresAtBlock)
+
+	_, err = entity.ExpiresAt(10, 10, 101, 100)
+	var tooLarge *entity.BTLTooLargeError
+	require.ErrorAs(t, err, &tooLarge)
+	require.ErrorIs(t, err, entity.ErrBTLTooLarge)
+	require.Equal(t, &entity.BTLTooLargeError{BlockNumber: 10, ExpiresAtBlock: 111, MaxBTL: 100}, tooLarge)
+
+	_, err = entity.ExpiresAt(10, math.MaxUint64-1, 2, math.MaxUint64)
+	require.ErrorAs(t, err, &tooLarge)
+	require.True(t, tooLarge.Overflow)
+}
+
+func TestExtendBTL_MaxBTL(t *testing.T) {
+	statedb := newBenchState(t, "memory", func(statedb *state.StateDB) { storeEntities(t, statedb, 1, 100) })
+
+	oldExpiresAtBlock, _, err := entity.ExtendBTL(statedb, benchKey(0), 50, 60, 100)
+	require.NoError(t, err)
+	require.Equal(t, uint64(100), oldExpiresAtBlock)
+
+	// The entity now expires at block 150, block 161 is more than 100 blocks after block 60
+	_, _, err = entity.ExtendBTL(statedb, benchKey(0), 11, 60, 100)
+	require.ErrorIs(t, err, entity.ErrBTLTooLarge)
+	_, _, err = entity.ExtendBTL(statedb, benchKey(0), math.MaxUint64, 60, 100)
+	require.ErrorIs(t, err, entity.ErrBTLTooLarge)
+
+	// The failed extensions left the entity as it was
+	md, err := entity.GetEntityMetaData(statedb, benchKey(0))
+	require.NoError(t, err)
+	require.Equal(t, uint64(150), md.ExpiresAtBlock)
+}
@@ -1,6 +1,7 @@
 package entity
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/ethereum/go-ethereum/arkiv/address"
@@ -10,11 +11,21 @@ import (
 
 var EntityMetaDataSalt = []byte("arkivEntityMetaData")
 
+// ErrEntityNotFound is returned for the keys that don't hold an entity.
+var ErrEntityNotFound = errors.New("entity not found")
+
+// Exists returns whether the key holds an entity.
+func Exists(access StateAccess, key common.Hash) bool {
+	return access.GetState(address.ArkivProcessorAddress, crypto.Keccak256Hash(EntityMetaDataSalt, key[:])) != (common.Hash{})
+}
+
+// GetEntityMetaData returns the metadata of the entity with the key. It fails with an
+// error wrapping ErrEntityNotFound, and the key, if the key doesn't hold an entity.
 func GetEntityMetaData(access StateAccess, key common.Hash) (*EntityMetaData, error) {
 	value := access.GetState(address.ArkivProcessorAddress, crypto.Keccak256Hash(EntityMetaDataSalt, key[:]))
 
 	if value == (common.Hash{}) {
-		return nil, fmt.Errorf("failed to retrieve entity metadata for key %s", key.Hex())
+		return nil, fmt.Errorf("failed to retrieve entity metadata for key %s: %w", key.Hex(), ErrEntityNotFound)
 	}
 
 	emd := &EntityMetaData{}
@@ -22,3 +33,31 @@ func GetEntityMetaData(access StateAccess, key common.Hash) (*EntityMetaData, er
 
 	return emd, nil
 }
+
+// GetEntityMetaDataBatch returns the metadata of the entities of the keys, in their
+// order. The error of a key that doesn't hold an entity is ErrEntityNotFound, and its
+// metadata nil. The slots of the keys are hashed with the same hasher, and the
+// metadata share a single allocation, which stays alive as long as any of them.
+func GetEntityMetaDataBatch(access StateAccess, keys []common.Hash) ([]*EntityMetaData, []error) {
+	mds := make([]*EntityMetaData, len(keys))
+	errs := make([]error, len(keys))
+	values := make([]EntityMetaData, len(keys))
+
+	hasher := crypto.NewKeccakState()
+	var slot common.Hash
+	for i, key := range keys {
+		hasher.Reset()
+		hasher.Write(EntityMetaDataSalt)
+		hasher.Write(key[:])
+		hasher.Read(slot[:])
+
+		value := access.GetState(address.ArkivProcessorAddress, slot)
+		if value == (common.Hash{}) {
+			errs[i] = ErrEntityNotFound
+			continue
+		}
+		values[i].Unmarshal(value)
+		mds[i] = &values[i]
+	}
+	return mds, errs
+}
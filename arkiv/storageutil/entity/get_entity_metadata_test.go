@@ -0,0 +1,54 @@
+package entity_test
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetEntityMetaDataBatch(t *testing.T) {
+	statedb := newBenchState(t, "memory", func(statedb *state.StateDB) { storeEntities(t, statedb, 3, 100) })
+
+	missing := common.Hash{1}
+	keys := []common.Hash{benchKey(2), missing, benchKey(0), benchKey(2)}
+	mds, errs := entity.GetEntityMetaDataBatch(statedb, keys)
+	require.Len(t, mds, len(keys))
+	require.Len(t, errs, len(keys))
+	for i, key := range keys {
+		md, err := entity.GetEntityMetaData(statedb, key)
+		if key == missing {
+			require.ErrorIs(t, err, entity.ErrEntityNotFound)
+			require.Equal(t, entity.ErrEntityNotFound, errs[i])
+			require.Nil(t, mds[i])
+			continue
+		}
+		require.NoError(t, err)
+		require.NoError(t, errs[i])
+		require.Equal(t, md, mds[i])
+	}
+
+	mds, errs = entity.GetEntityMetaDataBatch(statedb, nil)
+	require.Empty(t, mds)
+	require.Empty(t, errs)
+}
+
+func TestEntityNotFound(t *testing.T) {
+	statedb := newBenchState(t, "memory", func(statedb *state.StateDB) { storeEntities(t, statedb, 1, 100) })
+	missing := common.Hash{1}
+
+	_, err := entity.Delete(statedb, missing)
+	require.ErrorIs(t, err, entity.ErrEntityNotFound)
+	require.ErrorContains(t, err, missing.Hex())
+
+	_, _, err = entity.ExtendBTL(statedb, missing, 10, 1, 0)
+	require.ErrorIs(t, err, entity.ErrEntityNotFound)
+	require.ErrorContains(t, err, missing.Hex())
+
+	_, err = entity.Delete(statedb, benchKey(0))
+	require.NoError(t, err)
+	_, err = entity.Delete(statedb, benchKey(0))
+	require.ErrorIs(t, err, entity.ErrEntityNotFound)
+}
@@ -0,0 +1,65 @@
+package entity
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/arkiv/address"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/keyset"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/holiman/uint256"
+)
+
+var (
+	// IdempotencySalt is the salt of the slot holding the key of the entity created with
+	// an idempotency key.
+	IdempotencySalt = []byte("arkivIdempotency")
+	// IdempotencyExpirationSalt is the salt of the set of the idempotency keys expiring
+	// at a block.
+	IdempotencyExpirationSalt = []byte("arkivIdempotencyKeysToExpireAtBlock")
+)
+
+// IdempotencyHash returns the hash the slot of the idempotency key of the sender is
+// derived from, so that the keys of different senders don't collide.
+func IdempotencyHash(sender common.Address, idempotencyKey common.Hash) common.Hash {
+	return crypto.Keccak256Hash(sender[:], idempotencyKey[:])
+}
+
+func idempotencyKey(hash common.Hash) common.Hash {
+	return crypto.Keccak256Hash(IdempotencySalt, hash[:])
+}
+
+func idempotencyExpirationKey(blockNumber uint64) common.Hash {
+	return crypto.Keccak256Hash(IdempotencyExpirationSalt, uint256.NewInt(blockNumber).Bytes())
+}
+
+// StoreIdempotencyKey records that the entity with the key was created with the
+// idempotency key of the hash, until the housekeeping of the block expiresAtBlock.
+func StoreIdempotencyKey(access StateAccess, hash common.Hash, entityKey common.Hash, expiresAtBlock uint64) error {
+	access.SetState(address.ArkivProcessorAddress, idempotencyKey(hash), entityKey)
+
+	err := keyset.AddValue(access, idempotencyExpirationKey(expiresAtBlock), hash)
+	if err != nil {
+		return fmt.Errorf("failed to add idempotency key to the keys to expire at block %d: %w", expiresAtBlock, err)
+	}
+
+	return nil
+}
+
+// GetIdempotencyKey returns the key of the entity created with the idempotency key of
+// the hash, false if the idempotency key isn't recorded or expired.
+func GetIdempotencyKey(access StateAccess, hash common.Hash) (common.Hash, bool) {
+	entityKey := access.GetState(address.ArkivProcessorAddress, idempotencyKey(hash))
+	return entityKey, entityKey != (common.Hash{})
+}
+
+// ExpireIdempotencyKeys forgets the idempotency keys expiring at the block, freeing
+// their slots. The entities created with them are left alone.
+func ExpireIdempotencyKeys(access StateAccess, blockNumber uint64) {
+	expirationKey := idempotencyExpirationKey(blockNumber)
+
+	for hash := range keyset.Iterate(access, expirationKey) {
+		access.SetState(address.ArkivProcessorAddress, idempotencyKey(hash), common.Hash{})
+	}
+	keyset.Clear(access, expirationKey)
+}
@@ -0,0 +1,117 @@
+package entity
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/arkiv/address"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/keyset"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/holiman/uint256"
+)
+
+var (
+	// PendingOwnerSalt is the salt of the slot holding the pending owner of an entity.
+	PendingOwnerSalt = []byte("arkivEntityPendingOwner")
+	// PendingOwnerLapseSalt is the salt of the set of pending owners lapsing at a block.
+	PendingOwnerLapseSalt = []byte("arkivPendingOwnersToLapseAtBlock")
+)
+
+// PendingOwner is the owner an entity is being transferred to. The transfer takes
+// effect when the pending owner accepts it, and lapses at LapsesAtBlock otherwise.
+type PendingOwner struct {
+	Owner         common.Address `json:"owner"`
+	LapsesAtBlock uint64         `json:"lapsesAtBlock"`
+}
+
+func (p *PendingOwner) Marshal() common.Hash {
+	bytes := [32]byte{}
+	copy(bytes[:20], p.Owner[:])
+	binary.BigEndian.PutUint64(bytes[24:], p.LapsesAtBlock)
+	return bytes
+}
+
+func (p *PendingOwner) Unmarshal(hash common.Hash) {
+	p.Owner = common.BytesToAddress(hash[:20])
+	p.LapsesAtBlock = binary.BigEndian.Uint64(hash[24:])
+}
+
+func pendingOwnerKey(key common.Hash) common.Hash {
+	return crypto.Keccak256Hash(PendingOwnerSalt, key[:])
+}
+
+func pendingOwnerLapseKey(blockNumber uint64) common.Hash {
+	return crypto.Keccak256Hash(PendingOwnerLapseSalt, uint256.NewInt(blockNumber).Bytes())
+}
+
+// StorePendingOwner records the pending owner of an entity and schedules it to lapse,
+// replacing the transfer already pending for the entity if any.
+func StorePendingOwner(access StateAccess, key common.Hash, pending PendingOwner) error {
+	err := DeletePendingOwner(access, key)
+	if err != nil {
+		return err
+	}
+
+	access.SetState(address.ArkivProcessorAddress, pendingOwnerKey(key), pending.Marshal())
+
+	err = keyset.AddValue(access, pendingOwnerLapseKey(pending.LapsesAtBlock), key)
+	if err != nil {
+		return fmt.Errorf("failed to add pending owner to the pending owners to lapse at block %d: %w", pending.LapsesAtBlock, err)
+	}
+
+	return nil
+}
+
+// GetPendingOwner returns the pending owner of an entity, nil if no transfer of the
+// entity is pending.
+func GetPendingOwner(access StateAccess, key common.Hash) *PendingOwner {
+	value := access.GetState(address.ArkivProcessorAddress, pendingOwnerKey(key))
+	if value == (common.Hash{}) {
+		return nil
+	}
+
+	pending := &PendingOwner{}
+	pending.Unmarshal(value)
+	return pending
+}
+
+// DeletePendingOwner cancels the pending transfer of an entity, if any.
+func DeletePendingOwner(access StateAccess, key common.Hash) error {
+	pending := GetPendingOwner(access, key)
+	if pending == nil {
+		return nil
+	}
+
+	err := keyset.RemoveValue(access, pendingOwnerLapseKey(pending.LapsesAtBlock), key)
+	if err != nil {
+		return fmt.Errorf("failed to remove pending owner from the pending owners to lapse at block %d: %w", pending.LapsesAtBlock, err)
+	}
+
+	access.SetState(address.ArkivProcessorAddress, pendingOwnerKey(key), common.Hash{})
+	return nil
+}
+
+// LapsedTransfer is a transfer of an entity its pending owner didn't accept in time.
+type LapsedTransfer struct {
+	Key   common.Hash
+	Owner common.Address
+}
+
+// LapsePendingOwners removes the pending owners scheduled to lapse at the block,
+// freeing their slots, and returns the lapsed transfers.
+func LapsePendingOwners(access StateAccess, blockNumber uint64) []LapsedTransfer {
+	lapseKey := pendingOwnerLapseKey(blockNumber)
+
+	lapsed := []LapsedTransfer{}
+	for key := range keyset.Iterate(access, lapseKey) {
+		slot := pendingOwnerKey(key)
+		pending := &PendingOwner{}
+		pending.Unmarshal(access.GetState(address.ArkivProcessorAddress, slot))
+		access.SetState(address.ArkivProcessorAddress, slot, common.Hash{})
+		lapsed = append(lapsed, LapsedTransfer{Key: key, Owner: pending.Owner})
+	}
+	keyset.Clear(access, lapseKey)
+
+	return lapsed
+}
@@ -0,0 +1,50 @@
+package entity
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/arkiv/storageutil"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity/entityexpiration"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ReduceBTL brings the expiry of the entity with the key forward by the number of
+// blocks at blockNumber and returns its previous expiry and its owner. The entity must
+// still expire after blockNumber: the housekeeping of a block runs before its
+// transactions, so an entity scheduled at blockNumber or earlier would never expire. It
+// fails with an error wrapping ErrEntityNotFound if the key doesn't hold an entity.
+func ReduceBTL(
+	access storageutil.StateAccess,
+	entityKey common.Hash,
+	numberOfBlocks uint64,
+	blockNumber uint64) (uint64, common.Address, error) {
+
+	entity, err := GetEntityMetaData(access, entityKey)
+	if err != nil {
+		return 0, common.Address{}, err
+	}
+
+	oldExpiresAtBlock := entity.ExpiresAtBlock
+	if numberOfBlocks >= oldExpiresAtBlock || oldExpiresAtBlock-numberOfBlocks <= blockNumber {
+		return 0, common.Address{}, fmt.Errorf("reducing the expiry at block %d by %d blocks doesn't leave it after block %d", oldExpiresAtBlock, numberOfBlocks, blockNumber)
+	}
+
+	err = entityexpiration.RemoveFromEntitiesToExpire(access, oldExpiresAtBlock, entityKey)
+	if err != nil {
+		return 0, common.Address{}, fmt.Errorf("failed to remove from entities to expire at block %d: %w", oldExpiresAtBlock, err)
+	}
+
+	entity.ExpiresAtBlock = oldExpiresAtBlock - numberOfBlocks
+
+	err = entityexpiration.AddToEntitiesToExpireAtBlock(access, entity.ExpiresAtBlock, entityKey)
+	if err != nil {
+		return 0, common.Address{}, fmt.Errorf("failed to add to entities to expire at block %d: %w", entity.ExpiresAtBlock, err)
+	}
+
+	err = StoreEntityMetaData(access, entityKey, *entity)
+	if err != nil {
+		return 0, common.Address{}, fmt.Errorf("failed to store entity meta data: %w", err)
+	}
+
+	return oldExpiresAtBlock, entity.Owner, nil
+}
@@ -0,0 +1,38 @@
+package entity_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity/entityexpiration"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReduceBTL(t *testing.T) {
+	statedb := newBenchState(t, "memory", func(statedb *state.StateDB) { storeEntities(t, statedb, 1, 100) })
+
+	oldExpiresAtBlock, _, err := entity.ReduceBTL(statedb, benchKey(0), 40, 50)
+	require.NoError(t, err)
+	require.Equal(t, uint64(100), oldExpiresAtBlock)
+
+	// The entity moved to the bucket of block 60
+	require.Zero(t, entityexpiration.SizeOfEntitiesToExpireAtBlock(statedb, 100))
+	require.Equal(t, uint64(1), entityexpiration.SizeOfEntitiesToExpireAtBlock(statedb, 60))
+
+	// The entity must still expire after the block
+	_, _, err = entity.ReduceBTL(statedb, benchKey(0), 10, 50)
+	require.Error(t, err)
+	_, _, err = entity.ReduceBTL(statedb, benchKey(0), math.MaxUint64, 50)
+	require.Error(t, err)
+
+	_, _, err = entity.ReduceBTL(statedb, benchKey(0), 9, 50)
+	require.NoError(t, err)
+	md, err := entity.GetEntityMetaData(statedb, benchKey(0))
+	require.NoError(t, err)
+	require.Equal(t, uint64(51), md.ExpiresAtBlock)
+
+	_, _, err = entity.ReduceBTL(statedb, benchKey(1), 1, 50)
+	require.ErrorIs(t, err, entity.ErrEntityNotFound)
+}
@@ -1,6 +1,7 @@
 package entity
 
 import (
+	"errors"
 	"fmt"
 	"regexp"
 
@@ -17,19 +18,38 @@ var AnnotationIdentRegexCompiled *regexp.Regexp = regexp.MustCompile(fmt.Sprintf
 
 type StateAccess = storageutil.StateAccess
 
+// ErrEntityExists is returned when storing an entity under a key that already holds one.
+var ErrEntityExists = errors.New("entity already exists")
+
+// Store stores a new entity and schedules its expiry. It fails with ErrEntityExists if
+// the key already holds an entity, overwriting it would leave the entity in the
+// expiration bucket of its previous expiry. Updates delete the previous version of
+// the entity first.
+//
+// If contentHash is set, the ContentHash of the payload is stored next to the
+// metadata, see VerifyEntityContent.
 func Store(
 	access StateAccess,
 	key common.Hash,
 	sender common.Address,
 	emd EntityMetaData,
 	payload []byte,
+	contentHash bool,
 ) error {
 
+	if Exists(access, key) {
+		return fmt.Errorf("%w: %s", ErrEntityExists, key.Hex())
+	}
+
 	err := StoreEntityMetaData(access, key, emd)
 	if err != nil {
 		return fmt.Errorf("failed to store entity meta data: %w", err)
 	}
 
+	if contentHash {
+		StoreContentHash(access, key, ContentHash(payload))
+	}
+
 	err = entityexpiration.AddToEntitiesToExpireAtBlock(access, emd.ExpiresAtBlock, key)
 	if err != nil {
 		return fmt.Errorf("failed to add entity to entities to expire: %w", err)
@@ -0,0 +1,106 @@
+package entity
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/arkiv/address"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/keyset"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/holiman/uint256"
+)
+
+var (
+	// TombstoneSalt is the salt of the slot holding the tombstone of an entity.
+	TombstoneSalt = []byte("arkivEntityTombstone")
+	// TombstoneSweepSalt is the salt of the set of tombstones swept at a block.
+	TombstoneSweepSalt = []byte("arkivTombstonesToSweepAtBlock")
+)
+
+// TombstoneReason is the reason an entity is gone.
+type TombstoneReason uint8
+
+const (
+	TombstoneDeleted TombstoneReason = 1
+	TombstoneExpired TombstoneReason = 2
+)
+
+func (r TombstoneReason) String() string {
+	switch r {
+	case TombstoneDeleted:
+		return "deleted"
+	case TombstoneExpired:
+		return "expired"
+	default:
+		return fmt.Sprintf("unknown(%d)", uint8(r))
+	}
+}
+
+// Tombstone records why and when an entity was removed. It is kept for a number of
+// blocks after the removal and swept by the housekeeping transaction afterwards.
+type Tombstone struct {
+	Reason TombstoneReason `json:"reason"`
+	Block  uint64          `json:"block"`
+}
+
+func (t *Tombstone) Marshal() common.Hash {
+	bytes := [32]byte{}
+	bytes[0] = byte(t.Reason)
+	binary.BigEndian.PutUint64(bytes[24:], t.Block)
+	return bytes
+}
+
+func (t *Tombstone) Unmarshal(hash common.Hash) {
+	t.Reason = TombstoneReason(hash[0])
+	t.Block = binary.BigEndian.Uint64(hash[24:])
+}
+
+func tombstoneSweepKey(blockNumber uint64) common.Hash {
+	return crypto.Keccak256Hash(TombstoneSweepSalt, uint256.NewInt(blockNumber).Bytes())
+}
+
+// StoreTombstone records the tombstone of a removed entity and schedules it to be
+// swept at sweepAtBlock.
+func StoreTombstone(access StateAccess, key common.Hash, tombstone Tombstone, sweepAtBlock uint64) error {
+	access.SetState(
+		address.ArkivProcessorAddress,
+		crypto.Keccak256Hash(TombstoneSalt, key[:]),
+		tombstone.Marshal(),
+	)
+
+	err := keyset.AddValue(access, tombstoneSweepKey(sweepAtBlock), key)
+	if err != nil {
+		return fmt.Errorf("failed to add tombstone to the tombstones to sweep at block %d: %w", sweepAtBlock, err)
+	}
+
+	return nil
+}
+
+// GetTombstone returns the tombstone of an entity, nil if the entity has no tombstone
+// because it's live, it never existed or its tombstone was swept.
+func GetTombstone(access StateAccess, key common.Hash) *Tombstone {
+	value := access.GetState(address.ArkivProcessorAddress, crypto.Keccak256Hash(TombstoneSalt, key[:]))
+	if value == (common.Hash{}) {
+		return nil
+	}
+
+	tombstone := &Tombstone{}
+	tombstone.Unmarshal(value)
+	return tombstone
+}
+
+// SweepTombstones removes the tombstones scheduled to be swept at the block, freeing
+// their slots, and returns the number of swept tombstones.
+func SweepTombstones(access StateAccess, blockNumber uint64) int {
+	sweepKey := tombstoneSweepKey(blockNumber)
+
+	swept := 0
+	for key := range keyset.Iterate(access, sweepKey) {
+		access.SetState(address.ArkivProcessorAddress, crypto.Keccak256Hash(TombstoneSalt, key[:]), common.Hash{})
+		swept++
+	}
+	keyset.Clear(access, sweepKey)
+
+	return swept
+}
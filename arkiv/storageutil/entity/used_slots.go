@@ -0,0 +1,50 @@
+package entity
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	// EntityUsedSlots is the number of slots a live entity uses: its metadata, and its
+	// element and position in the set of the entities expiring at its block.
+	EntityUsedSlots = 3
+	// PendingOwnerUsedSlots is the number of slots a pending transfer adds to its
+	// entity: the pending owner, and its element and position in the set of the
+	// pending owners lapsing at its block.
+	PendingOwnerUsedSlots = 3
+	// ContentHashUsedSlots is the number of slots the content hash adds to its entity.
+	ContentHashUsedSlots = 1
+	// AliasUsedSlots is the number of slots an alias uses: the key of its entity, its
+	// owner and expiry, and its element and position in the set of the aliases
+	// expiring at its block.
+	AliasUsedSlots = 4
+)
+
+// UsedSlots returns the owner of the entity and the number of the slots it uses, 0 if
+// the key doesn't hold an entity. The sizes of the sets are shared by their elements,
+// they aren't counted.
+func UsedSlots(access StateAccess, key common.Hash) (common.Address, uint64) {
+	md, err := GetEntityMetaData(access, key)
+	if err != nil {
+		return common.Address{}, 0
+	}
+
+	slots := uint64(EntityUsedSlots)
+	if GetContentHash(access, key) != (common.Hash{}) {
+		slots += ContentHashUsedSlots
+	}
+	if GetPendingOwner(access, key) != nil {
+		slots += PendingOwnerUsedSlots
+	}
+	return md.Owner, slots
+}
+
+// UsedAliasSlots returns the owner of the alias with the name hash and the number of
+// the slots it uses, 0 if no alias is registered under the name.
+func UsedAliasSlots(access StateAccess, nameHash common.Hash) (common.Address, uint64) {
+	alias := GetAlias(access, nameHash)
+	if alias == nil {
+		return common.Address{}, 0
+	}
+	return alias.Owner, AliasUsedSlots
+}
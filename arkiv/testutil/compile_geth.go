@@ -0,0 +1,35 @@
+package testutil
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// CompileGeth builds the geth binary from the package path into a temporary directory,
+// with the build tags the Arkiv features of the node need. It returns the path of the
+// binary and a function removing it.
+func CompileGeth(gethPackage string) (string, func(), error) {
+	td, err := os.MkdirTemp("", "arkiv-compiled-geth-")
+	if err != nil {
+		return "", func() {}, fmt.Errorf("failed to create temp dir: %w", err)
+	}
+
+	gethBinaryPath := filepath.Join(td, "geth")
+
+	cmd := exec.Command("go", "build", "-tags", "sqlite_fts5", "-o", gethBinaryPath, gethPackage)
+	out := &bytes.Buffer{}
+	cmd.Stdout = out
+	cmd.Stderr = out
+	err = cmd.Run()
+	if err != nil {
+		os.RemoveAll(td)
+		return "", func() {}, fmt.Errorf("failed to compile geth: %w\n%s", err, out.String())
+	}
+
+	return gethBinaryPath, func() {
+		os.RemoveAll(td)
+	}, nil
+}
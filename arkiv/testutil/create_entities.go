@@ -5,62 +5,170 @@ import (
 	"fmt"
 	"math/big"
 
+	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/arkiv/address"
 	"github.com/ethereum/go-ethereum/arkiv/compression"
 	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/rlp"
 )
 
+// defaultStorageTransactionGas is used when the gas of a storage transaction can't be estimated.
+const defaultStorageTransactionGas = 12_800_000
+
+// Codec compresses the RLP encoded storage transaction into the transaction data.
+type Codec func(data []byte) ([]byte, error)
+
+type submitOptions struct {
+	gasLimit  uint64
+	codec     Codec
+	gasTipCap *big.Int
+	gasFeeCap *big.Int
+	signer    *FundedAccount
+}
+
+// SubmitOption configures how SubmitStorageTransaction builds and signs the transaction.
+type SubmitOption func(*submitOptions)
+
+// WithGasLimit sets the gas limit of the transaction instead of estimating it.
+func WithGasLimit(gas uint64) SubmitOption {
+	return func(o *submitOptions) {
+		o.gasLimit = gas
+	}
+}
+
+// WithCodec sets the codec used to compress the transaction data, brotli is used by default.
+func WithCodec(codec Codec) SubmitOption {
+	return func(o *submitOptions) {
+		o.codec = codec
+	}
+}
+
+// WithTxCodec compresses the transaction data with the codec of the compression
+// package, compression.TxCodecBrotli or compression.TxCodecZstd, behind its prefix.
+func WithTxCodec(codec byte) SubmitOption {
+	return WithCodec(func(data []byte) ([]byte, error) {
+		return compression.Compress(codec, data)
+	})
+}
+
+// WithFeeCaps sets the tip and fee caps of the transaction instead of deriving them from the base fee.
+func WithFeeCaps(gasTipCap, gasFeeCap *big.Int) SubmitOption {
+	return func(o *submitOptions) {
+		o.gasTipCap = gasTipCap
+		o.gasFeeCap = gasFeeCap
+	}
+}
+
+// WithSigner signs the transaction with the given account instead of the funded account of the world.
+func WithSigner(signer *FundedAccount) SubmitOption {
+	return func(o *submitOptions) {
+		o.signer = signer
+	}
+}
+
+// SubmitStorageTransaction encodes, signs and sends the storage transaction, returning the hash of the signed transaction.
+// Unless set with options, the gas limit is estimated by the node and the fee caps are derived from the latest base fee.
 func (w *World) SubmitStorageTransaction(
 	ctx context.Context,
 	storageTx *storagetx.ArkivTransaction,
-) error {
+	opts ...SubmitOption,
+) (common.Hash, error) {
+
+	o := &submitOptions{
+		codec:  compression.BrotliCompress,
+		signer: w.FundedAccount,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
 
 	client := w.GethInstance.ETHClient
 
 	chainID, err := client.ChainID(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get chain ID: %w", err)
+		return common.Hash{}, fmt.Errorf("failed to get chain ID: %w", err)
 	}
 
 	// Get the current nonce for the sender address
-	nonce, err := client.PendingNonceAt(ctx, w.FundedAccount.Address)
+	nonce, err := client.PendingNonceAt(ctx, o.signer.Address)
 	if err != nil {
-		return fmt.Errorf("failed to get nonce: %w", err)
+		return common.Hash{}, fmt.Errorf("failed to get nonce: %w", err)
 	}
 
 	// RLP encode the storage transaction
 	rlpData, err := rlp.EncodeToBytes(storageTx)
 	if err != nil {
-		return fmt.Errorf("failed to encode storage transaction: %w", err)
+		return common.Hash{}, fmt.Errorf("failed to encode storage transaction: %w", err)
+	}
+
+	data, err := o.codec(rlpData)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to compress storage transaction: %w", err)
+	}
+
+	header, err := client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to get latest header: %w", err)
+	}
+
+	if o.gasTipCap == nil {
+		o.gasTipCap = big.NewInt(1e9) // 1 Gwei
+	}
+
+	if o.gasFeeCap == nil {
+		o.gasFeeCap = big.NewInt(5e9) // 5 Gwei
+		if header.BaseFee != nil {
+			fromBaseFee := new(big.Int).Add(new(big.Int).Mul(header.BaseFee, big.NewInt(2)), o.gasTipCap)
+			if fromBaseFee.Cmp(o.gasFeeCap) > 0 {
+				o.gasFeeCap = fromBaseFee
+			}
+		}
+	}
+
+	if o.gasLimit == 0 {
+		o.gasLimit, err = client.EstimateGas(ctx, ethereum.CallMsg{
+			From:      o.signer.Address,
+			To:        &address.ArkivProcessorAddress,
+			GasTipCap: o.gasTipCap,
+			GasFeeCap: o.gasFeeCap,
+			Value:     big.NewInt(0),
+			Data:      data,
+		})
+		if err != nil {
+			// fall back to a fixed gas limit that still fits into the block
+			o.gasLimit = min(defaultStorageTransactionGas, header.GasLimit)
+		}
 	}
 
-	// Create UpdateStorageTx instance with the RLP encoded data
 	txdata := &types.DynamicFeeTx{
 		ChainID:    chainID,
 		Nonce:      nonce,
-		GasTipCap:  big.NewInt(1e9), // 1 Gwei
-		GasFeeCap:  big.NewInt(5e9), // 5 Gwei
-		Gas:        12_800_000,
+		GasTipCap:  o.gasTipCap,
+		GasFeeCap:  o.gasFeeCap,
+		Gas:        o.gasLimit,
 		To:         &address.ArkivProcessorAddress,
 		Value:      big.NewInt(0), // No ETH transfer needed
-		Data:       compression.MustBrotliCompress(rlpData),
+		Data:       data,
 		AccessList: types.AccessList{},
 	}
 
 	// Use the London signer since we're using a dynamic fee transaction
 	signer := types.LatestSignerForChainID(chainID)
 
-	// return fmt.Errorf("signer: %#v", signer)
-
 	// Create and sign the transaction
-	signedTx, err := types.SignNewTx(w.FundedAccount.PrivateKey, signer, txdata)
+	signedTx, err := types.SignNewTx(o.signer.PrivateKey, signer, txdata)
 	if err != nil {
-		return fmt.Errorf("failed to sign transaction: %w", err)
+		return common.Hash{}, fmt.Errorf("failed to sign transaction: %w", err)
 	}
 
 	// Send the transaction
-	return client.SendTransaction(ctx, signedTx)
+	err = client.SendTransaction(ctx, signedTx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	return signedTx.Hash(), nil
 
 }
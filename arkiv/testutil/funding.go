@@ -0,0 +1,107 @@
+package testutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/arkiv/logs"
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// NewFundedAccount creates an account funded with amount by the dev faucet of the node,
+// or by a transfer from the preloaded account if the node has no faucet, and waits for
+// the funds to be spendable.
+func (w *World) NewFundedAccount(ctx context.Context, amount *big.Int) (*FundedAccount, error) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate private key: %w", err)
+	}
+	acc := &FundedAccount{
+		PrivateKey: key,
+		Address:    crypto.PubkeyToAddress(key.PublicKey),
+	}
+
+	err = w.GethInstance.RPCClient.CallContext(ctx, nil, "dev_fundAccount", acc.Address, (*hexutil.Big)(amount))
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) && rpcErr.ErrorCode() == -32601 {
+		// The dev namespace is not available, fund the account from the preloaded one
+		return w.GethInstance.transferFunds(ctx, acc, amount)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fund account: %w", err)
+	}
+
+	for {
+		balance, err := w.GethInstance.ETHClient.BalanceAt(ctx, acc.Address, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get balance: %w", err)
+		}
+		if balance.Cmp(amount) >= 0 {
+			return acc, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("timeout waiting for the funds of %s: %w", acc.Address, ctx.Err())
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// NewOwnerWithEntities creates a funded account owning n small entities with the given
+// BTL, created in a single transaction, and returns it with the keys of the entities.
+func (w *World) NewOwnerWithEntities(ctx context.Context, n int, btl uint64) (*FundedAccount, []common.Hash, error) {
+	owner, err := w.NewFundedAccount(ctx, EthToWei(10))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	storageTx := &storagetx.ArkivTransaction{}
+	for i := range n {
+		storageTx.Create = append(storageTx.Create, storagetx.ArkivCreate{
+			BTL:         btl,
+			ContentType: "text/plain",
+			Payload:     fmt.Appendf(nil, "entity %d", i),
+			StringAnnotations: []storagetx.StringAnnotation{
+				{Key: "owner_with_entities", Value: owner.Address.Hex()},
+			},
+			NumericAnnotations: []storagetx.NumericAnnotation{
+				{Key: "index", Value: uint64(i)},
+			},
+		})
+	}
+
+	txHash, err := w.SubmitStorageTransaction(ctx, storageTx, WithSigner(owner))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to submit storage transaction: %w", err)
+	}
+
+	receipt, err := bind.WaitMinedHash(ctx, w.GethInstance.ETHClient, txHash)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to wait for transaction: %w", err)
+	}
+	if receipt.Status == types.ReceiptStatusFailed {
+		return nil, nil, fmt.Errorf("transaction failed")
+	}
+	w.LastReceipt = receipt
+
+	keys := make([]common.Hash, 0, n)
+	for _, l := range receipt.Logs {
+		if len(l.Topics) > 1 && l.Topics[0] == logs.ArkivEntityCreated {
+			keys = append(keys, l.Topics[1])
+		}
+	}
+	if len(keys) != n {
+		return nil, nil, fmt.Errorf("expected %d created entities, got %d", n, len(keys))
+	}
+
+	return owner, keys, nil
+}
@@ -32,6 +32,9 @@ type GethInstance struct {
 	ETHClient   *ethclient.Client
 	RPCClient   *rpc.Client
 	RPCEndpoint string
+
+	// MetricsEndpoint is the URL of the prometheus metrics of the node
+	MetricsEndpoint string
 }
 
 type gethProcess struct {
@@ -44,6 +47,11 @@ type gethProcess struct {
 func startGethInstance(ctx context.Context, gethPath string, tempDir string) (_ *GethInstance, err error) {
 	// Start geth in dev mode
 
+	metricsPort, err := freePort()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find a free port for metrics: %w", err)
+	}
+
 	geth, err := startGethWithPath(
 		ctx,
 		gethPath,
@@ -52,9 +60,14 @@ func startGethInstance(ctx context.Context, gethPath string, tempDir string) (_
 		"--http",           // Enable the HTTP-RPC server
 		"--ipcdisable",     // Disable ipc, to avoid concurrency issues (using the same socket path)
 		"--http.port", "0", // Use random port
-		"--http.api", "eth,web3,net,debug,arkiv", // Enable necessary APIs
+		"--http.api", "eth,web3,net,debug,arkiv,dev", // Enable necessary APIs
 		"--verbosity", "3", // Increase logging to see HTTP endpoint
 		"--golembase.sqlstatefile", filepath.Join(tempDir, "arkiv.db"),
+		"--arkiv.fulltext",       // Enable the full-text index
+		"--arkiv.store.compress", // Keep the payloads compressed in the store
+		"--metrics",              // Enable metrics collection
+		"--metrics.addr", "127.0.0.1",
+		"--metrics.port", strconv.Itoa(metricsPort),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to start geth: %w", err)
@@ -106,11 +119,12 @@ func startGethInstance(ctx context.Context, gethPath string, tempDir string) (_
 	}
 
 	gi := &GethInstance{
-		gethProcess: geth,
-		ETHClient:   client,
-		RPCClient:   rpcClient,
-		RPCEndpoint: endpoint,
-		shutdown:    cleanup,
+		gethProcess:     geth,
+		ETHClient:       client,
+		RPCClient:       rpcClient,
+		RPCEndpoint:     endpoint,
+		MetricsEndpoint: fmt.Sprintf("http://127.0.0.1:%d/debug/metrics/prometheus", metricsPort),
+		shutdown:        cleanup,
 	}
 
 	return gi, nil
@@ -245,9 +259,14 @@ func (g *GethInstance) createAccountAndTransferFunds(ctx context.Context, amount
 
 	acc.Address = crypto.PubkeyToAddress(acc.PrivateKey.PublicKey)
 
+	return g.transferFunds(ctx, acc, amount)
+}
+
+// transferFunds transfers amount from the dev account of the node to the account.
+func (g *GethInstance) transferFunds(ctx context.Context, acc *FundedAccount, amount *big.Int) (*FundedAccount, error) {
 	// Get dev account using eth_accounts RPC call
 	var accounts []common.Address
-	err = g.RPCClient.CallContext(ctx, &accounts, "eth_accounts")
+	err := g.RPCClient.CallContext(ctx, &accounts, "eth_accounts")
 	if err != nil {
 		return nil, fmt.Errorf("failed to get accounts from the node: %w", err)
 	}
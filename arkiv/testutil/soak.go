@@ -0,0 +1,328 @@
+package testutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	arkivlogs "github.com/ethereum/go-ethereum/arkiv/logs"
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"golang.org/x/sync/errgroup"
+)
+
+// SoakConfig configures a soak run of the Arkiv pipeline. The defaults describe a
+// short run, longer runs are configured with the environment variables named in the
+// comments of the fields.
+type SoakConfig struct {
+	// Blocks is the number of blocks the run lasts (ARKIV_SOAK_BLOCKS).
+	Blocks uint64
+	// Duration is the time limit of the run, it ends earlier if it's reached before
+	// the number of blocks (ARKIV_SOAK_DURATION).
+	Duration time.Duration
+	// Workers is the number of accounts submitting transactions concurrently (ARKIV_SOAK_WORKERS).
+	Workers int
+	// InvalidRatio is the share of the transactions that fail validation (ARKIV_SOAK_INVALID_RATIO).
+	InvalidRatio float64
+	// MaxBTL is the maximum BTL of the created entities and of the extensions, short
+	// BTLs make entities expire while the run still operates on them (ARKIV_SOAK_MAX_BTL).
+	MaxBTL uint64
+	// Seed seeds the generators, a run is reproducible up to the scheduling of the
+	// workers (ARKIV_SOAK_SEED).
+	Seed int64
+}
+
+// SoakConfigFromEnv returns the configuration of a soak run from the environment.
+func SoakConfigFromEnv() (SoakConfig, error) {
+	config := SoakConfig{
+		Blocks:       200,
+		Duration:     5 * time.Minute,
+		Workers:      4,
+		InvalidRatio: 0.2,
+		MaxBTL:       20,
+		Seed:         time.Now().UnixNano(),
+	}
+
+	var err error
+	if v := os.Getenv("ARKIV_SOAK_BLOCKS"); v != "" {
+		config.Blocks, err = strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return config, fmt.Errorf("invalid ARKIV_SOAK_BLOCKS: %w", err)
+		}
+	}
+	if v := os.Getenv("ARKIV_SOAK_DURATION"); v != "" {
+		config.Duration, err = time.ParseDuration(v)
+		if err != nil {
+			return config, fmt.Errorf("invalid ARKIV_SOAK_DURATION: %w", err)
+		}
+	}
+	if v := os.Getenv("ARKIV_SOAK_WORKERS"); v != "" {
+		config.Workers, err = strconv.Atoi(v)
+		if err != nil {
+			return config, fmt.Errorf("invalid ARKIV_SOAK_WORKERS: %w", err)
+		}
+	}
+	if v := os.Getenv("ARKIV_SOAK_INVALID_RATIO"); v != "" {
+		config.InvalidRatio, err = strconv.ParseFloat(v, 64)
+		if err != nil {
+			return config, fmt.Errorf("invalid ARKIV_SOAK_INVALID_RATIO: %w", err)
+		}
+	}
+	if v := os.Getenv("ARKIV_SOAK_MAX_BTL"); v != "" {
+		config.MaxBTL, err = strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return config, fmt.Errorf("invalid ARKIV_SOAK_MAX_BTL: %w", err)
+		}
+	}
+	if v := os.Getenv("ARKIV_SOAK_SEED"); v != "" {
+		config.Seed, err = strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return config, fmt.Errorf("invalid ARKIV_SOAK_SEED: %w", err)
+		}
+	}
+
+	switch {
+	case config.Blocks == 0:
+		return config, errors.New("ARKIV_SOAK_BLOCKS must be positive")
+	case config.Workers <= 0:
+		return config, errors.New("ARKIV_SOAK_WORKERS must be positive")
+	case config.InvalidRatio < 0 || config.InvalidRatio > 1:
+		return config, errors.New("ARKIV_SOAK_INVALID_RATIO must be between 0 and 1")
+	case config.MaxBTL == 0:
+		return config, errors.New("ARKIV_SOAK_MAX_BTL must be positive")
+	}
+
+	return config, nil
+}
+
+// SoakGenerator generates random storage transactions for a soak run.
+// Updates, extensions and deletes pick among all the entities created during the run,
+// including the expired and deleted ones, so they race the expiry of the entities and
+// fail on missing ones. A share of the transactions is invalid and must be rejected.
+type SoakGenerator struct {
+	config SoakConfig
+
+	mu   sync.Mutex
+	rand *rand.Rand
+	keys []common.Hash
+}
+
+func NewSoakGenerator(config SoakConfig) *SoakGenerator {
+	return &SoakGenerator{
+		config: config,
+		rand:   rand.New(rand.NewSource(config.Seed)),
+	}
+}
+
+// AddKeys makes the entities available to the operations on existing entities.
+func (g *SoakGenerator) AddKeys(keys ...common.Hash) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.keys = append(g.keys, keys...)
+}
+
+// Next returns a random storage transaction.
+func (g *SoakGenerator) Next() *storagetx.ArkivTransaction {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.rand.Float64() < g.config.InvalidRatio {
+		return g.invalid()
+	}
+
+	if len(g.keys) == 0 {
+		return &storagetx.ArkivTransaction{Create: []storagetx.ArkivCreate{g.create()}}
+	}
+
+	switch n := g.rand.Intn(10); {
+	case n < 4:
+		tx := &storagetx.ArkivTransaction{}
+		for range 1 + g.rand.Intn(3) {
+			tx.Create = append(tx.Create, g.create())
+		}
+		return tx
+	case n < 6:
+		return &storagetx.ArkivTransaction{Extend: []storagetx.ExtendBTL{{
+			EntityKey:      g.key(),
+			NumberOfBlocks: g.btl(),
+		}}}
+	case n < 8:
+		create := g.create()
+		return &storagetx.ArkivTransaction{Update: []storagetx.ArkivUpdate{{
+			EntityKey:         g.key(),
+			ContentType:       create.ContentType,
+			BTL:               create.BTL,
+			Payload:           create.Payload,
+			StringAnnotations: create.StringAnnotations,
+		}}}
+	default:
+		return &storagetx.ArkivTransaction{Delete: []common.Hash{g.key()}}
+	}
+}
+
+func (g *SoakGenerator) btl() uint64 {
+	return 1 + g.rand.Uint64()%g.config.MaxBTL
+}
+
+func (g *SoakGenerator) key() common.Hash {
+	return g.keys[g.rand.Intn(len(g.keys))]
+}
+
+func (g *SoakGenerator) create() storagetx.ArkivCreate {
+	// Half of the payloads are random bytes, the others compressible text
+	contentType := "application/octet-stream"
+	payload := make([]byte, 1+g.rand.Intn(256))
+	g.rand.Read(payload)
+	if g.rand.Intn(2) == 0 {
+		contentType = "text/plain"
+		payload = []byte(strings.Repeat(fmt.Sprintf("soak entity %d ", g.rand.Intn(1000)), 1+g.rand.Intn(32)))
+	}
+	return storagetx.ArkivCreate{
+		BTL:         g.btl(),
+		ContentType: contentType,
+		Payload:     payload,
+		StringAnnotations: []storagetx.StringAnnotation{
+			{Key: "soak", Value: strconv.Itoa(g.rand.Intn(16))},
+		},
+	}
+}
+
+func (g *SoakGenerator) invalid() *storagetx.ArkivTransaction {
+	create := g.create()
+	switch g.rand.Intn(4) {
+	case 0:
+		create.BTL = 0
+	case 1:
+		create.StringAnnotations = append(create.StringAnnotations, create.StringAnnotations[0])
+	case 2:
+		create.StringAnnotations[0].Key = "$owner"
+	default:
+		return &storagetx.ArkivTransaction{Extend: []storagetx.ExtendBTL{{
+			EntityKey: common.BytesToHash(create.Payload),
+		}}}
+	}
+	return &storagetx.ArkivTransaction{Create: []storagetx.ArkivCreate{create}}
+}
+
+// SoakStats counts the outcome of the transactions of a soak run.
+type SoakStats struct {
+	// Rejected transactions were not accepted by the node.
+	Rejected atomic.Uint64
+	// Succeeded and Failed transactions were included in a block.
+	Succeeded atomic.Uint64
+	Failed    atomic.Uint64
+}
+
+func (s *SoakStats) String() string {
+	return fmt.Sprintf("rejected=%d succeeded=%d failed=%d", s.Rejected.Load(), s.Succeeded.Load(), s.Failed.Load())
+}
+
+// FundAccounts creates n accounts funded with amount by the dev account of the node.
+func (w *World) FundAccounts(ctx context.Context, n int, amount *big.Int) ([]*FundedAccount, error) {
+	accounts := make([]*FundedAccount, 0, n)
+	for range n {
+		var acc *FundedAccount
+		var err error
+		for range 10 {
+			acc, err = w.GethInstance.createAccountAndTransferFunds(ctx, amount)
+			if err == nil {
+				break
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to create account and transfer funds: %w", err)
+		}
+		accounts = append(accounts, acc)
+	}
+	return accounts, nil
+}
+
+// RunSoakWorkers submits transactions from the generator with all the accounts
+// concurrently until the chain reaches untilBlock or the context is done. Every
+// account submits its next transaction once the previous one is included, the keys of
+// the created entities are handed back to the generator.
+func (w *World) RunSoakWorkers(
+	ctx context.Context,
+	generator *SoakGenerator,
+	accounts []*FundedAccount,
+	untilBlock uint64,
+) (*SoakStats, error) {
+	stats := &SoakStats{}
+
+	g, ctx := errgroup.WithContext(ctx)
+	done := atomic.Bool{}
+
+	for _, acc := range accounts {
+		g.Go(func() error {
+			for !done.Load() && ctx.Err() == nil {
+				txHash, err := w.SubmitStorageTransaction(ctx, generator.Next(), WithSigner(acc), WithGasLimit(soakGasLimit))
+				if err != nil {
+					stats.Rejected.Add(1)
+					continue
+				}
+
+				receipt, err := w.waitForReceipt(ctx, txHash)
+				if ctx.Err() != nil {
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+
+				if receipt.Status == types.ReceiptStatusSuccessful {
+					stats.Succeeded.Add(1)
+					generator.AddKeys(createdKeys(receipt)...)
+				} else {
+					stats.Failed.Add(1)
+				}
+
+				if receipt.BlockNumber.Uint64() >= untilBlock {
+					done.Store(true)
+				}
+			}
+			return nil
+		})
+	}
+
+	return stats, g.Wait()
+}
+
+// soakGasLimit is the gas limit of the soak transactions, set to skip the estimation
+// which fails for the invalid transactions.
+const soakGasLimit = 2_800_000
+
+func (w *World) waitForReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		receipt, err := w.GethInstance.ETHClient.TransactionReceipt(ctx, txHash)
+		if err == nil {
+			return receipt, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("timeout waiting for receipt of %s: %w", txHash.Hex(), ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+func createdKeys(receipt *types.Receipt) []common.Hash {
+	keys := []common.Hash{}
+	for _, log := range receipt.Logs {
+		if len(log.Topics) > 1 && log.Topics[0] == arkivlogs.ArkivEntityCreated {
+			keys = append(keys, log.Topics[1])
+		}
+	}
+	return keys
+}
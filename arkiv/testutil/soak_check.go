@@ -0,0 +1,243 @@
+package testutil
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+
+	sqlitestore "github.com/Arkiv-Network/sqlite-bitmap-store"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/arkiv/address"
+	arkivlogs "github.com/ethereum/go-ethereum/arkiv/logs"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/holiman/uint256"
+)
+
+// EntitySnapshot maps the keys of the live entities at a block to the block they expire at.
+type EntitySnapshot map[common.Hash]uint64
+
+// replayLogsRange is the number of blocks of the logs requested at once by ReplaySnapshot.
+const replayLogsRange = 1000
+
+// ReplaySnapshot rebuilds the live entities at the block by replaying the logs of the
+// processor from genesis. It also returns the keys of all the entities created until
+// the block, live or not.
+func (w *World) ReplaySnapshot(ctx context.Context, block uint64) (EntitySnapshot, []common.Hash, error) {
+	snapshot := EntitySnapshot{}
+	created := []common.Hash{}
+
+	for from := uint64(0); from <= block; from += replayLogsRange {
+		to := min(from+replayLogsRange-1, block)
+		logs, err := w.GethInstance.ETHClient.FilterLogs(ctx, ethereum.FilterQuery{
+			FromBlock: new(big.Int).SetUint64(from),
+			ToBlock:   new(big.Int).SetUint64(to),
+			Addresses: []common.Address{address.ArkivProcessorAddress},
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get logs of blocks %d-%d: %w", from, to, err)
+		}
+
+		for _, log := range logs {
+			if len(log.Topics) < 2 {
+				continue
+			}
+			key := log.Topics[1]
+			switch log.Topics[0] {
+			case arkivlogs.ArkivEntityCreated:
+				snapshot[key] = new(uint256.Int).SetBytes(log.Data[:32]).Uint64()
+				created = append(created, key)
+			case arkivlogs.ArkivEntityUpdated, arkivlogs.ArkivEntityBTLExtended:
+				snapshot[key] = new(uint256.Int).SetBytes(log.Data[32:64]).Uint64()
+			case arkivlogs.ArkivEntityDeleted, arkivlogs.ArkivEntityExpired:
+				delete(snapshot, key)
+			}
+		}
+	}
+
+	return snapshot, created, nil
+}
+
+// ProcessorSnapshot reads the metadata of the entities from the state of the processor at the block.
+func (w *World) ProcessorSnapshot(ctx context.Context, keys []common.Hash, block uint64) (EntitySnapshot, error) {
+	snapshot := EntitySnapshot{}
+	blockNumber := new(big.Int).SetUint64(block)
+
+	for _, key := range keys {
+		value, err := w.GethInstance.ETHClient.StorageAt(
+			ctx,
+			address.ArkivProcessorAddress,
+			crypto.Keccak256Hash(entity.EntityMetaDataSalt, key[:]),
+			blockNumber,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get metadata of entity %s: %w", key.Hex(), err)
+		}
+
+		hash := common.BytesToHash(value)
+		if hash == (common.Hash{}) {
+			continue
+		}
+
+		md := entity.EntityMetaData{}
+		md.Unmarshal(hash)
+		snapshot[key] = md.ExpiresAtBlock
+	}
+
+	return snapshot, nil
+}
+
+// WaitForStore waits until the store has processed the block.
+func (w *World) WaitForStore(ctx context.Context, block uint64) error {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		status := struct {
+			LastBlock hexutil.Uint64 `json:"lastBlock"`
+		}{}
+		err := w.GethInstance.RPCClient.CallContext(ctx, &status, "arkiv_syncStatus")
+		if err != nil {
+			return fmt.Errorf("failed to get sync status: %w", err)
+		}
+		if uint64(status.LastBlock) >= block {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("store is at block %d, waiting for block %d: %w", status.LastBlock, block, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// StoreSnapshot queries the live entities at the block from the store.
+func (w *World) StoreSnapshot(ctx context.Context, block uint64) (EntitySnapshot, error) {
+	snapshot := EntitySnapshot{}
+	pageSize := uint64(200)
+	options := sqlitestore.Options{
+		AtBlock: &block,
+		IncludeData: &sqlitestore.IncludeData{
+			Key:        true,
+			Expiration: true,
+		},
+		ResultsPerPage: &pageSize,
+	}
+
+	for {
+		response := sqlitestore.QueryResponse{}
+		err := w.GethInstance.RPCClient.CallContext(ctx, &response, "arkiv_query", "$all", options)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query the store: %w", err)
+		}
+
+		for _, d := range response.Data {
+			ed := sqlitestore.EntityData{}
+			err = json.Unmarshal(d, &ed)
+			if err != nil {
+				return nil, fmt.Errorf("failed to unmarshal entity data: %w", err)
+			}
+			if ed.Key == nil || ed.ExpiresAt == nil {
+				return nil, fmt.Errorf("entity data without key or expiration: %s", string(d))
+			}
+			snapshot[*ed.Key] = *ed.ExpiresAt
+		}
+
+		if response.Cursor == nil || *response.Cursor == "" {
+			return snapshot, nil
+		}
+		options.Cursor = *response.Cursor
+	}
+}
+
+// StorePayloadSizes returns the total size of the payloads of the live entities at the
+// block, as they were written and as the store serves them to a client accepting
+// brotli compressed payloads.
+func (w *World) StorePayloadSizes(ctx context.Context, block uint64) (raw uint64, served uint64, err error) {
+	for _, acceptEncoding := range [][]string{nil, {"br"}} {
+		pageSize := uint64(200)
+		options := struct {
+			sqlitestore.Options
+			AcceptEncoding []string `json:"acceptEncoding,omitempty"`
+		}{
+			Options: sqlitestore.Options{
+				AtBlock:        &block,
+				IncludeData:    &sqlitestore.IncludeData{Payload: true},
+				ResultsPerPage: &pageSize,
+			},
+			AcceptEncoding: acceptEncoding,
+		}
+
+		var total uint64
+		for {
+			response := sqlitestore.QueryResponse{}
+			err := w.GethInstance.RPCClient.CallContext(ctx, &response, "arkiv_query", "$all", options)
+			if err != nil {
+				return 0, 0, fmt.Errorf("failed to query the store: %w", err)
+			}
+			for _, d := range response.Data {
+				ed := sqlitestore.EntityData{}
+				if err := json.Unmarshal(d, &ed); err != nil {
+					return 0, 0, fmt.Errorf("failed to unmarshal entity data: %w", err)
+				}
+				total += uint64(len(ed.Value))
+			}
+			if response.Cursor == nil || *response.Cursor == "" {
+				break
+			}
+			options.Cursor = *response.Cursor
+		}
+
+		if acceptEncoding == nil {
+			raw = total
+		} else {
+			served = total
+		}
+	}
+	return raw, served, nil
+}
+
+// CompareSnapshots returns a description of every entity the named snapshots don't
+// agree on, either because it's missing from some of them or because its expiration
+// differs. The descriptions are sorted by key.
+func CompareSnapshots(snapshots map[string]EntitySnapshot) []string {
+	names := slices.Sorted(maps.Keys(snapshots))
+
+	keys := map[common.Hash]struct{}{}
+	for _, snapshot := range snapshots {
+		for key := range snapshot {
+			keys[key] = struct{}{}
+		}
+	}
+
+	divergences := []string{}
+	for key := range keys {
+		values := make([]string, len(names))
+		agree := true
+		for i, name := range names {
+			expiresAt, ok := snapshots[name][key]
+			if ok {
+				values[i] = fmt.Sprintf("%s=%d", name, expiresAt)
+			} else {
+				values[i] = name + "=missing"
+			}
+			first, firstOk := snapshots[names[0]][key]
+			if ok != firstOk || expiresAt != first {
+				agree = false
+			}
+		}
+		if !agree {
+			divergences = append(divergences, fmt.Sprintf("%s: %s", key.Hex(), strings.Join(values, " ")))
+		}
+	}
+	slices.Sort(divergences)
+
+	return divergences
+}
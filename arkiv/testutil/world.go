@@ -18,11 +18,21 @@ type World struct {
 	FundedAccount          *FundedAccount
 	SecondFundedAccount    *FundedAccount
 	LastReceipt            *types.Receipt
+	LastSubmittedTx        common.Hash
 	ArkivSearchResult      []sqlitestore.EntityData
 	CreatedEntityKey       common.Hash
 	SecondCreatedEntityKey common.Hash
 	LastError              error
 	LastTrace              json.RawMessage
+	LastQueryDiff          json.RawMessage
+	LastMetrics            map[string]float64
+
+	// EntityOwner owns the entities created with NewOwnerWithEntities
+	EntityOwner *FundedAccount
+
+	// Entities and blocks remembered by name for steps that compare chain heights
+	NamedEntityKeys map[string]common.Hash
+	RememberedBlock uint64
 
 	// Storage transaction validation fields
 	CurrentStorageTransaction *storagetx.ArkivTransaction
@@ -74,6 +84,7 @@ func NewWorld(ctx context.Context, gethPath string) (*World, error) {
 		GethInstance:        geth,
 		FundedAccount:       acc,
 		SecondFundedAccount: acc2,
+		NamedEntityKeys:     map[string]common.Hash{},
 		tempDir:             td,
 	}, nil
 
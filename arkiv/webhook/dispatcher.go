@@ -0,0 +1,331 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// requestTimeout is the timeout of a single webhook request.
+const requestTimeout = 10 * time.Second
+
+var (
+	// deliveryLatencyTimer is the time from queueing a request to its acknowledgement.
+	deliveryLatencyTimer = metrics.NewRegisteredTimer("arkiv/webhook/latency", nil)
+	deliveredMeter       = metrics.NewRegisteredMeter("arkiv/webhook/delivered", nil)
+	failuresMeter        = metrics.NewRegisteredMeter("arkiv/webhook/failures", nil)
+	droppedMeter         = metrics.NewRegisteredMeter("arkiv/webhook/dropped", nil)
+	queueGauge           = metrics.NewRegisteredGauge("arkiv/webhook/queue", nil)
+)
+
+// Chain is the part of the blockchain the dispatcher reads the events from.
+type Chain interface {
+	CurrentBlock() *types.Header
+	CurrentFinalBlock() *types.Header
+	GetHeaderByNumber(number uint64) *types.Header
+	GetReceiptsByHash(hash common.Hash) types.Receipts
+	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
+}
+
+// Dispatcher posts the entity events of the confirmed blocks to the webhooks.
+type Dispatcher struct {
+	config Config
+	filter filter
+	chain  Chain
+	client *http.Client
+
+	mu    sync.Mutex
+	queue *queue
+
+	// wake notifies the sender about new deliveries.
+	wake chan struct{}
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New creates a dispatcher for the chain, loading the undelivered requests of the
+// previous run from the queue file.
+func New(config Config, chain Chain) (*Dispatcher, error) {
+	config = config.withDefaults()
+	if err := config.check(); err != nil {
+		return nil, err
+	}
+
+	q, err := loadQueue(config.QueuePath, config.MaxQueue)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Dispatcher{
+		config: config,
+		filter: filter{owners: config.Owners, keys: config.Keys, kinds: config.Kinds},
+		chain:  chain,
+		client: &http.Client{Timeout: requestTimeout},
+		queue:  q,
+		wake:   make(chan struct{}, 1),
+		quit:   make(chan struct{}),
+	}, nil
+}
+
+// Start starts following the chain and delivering the requests.
+func (d *Dispatcher) Start() {
+	heads := make(chan core.ChainHeadEvent, 16)
+	sub := d.chain.SubscribeChainHeadEvent(heads)
+	d.collect()
+
+	d.wg.Add(2)
+	go d.follow(heads, sub)
+	go d.send()
+}
+
+// Stop stops the dispatcher, the undelivered requests stay in the queue file.
+func (d *Dispatcher) Stop() {
+	close(d.quit)
+	d.wg.Wait()
+}
+
+// target returns the last block whose events can be posted.
+func (d *Dispatcher) target() (uint64, bool) {
+	if d.config.Confirmations == 0 {
+		final := d.chain.CurrentFinalBlock()
+		if final == nil {
+			return 0, false
+		}
+		return final.Number.Uint64(), true
+	}
+
+	head := d.chain.CurrentBlock()
+	if head == nil || head.Number.Uint64() < d.config.Confirmations {
+		return 0, false
+	}
+	return head.Number.Uint64() - d.config.Confirmations, true
+}
+
+// follow queues the requests of the blocks as they get confirmed.
+func (d *Dispatcher) follow(heads chan core.ChainHeadEvent, sub event.Subscription) {
+	defer d.wg.Done()
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case <-heads:
+			d.collect()
+		case <-sub.Err():
+			return
+		case <-d.quit:
+			return
+		}
+	}
+}
+
+// collect queues the requests of the blocks confirmed since the last call.
+func (d *Dispatcher) collect() {
+	target, ok := d.target()
+	if !ok {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// Only the blocks confirmed after the first start are posted, the history of the
+	// chain is not replayed.
+	if !d.queue.state.Started {
+		d.queue.state.Started = true
+		d.queue.state.LastBlock = target
+		d.saveLocked()
+		return
+	}
+
+	queued := false
+	for number := d.queue.state.LastBlock + 1; number <= target; number++ {
+		select {
+		case <-d.quit:
+			return
+		default:
+		}
+
+		header := d.chain.GetHeaderByNumber(number)
+		if header == nil {
+			log.Warn("Arkiv webhook block not found", "number", number)
+			return
+		}
+		receipts := d.chain.GetReceiptsByHash(header.Hash())
+		if receipts == nil && header.TxHash != types.EmptyTxsHash {
+			log.Warn("Arkiv webhook receipts not found", "number", number, "hash", header.Hash())
+			return
+		}
+
+		d.queue.state.LastBlock = number
+
+		events := blockEvents(receipts, d.filter)
+		if len(events) == 0 {
+			continue
+		}
+
+		body, err := json.Marshal(Payload{
+			BlockNumber: hexutil.Uint64(number),
+			BlockHash:   header.Hash(),
+			Events:      events,
+		})
+		if err != nil {
+			log.Error("Arkiv webhook failed to encode payload", "number", number, "error", err)
+			continue
+		}
+
+		now := time.Now()
+		for i, url := range d.config.URLs {
+			dropped := d.queue.push(&delivery{
+				ID:          fmt.Sprintf("%s-%d", header.Hash().Hex(), i),
+				URL:         url,
+				Body:        body,
+				Created:     now,
+				NextAttempt: now,
+			})
+			if dropped > 0 {
+				droppedMeter.Mark(int64(dropped))
+				log.Warn("Arkiv webhook queue is full, dropped the oldest requests", "dropped", dropped, "limit", d.config.MaxQueue)
+			}
+		}
+		queued = true
+
+		// Persist as we go, so that a long catch up isn't lost on a crash
+		d.saveLocked()
+	}
+	d.saveLocked()
+
+	if queued {
+		select {
+		case d.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// saveLocked persists the queue, the lock must be held.
+func (d *Dispatcher) saveLocked() {
+	queueGauge.Update(int64(len(d.queue.state.Deliveries)))
+	if err := d.queue.save(); err != nil {
+		log.Error("Arkiv webhook failed to persist queue", "error", err)
+	}
+}
+
+// send delivers the queued requests, retrying the failed ones with exponential backoff.
+func (d *Dispatcher) send() {
+	defer d.wg.Done()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+		case <-d.wake:
+		case <-d.quit:
+			return
+		}
+
+		d.mu.Lock()
+		due, next := d.queue.due(time.Now())
+		d.mu.Unlock()
+
+		var wg sync.WaitGroup
+		for _, delivery := range due {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				d.attempt(delivery)
+			}()
+		}
+		wg.Wait()
+
+		// Deliver the following requests right away if anything was sent
+		wait := time.Until(next)
+		if len(due) > 0 {
+			wait = 0
+		} else if next.IsZero() {
+			wait = time.Hour
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(max(wait, 0))
+	}
+}
+
+// attempt sends a delivery once and updates the queue with the outcome.
+func (d *Dispatcher) attempt(delivery delivery) {
+	err := d.post(delivery)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err == nil {
+		deliveredMeter.Mark(1)
+		deliveryLatencyTimer.UpdateSince(delivery.Created)
+		d.queue.remove(delivery.ID)
+		d.saveLocked()
+		return
+	}
+
+	failuresMeter.Mark(1)
+	queued := d.queue.get(delivery.ID)
+	if queued == nil {
+		// Dropped while it was being sent
+		return
+	}
+	queued.Attempts++
+	queued.NextAttempt = time.Now().Add(d.backoff(queued.Attempts))
+	log.Warn("Arkiv webhook delivery failed", "url", delivery.URL, "id", delivery.ID, "attempts", queued.Attempts, "retry", queued.NextAttempt, "error", err)
+	d.saveLocked()
+}
+
+// backoff returns the delay before the next attempt of a delivery that failed the
+// number of times.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.config.MinBackoff
+	for i := 1; i < attempts && delay < d.config.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, d.config.MaxBackoff)
+}
+
+// post sends the request of the delivery, any non-2xx response is a failure.
+func (d *Dispatcher) post(delivery delivery) error {
+	req, err := http.NewRequest(http.MethodPost, delivery.URL, bytes.NewReader(delivery.Body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(DeliveryHeader, delivery.ID)
+	if d.config.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(d.config.Secret, delivery.Body))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,240 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/arkiv/address"
+	"github.com/ethereum/go-ethereum/arkiv/logs"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeChain is a chain whose blocks each hold the logs of a single receipt. It
+// starts with two empty blocks, so that a dispatcher waiting for one confirmation
+// starts after block 0.
+type fakeChain struct {
+	mu       sync.Mutex
+	headers  []*types.Header
+	receipts map[common.Hash]types.Receipts
+	feed     event.Feed
+}
+
+func newFakeChain() *fakeChain {
+	c := &fakeChain{receipts: map[common.Hash]types.Receipts{}}
+	c.addBlock()
+	c.addBlock()
+	return c
+}
+
+// addBlock appends a block with the logs and notifies the subscribers.
+func (c *fakeChain) addBlock(blockLogs ...*types.Log) {
+	c.mu.Lock()
+	header := &types.Header{Number: big.NewInt(int64(len(c.headers))), TxHash: types.EmptyTxsHash}
+	c.headers = append(c.headers, header)
+	c.receipts[header.Hash()] = types.Receipts{{Logs: blockLogs}}
+	c.mu.Unlock()
+
+	c.feed.Send(core.ChainHeadEvent{Header: header})
+}
+
+func (c *fakeChain) CurrentBlock() *types.Header {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.headers[len(c.headers)-1]
+}
+
+func (c *fakeChain) CurrentFinalBlock() *types.Header {
+	return nil
+}
+
+func (c *fakeChain) GetHeaderByNumber(number uint64) *types.Header {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if number >= uint64(len(c.headers)) {
+		return nil
+	}
+	return c.headers[number]
+}
+
+func (c *fakeChain) GetReceiptsByHash(hash common.Hash) types.Receipts {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.receipts[hash]
+}
+
+func (c *fakeChain) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription {
+	return c.feed.Subscribe(ch)
+}
+
+// entityLog returns a log of the processor whose expiration blocks are all 100.
+func entityLog(topic common.Hash, key common.Hash, owner common.Address) *types.Log {
+	expiresAt := common.BigToHash(big.NewInt(100)).Bytes()
+	return &types.Log{
+		Address: address.ArkivProcessorAddress,
+		Topics:  []common.Hash{topic, key, common.BytesToHash(owner.Bytes())},
+		Data:    append(append(expiresAt, expiresAt...), expiresAt...),
+	}
+}
+
+// receiver is a webhook endpoint failing the first requests.
+type receiver struct {
+	t      *testing.T
+	secret string
+
+	mu       sync.Mutex
+	failures int
+	attempts int
+	payloads []Payload
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(req.Body)
+	require.NoError(r.t, err)
+	require.True(r.t, Verify(r.secret, body, req.Header.Get(SignatureHeader)), "invalid signature")
+	require.NotEmpty(r.t, req.Header.Get(DeliveryHeader))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.attempts++
+	if r.failures > 0 {
+		r.failures--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	var payload Payload
+	require.NoError(r.t, json.Unmarshal(body, &payload))
+	r.payloads = append(r.payloads, payload)
+}
+
+func (r *receiver) delivered() []Payload {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Payload(nil), r.payloads...)
+}
+
+func (r *receiver) setFailures(failures int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.failures = failures
+}
+
+func startDispatcher(t *testing.T, config Config, chain Chain) *Dispatcher {
+	t.Helper()
+	config.MinBackoff = 10 * time.Millisecond
+	config.MaxBackoff = 50 * time.Millisecond
+	d, err := New(config, chain)
+	require.NoError(t, err)
+	d.Start()
+	return d
+}
+
+func TestDispatcher_RetriesSignedDeliveries(t *testing.T) {
+	owner := common.HexToAddress("0x01")
+	other := common.HexToAddress("0x02")
+	key := common.HexToHash("0x10")
+
+	r := &receiver{t: t, secret: "secret", failures: 2}
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	chain := newFakeChain()
+	d := startDispatcher(t, Config{
+		URLs:          []string{server.URL},
+		Secret:        "secret",
+		Owners:        []common.Address{owner},
+		Confirmations: 1,
+	}, chain)
+	defer d.Stop()
+
+	chain.addBlock(
+		entityLog(logs.ArkivEntityCreated, key, owner),
+		entityLog(logs.ArkivEntityCreated, common.HexToHash("0x11"), other),
+	)
+	chain.addBlock(entityLog(logs.ArkivEntityDeleted, key, owner))
+
+	// The block is posted once it has a confirmation, after two failed attempts
+	require.Eventually(t, func() bool { return len(r.delivered()) == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	payload := r.delivered()[0]
+	require.EqualValues(t, 2, payload.BlockNumber)
+	require.Equal(t, chain.GetHeaderByNumber(2).Hash(), payload.BlockHash)
+	require.Len(t, payload.Events, 1)
+	require.Equal(t, KindCreated, payload.Events[0].Kind)
+	require.Equal(t, key, payload.Events[0].Key)
+	require.Equal(t, owner, payload.Events[0].Owner)
+	require.EqualValues(t, 100, *payload.Events[0].ExpiresAt)
+
+	r.mu.Lock()
+	require.Equal(t, 3, r.attempts)
+	r.mu.Unlock()
+
+	chain.addBlock()
+	require.Eventually(t, func() bool { return len(r.delivered()) == 2 }, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, KindDeleted, r.delivered()[1].Events[0].Kind)
+}
+
+func TestDispatcher_PersistsQueueAcrossRestarts(t *testing.T) {
+	owner := common.HexToAddress("0x01")
+
+	r := &receiver{t: t, secret: "secret", failures: 1_000_000}
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	config := Config{
+		URLs:          []string{server.URL},
+		Secret:        "secret",
+		Kinds:         []string{KindCreated},
+		Confirmations: 1,
+		QueuePath:     filepath.Join(t.TempDir(), "webhooks.json"),
+		MaxQueue:      2,
+	}
+
+	chain := newFakeChain()
+	d := startDispatcher(t, config, chain)
+	for i := range 3 {
+		chain.addBlock(entityLog(logs.ArkivEntityCreated, common.BigToHash(big.NewInt(int64(i))), owner))
+	}
+	chain.addBlock()
+
+	require.Eventually(t, func() bool {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		return d.queue.state.LastBlock == 4
+	}, 5*time.Second, 10*time.Millisecond)
+	d.Stop()
+
+	// The bounded queue dropped the request of the first block
+	r.setFailures(0)
+	d = startDispatcher(t, config, chain)
+	defer d.Stop()
+
+	require.Eventually(t, func() bool { return len(r.delivered()) == 2 }, 5*time.Second, 10*time.Millisecond)
+	require.EqualValues(t, 3, r.delivered()[0].BlockNumber)
+	require.EqualValues(t, 4, r.delivered()[1].BlockNumber)
+}
+
+func TestDispatcher_Backoff(t *testing.T) {
+	d := &Dispatcher{config: Config{MinBackoff: time.Second, MaxBackoff: 5 * time.Second}}
+	require.Equal(t, time.Second, d.backoff(1))
+	require.Equal(t, 2*time.Second, d.backoff(2))
+	require.Equal(t, 4*time.Second, d.backoff(3))
+	require.Equal(t, 5*time.Second, d.backoff(4))
+	require.Equal(t, 5*time.Second, d.backoff(100))
+}
+
+func TestConfig_RejectsUnknownKinds(t *testing.T) {
+	_, err := New(Config{URLs: []string{"http://localhost"}, Kinds: []string{"destroyed"}}, newFakeChain())
+	require.ErrorContains(t, err, `unknown event kind "destroyed"`)
+}
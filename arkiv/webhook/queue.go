@@ -0,0 +1,147 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// delivery is a request to a webhook waiting to be acknowledged.
+type delivery struct {
+	ID          string          `json:"id"`
+	URL         string          `json:"url"`
+	Body        json.RawMessage `json:"body"`
+	Created     time.Time       `json:"created"`
+	Attempts    int             `json:"attempts"`
+	NextAttempt time.Time       `json:"nextAttempt"`
+}
+
+// queueState is the persisted state of the dispatcher.
+type queueState struct {
+	// LastBlock is the last block whose events were queued.
+	LastBlock uint64 `json:"lastBlock"`
+	// Started is set once LastBlock was initialized.
+	Started    bool        `json:"started"`
+	Deliveries []*delivery `json:"deliveries"`
+}
+
+// queue is the bounded queue of undelivered requests, persisted to a file together
+// with the progress of the dispatcher so that a block is never lost between the
+// moment it's read and the moment its requests are acknowledged.
+type queue struct {
+	path  string
+	limit int
+	state queueState
+}
+
+// loadQueue reads the queue from the file at path, or creates an empty queue if the
+// file doesn't exist. An empty path keeps the queue in memory.
+func loadQueue(path string, limit int) (*queue, error) {
+	q := &queue{path: path, limit: limit}
+	if path == "" {
+		return q, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return q, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read webhook queue: %w", err)
+	}
+	if err := json.Unmarshal(data, &q.state); err != nil {
+		return nil, fmt.Errorf("failed to decode webhook queue %s: %w", path, err)
+	}
+	return q, nil
+}
+
+// save writes the queue to its file, replacing the previous content atomically.
+func (q *queue) save() error {
+	if q.path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(q.state)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(q.path), filepath.Base(q.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write webhook queue: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write webhook queue: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write webhook queue: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write webhook queue: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), q.path); err != nil {
+		return fmt.Errorf("failed to write webhook queue: %w", err)
+	}
+	return nil
+}
+
+// push appends the deliveries to the queue and returns the number of deliveries
+// dropped to respect the limit, the oldest first.
+func (q *queue) push(deliveries ...*delivery) int {
+	q.state.Deliveries = append(q.state.Deliveries, deliveries...)
+	dropped := max(len(q.state.Deliveries)-q.limit, 0)
+	q.state.Deliveries = q.state.Deliveries[dropped:]
+	return dropped
+}
+
+// remove removes the delivery with the id, reporting whether it was still queued.
+func (q *queue) remove(id string) bool {
+	for i, d := range q.state.Deliveries {
+		if d.ID == id {
+			q.state.Deliveries = append(q.state.Deliveries[:i], q.state.Deliveries[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// get returns the delivery with the id, nil if it's no longer queued.
+func (q *queue) get(id string) *delivery {
+	for _, d := range q.state.Deliveries {
+		if d.ID == id {
+			return d
+		}
+	}
+	return nil
+}
+
+// due returns a copy of the oldest delivery of every URL, if its next attempt is due.
+// Later deliveries to a URL wait for the earlier ones, so that a receiver sees the
+// blocks in order. It also returns the time of the next attempt that is not due.
+func (q *queue) due(now time.Time) ([]delivery, time.Time) {
+	var (
+		result []delivery
+		next   time.Time
+		seen   = map[string]bool{}
+	)
+	for _, d := range q.state.Deliveries {
+		if seen[d.URL] {
+			continue
+		}
+		seen[d.URL] = true
+
+		if !d.NextAttempt.After(now) {
+			result = append(result, *d)
+		} else if next.IsZero() || d.NextAttempt.Before(next) {
+			next = d.NextAttempt
+		}
+	}
+	return result, next
+}
@@ -0,0 +1,225 @@
+// Package webhook notifies external services about the lifecycle of Arkiv entities.
+//
+// The dispatcher follows the chain, waits until a block is final (or buried under a
+// configured number of confirmations), extracts the Arkiv events of the block from
+// the logs of its receipts and POSTs the events matching the filter as JSON to the
+// configured URLs. Deliveries are retried with exponential backoff until they are
+// acknowledged with a 2xx response, so a receiver can see the same block more than
+// once and should deduplicate on the block hash.
+//
+// The body of every request is signed with HMAC-SHA256 using the shared secret, the
+// signature is sent hex encoded in the X-Arkiv-Signature header as `sha256=<hex>`.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/ethereum/go-ethereum/arkiv/address"
+	"github.com/ethereum/go-ethereum/arkiv/logs"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/holiman/uint256"
+)
+
+const (
+	// SignatureHeader is the header carrying the HMAC of the request body.
+	SignatureHeader = "X-Arkiv-Signature"
+	// DeliveryHeader is the header carrying the unique ID of a delivery, which is the
+	// same for all the attempts of the delivery.
+	DeliveryHeader = "X-Arkiv-Delivery"
+
+	// DefaultMaxQueue is the default number of undelivered requests kept for retry.
+	DefaultMaxQueue = 10_000
+	// DefaultMinBackoff is the default delay before the first retry of a delivery.
+	DefaultMinBackoff = time.Second
+	// DefaultMaxBackoff is the default upper bound of the delay between retries.
+	DefaultMaxBackoff = 5 * time.Minute
+)
+
+// The kinds of entity events.
+const (
+	KindCreated      = "created"
+	KindUpdated      = "updated"
+	KindDeleted      = "deleted"
+	KindExpired      = "expired"
+	KindExtended     = "extended"
+	KindOwnerChanged = "ownerChanged"
+)
+
+// Kinds are all the kinds of entity events.
+var Kinds = []string{KindCreated, KindUpdated, KindDeleted, KindExpired, KindExtended, KindOwnerChanged}
+
+// Config configures the webhook dispatcher.
+type Config struct {
+	// URLs are the endpoints every matching block is posted to.
+	URLs []string
+	// Secret is the shared secret used to sign the requests, requests are not signed
+	// if it's empty.
+	Secret string
+
+	// Owners, Keys and Kinds filter the events, an empty list matches everything.
+	// An ownership change matches both the previous and the new owner.
+	Owners []common.Address
+	Keys   []common.Hash
+	Kinds  []string
+
+	// Confirmations is the number of blocks a block must be buried under before its
+	// events are posted, 0 waits for the block to be finalized.
+	Confirmations uint64
+
+	// QueuePath is the file the undelivered requests are persisted to, so that they
+	// survive a restart. The queue is only kept in memory if the path is empty.
+	QueuePath string
+	// MaxQueue is the number of undelivered requests kept, the oldest ones are
+	// dropped when it's exceeded.
+	MaxQueue int
+
+	// MinBackoff and MaxBackoff bound the delay between the attempts of a delivery.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// withDefaults returns the config with the defaults applied to the unset fields.
+func (c Config) withDefaults() Config {
+	if c.MaxQueue <= 0 {
+		c.MaxQueue = DefaultMaxQueue
+	}
+	if c.MinBackoff <= 0 {
+		c.MinBackoff = DefaultMinBackoff
+	}
+	if c.MaxBackoff < c.MinBackoff {
+		c.MaxBackoff = max(DefaultMaxBackoff, c.MinBackoff)
+	}
+	return c
+}
+
+func (c Config) check() error {
+	if len(c.URLs) == 0 {
+		return fmt.Errorf("no webhook URL configured")
+	}
+	for _, kind := range c.Kinds {
+		if !slices.Contains(Kinds, kind) {
+			return fmt.Errorf("unknown event kind %q, expected one of %v", kind, Kinds)
+		}
+	}
+	return nil
+}
+
+// Event is a change of the lifecycle of an entity.
+type Event struct {
+	Kind  string         `json:"kind"`
+	Key   common.Hash    `json:"key"`
+	Owner common.Address `json:"owner"`
+	// PreviousOwner is the owner before an ownership change.
+	PreviousOwner *common.Address `json:"previousOwner,omitempty"`
+	// ExpiresAt is the expiration block of a created, updated or extended entity.
+	ExpiresAt *hexutil.Uint64 `json:"expiresAt,omitempty"`
+	TxHash    common.Hash     `json:"txHash"`
+	LogIndex  hexutil.Uint    `json:"logIndex"`
+}
+
+// Payload is the body of a webhook request, the events of a single block.
+type Payload struct {
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	BlockHash   common.Hash    `json:"blockHash"`
+	Events      []Event        `json:"events"`
+}
+
+// Sign returns the value of the signature header of the body.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether the signature header matches the body.
+func Verify(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+// filter selects the events posted to the webhooks.
+type filter struct {
+	owners []common.Address
+	keys   []common.Hash
+	kinds  []string
+}
+
+func (f filter) match(e Event) bool {
+	if len(f.kinds) > 0 && !slices.Contains(f.kinds, e.Kind) {
+		return false
+	}
+	if len(f.keys) > 0 && !slices.Contains(f.keys, e.Key) {
+		return false
+	}
+	if len(f.owners) > 0 && !slices.Contains(f.owners, e.Owner) &&
+		(e.PreviousOwner == nil || !slices.Contains(f.owners, *e.PreviousOwner)) {
+		return false
+	}
+	return true
+}
+
+// blockEvents returns the entity events in the logs of the receipts of a block that
+// match the filter.
+func blockEvents(receipts types.Receipts, f filter) []Event {
+	result := []Event{}
+	for _, receipt := range receipts {
+		for _, log := range receipt.Logs {
+			if log.Address != address.ArkivProcessorAddress || len(log.Topics) < 3 {
+				continue
+			}
+
+			event := Event{
+				Key:      log.Topics[1],
+				Owner:    common.BytesToAddress(log.Topics[2].Bytes()),
+				TxHash:   log.TxHash,
+				LogIndex: hexutil.Uint(log.Index),
+			}
+
+			switch log.Topics[0] {
+			case logs.ArkivEntityCreated:
+				event.Kind = KindCreated
+				event.ExpiresAt = expiration(log.Data, 0)
+			case logs.ArkivEntityUpdated:
+				event.Kind = KindUpdated
+				event.ExpiresAt = expiration(log.Data, 32)
+			case logs.ArkivEntityBTLExtended:
+				event.Kind = KindExtended
+				event.ExpiresAt = expiration(log.Data, 32)
+			case logs.ArkivEntityDeleted:
+				event.Kind = KindDeleted
+			case logs.ArkivEntityExpired:
+				event.Kind = KindExpired
+			case logs.ArkivEntityOwnerChanged:
+				if len(log.Topics) < 4 {
+					continue
+				}
+				event.Kind = KindOwnerChanged
+				previousOwner := event.Owner
+				event.PreviousOwner = &previousOwner
+				event.Owner = common.BytesToAddress(log.Topics[3].Bytes())
+			default:
+				continue
+			}
+
+			if f.match(event) {
+				result = append(result, event)
+			}
+		}
+	}
+	return result
+}
+
+// expiration decodes the expiration block at the offset of the log data.
+func expiration(data []byte, offset int) *hexutil.Uint64 {
+	if len(data) < offset+32 {
+		return nil
+	}
+	block := hexutil.Uint64(new(uint256.Int).SetBytes(data[offset : offset+32]).Uint64())
+	return &block
+}
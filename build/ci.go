@@ -202,7 +202,8 @@ func doInstall(cmdline []string) {
 		tc.Root = build.DownloadGo(csdb)
 	}
 	// Disable CLI markdown doc generation in release builds.
-	buildTags := []string{"urfave_cli_no_docs"}
+	// Enable FTS5 in the sqlite driver for the Arkiv full-text index.
+	buildTags := []string{"urfave_cli_no_docs", "sqlite_fts5"}
 
 	// Enable linking the CKZG library since we can make it work with additional flags.
 	if env.UbuntuVersion != "trusty" {
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	sqlitestore "github.com/Arkiv-Network/sqlite-bitmap-store"
+	arkivaddress "github.com/ethereum/go-ethereum/arkiv/address"
+	"github.com/ethereum/go-ethereum/arkiv/compression"
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/beacon"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/stretchr/testify/require"
+)
+
+// writeArkivChain writes the genesis and the blocks of a chain creating an entity in
+// every block as geth export does, and returns the keys of the entities.
+func writeArkivChain(t *testing.T, dir string, blocks int) (string, string, []common.Hash) {
+	t.Helper()
+
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	config := *params.MergedTestChainConfig
+	gspec := &core.Genesis{
+		Config:   &config,
+		GasLimit: 30_000_000,
+		BaseFee:  big.NewInt(params.InitialBaseFee),
+		Alloc:    types.GenesisAlloc{from: {Balance: big.NewInt(params.Ether)}},
+	}
+
+	var keys []common.Hash
+	signer := types.LatestSigner(&config)
+	_, chain, _ := core.GenerateChainWithGenesis(gspec, beacon.New(ethash.NewFaker()), blocks, func(i int, b *core.BlockGen) {
+		create := storagetx.ArkivCreate{BTL: 1000, ContentType: "text/plain", Payload: []byte{byte(i)}}
+		data, err := rlp.EncodeToBytes(&storagetx.ArkivTransaction{Create: []storagetx.ArkivCreate{create}})
+		require.NoError(t, err)
+		tx, err := types.SignNewTx(key, signer, &types.DynamicFeeTx{
+			ChainID:   config.ChainID,
+			Nonce:     b.TxNonce(from),
+			To:        &arkivaddress.ArkivProcessorAddress,
+			Gas:       1_000_000,
+			GasFeeCap: new(big.Int).Mul(b.BaseFee(), big.NewInt(2)),
+			GasTipCap: big.NewInt(1),
+			Data:      compression.MustBrotliCompress(data),
+		})
+		require.NoError(t, err)
+		b.AddTx(tx)
+		keys = append(keys, crypto.Keccak256Hash(tx.Hash().Bytes(), create.Payload, common.LeftPadBytes(nil, 32)))
+	})
+
+	genesisFile := filepath.Join(dir, "genesis.json")
+	genesis, err := json.Marshal(gspec)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(genesisFile, genesis, 0644))
+
+	chainFile := filepath.Join(dir, "chain.rlp")
+	out, err := os.Create(chainFile)
+	require.NoError(t, err)
+	defer out.Close()
+	for _, block := range chain {
+		require.NoError(t, block.EncodeRLP(out))
+	}
+	return genesisFile, chainFile, keys
+}
+
+// TestImportArkivCatchUp imports an exported chain and checks the Arkiv database is
+// reported behind, then fed the imported blocks with --arkiv.import.catchup.
+func TestImportArkivCatchUp(t *testing.T) {
+	t.Parallel()
+	const blocks = 5
+
+	dir := t.TempDir()
+	genesisFile, chainFile, keys := writeArkivChain(t, dir, blocks)
+	datadir := filepath.Join(dir, "datadir")
+	runGeth(t, "--datadir", datadir, "init", genesisFile).WaitExit()
+
+	importChain := func(args ...string) string {
+		geth := runGeth(t, append([]string{"--datadir", datadir, "import", "--nocompaction"}, append(args, chainFile)...)...)
+		output := string(geth.Output())
+		geth.WaitExit()
+		require.Zero(t, geth.ExitStatus(), geth.StderrText())
+		return output
+	}
+
+	output := importChain()
+	require.Contains(t, output, "is at block 0, behind the imported head 5")
+	require.Contains(t, output, "--arkiv.import.catchup")
+
+	output = importChain("--arkiv.import.catchup")
+	require.Contains(t, output, "Arkiv database at block 5 of 5")
+	require.Contains(t, output, "Arkiv database caught up")
+
+	// The database holds the entities of every imported block
+	store, err := sqlitestore.NewSQLiteStore(slog.New(slog.NewTextHandler(io.Discard, nil)), filepath.Join(datadir, "golem-base.db"), 1)
+	require.NoError(t, err)
+	lastBlock, err := store.GetLastBlock(context.Background())
+	require.NoError(t, err)
+	require.EqualValues(t, blocks, lastBlock)
+
+	atBlock := uint64(blocks)
+	res, err := store.QueryEntities(context.Background(), "$all", &sqlitestore.Options{
+		AtBlock:     &atBlock,
+		IncludeData: &sqlitestore.IncludeData{Key: true},
+	})
+	require.NoError(t, err)
+	var stored []common.Hash
+	for _, data := range res.Data {
+		var ed sqlitestore.EntityData
+		require.NoError(t, json.Unmarshal(data, &ed))
+		stored = append(stored, *ed.Key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Cmp(keys[j]) < 0 })
+	sort.Slice(stored, func(i, j int) bool { return stored[i].Cmp(stored[j]) < 0 })
+	require.Equal(t, keys, stored)
+	store.Close()
+
+	// Importing the chain again finds the database at its head
+	require.Contains(t, importChain(), "Arkiv database at the imported head 5")
+}
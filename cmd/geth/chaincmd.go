@@ -17,10 +17,12 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"regexp"
 	"runtime"
@@ -28,6 +30,7 @@ import (
 	"strconv"
 	"strings"
 	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/ethereum/go-ethereum/cmd/utils"
@@ -39,6 +42,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/internal/debug"
 	"github.com/ethereum/go-ethereum/internal/era"
@@ -119,6 +123,7 @@ if one is set.  Otherwise it prints the genesis from the datadir.`,
 			utils.LogNoHistoryFlag,
 			utils.LogExportCheckpointsFlag,
 			utils.StateHistoryFlag,
+			utils.ArkivImportCatchUpFlag,
 		}, utils.DatabaseFlags, debug.Flags),
 		Before: func(ctx *cli.Context) error {
 			flags.MigrateGlobalFlags(ctx)
@@ -130,7 +135,11 @@ containing multiple RLP-encoded blocks, or multiple files can be given.
 
 If only one file is used, an import error will result in the entire import process failing. If
 multiple files are processed, the import process will continue even if an individual RLP file fails
-to import successfully.`,
+to import successfully.
+
+The Arkiv database doesn't see the imported blocks. Once the import is done, the command checks it
+against the imported head and prints how to catch it up, or feeds it the missing blocks itself with
+--arkiv.import.catchup.`,
 	}
 	exportCommand = &cli.Command{
 		Action:    exportChain,
@@ -396,6 +405,13 @@ func importChain(ctx *cli.Context) error {
 	chain.Stop()
 	fmt.Printf("Import done in %v.\n\n", time.Since(start))
 
+	if err := checkArkivStore(ctx, stack, db); err != nil {
+		log.Error("Arkiv database error", "err", err)
+		if importErr == nil {
+			importErr = err
+		}
+	}
+
 	// Output pre-compaction stats mostly to see the import trashing
 	showDBStats(db)
 
@@ -424,6 +440,50 @@ func importChain(ctx *cli.Context) error {
 	return importErr
 }
 
+// checkArkivStore checks the Arkiv database against the head of the imported chain.
+// The blocks it's missing are fed to it with --arkiv.import.catchup, otherwise the
+// command prints how to catch it up.
+func checkArkivStore(ctx *cli.Context, stack *node.Node, db ethdb.Database) error {
+	interrupt, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	lag, err := eth.ReadArkivStoreLag(interrupt, stack.Config(), db)
+	if err != nil {
+		return err
+	}
+	switch {
+	case lag.Path == "":
+		// The database in memory is rebuilt on every start
+		return nil
+	case lag.Ahead():
+		return fmt.Errorf("the Arkiv database %s is at block %d, after the imported head %d: remove it to rebuild it from the chain", lag.Path, lag.LastBlock, lag.Head)
+	case !lag.Behind():
+		fmt.Printf("Arkiv database at the imported head %d.\n\n", lag.Head)
+		return nil
+	case !ctx.Bool(utils.ArkivImportCatchUpFlag.Name):
+		fmt.Printf("The Arkiv database %s is at block %d, behind the imported head %d.\n", lag.Path, lag.LastBlock, lag.Head)
+		fmt.Printf("The node feeds it the missing blocks once it's started and follows a new head, or run the import again with --%s to catch it up now.\n\n", utils.ArkivImportCatchUpFlag.Name)
+		return nil
+	}
+
+	fmt.Printf("Catching up the Arkiv database %s from block %d to %d...\n", lag.Path, lag.LastBlock, lag.Head)
+	start, reported := time.Now(), time.Now()
+	lag, err = eth.CatchUpArkivStore(interrupt, stack.Config(), db, func(lastBlock, head uint64) {
+		if time.Since(reported) > 8*time.Second || lastBlock >= head {
+			fmt.Printf("Arkiv database at block %d of %d, elapsed %v\n", lastBlock, head, common.PrettyDuration(time.Since(start)))
+			reported = time.Now()
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if lag.Behind() {
+		return fmt.Errorf("the Arkiv database %s stopped at block %d, before the imported head %d", lag.Path, lag.LastBlock, lag.Head)
+	}
+	fmt.Printf("Arkiv database caught up in %v.\n\n", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
 func exportChain(ctx *cli.Context) error {
 	if ctx.Args().Len() < 1 {
 		utils.Fatalf("This command requires an argument.")
@@ -90,6 +90,8 @@ var (
 		utils.TxPoolLifetimeFlag,
 		utils.TxPoolMaxTxGasLimitFlag,
 		utils.TxPoolDisableNonGolemBaseTransactions,
+		utils.TxPoolArkivSlotsFractionFlag,
+		utils.TxPoolArkivEncryptionSchemesFlag,
 		utils.BlobPoolDataDirFlag,
 		utils.BlobPoolDataCapFlag,
 		utils.BlobPoolPriceBumpFlag,
@@ -104,6 +106,59 @@ var (
 		utils.LogHistoryFlag,
 		utils.ArkivHistoricBlocksFlag,
 		utils.ArkivDatabaseDisabledFlag,
+		utils.ArkivSkipPrunedFlag,
+		utils.ArkivReceiptRetentionFlag,
+		utils.ArkivSkipPoisonFlag,
+		utils.ArkivShadowEnforcementFlag,
+		utils.ArkivStoreCompressFlag,
+		utils.ArkivShardsFlag,
+		utils.ArkivPerKindOpIndexFlag,
+		utils.ArkivEventsBatchSizeFlag,
+		utils.ArkivEventsConcurrencyFlag,
+		utils.ArkivEventsStuckHeadsFlag,
+		utils.ArkivSelfCheckWarnOnlyFlag,
+		utils.ArkivHookBudgetFlag,
+		utils.ArkivFullTextFlag,
+		utils.ArkivFullTextContentTypesFlag,
+		utils.ArkivFullTextMaxSizeFlag,
+		utils.ArkivQueryMaxScanFractionFlag,
+		utils.ArkivQueryMemoryBudgetFlag,
+		utils.ArkivQueryMemoryWaitFlag,
+		utils.ArkivQueryTimeoutFlag,
+		utils.ArkivQueryMaxRowsFlag,
+		utils.ArkivQueryMaxBytesFlag,
+		utils.ArkivLegacyJSONFlag,
+		utils.ArkivRPCEnableFlag,
+		utils.ArkivRPCDisableFlag,
+		utils.ArkivWebhookURLsFlag,
+		utils.ArkivWebhookSecretFlag,
+		utils.ArkivWebhookOwnersFlag,
+		utils.ArkivWebhookKeysFlag,
+		utils.ArkivWebhookKindsFlag,
+		utils.ArkivWebhookConfirmationsFlag,
+		utils.ArkivWebhookMaxQueueFlag,
+		utils.ArkivTxBroadcastToFlag,
+		utils.ArkivTxBroadcastMaxSizeFlag,
+		utils.ArkivReadOnlyFlag,
+		utils.ArkivReadOnlyUpstreamFlag,
+		utils.ArkivDABackpressureBlocksFlag,
+		utils.ArkivDABackpressureMinSizeFlag,
+		utils.ArkivDABackpressureBlockBudgetFlag,
+		utils.ArkivWarmupFileFlag,
+		utils.ArkivWarmupPopularFlag,
+		utils.ArkivWarmupGateFlag,
+		utils.ArkivGatewayAddrFlag,
+		utils.ArkivGatewayPortFlag,
+		utils.ArkivGatewayCORSDomainFlag,
+		utils.ArkivGatewayVirtualHostsFlag,
+		utils.ArkivGatewayRateLimitFlag,
+		utils.ArkivGatewayBurstFlag,
+		utils.ArkivGatewayKeysFlag,
+		utils.ArkivGatewaySignFlag,
+		utils.ArkivRelayerKeyFlag,
+		utils.ArkivContentResolverFlag,
+		utils.ArkivContentAttemptsFlag,
+		utils.ArkivContentTimeoutFlag,
 		utils.LogNoHistoryFlag,
 		utils.LogExportCheckpointsFlag,
 		utils.StateHistoryFlag,
@@ -0,0 +1,178 @@
+package challenge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/arkiv/rpctypes"
+	"github.com/ethereum/go-ethereum/arkiv/storagechallenge"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/urfave/cli/v2"
+)
+
+// Report is the outcome of a storage challenge of a replica.
+type Report struct {
+	Block   hexutil.Uint64            `json:"block"`
+	Nonce   hexutil.Bytes             `json:"nonce"`
+	Results []storagechallenge.Result `json:"results"`
+}
+
+func Challenge() *cli.Command {
+	cfg := struct {
+		nodeURL      string
+		referenceURL string
+		payloadDir   string
+		keys         cli.StringSlice
+	}{}
+	return &cli.Command{
+		Name:  "challenge",
+		Usage: "Challenge a replica to prove it stores the payloads of entities",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:        "node-url",
+				Usage:       "The URL of the replica to challenge",
+				Value:       "http://localhost:8545",
+				EnvVars:     []string{"NODE_URL"},
+				Destination: &cfg.nodeURL,
+			},
+			&cli.StringFlag{
+				Name:        "reference-url",
+				Usage:       "The URL of a trusted node answering the same challenge with the expected responses",
+				Destination: &cfg.referenceURL,
+			},
+			&cli.StringFlag{
+				Name:        "payload-dir",
+				Usage:       "The directory holding the expected payloads, in files named by the keys of the entities",
+				Destination: &cfg.payloadDir,
+			},
+			&cli.StringSliceFlag{
+				Name:        "key",
+				Usage:       "The key of an entity to challenge, can be repeated",
+				Required:    true,
+				Destination: &cfg.keys,
+			},
+		},
+		Action: func(c *cli.Context) error {
+
+			ctx, stop := signal.NotifyContext(c.Context, os.Interrupt)
+			defer stop()
+
+			if (cfg.referenceURL == "") == (cfg.payloadDir == "") {
+				return fmt.Errorf("one of --reference-url and --payload-dir is required")
+			}
+
+			keys := []common.Hash{}
+			for _, key := range cfg.keys.Value() {
+				b, err := hexutil.Decode(key)
+				if err != nil || len(b) != common.HashLength {
+					return fmt.Errorf("invalid entity key: %s", key)
+				}
+				keys = append(keys, common.BytesToHash(b))
+			}
+
+			nonce, err := storagechallenge.NewNonce()
+			if err != nil {
+				return err
+			}
+
+			challenge, err := challengeNode(ctx, cfg.nodeURL, keys, nonce)
+			if err != nil {
+				return fmt.Errorf("failed to challenge the replica: %w", err)
+			}
+
+			var expected []*common.Hash
+			if cfg.referenceURL != "" {
+				reference, err := challengeNode(ctx, cfg.referenceURL, keys, nonce)
+				if err != nil {
+					return fmt.Errorf("failed to challenge the reference node: %w", err)
+				}
+				if reference.Block != challenge.Block {
+					fmt.Fprintf(os.Stderr, "warning: the replica answered at block %d, the reference node at block %d\n", challenge.Block, reference.Block)
+				}
+				expected = responses(reference)
+			} else {
+				if expected, err = payloadResponses(cfg.payloadDir, keys, nonce); err != nil {
+					return err
+				}
+			}
+
+			results, err := storagechallenge.Verify(keys, expected, responses(challenge))
+			if err != nil {
+				return err
+			}
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(&Report{Block: challenge.Block, Nonce: nonce, Results: results}); err != nil {
+				return err
+			}
+
+			failed := 0
+			for _, result := range results {
+				if result.Status == storagechallenge.StatusMissing || result.Status == storagechallenge.StatusCorrupted {
+					failed++
+				}
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d of %d entities missing or corrupted", failed, len(results))
+			}
+			return nil
+		},
+	}
+}
+
+// challengeNode sends the challenge to the node.
+func challengeNode(ctx context.Context, url string, keys []common.Hash, nonce []byte) (*rpctypes.StorageChallenge, error) {
+	rpcClient, err := rpc.DialContext(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to node: %w", err)
+	}
+	defer rpcClient.Close()
+
+	challenge := &rpctypes.StorageChallenge{}
+	if err := rpcClient.CallContext(ctx, challenge, "arkiv_storageChallenge", keys, hexutil.Bytes(nonce)); err != nil {
+		return nil, err
+	}
+	if len(challenge.Responses) != len(keys) {
+		return nil, fmt.Errorf("%d responses for %d keys", len(challenge.Responses), len(keys))
+	}
+	return challenge, nil
+}
+
+// responses returns the responses of the challenge, in the order of the keys.
+func responses(challenge *rpctypes.StorageChallenge) []*common.Hash {
+	hashes := make([]*common.Hash, len(challenge.Responses))
+	for i, response := range challenge.Responses {
+		hashes[i] = response.Response
+	}
+	return hashes
+}
+
+// payloadResponses returns the responses expected for the payloads of the keys in the
+// directory, nil for the keys without a file. The files are streamed through the hash.
+func payloadResponses(dir string, keys []common.Hash, nonce []byte) ([]*common.Hash, error) {
+	hashes := make([]*common.Hash, len(keys))
+	for i, key := range keys {
+		f, err := os.Open(filepath.Join(dir, key.Hex()))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		response, err := storagechallenge.Response(nonce, f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash the payload of %s: %w", key.Hex(), err)
+		}
+		hashes[i] = &response
+	}
+	return hashes, nil
+}
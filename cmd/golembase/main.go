@@ -7,9 +7,11 @@ import (
 	"github.com/ethereum/go-ethereum/cmd/golembase/account"
 	"github.com/ethereum/go-ethereum/cmd/golembase/blocks"
 	"github.com/ethereum/go-ethereum/cmd/golembase/cat"
+	"github.com/ethereum/go-ethereum/cmd/golembase/challenge"
 	"github.com/ethereum/go-ethereum/cmd/golembase/entity"
 	"github.com/ethereum/go-ethereum/cmd/golembase/query"
 	"github.com/ethereum/go-ethereum/cmd/golembase/state"
+	"github.com/ethereum/go-ethereum/cmd/golembase/usage"
 	"github.com/urfave/cli/v2"
 )
 
@@ -27,6 +29,8 @@ func main() {
 			cat.Cat(),
 			query.Query(),
 			state.State(),
+			usage.Usage(),
+			challenge.Challenge(),
 		},
 	}
 
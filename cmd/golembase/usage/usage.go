@@ -0,0 +1,139 @@
+package usage
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/urfave/cli/v2"
+)
+
+// OwnerUsageReport is the usage report returned by arkiv_getOwnerUsageReport.
+type OwnerUsageReport struct {
+	Owner        common.Address `json:"owner"`
+	FromBlock    hexutil.Uint64 `json:"fromBlock"`
+	ToBlock      hexutil.Uint64 `json:"toBlock"`
+	Creates      uint64         `json:"creates"`
+	Updates      uint64         `json:"updates"`
+	Deletes      uint64         `json:"deletes"`
+	PayloadBytes uint64         `json:"payloadBytes"`
+	SlotBlocks   hexutil.Uint64 `json:"slotBlocks"`
+	GasUsed      hexutil.Uint64 `json:"gasUsed"`
+}
+
+var csvHeader = []string{"owner", "fromBlock", "toBlock", "creates", "updates", "deletes", "payloadBytes", "slotBlocks", "gasUsed"}
+
+func (r *OwnerUsageReport) csvRecord() []string {
+	return []string{
+		r.Owner.Hex(),
+		strconv.FormatUint(uint64(r.FromBlock), 10),
+		strconv.FormatUint(uint64(r.ToBlock), 10),
+		strconv.FormatUint(r.Creates, 10),
+		strconv.FormatUint(r.Updates, 10),
+		strconv.FormatUint(r.Deletes, 10),
+		strconv.FormatUint(r.PayloadBytes, 10),
+		strconv.FormatUint(uint64(r.SlotBlocks), 10),
+		strconv.FormatUint(uint64(r.GasUsed), 10),
+	}
+}
+
+func Usage() *cli.Command {
+	cfg := struct {
+		nodeURL string
+		owners  cli.StringSlice
+		from    uint64
+		to      uint64
+		csv     bool
+	}{}
+	return &cli.Command{
+		Name:  "usage",
+		Usage: "Report the storage usage of owners over a range of blocks",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:        "node-url",
+				Usage:       "The URL of the node to connect to",
+				Value:       "http://localhost:8545",
+				EnvVars:     []string{"NODE_URL"},
+				Destination: &cfg.nodeURL,
+			},
+			&cli.StringSliceFlag{
+				Name:        "owner",
+				Usage:       "The owner to report on, can be repeated",
+				Required:    true,
+				Destination: &cfg.owners,
+			},
+			&cli.Uint64Flag{
+				Name:        "from",
+				Usage:       "The first block of the range",
+				Required:    true,
+				Destination: &cfg.from,
+			},
+			&cli.Uint64Flag{
+				Name:        "to",
+				Usage:       "The last block of the range",
+				Required:    true,
+				Destination: &cfg.to,
+			},
+			&cli.BoolFlag{
+				Name:        "csv",
+				Usage:       "Print the reports as CSV, one row per owner",
+				Destination: &cfg.csv,
+			},
+		},
+		Action: func(c *cli.Context) error {
+
+			ctx, stop := signal.NotifyContext(c.Context, os.Interrupt)
+			defer stop()
+
+			rpcClient, err := rpc.Dial(cfg.nodeURL)
+			if err != nil {
+				return fmt.Errorf("failed to connect to node: %w", err)
+			}
+			defer rpcClient.Close()
+
+			reports := []*OwnerUsageReport{}
+			for _, owner := range cfg.owners.Value() {
+				if !common.IsHexAddress(owner) {
+					return fmt.Errorf("invalid owner address: %s", owner)
+				}
+				report := &OwnerUsageReport{}
+				err = rpcClient.CallContext(
+					ctx,
+					report,
+					"arkiv_getOwnerUsageReport",
+					common.HexToAddress(owner),
+					hexutil.Uint64(cfg.from),
+					hexutil.Uint64(cfg.to),
+				)
+				if err != nil {
+					return fmt.Errorf("failed to get usage report of %s: %w", owner, err)
+				}
+				reports = append(reports, report)
+			}
+
+			if !cfg.csv {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				return enc.Encode(reports)
+			}
+
+			w := csv.NewWriter(os.Stdout)
+			if err := w.Write(csvHeader); err != nil {
+				return err
+			}
+			for _, report := range reports {
+				if err := w.Write(report.csvRecord()); err != nil {
+					return err
+				}
+			}
+			w.Flush()
+			return w.Error()
+		},
+	}
+}
@@ -36,7 +36,10 @@ import (
 
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/arkiv/address"
 	"github.com/ethereum/go-ethereum/arkiv/dbevents"
+	"github.com/ethereum/go-ethereum/arkiv/fulltext"
+	"github.com/ethereum/go-ethereum/arkiv/webhook"
 	bparams "github.com/ethereum/go-ethereum/beacon/params"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/fdlimit"
@@ -511,6 +514,18 @@ var (
 		Value:    ethconfig.Defaults.TxPool.DisableNonGolemBaseTransactions,
 		Category: flags.TxPoolCategory,
 	}
+	TxPoolArkivSlotsFractionFlag = &cli.Float64Flag{
+		Name:     "txpool.arkivslotsfraction",
+		Usage:    "Maximum fraction of the pool slots that can be occupied by Arkiv transactions (0 = no cap)",
+		Value:    ethconfig.Defaults.TxPool.ArkivSlotsFraction,
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolArkivEncryptionSchemesFlag = &cli.StringSliceFlag{
+		Name:     "txpool.arkivencryptionschemes",
+		Usage:    "Encryption schemes accepted in the encryption info of Arkiv transactions (empty = any scheme)",
+		Value:    cli.NewStringSlice(ethconfig.Defaults.TxPool.ArkivEncryptionSchemes...),
+		Category: flags.TxPoolCategory,
+	}
 	// Blob transaction pool settings
 	BlobPoolDataDirFlag = &cli.StringFlag{
 		Name:     "blobpool.datadir",
@@ -962,6 +977,306 @@ var (
 		Category: flags.MiscCategory,
 		Value:    false,
 	}
+	ArkivSkipPrunedFlag = &cli.BoolFlag{
+		Name:     "arkiv.skip-pruned",
+		Usage:    "Skip indexing of blocks whose receipts were pruned, leaving a gap in the Arkiv database",
+		Category: flags.MiscCategory,
+		Value:    false,
+	}
+	ArkivReceiptRetentionFlag = &cli.Uint64Flag{
+		Name:     "arkiv.receipts.retention",
+		Usage:    "Blocks after their indexing the receipts of blocks without Arkiv logs are kept for (0 = keep them)",
+		Category: flags.MiscCategory,
+	}
+	ArkivSkipPoisonFlag = &cli.BoolFlag{
+		Name:     "arkiv.skip-poison",
+		Usage:    "Skip a batch the Arkiv database failed to ingest after retries, once written to the dead-letter queue, instead of halting the indexing",
+		Category: flags.MiscCategory,
+		Value:    false,
+	}
+	ArkivShadowEnforcementFlag = &cli.BoolFlag{
+		Name:     "arkiv.shadow-enforcement",
+		Usage:    "Check the Arkiv consensus rules on every transaction before their forks activate, recording the transactions that would violate them without affecting the execution",
+		Category: flags.MiscCategory,
+		Value:    false,
+	}
+	ArkivStoreCompressFlag = &cli.BoolFlag{
+		Name:     "arkiv.store.compress",
+		Usage:    "Keep the payloads of the Arkiv entities brotli compressed in the Arkiv database, set when the database is created",
+		Category: flags.MiscCategory,
+		Value:    false,
+	}
+	ArkivShardsFlag = &cli.StringSliceFlag{
+		Name:     "arkiv.shards",
+		Usage:    "Rules keeping the Arkiv entities of a namespace or of creators with an address prefix in a shard of the Arkiv database, as <shard>=namespace:<value> or <shard>=owner:<0x prefix>",
+		Category: flags.MiscCategory,
+	}
+	ArkivPerKindOpIndexFlag = &cli.BoolFlag{
+		Name:     "arkiv.events.perkindopindex",
+		Usage:    "Number the Arkiv operations of a transaction per kind like the previous releases instead of in their canonical order (deprecated, removed in the next release)",
+		Category: flags.MiscCategory,
+		Value:    false,
+	}
+	ArkivEventsBatchSizeFlag = &cli.Uint64Flag{
+		Name:     "arkiv.events.batchsize",
+		Usage:    "Maximum number of blocks the Arkiv database ingests at once, raise it to catch up with a large backlog faster",
+		Category: flags.MiscCategory,
+		Value:    dbevents.DefaultBatchSize,
+	}
+	ArkivEventsConcurrencyFlag = &cli.IntFlag{
+		Name:     "arkiv.events.concurrency",
+		Usage:    "Number of blocks the Arkiv database reads from the chain at once while it ingests a batch",
+		Category: flags.MiscCategory,
+		Value:    dbevents.DefaultConcurrency,
+	}
+	ArkivEventsStuckHeadsFlag = &cli.Uint64Flag{
+		Name:     "arkiv.events.stuckheads",
+		Usage:    "Number of new heads the Arkiv events iterator can miss before it's reported stuck",
+		Category: flags.MiscCategory,
+		Value:    dbevents.DefaultStuckHeads,
+	}
+	ArkivSelfCheckWarnOnlyFlag = &cli.BoolFlag{
+		Name:     "arkiv.selfcheck.warnonly",
+		Usage:    "Start the node even when the Arkiv self-check fails, only logging the failed checks",
+		Category: flags.MiscCategory,
+		Value:    false,
+	}
+	ArkivImportCatchUpFlag = &cli.BoolFlag{
+		Name:     "arkiv.import.catchup",
+		Usage:    "Feed the Arkiv database the imported blocks once geth import is done, instead of printing how to catch it up",
+		Category: flags.MiscCategory,
+		Value:    false,
+	}
+	ArkivHookBudgetFlag = &cli.DurationFlag{
+		Name:     "arkiv.hooks.budget",
+		Usage:    "How long the import of a block waits for the Arkiv block hooks before moving on",
+		Category: flags.MiscCategory,
+		Value:    dbevents.DefaultHookBudget,
+	}
+	ArkivFullTextFlag = &cli.BoolFlag{
+		Name:     "arkiv.fulltext",
+		Usage:    "Enable the full-text index of the payloads of Arkiv entities (requires a build with the sqlite_fts5 tag)",
+		Category: flags.MiscCategory,
+		Value:    false,
+	}
+	ArkivFullTextContentTypesFlag = &cli.StringSliceFlag{
+		Name:     "arkiv.fulltext.contenttypes",
+		Usage:    "Content types of the payloads included in the Arkiv full-text index, e.g. text/* or application/json",
+		Category: flags.MiscCategory,
+		Value:    cli.NewStringSlice(fulltext.DefaultContentTypes...),
+	}
+	ArkivFullTextMaxSizeFlag = &cli.Uint64Flag{
+		Name:     "arkiv.fulltext.maxsize",
+		Usage:    "Maximum size in bytes of the payloads included in the Arkiv full-text index",
+		Category: flags.MiscCategory,
+		Value:    fulltext.DefaultMaxPayloadSize,
+	}
+	ArkivQueryMaxScanFractionFlag = &cli.Float64Flag{
+		Name:     "arkiv.query.maxscanfraction",
+		Usage:    "Maximum estimated fraction of the live Arkiv entities a query can select without allowFullScan (0 = no limit)",
+		Category: flags.MiscCategory,
+		Value:    eth.DefaultArkivQueryMaxScanFraction,
+	}
+	ArkivQueryMemoryBudgetFlag = &cli.Uint64Flag{
+		Name:     "arkiv.query.membudget",
+		Usage:    "Estimated memory in bytes the running Arkiv queries can hold at once (0 = no limit)",
+		Category: flags.MiscCategory,
+		Value:    eth.DefaultArkivQueryMemoryBudget,
+	}
+	ArkivQueryMemoryWaitFlag = &cli.DurationFlag{
+		Name:     "arkiv.query.memwait",
+		Usage:    "How long an Arkiv query waits for the memory budget before failing (0 = fail right away)",
+		Category: flags.MiscCategory,
+	}
+	ArkivQueryTimeoutFlag = &cli.DurationFlag{
+		Name:     "arkiv.query.timeout",
+		Usage:    "How long an Arkiv query can run in the store before failing (0 = no limit)",
+		Category: flags.MiscCategory,
+		Value:    eth.DefaultArkivQueryTimeout,
+	}
+	ArkivQueryMaxRowsFlag = &cli.Uint64Flag{
+		Name:     "arkiv.query.maxrows",
+		Usage:    "Maximum number of rows an Arkiv query can return (0 = no limit)",
+		Category: flags.MiscCategory,
+		Value:    eth.DefaultArkivQueryMaxRows,
+	}
+	ArkivQueryMaxBytesFlag = &cli.Uint64Flag{
+		Name:     "arkiv.query.maxbytes",
+		Usage:    "Maximum size in bytes of the rows an Arkiv query can return (0 = no limit)",
+		Category: flags.MiscCategory,
+		Value:    eth.DefaultArkivQueryMaxBytes,
+	}
+	ArkivLegacyJSONFlag = &cli.BoolFlag{
+		Name:     "arkiv.rpc.legacyjson",
+		Usage:    "Also emit the deprecated snake_case fields in arkiv RPC responses (removed in the next release)",
+		Category: flags.MiscCategory,
+		Value:    false,
+	}
+	ArkivRPCEnableFlag = &cli.StringSliceFlag{
+		Name:     "arkiv.rpc.enable",
+		Usage:    "Methods of the arkiv namespace to serve, like arkiv_getEntity, the others fail with a method disabled error (default: all)",
+		Category: flags.MiscCategory,
+	}
+	ArkivRPCDisableFlag = &cli.StringSliceFlag{
+		Name:     "arkiv.rpc.disable",
+		Usage:    "Methods of the arkiv namespace to disable, like arkiv_query",
+		Category: flags.MiscCategory,
+	}
+	ArkivWebhookURLsFlag = &cli.StringSliceFlag{
+		Name:     "arkiv.webhook.url",
+		Usage:    "URLs the Arkiv entity events of the confirmed blocks are posted to (enables the webhooks)",
+		Category: flags.MiscCategory,
+	}
+	ArkivWebhookSecretFlag = &cli.StringFlag{
+		Name:     "arkiv.webhook.secret",
+		Usage:    "Shared secret used to sign the Arkiv webhook requests with HMAC-SHA256",
+		Category: flags.MiscCategory,
+	}
+	ArkivWebhookOwnersFlag = &cli.StringSliceFlag{
+		Name:     "arkiv.webhook.owners",
+		Usage:    "Only post the events of the entities owned by these addresses",
+		Category: flags.MiscCategory,
+	}
+	ArkivWebhookKeysFlag = &cli.StringSliceFlag{
+		Name:     "arkiv.webhook.keys",
+		Usage:    "Only post the events of the entities with these keys",
+		Category: flags.MiscCategory,
+	}
+	ArkivWebhookKindsFlag = &cli.StringSliceFlag{
+		Name:     "arkiv.webhook.kinds",
+		Usage:    "Only post the events of these kinds (created, updated, deleted, expired, extended, ownerChanged)",
+		Category: flags.MiscCategory,
+	}
+	ArkivWebhookConfirmationsFlag = &cli.Uint64Flag{
+		Name:     "arkiv.webhook.confirmations",
+		Usage:    "Number of confirmations before the events of a block are posted (0 = wait for finalization)",
+		Category: flags.MiscCategory,
+		Value:    0,
+	}
+	ArkivWebhookMaxQueueFlag = &cli.IntFlag{
+		Name:     "arkiv.webhook.maxqueue",
+		Usage:    "Maximum number of undelivered Arkiv webhook requests kept for retry, the oldest are dropped",
+		Category: flags.MiscCategory,
+		Value:    webhook.DefaultMaxQueue,
+	}
+	ArkivTxBroadcastToFlag = &cli.StringSliceFlag{
+		Name:     "arkiv.txbroadcast.to",
+		Usage:    "Recipients whose small transactions are sent in full to all peers instead of being announced (default: the Arkiv processor)",
+		Category: flags.MiscCategory,
+	}
+	ArkivTxBroadcastMaxSizeFlag = &cli.Uint64Flag{
+		Name:     "arkiv.txbroadcast.maxsize",
+		Usage:    "Encoded size in bytes up to which the transactions to --arkiv.txbroadcast.to are sent in full to all peers (0 = disabled)",
+		Category: flags.MiscCategory,
+		Value:    eth.DefaultArkivTxBroadcastMaxSize,
+	}
+	ArkivReadOnlyFlag = &cli.BoolFlag{
+		Name:     "arkiv.readonly",
+		Usage:    "Run as a read replica serving the Arkiv API, without transaction gossip nor block building",
+		Category: flags.MiscCategory,
+	}
+	ArkivReadOnlyUpstreamFlag = &cli.StringFlag{
+		Name:     "arkiv.readonly.upstream",
+		Usage:    "HTTP endpoint of the sequencer the transactions submitted to a read replica are forwarded to (default: rejected)",
+		Category: flags.MiscCategory,
+	}
+	ArkivDABackpressureBlocksFlag = &cli.Uint64Flag{
+		Name:     "arkiv.dabackpressure.blocks",
+		Usage:    "Blocks of DA backlog in the pending pool beyond which the sequencer rejects large transactions submitted over RPC (0 = disabled)",
+		Category: flags.MiscCategory,
+	}
+	ArkivDABackpressureMinSizeFlag = &cli.Uint64Flag{
+		Name:     "arkiv.dabackpressure.minsize",
+		Usage:    "Calldata size in bytes from which submitted transactions are subject to the DA back-pressure",
+		Category: flags.MiscCategory,
+		Value:    eth.DefaultArkivDABackpressureMinSize,
+	}
+	ArkivDABackpressureBlockBudgetFlag = &cli.Uint64Flag{
+		Name:     "arkiv.dabackpressure.blockbudget",
+		Usage:    "DA budget of a block in bytes the backlog is measured with, unless the batcher sets one (0 = the batcher's only)",
+		Category: flags.MiscCategory,
+	}
+	ArkivWarmupFileFlag = &cli.StringFlag{
+		Name:     "arkiv.warmup.file",
+		Usage:    "JSON file listing the Arkiv queries run at startup to warm up the query caches",
+		Category: flags.MiscCategory,
+	}
+	ArkivWarmupPopularFlag = &cli.IntFlag{
+		Name:     "arkiv.warmup.popular",
+		Usage:    "Number of the most popular Arkiv queries persisted across restarts and run at startup to warm up the query caches (0 = disabled)",
+		Category: flags.MiscCategory,
+		Value:    eth.DefaultArkivWarmupPopular,
+	}
+	ArkivWarmupGateFlag = &cli.BoolFlag{
+		Name:     "arkiv.warmup.gate",
+		Usage:    "Report the node as not ready in arkiv_syncStatus until the warmup of the query caches completes",
+		Category: flags.MiscCategory,
+	}
+	ArkivGatewayAddrFlag = &cli.StringFlag{
+		Name:     "arkiv.gateway.addr",
+		Usage:    "Interface of the Arkiv query gateway, serving the arkiv namespace alone to the public (default: disabled)",
+		Category: flags.MiscCategory,
+	}
+	ArkivGatewayPortFlag = &cli.IntFlag{
+		Name:     "arkiv.gateway.port",
+		Usage:    "Port of the Arkiv query gateway",
+		Category: flags.MiscCategory,
+		Value:    node.DefaultArkivGatewayPort,
+	}
+	ArkivGatewayCORSDomainFlag = &cli.StringSliceFlag{
+		Name:     "arkiv.gateway.corsdomain",
+		Usage:    "Domains from which the Arkiv query gateway accepts cross origin requests (browser enforced)",
+		Category: flags.MiscCategory,
+	}
+	ArkivGatewayVirtualHostsFlag = &cli.StringSliceFlag{
+		Name:     "arkiv.gateway.vhosts",
+		Usage:    "Virtual hostnames from which the Arkiv query gateway accepts requests (server enforced). Accepts '*' wildcard.",
+		Category: flags.MiscCategory,
+		Value:    cli.NewStringSlice("localhost"),
+	}
+	ArkivGatewayRateLimitFlag = &cli.Float64Flag{
+		Name:     "arkiv.gateway.ratelimit",
+		Usage:    "Calls per second the Arkiv query gateway serves to an IP, or an API key without a quota (0 = unlimited)",
+		Category: flags.MiscCategory,
+	}
+	ArkivGatewayBurstFlag = &cli.IntFlag{
+		Name:     "arkiv.gateway.burst",
+		Usage:    "Calls in a burst the Arkiv query gateway serves to an IP, or an API key without a quota",
+		Category: flags.MiscCategory,
+		Value:    100,
+	}
+	ArkivGatewayKeysFlag = &cli.StringFlag{
+		Name:     "arkiv.gateway.keys",
+		Usage:    "JSON file of the API keys the requests to the Arkiv query gateway must carry and their quotas, reloaded when it changes",
+		Category: flags.MiscCategory,
+	}
+	ArkivGatewaySignFlag = &cli.BoolFlag{
+		Name:     "arkiv.gateway.sign",
+		Usage:    "Sign the responses of the Arkiv query gateway and the hash of the head block with the node key",
+		Category: flags.MiscCategory,
+	}
+	ArkivRelayerKeyFlag = &cli.StringFlag{
+		Name:     "arkiv.relayer.key",
+		Usage:    "File of the private key of the account sending the Arkiv operations signed as typed data (arkiv_sendSignedOperation)",
+		Category: flags.MiscCategory,
+	}
+	ArkivContentResolverFlag = &cli.StringFlag{
+		Name:     "arkiv.content.resolver",
+		Usage:    "Base URL of the data availability service the Arkiv payloads stored off chain are fetched from (GET <url>/<pointer>)",
+		Category: flags.MiscCategory,
+	}
+	ArkivContentAttemptsFlag = &cli.IntFlag{
+		Name:     "arkiv.content.attempts",
+		Usage:    "How many times an Arkiv payload stored off chain is fetched before it's left pending",
+		Category: flags.MiscCategory,
+		Value:    dbevents.DefaultContentResolveAttempts,
+	}
+	ArkivContentTimeoutFlag = &cli.DurationFlag{
+		Name:     "arkiv.content.timeout",
+		Usage:    "How long a fetch of an Arkiv payload stored off chain can take",
+		Category: flags.MiscCategory,
+		Value:    dbevents.DefaultContentResolveTimeout,
+	}
 
 	// Console
 	JSpathFlag = &flags.DirectoryFlag{
@@ -1639,6 +1954,44 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	}
 
 	cfg.ArkivDatabaseDisabled = ctx.Bool(ArkivDatabaseDisabledFlag.Name)
+	cfg.ArkivSkipPruned = ctx.Bool(ArkivSkipPrunedFlag.Name)
+	cfg.ArkivReceiptRetention = ctx.Uint64(ArkivReceiptRetentionFlag.Name)
+	cfg.ArkivSkipPoison = ctx.Bool(ArkivSkipPoisonFlag.Name)
+	cfg.ArkivShadowEnforcement = ctx.Bool(ArkivShadowEnforcementFlag.Name)
+	cfg.ArkivStoreCompress = ctx.Bool(ArkivStoreCompressFlag.Name)
+	cfg.ArkivShards = ctx.StringSlice(ArkivShardsFlag.Name)
+	cfg.ArkivPerKindOpIndex = ctx.Bool(ArkivPerKindOpIndexFlag.Name)
+	cfg.ArkivEventsBatchSize = ctx.Uint64(ArkivEventsBatchSizeFlag.Name)
+	cfg.ArkivEventsConcurrency = ctx.Int(ArkivEventsConcurrencyFlag.Name)
+	cfg.ArkivEventsStuckHeads = ctx.Uint64(ArkivEventsStuckHeadsFlag.Name)
+	cfg.ArkivSelfCheckWarnOnly = ctx.Bool(ArkivSelfCheckWarnOnlyFlag.Name)
+	cfg.ArkivHookBudget = ctx.Duration(ArkivHookBudgetFlag.Name)
+	cfg.ArkivFullText = ctx.Bool(ArkivFullTextFlag.Name)
+	cfg.ArkivFullTextContentTypes = ctx.StringSlice(ArkivFullTextContentTypesFlag.Name)
+	cfg.ArkivFullTextMaxPayloadSize = ctx.Uint64(ArkivFullTextMaxSizeFlag.Name)
+	cfg.ArkivQueryMaxScanFraction = ctx.Float64(ArkivQueryMaxScanFractionFlag.Name)
+	cfg.ArkivQueryMemoryBudget = ctx.Uint64(ArkivQueryMemoryBudgetFlag.Name)
+	cfg.ArkivQueryMemoryWait = ctx.Duration(ArkivQueryMemoryWaitFlag.Name)
+	cfg.ArkivQueryTimeout = ctx.Duration(ArkivQueryTimeoutFlag.Name)
+	cfg.ArkivQueryMaxRows = ctx.Uint64(ArkivQueryMaxRowsFlag.Name)
+	cfg.ArkivQueryMaxBytes = ctx.Uint64(ArkivQueryMaxBytesFlag.Name)
+	cfg.ArkivLegacyJSON = ctx.Bool(ArkivLegacyJSONFlag.Name)
+	cfg.ArkivRPCEnable = ctx.StringSlice(ArkivRPCEnableFlag.Name)
+	cfg.ArkivRPCDisable = ctx.StringSlice(ArkivRPCDisableFlag.Name)
+	setArkivWebhooks(ctx, cfg)
+	setArkivTxBroadcast(ctx, cfg)
+	setArkivReadOnly(ctx, cfg)
+	setArkivGateway(ctx, cfg)
+	cfg.ArkivRelayerKeyFile = ctx.String(ArkivRelayerKeyFlag.Name)
+	cfg.ArkivContentResolverURL = ctx.String(ArkivContentResolverFlag.Name)
+	cfg.ArkivContentResolveAttempts = ctx.Int(ArkivContentAttemptsFlag.Name)
+	cfg.ArkivContentResolveTimeout = ctx.Duration(ArkivContentTimeoutFlag.Name)
+	cfg.ArkivDABackpressureBlocks = ctx.Uint64(ArkivDABackpressureBlocksFlag.Name)
+	cfg.ArkivDABackpressureMinSize = ctx.Uint64(ArkivDABackpressureMinSizeFlag.Name)
+	cfg.ArkivDABackpressureBlockBudget = ctx.Uint64(ArkivDABackpressureBlockBudgetFlag.Name)
+	cfg.ArkivWarmupFile = ctx.String(ArkivWarmupFileFlag.Name)
+	cfg.ArkivWarmupPopular = ctx.Int(ArkivWarmupPopularFlag.Name)
+	cfg.ArkivWarmupGate = ctx.Bool(ArkivWarmupGateFlag.Name)
 
 	// deprecation notice for log debug flags (TODO: find a more appropriate place to put these?)
 	if ctx.IsSet(LogBacktraceAtFlag.Name) {
@@ -1704,6 +2057,73 @@ func setGPO(ctx *cli.Context, cfg *gasprice.Config) {
 	}
 }
 
+func setArkivWebhooks(ctx *cli.Context, cfg *node.Config) {
+	cfg.ArkivWebhookURLs = ctx.StringSlice(ArkivWebhookURLsFlag.Name)
+	cfg.ArkivWebhookSecret = ctx.String(ArkivWebhookSecretFlag.Name)
+	cfg.ArkivWebhookKinds = ctx.StringSlice(ArkivWebhookKindsFlag.Name)
+	cfg.ArkivWebhookConfirmations = ctx.Uint64(ArkivWebhookConfirmationsFlag.Name)
+	cfg.ArkivWebhookMaxQueue = ctx.Int(ArkivWebhookMaxQueueFlag.Name)
+
+	for _, owner := range ctx.StringSlice(ArkivWebhookOwnersFlag.Name) {
+		if trimmed := strings.TrimSpace(owner); !common.IsHexAddress(trimmed) {
+			Fatalf("Invalid address in --%s: %s", ArkivWebhookOwnersFlag.Name, trimmed)
+		} else {
+			cfg.ArkivWebhookOwners = append(cfg.ArkivWebhookOwners, common.HexToAddress(trimmed))
+		}
+	}
+	for _, key := range ctx.StringSlice(ArkivWebhookKeysFlag.Name) {
+		var hash common.Hash
+		if err := hash.UnmarshalText([]byte(strings.TrimSpace(key))); err != nil {
+			Fatalf("Invalid entity key in --%s: %s", ArkivWebhookKeysFlag.Name, key)
+		}
+		cfg.ArkivWebhookKeys = append(cfg.ArkivWebhookKeys, hash)
+	}
+}
+
+func setArkivTxBroadcast(ctx *cli.Context, cfg *node.Config) {
+	cfg.ArkivTxBroadcastMaxSize = ctx.Uint64(ArkivTxBroadcastMaxSizeFlag.Name)
+	if !ctx.IsSet(ArkivTxBroadcastToFlag.Name) {
+		cfg.ArkivTxBroadcastTo = []common.Address{address.ArkivProcessorAddress}
+		return
+	}
+	for _, to := range ctx.StringSlice(ArkivTxBroadcastToFlag.Name) {
+		if trimmed := strings.TrimSpace(to); !common.IsHexAddress(trimmed) {
+			Fatalf("Invalid address in --%s: %s", ArkivTxBroadcastToFlag.Name, trimmed)
+		} else {
+			cfg.ArkivTxBroadcastTo = append(cfg.ArkivTxBroadcastTo, common.HexToAddress(trimmed))
+		}
+	}
+}
+
+func setArkivReadOnly(ctx *cli.Context, cfg *node.Config) {
+	cfg.ArkivReadOnly = ctx.Bool(ArkivReadOnlyFlag.Name)
+	if !ctx.IsSet(ArkivReadOnlyUpstreamFlag.Name) {
+		return
+	}
+	if !cfg.ArkivReadOnly {
+		Fatalf("--%s requires --%s", ArkivReadOnlyUpstreamFlag.Name, ArkivReadOnlyFlag.Name)
+	}
+	if ctx.IsSet(RollupSequencerHTTPFlag.Name) {
+		Fatalf("--%s and --%s are mutually exclusive", ArkivReadOnlyUpstreamFlag.Name, RollupSequencerHTTPFlag.Name)
+	}
+	cfg.ArkivReadOnlyUpstream = ctx.String(ArkivReadOnlyUpstreamFlag.Name)
+}
+
+// setArkivGateway configures the Arkiv query gateway, enabled by its interface.
+func setArkivGateway(ctx *cli.Context, cfg *node.Config) {
+	if !ctx.IsSet(ArkivGatewayAddrFlag.Name) {
+		return
+	}
+	cfg.ArkivGatewayHost = ctx.String(ArkivGatewayAddrFlag.Name)
+	cfg.ArkivGatewayPort = ctx.Int(ArkivGatewayPortFlag.Name)
+	cfg.ArkivGatewayCors = ctx.StringSlice(ArkivGatewayCORSDomainFlag.Name)
+	cfg.ArkivGatewayVirtualHosts = ctx.StringSlice(ArkivGatewayVirtualHostsFlag.Name)
+	cfg.ArkivGatewayRateLimit = ctx.Float64(ArkivGatewayRateLimitFlag.Name)
+	cfg.ArkivGatewayBurst = ctx.Int(ArkivGatewayBurstFlag.Name)
+	cfg.ArkivGatewayKeysFile = ctx.String(ArkivGatewayKeysFlag.Name)
+	cfg.ArkivGatewaySign = ctx.Bool(ArkivGatewaySignFlag.Name)
+}
+
 func setTxPool(ctx *cli.Context, cfg *legacypool.Config) {
 	if ctx.IsSet(TxPoolLocalsFlag.Name) {
 		locals := strings.Split(ctx.String(TxPoolLocalsFlag.Name), ",")
@@ -1751,6 +2171,12 @@ func setTxPool(ctx *cli.Context, cfg *legacypool.Config) {
 	if ctx.IsSet(TxPoolDisableNonGolemBaseTransactions.Name) {
 		cfg.DisableNonGolemBaseTransactions = ctx.Bool(TxPoolDisableNonGolemBaseTransactions.Name)
 	}
+	if ctx.IsSet(TxPoolArkivSlotsFractionFlag.Name) {
+		cfg.ArkivSlotsFraction = ctx.Float64(TxPoolArkivSlotsFractionFlag.Name)
+	}
+	if ctx.IsSet(TxPoolArkivEncryptionSchemesFlag.Name) {
+		cfg.ArkivEncryptionSchemes = ctx.StringSlice(TxPoolArkivEncryptionSchemesFlag.Name)
+	}
 	if ctx.IsSet(MinerEffectiveGasLimitFlag.Name) {
 		// While technically this is a miner config parameter, we also want the txpool to enforce
 		// it to avoid accepting transactions that can never be included in a block.
@@ -2598,7 +3024,9 @@ func MakeChain(ctx *cli.Context, stack *node.Node, readonly bool) (*core.BlockCh
 	// 	Fatalf("failed to create SQLStore: %v", err)
 	// }
 
-	batchIterator, onNewHead := dbevents.NewChainBatchIterator(chainDb, 0)
+	hooks := dbevents.NewHooks(chainDb, ctx.Duration(ArkivHookBudgetFlag.Name))
+	hooks.SetPerKindOpIndexes(ctx.Bool(ArkivPerKindOpIndexFlag.Name))
+	batchIterator, _ := dbevents.NewChainBatchIterator(chainDb, hooks, 0, ctx.Bool(ArkivSkipPrunedFlag.Name), dbevents.WithBatchSize(ctx.Uint64(ArkivEventsBatchSizeFlag.Name)), dbevents.WithConcurrency(ctx.Int(ArkivEventsConcurrencyFlag.Name)))
 
 	go func() {
 		for b := range batchIterator {
@@ -2606,9 +3034,9 @@ func MakeChain(ctx *cli.Context, stack *node.Node, readonly bool) (*core.BlockCh
 		}
 	}()
 
-	chain, err := core.NewBlockChainWithOnNewBlock(chainDb, gspec, engine, options, onNewHead)
+	chain, err := core.NewBlockChainWithHooks(chainDb, gspec, engine, options, hooks)
 	if err != nil {
-		Fatalf("Can't create BlockChain with onNewBlock: %v", err)
+		Fatalf("Can't create BlockChain with hooks: %v", err)
 	}
 	return chain, chainDb
 
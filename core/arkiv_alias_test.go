@@ -0,0 +1,121 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/arkiv/storageaccounting"
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+func aliasesConfig(active bool) *params.ChainConfig {
+	config := *params.OptimismTestConfig
+	config.ArkivOwnerSlotsTime = new(uint64)
+	if active {
+		config.ArkivAliasesTime = new(uint64)
+	}
+	return &config
+}
+
+func aliasTransaction(ops *storagetx.ArkivTransaction) *storagetx.ArkivTransaction {
+	ops.Version = storagetx.TransactionVersionAliases
+	return ops
+}
+
+func TestArkivAliases(t *testing.T) {
+	config := aliasesConfig(true)
+	statedb, err := state.New(types.EmptyRootHash, state.NewDatabaseForTesting())
+	require.NoError(t, err)
+	owner, next := common.HexToAddress("0x1"), common.HexToAddress("0x2")
+	ownerSlots := func(owner common.Address) uint64 {
+		return storageaccounting.GetNumberOfUsedSlotsByOwner(statedb, owner).Uint64()
+	}
+
+	logs := applyArkivTransaction(t, config, statedb, 1, &storagetx.ArkivTransaction{
+		Create: []storagetx.ArkivCreate{
+			{BTL: 100, ContentType: "text/plain", Payload: []byte("v1")},
+			{BTL: 100, ContentType: "text/plain", Payload: []byte("v2")},
+		},
+	})
+	first, second := logs[0].Topics[1], logs[1].Topics[1]
+	entitySlots := ownerSlots(owner)
+	nameHash := entity.AliasNameHash("docs")
+
+	// The alias is registered to the sender for its BTL
+	logs = applyArkivTransaction(t, config, statedb, 2, aliasTransaction(&storagetx.ArkivTransaction{
+		RegisterAlias: []storagetx.ArkivRegisterAlias{{Name: "docs", EntityKey: first, BTL: 5}},
+	}))
+	require.Len(t, logs, 1)
+	require.Equal(t, nameHash, logs[0].Topics[1])
+	require.Equal(t, &entity.Alias{Key: first, Owner: owner, ExpiresAtBlock: 7}, entity.GetAlias(statedb, nameHash))
+	require.Equal(t, entitySlots+entity.AliasUsedSlots, ownerSlots(owner))
+
+	// Only its owner can repoint it, and only to a live entity
+	_, err = executeArkivTransaction(t, config, statedb, 3, next, aliasTransaction(&storagetx.ArkivTransaction{
+		RegisterAlias: []storagetx.ArkivRegisterAlias{{Name: "docs", EntityKey: second, BTL: 5}},
+	}))
+	require.Error(t, err)
+	_, err = executeArkivTransaction(t, config, statedb, 3, owner, aliasTransaction(&storagetx.ArkivTransaction{
+		RegisterAlias: []storagetx.ArkivRegisterAlias{{Name: "docs", EntityKey: common.HexToHash("0x01"), BTL: 5}},
+	}))
+	require.Error(t, err)
+
+	// Repointing the alias renews it, it no longer expires at block 7
+	applyArkivTransaction(t, config, statedb, 3, aliasTransaction(&storagetx.ArkivTransaction{
+		RegisterAlias: []storagetx.ArkivRegisterAlias{{Name: "docs", EntityKey: second, BTL: 10}},
+	}))
+	require.Equal(t, &entity.Alias{Key: second, Owner: owner, ExpiresAtBlock: 13}, entity.GetAlias(statedb, nameHash))
+	require.Equal(t, entitySlots+entity.AliasUsedSlots, ownerSlots(owner))
+
+	// Transferring the alias moves its slots to the new owner
+	logs, err = executeArkivTransaction(t, config, statedb, 4, owner, aliasTransaction(&storagetx.ArkivTransaction{
+		TransferAlias: []storagetx.ArkivTransferAlias{{Name: "docs", NewOwner: next}},
+	}))
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	require.Len(t, logs[0].Topics, 4)
+	require.Equal(t, &entity.Alias{Key: second, Owner: next, ExpiresAtBlock: 13}, entity.GetAlias(statedb, nameHash))
+	require.Equal(t, entitySlots, ownerSlots(owner))
+	require.Equal(t, uint64(entity.AliasUsedSlots), ownerSlots(next))
+
+	_, err = executeArkivTransaction(t, config, statedb, 5, owner, aliasTransaction(&storagetx.ArkivTransaction{
+		TransferAlias: []storagetx.ArkivTransferAlias{{Name: "docs", NewOwner: owner}},
+	}))
+	require.Error(t, err)
+
+	applyHousekeepingDeposit(t, config, statedb, 7)
+	require.NotNil(t, entity.GetAlias(statedb, nameHash))
+
+	// The alias expires at the end of its BTL while its entity is still live
+	applyHousekeepingDeposit(t, config, statedb, 13)
+	require.Nil(t, entity.GetAlias(statedb, nameHash))
+	require.True(t, entity.Exists(statedb, second))
+	require.Zero(t, ownerSlots(next))
+	require.Equal(t, entitySlots, ownerSlots(owner))
+
+	// The name is free again
+	applyArkivTransaction(t, config, statedb, 14, aliasTransaction(&storagetx.ArkivTransaction{
+		RegisterAlias: []storagetx.ArkivRegisterAlias{{Name: "docs", EntityKey: first, BTL: 5}},
+	}))
+	require.Equal(t, &entity.Alias{Key: first, Owner: owner, ExpiresAtBlock: 19}, entity.GetAlias(statedb, nameHash))
+}
+
+func TestArkivAliasesBeforeFork(t *testing.T) {
+	config := aliasesConfig(false)
+	statedb, err := state.New(types.EmptyRootHash, state.NewDatabaseForTesting())
+	require.NoError(t, err)
+
+	logs := applyArkivTransaction(t, config, statedb, 1, &storagetx.ArkivTransaction{
+		Create: []storagetx.ArkivCreate{{BTL: 100, ContentType: "text/plain", Payload: []byte("v1")}},
+	})
+	_, err = executeArkivTransaction(t, config, statedb, 2, common.HexToAddress("0x1"), aliasTransaction(&storagetx.ArkivTransaction{
+		RegisterAlias: []storagetx.ArkivRegisterAlias{{Name: "docs", EntityKey: logs[0].Topics[1], BTL: 5}},
+	}))
+	require.Error(t, err)
+	require.Nil(t, entity.GetAlias(statedb, entity.AliasNameHash("docs")))
+}
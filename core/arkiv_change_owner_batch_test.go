@@ -0,0 +1,74 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/arkiv/logs"
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+func changeOwnerBatchConfig(active bool) *params.ChainConfig {
+	config := *params.OptimismTestConfig
+	if active {
+		config.ArkivChangeOwnerBatchTime = new(uint64)
+	}
+	return &config
+}
+
+func changeOwnerBatch(newOwner common.Address, keys ...common.Hash) *storagetx.ArkivTransaction {
+	return &storagetx.ArkivTransaction{
+		Version:          storagetx.TransactionVersionChangeOwnerBatch,
+		ChangeOwnerBatch: []storagetx.ArkivChangeOwnerBatch{{EntityKeys: keys, NewOwner: newOwner}},
+	}
+}
+
+func TestArkivChangeOwnerBatch(t *testing.T) {
+	config := changeOwnerBatchConfig(true)
+	statedb, first := createExpiringEntity(t, config)
+	second := applyArkivTransaction(t, config, statedb, 2, &storagetx.ArkivTransaction{
+		Create: []storagetx.ArkivCreate{{BTL: 10, ContentType: "text/plain", Payload: []byte("second")}},
+	})[0].Topics[1]
+	// The third entity belongs to 0x2
+	third, err := executeArkivTransaction(t, config, statedb, 2, common.HexToAddress("0x2"), &storagetx.ArkivTransaction{
+		Create: []storagetx.ArkivCreate{{BTL: 10, ContentType: "text/plain", Payload: []byte("third")}},
+	})
+	require.NoError(t, err)
+	newOwner := common.HexToAddress("0x3")
+
+	ownerOf := func(key common.Hash) common.Address {
+		md, err := entity.GetEntityMetaData(statedb, key)
+		require.NoError(t, err)
+		return md.Owner
+	}
+
+	// None of the entities is transferred if the sender doesn't own all of them
+	_, err = executeArkivTransaction(t, config, statedb, 3, common.HexToAddress("0x1"), changeOwnerBatch(newOwner, first, second, third[0].Topics[1]))
+	require.ErrorContains(t, err, "is not the owner")
+	require.Equal(t, common.HexToAddress("0x1"), ownerOf(first))
+	require.Equal(t, common.HexToAddress("0x1"), ownerOf(second))
+
+	changed := applyArkivTransaction(t, config, statedb, 3, changeOwnerBatch(newOwner, first, second))
+	require.Len(t, changed, 2)
+	for i, key := range []common.Hash{first, second} {
+		require.Equal(t, []common.Hash{logs.ArkivEntityOwnerChanged, key, common.BytesToHash(common.HexToAddress("0x1").Bytes()), common.BytesToHash(newOwner.Bytes())}, changed[i].Topics)
+		require.Equal(t, newOwner, ownerOf(key))
+	}
+}
+
+func TestArkivChangeOwnerBatchBeforeFork(t *testing.T) {
+	config := changeOwnerBatchConfig(false)
+	statedb, key := createExpiringEntity(t, config)
+
+	_, err := executeArkivTransaction(t, config, statedb, 3, common.HexToAddress("0x1"), changeOwnerBatch(common.HexToAddress("0x3"), key))
+	require.ErrorContains(t, err, "batched ownership changes are not active")
+
+	// Older transaction versions can't carry the batches
+	tx := changeOwnerBatch(common.HexToAddress("0x3"), key)
+	tx.Version = storagetx.TransactionVersionReduceBTL
+	_, err = executeArkivTransaction(t, config, statedb, 3, common.HexToAddress("0x1"), tx)
+	require.ErrorContains(t, err, "requires transaction version")
+}
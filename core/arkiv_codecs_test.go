@@ -0,0 +1,52 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/arkiv/compression"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/stretchr/testify/require"
+)
+
+func codecsConfig(active bool) *params.ChainConfig {
+	config := *params.OptimismTestConfig
+	if active {
+		config.ArkivCodecsTime = new(uint64)
+	}
+	return &config
+}
+
+// zstdMessage is the message of arkivMessage with its calldata compressed with zstd
+// behind the codec prefix.
+func zstdMessage(t *testing.T, blockNumber uint64, sizes ...int) *Message {
+	t.Helper()
+
+	data, err := rlp.EncodeToBytes(createPayloads(sizes...))
+	require.NoError(t, err)
+	msg := arkivMessage(t, blockNumber, createPayloads(sizes...))
+	msg.Data = compression.MustCompress(compression.TxCodecZstd, data)
+	return msg
+}
+
+func TestArkivCodecs(t *testing.T) {
+	statedb, err := state.New(types.EmptyRootHash, state.NewDatabaseForTesting())
+	require.NoError(t, err)
+
+	results := applyBlock(t, codecsConfig(true), statedb, 1,
+		zstdMessage(t, 1, 16),
+		arkivMessage(t, 1, createPayloads(17)),
+	)
+	require.NoError(t, results[0].Err)
+	require.NoError(t, results[1].Err)
+}
+
+func TestArkivCodecsBeforeFork(t *testing.T) {
+	statedb, err := state.New(types.EmptyRootHash, state.NewDatabaseForTesting())
+	require.NoError(t, err)
+
+	results := applyBlock(t, codecsConfig(false), statedb, 1, zstdMessage(t, 1, 16))
+	require.ErrorContains(t, results[0].Err, "failed to unpack arkiv transaction")
+}
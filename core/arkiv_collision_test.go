@@ -0,0 +1,66 @@
+package core
+
+import (
+	"math/big"
+	"slices"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/arkiv/logs"
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity/entityexpiration"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArkivCreateDoesNotOverwriteEntity(t *testing.T) {
+	config := params.OptimismTestConfig
+	statedb, err := state.New(types.EmptyRootHash, state.NewDatabaseForTesting())
+	require.NoError(t, err)
+
+	// Store an entity under the key the create of block 5 derives
+	payload := []byte("payload")
+	txHash := common.BigToHash(big.NewInt(5))
+	key := entity.DeriveEntityKey(txHash, 0, payload)
+
+	original := entity.EntityMetaData{Owner: common.HexToAddress("0x2"), ExpiresAtBlock: 50}
+	require.NoError(t, entity.Store(statedb, key, original.Owner, original, payload, false))
+
+	_, err = executeArkivTransaction(t, config, statedb, 5, common.HexToAddress("0x1"), &storagetx.ArkivTransaction{
+		Create: []storagetx.ArkivCreate{{BTL: 10, ContentType: "text/plain", Payload: payload}},
+	})
+	require.ErrorIs(t, err, entity.ErrEntityExists)
+
+	// The original entity and its expiration bucket are intact
+	md, err := entity.GetEntityMetaData(statedb, key)
+	require.NoError(t, err)
+	require.Equal(t, original, *md)
+	require.Equal(t, []common.Hash{key}, slices.Collect(entityexpiration.IteratorOfEntitiesToExpireAtBlock(statedb, 50)))
+	require.Empty(t, slices.Collect(entityexpiration.IteratorOfEntitiesToExpireAtBlock(statedb, 15)))
+}
+
+func TestArkivCreateDerivesEntityKeys(t *testing.T) {
+	config := params.OptimismTestConfig
+	statedb, err := state.New(types.EmptyRootHash, state.NewDatabaseForTesting())
+	require.NoError(t, err)
+
+	// The same payload created twice gets a key per operation index
+	creates := []storagetx.ArkivCreate{
+		{BTL: 10, ContentType: "text/plain", Payload: []byte("payload")},
+		{BTL: 10, ContentType: "text/plain", Payload: []byte("payload")},
+		{BTL: 10, ContentType: "text/plain"},
+	}
+	created, err := executeArkivTransaction(t, config, statedb, 5, common.HexToAddress("0x1"), &storagetx.ArkivTransaction{Create: creates})
+	require.NoError(t, err)
+	require.Len(t, created, len(creates))
+
+	txHash := common.BigToHash(big.NewInt(5))
+	for i, create := range creates {
+		require.Equal(t, logs.ArkivEntityCreated, created[i].Topics[0])
+		require.Equal(t, entity.DeriveEntityKey(txHash, i, create.Payload), created[i].Topics[1])
+	}
+	require.NotEqual(t, created[0].Topics[1], created[1].Topics[1])
+}
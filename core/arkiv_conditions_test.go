@@ -0,0 +1,104 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+func conditionsConfig(active bool) *params.ChainConfig {
+	config := tombstonesConfig(true)
+	if active {
+		config.ArkivConditionsTime = new(uint64)
+	}
+	return config
+}
+
+// conditional returns the transaction carrying the conditions, at the version
+// supporting them.
+func conditional(tx *storagetx.ArkivTransaction, conditions ...storagetx.ArkivCondition) *storagetx.ArkivTransaction {
+	tx.Version = storagetx.TransactionVersionConditions
+	tx.Conditions = conditions
+	return tx
+}
+
+func TestArkivConditionsCompetingRenewals(t *testing.T) {
+	config := conditionsConfig(true)
+	statedb, key := createExpiringEntity(t, config)
+
+	// Two renewers race to extend the entity expiring at block 11, only if nobody
+	// renewed it yet: the second transaction of the block sees the first extension
+	renewal := storagetx.ArkivCondition{Operation: storagetx.ConditionOperationExtend, RequireExpiresAtMost: 11}
+	first := arkivMessage(t, 5, conditional(extend(key, 10), renewal))
+	second := arkivMessage(t, 5, conditional(extend(key, 20), renewal))
+	second.From = common.HexToAddress("0x2")
+
+	results := applyBlock(t, config, statedb, 5, first, second)
+	require.NoError(t, results[0].Err)
+	require.ErrorIs(t, results[1].Err, storagetx.ErrConditionFailed)
+	require.EqualError(t, results[1].Err, "failed to run storage transaction: failed to extend BTL of entity "+key.Hex()+": condition[0] of extend[0]: condition failed: entity "+key.Hex()+" expires at block 21, after 11")
+
+	md, err := entity.GetEntityMetaData(statedb, key)
+	require.NoError(t, err)
+	require.Equal(t, uint64(21), md.ExpiresAtBlock)
+}
+
+func TestArkivConditionsCompetingCreates(t *testing.T) {
+	config := conditionsConfig(true)
+	statedb, key := createExpiringEntity(t, config)
+
+	// The replacement of the entity is only created once it's gone
+	replace := func() *storagetx.ArkivTransaction {
+		return conditional(
+			&storagetx.ArkivTransaction{Create: []storagetx.ArkivCreate{{BTL: 10, ContentType: "text/plain", Payload: []byte("replacement")}}},
+			storagetx.ArkivCondition{Operation: storagetx.ConditionOperationCreate, EntityKey: key, RequireAbsent: true},
+		)
+	}
+	// The creates run before the deletes of their own transaction
+	deleteAndReplace := replace()
+	deleteAndReplace.Delete = []common.Hash{key}
+
+	results := applyBlock(t, config, statedb, 5,
+		arkivMessage(t, 5, deleteAndReplace),
+		arkivMessage(t, 5, &storagetx.ArkivTransaction{Delete: []common.Hash{key}}),
+		arkivMessage(t, 5, replace()),
+	)
+	require.ErrorIs(t, results[0].Err, storagetx.ErrConditionFailed)
+	require.ErrorContains(t, results[0].Err, "condition[0] of create[0]: condition failed: entity "+key.Hex()+" exists")
+	require.NoError(t, results[1].Err)
+	require.NoError(t, results[2].Err)
+	require.False(t, entity.Exists(statedb, key))
+}
+
+func TestArkivConditionsOwner(t *testing.T) {
+	config := conditionsConfig(true)
+	statedb, key := createExpiringEntity(t, config)
+
+	update := &storagetx.ArkivTransaction{Update: []storagetx.ArkivUpdate{{EntityKey: key, BTL: 10, ContentType: "text/plain", Payload: []byte("updated")}}}
+	_, err := executeArkivTransaction(t, config, statedb, 5, common.HexToAddress("0x1"), conditional(update,
+		storagetx.ArkivCondition{Operation: storagetx.ConditionOperationUpdate, RequireOwner: common.HexToAddress("0x2")},
+	))
+	require.ErrorIs(t, err, storagetx.ErrConditionFailed)
+	require.ErrorContains(t, err, "is owned by 0x0000000000000000000000000000000000000001, not 0x0000000000000000000000000000000000000002")
+
+	// A condition may check another entity than the one of its operation
+	_, err = executeArkivTransaction(t, config, statedb, 5, common.HexToAddress("0x1"), conditional(update,
+		storagetx.ArkivCondition{Operation: storagetx.ConditionOperationUpdate, RequireOwner: common.HexToAddress("0x1")},
+		storagetx.ArkivCondition{Operation: storagetx.ConditionOperationUpdate, EntityKey: common.HexToHash("0x2"), RequireAbsent: true},
+	))
+	require.NoError(t, err)
+}
+
+func TestArkivConditionsBeforeFork(t *testing.T) {
+	config := conditionsConfig(false)
+	statedb, key := createExpiringEntity(t, config)
+
+	results := applyBlock(t, config, statedb, 5, arkivMessage(t, 5, conditional(extend(key, 10),
+		storagetx.ArkivCondition{Operation: storagetx.ConditionOperationExtend, RequireExpiresAtMost: 11},
+	)))
+	require.EqualError(t, results[0].Err, "failed to unpack arkiv transaction: conditions are not active")
+}
@@ -0,0 +1,75 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/arkiv/storageaccounting"
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+func contentHashConfig(active bool) *params.ChainConfig {
+	config := *params.OptimismTestConfig
+	if active {
+		config.ArkivContentHashTime = new(uint64)
+	}
+	return &config
+}
+
+func TestArkivContentHash(t *testing.T) {
+	config := contentHashConfig(true)
+	statedb, err := state.New(types.EmptyRootHash, state.NewDatabaseForTesting())
+	require.NoError(t, err)
+
+	logs := applyArkivTransaction(t, config, statedb, 1, &storagetx.ArkivTransaction{
+		Create: []storagetx.ArkivCreate{{BTL: 100, ContentType: "text/plain", Payload: []byte("v1")}},
+	})
+	key := logs[0].Topics[1]
+	require.NoError(t, entity.VerifyEntityContent(statedb, key, []byte("v1")))
+	require.ErrorIs(t, entity.VerifyEntityContent(statedb, key, []byte("v2")), entity.ErrContentMismatch)
+	require.Equal(t, uint64(entity.EntityUsedSlots+entity.ContentHashUsedSlots+1), storageaccounting.GetNumberOfUsedSlots(statedb).Uint64())
+
+	// Updates replace the content hash, extensions keep it
+	applyArkivTransaction(t, config, statedb, 2, &storagetx.ArkivTransaction{
+		Update: []storagetx.ArkivUpdate{{EntityKey: key, BTL: 100, ContentType: "text/plain", Payload: []byte("v2")}},
+	})
+	applyArkivTransaction(t, config, statedb, 3, &storagetx.ArkivTransaction{
+		Extend: []storagetx.ExtendBTL{{EntityKey: key, NumberOfBlocks: 10}},
+	})
+	require.NoError(t, entity.VerifyEntityContent(statedb, key, []byte("v2")))
+	require.ErrorIs(t, entity.VerifyEntityContent(statedb, key, []byte("v1")), entity.ErrContentMismatch)
+
+	// Deleting the entity clears its content hash
+	applyArkivTransaction(t, config, statedb, 4, &storagetx.ArkivTransaction{Delete: []common.Hash{key}})
+	require.Error(t, entity.VerifyEntityContent(statedb, key, []byte("v2")))
+	require.Zero(t, storageaccounting.GetNumberOfUsedSlots(statedb).Uint64())
+}
+
+func TestArkivContentHashBeforeFork(t *testing.T) {
+	statedb, err := state.New(types.EmptyRootHash, state.NewDatabaseForTesting())
+	require.NoError(t, err)
+
+	logs := applyArkivTransaction(t, contentHashConfig(false), statedb, 1, &storagetx.ArkivTransaction{
+		Create: []storagetx.ArkivCreate{{BTL: 100, ContentType: "text/plain", Payload: []byte("legacy")}},
+	})
+	key := logs[0].Topics[1]
+	require.ErrorIs(t, entity.VerifyEntityContent(statedb, key, []byte("legacy")), entity.ErrNoContentHash)
+	require.Equal(t, uint64(entity.EntityUsedSlots+1), storageaccounting.GetNumberOfUsedSlots(statedb).Uint64())
+
+	// The entities stored before the fork get a content hash when they are updated
+	config := contentHashConfig(true)
+	applyArkivTransaction(t, config, statedb, 2, &storagetx.ArkivTransaction{
+		Extend: []storagetx.ExtendBTL{{EntityKey: key, NumberOfBlocks: 10}},
+	})
+	require.ErrorIs(t, entity.VerifyEntityContent(statedb, key, []byte("legacy")), entity.ErrNoContentHash)
+
+	applyArkivTransaction(t, config, statedb, 3, &storagetx.ArkivTransaction{
+		Update: []storagetx.ArkivUpdate{{EntityKey: key, BTL: 100, ContentType: "text/plain", Payload: []byte("updated")}},
+	})
+	require.NoError(t, entity.VerifyEntityContent(statedb, key, []byte("updated")))
+}
@@ -0,0 +1,42 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArkivEntityNotFoundReverts(t *testing.T) {
+	config := params.OptimismTestConfig
+	statedb, err := state.New(types.EmptyRootHash, state.NewDatabaseForTesting())
+	require.NoError(t, err)
+	missing := common.HexToHash("0x01")
+
+	for name, tx := range map[string]*storagetx.ArkivTransaction{
+		"delete":      {Delete: []common.Hash{missing}},
+		"extend":      extend(missing, 5),
+		"changeOwner": {ChangeOwner: []storagetx.ArkivChangeOwner{{EntityKey: missing, NewOwner: common.HexToAddress("0x2")}}},
+	} {
+		results := applyBlock(t, config, statedb, 1, arkivMessage(t, 1, tx))
+		require.Equal(t, vm.ErrExecutionReverted, results[0].Err, name)
+
+		reason, err := abi.UnpackRevert(results[0].Revert())
+		require.NoError(t, err, name)
+		require.Contains(t, reason, missing.Hex(), name)
+		require.Contains(t, reason, "entity not found", name)
+	}
+
+	// The other failures are left as they are
+	results := applyBlock(t, config, statedb, 1, arkivMessage(t, 1, &storagetx.ArkivTransaction{
+		Extend: []storagetx.ExtendBTL{{EntityKey: missing}},
+	}))
+	require.Error(t, results[0].Err)
+	require.NotEqual(t, vm.ErrExecutionReverted, results[0].Err)
+}
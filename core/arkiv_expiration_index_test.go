@@ -0,0 +1,80 @@
+package core
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/arkiv/address"
+	"github.com/ethereum/go-ethereum/arkiv/storageaccounting"
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity/entityexpiration"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+)
+
+// expirationBucketSize returns the size slot of the expiration bucket of the block,
+// which is zero once the bucket is empty.
+func expirationBucketSize(statedb *state.StateDB, blockNumber uint64) common.Hash {
+	key := crypto.Keccak256Hash(entityexpiration.BlockExpirationSalt, uint256.NewInt(blockNumber).Bytes())
+	return statedb.GetState(address.ArkivProcessorAddress, key)
+}
+
+// The expiration index frees its slots as the buckets empty, so mass deletions leave
+// no empty buckets behind for a compaction to reclaim.
+func TestArkivExpirationIndexAfterMassDeletion(t *testing.T) {
+	config := tombstonesConfig(false)
+	statedb, err := state.New(types.EmptyRootHash, state.NewDatabaseForTesting())
+	require.NoError(t, err)
+
+	// 60 entities expiring at blocks 11, 12 and 13
+	create := &storagetx.ArkivTransaction{}
+	for i := range 60 {
+		create.Create = append(create.Create, storagetx.ArkivCreate{
+			BTL:         10 + uint64(i%3),
+			ContentType: "text/plain",
+			Payload:     []byte{byte(i)},
+		})
+	}
+	logs := applyArkivTransaction(t, config, statedb, 1, create)
+	require.Len(t, logs, 60)
+	buckets := map[uint64][]common.Hash{}
+	for i, l := range logs {
+		buckets[11+uint64(i%3)] = append(buckets[11+uint64(i%3)], l.Topics[1])
+	}
+	// 3 slots per entity and the size slot of every bucket
+	require.Equal(t, uint64(60*3+3), storageaccounting.GetNumberOfUsedSlots(statedb).Uint64())
+
+	// Delete the whole bucket of block 11, and all but one entity of the others
+	kept := map[uint64]common.Hash{12: buckets[12][7], 13: buckets[13][0]}
+	deletion := &storagetx.ArkivTransaction{}
+	for block := uint64(11); block <= 13; block++ {
+		for _, key := range buckets[block] {
+			if key != kept[block] {
+				deletion.Delete = append(deletion.Delete, key)
+			}
+		}
+	}
+	applyArkivTransaction(t, config, statedb, 2, deletion)
+
+	// The counter only counts the slots of the two entities left and their buckets
+	require.Equal(t, uint64(2*3+2), storageaccounting.GetNumberOfUsedSlots(statedb).Uint64())
+	require.Equal(t, common.Hash{}, expirationBucketSize(statedb, 11))
+	require.Equal(t, common.BigToHash(common.Big1), expirationBucketSize(statedb, 12))
+	require.Equal(t, common.BigToHash(common.Big1), expirationBucketSize(statedb, 13))
+
+	// Lookups see exactly the entities left
+	require.Empty(t, slices.Collect(entityexpiration.IteratorOfEntitiesToExpireAtBlock(statedb, 11)))
+	require.Equal(t, []common.Hash{kept[12]}, slices.Collect(entityexpiration.IteratorOfEntitiesToExpireAtBlock(statedb, 12)))
+	require.Equal(t, []common.Hash{kept[13]}, slices.Collect(entityexpiration.IteratorOfEntitiesToExpireAtBlock(statedb, 13)))
+
+	// Housekeeping runs over the empty bucket and expires the entities left
+	for block := uint64(11); block <= 13; block++ {
+		applyHousekeepingDeposit(t, config, statedb, block)
+		require.Equal(t, common.Hash{}, expirationBucketSize(statedb, block))
+	}
+	require.Zero(t, storageaccounting.GetNumberOfUsedSlots(statedb).Uint64())
+}
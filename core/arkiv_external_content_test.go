@@ -0,0 +1,56 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+func externalContentConfig(active bool) *params.ChainConfig {
+	config := *params.OptimismTestConfig
+	if active {
+		config.ArkivExternalContentTime = new(uint64)
+	}
+	return &config
+}
+
+// externalContentCreate is a create with its payload stored off chain.
+func externalContentCreate(payload []byte) *storagetx.ArkivTransaction {
+	return &storagetx.ArkivTransaction{
+		Version: storagetx.TransactionVersionExternalContent,
+		Create:  []storagetx.ArkivCreate{{BTL: 10, ContentType: "text/plain"}},
+		ExternalContent: []storagetx.ArkivExternalContent{{
+			Type:       storagetx.ContentCommitmentKeccak256,
+			Commitment: crypto.Keccak256Hash(payload),
+			Size:       uint64(len(payload)),
+			Pointer:    "blobs/1",
+		}},
+	}
+}
+
+func TestArkivExternalContent(t *testing.T) {
+	statedb, err := state.New(types.EmptyRootHash, state.NewDatabaseForTesting())
+	require.NoError(t, err)
+
+	results := applyBlock(t, externalContentConfig(true), statedb, 1,
+		arkivMessage(t, 1, externalContentCreate([]byte("stored off chain"))),
+		arkivMessage(t, 1, createPayloads(16)),
+	)
+	require.NoError(t, results[0].Err)
+	require.NoError(t, results[1].Err)
+}
+
+func TestArkivExternalContentBeforeFork(t *testing.T) {
+	statedb, err := state.New(types.EmptyRootHash, state.NewDatabaseForTesting())
+	require.NoError(t, err)
+
+	results := applyBlock(t, externalContentConfig(false), statedb, 1,
+		arkivMessage(t, 1, externalContentCreate([]byte("stored off chain"))),
+	)
+	require.EqualError(t, results[0].Err, "failed to unpack arkiv transaction: external content is not active")
+}
@@ -0,0 +1,173 @@
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/arkiv/address"
+	"github.com/ethereum/go-ethereum/arkiv/compression"
+	"github.com/ethereum/go-ethereum/arkiv/housekeepingtx"
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+)
+
+func housekeepingOrderConfig(active bool) *params.ChainConfig {
+	config := tombstonesConfig(true)
+	if active {
+		config.ArkivHousekeepingOrderTime = new(uint64)
+	}
+	return config
+}
+
+func l1AttributesDeposit(blockNumber uint64) *Message {
+	return &Message{
+		From:        housekeepingtx.L1AttributesDepositor,
+		To:          &types.L1BlockAddr,
+		GasLimit:    1_000_000,
+		GasPrice:    big.NewInt(0),
+		GasFeeCap:   big.NewInt(0),
+		GasTipCap:   big.NewInt(0),
+		Value:       big.NewInt(0),
+		IsDepositTx: true,
+		BlockNumber: blockNumber,
+	}
+}
+
+func userDeposit(blockNumber uint64) *Message {
+	to := common.HexToAddress("0x2")
+	return &Message{
+		From:        common.HexToAddress("0x3"),
+		To:          &to,
+		GasLimit:    1_000_000,
+		GasPrice:    big.NewInt(0),
+		GasFeeCap:   big.NewInt(0),
+		GasTipCap:   big.NewInt(0),
+		Value:       big.NewInt(0),
+		IsDepositTx: true,
+		BlockNumber: blockNumber,
+	}
+}
+
+func arkivMessage(t *testing.T, blockNumber uint64, tx *storagetx.ArkivTransaction) *Message {
+	t.Helper()
+
+	data, err := rlp.EncodeToBytes(tx)
+	require.NoError(t, err)
+	return &Message{
+		From:            common.HexToAddress("0x1"),
+		To:              &address.ArkivProcessorAddress,
+		GasLimit:        10_000_000,
+		GasPrice:        big.NewInt(0),
+		GasFeeCap:       big.NewInt(0),
+		GasTipCap:       big.NewInt(0),
+		Value:           big.NewInt(0),
+		Data:            compression.MustBrotliCompress(data),
+		SkipNonceChecks: true,
+		BlockNumber:     blockNumber,
+	}
+}
+
+// applyBlock applies the messages in order as the transactions of the block with the
+// number.
+func applyBlock(t *testing.T, config *params.ChainConfig, statedb *state.StateDB, blockNumber uint64, msgs ...*Message) []*ExecutionResult {
+	t.Helper()
+
+	blockContext := vm.BlockContext{
+		CanTransfer: CanTransfer,
+		Transfer:    Transfer,
+		BlockNumber: new(big.Int).SetUint64(blockNumber),
+		GasLimit:    30_000_000,
+		BaseFee:     big.NewInt(0),
+		Random:      &common.Hash{},
+		L1CostFunc:  func(types.RollupCostData, uint64) *big.Int { return nil },
+		OperatorCostFunc: func(uint64, uint64) *uint256.Int {
+			return uint256.NewInt(0)
+		},
+	}
+	evm := vm.NewEVM(blockContext, statedb, config, vm.Config{})
+
+	results := make([]*ExecutionResult, len(msgs))
+	for i, msg := range msgs {
+		res, err := ApplyMessageWithIndex(evm, msg, new(GasPool).AddGas(blockContext.GasLimit), i)
+		require.NoError(t, err)
+		results[i] = res
+	}
+	return results
+}
+
+// createExpiringEntity creates an entity expiring at block 11.
+func createExpiringEntity(t *testing.T, config *params.ChainConfig) (*state.StateDB, common.Hash) {
+	t.Helper()
+
+	statedb, err := state.New(types.EmptyRootHash, state.NewDatabaseForTesting())
+	require.NoError(t, err)
+
+	logs := applyArkivTransaction(t, config, statedb, 1, &storagetx.ArkivTransaction{
+		Create: []storagetx.ArkivCreate{{BTL: 10, ContentType: "text/plain", Payload: []byte("expiring")}},
+	})
+	return statedb, logs[0].Topics[1]
+}
+
+func extend(key common.Hash, blocks uint64) *storagetx.ArkivTransaction {
+	return &storagetx.ArkivTransaction{
+		Extend: []storagetx.ExtendBTL{{EntityKey: key, NumberOfBlocks: blocks}},
+	}
+}
+
+func TestArkivHousekeepingOrderExtendAtExpiration(t *testing.T) {
+	config := housekeepingOrderConfig(true)
+	statedb, key := createExpiringEntity(t, config)
+
+	// The housekeeping expires the entity before the extension in the same block runs
+	results := applyBlock(t, config, statedb, 11,
+		l1AttributesDeposit(11),
+		arkivMessage(t, 11, extend(key, 5)),
+	)
+	require.NoError(t, results[0].Err)
+	require.ErrorIs(t, results[1].Err, storagetx.ErrEntityExpired)
+	require.EqualError(t, results[1].Err, "failed to run storage transaction: failed to extend BTL of entity "+key.Hex()+": entity expired at block 11, the housekeeping of a block runs before its transactions, extend it at block 10 at the latest")
+	require.False(t, entity.Exists(statedb, key))
+}
+
+func TestArkivHousekeepingOrderExtendBeforeExpiration(t *testing.T) {
+	config := housekeepingOrderConfig(true)
+	statedb, key := createExpiringEntity(t, config)
+
+	results := applyBlock(t, config, statedb, 10,
+		l1AttributesDeposit(10),
+		arkivMessage(t, 10, extend(key, 5)),
+	)
+	require.NoError(t, results[1].Err)
+
+	md, err := entity.GetEntityMetaData(statedb, key)
+	require.NoError(t, err)
+	require.Equal(t, uint64(16), md.ExpiresAtBlock)
+
+	applyBlock(t, config, statedb, 11, l1AttributesDeposit(11))
+	require.True(t, entity.Exists(statedb, key))
+}
+
+func TestArkivHousekeepingOrderDeposits(t *testing.T) {
+	// From the fork the housekeeping only runs in the L1 attributes deposit
+	config := housekeepingOrderConfig(true)
+	statedb, key := createExpiringEntity(t, config)
+	applyBlock(t, config, statedb, 11, userDeposit(11))
+	require.True(t, entity.Exists(statedb, key))
+
+	applyBlock(t, config, statedb, 11, l1AttributesDeposit(11), userDeposit(11))
+	require.False(t, entity.Exists(statedb, key))
+
+	// Before the fork it runs in every deposit
+	config = housekeepingOrderConfig(false)
+	statedb, key = createExpiringEntity(t, config)
+	applyBlock(t, config, statedb, 11, userDeposit(11))
+	require.False(t, entity.Exists(statedb, key))
+}
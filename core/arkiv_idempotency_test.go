@@ -0,0 +1,79 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/arkiv/logs"
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+func idempotencyConfig(active bool) *params.ChainConfig {
+	config := *params.OptimismTestConfig
+	config.ArkivIdempotencyTTL = 10
+	if active {
+		config.ArkivIdempotencyTime = new(uint64)
+	}
+	return &config
+}
+
+func idempotentCreate(idempotencyKey common.Hash) *storagetx.ArkivTransaction {
+	return &storagetx.ArkivTransaction{
+		Version: storagetx.TransactionVersionIdempotency,
+		Create:  []storagetx.ArkivCreate{{BTL: 100, ContentType: "text/plain", Payload: []byte("retried"), IdempotencyKey: &idempotencyKey}},
+	}
+}
+
+func TestArkivIdempotency(t *testing.T) {
+	config := idempotencyConfig(true)
+	statedb, err := state.New(types.EmptyRootHash, state.NewDatabaseForTesting())
+	require.NoError(t, err)
+	idempotencyKey := common.HexToHash("0xabc")
+
+	created := applyArkivTransaction(t, config, statedb, 1, idempotentCreate(idempotencyKey))
+	require.Len(t, created, 1)
+	require.Equal(t, logs.ArkivEntityCreated, created[0].Topics[0])
+	original := created[0].Topics[1]
+
+	// A retry within the TTL creates nothing and points to the original entity
+	retried := applyArkivTransaction(t, config, statedb, 5, idempotentCreate(idempotencyKey))
+	require.Len(t, retried, 1)
+	require.Equal(t, []common.Hash{logs.ArkivCreateDeduplicated, original, created[0].Topics[2]}, retried[0].Topics)
+	require.Equal(t, idempotencyKey.Bytes(), retried[0].Data)
+
+	// The key is bound to its sender
+	other, err := executeArkivTransaction(t, config, statedb, 6, common.HexToAddress("0x2"), idempotentCreate(idempotencyKey))
+	require.NoError(t, err)
+	require.Equal(t, logs.ArkivEntityCreated, other[0].Topics[0])
+
+	// The housekeeping of the block the TTL ends at forgets the key, a retry then
+	// creates another entity
+	applyHousekeepingDeposit(t, config, statedb, 11)
+	_, ok := entity.GetIdempotencyKey(statedb, entity.IdempotencyHash(common.HexToAddress("0x1"), idempotencyKey))
+	require.False(t, ok)
+	recreated := applyArkivTransaction(t, config, statedb, 11, idempotentCreate(idempotencyKey))
+	require.Equal(t, logs.ArkivEntityCreated, recreated[0].Topics[0])
+	require.NotEqual(t, original, recreated[0].Topics[1])
+	require.True(t, entity.Exists(statedb, original))
+	require.True(t, entity.Exists(statedb, recreated[0].Topics[1]))
+}
+
+func TestArkivIdempotencyBeforeFork(t *testing.T) {
+	config := idempotencyConfig(false)
+	statedb, err := state.New(types.EmptyRootHash, state.NewDatabaseForTesting())
+	require.NoError(t, err)
+
+	_, err = executeArkivTransaction(t, config, statedb, 1, common.HexToAddress("0x1"), idempotentCreate(common.HexToHash("0xabc")))
+	require.ErrorContains(t, err, "idempotency keys are not active")
+
+	// Older transaction versions can't carry the keys
+	tx := idempotentCreate(common.HexToHash("0xabc"))
+	tx.Version = storagetx.TransactionVersionAliases
+	_, err = executeArkivTransaction(t, config, statedb, 1, common.HexToAddress("0x1"), tx)
+	require.ErrorContains(t, err, "requires transaction version")
+}
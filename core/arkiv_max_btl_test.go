@@ -0,0 +1,70 @@
+package core
+
+import (
+	"math"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+func maxBTLConfig(active bool) *params.ChainConfig {
+	config := *params.OptimismTestConfig
+	config.ArkivMaxBTL = 100
+	if active {
+		config.ArkivMaxBTLTime = new(uint64)
+	}
+	return &config
+}
+
+func createWithBTL(btl uint64) *storagetx.ArkivTransaction {
+	return &storagetx.ArkivTransaction{
+		Create: []storagetx.ArkivCreate{{BTL: btl, ContentType: "text/plain", Payload: []byte("capped")}},
+	}
+}
+
+func TestArkivMaxBTL(t *testing.T) {
+	config := maxBTLConfig(true)
+	// The entity expires at block 11
+	statedb, key := createExpiringEntity(t, config)
+
+	// The expiry can be up to 100 blocks after the current block
+	applyArkivTransaction(t, config, statedb, 2, createWithBTL(100))
+	applyArkivTransaction(t, config, statedb, 2, extend(key, 91))
+
+	for name, tx := range map[string]*storagetx.ArkivTransaction{
+		"create":   createWithBTL(101),
+		"update":   {Update: []storagetx.ArkivUpdate{{EntityKey: key, BTL: 101, ContentType: "text/plain", Payload: []byte("capped")}}},
+		"extend":   extend(key, 1),
+		"overflow": extend(key, math.MaxUint64),
+	} {
+		_, err := executeArkivTransaction(t, config, statedb, 2, common.HexToAddress("0x1"), tx)
+		require.ErrorIs(t, err, entity.ErrBTLTooLarge, name)
+
+		results := applyBlock(t, config, statedb, 2, arkivMessage(t, 2, tx))
+		require.Equal(t, vm.ErrExecutionReverted, results[0].Err, name)
+
+		reason, err := abi.UnpackRevert(results[0].Revert())
+		require.NoError(t, err, name)
+		require.Contains(t, reason, "BTL", name)
+	}
+
+	// The failed extensions left the expiry as it was
+	md, err := entity.GetEntityMetaData(statedb, key)
+	require.NoError(t, err)
+	require.Equal(t, uint64(102), md.ExpiresAtBlock)
+}
+
+func TestArkivMaxBTLBeforeFork(t *testing.T) {
+	config := maxBTLConfig(false)
+	statedb, key := createExpiringEntity(t, config)
+
+	// The expiries aren't capped
+	applyArkivTransaction(t, config, statedb, 2, createWithBTL(1000))
+	applyArkivTransaction(t, config, statedb, 2, extend(key, 1000))
+}
@@ -0,0 +1,76 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/arkiv/storageaccounting"
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+func ownerSlotsConfig(active bool) *params.ChainConfig {
+	config := ownershipConfig(true)
+	if active {
+		config.ArkivOwnerSlotsTime = new(uint64)
+	}
+	return config
+}
+
+func requireOwnerSlots(t *testing.T, statedb *state.StateDB, ownerSlots, newOwnerSlots uint64) {
+	t.Helper()
+
+	require.Equal(t, ownerSlots, storageaccounting.GetNumberOfUsedSlotsByOwner(statedb, owner).Uint64(), "owner")
+	require.Equal(t, newOwnerSlots, storageaccounting.GetNumberOfUsedSlotsByOwner(statedb, newOwner).Uint64(), "new owner")
+}
+
+func TestArkivOwnerSlots(t *testing.T) {
+	config := ownerSlotsConfig(true)
+
+	// The entity is created at block 1 and its transfer is proposed at block 2
+	statedb, key := createOwnedEntity(t, config)
+	requireOwnerSlots(t, statedb, 6, 0)
+
+	// The slots move to the new owner with the entity
+	_, err := executeArkivTransaction(t, config, statedb, 3, newOwner, acceptOwnership(key))
+	require.NoError(t, err)
+	requireOwnerSlots(t, statedb, 0, 3)
+
+	// An expiring entity of owner and a transfer that lapses at block 9
+	logs := applyArkivTransaction(t, config, statedb, 4, &storagetx.ArkivTransaction{
+		Create: []storagetx.ArkivCreate{{BTL: 10, ContentType: "text/plain", Payload: []byte("expiring")}},
+	})
+	expiring := logs[0].Topics[1]
+	applyArkivTransaction(t, config, statedb, 4, &storagetx.ArkivTransaction{
+		ChangeOwner: []storagetx.ArkivChangeOwner{{EntityKey: expiring, NewOwner: newOwner}},
+	})
+	requireOwnerSlots(t, statedb, 6, 3)
+
+	applyHousekeepingDeposit(t, config, statedb, 9)
+	requireOwnerSlots(t, statedb, 3, 3)
+
+	applyHousekeepingDeposit(t, config, statedb, 14)
+	requireOwnerSlots(t, statedb, 0, 3)
+
+	_, err = executeArkivTransaction(t, config, statedb, 15, newOwner, &storagetx.ArkivTransaction{Delete: []common.Hash{key}})
+	require.NoError(t, err)
+	requireOwnerSlots(t, statedb, 0, 0)
+	require.Zero(t, storageaccounting.GetNumberOfUsedSlots(statedb).Uint64())
+}
+
+func TestArkivOwnerSlotsBeforeFork(t *testing.T) {
+	statedb, key := createOwnedEntity(t, ownerSlotsConfig(false))
+	requireOwnerSlots(t, statedb, 0, 0)
+
+	// The slots of the entities created before the fork are not counted
+	config := ownerSlotsConfig(true)
+	applyArkivTransaction(t, config, statedb, 3, &storagetx.ArkivTransaction{
+		Create: []storagetx.ArkivCreate{{BTL: 100, ContentType: "text/plain", Payload: []byte("counted")}},
+	})
+	requireOwnerSlots(t, statedb, 3, 0)
+
+	applyArkivTransaction(t, config, statedb, 4, &storagetx.ArkivTransaction{Delete: []common.Hash{key}})
+	requireOwnerSlots(t, statedb, 0, 0)
+}
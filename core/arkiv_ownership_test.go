@@ -0,0 +1,214 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/arkiv/address"
+	arkivlogs "github.com/ethereum/go-ethereum/arkiv/logs"
+	"github.com/ethereum/go-ethereum/arkiv/storageaccounting"
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	owner    = common.HexToAddress("0x1")
+	newOwner = common.HexToAddress("0x2")
+)
+
+func ownershipConfig(active bool) *params.ChainConfig {
+	config := *params.OptimismTestConfig
+	if active {
+		config.ArkivOwnershipTime = new(uint64)
+		config.ArkivOwnershipTransferWindow = 5
+	}
+	return &config
+}
+
+// createOwnedEntity creates an entity of owner at block 1 and proposes to transfer it
+// to newOwner at block 2, the transfer lapses at block 7.
+func createOwnedEntity(t *testing.T, config *params.ChainConfig) (*state.StateDB, common.Hash) {
+	t.Helper()
+
+	statedb, err := state.New(types.EmptyRootHash, state.NewDatabaseForTesting())
+	require.NoError(t, err)
+
+	logs := applyArkivTransaction(t, config, statedb, 1, &storagetx.ArkivTransaction{
+		Create: []storagetx.ArkivCreate{{BTL: 100, ContentType: "text/plain", Payload: []byte("owned")}},
+	})
+	require.Len(t, logs, 1)
+	key := logs[0].Topics[1]
+
+	logs = applyArkivTransaction(t, config, statedb, 2, &storagetx.ArkivTransaction{
+		ChangeOwner: []storagetx.ArkivChangeOwner{{EntityKey: key, NewOwner: newOwner}},
+	})
+	require.Len(t, logs, 1)
+	require.Equal(t, arkivlogs.ArkivEntityOwnershipTransferProposed, logs[0].Topics[0])
+
+	return statedb, key
+}
+
+func acceptOwnership(key common.Hash) *storagetx.ArkivTransaction {
+	return &storagetx.ArkivTransaction{
+		Version:         storagetx.TransactionVersionOwnership,
+		AcceptOwnership: []common.Hash{key},
+	}
+}
+
+func requireOwner(t *testing.T, statedb *state.StateDB, key common.Hash, expected common.Address) {
+	t.Helper()
+
+	md, err := entity.GetEntityMetaData(statedb, key)
+	require.NoError(t, err)
+	require.Equal(t, expected, md.Owner)
+}
+
+// lapsedTransfers returns the keys of the entities whose transfer lapsed.
+func lapsedTransfers(statedb *state.StateDB) []common.Hash {
+	keys := []common.Hash{}
+	for _, log := range statedb.Logs() {
+		if log.Topics[0] == arkivlogs.ArkivEntityOwnershipTransferLapsed {
+			keys = append(keys, log.Topics[1])
+		}
+	}
+	return keys
+}
+
+func TestArkivOwnershipTransferAccepted(t *testing.T) {
+	config := ownershipConfig(true)
+	statedb, key := createOwnedEntity(t, config)
+
+	// The owner doesn't change until the transfer is accepted
+	requireOwner(t, statedb, key, owner)
+	require.Equal(t, &entity.PendingOwner{Owner: newOwner, LapsesAtBlock: 7}, entity.GetPendingOwner(statedb, key))
+
+	_, err := executeArkivTransaction(t, config, statedb, 3, common.HexToAddress("0x3"), acceptOwnership(key))
+	require.Error(t, err)
+
+	// Block 6 is the last block the transfer can be accepted at
+	applyHousekeepingDeposit(t, config, statedb, 6)
+	logs, err := executeArkivTransaction(t, config, statedb, 6, newOwner, acceptOwnership(key))
+	require.NoError(t, err)
+	require.Len(t, logs, 2)
+	require.Equal(t, arkivlogs.ArkivEntityOwnershipTransferAccepted, logs[0].Topics[0])
+	require.Equal(t, arkivlogs.ArkivEntityOwnerChanged, logs[1].Topics[0])
+
+	requireOwner(t, statedb, key, newOwner)
+	require.Nil(t, entity.GetPendingOwner(statedb, key))
+
+	applyHousekeepingDeposit(t, config, statedb, 7)
+	require.Empty(t, lapsedTransfers(statedb))
+	requireOwner(t, statedb, key, newOwner)
+}
+
+func TestArkivOwnershipTransferLapsed(t *testing.T) {
+	config := ownershipConfig(true)
+	statedb, key := createOwnedEntity(t, config)
+
+	applyHousekeepingDeposit(t, config, statedb, 6)
+	require.NotNil(t, entity.GetPendingOwner(statedb, key))
+	require.Empty(t, lapsedTransfers(statedb))
+
+	// The transfer lapses at block 7, before the transactions of the block
+	applyHousekeepingDeposit(t, config, statedb, 7)
+	require.Nil(t, entity.GetPendingOwner(statedb, key))
+	require.Equal(t, []common.Hash{key}, lapsedTransfers(statedb))
+
+	_, err := executeArkivTransaction(t, config, statedb, 7, newOwner, acceptOwnership(key))
+	require.Error(t, err)
+	requireOwner(t, statedb, key, owner)
+
+	// The slots of the pending transfer are reclaimed
+	applyArkivTransaction(t, config, statedb, 8, &storagetx.ArkivTransaction{Delete: []common.Hash{key}})
+	require.Zero(t, storageaccounting.GetNumberOfUsedSlots(statedb).Uint64())
+}
+
+func TestArkivOwnershipTransferReplaced(t *testing.T) {
+	config := ownershipConfig(true)
+	statedb, key := createOwnedEntity(t, config)
+
+	// A new proposal replaces the pending transfer and its lapse
+	other := common.HexToAddress("0x3")
+	applyArkivTransaction(t, config, statedb, 4, &storagetx.ArkivTransaction{
+		ChangeOwner: []storagetx.ArkivChangeOwner{{EntityKey: key, NewOwner: other}},
+	})
+	require.Equal(t, &entity.PendingOwner{Owner: other, LapsesAtBlock: 9}, entity.GetPendingOwner(statedb, key))
+
+	_, err := executeArkivTransaction(t, config, statedb, 5, newOwner, acceptOwnership(key))
+	require.Error(t, err)
+
+	applyHousekeepingDeposit(t, config, statedb, 7)
+	require.Empty(t, lapsedTransfers(statedb))
+	applyHousekeepingDeposit(t, config, statedb, 9)
+	require.Equal(t, []common.Hash{key}, lapsedTransfers(statedb))
+}
+
+func TestArkivOwnershipTransferImmediate(t *testing.T) {
+	config := ownershipConfig(true)
+	statedb, key := createOwnedEntity(t, config)
+
+	logs := applyArkivTransaction(t, config, statedb, 3, &storagetx.ArkivTransaction{
+		Version:     storagetx.TransactionVersionOwnership,
+		ChangeOwner: []storagetx.ArkivChangeOwner{{EntityKey: key, NewOwner: common.HexToAddress("0x3"), Immediate: true}},
+	})
+	require.Len(t, logs, 1)
+	require.Equal(t, arkivlogs.ArkivEntityOwnerChanged, logs[0].Topics[0])
+
+	// The immediate change cancels the pending transfer
+	requireOwner(t, statedb, key, common.HexToAddress("0x3"))
+	require.Nil(t, entity.GetPendingOwner(statedb, key))
+	applyHousekeepingDeposit(t, config, statedb, 7)
+	require.Empty(t, lapsedTransfers(statedb))
+
+	// Immediate changes require the ownership transaction version
+	_, err := executeArkivTransaction(t, config, statedb, 8, common.HexToAddress("0x3"), &storagetx.ArkivTransaction{
+		Version:     storagetx.TransactionVersionEncryption,
+		ChangeOwner: []storagetx.ArkivChangeOwner{{EntityKey: key, NewOwner: owner, Immediate: true}},
+	})
+	require.ErrorContains(t, err, "requires transaction version")
+}
+
+func TestArkivOwnershipTransferInvalidOwner(t *testing.T) {
+	config := ownershipConfig(true)
+	statedb, key := createOwnedEntity(t, config)
+
+	for _, invalid := range []common.Address{{}, address.ArkivProcessorAddress} {
+		for _, immediate := range []bool{false, true} {
+			_, err := executeArkivTransaction(t, config, statedb, 3, owner, &storagetx.ArkivTransaction{
+				Version:     storagetx.TransactionVersionOwnership,
+				ChangeOwner: []storagetx.ArkivChangeOwner{{EntityKey: key, NewOwner: invalid, Immediate: immediate}},
+			})
+			require.Error(t, err, "new owner %s, immediate %v", invalid, immediate)
+		}
+	}
+
+	requireOwner(t, statedb, key, owner)
+	require.Equal(t, newOwner, entity.GetPendingOwner(statedb, key).Owner)
+}
+
+func TestArkivOwnershipTransferBeforeFork(t *testing.T) {
+	config := ownershipConfig(false)
+	statedb, err := state.New(types.EmptyRootHash, state.NewDatabaseForTesting())
+	require.NoError(t, err)
+
+	logs := applyArkivTransaction(t, config, statedb, 1, &storagetx.ArkivTransaction{
+		Create: []storagetx.ArkivCreate{{BTL: 100, ContentType: "text/plain", Payload: []byte("owned")}},
+	})
+	key := logs[0].Topics[1]
+
+	// Ownership changes right away
+	logs = applyArkivTransaction(t, config, statedb, 2, &storagetx.ArkivTransaction{
+		ChangeOwner: []storagetx.ArkivChangeOwner{{EntityKey: key, NewOwner: newOwner}},
+	})
+	require.Len(t, logs, 1)
+	require.Equal(t, arkivlogs.ArkivEntityOwnerChanged, logs[0].Topics[0])
+	requireOwner(t, statedb, key, newOwner)
+	require.Nil(t, entity.GetPendingOwner(statedb, key))
+
+	_, err = executeArkivTransaction(t, config, statedb, 3, newOwner, acceptOwnership(key))
+	require.Error(t, err)
+}
@@ -0,0 +1,58 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+func payloadLimitsConfig(active bool) *params.ChainConfig {
+	config := *params.OptimismTestConfig
+	if active {
+		config.ArkivPayloadLimitsTime = new(uint64)
+		config.ArkivMaxPayloadSize = 16
+		config.ArkivMaxOperations = 2
+	}
+	return &config
+}
+
+func createPayloads(sizes ...int) *storagetx.ArkivTransaction {
+	tx := &storagetx.ArkivTransaction{}
+	for _, size := range sizes {
+		tx.Create = append(tx.Create, storagetx.ArkivCreate{BTL: 10, ContentType: "text/plain", Payload: make([]byte, size)})
+	}
+	return tx
+}
+
+func TestArkivPayloadLimits(t *testing.T) {
+	config := payloadLimitsConfig(true)
+	statedb, err := state.New(types.EmptyRootHash, state.NewDatabaseForTesting())
+	require.NoError(t, err)
+
+	results := applyBlock(t, config, statedb, 1,
+		arkivMessage(t, 1, createPayloads(16, 16)),
+		arkivMessage(t, 1, createPayloads(16, 17)),
+		arkivMessage(t, 1, createPayloads(1, 1, 1)),
+	)
+	require.NoError(t, results[0].Err)
+	require.EqualError(t, results[1].Err, "failed to unpack arkiv transaction: create[1] payload is too large: 17 bytes (max 16)")
+	require.EqualError(t, results[2].Err, "failed to unpack arkiv transaction: number of operations is greater than 2: 3 operations")
+
+	// The processor enforces the same limits
+	_, err = executeArkivTransaction(t, config, statedb, 1, common.HexToAddress("0x1"), createPayloads(17))
+	require.ErrorContains(t, err, "create[0] payload is too large")
+}
+
+func TestArkivPayloadLimitsBeforeFork(t *testing.T) {
+	config := payloadLimitsConfig(false)
+	statedb, err := state.New(types.EmptyRootHash, state.NewDatabaseForTesting())
+	require.NoError(t, err)
+
+	results := applyBlock(t, config, statedb, 1, arkivMessage(t, 1, createPayloads(17, 1, 1)))
+	require.NoError(t, results[0].Err)
+}
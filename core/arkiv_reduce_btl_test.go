@@ -0,0 +1,68 @@
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/arkiv/logs"
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+func reduceBTLConfig(active bool) *params.ChainConfig {
+	config := *params.OptimismTestConfig
+	if active {
+		config.ArkivReduceBTLTime = new(uint64)
+	}
+	return &config
+}
+
+func reduceBTL(key common.Hash, blocks uint64) *storagetx.ArkivTransaction {
+	return &storagetx.ArkivTransaction{
+		Version:   storagetx.TransactionVersionReduceBTL,
+		ReduceBTL: []storagetx.ArkivReduceBTL{{EntityKey: key, NumberOfBlocks: blocks}},
+	}
+}
+
+func TestArkivReduceBTL(t *testing.T) {
+	config := reduceBTLConfig(true)
+	// The entity expires at block 11
+	statedb, key := createExpiringEntity(t, config)
+
+	// Only the owner can reduce the BTL
+	_, err := executeArkivTransaction(t, config, statedb, 3, common.HexToAddress("0x2"), reduceBTL(key, 6))
+	require.ErrorContains(t, err, "is not the owner")
+
+	// The entity must still expire after the block
+	_, err = executeArkivTransaction(t, config, statedb, 3, common.HexToAddress("0x1"), reduceBTL(key, 8))
+	require.Error(t, err)
+
+	reduced := applyArkivTransaction(t, config, statedb, 3, reduceBTL(key, 6))
+	require.Len(t, reduced, 1)
+	require.Equal(t, []common.Hash{logs.ArkivEntityBTLReduced, key, common.BytesToHash(common.HexToAddress("0x1").Bytes())}, reduced[0].Topics)
+	require.Equal(t, uint64(11), new(big.Int).SetBytes(reduced[0].Data[:32]).Uint64())
+	require.Equal(t, uint64(5), new(big.Int).SetBytes(reduced[0].Data[32:]).Uint64())
+
+	// The housekeeping expires the entity at its new expiry
+	applyHousekeepingDeposit(t, config, statedb, 4)
+	require.True(t, entity.Exists(statedb, key))
+	applyHousekeepingDeposit(t, config, statedb, 5)
+	require.False(t, entity.Exists(statedb, key))
+}
+
+func TestArkivReduceBTLBeforeFork(t *testing.T) {
+	config := reduceBTLConfig(false)
+	statedb, key := createExpiringEntity(t, config)
+
+	_, err := executeArkivTransaction(t, config, statedb, 3, common.HexToAddress("0x1"), reduceBTL(key, 6))
+	require.ErrorContains(t, err, "reducing the BTL is not active")
+
+	// Older transaction versions can't carry the reductions
+	tx := reduceBTL(key, 6)
+	tx.Version = storagetx.TransactionVersionIdempotency
+	_, err = executeArkivTransaction(t, config, statedb, 3, common.HexToAddress("0x1"), tx)
+	require.ErrorContains(t, err, "requires transaction version")
+}
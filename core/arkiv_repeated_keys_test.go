@@ -0,0 +1,90 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/arkiv/logs"
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity/entityexpiration"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+// createIdempotentEntity creates an entity with the idempotency key at block 1,
+// expiring at block 101.
+func createIdempotentEntity(t *testing.T, idempotencyKey common.Hash) (*state.StateDB, common.Hash) {
+	t.Helper()
+
+	statedb, err := state.New(types.EmptyRootHash, state.NewDatabaseForTesting())
+	require.NoError(t, err)
+	created := applyArkivTransaction(t, idempotencyConfig(true), statedb, 1, idempotentCreate(idempotencyKey))
+	return statedb, created[0].Topics[1]
+}
+
+func TestArkivRepeatedKeysCreateDelete(t *testing.T) {
+	config := idempotencyConfig(true)
+	idempotencyKey := common.HexToHash("0xabc")
+	statedb, key := createIdempotentEntity(t, idempotencyKey)
+
+	// The retried create resolves to the entity, which the delete of the same
+	// transaction then removes, along with its expiration
+	tx := idempotentCreate(idempotencyKey)
+	tx.Delete = []common.Hash{key}
+	applied := applyArkivTransaction(t, config, statedb, 5, tx)
+	require.Len(t, applied, 2)
+	require.Equal(t, []common.Hash{logs.ArkivCreateDeduplicated, key}, applied[0].Topics[:2])
+	require.Equal(t, []common.Hash{logs.ArkivEntityDeleted, key}, applied[1].Topics[:2])
+	require.False(t, entity.Exists(statedb, key))
+	require.Zero(t, entityexpiration.SizeOfEntitiesToExpireAtBlock(statedb, 101))
+
+	// A create retried after the delete doesn't bring the entity back
+	retried := applyArkivTransaction(t, config, statedb, 6, idempotentCreate(idempotencyKey))
+	require.Equal(t, []common.Hash{logs.ArkivCreateDeduplicated, key}, retried[0].Topics[:2])
+	require.False(t, entity.Exists(statedb, key))
+}
+
+func TestArkivRepeatedKeysCreateUpdateDelete(t *testing.T) {
+	config := idempotencyConfig(true)
+	idempotencyKey := common.HexToHash("0xabc")
+	statedb, key := createIdempotentEntity(t, idempotencyKey)
+
+	// The deletes run before the updates, the update finds no entity and the whole
+	// transaction fails
+	tx := idempotentCreate(idempotencyKey)
+	tx.Update = []storagetx.ArkivUpdate{{EntityKey: key, BTL: 10, ContentType: "text/plain", Payload: []byte("updated")}}
+	tx.Delete = []common.Hash{key}
+	_, err := executeArkivTransaction(t, config, statedb, 5, common.HexToAddress("0x1"), tx)
+	require.ErrorIs(t, err, entity.ErrEntityNotFound)
+	require.ErrorContains(t, err, "failed to get entity meta data for update "+key.Hex())
+	require.True(t, entity.Exists(statedb, key))
+
+	// A key deleted twice fails the same way
+	_, err = executeArkivTransaction(t, config, statedb, 5, common.HexToAddress("0x1"), &storagetx.ArkivTransaction{Delete: []common.Hash{key, key}})
+	require.ErrorIs(t, err, entity.ErrEntityNotFound)
+	require.True(t, entity.Exists(statedb, key))
+}
+
+func TestArkivRepeatedKeysUpdates(t *testing.T) {
+	config := idempotencyConfig(true)
+	statedb, key := createIdempotentEntity(t, common.HexToHash("0xabc"))
+
+	// The updates of the same key apply in order, the extend after them, and only the
+	// final expiry of the entity is scheduled
+	update := func(btl uint64, payload string) storagetx.ArkivUpdate {
+		return storagetx.ArkivUpdate{EntityKey: key, BTL: btl, ContentType: "text/plain", Payload: []byte(payload)}
+	}
+	applyArkivTransaction(t, config, statedb, 5, &storagetx.ArkivTransaction{
+		Update: []storagetx.ArkivUpdate{update(10, "first"), update(20, "second")},
+		Extend: []storagetx.ExtendBTL{{EntityKey: key, NumberOfBlocks: 5}},
+	})
+	md, err := entity.GetEntityMetaData(statedb, key)
+	require.NoError(t, err)
+	require.Equal(t, uint64(30), md.ExpiresAtBlock)
+	for _, block := range []uint64{15, 25, 101} {
+		require.Zero(t, entityexpiration.SizeOfEntitiesToExpireAtBlock(statedb, block))
+	}
+	require.Equal(t, uint64(1), entityexpiration.SizeOfEntitiesToExpireAtBlock(statedb, 30))
+}
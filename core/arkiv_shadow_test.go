@@ -0,0 +1,105 @@
+package core
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/arkiv/shadow"
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+)
+
+func gasScheduleConfig(active bool) *params.ChainConfig {
+	config := *params.OptimismTestConfig
+	if active {
+		config.ArkivGasScheduleTime = new(uint64)
+	}
+	return &config
+}
+
+func createAnnotationValue(size int) *storagetx.ArkivTransaction {
+	return &storagetx.ArkivTransaction{Create: []storagetx.ArkivCreate{{
+		BTL:               10,
+		ContentType:       "text/plain",
+		Payload:           []byte("payload"),
+		StringAnnotations: []storagetx.StringAnnotation{{Key: "a", Value: strings.Repeat("a", size)}},
+	}}}
+}
+
+// applyShadowed applies the message on an empty state, checking the rules before their
+// forks with the recorder if it's set.
+func applyShadowed(t *testing.T, config *params.ChainConfig, recorder *shadow.Recorder, msg *Message) *ExecutionResult {
+	t.Helper()
+
+	statedb, err := state.New(types.EmptyRootHash, state.NewDatabaseForTesting())
+	require.NoError(t, err)
+
+	blockContext := vm.BlockContext{
+		CanTransfer: CanTransfer,
+		Transfer:    Transfer,
+		BlockNumber: new(big.Int).SetUint64(msg.BlockNumber),
+		GasLimit:    30_000_000,
+		BaseFee:     big.NewInt(0),
+		Random:      &common.Hash{},
+		L1CostFunc:  func(types.RollupCostData, uint64) *big.Int { return nil },
+		OperatorCostFunc: func(uint64, uint64) *uint256.Int {
+			return uint256.NewInt(0)
+		},
+	}
+	evm := vm.NewEVM(blockContext, statedb, config, vm.Config{ArkivShadow: recorder})
+
+	res, err := ApplyMessage(evm, msg, new(GasPool).AddGas(blockContext.GasLimit))
+	require.NoError(t, err)
+	return res
+}
+
+func TestArkivAnnotationValueCapShadowEnforcement(t *testing.T) {
+	recorder := shadow.NewRecorder(shadow.DefaultRecentViolations)
+	maxSize := int(params.DefaultArkivMaxAnnotationValueSize)
+	violations := 0
+	for i, size := range []int{16, maxSize + 1, maxSize} {
+		msg := arkivMessage(t, uint64(10+i), createAnnotationValue(size))
+		msg.TransactionHash = common.Hash{byte(i)}
+
+		// The live rule and its shadow classify the transaction the same way
+		live := applyShadowed(t, gasScheduleConfig(true), nil, msg)
+		shadowed := applyShadowed(t, gasScheduleConfig(false), recorder, msg)
+		require.NoError(t, shadowed.Err)
+
+		violated := len(recorder.Recent()) > violations
+		violations = len(recorder.Recent())
+		require.Equal(t, live.Err != nil, violated, "size %d", size)
+	}
+
+	stats := recorder.Stats()
+	require.Equal(t, []shadow.RuleStats{{Feature: params.ArkivFeatureGasSchedule, Evaluated: 3, Violations: 1}}, stats)
+	recent := recorder.Recent()
+	require.Len(t, recent, 1)
+	require.Equal(t, uint64(11), recent[0].Block)
+	require.Equal(t, common.Hash{1}, recent[0].TxHash)
+	require.Equal(t, "create[0] string annotation a value is too long: 8193 bytes (max 8192)", recent[0].Error)
+
+	// Once the fork is active the rule is enforced, not recorded
+	live := applyShadowed(t, gasScheduleConfig(true), recorder, arkivMessage(t, 11, createAnnotationValue(maxSize+1)))
+	require.EqualError(t, live.Err, "failed to unpack arkiv transaction: create[0] string annotation a value is too long: 8193 bytes (max 8192)")
+	require.Equal(t, stats, recorder.Stats())
+}
+
+func TestArkivAnnotationValueCapConfigured(t *testing.T) {
+	// Before the fork the shadow checks the cap the fork will apply
+	recorder := shadow.NewRecorder(shadow.DefaultRecentViolations)
+	config := gasScheduleConfig(false)
+	config.ArkivMaxAnnotationValueSize = 64
+
+	res := applyShadowed(t, config, recorder, arkivMessage(t, 1, createAnnotationValue(65)))
+	require.NoError(t, res.Err)
+	require.Equal(t, []shadow.RuleStats{{Feature: params.ArkivFeatureGasSchedule, Evaluated: 1, Violations: 1}}, recorder.Stats())
+	require.Equal(t, "create[0] string annotation a value is too long: 65 bytes (max 64)", recorder.Recent()[0].Error)
+}
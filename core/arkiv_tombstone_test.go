@@ -0,0 +1,158 @@
+package core
+
+import (
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/arkiv/compression"
+	"github.com/ethereum/go-ethereum/arkiv/storageaccounting"
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/stretchr/testify/require"
+)
+
+func tombstonesConfig(active bool) *params.ChainConfig {
+	config := *params.OptimismTestConfig
+	if active {
+		config.ArkivTombstonesTime = new(uint64)
+		config.ArkivTombstoneRetention = 5
+	}
+	return &config
+}
+
+// applyArkivTransaction executes an Arkiv transaction in the block with the number.
+func applyArkivTransaction(t *testing.T, config *params.ChainConfig, statedb *state.StateDB, blockNumber uint64, tx *storagetx.ArkivTransaction) []*types.Log {
+	t.Helper()
+
+	logs, err := executeArkivTransaction(t, config, statedb, blockNumber, common.HexToAddress("0x1"), tx)
+	require.NoError(t, err)
+	return logs
+}
+
+// executeArkivTransaction executes an Arkiv transaction of the sender in the block
+// with the number, the state is left as is if the transaction fails.
+func executeArkivTransaction(t *testing.T, config *params.ChainConfig, statedb *state.StateDB, blockNumber uint64, sender common.Address, tx *storagetx.ArkivTransaction) ([]*types.Log, error) {
+	t.Helper()
+
+	data, err := rlp.EncodeToBytes(tx)
+	require.NoError(t, err)
+
+	snapshot := statedb.Snapshot()
+	logs, err := storagetx.ExecuteArkivTransaction(
+		compression.MustBrotliCompress(data),
+		storagetx.RulesAt(config, 0),
+		blockNumber,
+		common.BigToHash(new(big.Int).SetUint64(blockNumber)),
+		0,
+		sender,
+		statedb,
+	)
+	if err != nil {
+		statedb.RevertToSnapshot(snapshot)
+	}
+	return logs, err
+}
+
+// applyHousekeepingDeposit applies a housekeeping deposit in the block with the number.
+func applyHousekeepingDeposit(t *testing.T, config *params.ChainConfig, statedb *state.StateDB, blockNumber uint64) {
+	t.Helper()
+
+	blockContext := vm.BlockContext{
+		CanTransfer: CanTransfer,
+		Transfer:    Transfer,
+		BlockNumber: new(big.Int).SetUint64(blockNumber),
+		GasLimit:    30_000_000,
+		BaseFee:     big.NewInt(0),
+		Random:      &common.Hash{},
+	}
+	evm := vm.NewEVM(blockContext, statedb, config, vm.Config{})
+
+	to := common.HexToAddress("0x2")
+	res, err := ApplyMessage(evm, &Message{
+		From:        common.HexToAddress("0x3"),
+		To:          &to,
+		GasLimit:    1_000_000,
+		GasPrice:    big.NewInt(0),
+		GasFeeCap:   big.NewInt(0),
+		GasTipCap:   big.NewInt(0),
+		Value:       big.NewInt(0),
+		IsDepositTx: true,
+		BlockNumber: blockNumber,
+	}, new(GasPool).AddGas(math.MaxUint64))
+	require.NoError(t, err)
+	require.NoError(t, res.Err)
+}
+
+// createEntities creates an entity expiring at block 11 and one deleted at block 2.
+func createEntities(t *testing.T, config *params.ChainConfig) (*state.StateDB, common.Hash, common.Hash) {
+	t.Helper()
+
+	statedb, err := state.New(types.EmptyRootHash, state.NewDatabaseForTesting())
+	require.NoError(t, err)
+
+	logs := applyArkivTransaction(t, config, statedb, 1, &storagetx.ArkivTransaction{
+		Create: []storagetx.ArkivCreate{
+			{BTL: 10, ContentType: "text/plain", Payload: []byte("expires")},
+			{BTL: 100, ContentType: "text/plain", Payload: []byte("deleted")},
+		},
+	})
+	require.Len(t, logs, 2)
+	expired, deleted := logs[0].Topics[1], logs[1].Topics[1]
+
+	applyArkivTransaction(t, config, statedb, 2, &storagetx.ArkivTransaction{
+		Delete: []common.Hash{deleted},
+	})
+
+	return statedb, expired, deleted
+}
+
+func TestArkivTombstones(t *testing.T) {
+	config := tombstonesConfig(true)
+	statedb, expired, deleted := createEntities(t, config)
+
+	require.Equal(t, &entity.Tombstone{Reason: entity.TombstoneDeleted, Block: 2}, entity.GetTombstone(statedb, deleted))
+	require.Nil(t, entity.GetTombstone(statedb, expired))
+
+	slots := storageaccounting.GetNumberOfUsedSlots(statedb).Uint64()
+
+	// The tombstone of the deleted entity is kept until block 7
+	applyHousekeepingDeposit(t, config, statedb, 6)
+	require.NotNil(t, entity.GetTombstone(statedb, deleted))
+
+	applyHousekeepingDeposit(t, config, statedb, 7)
+	require.Nil(t, entity.GetTombstone(statedb, deleted))
+	sweptSlots := storageaccounting.GetNumberOfUsedSlots(statedb).Uint64()
+	require.Less(t, sweptSlots, slots)
+
+	// The expired entity leaves a tombstone kept until block 16
+	applyHousekeepingDeposit(t, config, statedb, 11)
+	_, err := entity.GetEntityMetaData(statedb, expired)
+	require.Error(t, err)
+	require.Equal(t, &entity.Tombstone{Reason: entity.TombstoneExpired, Block: 11}, entity.GetTombstone(statedb, expired))
+
+	applyHousekeepingDeposit(t, config, statedb, 15)
+	require.NotNil(t, entity.GetTombstone(statedb, expired))
+
+	applyHousekeepingDeposit(t, config, statedb, 16)
+	require.Nil(t, entity.GetTombstone(statedb, expired))
+
+	// All the slots of the entities and their tombstones are reclaimed
+	require.Zero(t, storageaccounting.GetNumberOfUsedSlots(statedb).Uint64())
+}
+
+func TestArkivTombstonesBeforeFork(t *testing.T) {
+	config := tombstonesConfig(false)
+	statedb, expired, deleted := createEntities(t, config)
+	applyHousekeepingDeposit(t, config, statedb, 11)
+
+	require.Nil(t, entity.GetTombstone(statedb, deleted))
+	require.Nil(t, entity.GetTombstone(statedb, expired))
+	require.Zero(t, storageaccounting.GetNumberOfUsedSlots(statedb).Uint64())
+}
@@ -338,7 +338,17 @@ type BlockChain struct {
 	stateSizer *state.SizeTracker // State size tracking
 
 	lastForkReadyAlert time.Time // Last time there was a fork readiness print out
-	onNewBlock         func(chainConfig *params.ChainConfig, block *types.Block) error
+	hooks              BlockHooks
+}
+
+// BlockHooks are notified about the blocks joining and leaving the canonical chain,
+// with the chain mutex held. Errors are logged, they don't fail the import.
+type BlockHooks interface {
+	// OnNewBlock is called with every block becoming the head of the canonical chain.
+	OnNewBlock(chainConfig *params.ChainConfig, block *types.Block) error
+	// OnRemovedBlock is called with every block a reorg removes from the canonical
+	// chain, newest first, before the blocks of the new chain are added.
+	OnRemovedBlock(chainConfig *params.ChainConfig, block *types.Block) error
 }
 
 // NewBlockChain returns a fully initialised block chain using information
@@ -350,13 +360,12 @@ type BlockChain struct {
 //			cfg = DefaultConfig()
 //		}
 func NewBlockChain(db ethdb.Database, genesis *Genesis, engine consensus.Engine, cfg *BlockChainConfig) (*BlockChain, error) {
-	return NewBlockChainWithOnNewBlock(db, genesis, engine, cfg, nil)
+	return NewBlockChainWithHooks(db, genesis, engine, cfg, nil)
 }
 
-// NewBlockChain returns a fully initialised block chain using information
-// available in the database. It initialises the default Ethereum Validator
-// and Processor.
-func NewBlockChainWithOnNewBlock(db ethdb.Database, genesis *Genesis, engine consensus.Engine, cfg *BlockChainConfig, onNewBlock func(chainConfig *params.ChainConfig, block *types.Block) error) (*BlockChain, error) {
+// NewBlockChainWithHooks returns a block chain like NewBlockChain, notifying the hooks
+// about the changes of the canonical chain.
+func NewBlockChainWithHooks(db ethdb.Database, genesis *Genesis, engine consensus.Engine, cfg *BlockChainConfig, hooks BlockHooks) (*BlockChain, error) {
 	if cfg == nil {
 		cfg = DefaultConfig()
 	}
@@ -402,7 +411,7 @@ func NewBlockChainWithOnNewBlock(db ethdb.Database, genesis *Genesis, engine con
 		txLookupCache: lru.NewCache[common.Hash, txLookup](txLookupCacheLimit),
 		engine:        engine,
 		logger:        cfg.VmConfig.Tracer,
-		onNewBlock:    onNewBlock,
+		hooks:         hooks,
 	}
 	bc.hc, err = NewHeaderChain(db, chainConfig, engine, bc.insertStopped)
 	if err != nil {
@@ -1266,11 +1275,9 @@ func (bc *BlockChain) writeHeadBlock(block *types.Block) {
 	// OPStack addition
 	updateOptimismBlockMetrics(block.Header())
 
-	if bc.onNewBlock != nil {
-
-		err := bc.onNewBlock(bc.chainConfig, block)
-		if err != nil {
-			log.Warn("Failed to call onNewBlock", "err", err)
+	if bc.hooks != nil {
+		if err := bc.hooks.OnNewBlock(bc.chainConfig, block); err != nil {
+			log.Warn("Failed to call OnNewBlock hook", "err", err)
 		}
 	}
 
@@ -2528,6 +2535,11 @@ func (bc *BlockChain) reorg(oldHead *types.Header, newHead *types.Header) error
 		for _, tx := range block.Transactions() {
 			deletedTxs = append(deletedTxs, tx.Hash())
 		}
+		if bc.hooks != nil {
+			if err := bc.hooks.OnRemovedBlock(bc.chainConfig, block); err != nil {
+				log.Warn("Failed to call OnRemovedBlock hook", "err", err)
+			}
+		}
 		// Collect deleted logs and emit them for new integrations
 		if logs := bc.collectLogs(block, true); len(logs) > 0 {
 			// Emit revertals latest first, older then
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"math/big"
 
+	arkivgenesis "github.com/ethereum/go-ethereum/arkiv/genesis"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/common/math"
@@ -35,6 +36,7 @@ func (g Genesis) MarshalJSON() ([]byte, error) {
 		ExcessBlobGas *math.HexOrDecimal64                       `json:"excessBlobGas"`
 		BlobGasUsed   *math.HexOrDecimal64                       `json:"blobGasUsed"`
 		StateHash     *common.Hash                               `json:"stateHash,omitempty"`
+		Arkiv         *arkivgenesis.Manifest                     `json:"arkiv,omitempty"`
 	}
 	var enc Genesis
 	enc.Config = g.Config
@@ -58,6 +60,7 @@ func (g Genesis) MarshalJSON() ([]byte, error) {
 	enc.ExcessBlobGas = (*math.HexOrDecimal64)(g.ExcessBlobGas)
 	enc.BlobGasUsed = (*math.HexOrDecimal64)(g.BlobGasUsed)
 	enc.StateHash = g.StateHash
+	enc.Arkiv = g.Arkiv
 	return json.Marshal(&enc)
 }
 
@@ -80,6 +83,7 @@ func (g *Genesis) UnmarshalJSON(input []byte) error {
 		ExcessBlobGas *math.HexOrDecimal64                       `json:"excessBlobGas"`
 		BlobGasUsed   *math.HexOrDecimal64                       `json:"blobGasUsed"`
 		StateHash     *common.Hash                               `json:"stateHash,omitempty"`
+		Arkiv         *arkivgenesis.Manifest                     `json:"arkiv,omitempty"`
 	}
 	var dec Genesis
 	if err := json.Unmarshal(input, &dec); err != nil {
@@ -139,5 +143,8 @@ func (g *Genesis) UnmarshalJSON(input []byte) error {
 	if dec.StateHash != nil {
 		g.StateHash = dec.StateHash
 	}
+	if dec.Arkiv != nil {
+		g.Arkiv = dec.Arkiv
+	}
 	return nil
 }
@@ -24,6 +24,8 @@ import (
 	"math/big"
 	"strings"
 
+	"github.com/ethereum/go-ethereum/arkiv/address"
+	arkivgenesis "github.com/ethereum/go-ethereum/arkiv/genesis"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/common/math"
@@ -79,6 +81,9 @@ type Genesis struct {
 	// Chains with history pruning, or extraordinarily large genesis allocation (e.g. after a regenesis event)
 	// may utilize this to get started, and then state-sync the latest state, while still verifying the header chain.
 	StateHash *common.Hash `json:"stateHash,omitempty"`
+
+	// Arkiv declares the Arkiv entities seeded by the allocation.
+	Arkiv *arkivgenesis.Manifest `json:"arkiv,omitempty"`
 }
 
 // copy copies the genesis.
@@ -709,6 +714,11 @@ func (g *Genesis) Commit(db ethdb.Database, triedb *triedb.Database) (*types.Blo
 	if config.Clique != nil && len(g.ExtraData) < 32+crypto.SignatureLength {
 		return nil, errors.New("can't start clique chain without signers")
 	}
+	if g.Arkiv != nil {
+		if err := g.Arkiv.Validate(g.Alloc[address.ArkivProcessorAddress].Storage); err != nil {
+			return nil, fmt.Errorf("invalid Arkiv genesis manifest: %w", err)
+		}
+	}
 	var stateRoot, storageRootMessagePasser common.Hash
 	var err error
 	if len(g.Alloc) == 0 {
@@ -733,6 +743,13 @@ func (g *Genesis) Commit(db ethdb.Database, triedb *triedb.Database) (*types.Blo
 	}
 	batch := db.NewBatch()
 	rawdb.WriteGenesisStateSpec(batch, block.Hash(), blob)
+	if g.Arkiv != nil {
+		manifest, err := json.Marshal(g.Arkiv)
+		if err != nil {
+			return nil, err
+		}
+		rawdb.WriteArkivGenesisManifest(batch, block.Hash(), manifest)
+	}
 	rawdb.WriteBlock(batch, block)
 	rawdb.WriteReceipts(batch, block.Hash(), block.NumberU64(), nil)
 	rawdb.WriteCanonicalHash(batch, block.Hash(), block.NumberU64())
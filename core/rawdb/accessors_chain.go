@@ -663,6 +663,22 @@ func DeleteReceipts(db ethdb.KeyValueWriter, hash common.Hash, number uint64) {
 	}
 }
 
+// PruneArkivReceipts replaces the receipts of a block with an empty list and marks them
+// as pruned by the Arkiv retention policy. The empty list keeps the block freezable.
+func PruneArkivReceipts(db ethdb.KeyValueWriter, hash common.Hash, number uint64) {
+	WriteRawReceipts(db, hash, number, rlp.EmptyList)
+	if err := db.Put(arkivPrunedReceiptsKey(number, hash), []byte{1}); err != nil {
+		log.Crit("Failed to store Arkiv pruned receipts marker", "err", err)
+	}
+}
+
+// HasArkivPrunedReceipts reports whether the receipts of a block were pruned by the
+// Arkiv retention policy.
+func HasArkivPrunedReceipts(db ethdb.KeyValueReader, hash common.Hash, number uint64) bool {
+	has, _ := db.Has(arkivPrunedReceiptsKey(number, hash))
+	return has
+}
+
 // ReceiptLogs is a barebone version of ReceiptForStorage which only keeps
 // the list of logs. When decoding a stored receipt into this object we
 // avoid creating the bloom filter.
@@ -17,6 +17,7 @@
 package rawdb
 
 import (
+	"encoding/binary"
 	"encoding/json"
 	"time"
 
@@ -98,6 +99,21 @@ func WriteGenesisStateSpec(db ethdb.KeyValueWriter, blockhash common.Hash, data
 	}
 }
 
+// ReadArkivGenesisManifest retrieves the manifest of the Arkiv entities seeded by the
+// genesis, nil if it declares none.
+func ReadArkivGenesisManifest(db ethdb.KeyValueReader, blockhash common.Hash) []byte {
+	data, _ := db.Get(arkivGenesisManifestKey(blockhash))
+	return data
+}
+
+// WriteArkivGenesisManifest writes the manifest of the Arkiv entities seeded by the
+// genesis into the disk.
+func WriteArkivGenesisManifest(db ethdb.KeyValueWriter, blockhash common.Hash, data []byte) {
+	if err := db.Put(arkivGenesisManifestKey(blockhash), data); err != nil {
+		log.Crit("Failed to store Arkiv genesis manifest", "err", err)
+	}
+}
+
 // crashList is a list of unclean-shutdown-markers, for rlp-encoding to the
 // database
 type crashList struct {
@@ -177,3 +193,75 @@ func UpdateUncleanShutdownMarker(db ethdb.KeyValueStore) {
 		log.Warn("Failed to write unclean-shutdown marker", "err", err)
 	}
 }
+
+// ReadArkivStoreCompressedPayloads retrieves whether the Arkiv store keeps its
+// payloads compressed.
+func ReadArkivStoreCompressedPayloads(db ethdb.KeyValueReader) bool {
+	compressed, _ := db.Has(arkivStoreCompressedPayloadsKey)
+	return compressed
+}
+
+// WriteArkivStoreCompressedPayloads stores whether the Arkiv store keeps its payloads
+// compressed.
+func WriteArkivStoreCompressedPayloads(db ethdb.KeyValueWriter, compressed bool) {
+	var err error
+	if compressed {
+		err = db.Put(arkivStoreCompressedPayloadsKey, []byte{1})
+	} else {
+		err = db.Delete(arkivStoreCompressedPayloadsKey)
+	}
+	if err != nil {
+		log.Crit("Failed to store Arkiv store payload mode", "err", err)
+	}
+}
+
+// ArkivEventsCheckpoint is the last block the Arkiv event iterator handed to its
+// consumer.
+type ArkivEventsCheckpoint struct {
+	Number uint64
+	Hash   common.Hash
+}
+
+// ReadArkivEventsCheckpoint retrieves the checkpoint of the Arkiv event iterator, nil
+// if none was written.
+func ReadArkivEventsCheckpoint(db ethdb.KeyValueReader) *ArkivEventsCheckpoint {
+	data, _ := db.Get(arkivEventsCheckpointKey)
+	if len(data) == 0 {
+		return nil
+	}
+	var checkpoint ArkivEventsCheckpoint
+	if err := rlp.DecodeBytes(data, &checkpoint); err != nil {
+		log.Error("Invalid Arkiv events checkpoint", "err", err)
+		return nil
+	}
+	return &checkpoint
+}
+
+// WriteArkivEventsCheckpoint stores the checkpoint of the Arkiv event iterator.
+func WriteArkivEventsCheckpoint(db ethdb.KeyValueWriter, checkpoint ArkivEventsCheckpoint) {
+	data, err := rlp.EncodeToBytes(checkpoint)
+	if err != nil {
+		log.Crit("Failed to encode Arkiv events checkpoint", "err", err)
+	}
+	if err := db.Put(arkivEventsCheckpointKey, data); err != nil {
+		log.Crit("Failed to store Arkiv events checkpoint", "err", err)
+	}
+}
+
+// ReadArkivReceiptsPrunedTo retrieves the last block the Arkiv receipt pruning went
+// through, 0 if it never ran.
+func ReadArkivReceiptsPrunedTo(db ethdb.KeyValueReader) uint64 {
+	data, _ := db.Get(arkivReceiptsPrunedToKey)
+	if len(data) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(data)
+}
+
+// WriteArkivReceiptsPrunedTo stores the last block the Arkiv receipt pruning went
+// through.
+func WriteArkivReceiptsPrunedTo(db ethdb.KeyValueWriter, number uint64) {
+	if err := db.Put(arkivReceiptsPrunedToKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store Arkiv receipt pruning progress", "err", err)
+	}
+}
@@ -55,6 +55,17 @@ var (
 	// snapshotDisabledKey flags that the snapshot should not be maintained due to initial sync.
 	snapshotDisabledKey = []byte("SnapshotDisabled")
 
+	// arkivStoreCompressedPayloadsKey flags that the Arkiv store keeps its payloads compressed.
+	arkivStoreCompressedPayloadsKey = []byte("ArkivStoreCompressedPayloads")
+
+	// arkivEventsCheckpointKey tracks the last block handed to the consumer of the Arkiv
+	// event iterator.
+	arkivEventsCheckpointKey = []byte("ArkivEventsCheckpoint")
+
+	// arkivReceiptsPrunedToKey tracks the last block the Arkiv receipt pruning went
+	// through.
+	arkivReceiptsPrunedToKey = []byte("ArkivReceiptsPrunedTo")
+
 	// SnapshotRootKey tracks the hash of the last snapshot.
 	SnapshotRootKey = []byte("SnapshotRoot")
 
@@ -145,6 +156,9 @@ var (
 	configPrefix   = []byte("ethereum-config-")  // config prefix for the db
 	genesisPrefix  = []byte("ethereum-genesis-") // genesis state prefix for the db
 
+	arkivGenesisManifestPrefix = []byte("arkiv-genesis-manifest-") // arkivGenesisManifestPrefix + hash -> Arkiv genesis manifest
+	arkivPrunedReceiptsPrefix  = []byte("arkiv-pruned-receipts-")  // arkivPrunedReceiptsPrefix + num (uint64 big endian) + hash -> pruned receipts marker
+
 	CliqueSnapshotPrefix = []byte("clique-")
 
 	BestUpdateKey         = []byte("update-")    // bigEndian64(syncPeriod) -> RLP(types.LightClientUpdate)  (nextCommittee only referenced by root hash)
@@ -272,6 +286,16 @@ func genesisStateSpecKey(hash common.Hash) []byte {
 	return append(genesisPrefix, hash.Bytes()...)
 }
 
+// arkivGenesisManifestKey = arkivGenesisManifestPrefix + hash
+func arkivGenesisManifestKey(hash common.Hash) []byte {
+	return append(arkivGenesisManifestPrefix, hash.Bytes()...)
+}
+
+// arkivPrunedReceiptsKey = arkivPrunedReceiptsPrefix + num (uint64 big endian) + hash
+func arkivPrunedReceiptsKey(number uint64, hash common.Hash) []byte {
+	return append(append(arkivPrunedReceiptsPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
+}
+
 // stateIDKey = stateIDPrefix + root (32 bytes)
 func stateIDKey(root common.Hash) []byte {
 	return append(stateIDPrefix, root.Bytes()...)
@@ -160,6 +160,7 @@ func ApplyTransactionWithEVM(msg *Message, gp *GasPool, statedb *state.StateDB,
 
 			logs, err := storagetx.ExecuteArkivTransaction(
 				tx.Data(),
+				storagetx.RulesAt(evm.ChainConfig(), blockTime),
 				blockNumber.Uint64(),
 				blockHash,
 				txIx,
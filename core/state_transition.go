@@ -18,13 +18,16 @@ package core
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"math"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/arkiv/address"
 	"github.com/ethereum/go-ethereum/arkiv/housekeepingtx"
+	"github.com/ethereum/go-ethereum/arkiv/shadow"
 	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/tracing"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -636,9 +639,15 @@ func (st *stateTransition) innerExecute() (*ExecutionResult, error) {
 			var logs []*types.Log
 			snapshot := st.evm.StateDB.Snapshot()
 			// run the arkiv transaction
-			logs, vmerr = storagetx.ExecuteArkivTransaction(st.msg.Data, st.msg.BlockNumber, st.msg.TransactionHash, st.txIndex, msg.From, st.evm.StateDB)
+			logs, vmerr = st.executeArkivTransaction()
 			if vmerr != nil {
 				st.evm.StateDB.RevertToSnapshot(snapshot)
+				// an operation on an entity that doesn't exist or setting an expiry past
+				// the maximum BTL reverts with the error as reason, so that callers can
+				// tell it from the other failures
+				if errors.Is(vmerr, entity.ErrEntityNotFound) || errors.Is(vmerr, entity.ErrBTLTooLarge) {
+					ret, vmerr = storagetx.RevertReason(vmerr), vm.ErrExecutionReverted
+				}
 			} else {
 				// add logs of the arkiv transaction
 				for _, log := range logs {
@@ -647,14 +656,12 @@ func (st *stateTransition) innerExecute() (*ExecutionResult, error) {
 			}
 		case msg.IsDepositTx:
 
-			logs, err := housekeepingtx.ExecuteTransaction(st.msg.BlockNumber, st.msg.TransactionHash, st.evm.StateDB)
-			if err != nil {
-				return nil, fmt.Errorf("failed to execute housekeeping transaction: %w", err)
-			}
-
-			// add logs of the houskeeping transaction
-			for _, log := range logs {
-				st.evm.StateDB.AddLog(log)
+			housekeeping := housekeepingtx.RulesAt(st.evm.ChainConfig(), st.evm.Context.Time)
+			if housekeepingtx.RunsIn(msg.From, st.to(), housekeeping.Ordered) {
+				err := housekeepingtx.ExecuteTransaction(st.msg.BlockNumber, st.msg.TransactionHash, housekeeping, st.evm.StateDB, st.evm.StateDB)
+				if err != nil {
+					return nil, fmt.Errorf("failed to execute housekeeping transaction: %w", err)
+				}
 			}
 
 			// Execute the transaction's call.
@@ -886,3 +893,33 @@ func (st *stateTransition) gasUsed() uint64 {
 func (st *stateTransition) blobGasUsed() uint64 {
 	return uint64(len(st.msg.BlobHashes) * params.BlobTxBlobGasPerBlob)
 }
+
+// executeArkivTransaction checks the rules of the active Arkiv forks, charges the Arkiv
+// gas schedule on top of the intrinsic gas and runs the arkiv transaction carried in the
+// message data.
+func (st *stateTransition) executeArkivTransaction() ([]*types.Log, error) {
+	config := st.evm.ChainConfig()
+	rules := storagetx.RulesAt(config, st.evm.Context.Time)
+	tx, err := storagetx.UnpackArkivTransactionWithLimits(st.msg.Data, rules.Unpack)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unpack arkiv transaction: %w", err)
+	}
+
+	// the decoding caps the annotation values from the gas schedule fork, before it the
+	// same check only measures the transactions the cap will reject
+	err = shadow.Enforce(st.evm.Config.ArkivShadow, config, params.ArkivFeatureGasSchedule, st.evm.Context.Time, st.msg.BlockNumber, st.msg.TransactionHash, func() error {
+		return tx.ValidateAnnotationValueSizes(config.ArkivAnnotationValueCap())
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to unpack arkiv transaction: %w", err)
+	}
+
+	arkivGas := tx.Gas(rules.Gas)
+	if st.gasRemaining < arkivGas {
+		st.gasRemaining = 0
+		return nil, vm.ErrOutOfGas
+	}
+	st.gasRemaining -= arkivGas
+
+	return tx.Execute(st.msg.BlockNumber, st.msg.TransactionHash, st.txIndex, st.msg.From, rules, st.evm.StateDB)
+}
@@ -30,7 +30,6 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/arkiv/address"
-	"github.com/ethereum/go-ethereum/arkiv/compression"
 	"github.com/ethereum/go-ethereum/arkiv/storagetx"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/prque"
@@ -82,6 +81,11 @@ var (
 
 	// ErrNonGolembaseTransaction is returned if a transaction is not a Golembase transaction.
 	ErrNonGolembaseTransaction = errors.New("non-golembase transaction")
+
+	// ErrArkivTxPoolOverflow is returned if Arkiv transactions already occupy their
+	// configured share of the pool and the transaction doesn't replace one of them
+	// with at most as many slots.
+	ErrArkivTxPoolOverflow = errors.New("arkiv transactions exceed their share of the txpool")
 )
 
 var (
@@ -123,6 +127,10 @@ var (
 	queuedGauge  = metrics.NewRegisteredGauge("txpool/queued", nil)
 	slotsGauge   = metrics.NewRegisteredGauge("txpool/slots", nil)
 
+	// Metrics for the transactions sent to the Arkiv processor
+	arkivSlotsGauge        = metrics.NewRegisteredGauge("txpool/arkiv/slots", nil)
+	arkivOverflowedTxMeter = metrics.NewRegisteredMeter("txpool/arkiv/overflowed", nil)
+
 	reheapTimer = metrics.NewRegisteredTimer("txpool/reheap", nil)
 )
 
@@ -171,6 +179,10 @@ type Config struct {
 	FilterInterval time.Duration
 
 	DisableNonGolemBaseTransactions bool // Disallow non-Golembase transactions such as transfers to non-Golembase accounts and contract creations
+
+	ArkivSlotsFraction float64 // Maximum fraction of the pool slots that can be occupied by Arkiv transactions (0 = no cap)
+
+	ArkivEncryptionSchemes []string // Encryption schemes accepted in Arkiv transactions (empty = any scheme)
 }
 
 // DefaultConfig contains the default configurations for the transaction pool.
@@ -188,6 +200,9 @@ var DefaultConfig = Config{
 
 	MaxTxGasLimit: 0, // 0 means no limit (default behavior)
 
+	ArkivSlotsFraction:     0,
+	ArkivEncryptionSchemes: storagetx.DefaultEncryptionSchemes,
+
 	Lifetime:       3 * time.Hour,
 	FilterInterval: 12 * time.Second,
 }
@@ -224,6 +239,10 @@ func (config *Config) sanitize() Config {
 		log.Warn("Sanitizing invalid txpool lifetime", "provided", conf.Lifetime, "updated", DefaultConfig.Lifetime)
 		conf.Lifetime = DefaultConfig.Lifetime
 	}
+	if conf.ArkivSlotsFraction < 0 || conf.ArkivSlotsFraction > 1 {
+		log.Warn("Sanitizing invalid txpool arkiv slots fraction", "provided", conf.ArkivSlotsFraction, "updated", DefaultConfig.ArkivSlotsFraction)
+		conf.ArkivSlotsFraction = DefaultConfig.ArkivSlotsFraction
+	}
 	if conf.FilterInterval <= 0 {
 		log.Warn("Sanitizing invalid txpool filter interval", "provided", conf.FilterInterval, "updated", DefaultConfig.FilterInterval)
 		conf.FilterInterval = DefaultConfig.FilterInterval
@@ -633,6 +652,30 @@ func (pool *LegacyPool) Pending(filter txpool.PendingFilter) map[common.Address]
 	return pending
 }
 
+// isArkivTransaction reports whether the transaction is sent to the Arkiv processor.
+func isArkivTransaction(tx *types.Transaction) bool {
+	to := tx.To()
+	return to != nil && *to == address.ArkivProcessorAddress
+}
+
+// arkivReplacedSlots returns the slots of the pooled Arkiv transaction with the same
+// sender and nonce, which the transaction would replace, 0 if there is none.
+func (pool *LegacyPool) arkivReplacedSlots(from common.Address, tx *types.Transaction) int {
+	var old *types.Transaction
+	if list := pool.pending[from]; list != nil {
+		old = list.txs.Get(tx.Nonce())
+	}
+	if old == nil {
+		if list, ok := pool.queue.get(from); ok {
+			old = list.txs.Get(tx.Nonce())
+		}
+	}
+	if old == nil || !isArkivTransaction(old) {
+		return 0
+	}
+	return numSlots(old)
+}
+
 // ValidateTxBasics checks whether a transaction is valid according to the consensus
 // rules, but does not check state-dependent validation such as sufficient balance.
 // This check is meant as an early check which only needs to be performed once,
@@ -659,21 +702,40 @@ func (pool *LegacyPool) ValidateTxBasics(tx *types.Transaction) error {
 			return fmt.Errorf("arkiv transaction data is empty")
 		}
 
-		_, err := compression.BrotliDecompress(tx.Data())
+		// Decode with the limits at the head. Before the payload limits fork the
+		// chain truncates the calldata, the pool rejects it instead of decompressing it
+		// all.
+		head := pool.currentHead.Load()
+		unpackLimits := storagetx.UnpackLimitsAt(pool.chainconfig, head.Time)
+		if unpackLimits.MaxDecompressedSize == 0 {
+			unpackLimits.MaxDecompressedSize = params.DefaultArkivMaxDecompressedSize
+		}
+		atx, err := storagetx.UnpackArkivTransactionWithLimits(tx.Data(), unpackLimits)
 		if err != nil {
-			return fmt.Errorf("failed to decompress arkiv transaction data: %w", err)
+			return fmt.Errorf("failed to unpack arkiv transaction: %w", err)
 		}
 
-		tx, err := storagetx.UnpackArkivTransaction(tx.Data())
+		err = atx.Validate()
 		if err != nil {
-			return fmt.Errorf("failed to unpack arkiv transaction: %w", err)
+			return fmt.Errorf("failed to validate arkiv transaction: %w", err)
 		}
 
-		err = tx.Validate()
+		err = atx.ValidateEncryptionSchemes(pool.config.ArkivEncryptionSchemes)
 		if err != nil {
 			return fmt.Errorf("failed to validate arkiv transaction: %w", err)
 		}
 
+		// Ensure the transaction covers the intrinsic gas and the Arkiv gas schedule,
+		// the same way it is charged during execution
+		rules := pool.chainconfig.Rules(head.Number, head.Difficulty.Sign() == 0, head.Time)
+		intrGas, err := core.IntrinsicGas(tx.Data(), tx.AccessList(), tx.SetCodeAuthorizations(), false, true, rules.IsIstanbul, rules.IsShanghai)
+		if err != nil {
+			return err
+		}
+		if needed := intrGas + atx.Gas(storagetx.GasScheduleAt(pool.chainconfig, head.Time)); tx.Gas() < needed {
+			return fmt.Errorf("%w: gas %v, minimum needed %v", core.ErrIntrinsicGas, tx.Gas(), needed)
+		}
+
 		return nil
 
 	}
@@ -793,7 +855,9 @@ func (pool *LegacyPool) validateAuth(tx *types.Transaction) error {
 // add validates a transaction and inserts it into the non-executable queue for later
 // pending promotion and execution. If the transaction is a replacement for an already
 // pending or queued one, it overwrites the previous transaction if its price is higher.
-func (pool *LegacyPool) add(tx *types.Transaction) (replaced bool, err error) {
+// A reinjected transaction, dropped from the chain by a reorg, isn't subject to the
+// share of the pool of the Arkiv transactions.
+func (pool *LegacyPool) add(tx *types.Transaction, reinject bool) (replaced bool, err error) {
 	// If the transaction is already known, discard it
 	hash := tx.Hash()
 	if pool.all.Get(hash) != nil {
@@ -833,6 +897,17 @@ func (pool *LegacyPool) add(tx *types.Transaction) (replaced bool, err error) {
 			}
 		}()
 	}
+	// If Arkiv transactions already occupy their share of the pool, only allow
+	// them to replace each other without growing it
+	if isArkivTransaction(tx) && pool.config.ArkivSlotsFraction > 0 && !reinject {
+		limit := int(float64(pool.config.GlobalSlots+pool.config.GlobalQueue) * pool.config.ArkivSlotsFraction)
+		slots, replacedSlots := numSlots(tx), pool.arkivReplacedSlots(from, tx)
+		if slots > replacedSlots && pool.all.ArkivSlots()-replacedSlots+slots > limit {
+			log.Trace("Discarding overflown arkiv transaction", "hash", hash, "slots", pool.all.ArkivSlots(), "limit", limit)
+			arkivOverflowedTxMeter.Mark(1)
+			return false, ErrArkivTxPoolOverflow
+		}
+	}
 	// If the transaction pool is full, discard underpriced transactions
 	if uint64(pool.all.Slots()+numSlots(tx)) > pool.config.GlobalSlots+pool.config.GlobalQueue {
 		// If the new transaction is underpriced, don't accept it
@@ -1070,7 +1145,7 @@ func (pool *LegacyPool) Add(txs []*types.Transaction, sync bool) []error {
 
 	// Process all the new transaction and merge any errors into the original slice
 	pool.mu.Lock()
-	newErrs, dirtyAddrs := pool.addTxsLocked(news)
+	newErrs, dirtyAddrs := pool.addTxsLocked(news, false)
 	pool.mu.Unlock()
 
 	nilSlot := 0
@@ -1089,10 +1164,11 @@ func (pool *LegacyPool) Add(txs []*types.Transaction, sync bool) []error {
 	return errs
 }
 
-// addTxsLocked attempts to queue a batch of transactions if they are valid.
+// addTxsLocked attempts to queue a batch of transactions if they are valid, reinject
+// is set for the transactions dropped from the chain by a reorg.
 // The transaction pool lock must be held.
 // Returns the error for each tx, and the set of accounts that might became promotable.
-func (pool *LegacyPool) addTxsLocked(txs []*types.Transaction) ([]error, *accountSet) {
+func (pool *LegacyPool) addTxsLocked(txs []*types.Transaction, reinject bool) ([]error, *accountSet) {
 	var (
 		dirty = newAccountSet(pool.signer)
 		errs  = make([]error, len(txs))
@@ -1113,7 +1189,7 @@ func (pool *LegacyPool) addTxsLocked(txs []*types.Transaction) ([]error, *accoun
 		if filtered {
 			continue
 		}
-		replaced, err := pool.add(tx)
+		replaced, err := pool.add(tx, reinject)
 		errs[i] = err
 		if err == nil {
 			if !replaced {
@@ -1547,7 +1623,7 @@ func (pool *LegacyPool) reset(oldHead, newHead *types.Header) {
 	// Inject any transactions discarded due to reorgs
 	log.Debug("Reinjecting stale transactions", "count", len(reinject))
 	core.SenderCacher().Recover(pool.signer, reinject)
-	pool.addTxsLocked(reinject)
+	pool.addTxsLocked(reinject, true)
 }
 
 func (pool *LegacyPool) resetRollupCostFn(ts uint64, statedb *state.StateDB) {
@@ -1820,9 +1896,10 @@ func (as *accountSet) merge(other *accountSet) {
 // peeking into the pool in LegacyPool.Get without having to acquire the widely scoped
 // LegacyPool.mu mutex.
 type lookup struct {
-	slots int
-	lock  sync.RWMutex
-	txs   map[common.Hash]*types.Transaction
+	slots      int
+	arkivSlots int // Slots used by transactions sent to the Arkiv processor
+	lock       sync.RWMutex
+	txs        map[common.Hash]*types.Transaction
 
 	auths map[common.Address][]common.Hash // All accounts with a pooled authorization
 }
@@ -1873,6 +1950,14 @@ func (t *lookup) Slots() int {
 	return t.slots
 }
 
+// ArkivSlots returns the current number of slots used by Arkiv transactions in the lookup.
+func (t *lookup) ArkivSlots() int {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return t.arkivSlots
+}
+
 // Add adds a transaction to the lookup.
 func (t *lookup) Add(tx *types.Transaction) {
 	t.lock.Lock()
@@ -1881,6 +1966,11 @@ func (t *lookup) Add(tx *types.Transaction) {
 	t.slots += numSlots(tx)
 	slotsGauge.Update(int64(t.slots))
 
+	if isArkivTransaction(tx) {
+		t.arkivSlots += numSlots(tx)
+		arkivSlotsGauge.Update(int64(t.arkivSlots))
+	}
+
 	t.txs[tx.Hash()] = tx
 	t.addAuthorities(tx)
 }
@@ -1899,6 +1989,11 @@ func (t *lookup) Remove(hash common.Hash) {
 	t.slots -= numSlots(tx)
 	slotsGauge.Update(int64(t.slots))
 
+	if isArkivTransaction(tx) {
+		t.arkivSlots -= numSlots(tx)
+		arkivSlotsGauge.Update(int64(t.arkivSlots))
+	}
+
 	delete(t.txs, hash)
 }
 
@@ -1908,6 +2003,7 @@ func (t *lookup) Clear() {
 	defer t.lock.Unlock()
 
 	t.slots = 0
+	t.arkivSlots = 0
 	t.txs = make(map[common.Hash]*types.Transaction)
 	t.auths = make(map[common.Address][]common.Hash)
 }
@@ -25,11 +25,15 @@ import (
 	"math/big"
 	"math/rand"
 	"slices"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/ethereum/go-ethereum/arkiv/address"
+	"github.com/ethereum/go-ethereum/arkiv/compression"
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/state"
@@ -39,7 +43,9 @@ import (
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/trie"
 	"github.com/holiman/uint256"
 )
@@ -577,14 +583,14 @@ func TestChainFork(t *testing.T) {
 	resetState()
 
 	tx := transaction(0, 100000, key)
-	if _, err := pool.add(tx); err != nil {
+	if _, err := pool.add(tx, false); err != nil {
 		t.Error("didn't expect error", err)
 	}
 	pool.removeTx(tx.Hash(), true, true)
 
 	// reset the pool's internal state
 	resetState()
-	if _, err := pool.add(tx); err != nil {
+	if _, err := pool.add(tx, false); err != nil {
 		t.Error("didn't expect error", err)
 	}
 }
@@ -611,10 +617,10 @@ func TestDoubleNonce(t *testing.T) {
 	tx3, _ := types.SignTx(types.NewTransaction(0, common.Address{}, big.NewInt(100), 1000000, big.NewInt(1), nil), signer, key)
 
 	// Add the first two transaction, ensure higher priced stays only
-	if replace, err := pool.add(tx1); err != nil || replace {
+	if replace, err := pool.add(tx1, false); err != nil || replace {
 		t.Errorf("first transaction insert failed (%v) or reported replacement (%v)", err, replace)
 	}
-	if replace, err := pool.add(tx2); err != nil || !replace {
+	if replace, err := pool.add(tx2, false); err != nil || !replace {
 		t.Errorf("second transaction insert failed (%v) or not reported replacement (%v)", err, replace)
 	}
 	<-pool.requestPromoteExecutables(newAccountSet(signer, addr))
@@ -626,7 +632,7 @@ func TestDoubleNonce(t *testing.T) {
 	}
 
 	// Add the third transaction and ensure it's not saved (smaller price)
-	pool.add(tx3)
+	pool.add(tx3, false)
 	<-pool.requestPromoteExecutables(newAccountSet(signer, addr))
 	if pool.pending[addr].Len() != 1 {
 		t.Error("expected 1 pending transactions, got", pool.pending[addr].Len())
@@ -649,7 +655,7 @@ func TestMissingNonce(t *testing.T) {
 	addr := crypto.PubkeyToAddress(key.PublicKey)
 	testAddBalance(pool, addr, big.NewInt(100000000000000))
 	tx := transaction(1, 100000, key)
-	if _, err := pool.add(tx); err != nil {
+	if _, err := pool.add(tx, false); err != nil {
 		t.Error("didn't expect error", err)
 	}
 	if len(pool.pending) != 0 {
@@ -2848,3 +2854,231 @@ func TestTxPoolMaxTxGasLimitDisabled(t *testing.T) {
 		t.Errorf("Expected transaction to be accepted when MaxTxGasLimit is disabled, got %v", err)
 	}
 }
+
+func arkivTransaction(nonce uint64, gasprice *big.Int, key *ecdsa.PrivateKey) *types.Transaction {
+	return encodeArkivTransaction(nonce, gasprice, key, &storagetx.ArkivTransaction{
+		Create: []storagetx.ArkivCreate{
+			{
+				BTL:         100,
+				ContentType: "text/plain",
+				Payload:     []byte("payload"),
+			},
+		},
+	})
+}
+
+func encodeArkivTransaction(nonce uint64, gasprice *big.Int, key *ecdsa.PrivateKey, atx *storagetx.ArkivTransaction) *types.Transaction {
+	data, _ := rlp.EncodeToBytes(atx)
+	tx, _ := types.SignTx(types.NewTransaction(nonce, address.ArkivProcessorAddress, big.NewInt(0), 1000000, gasprice, compression.MustBrotliCompress(data)), types.HomesteadSigner{}, key)
+	return tx
+}
+
+// Tests that Arkiv transactions can't occupy more than their configured share of
+// the pool, while ordinary transactions and replacements are still admitted.
+func TestArkivSlotsLimiting(t *testing.T) {
+	t.Parallel()
+
+	config := testTxPoolConfig
+	config.GlobalSlots = 4
+	config.GlobalQueue = 4
+	config.ArkivSlotsFraction = 0.5
+
+	pool, _ := setupPoolWithTxPoolConfig(params.TestChainConfig, config)
+	defer pool.Close()
+
+	keys := make([]*ecdsa.PrivateKey, 6)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		testAddBalance(pool, crypto.PubkeyToAddress(keys[i].PublicKey), big.NewInt(1000000000))
+	}
+	// Fill the Arkiv share of the pool
+	for i := 0; i < 4; i++ {
+		if err := pool.addRemoteSync(arkivTransaction(0, big.NewInt(1), keys[i])); err != nil {
+			t.Fatalf("failed to add arkiv transaction %d: %v", i, err)
+		}
+	}
+	if slots := pool.all.ArkivSlots(); slots != 4 {
+		t.Fatalf("arkiv slots mismatch: have %d, want %d", slots, 4)
+	}
+	// Further Arkiv transactions are rejected
+	overflowed := arkivOverflowedTxMeter.Snapshot().Count()
+	if err := pool.addRemoteSync(arkivTransaction(0, big.NewInt(1), keys[4])); !errors.Is(err, ErrArkivTxPoolOverflow) {
+		t.Fatalf("adding arkiv transaction over the cap error mismatch: have %v, want %v", err, ErrArkivTxPoolOverflow)
+	}
+	if count := arkivOverflowedTxMeter.Snapshot().Count(); metrics.Enabled() && count != overflowed+1 {
+		t.Fatalf("overflowed meter mismatch: have %d, want %d", count, overflowed+1)
+	}
+	// Replacing an existing Arkiv transaction is still allowed
+	if err := pool.addRemoteSync(arkivTransaction(0, big.NewInt(2), keys[0])); err != nil {
+		t.Fatalf("failed to replace arkiv transaction: %v", err)
+	}
+	// Ordinary transactions are still admitted
+	if err := pool.addRemoteSync(pricedTransaction(0, 100000, big.NewInt(1), keys[5])); err != nil {
+		t.Fatalf("failed to add ordinary transaction: %v", err)
+	}
+	pending, queued := pool.Stats()
+	if pending != 5 {
+		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 5)
+	}
+	if queued != 0 {
+		t.Fatalf("queued transactions mismatched: have %d, want %d", queued, 0)
+	}
+	if slots := pool.all.ArkivSlots(); slots != 4 {
+		t.Fatalf("arkiv slots mismatch: have %d, want %d", slots, 4)
+	}
+	if err := validatePoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that an Arkiv transaction over the share of the pool only replaces another
+// one without growing the share, and that the transactions reinjected after a reorg
+// aren't capped.
+func TestArkivSlotsLimitingReplacements(t *testing.T) {
+	t.Parallel()
+
+	config := testTxPoolConfig
+	config.GlobalSlots = 4
+	config.GlobalQueue = 4
+	config.ArkivSlotsFraction = 0.5
+
+	pool, _ := setupPoolWithTxPoolConfig(params.TestChainConfig, config)
+	defer pool.Close()
+
+	keys := make([]*ecdsa.PrivateKey, 5)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		testAddBalance(pool, crypto.PubkeyToAddress(keys[i].PublicKey), big.NewInt(1000000000))
+	}
+	// Random bytes don't compress, the transaction takes two slots
+	payload := make([]byte, txSlotSize)
+	crand.Read(payload)
+	large := func(nonce uint64, gasprice *big.Int, key *ecdsa.PrivateKey) *types.Transaction {
+		return encodeArkivTransaction(nonce, gasprice, key, &storagetx.ArkivTransaction{
+			Create: []storagetx.ArkivCreate{{BTL: 100, ContentType: "application/octet-stream", Payload: payload}},
+		})
+	}
+	if slots := numSlots(large(0, big.NewInt(1), keys[0])); slots != 2 {
+		t.Fatalf("large arkiv transaction slots mismatch: have %d, want %d", slots, 2)
+	}
+	for i := 0; i < 3; i++ {
+		if err := pool.addRemoteSync(arkivTransaction(0, big.NewInt(1), keys[i])); err != nil {
+			t.Fatalf("failed to add arkiv transaction %d: %v", i, err)
+		}
+	}
+	// A replacement can grow as long as the share holds
+	if err := pool.addRemoteSync(large(0, big.NewInt(2), keys[0])); err != nil {
+		t.Fatalf("failed to grow arkiv transaction within the share: %v", err)
+	}
+	if slots := pool.all.ArkivSlots(); slots != 4 {
+		t.Fatalf("arkiv slots mismatch: have %d, want %d", slots, 4)
+	}
+	// Once the share is used up it can't grow, but it can keep or shrink its slots
+	if err := pool.addRemoteSync(large(0, big.NewInt(2), keys[1])); !errors.Is(err, ErrArkivTxPoolOverflow) {
+		t.Fatalf("growing arkiv transaction over the share error mismatch: have %v, want %v", err, ErrArkivTxPoolOverflow)
+	}
+	if err := pool.addRemoteSync(large(0, big.NewInt(3), keys[0])); err != nil {
+		t.Fatalf("failed to replace arkiv transaction with as many slots: %v", err)
+	}
+	if err := pool.addRemoteSync(arkivTransaction(0, big.NewInt(4), keys[0])); err != nil {
+		t.Fatalf("failed to shrink arkiv transaction: %v", err)
+	}
+	if err := pool.addRemoteSync(arkivTransaction(0, big.NewInt(1), keys[3])); err != nil {
+		t.Fatalf("failed to add arkiv transaction: %v", err)
+	}
+	// The transactions reinjected after a reorg aren't capped
+	pool.mu.Lock()
+	_, err := pool.add(arkivTransaction(0, big.NewInt(1), keys[4]), false)
+	if !errors.Is(err, ErrArkivTxPoolOverflow) {
+		t.Errorf("adding arkiv transaction over the share error mismatch: have %v, want %v", err, ErrArkivTxPoolOverflow)
+	}
+	if _, err := pool.add(arkivTransaction(0, big.NewInt(1), keys[4]), true); err != nil {
+		t.Errorf("failed to reinject arkiv transaction: %v", err)
+	}
+	pool.mu.Unlock()
+
+	if slots := pool.all.ArkivSlots(); slots != 5 {
+		t.Fatalf("arkiv slots mismatch: have %d, want %d", slots, 5)
+	}
+	if err := validatePoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that Arkiv transactions carrying encryption info are only admitted if their
+// encryption scheme is in the configured allowlist.
+func TestArkivEncryptionSchemes(t *testing.T) {
+	t.Parallel()
+
+	config := testTxPoolConfig
+	config.ArkivEncryptionSchemes = []string{"aes-256-gcm"}
+
+	chainConfig := *params.TestChainConfig
+	chainConfig.ArkivEncryptionTime = new(uint64)
+
+	pool, _ := setupPoolWithTxPoolConfig(&chainConfig, config)
+	defer pool.Close()
+
+	key, _ := crypto.GenerateKey()
+	testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+
+	encrypted := func(nonce uint64, scheme string) *types.Transaction {
+		return encodeArkivTransaction(nonce, big.NewInt(1), key, &storagetx.ArkivTransaction{
+			Create: []storagetx.ArkivCreate{
+				{
+					BTL:         100,
+					ContentType: "application/octet-stream",
+					Payload:     []byte("ciphertext"),
+					Encryption: &storagetx.EncryptionInfo{
+						Scheme: scheme,
+						KeyID:  "key-1",
+						Nonce:  []byte{1, 2, 3},
+					},
+				},
+			},
+			Version: storagetx.TransactionVersionEncryption,
+		})
+	}
+
+	if err := pool.addRemoteSync(encrypted(0, "rot13")); err == nil || !strings.Contains(err.Error(), `encryption scheme "rot13" is not allowed`) {
+		t.Fatalf("adding transaction with unknown encryption scheme error mismatch: have %v", err)
+	}
+	if err := pool.addRemoteSync(encrypted(0, "aes-256-gcm")); err != nil {
+		t.Fatalf("failed to add transaction with allowed encryption scheme: %v", err)
+	}
+}
+
+// Tests that Arkiv transactions with invalid annotation keys are rejected on
+// submission instead of failing at execution.
+func TestArkivAnnotationKeys(t *testing.T) {
+	t.Parallel()
+
+	pool, _ := setupPoolWithTxPoolConfig(params.TestChainConfig, testTxPoolConfig)
+	defer pool.Close()
+
+	key, _ := crypto.GenerateKey()
+	testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+
+	annotated := func(annotationKey string) *types.Transaction {
+		return encodeArkivTransaction(0, big.NewInt(1), key, &storagetx.ArkivTransaction{
+			Create: []storagetx.ArkivCreate{
+				{
+					BTL:               100,
+					ContentType:       "text/plain",
+					Payload:           []byte("payload"),
+					StringAnnotations: []storagetx.StringAnnotation{{Key: annotationKey, Value: "v"}},
+				},
+			},
+		})
+	}
+
+	for _, annotationKey := range []string{"$owner", "0xab", ""} {
+		want := fmt.Sprintf("create[0] string annotation key %q", annotationKey)
+		if err := pool.addRemoteSync(annotated(annotationKey)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("adding transaction with annotation key %q error mismatch: have %v, want %s", annotationKey, err, want)
+		}
+	}
+	if err := pool.addRemoteSync(annotated("region")); err != nil {
+		t.Fatalf("failed to add transaction with valid annotation key: %v", err)
+	}
+}
@@ -20,14 +20,17 @@ func (rs Receipts) deriveOPStackFields(config *params.ChainConfig, blockTime uin
 		return fmt.Errorf("failed to extract L1 gas params: %w", err)
 	}
 
-	var daFootprintGasScalar uint64
+	var (
+		daFootprintGasScalar        uint16
+		receiptDAFootprintGasScalar uint64
+	)
 	isJovian := config.IsJovian(blockTime)
 	if isJovian {
-		scalar, err := ExtractDAFootprintGasScalar(l1AttributesData)
+		daFootprintGasScalar, err = ExtractDAFootprintGasScalar(l1AttributesData)
 		if err != nil {
 			return fmt.Errorf("failed to extract DA footprint gas scalar: %w", err)
 		}
-		daFootprintGasScalar = uint64(scalar)
+		receiptDAFootprintGasScalar = uint64(daFootprintGasScalar)
 	}
 
 	for i := range rs {
@@ -46,8 +49,8 @@ func (rs Receipts) deriveOPStackFields(config *params.ChainConfig, blockTime uin
 			rs[i].OperatorFeeConstant = gasParams.operatorFeeConstant
 		}
 		if isJovian {
-			rs[i].DAFootprintGasScalar = &daFootprintGasScalar
-			rs[i].BlobGasUsed = daFootprintGasScalar * rcd.EstimatedDASize().Uint64()
+			rs[i].DAFootprintGasScalar = &receiptDAFootprintGasScalar
+			rs[i].BlobGasUsed = DAFootprintGas(txs[i], daFootprintGasScalar)
 		}
 	}
 	return nil
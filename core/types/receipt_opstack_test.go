@@ -214,7 +214,7 @@ func getOptimismJovianTxReceipts(l1AttributesPayload []byte, l1GasPrice, l1BlobB
 	txs, receipts := getOptimismIsthmusTxReceipts(l1AttributesPayload, l1GasPrice, l1BlobBaseFee, l1GasUsed, l1Fee, baseFeeScalar, blobBaseFeeScalar, operatorFeeScalar, operatorFeeConstant)
 	receipts[1].DAFootprintGasScalar = daFootprintGasScalar
 	if daFootprintGasScalar != nil {
-		receipts[1].BlobGasUsed = *daFootprintGasScalar * txs[1].RollupCostData().EstimatedDASize().Uint64()
+		receipts[1].BlobGasUsed = DAFootprintGas(txs[1], uint16(*daFootprintGasScalar))
 	}
 	return txs, receipts
 }
@@ -580,14 +580,52 @@ func CalcDAFootprint(txs []*Transaction) (uint64, error) {
 	if err != nil {
 		return 0, err
 	}
-	var daFootprint uint64
+	daFootprint := NewDAFootprintAccumulator(daFootprintGasScalar)
 	for _, tx := range txs {
-		if tx.IsDepositTx() {
-			continue
-		}
-		daFootprint += tx.RollupCostData().EstimatedDASize().Uint64() * uint64(daFootprintGasScalar)
+		daFootprint.Add(tx)
+	}
+	return daFootprint.Total(), nil
+}
+
+// DAFootprintGas returns the DA footprint of a transaction of a Jovian block with the DA
+// footprint gas scalar of the block. Deposit transactions have no DA footprint.
+func DAFootprintGas(tx *Transaction, daFootprintGasScalar uint16) uint64 {
+	if tx.IsDepositTx() {
+		return 0
 	}
-	return daFootprint, nil
+	return DAFootprintGasOfSize(tx.RollupCostData().EstimatedDASize().Uint64(), daFootprintGasScalar)
+}
+
+// DAFootprintGasOfSize returns the DA footprint of a non-deposit transaction with the
+// estimated DA size, for callers that already hold it, such as the txpool's lazy
+// transactions.
+func DAFootprintGasOfSize(estimatedDASize uint64, daFootprintGasScalar uint16) uint64 {
+	return estimatedDASize * uint64(daFootprintGasScalar)
+}
+
+// DAFootprintAccumulator sums the DA footprint of the transactions of a Jovian block,
+// the value of its BlobGasUsed header field.
+type DAFootprintAccumulator struct {
+	daFootprintGasScalar uint16
+	total                uint64
+}
+
+// NewDAFootprintAccumulator returns an accumulator for a block with the DA footprint gas
+// scalar.
+func NewDAFootprintAccumulator(daFootprintGasScalar uint16) *DAFootprintAccumulator {
+	return &DAFootprintAccumulator{daFootprintGasScalar: daFootprintGasScalar}
+}
+
+// Add adds the DA footprint of the transaction to the total and returns it.
+func (a *DAFootprintAccumulator) Add(tx *Transaction) uint64 {
+	footprint := DAFootprintGas(tx, a.daFootprintGasScalar)
+	a.total += footprint
+	return footprint
+}
+
+// Total returns the DA footprint of the transactions added.
+func (a *DAFootprintAccumulator) Total() uint64 {
+	return a.total
 }
 
 // L1Cost computes the the data availability fee for transactions in blocks prior to the Ecotone
@@ -25,12 +25,14 @@ import (
 	"math"
 	"math/big"
 	"math/bits"
+	"slices"
 
 	"github.com/consensys/gnark-crypto/ecc"
 	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
 	"github.com/consensys/gnark-crypto/ecc/bls12-381/fp"
 	"github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
 	patched_big "github.com/ethereum/go-bigmodexpfix/src/math/big"
+	arkivaddress "github.com/ethereum/go-ethereum/arkiv/address"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/bitutil"
 	"github.com/ethereum/go-ethereum/core/tracing"
@@ -302,6 +304,20 @@ func init() {
 }
 
 func activePrecompiledContracts(rules params.Rules) PrecompiledContracts {
+	contracts := forkPrecompiledContracts(rules)
+	if rules.IsArkivCapabilities || rules.IsArkivContentHashRead {
+		contracts = maps.Clone(contracts)
+	}
+	if rules.IsArkivCapabilities {
+		contracts[arkivaddress.ArkivCapabilitiesAddress] = &arkivCapabilities{features: rules.ArkivFeatures}
+	}
+	if rules.IsArkivContentHashRead {
+		contracts[arkivaddress.ArkivContentHashAddress] = &arkivContentHash{}
+	}
+	return contracts
+}
+
+func forkPrecompiledContracts(rules params.Rules) PrecompiledContracts {
 	// note: the order of these switch cases is important
 	switch {
 	case rules.IsOptimismJovian:
@@ -338,6 +354,17 @@ func ActivePrecompiledContracts(rules params.Rules) PrecompiledContracts {
 
 // ActivePrecompiles returns the precompile addresses enabled with the current configuration.
 func ActivePrecompiles(rules params.Rules) []common.Address {
+	addresses := forkPrecompiles(rules)
+	if rules.IsArkivCapabilities {
+		addresses = append(slices.Clone(addresses), arkivaddress.ArkivCapabilitiesAddress)
+	}
+	if rules.IsArkivContentHashRead {
+		addresses = append(slices.Clone(addresses), arkivaddress.ArkivContentHashAddress)
+	}
+	return addresses
+}
+
+func forkPrecompiles(rules params.Rules) []common.Address {
 	switch {
 	case rules.IsOptimismJovian:
 		return PrecompiledAddressesJovian
@@ -0,0 +1,106 @@
+package vm
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	// arkivSupportsFeatureSelector is the selector of supportsFeature(bytes4).
+	arkivSupportsFeatureSelector = []byte{0x58, 0x2d, 0xe3, 0xe7}
+
+	errArkivCapabilitiesInput = errors.New("invalid input, expected supportsFeature(bytes4)")
+
+	// arkivGetContentHashSelector is the selector of getContentHash(bytes32).
+	arkivGetContentHashSelector = []byte{0x51, 0x57, 0x7e, 0xa9}
+	// arkivEntityNotFoundSelector and arkivContentHashNotStoredSelector are the
+	// selectors of the EntityNotFound(bytes32) and ContentHashNotStored(bytes32) errors
+	// getContentHash reverts with.
+	arkivEntityNotFoundSelector       = []byte{0x66, 0xa8, 0x9f, 0x2a}
+	arkivContentHashNotStoredSelector = []byte{0xe1, 0xe1, 0xb4, 0x61}
+
+	errArkivContentHashInput = errors.New("invalid input, expected getContentHash(bytes32)")
+)
+
+// arkivCapabilities implements the Arkiv capabilities precompile. Its supportsFeature(bytes4 id)
+// returns whether the Arkiv feature is active at the time of the block, from the same
+// registry as the fork gating of the processor, see params.ArkivFeatures.
+type arkivCapabilities struct {
+	features params.ArkivFeatureSet
+}
+
+func (c *arkivCapabilities) RequiredGas(input []byte) uint64 {
+	return params.ArkivCapabilitiesGas
+}
+
+func (c *arkivCapabilities) Run(input []byte) ([]byte, error) {
+	// The call is the selector followed by the id, left aligned in a word
+	if len(input) != 4+32 || !bytes.Equal(input[:4], arkivSupportsFeatureSelector) {
+		return nil, errArkivCapabilitiesInput
+	}
+	var id params.ArkivFeature
+	copy(id[:], input[4:8])
+	if !bytes.Equal(input[8:36], make([]byte, 28)) {
+		return nil, errArkivCapabilitiesInput
+	}
+	if c.features.Supports(id) {
+		return common.LeftPadBytes([]byte{1}, 32), nil
+	}
+	return make([]byte, 32), nil
+}
+
+func (c *arkivCapabilities) Name() string {
+	return "ARKIV_CAPABILITIES"
+}
+
+// arkivStatePrecompile is a precompile reading the state of the EVM calling it. The
+// precompiles of the fork are shared, the EVM binds its state to its own copy, see
+// EVM.bindPrecompiles.
+type arkivStatePrecompile interface {
+	PrecompiledContract
+	withState(state StateDB) PrecompiledContract
+}
+
+// arkivContentHash implements the Arkiv content hash precompile. Its
+// getContentHash(bytes32 key) returns the content hash of the entity, the keccak256
+// hash of its payload, so contracts can check a payload supplied to them against the
+// entity. It reverts with EntityNotFound(bytes32) if the key doesn't hold an entity,
+// and with ContentHashNotStored(bytes32) if the entity was stored before the content
+// hash fork, without one.
+type arkivContentHash struct {
+	state StateDB
+}
+
+func (c *arkivContentHash) withState(state StateDB) PrecompiledContract {
+	return &arkivContentHash{state: state}
+}
+
+// RequiredGas charges the two cold storage reads of the metadata and the content hash
+// of the entity. A precompile reading the state for free could be called in a loop to
+// load any number of slots, so the read is priced like the SLOADs it replaces.
+func (c *arkivContentHash) RequiredGas(input []byte) uint64 {
+	return params.ArkivContentHashGas
+}
+
+func (c *arkivContentHash) Run(input []byte) ([]byte, error) {
+	if len(input) != 4+32 || !bytes.Equal(input[:4], arkivGetContentHashSelector) {
+		return nil, errArkivContentHashInput
+	}
+	key := common.BytesToHash(input[4:])
+	if !entity.Exists(c.state, key) {
+		return append(bytes.Clone(arkivEntityNotFoundSelector), key[:]...), ErrExecutionReverted
+	}
+	hash := entity.GetContentHash(c.state, key)
+	if hash == (common.Hash{}) {
+		return append(bytes.Clone(arkivContentHashNotStoredSelector), key[:]...), ErrExecutionReverted
+	}
+	return hash[:], nil
+}
+
+func (c *arkivContentHash) Name() string {
+	return "ARKIV_CONTENT_HASH"
+}
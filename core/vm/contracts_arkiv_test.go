@@ -0,0 +1,160 @@
+package vm
+
+import (
+	"math/big"
+	"slices"
+	"testing"
+
+	arkivaddress "github.com/ethereum/go-ethereum/arkiv/address"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+func supportsFeatureInput(id params.ArkivFeature) []byte {
+	return append(slices.Clone(arkivSupportsFeatureSelector), common.RightPadBytes(id[:], 32)...)
+}
+
+func TestArkivCapabilities(t *testing.T) {
+	capabilitiesTime, ownershipTime := uint64(100), uint64(200)
+	config := *params.TestChainConfig
+	config.ArkivCapabilitiesTime = &capabilitiesTime
+	config.ArkivOwnershipTime = &ownershipTime
+
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabaseForTesting())
+	newEVM := func(time uint64) *EVM {
+		return NewEVM(BlockContext{BlockNumber: big.NewInt(1), Time: time}, statedb, &config, Config{})
+	}
+	supportsFeature := func(t *testing.T, evm *EVM, id params.ArkivFeature) bool {
+		t.Helper()
+		ret, _, err := evm.StaticCall(common.Address{}, arkivaddress.ArkivCapabilitiesAddress, supportsFeatureInput(id), 10_000)
+		require.NoError(t, err)
+		require.Len(t, ret, 32)
+		return ret[31] == 1
+	}
+
+	t.Run("BeforeFork", func(t *testing.T) {
+		evm := newEVM(99)
+		_, ok := evm.precompile(arkivaddress.ArkivCapabilitiesAddress)
+		require.False(t, ok)
+		require.NotContains(t, ActivePrecompiles(evm.chainRules), arkivaddress.ArkivCapabilitiesAddress)
+	})
+
+	t.Run("AtActivation", func(t *testing.T) {
+		// The answers follow the time of the block, from the same registry as the
+		// fork gating of the processor
+		for _, time := range []uint64{100, 199, 200} {
+			evm := newEVM(time)
+			require.Contains(t, ActivePrecompiles(evm.chainRules), arkivaddress.ArkivCapabilitiesAddress)
+			for _, feature := range params.ArkivFeatures() {
+				require.Equal(t, config.IsArkivFeature(feature.ID, time), supportsFeature(t, evm, feature.ID), "%s at %d", feature.Name, time)
+			}
+			require.True(t, supportsFeature(t, evm, params.ArkivFeatureCapabilities))
+			require.Equal(t, config.IsArkivOwnership(time), supportsFeature(t, evm, params.ArkivFeatureTwoStepTransfer))
+		}
+		require.False(t, supportsFeature(t, newEVM(199), params.ArkivFeatureTwoStepTransfer))
+		require.True(t, supportsFeature(t, newEVM(200), params.ArkivFeatureTwoStepTransfer))
+		require.False(t, supportsFeature(t, newEVM(200), params.ArkivFeature{0xde, 0xad, 0xbe, 0xef}))
+	})
+
+	t.Run("InvalidInput", func(t *testing.T) {
+		p := &arkivCapabilities{features: config.ArkivFeaturesAt(200)}
+		input := supportsFeatureInput(params.ArkivFeatureTwoStepTransfer)
+
+		for _, invalid := range [][]byte{
+			nil,
+			input[:35],
+			append(slices.Clone(input), 0),
+			append([]byte{0x01, 0xff, 0xc9, 0xa7}, input[4:]...),
+			append(slices.Clone(input[:35]), 1),
+		} {
+			_, err := p.Run(invalid)
+			require.ErrorIs(t, err, errArkivCapabilitiesInput)
+		}
+		_, _, err := RunPrecompiledContract(p, input, params.ArkivCapabilitiesGas-1, nil)
+		require.ErrorIs(t, err, ErrOutOfGas)
+	})
+}
+
+func getContentHashInput(key common.Hash) []byte {
+	return append(slices.Clone(arkivGetContentHashSelector), key[:]...)
+}
+
+func TestArkivContentHash(t *testing.T) {
+	readTime := uint64(100)
+	config := *params.TestChainConfig
+	config.ArkivContentHashReadTime = &readTime
+
+	payload := []byte("payload")
+	hashed, unhashed, missing := common.Hash{1}, common.Hash{2}, common.Hash{3}
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabaseForTesting())
+	meta := entity.EntityMetaData{Owner: common.Address{1}, ExpiresAtBlock: 1000}
+	require.NoError(t, entity.Store(statedb, hashed, meta.Owner, meta, payload, true))
+	// Stored before the content hash fork
+	require.NoError(t, entity.Store(statedb, unhashed, meta.Owner, meta, payload, false))
+
+	newEVM := func(time uint64) *EVM {
+		return NewEVM(BlockContext{BlockNumber: big.NewInt(1), Time: time}, statedb, &config, Config{})
+	}
+
+	t.Run("BeforeFork", func(t *testing.T) {
+		evm := newEVM(99)
+		_, ok := evm.precompile(arkivaddress.ArkivContentHashAddress)
+		require.False(t, ok)
+		require.NotContains(t, ActivePrecompiles(evm.chainRules), arkivaddress.ArkivContentHashAddress)
+	})
+
+	t.Run("AtActivation", func(t *testing.T) {
+		evm := newEVM(100)
+		require.Contains(t, ActivePrecompiles(evm.chainRules), arkivaddress.ArkivContentHashAddress)
+
+		ret, left, err := evm.StaticCall(common.Address{}, arkivaddress.ArkivContentHashAddress, getContentHashInput(hashed), 10_000)
+		require.NoError(t, err)
+		require.Equal(t, entity.ContentHash(payload).Bytes(), ret)
+		require.Equal(t, 10_000-params.ArkivContentHashGas, left)
+	})
+
+	t.Run("Reverts", func(t *testing.T) {
+		// The reverts keep the gas left and tell the missing entities from the ones
+		// without a content hash
+		evm := newEVM(100)
+		ret, left, err := evm.StaticCall(common.Address{}, arkivaddress.ArkivContentHashAddress, getContentHashInput(unhashed), 10_000)
+		require.ErrorIs(t, err, ErrExecutionReverted)
+		require.Equal(t, append(slices.Clone(arkivContentHashNotStoredSelector), unhashed[:]...), ret)
+		require.Equal(t, 10_000-params.ArkivContentHashGas, left)
+
+		ret, left, err = evm.StaticCall(common.Address{}, arkivaddress.ArkivContentHashAddress, getContentHashInput(missing), 10_000)
+		require.ErrorIs(t, err, ErrExecutionReverted)
+		require.Equal(t, append(slices.Clone(arkivEntityNotFoundSelector), missing[:]...), ret)
+		require.Equal(t, 10_000-params.ArkivContentHashGas, left)
+	})
+
+	t.Run("SetPrecompiles", func(t *testing.T) {
+		// The precompiles set over RPC read the state of the EVM too
+		evm := newEVM(100)
+		evm.SetPrecompiles(ActivePrecompiledContracts(evm.chainRules))
+		ret, _, err := evm.StaticCall(common.Address{}, arkivaddress.ArkivContentHashAddress, getContentHashInput(hashed), 10_000)
+		require.NoError(t, err)
+		require.Equal(t, entity.ContentHash(payload).Bytes(), ret)
+	})
+
+	t.Run("InvalidInput", func(t *testing.T) {
+		p := &arkivContentHash{state: statedb}
+		input := getContentHashInput(hashed)
+
+		for _, invalid := range [][]byte{
+			nil,
+			input[:35],
+			append(slices.Clone(input), 0),
+			append(slices.Clone(arkivSupportsFeatureSelector), input[4:]...),
+		} {
+			_, err := p.Run(invalid)
+			require.ErrorIs(t, err, errArkivContentHashInput)
+		}
+		_, _, err := RunPrecompiledContract(p, input, params.ArkivContentHashGas-1, nil)
+		require.ErrorIs(t, err, ErrOutOfGas)
+	})
+}
@@ -18,6 +18,7 @@ package vm
 
 import (
 	"errors"
+	"maps"
 	"math/big"
 	"sync/atomic"
 
@@ -154,7 +155,7 @@ func NewEVM(blockCtx BlockContext, statedb StateDB, chainConfig *params.ChainCon
 		jumpDests:   newMapJumpDests(),
 		hasher:      crypto.NewKeccakState(),
 	}
-	evm.precompiles = activePrecompiledContracts(evm.chainRules)
+	evm.precompiles = evm.bindPrecompiles(activePrecompiledContracts(evm.chainRules))
 
 	switch {
 	case evm.chainRules.IsOsaka:
@@ -210,7 +211,25 @@ func NewEVM(blockCtx BlockContext, statedb StateDB, chainConfig *params.ChainCon
 // This method is only used through RPC calls.
 // It is not thread-safe.
 func (evm *EVM) SetPrecompiles(precompiles PrecompiledContracts) {
-	evm.precompiles = precompiles
+	evm.precompiles = evm.bindPrecompiles(precompiles)
+}
+
+// bindPrecompiles returns the precompiles with the ones reading the state bound to the
+// state of the EVM, a copy if there are any.
+func (evm *EVM) bindPrecompiles(precompiles PrecompiledContracts) PrecompiledContracts {
+	var bound PrecompiledContracts
+	for addr, p := range precompiles {
+		if p, ok := p.(arkivStatePrecompile); ok {
+			if bound == nil {
+				bound = maps.Clone(precompiles)
+			}
+			bound[addr] = p.withState(evm.StateDB)
+		}
+	}
+	if bound == nil {
+		return precompiles
+	}
+	return bound
 }
 
 // SetJumpDestCache configures the analysis cache.
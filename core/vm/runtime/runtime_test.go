@@ -17,6 +17,7 @@
 package runtime
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
 	"math/big"
@@ -26,6 +27,8 @@ import (
 	"testing"
 
 	"github.com/ethereum/go-ethereum/accounts/abi"
+	arkivaddress "github.com/ethereum/go-ethereum/arkiv/address"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/core"
@@ -944,3 +947,121 @@ func TestDelegatedAccountAccessCost(t *testing.T) {
 		}
 	}
 }
+
+// arkivVerifyBlobCode is a contract checking a blob against the content hash of an
+// entity. It's called with the key of the entity followed by the blob, returns 1 if
+// the blob is the payload of the entity, 0 if it isn't, and bubbles up the reverts of
+// the content hash precompile.
+func arkivVerifyBlobCode() []byte {
+	p := program.New()
+	// getContentHash(key), the hash overwrites the input
+	p.Push(0x51577ea9).Push(224).Op(vm.SHL).Push(0).Op(vm.MSTORE)
+	p.Push(0).Op(vm.CALLDATALOAD).Push(4).Op(vm.MSTORE)
+	p.StaticCall(nil, arkivaddress.ArkivContentHashAddress, 0, 36, 0, 32)
+	// The destination of the jump is patched once the code is complete
+	p.Op(vm.PUSH2).Append([]byte{0, 0}).Op(vm.JUMPI)
+	jump := p.Size() - 3
+	p.Op(vm.RETURNDATASIZE).Push(0).Push(0).Op(vm.RETURNDATACOPY)
+	p.Op(vm.RETURNDATASIZE).Push(0).Op(vm.REVERT)
+	_, verify := p.Jumpdest()
+	// keccak256(blob) == hash
+	p.Push(32).Op(vm.CALLDATASIZE).Op(vm.SUB).Push(32).Push(64).Op(vm.CALLDATACOPY)
+	p.Push(32).Op(vm.CALLDATASIZE).Op(vm.SUB).Push(64).Op(vm.KECCAK256)
+	p.Push(0).Op(vm.MLOAD).Op(vm.EQ).Push(0).Op(vm.MSTORE)
+	p.Return(0, 32)
+
+	code := p.Bytes()
+	binary.BigEndian.PutUint16(code[jump:], uint16(verify))
+	return code
+}
+
+func TestArkivContentHashContract(t *testing.T) {
+	config := *params.MergedTestChainConfig
+	config.ArkivContentHashReadTime = new(uint64)
+
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabaseForTesting())
+	payload := []byte("the payload of the entity")
+	hashed, unhashed, missing := common.Hash{1}, common.Hash{2}, common.Hash{3}
+	meta := entity.EntityMetaData{Owner: common.Address{1}, ExpiresAtBlock: 1000}
+	if err := entity.Store(statedb, hashed, meta.Owner, meta, payload, true); err != nil {
+		t.Fatal(err)
+	}
+	// Stored before the content hash fork
+	if err := entity.Store(statedb, unhashed, meta.Owner, meta, payload, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// The gas left before the call of the precompile, and at the next opcode
+	var before, after uint64
+	tracer := &tracing.Hooks{
+		OnOpcode: func(pc uint64, op byte, gas, cost uint64, scope tracing.OpContext, rData []byte, depth int, err error) {
+			if depth != 1 {
+				return
+			}
+			if vm.OpCode(op) == vm.STATICCALL {
+				before = gas
+			} else if before != 0 && after == 0 {
+				after = gas
+			}
+		},
+	}
+	verify := func(key common.Hash, blob []byte) ([]byte, error) {
+		before, after = 0, 0
+		ret, _, err := Execute(arkivVerifyBlobCode(), append(key.Bytes(), blob...), &Config{
+			ChainConfig: &config,
+			State:       statedb,
+			EVMConfig:   vm.Config{Tracer: tracer},
+		})
+		return ret, err
+	}
+
+	ret, err := verify(hashed, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ret[31] != 1 {
+		t.Fatalf("payload not verified: %x", ret)
+	}
+	// The precompile is warm, the call costs the warm access and the gas of the
+	// precompile
+	if have, want := before-after, params.WarmStorageReadCostEIP2929+params.ArkivContentHashGas; have != want {
+		t.Fatalf("gas of the precompile call: have %d, want %d", have, want)
+	}
+
+	ret, err = verify(hashed, []byte("another payload"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ret[31] != 0 {
+		t.Fatalf("other payload verified: %x", ret)
+	}
+
+	// The reverts of the precompile tell the entities without a content hash from the
+	// missing ones
+	for _, tc := range []struct {
+		key      common.Hash
+		selector string
+	}{
+		{unhashed, "0xe1e1b461"}, // ContentHashNotStored(bytes32)
+		{missing, "0x66a89f2a"},  // EntityNotFound(bytes32)
+	} {
+		ret, err = verify(tc.key, payload)
+		if err != vm.ErrExecutionReverted {
+			t.Fatalf("key %x: have error %v, want %v", tc.key, err, vm.ErrExecutionReverted)
+		}
+		if want := append(common.FromHex(tc.selector), tc.key[:]...); !bytes.Equal(ret, want) {
+			t.Fatalf("key %x: have revert %x, want %x", tc.key, ret, want)
+		}
+	}
+
+	// Before the fork the address is empty, the call succeeds without returning data
+	// and the blob isn't verified
+	config.ArkivContentHashReadTime = nil
+	ret, err = verify(hashed, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ret[31] != 0 {
+		t.Fatalf("payload verified before the fork: %x", ret)
+	}
+}
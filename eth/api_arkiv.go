@@ -1,36 +1,160 @@
 package eth
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
 	"math/big"
+	"slices"
 	"time"
 
+	"github.com/Arkiv-Network/arkiv-events/events"
 	sqlitestore "github.com/Arkiv-Network/sqlite-bitmap-store"
+	"github.com/Arkiv-Network/sqlite-bitmap-store/query"
+	"github.com/ethereum/go-ethereum/arkiv/dbevents"
+	"github.com/ethereum/go-ethereum/arkiv/fulltext"
+	"github.com/ethereum/go-ethereum/arkiv/limits"
+	"github.com/ethereum/go-ethereum/arkiv/rpctypes"
 	"github.com/ethereum/go-ethereum/arkiv/storageaccounting"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/log"
 )
 
+// arkivAPI is the arkiv RPC namespace.
+//
+// Its responses follow the conventions of the eth namespace: fields are camelCase,
+// numeric chain quantities (block numbers, timestamps, gas, storage slots) are encoded
+// as hexutil.Uint64 or hexutil.Big, and hashes and addresses as common.Hash and
+// common.Address. Counts and sizes that aren't chain quantities, like numbers of
+// entities or sizes in bytes, are plain numbers. The results of queries keep the
+// encoding of the store. The exact encoding of every response type is pinned by
+// api_arkiv_json_test.go.
 type arkivAPI struct {
-	eth   *Ethereum
-	store *sqlitestore.SQLiteStore
+	eth        *Ethereum
+	store      arkivStore
+	syncStatus *dbevents.SyncStatusTracker
+	fullText   *fulltext.Index
+	planner    arkivQueryPlanner
+	memory     *arkivQueryMemoryBudget
+
+	// queryLimits bound the time a query runs in the store and the size of its results.
+	queryLimits arkivQueryLimits
+
+	// legacyJSON adds the fields of the previous encoding to the responses whose
+	// encoding changed, see BlockTiming.
+	legacyJSON bool
+
+	// readOnly is whether the node is a read replica, reported by SyncStatus.
+	readOnly bool
+
+	// compressedPayloads is whether the store keeps the payloads compressed, see
+	// compression.EncodePayload.
+	compressedPayloads bool
+
+	// methods are the methods the operator disabled.
+	methods arkivMethodFilter
+
+	// popular counts the queries for the warmup, warmup gates the readiness of the
+	// node reported by SyncStatus.
+	popular *arkivPopularQueries
+	warmup  *arkivWarmup
+
+	// relayer sends the operations signed as typed data, nil unless the operator set
+	// a relayer account.
+	relayer *arkivRelayer
 }
 
-func NewArkivAPI(eth *Ethereum, store *sqlitestore.SQLiteStore) (*arkivAPI, error) {
+// ArkivAPIConfig is the configuration of the arkiv RPC namespace, set from the
+// --arkiv.query.*, --arkiv.rpc.* and --arkiv.readonly flags.
+type ArkivAPIConfig struct {
+	// QueryMaxScanFraction is the fraction of the live entities a query can select
+	// without allowing a full scan, see arkivQueryPlanner.
+	QueryMaxScanFraction float64
+
+	// QueryMemoryBudget bounds the memory of the queries running at the same time,
+	// a query waits at most QueryMemoryWait for its share.
+	QueryMemoryBudget uint64
+	QueryMemoryWait   time.Duration
+
+	// QueryTimeout, QueryMaxRows and QueryMaxBytes bound the time a query runs in
+	// the store and the size of its results.
+	QueryTimeout  time.Duration
+	QueryMaxRows  uint64
+	QueryMaxBytes uint64
+
+	LegacyJSON         bool
+	ReadOnly           bool
+	CompressedPayloads bool
+
+	// EnabledMethods and DisabledMethods are the methods the operator enabled and
+	// disabled, see newArkivMethodFilter.
+	EnabledMethods  []string
+	DisabledMethods []string
+}
+
+func NewArkivAPI(
+	eth *Ethereum,
+	store arkivStore,
+	syncStatus *dbevents.SyncStatusTracker,
+	fullText *fulltext.Index,
+	config ArkivAPIConfig,
+) (*arkivAPI, error) {
+	methods, err := newArkivMethodFilter(config.EnabledMethods, config.DisabledMethods)
+	if err != nil {
+		return nil, err
+	}
 	return &arkivAPI{
-		eth:   eth,
-		store: store,
+		eth:        eth,
+		store:      store,
+		syncStatus: syncStatus,
+		fullText:   fullText,
+		planner: arkivQueryPlanner{
+			maxScanFraction: config.QueryMaxScanFraction,
+			minScanEntities: arkivQueryMinScanEntities,
+		},
+		memory:             newArkivQueryMemoryBudget(config.QueryMemoryBudget, config.QueryMemoryWait),
+		legacyJSON:         config.LegacyJSON,
+		readOnly:           config.ReadOnly,
+		compressedPayloads: config.CompressedPayloads,
+		queryLimits: arkivQueryLimits{
+			timeout:  config.QueryTimeout,
+			maxRows:  config.QueryMaxRows,
+			maxBytes: config.QueryMaxBytes,
+		},
+		methods: methods,
 	}, nil
 }
 
 func (api *arkivAPI) Query(
 	ctx context.Context,
 	req string,
-	op *sqlitestore.Options,
-) (*sqlitestore.QueryResponse, error) {
+	op *QueryOptions,
+) (_ *QueryResponse, err error) {
+	defer func() { err = arkivRPCError(err) }()
+
+	if err := api.methods.check("query"); err != nil {
+		return nil, err
+	}
+
 	if op == nil {
-		op = &sqlitestore.Options{}
+		op = &QueryOptions{}
+	}
+	if err := checkQueryOrder(op); err != nil {
+		return nil, err
+	}
+	cursor, err := checkQueryCursor(req, op)
+	if err != nil {
+		return nil, err
+	}
+	if err := api.queryLimits.checkPage(op); err != nil {
+		return nil, err
 	}
 	if op.AtBlock == nil {
 		lastBlock := api.eth.blockchain.CurrentHeader().Number.Uint64()
@@ -38,19 +162,318 @@ func (api *arkivAPI) Query(
 	}
 
 	startTime := time.Now()
-	response, err := api.store.QueryEntities(ctx, req, op)
+
+	accepted, err := acceptedCodecs(op.AcceptEncoding)
+	if err != nil {
+		return nil, err
+	}
+
+	// The keys and the $sequence of the entities are needed to page through the query
+	storeOptions := op.Options
+	include := pagedIncludeData(op.GetIncludeData())
+	storeOptions.IncludeData = &include
+	storeOptions.Cursor = ""
+	if cursor != nil {
+		storeOptions.Cursor = cursor.store
+	}
+	var overlay *arkivOverlay
+	if op.PendingView != nil {
+		var err error
+		overlay, err = api.pendingOverlay(ctx, op.PendingView, *op.AtBlock)
+		if err != nil {
+			return nil, err
+		}
+		// The store is queried at the last block it indexed
+		storeOptions.AtBlock = &overlay.indexedBlock
+	}
+
+	query := req
+	var keys []common.Hash
+	if op.Text != "" || op.KeyPrefix != "" {
+		var err error
+		keys, err = api.matchKeys(ctx, op)
+		if err != nil {
+			return nil, err
+		}
+		if len(keys) == 0 {
+			response := &QueryResponse{
+				QueryResponse: &sqlitestore.QueryResponse{
+					Data:        []json.RawMessage{},
+					BlockNumber: *op.AtBlock,
+				},
+			}
+			if op.IncludeStats {
+				liveEntities, err := api.store.GetNumberOfEntities(ctx)
+				if err != nil {
+					return nil, fmt.Errorf("failed to get entity count: %w", err)
+				}
+				response.Stats = &QueryStats{Estimate: &QueryEstimate{LiveEntities: liveEntities}}
+			}
+			if err := api.pageQuery(ctx, req, op, keys, cursor, overlay, response); err != nil {
+				return nil, err
+			}
+			return response, nil
+		}
+		query = withKeyMatch(req, keys)
+	}
+
+	estimate, err := api.planner.estimate(ctx, api.store, query)
+	if err != nil {
+		return nil, err
+	}
+	if estimate.FullScan && !op.AllowFullScan {
+		return nil, invalidRequest(
+			"query selects about %d of %d entities, narrow it down or set allowFullScan",
+			estimate.Entities,
+			estimate.LiveEntities,
+		)
+	}
+
+	memory := &QueryMemory{Estimated: estimateQueryMemory(estimate.Entities, &op.Options)}
+	release, err := api.memory.reserve(ctx, memory.Estimated)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	storeCtx, cancel := api.queryLimits.withTimeout(ctx, op)
+	defer cancel()
+	var response *sqlitestore.QueryResponse
+	if op.OrderBy != nil {
+		response, err = api.queryAllEntities(storeCtx, query, &storeOptions)
+	} else {
+		response, err = api.store.QueryEntities(storeCtx, query, &storeOptions)
+	}
 	if err != nil {
+		if errors.Is(storeCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+			return nil, fmt.Errorf("query timed out after %v, narrow it down or use pagination: %w", api.queryLimits.timeoutOf(op), context.DeadlineExceeded)
+		}
 		return nil, fmt.Errorf("error executing query: %w", err)
 	}
+	if api.compressedPayloads {
+		if err := decodeStorePayloads(response.Data, accepted); err != nil {
+			return nil, err
+		}
+	}
+	// The results of the ordered queries are checked once paged
+	if op.OrderBy == nil {
+		if err := api.queryLimits.checkResults(response.Data); err != nil {
+			return nil, err
+		}
+	}
+	memory.Peak = peakQueryMemory(estimate.Entities, response)
+	arkivQueryMemoryHistogram.Update(int64(memory.Peak))
 	elapsed := time.Since(startTime)
+	arkivQueryTimer(req).Update(elapsed)
+
+	log.Info("arkiv api", "query", req, "text", op.Text, "keyPrefix", op.KeyPrefix, "block", op.GetAtBlock(), "responses", len(response.Data), "estimate", estimate.Entities, "elapsed_ms", elapsed.Milliseconds())
+
+	result := &QueryResponse{QueryResponse: response}
+	if op.IncludeStats {
+		result.Stats = &QueryStats{Estimate: estimate, Memory: memory}
+	}
+	if err := api.pageQuery(ctx, req, op, keys, cursor, overlay, result); err != nil {
+		return nil, err
+	}
+	if ctx.Value(arkivWarmupKey{}) == nil {
+		api.popular.record(req)
+	}
+
+	return result, nil
+}
+
+// maxQueryDiffEntries is the maximum number of keys returned by QueryDiff.
+const maxQueryDiffEntries = limits.MaxQueryDiffEntries
+
+// maxQueryDiffBlocks is the largest distance between the blocks QueryDiff compares.
+const maxQueryDiffBlocks = limits.MaxQueryDiffBlocks
+
+// QueryDiff evaluates the query at blockA and blockB and returns the keys of the entities
+// that were added to, removed from or changed within the result set in between.
+// An entity is changed if its payload or its annotations differ between the two blocks.
+//
+// The store only holds the current entities, so the entities changed in between are
+// rebuilt from the operations of the chain, see entitiesAt. Decoding the blocks counts
+// against the timeout and the memory budget of the queries. The number of returned keys
+// is capped by the results per page of the options, the cursor of a truncated diff
+// returns the next keys.
+func (api *arkivAPI) QueryDiff(
+	ctx context.Context,
+	req string,
+	blockA uint64,
+	blockB uint64,
+	op *sqlitestore.Options,
+) (_ *QueryDiff, err error) {
+	defer func() { err = arkivRPCError(err) }()
+
+	if err := api.methods.check("queryDiff"); err != nil {
+		return nil, err
+	}
+
+	if blockA > blockB {
+		return nil, invalidRequest("blockA %d is after blockB %d", blockA, blockB)
+	}
+	if blockB-blockA > maxQueryDiffBlocks {
+		return nil, invalidRequest("range of %d blocks exceeds the limit of %d blocks", blockB-blockA, maxQueryDiffBlocks)
+	}
+	head := api.eth.blockchain.CurrentHeader().Number.Uint64()
+	if blockB > head {
+		return nil, invalidRequest("block is in the future: head is %d", head)
+	}
+
+	ast, err := query.Parse(req)
+	if err != nil {
+		return nil, invalidRequest("error parsing query: %w", err)
+	}
+	if op == nil {
+		op = &sqlitestore.Options{}
+	}
+	var cursor *queryDiffCursor
+	if op.Cursor != "" {
+		if cursor, err = checkQueryDiffCursor(op.Cursor, req, blockA, blockB); err != nil {
+			return nil, err
+		}
+	}
+
+	queryOp := &QueryOptions{Options: *op}
+	decodeCtx, cancel := api.queryLimits.withTimeout(ctx, queryOp)
+	defer cancel()
+	timedOut := func(err error) error {
+		if errors.Is(decodeCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+			return fmt.Errorf("query timed out after %v, narrow the range of blocks: %w", api.queryLimits.timeoutOf(queryOp), context.DeadlineExceeded)
+		}
+		return err
+	}
+
+	// The operations in between, and the entities they change that exist at blockA
+	var releases []func()
+	defer func() {
+		for _, release := range releases {
+			release()
+		}
+	}()
+	var changes []*events.Block
+	touched := map[common.Hash]struct{}{}
+	existing := map[common.Hash]struct{}{}
+	for number := blockA + 1; number <= blockB; number++ {
+		if err := decodeCtx.Err(); err != nil {
+			return nil, timedOut(err)
+		}
+		decoded, err := api.blockOperations(number)
+		if err != nil {
+			return nil, err
+		}
+		release, err := api.memory.reserve(decodeCtx, operationsMemory(decoded))
+		if err != nil {
+			return nil, timedOut(err)
+		}
+		releases = append(releases, release)
+		changes = append(changes, decoded)
+		for _, operation := range decoded.Operations {
+			key := operationKey(operation)
+			if _, ok := touched[key]; ok {
+				continue
+			}
+			touched[key] = struct{}{}
+			if operation.Create == nil {
+				existing[key] = struct{}{}
+			}
+		}
+	}
+
+	before, err := api.entitiesAt(decodeCtx, existing, blockA)
+	if err != nil {
+		return nil, timedOut(err)
+	}
+	after := &arkivOverlay{
+		entities: map[common.Hash]*overlayEntity{},
+		base: func(key common.Hash) (*overlayEntity, error) {
+			return before[key], nil
+		},
+	}
+	for _, decoded := range changes {
+		if err := after.apply(decoded.Number, decoded.Operations, EntityProvenanceIndexed); err != nil {
+			return nil, err
+		}
+	}
+
+	diff := &QueryDiff{
+		FromBlock: hexutil.Uint64(blockA),
+		ToBlock:   hexutil.Uint64(blockB),
+		Added:     []common.Hash{},
+		Removed:   []common.Hash{},
+		Changed:   []common.Hash{},
+	}
+
+	limit := uint64(maxQueryDiffEntries)
+	if op.ResultsPerPage != nil {
+		limit = min(limit, *op.ResultsPerPage)
+	}
+	entries := uint64(0)
+	matches := func(entity *overlayEntity) bool {
+		return entity != nil && matchQuery(ast, entity.strs, entity.nums)
+	}
+	next := newQueryDiffCursor(req, blockA, blockB)
+	if cursor != nil {
+		next.key = cursor.key
+	}
+	for _, key := range sortedKeys(touched) {
+		if cursor != nil && key.Cmp(cursor.key) <= 0 {
+			continue
+		}
+		previous := before[key]
+		current, err := after.lookup(key)
+		if err != nil {
+			return nil, err
+		}
+
+		var list *[]common.Hash
+		switch inBefore, inAfter := matches(previous), matches(current); {
+		case inAfter && !inBefore:
+			list = &diff.Added
+		case inBefore && !inAfter:
+			list = &diff.Removed
+		case inBefore && inAfter && !previous.sameContent(current):
+			list = &diff.Changed
+		default:
+			continue
+		}
+		if entries >= limit {
+			diff.Truncated = true
+			diff.Cursor = next.encode()
+			break
+		}
+		entries++
+		*list = append(*list, key)
+		next.key = key
+	}
 
-	log.Info("arkiv api", "query", req, "block", op.GetAtBlock(), "responses", len(response.Data), "elapsed_ms", elapsed.Milliseconds())
+	return diff, nil
+}
 
-	return response, nil
+// sameContent tells whether the entities have the same payload and annotations.
+func (e *overlayEntity) sameContent(other *overlayEntity) bool {
+	annotations := sqlitestore.IncludeData{Attributes: true}
+	return bytes.Equal(e.payload, other.payload) &&
+		maps.Equal(filterEntityAttributes(e.strs, annotations), filterEntityAttributes(other.strs, annotations)) &&
+		maps.Equal(filterEntityAttributes(e.nums, annotations), filterEntityAttributes(other.nums, annotations))
+}
+
+func sortedKeys[V any](m map[common.Hash]V) []common.Hash {
+	keys := slices.Collect(maps.Keys(m))
+	slices.SortFunc(keys, func(a, b common.Hash) int {
+		return a.Cmp(b)
+	})
+	return keys
 }
 
 // GetEntityCount returns the total number of entities in the storage.
-func (api *arkivAPI) GetEntityCount(ctx context.Context) (uint64, error) {
+func (api *arkivAPI) GetEntityCount(ctx context.Context) (_ uint64, err error) {
+	defer func() { err = arkivRPCError(err) }()
+
+	if err := api.methods.check("getEntityCount"); err != nil {
+		return 0, err
+	}
 
 	count, err := api.store.GetNumberOfEntities(ctx)
 	if err != nil {
@@ -60,7 +483,13 @@ func (api *arkivAPI) GetEntityCount(ctx context.Context) (uint64, error) {
 
 }
 
-func (api *arkivAPI) GetNumberOfUsedSlots() (*hexutil.Big, error) {
+func (api *arkivAPI) GetNumberOfUsedSlots() (_ *hexutil.Big, err error) {
+	defer func() { err = arkivRPCError(err) }()
+
+	if err := api.methods.check("getNumberOfUsedSlots"); err != nil {
+		return nil, err
+	}
+
 	header := api.eth.blockchain.CurrentBlock()
 	stateDB, err := api.eth.BlockChain().StateAt(header.Root)
 	if err != nil {
@@ -73,13 +502,170 @@ func (api *arkivAPI) GetNumberOfUsedSlots() (*hexutil.Big, error) {
 	return (*hexutil.Big)(counterAsBigInt), nil
 }
 
+// GetUsedSlotsByOwner returns the number of state slots used by the entities of the
+// owner at the current block. The slots are counted from the activation of the owner
+// slots fork, entities created before it are not accounted to their owner.
+func (api *arkivAPI) GetUsedSlotsByOwner(owner common.Address) (_ *hexutil.Big, err error) {
+	defer func() { err = arkivRPCError(err) }()
+
+	if err := api.methods.check("getUsedSlotsByOwner"); err != nil {
+		return nil, err
+	}
+
+	header := api.eth.blockchain.CurrentBlock()
+	stateDB, err := api.eth.BlockChain().StateAt(header.Root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get state: %w", err)
+	}
+
+	return (*hexutil.Big)(storageaccounting.GetNumberOfUsedSlotsByOwner(stateDB, owner).ToBig()), nil
+}
+
+// GetEntityMetaData returns the status of an entity at the current block. Removed
+// entities keep a tombstone telling whether they were deleted or expired for the
+// retention configured in the chain config.
+func (api *arkivAPI) GetEntityMetaData(key common.Hash) (_ *EntityMetaData, err error) {
+	defer func() { err = arkivRPCError(err) }()
+
+	if err := api.methods.check("getEntityMetaData"); err != nil {
+		return nil, err
+	}
+
+	header := api.eth.blockchain.CurrentBlock()
+	stateDB, err := api.eth.BlockChain().StateAt(header.Root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get state: %w", err)
+	}
+	return entityMetaData(stateDB, key), nil
+}
+
+func entityMetaData(access storageutil.StateAccess, key common.Hash) *EntityMetaData {
+	if md, err := entity.GetEntityMetaData(access, key); err == nil {
+		expiresAtBlock := hexutil.Uint64(md.ExpiresAtBlock)
+		result := &EntityMetaData{
+			Status:         EntityStatusLive,
+			Owner:          &md.Owner,
+			ExpiresAtBlock: &expiresAtBlock,
+		}
+		if pending := entity.GetPendingOwner(access, key); pending != nil {
+			result.PendingOwner = &PendingOwner{
+				Owner:         pending.Owner,
+				LapsesAtBlock: hexutil.Uint64(pending.LapsesAtBlock),
+			}
+		}
+		return result
+	}
+
+	tombstone := entity.GetTombstone(access, key)
+	if tombstone == nil {
+		return &EntityMetaData{Status: EntityStatusUnknown}
+	}
+
+	block := hexutil.Uint64(tombstone.Block)
+	status := EntityStatusDeleted
+	if tombstone.Reason == entity.TombstoneExpired {
+		status = EntityStatusExpired
+	}
+	return &EntityMetaData{Status: status, Block: &block}
+}
+
+// arkivExpiryWindow is the number of blocks the average block interval of the expiry
+// estimates is computed over.
+const arkivExpiryWindow = 1000
+
+// GetEntityExpiry returns the expiry block of an entity at the current block, the
+// number of blocks until then and an estimate of its wall-clock time.
+func (api *arkivAPI) GetEntityExpiry(ctx context.Context, key common.Hash) (_ *EntityExpiry, err error) {
+	defer func() { err = arkivRPCError(err) }()
+
+	if err := api.methods.check("getEntityExpiry"); err != nil {
+		return nil, err
+	}
+
+	header := api.eth.blockchain.CurrentBlock()
+	stateDB, err := api.eth.BlockChain().StateAt(header.Root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get state: %w", err)
+	}
+	return entityExpiry(stateDB, api.eth.blockchain, header, key, arkivExpiryWindow), nil
+}
+
+// headerByNumberReader reads the headers of the canonical chain.
+type headerByNumberReader interface {
+	GetHeaderByNumber(number uint64) *types.Header
+}
+
+func entityExpiry(access storageutil.StateAccess, chain headerByNumberReader, head *types.Header, key common.Hash, window uint64) *EntityExpiry {
+	md := entityMetaData(access, key)
+	if md.Status != EntityStatusLive {
+		return &EntityExpiry{Status: md.Status, Block: md.Block}
+	}
+
+	remaining := hexutil.Uint64(0)
+	if uint64(*md.ExpiresAtBlock) > head.Number.Uint64() {
+		remaining = *md.ExpiresAtBlock - hexutil.Uint64(head.Number.Uint64())
+	}
+	return &EntityExpiry{
+		Status:          md.Status,
+		ExpiresAtBlock:  md.ExpiresAtBlock,
+		BlocksRemaining: &remaining,
+		Estimate:        expiryEstimate(chain, head, uint64(remaining), window),
+	}
+}
+
+// expiryEstimate estimates the time of the block coming the number of blocks after
+// the head from the average block interval of the window, nil at genesis.
+func expiryEstimate(chain headerByNumberReader, head *types.Header, blocks uint64, window uint64) *ExpiryEstimate {
+	window = min(window, head.Number.Uint64())
+	if window == 0 {
+		return nil
+	}
+	first := chain.GetHeaderByNumber(head.Number.Uint64() - window)
+	if first == nil {
+		return nil
+	}
+
+	span := head.Time - first.Time
+	return &ExpiryEstimate{
+		ExpiresAt:            hexutil.Uint64(head.Time + blocks*span/window),
+		AverageBlockInterval: span * 1000 / window,
+		Window:               hexutil.Uint64(window),
+	}
+}
+
+// BlockTiming is the timing of the current block, with the fields of the previous
+// encoding if legacy is set.
 type BlockTiming struct {
-	CurrentBlock     uint64 `json:"current_block"`
-	CurrentBlockTime uint64 `json:"current_block_time"`
-	BlockDuration    uint64 `json:"duration"`
+	rpctypes.BlockTiming
+
+	// legacy adds the snake_case fields with plain numbers of the previous releases
+	// to the encoding, for clients that haven't migrated yet. The legacy fields will
+	// be removed in the next release.
+	legacy bool
+}
+
+func (t BlockTiming) MarshalJSON() ([]byte, error) {
+	if !t.legacy {
+		return json.Marshal(t.BlockTiming)
+	}
+	return json.Marshal(struct {
+		rpctypes.BlockTiming
+		LegacyCurrentBlock     uint64 `json:"current_block"`
+		LegacyCurrentBlockTime uint64 `json:"current_block_time"`
+	}{
+		BlockTiming:            t.BlockTiming,
+		LegacyCurrentBlock:     uint64(t.CurrentBlock),
+		LegacyCurrentBlockTime: uint64(t.CurrentBlockTime),
+	})
 }
 
-func (api *arkivAPI) GetBlockTiming(ctx context.Context) (*BlockTiming, error) {
+func (api *arkivAPI) GetBlockTiming(ctx context.Context) (_ *BlockTiming, err error) {
+	defer func() { err = arkivRPCError(err) }()
+
+	if err := api.methods.check("getBlockTiming"); err != nil {
+		return nil, err
+	}
+
 	header := api.eth.blockchain.CurrentHeader()
 	previousHeader := api.eth.blockchain.GetHeaderByHash(header.ParentHash)
 	if previousHeader == nil {
@@ -87,8 +673,56 @@ func (api *arkivAPI) GetBlockTiming(ctx context.Context) (*BlockTiming, error) {
 	}
 
 	return &BlockTiming{
-		CurrentBlock:     header.Number.Uint64(),
-		CurrentBlockTime: header.Time,
-		BlockDuration:    header.Time - previousHeader.Time,
+		BlockTiming: rpctypes.BlockTiming{
+			CurrentBlock:     hexutil.Uint64(header.Number.Uint64()),
+			CurrentBlockTime: hexutil.Uint64(header.Time),
+			BlockDuration:    hexutil.Uint64(header.Time - previousHeader.Time),
+		},
+		legacy: api.legacyJSON,
 	}, nil
 }
+
+func newSyncStatus(status dbevents.SyncStatus) *SyncStatus {
+	res := &SyncStatus{
+		LastBlock:              hexutil.Uint64(status.LastBlock),
+		HeadBlock:              hexutil.Uint64(status.HeadBlock),
+		EarliestIndexableBlock: hexutil.Uint64(status.EarliestIndexableBlock),
+		Stalled:                status.Stalled,
+		UnknownOperations:      hexutil.Uint64(status.UnknownOperations),
+		DeadLetterBatches:      status.DeadLetterBatches,
+		HeadsSinceHeartbeat:    hexutil.Uint64(status.HeadsSinceHeartbeat),
+		IteratorRestarts:       status.IteratorRestarts,
+	}
+	if !status.Heartbeat.IsZero() {
+		res.HeartbeatAge = hexutil.Uint64(time.Since(status.Heartbeat).Milliseconds())
+	}
+	if status.FirstUnknownOperationBlock != nil {
+		res.FirstUnknownOperationBlock = (*hexutil.Uint64)(status.FirstUnknownOperationBlock)
+	}
+	if status.PrunedGap != nil {
+		res.PrunedGap = &PrunedGap{
+			From: hexutil.Uint64(status.PrunedGap.From),
+			To:   hexutil.Uint64(status.PrunedGap.To),
+		}
+	}
+	return res
+}
+
+// SyncStatus returns the progress of the Arkiv indexer, including the range of
+// blocks that could not be indexed because their receipts were pruned, whether the
+// node is a read replica and whether it's ready to serve queries. It's one of the
+// arkivReportingMethods, which the operator can't disable.
+func (api *arkivAPI) SyncStatus() *SyncStatus {
+	status := newSyncStatus(api.syncStatus.Status())
+	status.ReadOnly = api.readOnly
+	status.Ready = api.warmup.ready()
+	status.Warmup = api.warmup.report()
+	return status
+}
+
+// SelfCheck runs the checks of the wiring of the Arkiv subsystem the node runs when it
+// starts: the store, its checkpoint, the processor address, the forks and the events
+// pipeline. It's one of the arkivReportingMethods, which the operator can't disable.
+func (api *arkivAPI) SelfCheck(ctx context.Context) *SelfCheck {
+	return api.eth.arkivSelfCheck.run(ctx)
+}
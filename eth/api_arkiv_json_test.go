@@ -0,0 +1,439 @@
+package eth
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	sqlitestore "github.com/Arkiv-Network/sqlite-bitmap-store"
+	"github.com/ethereum/go-ethereum/arkiv/dbevents"
+	"github.com/ethereum/go-ethereum/arkiv/rpctypes"
+	"github.com/ethereum/go-ethereum/arkiv/statediff"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+// TestArkivResponseJSON pins the encoding of the responses of the arkiv namespace,
+// new response types must be added here.
+func TestArkivResponseJSON(t *testing.T) {
+	key := common.HexToHash("0x01")
+	cursor := "0x10"
+	owner := common.HexToAddress("0x02")
+	block := hexutil.Uint64(100)
+	first := uint64(28)
+	position := uint64(0)
+
+	for _, tc := range []struct {
+		name     string
+		response any
+		json     string
+	}{
+		{
+			name: "BlockTiming",
+			response: &BlockTiming{
+				BlockTiming: rpctypes.BlockTiming{
+					CurrentBlock:     100,
+					CurrentBlockTime: 1700000000,
+					BlockDuration:    2,
+				},
+			},
+			json: `{"currentBlock":"0x64","currentBlockTime":"0x6553f100","duration":"0x2"}`,
+		},
+		{
+			name: "BlockTiming with legacy fields",
+			response: &BlockTiming{
+				BlockTiming: rpctypes.BlockTiming{
+					CurrentBlock:     100,
+					CurrentBlockTime: 1700000000,
+					BlockDuration:    2,
+				},
+				legacy: true,
+			},
+			json: `{"currentBlock":"0x64","currentBlockTime":"0x6553f100","duration":"0x2","current_block":100,"current_block_time":1700000000}`,
+		},
+		{
+			name:     "NumberOfUsedSlots",
+			response: (*hexutil.Big)(big.NewInt(300)),
+			json:     `"0x12c"`,
+		},
+		{
+			name:     "EntityCount",
+			response: uint64(42),
+			json:     `42`,
+		},
+		{
+			name: "QueryDiff",
+			response: &QueryDiff{
+				FromBlock: 10,
+				ToBlock:   20,
+				Added:     []common.Hash{key},
+				Removed:   []common.Hash{},
+				Changed:   []common.Hash{},
+			},
+			json: `{"fromBlock":"0xa","toBlock":"0x14","added":["0x0000000000000000000000000000000000000000000000000000000000000001"],"removed":[],"changed":[],"truncated":false}`,
+		},
+		{
+			name: "SyncStatus",
+			response: newSyncStatus(dbevents.SyncStatus{
+				LastBlock: 30,
+				HeadBlock: 31,
+			}),
+			json: `{"lastBlock":"0x1e","headBlock":"0x1f","earliestIndexableBlock":"0x0","stalled":false,"unknownOperations":"0x0","deadLetterBatches":0,"heartbeatAge":"0x0","headsSinceHeartbeat":"0x0","iteratorRestarts":0,"readOnly":false,"ready":false}`,
+		},
+		{
+			name: "SyncStatus with pruned gap",
+			response: newSyncStatus(dbevents.SyncStatus{
+				LastBlock:              30,
+				HeadBlock:              31,
+				EarliestIndexableBlock: 5,
+				PrunedGap:              &dbevents.PrunedGap{From: 1, To: 4},
+			}),
+			json: `{"lastBlock":"0x1e","headBlock":"0x1f","earliestIndexableBlock":"0x5","stalled":false,"prunedGap":{"from":"0x1","to":"0x4"},"unknownOperations":"0x0","deadLetterBatches":0,"heartbeatAge":"0x0","headsSinceHeartbeat":"0x0","iteratorRestarts":0,"readOnly":false,"ready":false}`,
+		},
+		{
+			name: "SyncStatus with unknown operations",
+			response: newSyncStatus(dbevents.SyncStatus{
+				LastBlock:                  30,
+				HeadBlock:                  31,
+				UnknownOperations:          3,
+				FirstUnknownOperationBlock: &first,
+			}),
+			json: `{"lastBlock":"0x1e","headBlock":"0x1f","earliestIndexableBlock":"0x0","stalled":false,"unknownOperations":"0x3","firstUnknownOperationBlock":"0x1c","deadLetterBatches":0,"heartbeatAge":"0x0","headsSinceHeartbeat":"0x0","iteratorRestarts":0,"readOnly":false,"ready":false}`,
+		},
+		{
+			name: "SyncStatus with dead letters",
+			response: newSyncStatus(dbevents.SyncStatus{
+				LastBlock:         30,
+				HeadBlock:         31,
+				Stalled:           true,
+				DeadLetterBatches: 2,
+			}),
+			json: `{"lastBlock":"0x1e","headBlock":"0x1f","earliestIndexableBlock":"0x0","stalled":true,"unknownOperations":"0x0","deadLetterBatches":2,"heartbeatAge":"0x0","headsSinceHeartbeat":"0x0","iteratorRestarts":0,"readOnly":false,"ready":false}`,
+		},
+		{
+			name: "SyncStatus with iterator restarts",
+			response: newSyncStatus(dbevents.SyncStatus{
+				LastBlock:           30,
+				HeadBlock:           33,
+				HeadsSinceHeartbeat: 3,
+				IteratorRestarts:    1,
+			}),
+			json: `{"lastBlock":"0x1e","headBlock":"0x21","earliestIndexableBlock":"0x0","stalled":false,"unknownOperations":"0x0","deadLetterBatches":0,"heartbeatAge":"0x0","headsSinceHeartbeat":"0x3","iteratorRestarts":1,"readOnly":false,"ready":false}`,
+		},
+		{
+			name: "SyncStatus with warmup",
+			response: func() *SyncStatus {
+				status := newSyncStatus(dbevents.SyncStatus{LastBlock: 30, HeadBlock: 30})
+				status.Ready = true
+				status.Warmup = &WarmupReport{Block: 30, Done: true, Queries: []WarmupQuery{
+					{Query: `type = "note"`, Source: WarmupSourceConfig, DurationMs: 12},
+					{Query: `$owner = 0x1`, Source: WarmupSourcePopular, DurationMs: 3, Error: "query memory budget exceeded"},
+				}}
+				return status
+			}(),
+			json: `{"lastBlock":"0x1e","headBlock":"0x1e","earliestIndexableBlock":"0x0","stalled":false,"unknownOperations":"0x0","deadLetterBatches":0,"heartbeatAge":"0x0","headsSinceHeartbeat":"0x0","iteratorRestarts":0,"readOnly":false,"ready":true,"warmup":{"block":"0x1e","done":true,"queries":[{"query":"type = \"note\"","source":"config","durationMs":12},{"query":"$owner = 0x1","source":"popular","durationMs":3,"error":"query memory budget exceeded"}]}}`,
+		},
+		{
+			name:     "EventsCheckpoint",
+			response: &EventsCheckpoint{Previous: 2, Block: 4},
+			json:     `{"previous":"0x2","block":"0x4"}`,
+		},
+		{
+			name:     "DeadLetter",
+			response: &DeadLetter{File: "blocks-7-8-1.json", FromBlock: 7, ToBlock: 8, Error: "database or disk is full", Attempts: 6},
+			json:     `{"file":"blocks-7-8-1.json","fromBlock":"0x7","toBlock":"0x8","error":"database or disk is full","attempts":6}`,
+		},
+		{
+			name: "Entity",
+			response: &Entity{
+				Key:               key,
+				Owner:             owner,
+				ExpiresAtBlock:    block,
+				ContentType:       "text/plain",
+				StringAttributes:  map[string]string{"kind": "x"},
+				NumericAttributes: map[string]uint64{"size": 1},
+				Payload:           []byte("hi"),
+				Block:             4,
+			},
+			json: `{"key":"0x0000000000000000000000000000000000000000000000000000000000000001","owner":"0x0000000000000000000000000000000000000002","expiresAtBlock":"0x64","contentType":"text/plain","stringAttributes":{"kind":"x"},"numericAttributes":{"size":1},"payload":"0x6869","block":"0x4"}`,
+		},
+		{
+			name: "SelfCheck",
+			response: &SelfCheck{
+				OK: true,
+				Findings: []SelfCheckFinding{
+					{Check: "checkpoint", Status: SelfCheckOK, Message: "block 4 of 5"},
+					{Check: "processor", Status: SelfCheckOK},
+					{Check: "forks", Status: SelfCheckWarning, Message: "arkivOwnershipTransferWindow is set without arkivOwnershipTime"},
+				},
+			},
+			json: `{"ok":true,"findings":[{"check":"checkpoint","status":"ok","message":"block 4 of 5"},{"check":"processor","status":"ok"},{"check":"forks","status":"warning","message":"arkivOwnershipTransferWindow is set without arkivOwnershipTime"}]}`,
+		},
+		{
+			name: "OwnerUsageReport",
+			response: &OwnerUsageReport{
+				Owner:        owner,
+				FromBlock:    2,
+				ToBlock:      7,
+				Creates:      1,
+				Updates:      1,
+				Deletes:      1,
+				PayloadBytes: 24,
+				SlotBlocks:   24,
+				GasUsed:      100000,
+			},
+			json: `{"owner":"0x0000000000000000000000000000000000000002","fromBlock":"0x2","toBlock":"0x7","creates":1,"updates":1,"deletes":1,"payloadBytes":24,"slotBlocks":"0x18","gasUsed":"0x186a0"}`,
+		},
+		{
+			name: "ProcessorLogs",
+			response: &ProcessorLogs{
+				Logs: []ProcessorLog{{
+					BlockNumber: 5,
+					TxHash:      key,
+					TxIndex:     1,
+					LogIndex:    2,
+					Kind:        "ownerChanged",
+					Key:         key,
+					Owner:       owner,
+					NewOwner:    &owner,
+					Data:        []byte{},
+				}},
+				Cursor:        &cursor,
+				Path:          "bloom",
+				BlocksScanned: 6,
+			},
+			json: `{"logs":[{"blockNumber":"0x5","transactionHash":"0x0000000000000000000000000000000000000000000000000000000000000001","transactionIndex":"0x1","logIndex":"0x2","kind":"ownerChanged","key":"0x0000000000000000000000000000000000000000000000000000000000000001","owner":"0x0000000000000000000000000000000000000002","newOwner":"0x0000000000000000000000000000000000000002","data":"0x"}],"cursor":"0x10","path":"bloom","blocksScanned":6}`,
+		},
+		{
+			name: "ProcessorLogs with buckets",
+			response: &ProcessorLogs{
+				Buckets: []ProcessorLogBucket{{FromBlock: 1, ToBlock: 10, Counts: map[string]uint64{"created": 3}}},
+				Path:    "index",
+			},
+			json: `{"buckets":[{"fromBlock":"0x1","toBlock":"0xa","counts":{"created":3}}],"path":"index","blocksScanned":0}`,
+		},
+		{
+			name: "EntityMetaData",
+			response: &EntityMetaData{
+				Status:         EntityStatusLive,
+				Owner:          &owner,
+				ExpiresAtBlock: &block,
+			},
+			json: `{"status":"live","owner":"0x0000000000000000000000000000000000000002","expiresAtBlock":"0x64"}`,
+		},
+		{
+			name: "EntityMetaData of an entity being transferred",
+			response: &EntityMetaData{
+				Status:         EntityStatusLive,
+				Owner:          &owner,
+				ExpiresAtBlock: &block,
+				PendingOwner:   &PendingOwner{Owner: owner, LapsesAtBlock: 7},
+			},
+			json: `{"status":"live","owner":"0x0000000000000000000000000000000000000002","expiresAtBlock":"0x64","pendingOwner":{"owner":"0x0000000000000000000000000000000000000002","lapsesAtBlock":"0x7"}}`,
+		},
+		{
+			name:     "EntityMetaData of an expired entity",
+			response: &EntityMetaData{Status: EntityStatusExpired, Block: &block},
+			json:     `{"status":"expired","block":"0x64"}`,
+		},
+		{
+			name:     "EntityMetaData of an unknown entity",
+			response: &EntityMetaData{Status: EntityStatusUnknown},
+			json:     `{"status":"unknown"}`,
+		},
+		{
+			name: "EntityExpiry",
+			response: &EntityExpiry{
+				Status:          EntityStatusLive,
+				ExpiresAtBlock:  &block,
+				BlocksRemaining: &block,
+				Estimate: &ExpiryEstimate{
+					ExpiresAt:            1700000200,
+					AverageBlockInterval: 2000,
+					Window:               1000,
+				},
+			},
+			json: `{"status":"live","expiresAtBlock":"0x64","blocksRemaining":"0x64","estimate":{"expiresAt":"0x6553f1c8","averageBlockIntervalMs":2000,"window":"0x3e8"}}`,
+		},
+		{
+			name:     "EntityExpiry of a deleted entity",
+			response: &EntityExpiry{Status: EntityStatusDeleted, Block: &block},
+			json:     `{"status":"deleted","block":"0x64"}`,
+		},
+		{
+			name: "Limits",
+			response: &Limits{
+				Version: 1,
+				Block:   100,
+				Time:    1000,
+				Consensus: []Limit{
+					{Name: "maxAnnotationValueSize", Unit: "bytes", Value: 1024},
+					{Name: "tombstoneRetention", Unit: "blocks", Value: 10, ForkGated: true, Feature: "arkiv.tombstones", ActivationTime: &block},
+				},
+				Node:                        []Limit{{Name: "maxQueryDiffBlocks", Unit: "blocks", Value: 50}},
+				MaxAnnotationValueSize:      1024,
+				AnnotationValueGasThreshold: 64,
+				AnnotationValueGasPerByte:   16,
+			},
+			json: `{"version":1,"block":"0x64","time":"0x3e8","consensus":[{"name":"maxAnnotationValueSize","unit":"bytes","value":1024,"forkGated":false},{"name":"tombstoneRetention","unit":"blocks","value":10,"forkGated":true,"feature":"arkiv.tombstones","activationTime":"0x64"}],"node":[{"name":"maxQueryDiffBlocks","unit":"blocks","value":50,"forkGated":false}],"maxAnnotationValueSize":1024,"annotationValueGasThreshold":64,"annotationValueGasPerByte":"0x10"}`,
+		},
+		{
+			name: "ShadowEnforcementStats",
+			response: &ShadowEnforcementStats{
+				Enabled: true,
+				Rules:   []ShadowRule{{ID: hexutil.Bytes{0x05, 0x04, 0x99, 0xec}, Name: "arkiv.gasSchedule", Evaluated: 3, Violations: 1}},
+				Recent:  []ShadowViolation{{Rule: "arkiv.gasSchedule", Block: 100, TxHash: key, Error: "create[0] string annotation a value is too long: 9000 bytes (max 8192)"}},
+			},
+			json: `{"enabled":true,"rules":[{"id":"0x050499ec","name":"arkiv.gasSchedule","evaluated":3,"violations":1}],"recent":[{"rule":"arkiv.gasSchedule","block":"0x64","txHash":"0x0000000000000000000000000000000000000000000000000000000000000001","error":"create[0] string annotation a value is too long: 9000 bytes (max 8192)"}]}`,
+		},
+		{
+			name: "ContentHashVerification",
+			response: &ContentHashVerification{
+				Block: 100,
+				Results: []ContentHashResult{
+					{Key: key, Status: ContentHashMatch},
+					{Key: key, Status: ContentHashMismatch, ContentHash: &key},
+				},
+			},
+			json: `{"block":"0x64","offset":0,"results":[{"key":"0x0000000000000000000000000000000000000000000000000000000000000001","status":"match"},{"key":"0x0000000000000000000000000000000000000000000000000000000000000001","status":"mismatch","contentHash":"0x0000000000000000000000000000000000000000000000000000000000000001"}]}`,
+		},
+		{
+			name: "OwnerEntities",
+			response: &OwnerEntities{
+				Owner:    owner,
+				Block:    4,
+				Entities: []OwnerEntity{{Key: key, ExpiresAtBlock: block}},
+				Cursor:   &key,
+			},
+			json: `{"owner":"0x0000000000000000000000000000000000000002","block":"0x4","entities":[{"key":"0x0000000000000000000000000000000000000000000000000000000000000001","expiresAtBlock":"0x64"}],"cursor":"0x0000000000000000000000000000000000000000000000000000000000000001"}`,
+		},
+		{
+			name: "OwnerEntitiesByExpiry",
+			response: &OwnerEntitiesByExpiry{
+				Owner:    owner,
+				Block:    4,
+				Entities: []ExpiringEntity{{Key: key, ExpiresAtBlock: block, BlocksRemaining: 96}},
+				Cursor:   &ExpiryCursor{ExpiresAtBlock: block, Key: key},
+			},
+			json: `{"owner":"0x0000000000000000000000000000000000000002","block":"0x4","entities":[{"key":"0x0000000000000000000000000000000000000000000000000000000000000001","expiresAtBlock":"0x64","blocksRemaining":"0x60"}],"cursor":{"expiresAtBlock":"0x64","key":"0x0000000000000000000000000000000000000000000000000000000000000001"}}`,
+		},
+		{
+			name: "EntitiesToExpire",
+			response: &EntitiesToExpire{
+				Block:     4,
+				FromBlock: 4,
+				ToBlock:   block,
+				Owner:     &owner,
+				Blocks:    []ExpiringBlock{{Block: block, Keys: []common.Hash{key}}},
+			},
+			json: `{"block":"0x4","fromBlock":"0x4","toBlock":"0x64","owner":"0x0000000000000000000000000000000000000002","blocks":[{"block":"0x64","keys":["0x0000000000000000000000000000000000000000000000000000000000000001"]}]}`,
+		},
+		{
+			name:     "QueryCount",
+			response: &QueryCount{Count: 12, Block: 100},
+			json:     `{"count":12,"block":"0x64"}`,
+		},
+		{
+			name: "EntitySample",
+			response: &EntitySample{
+				Block:      100,
+				Seed:       hexutil.Bytes{0x01},
+				Population: 2,
+				Entities: []SampledEntity{
+					{Key: key},
+					{Key: key, MetaData: &EntityMetaData{Status: EntityStatusLive, Owner: &owner, ExpiresAtBlock: &block}},
+				},
+			},
+			json: `{"block":"0x64","seed":"0x01","population":2,"entities":[{"key":"0x0000000000000000000000000000000000000000000000000000000000000001"},{"key":"0x0000000000000000000000000000000000000000000000000000000000000001","metaData":{"status":"live","owner":"0x0000000000000000000000000000000000000002","expiresAtBlock":"0x64"}}]}`,
+		},
+		{
+			name:     "ContentHashPair",
+			response: ContentHashPair{Key: key, Hash: key},
+			json:     `["0x0000000000000000000000000000000000000000000000000000000000000001","0x0000000000000000000000000000000000000000000000000000000000000001"]`,
+		},
+		{
+			name: "SimulationResult",
+			response: &SimulationResult{
+				Block:           4,
+				TargetBlock:     7,
+				Success:         true,
+				Logs:            []*types.Log{},
+				ExpiredEntities: []common.Hash{key},
+				StateDiff: []statediff.DecodedSlot{{
+					Slot:     statediff.Slot{Slot: key, New: key},
+					Kind:     statediff.KindEntitiesToExpire,
+					Block:    &block,
+					Part:     statediff.PartElement,
+					Position: &position,
+					NewValue: key,
+				}},
+			},
+			json: `{"block":"0x4","targetBlock":"0x7","success":true,"logs":[],"expiredEntities":["0x0000000000000000000000000000000000000000000000000000000000000001"],"stateDiff":[{"slot":"0x0000000000000000000000000000000000000000000000000000000000000001","old":"0x0000000000000000000000000000000000000000000000000000000000000000","new":"0x0000000000000000000000000000000000000000000000000000000000000001","kind":"entitiesToExpire","block":"0x64","part":"element","position":0,"oldValue":null,"newValue":"0x0000000000000000000000000000000000000000000000000000000000000001"}]}`,
+		},
+		{
+			name: "QueryResponse",
+			response: &QueryResponse{
+				QueryResponse: &sqlitestore.QueryResponse{
+					Data:        []json.RawMessage{json.RawMessage(`{"key":"0x01"}`)},
+					BlockNumber: 20,
+					Cursor:      &cursor,
+				},
+			},
+			json: `{"data":[{"key":"0x01"}],"blockNumber":20,"cursor":"0x10"}`,
+		},
+		{
+			name: "EntityData with a compressed value",
+			response: &EntityData{
+				EntityData:    sqlitestore.EntityData{Key: &key, Value: []byte{0x0b, 0x02}},
+				ValueEncoding: "br",
+			},
+			json: `{"key":"0x0000000000000000000000000000000000000000000000000000000000000001","value":"0x0b02","valueEncoding":"br"}`,
+		},
+		{
+			name: "QueryResponse with stats",
+			response: &QueryResponse{
+				QueryResponse: &sqlitestore.QueryResponse{
+					Data:        []json.RawMessage{},
+					BlockNumber: 20,
+				},
+				Stats: &QueryStats{
+					Estimate: &QueryEstimate{Entities: 5, LiveEntities: 10},
+				},
+			},
+			json: `{"data":[],"blockNumber":20,"stats":{"estimate":{"entities":5,"liveEntities":10,"fullScan":false}}}`,
+		},
+		{
+			name: "QueryResponse with a pending view",
+			response: &QueryResponse{
+				QueryResponse: &sqlitestore.QueryResponse{
+					Data:        []json.RawMessage{json.RawMessage(`{"key":"0x01"}`), json.RawMessage(`{"key":"0x02"}`)},
+					BlockNumber: 20,
+				},
+				Provenance: []EntityProvenance{EntityProvenancePending, EntityProvenanceIndexed},
+			},
+			json: `{"data":[{"key":"0x01"},{"key":"0x02"}],"blockNumber":20,"provenance":["pending","indexed"]}`,
+		},
+		{
+			name: "QueryResponse with memory stats",
+			response: &QueryResponse{
+				QueryResponse: &sqlitestore.QueryResponse{
+					Data:        []json.RawMessage{},
+					BlockNumber: 20,
+				},
+				Stats: &QueryStats{
+					Estimate: &QueryEstimate{Entities: 5, LiveEntities: 10},
+					Memory:   &QueryMemory{Estimated: 5440, Peak: 320},
+				},
+			},
+			json: `{"data":[],"blockNumber":20,"stats":{"estimate":{"entities":5,"liveEntities":10,"fullScan":false},"memory":{"estimated":5440,"peak":320}}}`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			encoded, err := json.Marshal(tc.response)
+			require.NoError(t, err)
+			require.Equal(t, tc.json, string(encoded))
+		})
+	}
+}
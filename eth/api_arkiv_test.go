@@ -0,0 +1,119 @@
+package eth
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/arkiv/compression"
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/stretchr/testify/require"
+)
+
+// testHeaders is a canonical chain of headers.
+type testHeaders []*types.Header
+
+func (h testHeaders) GetHeaderByNumber(number uint64) *types.Header {
+	if number >= uint64(len(h)) {
+		return nil
+	}
+	return h[number]
+}
+
+// extend appends the number of headers, each coming the interval after its parent.
+func (h testHeaders) extend(blocks int, interval uint64) testHeaders {
+	for range blocks {
+		header := &types.Header{Number: big.NewInt(int64(len(h)))}
+		if len(h) > 0 {
+			header.Time = h[len(h)-1].Time + interval
+		}
+		h = append(h, header)
+	}
+	return h
+}
+
+func executeArkivTransaction(t *testing.T, statedb *state.StateDB, blockNumber uint64, tx *storagetx.ArkivTransaction) common.Hash {
+	t.Helper()
+
+	data, err := rlp.EncodeToBytes(tx)
+	require.NoError(t, err)
+	logs, err := storagetx.ExecuteArkivTransaction(compression.MustBrotliCompress(data), storagetx.Rules{TombstoneRetention: 1000}, blockNumber, common.Hash{byte(blockNumber)}, 0, common.HexToAddress("0x1"), statedb)
+	require.NoError(t, err)
+	require.NotEmpty(t, logs)
+	return logs[0].Topics[1]
+}
+
+func TestEntityExpiry(t *testing.T) {
+	statedb, err := state.New(types.EmptyRootHash, state.NewDatabaseForTesting())
+	require.NoError(t, err)
+
+	key := executeArkivTransaction(t, statedb, 1, &storagetx.ArkivTransaction{
+		Create: []storagetx.ArkivCreate{{BTL: 399, ContentType: "text/plain", Payload: []byte("hello")}},
+	})
+
+	// Blocks every 2 seconds
+	headers := testHeaders{}.extend(101, 2)
+	expiry := entityExpiry(statedb, headers, headers[100], key, 50)
+	require.Equal(t, EntityStatusLive, expiry.Status)
+	require.EqualValues(t, 400, *expiry.ExpiresAtBlock)
+	require.EqualValues(t, 300, *expiry.BlocksRemaining)
+	require.Equal(t, &ExpiryEstimate{
+		ExpiresAt:            hexutil.Uint64(headers[100].Time + 600),
+		AverageBlockInterval: 2000,
+		Window:               50,
+	}, expiry.Estimate)
+
+	// The estimate follows the blocks slowing down to every 4 seconds, the average
+	// mixes both intervals until the window only holds slow blocks
+	headers = headers.extend(25, 4)
+	expiry = entityExpiry(statedb, headers, headers[125], key, 50)
+	require.EqualValues(t, 275, *expiry.BlocksRemaining)
+	require.EqualValues(t, 3000, expiry.Estimate.AverageBlockInterval)
+	require.EqualValues(t, headers[125].Time+825, expiry.Estimate.ExpiresAt)
+
+	headers = headers.extend(25, 4)
+	expiry = entityExpiry(statedb, headers, headers[150], key, 50)
+	require.EqualValues(t, 250, *expiry.BlocksRemaining)
+	require.EqualValues(t, 4000, expiry.Estimate.AverageBlockInterval)
+	require.EqualValues(t, headers[150].Time+1000, expiry.Estimate.ExpiresAt)
+}
+
+func TestEntityExpiryCloseToGenesis(t *testing.T) {
+	statedb, err := state.New(types.EmptyRootHash, state.NewDatabaseForTesting())
+	require.NoError(t, err)
+
+	key := executeArkivTransaction(t, statedb, 0, &storagetx.ArkivTransaction{
+		Create: []storagetx.ArkivCreate{{BTL: 10, ContentType: "text/plain", Payload: []byte("hello")}},
+	})
+
+	// The window is shortened to the blocks since genesis
+	headers := testHeaders{}.extend(5, 2)
+	expiry := entityExpiry(statedb, headers, headers[4], key, 50)
+	require.EqualValues(t, 6, *expiry.BlocksRemaining)
+	require.EqualValues(t, 4, expiry.Estimate.Window)
+	require.EqualValues(t, headers[4].Time+12, expiry.Estimate.ExpiresAt)
+
+	// There is no estimate at genesis
+	expiry = entityExpiry(statedb, headers, headers[0], key, 50)
+	require.EqualValues(t, 10, *expiry.BlocksRemaining)
+	require.Nil(t, expiry.Estimate)
+}
+
+func TestEntityExpiryOfRemovedEntity(t *testing.T) {
+	statedb, err := state.New(types.EmptyRootHash, state.NewDatabaseForTesting())
+	require.NoError(t, err)
+
+	key := executeArkivTransaction(t, statedb, 1, &storagetx.ArkivTransaction{
+		Create: []storagetx.ArkivCreate{{BTL: 100, ContentType: "text/plain", Payload: []byte("hello")}},
+	})
+	executeArkivTransaction(t, statedb, 2, &storagetx.ArkivTransaction{Delete: []common.Hash{key}})
+
+	headers := testHeaders{}.extend(3, 2)
+	block := hexutil.Uint64(2)
+	require.Equal(t, &EntityExpiry{Status: EntityStatusDeleted, Block: &block}, entityExpiry(statedb, headers, headers[2], key, 50))
+	require.Equal(t, &EntityExpiry{Status: EntityStatusUnknown}, entityExpiry(statedb, headers, headers[2], common.HexToHash("0x01"), 50))
+}
@@ -52,6 +52,8 @@ type EthAPIBackend struct {
 	extRPCEnabled       bool
 	allowUnprotectedTxs bool
 	disableTxPool       bool
+	readOnly            bool
+	daBackpressure      *arkivDABackpressure
 	eth                 *Ethereum
 	gpo                 *gasprice.Oracle
 }
@@ -284,6 +286,11 @@ func (b *EthAPIBackend) HistoryPruningCutoff() uint64 {
 }
 
 func (b *EthAPIBackend) GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
+	if number := b.eth.blockchain.GetBlockNumber(hash); number != nil {
+		if err := arkivPrunedReceiptsError(b.eth.chainDb, hash, *number); err != nil {
+			return nil, err
+		}
+	}
 	receipts := b.eth.blockchain.GetReceiptsByHash(hash)
 
 	if len(receipts) == 0 {
@@ -320,6 +327,9 @@ func (b *EthAPIBackend) GetCanonicalReceipt(tx *types.Transaction, blockHash com
 }
 
 func (b *EthAPIBackend) GetLogs(ctx context.Context, hash common.Hash, number uint64) ([][]*types.Log, error) {
+	if err := arkivPrunedReceiptsError(b.eth.chainDb, hash, number); err != nil {
+		return nil, err
+	}
 	return rawdb.ReadLogs(b.eth.chainDb, hash, number), nil
 }
 
@@ -353,9 +363,17 @@ func (b *EthAPIBackend) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscri
 }
 
 func (b *EthAPIBackend) SendTx(ctx context.Context, signedTx *types.Transaction) error {
+	if b.readOnly {
+		return b.sendReadOnlyTx(ctx, signedTx)
+	}
 	if b.ChainConfig().IsOptimism() && signedTx.Type() == types.BlobTxType {
 		return types.ErrTxTypeNotSupported
 	}
+	if b.eth.seqRPCService == nil {
+		if err := b.checkDABacklog(signedTx); err != nil {
+			return err
+		}
+	}
 
 	// OP-Stack: forward to remote sequencer RPC
 	if b.eth.seqRPCService != nil {
@@ -382,6 +400,12 @@ func (b *EthAPIBackend) SendTx(ctx context.Context, signedTx *types.Transaction)
 
 func (b *EthAPIBackend) sendTx(ctx context.Context, signedTx *types.Transaction) error {
 	err := b.eth.txPool.Add([]*types.Transaction{signedTx}, false)[0]
+	if err == nil && b.eth.arkivIngestLatency != nil {
+		b.eth.arkivIngestLatency.submitted(signedTx)
+	}
+	if err == nil {
+		b.daBackpressure.admitted(signedTx)
+	}
 
 	// If the local transaction tracker is not configured, returns whatever
 	// returned from the txpool.
@@ -0,0 +1,55 @@
+package eth
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// aliasNotFoundError is returned for a name no alias is registered under at the block.
+type aliasNotFoundError struct {
+	name  string
+	block uint64
+}
+
+func (e *aliasNotFoundError) Error() string {
+	return fmt.Sprintf("alias %q not found at block %d", e.name, e.block)
+}
+
+// ResolveAlias returns the entity the alias with the name points to at the block, the
+// current block if atBlock is nil, with the owner and the expiry of the alias. The
+// alias is read from the state, an alias outliving its entity still resolves, with
+// EntityLive unset.
+func (api *arkivAPI) ResolveAlias(ctx context.Context, name string, atBlock *hexutil.Uint64) (_ *AliasResolution, err error) {
+	defer func() { err = arkivRPCError(err) }()
+
+	if err := api.methods.check("resolveAlias"); err != nil {
+		return nil, err
+	}
+
+	if err := entity.ValidateAliasName(name); err != nil {
+		return nil, invalidRequest("%w", err)
+	}
+	header, stateDB, err := api.headerState(atBlock)
+	if err != nil {
+		return nil, err
+	}
+	block := header.Number.Uint64()
+
+	nameHash := entity.AliasNameHash(name)
+	alias := entity.GetAlias(stateDB, nameHash)
+	if alias == nil {
+		return nil, &aliasNotFoundError{name: name, block: block}
+	}
+	return &AliasResolution{
+		Name:           name,
+		NameHash:       nameHash,
+		Key:            alias.Key,
+		Owner:          alias.Owner,
+		ExpiresAtBlock: hexutil.Uint64(alias.ExpiresAtBlock),
+		Block:          hexutil.Uint64(block),
+		EntityLive:     entity.Exists(stateDB, alias.Key),
+	}, nil
+}
@@ -0,0 +1,26 @@
+package eth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/arkiv/rpctypes"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArkivAPI_ResolveAlias(t *testing.T) {
+	ctx := context.Background()
+	api, _, _ := newContentHashesAPI(t, 3)
+
+	var rpcErr rpc.Error
+	_, err := api.ResolveAlias(ctx, "unregistered", nil)
+	require.ErrorAs(t, err, &rpcErr)
+	require.Equal(t, rpctypes.ErrCodeNotFound, rpcErr.ErrorCode())
+
+	for _, name := range []string{"", "Upper", "-dash", "spa ce"} {
+		_, err = api.ResolveAlias(ctx, name, nil)
+		require.ErrorAs(t, err, &rpcErr, "%q", name)
+		require.Equal(t, rpctypes.ErrCodeValidation, rpcErr.ErrorCode(), "%q", name)
+	}
+}
@@ -0,0 +1,168 @@
+package eth
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/arkiv/limits"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+const (
+	// DefaultArkivDABackpressureMinSize is the default calldata size in bytes from which
+	// a transaction is subject to the DA back-pressure.
+	DefaultArkivDABackpressureMinSize = 16 * 1024
+
+	// arkivDefaultBlockPeriod is the block period the retry time is estimated with
+	// when the chain has no parent block to measure it.
+	arkivDefaultBlockPeriod = 2 * time.Second
+)
+
+// ErrDABacklog is returned for the large transactions submitted while the pending
+// pool holds more DA footprint than the next blocks can include.
+var ErrDABacklog = errors.New("DA backlog of the transaction pool is saturated")
+
+var arkivDABackpressureRejectedCounter = metrics.NewRegisteredCounter("arkiv/dabackpressure/rejected", nil)
+
+// arkivDABackpressure is the admission policy of the transactions submitted over RPC
+// to the sequencer: the transactions with more than minSize bytes of calldata are
+// rejected while the DA footprint of the pending pool is more than maxBlocks times the
+// DA budget of a block.
+type arkivDABackpressure struct {
+	maxBlocks uint64
+	minSize   uint64
+
+	// blockBudget is the DA budget of a block in bytes, used unless the batcher sets
+	// one with miner_setMaxDASize.
+	blockBudget uint64
+
+	// exempt are the priority senders, never rejected.
+	exempt map[common.Address]struct{}
+
+	// footprint is the DA footprint of the pending pool in bytes as measured at the
+	// block head, plus the footprint of the transactions admitted since. The pool is
+	// only walked again once the head changes.
+	mu        sync.Mutex
+	head      common.Hash
+	footprint uint64
+}
+
+// newArkivDABackpressure returns the policy, nil if maxBlocks is 0.
+func newArkivDABackpressure(maxBlocks, minSize, blockBudget uint64, exempt []common.Address) *arkivDABackpressure {
+	if maxBlocks == 0 {
+		return nil
+	}
+	policy := &arkivDABackpressure{
+		maxBlocks:   maxBlocks,
+		minSize:     minSize,
+		blockBudget: blockBudget,
+		exempt:      make(map[common.Address]struct{}, len(exempt)),
+	}
+	for _, addr := range exempt {
+		policy.exempt[addr] = struct{}{}
+	}
+	return policy
+}
+
+// limits returns the limits of the policy, none if the policy is disabled.
+func (p *arkivDABackpressure) limits() []limits.Limit {
+	if p == nil {
+		return nil
+	}
+	return []limits.Limit{
+		{Name: "daBackpressureBlocks", Scope: limits.Node, Unit: "blocks", Value: p.maxBlocks},
+		{Name: "daBackpressureMinSize", Scope: limits.Node, Unit: "bytes", Value: p.minSize},
+	}
+}
+
+// pendingFootprint returns the DA footprint of the pending pool in bytes, measured at
+// a new head and kept up to date with the admitted transactions until the next one.
+func (p *arkivDABackpressure) pendingFootprint(head common.Hash, measure func() uint64) uint64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if head != p.head {
+		p.head = head
+		p.footprint = measure()
+	}
+	return p.footprint
+}
+
+// admitted adds the DA footprint of a transaction admitted to the pool.
+func (p *arkivDABackpressure) admitted(tx *types.Transaction) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.footprint += tx.RollupCostData().EstimatedDASize().Uint64()
+}
+
+// daBlockBudget returns the DA budget of a block in bytes, 0 if it's unknown.
+func (b *EthAPIBackend) daBlockBudget() uint64 {
+	if b.eth.miner != nil {
+		if budget := b.eth.miner.MaxDABlockSize(); budget != nil {
+			return budget.Uint64()
+		}
+	}
+	return b.daBackpressure.blockBudget
+}
+
+// blockPeriod returns the time between the last two blocks of the chain.
+func (b *EthAPIBackend) blockPeriod() time.Duration {
+	head := b.eth.blockchain.CurrentHeader()
+	parent := b.eth.blockchain.GetHeaderByHash(head.ParentHash)
+	if parent == nil || head.Time <= parent.Time {
+		return arkivDefaultBlockPeriod
+	}
+	return time.Duration(head.Time-parent.Time) * time.Second
+}
+
+// checkDABacklog rejects a large transaction while the pending pool holds more than
+// the configured number of blocks of DA footprint, telling the sender the depth of
+// the backlog and when to retry. The footprint of the transactions reaching the pool
+// from the network is only counted from the next head.
+func (b *EthAPIBackend) checkDABacklog(tx *types.Transaction) error {
+	policy := b.daBackpressure
+	if policy == nil || uint64(len(tx.Data())) < policy.minSize {
+		return nil
+	}
+	from, err := types.Sender(types.LatestSigner(b.ChainConfig()), tx)
+	if err != nil {
+		// Rejected by the pool
+		return nil
+	}
+	if _, ok := policy.exempt[from]; ok {
+		return nil
+	}
+	budget := b.daBlockBudget()
+	if budget == 0 {
+		return nil
+	}
+
+	footprint := policy.pendingFootprint(b.eth.blockchain.CurrentHeader().Hash(), func() uint64 {
+		var footprint uint64
+		for _, txs := range b.eth.txPool.Pending(txpool.PendingFilter{}) {
+			for _, ltx := range txs {
+				if ltx.DABytes != nil {
+					footprint += ltx.DABytes.Uint64()
+				}
+			}
+		}
+		return footprint
+	})
+	depth := footprint / budget
+	if depth < policy.maxBlocks {
+		return nil
+	}
+
+	arkivDABackpressureRejectedCounter.Inc(1)
+	retry := time.Duration(depth-policy.maxBlocks+1) * b.blockPeriod()
+	return fmt.Errorf("%w: %d blocks of backlog (max %d), retry in %v", ErrDABacklog, depth, policy.maxBlocks, retry)
+}
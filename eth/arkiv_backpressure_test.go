@@ -0,0 +1,88 @@
+package eth
+
+import (
+	"context"
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+func makeLargeTx(t *testing.T, nonce uint64, size int) *types.Transaction {
+	t.Helper()
+
+	data := make([]byte, size)
+	_, err := rand.Read(data)
+	require.NoError(t, err)
+	tx, err := types.SignTx(types.NewTransaction(nonce, common.Address{0x00}, big.NewInt(1000), params.TxGas+uint64(size)*params.TxTokenPerNonZeroByte*params.TxCostFloorPerToken, big.NewInt(params.GWei), data), signer, key)
+	require.NoError(t, err)
+	return tx
+}
+
+func TestDABackpressure(t *testing.T) {
+	b := initBackend(false)
+
+	// Each large transaction fills the DA budget of a block
+	txs := []*types.Transaction{makeLargeTx(t, 0, 2000), makeLargeTx(t, 1, 2000), makeLargeTx(t, 2, 2000)}
+	budget := txs[0].RollupCostData().EstimatedDASize().Uint64()
+	b.daBackpressure = newArkivDABackpressure(2, 1000, budget, nil)
+
+	require.NoError(t, b.SendTx(context.Background(), txs[0]))
+	require.NoError(t, b.SendTx(context.Background(), txs[1]))
+
+	// The pool holds two blocks of backlog
+	err := b.SendTx(context.Background(), txs[2])
+	require.ErrorIs(t, err, ErrDABacklog)
+	require.EqualError(t, err, "DA backlog of the transaction pool is saturated: 2 blocks of backlog (max 2), retry in 2s")
+	require.Nil(t, b.eth.txPool.Get(txs[2].Hash()))
+
+	// Small transactions are still admitted
+	require.NoError(t, b.SendTx(context.Background(), makeTx(2, nil, nil, key)))
+}
+
+func TestDABackpressureExempt(t *testing.T) {
+	b := initBackend(false)
+
+	txs := []*types.Transaction{makeLargeTx(t, 0, 2000), makeLargeTx(t, 1, 2000)}
+	budget := txs[0].RollupCostData().EstimatedDASize().Uint64()
+	b.daBackpressure = newArkivDABackpressure(1, 1000, budget, []common.Address{address})
+
+	// The priority senders are never rejected
+	require.NoError(t, b.SendTx(context.Background(), txs[0]))
+	require.NoError(t, b.SendTx(context.Background(), txs[1]))
+}
+
+func TestDABackpressureDisabled(t *testing.T) {
+	require.Nil(t, newArkivDABackpressure(0, 1000, 1000, nil))
+
+	// Without a DA budget the backlog can't be measured
+	b := initBackend(false)
+	b.daBackpressure = newArkivDABackpressure(1, 1000, 0, nil)
+	require.NoError(t, b.SendTx(context.Background(), makeLargeTx(t, 0, 2000)))
+	require.NoError(t, b.SendTx(context.Background(), makeLargeTx(t, 1, 2000)))
+}
+
+func TestDABackpressureFootprint(t *testing.T) {
+	policy := newArkivDABackpressure(1, 1000, 1000, nil)
+	measured := 0
+	measure := func() uint64 {
+		measured++
+		return 100
+	}
+
+	// The pool is walked once per head, the admitted transactions are added meanwhile
+	require.Equal(t, uint64(100), policy.pendingFootprint(common.Hash{0x1}, measure))
+	tx := makeLargeTx(t, 0, 2000)
+	policy.admitted(tx)
+	footprint := 100 + tx.RollupCostData().EstimatedDASize().Uint64()
+	require.Equal(t, footprint, policy.pendingFootprint(common.Hash{0x1}, measure))
+	require.Equal(t, 1, measured)
+
+	// A new head measures the pool again
+	require.Equal(t, uint64(100), policy.pendingFootprint(common.Hash{0x2}, measure))
+	require.Equal(t, 2, measured)
+}
@@ -0,0 +1,49 @@
+package eth
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// DefaultArkivTxBroadcastMaxSize is the default encoded size up to which the
+// transactions to the full broadcast recipients are sent in full to all peers.
+const DefaultArkivTxBroadcastMaxSize = 512
+
+var (
+	// fullBroadcastTxMeter counts the transactions sent in full to all peers because
+	// of their recipient, fullBroadcastSendMeter the resulting direct sends.
+	fullBroadcastTxMeter   = metrics.NewRegisteredMeter("arkiv/txbroadcast/txs", nil)
+	fullBroadcastSendMeter = metrics.NewRegisteredMeter("arkiv/txbroadcast/sends", nil)
+)
+
+// fullBroadcast reports whether the transaction is small enough and sent to one of
+// the full broadcast recipients, so that it's pushed to all the peers instead of
+// being announced to most of them, saving the round trip of the fetch.
+func (h *handler) fullBroadcast(tx *types.Transaction) bool {
+	if h.fullBroadcastMaxSize == 0 || tx.To() == nil || tx.Size() > h.fullBroadcastMaxSize {
+		return false
+	}
+	_, ok := h.fullBroadcastTo[*tx.To()]
+	return ok
+}
+
+// fullBroadcastPeers returns the peers transactions can be gossiped to.
+func (h *handler) fullBroadcastPeers(peers []*ethPeer) map[*ethPeer]struct{} {
+	allowed := make(map[*ethPeer]struct{}, len(peers))
+	for _, peer := range peers {
+		if (*ethHandler)(h).txGossipAllowed(peer.Peer.Peer) {
+			allowed[peer] = struct{}{}
+		}
+	}
+	return allowed
+}
+
+// newFullBroadcastSet returns the set of the full broadcast recipients.
+func newFullBroadcastSet(addresses []common.Address) map[common.Address]struct{} {
+	set := make(map[common.Address]struct{}, len(addresses))
+	for _, address := range addresses {
+		set[address] = struct{}{}
+	}
+	return set
+}
@@ -0,0 +1,162 @@
+package eth
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	arkivaddress "github.com/ethereum/go-ethereum/arkiv/address"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/eth/protocols/eth"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that small transactions to the full broadcast recipients are sent in full
+// to every peer allowed to receive transactions, while the others keep being
+// announced to most peers.
+func TestArkivFullBroadcast(t *testing.T) {
+	t.Parallel()
+
+	db := rawdb.NewMemoryDatabase()
+	gspec := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc:  types.GenesisAlloc{testAddr: {Balance: big.NewInt(1000000)}},
+	}
+	chain, _ := core.NewBlockChain(db, gspec, ethash.NewFaker(), nil)
+	txpool := newTestTxPool()
+
+	handler, err := newHandler(&handlerConfig{
+		Database:                 db,
+		Chain:                    chain,
+		TxPool:                   txpool,
+		Network:                  1,
+		Sync:                     ethconfig.FullSync,
+		TxGossipTrustedPeersOnly: true,
+		FullBroadcastTo:          []common.Address{arkivaddress.ArkivProcessorAddress},
+		FullBroadcastMaxSize:     DefaultArkivTxBroadcastMaxSize,
+	})
+	if err != nil {
+		t.Fatalf("failed to create handler: %v", err)
+	}
+	handler.Start(1000)
+	defer handler.Stop()
+	defer chain.Stop()
+
+	// Connect 8 sinks, the last 2 aren't trusted and can't receive transactions
+	const sinks, trusted = 8, 6
+	backends := make([]*testEthHandler, sinks)
+	for i := range sinks {
+		srcPipe, sinkPipe := p2p.MsgPipe()
+		defer srcPipe.Close()
+		defer sinkPipe.Close()
+
+		src := eth.NewPeer(eth.ETH68, p2p.NewPeerPipe(enode.ID{byte(i + 1)}, "", nil, srcPipe), srcPipe, txpool)
+		sink := eth.NewPeer(eth.ETH68, p2p.NewPeerPipe(enode.ID{0}, "", nil, sinkPipe), sinkPipe, txpool)
+		defer src.Close()
+		defer sink.Close()
+		src.Peer.TestSetTrusted(i < trusted)
+
+		go handler.runEthPeer(src, func(peer *eth.Peer) error {
+			return eth.Handle((*ethHandler)(handler), peer)
+		})
+		if err := sink.Handshake(1, chain, eth.BlockRangeUpdatePacket{}); err != nil {
+			t.Fatalf("failed to run protocol handshake: %v", err)
+		}
+		backends[i] = new(testEthHandler)
+		go eth.Handle(backends[i], sink)
+	}
+	for handler.peers.len() < sinks {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Subscribe to the announcements and broadcasts of all the sinks
+	type received struct {
+		sink     int
+		hashes   []common.Hash
+		announce bool
+	}
+	events := make(chan received, 1024)
+	for i, backend := range backends {
+		anns := make(chan []common.Hash)
+		annSub := backend.txAnnounces.Subscribe(anns)
+		defer annSub.Unsubscribe()
+
+		bcasts := make(chan []*types.Transaction)
+		bcastSub := backend.txBroadcasts.Subscribe(bcasts)
+		defer bcastSub.Unsubscribe()
+
+		go func() {
+			for {
+				select {
+				case hashes := <-anns:
+					events <- received{sink: i, hashes: hashes, announce: true}
+				case txs := <-bcasts:
+					hashes := make([]common.Hash, len(txs))
+					for j, tx := range txs {
+						hashes[j] = tx.Hash()
+					}
+					events <- received{sink: i, hashes: hashes}
+				case <-annSub.Err():
+					return
+				}
+			}
+		}()
+	}
+
+	arkivTx, _ := types.SignTx(types.NewTransaction(0, arkivaddress.ArkivProcessorAddress, big.NewInt(0), 100000, big.NewInt(0), make([]byte, 64)), types.HomesteadSigner{}, testKey)
+	plainTx, _ := types.SignTx(types.NewTransaction(1, common.Address{}, big.NewInt(0), 100000, big.NewInt(0), make([]byte, 64)), types.HomesteadSigner{}, testKey)
+	largeArkivTx, _ := types.SignTx(types.NewTransaction(2, arkivaddress.ArkivProcessorAddress, big.NewInt(0), 100000, big.NewInt(0), make([]byte, 1024)), types.HomesteadSigner{}, testKey)
+	txpool.Add([]*types.Transaction{arkivTx, plainTx, largeArkivTx}, false)
+
+	var (
+		arkivFull     = make(map[int]bool)
+		arkivAnnounce = make(map[int]bool)
+		plainAnnounce int
+		largeAnnounce int
+	)
+	for timeout := time.After(time.Second); ; {
+		select {
+		case event := <-events:
+			for _, hash := range event.hashes {
+				switch {
+				case hash == arkivTx.Hash() && event.announce:
+					arkivAnnounce[event.sink] = true
+				case hash == arkivTx.Hash():
+					arkivFull[event.sink] = true
+				case hash == plainTx.Hash() && event.announce:
+					plainAnnounce++
+				case hash == largeArkivTx.Hash() && event.announce:
+					largeAnnounce++
+				}
+			}
+			continue
+		case <-timeout:
+		}
+		break
+	}
+
+	for i := range sinks {
+		if i < trusted && !arkivFull[i] {
+			t.Errorf("sink %d: small Arkiv transaction not received in full", i)
+		}
+		if i >= trusted && arkivFull[i] {
+			t.Errorf("sink %d: small Arkiv transaction sent in full to a peer not allowed to receive transactions", i)
+		}
+		if i < trusted && arkivAnnounce[i] {
+			t.Errorf("sink %d: small Arkiv transaction announced", i)
+		}
+	}
+	if plainAnnounce == 0 {
+		t.Errorf("plain transaction not announced to any peer")
+	}
+	if largeAnnounce == 0 {
+		t.Errorf("large Arkiv transaction not announced to any peer")
+	}
+}
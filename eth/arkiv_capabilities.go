@@ -0,0 +1,49 @@
+package eth
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Capabilities returns the features of the Arkiv processor active at the time of the
+// block, at the head if atBlock is nil. It mirrors supportsFeature(bytes4) of the
+// capabilities precompile, both read the registry of params.ArkivFeatures. It also
+// lists the methods the operator disabled, and can't be disabled itself, see
+// arkivReportingMethods.
+func (api *arkivAPI) Capabilities(atBlock *hexutil.Uint64) (_ *Capabilities, err error) {
+	defer func() { err = arkivRPCError(err) }()
+
+	header := api.eth.blockchain.CurrentBlock()
+	if atBlock != nil {
+		if uint64(*atBlock) > header.Number.Uint64() {
+			return nil, invalidRequest("block is in the future: head is %d", header.Number.Uint64())
+		}
+		header = api.eth.blockchain.GetHeaderByNumber(uint64(*atBlock))
+		if header == nil {
+			return nil, fmt.Errorf("block %d not found", uint64(*atBlock))
+		}
+	}
+	config := api.eth.blockchain.Config()
+	features := config.ArkivFeaturesAt(header.Time)
+
+	result := &Capabilities{
+		Block:           hexutil.Uint64(header.Number.Uint64()),
+		Time:            hexutil.Uint64(header.Time),
+		DisabledMethods: api.methods.disabledMethods(),
+	}
+	for _, feature := range params.ArkivFeatures() {
+		capability := Capability{
+			ID:     feature.ID[:],
+			Name:   feature.Name,
+			Active: features.Supports(feature.ID),
+		}
+		if activation := feature.Activation(config); activation != nil {
+			time := hexutil.Uint64(*activation)
+			capability.ActivationTime = &time
+		}
+		result.Features = append(result.Features, capability)
+	}
+	return result, nil
+}
@@ -0,0 +1,69 @@
+package eth
+
+import (
+	"crypto/ecdsa"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/beacon"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArkivAPI_Capabilities(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	empty := func([]common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) { return nil, nil }
+	gspec, blocks, _ := newArkivTestChain(t, key, key, []arkivTestStep{empty, empty, empty})
+
+	// The two-step transfer activates at block 3, the blocks carry no transfer
+	ownershipTime := blocks[2].Time()
+	gspec.Config.ArkivOwnershipTime = &ownershipTime
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), gspec, beacon.New(ethash.NewFaker()), nil)
+	require.NoError(t, err)
+	t.Cleanup(chain.Stop)
+	_, err = chain.InsertChain(blocks)
+	require.NoError(t, err)
+	api := &arkivAPI{eth: &Ethereum{blockchain: chain}}
+
+	feature := func(capabilities *Capabilities, id params.ArkivFeature) Capability {
+		t.Helper()
+		for _, capability := range capabilities.Features {
+			if string(capability.ID) == string(id[:]) {
+				return capability
+			}
+		}
+		t.Fatalf("feature %x not found", id)
+		return Capability{}
+	}
+
+	block := hexutil.Uint64(2)
+	before, err := api.Capabilities(&block)
+	require.NoError(t, err)
+	require.Equal(t, hexutil.Uint64(2), before.Block)
+	require.Len(t, before.Features, len(params.ArkivFeatures()))
+	transfer := feature(before, params.ArkivFeatureTwoStepTransfer)
+	require.Equal(t, "arkiv.twoStepTransfer", transfer.Name)
+	require.False(t, transfer.Active)
+	require.Equal(t, hexutil.Uint64(ownershipTime), *transfer.ActivationTime)
+
+	head, err := api.Capabilities(nil)
+	require.NoError(t, err)
+	require.Equal(t, hexutil.Uint64(3), head.Block)
+	require.True(t, feature(head, params.ArkivFeatureTwoStepTransfer).Active)
+	require.True(t, feature(head, params.ArkivFeatureTombstones).Active)
+	require.True(t, feature(head, params.ArkivFeatureGasSchedule).Active)
+	upsert := feature(head, params.ArkivFeatureUpsert)
+	require.False(t, upsert.Active)
+	require.Nil(t, upsert.ActivationTime)
+
+	block = 4
+	_, err = api.Capabilities(&block)
+	var invalid *validationError
+	require.ErrorAs(t, err, &invalid)
+}
@@ -0,0 +1,213 @@
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	arkivevents "github.com/Arkiv-Network/arkiv-events"
+	"github.com/Arkiv-Network/arkiv-events/events"
+	sqlitestore "github.com/Arkiv-Network/sqlite-bitmap-store"
+	"github.com/ethereum/go-ethereum/arkiv/dbevents"
+	"github.com/ethereum/go-ethereum/arkiv/shards"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/node"
+)
+
+// openArkivStore opens the store of the node and its shards. The router is closed
+// before the store.
+func openArkivStore(nodeConfig *node.Config) (*sqlitestore.SQLiteStore, *shards.Router, error) {
+	path := nodeConfig.GolemBaseSQLStateFile
+	if path == "" {
+		path = ":memory:"
+	}
+	log.Info("Creating SQLStore", "path", path)
+	store, err := sqlitestore.NewSQLiteStore(slog.New(log.Root().Handler()), path, 7)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create sql store: %w", err)
+	}
+	shardRules, err := shards.ParseRules(nodeConfig.ArkivShards)
+	if err != nil {
+		store.Close()
+		return nil, nil, err
+	}
+	router, err := shards.New(store, path, shardRules, func(path string) (*sqlitestore.SQLiteStore, error) {
+		log.Info("Opening Arkiv store shard", "path", path)
+		return sqlitestore.NewSQLiteStore(slog.New(log.Root().Handler()), path, 7)
+	})
+	if err != nil {
+		store.Close()
+		return nil, nil, fmt.Errorf("failed to open the Arkiv store shards: %w", err)
+	}
+	return store, router, nil
+}
+
+// ArkivStoreLag is how far the Arkiv store of a stopped node is from the head of its
+// chain.
+type ArkivStoreLag struct {
+	// Path is the file of the store, empty if the store is kept in memory.
+	Path string
+	// LastBlock is the last block ingested by the store, by all its shards.
+	LastBlock uint64
+	// Head is the head block of the chain.
+	Head uint64
+	// Checkpoint is the last block handed to the store according to the chain
+	// database, nil if it has none.
+	Checkpoint *uint64
+}
+
+// Behind reports whether the store is missing blocks of the chain.
+func (l *ArkivStoreLag) Behind() bool {
+	return l.LastBlock < l.Head
+}
+
+// Ahead reports whether the store ingested blocks the chain doesn't have, it can't
+// catch up and must be rebuilt.
+func (l *ArkivStoreLag) Ahead() bool {
+	return l.LastBlock > l.Head
+}
+
+// ReadArkivStoreLag compares the Arkiv store of the node, which must be stopped, with
+// the head of the chain of the database.
+func ReadArkivStoreLag(ctx context.Context, nodeConfig *node.Config, db ethdb.Database) (*ArkivStoreLag, error) {
+	head := rawdb.ReadHeadBlock(db)
+	if head == nil {
+		return nil, errors.New("head block not found")
+	}
+	lag := &ArkivStoreLag{Path: nodeConfig.GolemBaseSQLStateFile, Head: head.NumberU64()}
+	if checkpoint := rawdb.ReadArkivEventsCheckpoint(db); checkpoint != nil {
+		lag.Checkpoint = &checkpoint.Number
+	}
+	if lag.Path == "" {
+		// The store in memory is rebuilt from the start of the chain on every start
+		return lag, nil
+	}
+
+	store, router, err := openArkivStore(nodeConfig)
+	if err != nil {
+		return nil, err
+	}
+	defer closeArkivStore(store, router)
+
+	lag.LastBlock, err = router.GetLastBlock(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last block from store: %w", err)
+	}
+	return lag, nil
+}
+
+// CatchUpArkivStore feeds the Arkiv store of the node, which must be stopped, the
+// blocks of the chain of the database after its last block, up to the head, the way
+// the node does once it's started. It's meant for the chains imported with geth
+// import, whose blocks the store never saw. The progress function is called with the
+// last block ingested after every batch. The full-text index catches up when the
+// node opens it.
+func CatchUpArkivStore(ctx context.Context, nodeConfig *node.Config, db ethdb.Database, progress func(lastBlock, head uint64)) (*ArkivStoreLag, error) {
+	lag, err := ReadArkivStoreLag(ctx, nodeConfig, db)
+	if err != nil {
+		return nil, err
+	}
+	if lag.Path == "" || !lag.Behind() {
+		return lag, nil
+	}
+
+	store, router, err := openArkivStore(nodeConfig)
+	if err != nil {
+		return nil, err
+	}
+	defer closeArkivStore(store, router)
+
+	compressedPayloads := nodeConfig.ArkivStoreCompress
+	if err := checkArkivPayloadStorage(db, lag.LastBlock, compressedPayloads); err != nil {
+		return nil, err
+	}
+	head := rawdb.ReadHeadBlock(db)
+	chainConfig := rawdb.ReadChainConfig(db, rawdb.ReadCanonicalHash(db, 0))
+	if chainConfig == nil {
+		return nil, errors.New("chain config not found")
+	}
+
+	// The iterator reads a batch per head it's notified of, it's notified of the head
+	// again until the store reaches it
+	stopping, stop := context.WithCancel(ctx)
+	defer stop()
+	hooks := dbevents.NewHooks(db, nodeConfig.ArkivHookBudget)
+	chainIterator, syncStatus := dbevents.NewChainBatchIterator(
+		db,
+		hooks,
+		lag.LastBlock,
+		nodeConfig.ArkivSkipPruned,
+		dbevents.WithBatchSize(nodeConfig.ArkivEventsBatchSize),
+		dbevents.WithConcurrency(nodeConfig.ArkivEventsConcurrency),
+		dbevents.WithContext(stopping),
+		dbevents.WithCheckpoint(),
+	)
+	batchIterator := chainIterator.Events()
+	if lag.LastBlock == 0 {
+		batchIterator = dbevents.WithGenesis(batchIterator, func() (*events.Block, error) {
+			return dbevents.GenesisBlock(db)
+		})
+	}
+	batchIterator = dbevents.VerifyContinuity(batchIterator, func() (uint64, error) {
+		return router.GetLastBlock(ctx)
+	}, syncStatus)
+	if batchIterator, err = resolveArkivContent(stopping, nodeConfig, batchIterator); err != nil {
+		return nil, err
+	}
+	if nodeConfig.ArkivPerKindOpIndex {
+		batchIterator = dbevents.PerKindOpIndexes(batchIterator)
+	}
+	if compressedPayloads {
+		batchIterator = dbevents.CompressPayloads(batchIterator)
+	}
+
+	var batchErr error
+	feed := func(yield func(arkivevents.BatchOrError) bool) {
+		for batch := range batchIterator {
+			if batch.Error != nil {
+				batchErr = batch.Error
+				return
+			}
+			if !yield(batch) {
+				return
+			}
+			last := batch.Batch.Blocks[len(batch.Batch.Blocks)-1].Number
+			progress(last, lag.Head)
+			if last >= lag.Head {
+				// The iterator writes the checkpoint of the batch and ends
+				stop()
+				continue
+			}
+			if err := hooks.OnNewBlock(chainConfig, head); err != nil {
+				batchErr = err
+				return
+			}
+		}
+	}
+	if err := hooks.OnNewBlock(chainConfig, head); err != nil {
+		return nil, err
+	}
+	if err := router.FollowEvents(ctx, feed); err != nil {
+		return nil, fmt.Errorf("failed to ingest the chain: %w", err)
+	}
+	if batchErr != nil {
+		return nil, fmt.Errorf("failed to ingest the chain: %w", batchErr)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return ReadArkivStoreLag(ctx, nodeConfig, db)
+}
+
+// closeArkivStore closes the shards of the store, then the store.
+func closeArkivStore(store *sqlitestore.SQLiteStore, router *shards.Router) {
+	if err := router.Close(); err != nil {
+		log.Error("Failed to close the Arkiv store shards", "err", err)
+	}
+	if err := store.Close(); err != nil {
+		log.Error("Failed to close the Arkiv store", "err", err)
+	}
+}
@@ -0,0 +1,79 @@
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	arkivevents "github.com/Arkiv-Network/arkiv-events"
+	"github.com/Arkiv-Network/arkiv-events/events"
+	"github.com/ethereum/go-ethereum/arkiv/dbevents"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// errCheckpointNotForced is returned when moving the events checkpoint without force.
+var errCheckpointNotForced error = &validationError{errors.New("moving the events checkpoint skips or replays blocks, set force to proceed")}
+
+// arkivAdminAPI holds the recovery methods of the arkiv namespace, they are only
+// served on the authenticated endpoints, like IPC.
+type arkivAdminAPI struct {
+	store    arkivStore
+	ingester *dbevents.Ingester
+	warmup   *arkivWarmup
+}
+
+// SetEventsCheckpoint sets the last block ingested by the store, by all its shards if
+// it's sharded, the ingestion resumes after it once the node is restarted. It recovers an ingestion halted by a gap in the
+// events at the cost of the operations of the skipped blocks, force must be set.
+func (api *arkivAdminAPI) SetEventsCheckpoint(ctx context.Context, block hexutil.Uint64, force bool) (_ *EventsCheckpoint, err error) {
+	defer func() { err = arkivRPCError(err) }()
+
+	previous, err := api.store.GetLastBlock(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last block from store: %w", err)
+	}
+	if !force {
+		return nil, fmt.Errorf("%w: last ingested block is %d", errCheckpointNotForced, previous)
+	}
+
+	// The store records the last block of every batch it ingests, the blocks it has
+	// already ingested included, so an empty block moves the checkpoint either way.
+	checkpoint := func(yield func(arkivevents.BatchOrError) bool) {
+		yield(arkivevents.BatchOrError{Batch: events.BlockBatch{Blocks: []events.Block{{Number: uint64(block)}}}})
+	}
+	for _, store := range arkivStores(api.store, "$all") {
+		if err := store.FollowEvents(ctx, checkpoint); err != nil {
+			return nil, fmt.Errorf("failed to set the events checkpoint: %w", err)
+		}
+	}
+	log.Warn("Arkiv events checkpoint moved, restart the node to resume the ingestion", "previous", previous, "block", uint64(block))
+
+	return &EventsCheckpoint{
+		Previous: hexutil.Uint64(previous),
+		Block:    block,
+	}, nil
+}
+
+// ReinjectDeadLetter ingests the batch of a file of the dead-letter queue, once the
+// issue that made the store fail to ingest it is fixed, and removes the file. An
+// ingestion halted on the batch resumes. The batches skipped with --arkiv.skip-poison
+// can't be re-injected, the store is past their blocks.
+func (api *arkivAdminAPI) ReinjectDeadLetter(ctx context.Context, file string) (_ *DeadLetter, err error) {
+	defer func() { err = arkivRPCError(err) }()
+
+	letter, err := api.ingester.Reinject(ctx, file)
+	if errors.Is(err, dbevents.ErrDeadLetterNotFound) || errors.Is(err, dbevents.ErrDeadLetterSkipped) || errors.Is(err, dbevents.ErrDeadLetterGap) {
+		return nil, &validationError{err}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &DeadLetter{
+		File:      letter.Name,
+		FromBlock: hexutil.Uint64(letter.FromBlock),
+		ToBlock:   hexutil.Uint64(letter.ToBlock),
+		Error:     letter.Error,
+		Attempts:  uint64(letter.Attempts),
+	}, nil
+}
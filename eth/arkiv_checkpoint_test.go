@@ -0,0 +1,71 @@
+package eth
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	arkivevents "github.com/Arkiv-Network/arkiv-events"
+	"github.com/Arkiv-Network/arkiv-events/events"
+	sqlitestore "github.com/Arkiv-Network/sqlite-bitmap-store"
+	"github.com/ethereum/go-ethereum/arkiv/dbevents"
+	"github.com/stretchr/testify/require"
+)
+
+// followBlocks ingests a batch of empty blocks through the continuity check.
+func followBlocks(store *sqlitestore.SQLiteStore, numbers ...uint64) error {
+	batch := arkivevents.BatchOrError{}
+	for _, number := range numbers {
+		batch.Batch.Blocks = append(batch.Batch.Blocks, events.Block{Number: number})
+	}
+	iterator := func(yield func(arkivevents.BatchOrError) bool) {
+		yield(batch)
+	}
+	lastBlock := func() (uint64, error) {
+		return store.GetLastBlock(context.Background())
+	}
+	return store.FollowEvents(context.Background(), dbevents.VerifyContinuity(iterator, lastBlock, &dbevents.SyncStatusTracker{}))
+}
+
+func TestSetEventsCheckpoint(t *testing.T) {
+	store, err := sqlitestore.NewSQLiteStore(slog.New(slog.DiscardHandler), filepath.Join(t.TempDir(), "arkiv.db"), 1)
+	require.NoError(t, err)
+	defer store.Close()
+	api := &arkivAdminAPI{store: store}
+
+	require.NoError(t, followBlocks(store, 1, 2))
+
+	// The ingestion halts on a gap
+	err = followBlocks(store, 5, 6)
+	require.ErrorContains(t, err, "events of block 5 received, expected block 3")
+	last, err := store.GetLastBlock(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), last)
+
+	// The checkpoint only moves when forced
+	_, err = api.SetEventsCheckpoint(context.Background(), 4, false)
+	require.ErrorIs(t, err, errCheckpointNotForced)
+	require.ErrorContains(t, err, "last ingested block is 2")
+	last, err = store.GetLastBlock(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), last)
+
+	checkpoint, err := api.SetEventsCheckpoint(context.Background(), 4, true)
+	require.NoError(t, err)
+	require.Equal(t, &EventsCheckpoint{Previous: 2, Block: 4}, checkpoint)
+
+	// The ingestion resumes after the new checkpoint
+	require.NoError(t, followBlocks(store, 5, 6))
+	last, err = store.GetLastBlock(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, uint64(6), last)
+
+	// The checkpoint moves back too, to replay blocks
+	checkpoint, err = api.SetEventsCheckpoint(context.Background(), 3, true)
+	require.NoError(t, err)
+	require.Equal(t, &EventsCheckpoint{Previous: 6, Block: 3}, checkpoint)
+	last, err = store.GetLastBlock(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), last)
+}
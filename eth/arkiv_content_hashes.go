@@ -0,0 +1,154 @@
+package eth
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/arkiv/limits"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// maxContentHashPairs is the largest number of pairs VerifyContentHashes checks, and of
+// the results of a notification of ContentHashVerification.
+const maxContentHashPairs = limits.MaxContentHashPairs
+
+// VerifyContentHashes checks the pairs against the payloads of the entities at the
+// block, the current block if atBlock is nil: the keccak256 hash of the payload of the
+// entity with the key of a pair must be the hash of the pair. All the pairs are checked
+// against the same state. Larger jobs go through the ContentHashVerification
+// subscription.
+func (api *arkivAPI) VerifyContentHashes(ctx context.Context, pairs []ContentHashPair, atBlock *hexutil.Uint64) (_ *ContentHashVerification, err error) {
+	defer func() { err = arkivRPCError(err) }()
+
+	if err := api.methods.check("verifyContentHashes"); err != nil {
+		return nil, err
+	}
+
+	if len(pairs) > maxContentHashPairs {
+		return nil, invalidRequest("%d pairs, more than the limit of %d, use the contentHashVerification subscription", len(pairs), maxContentHashPairs)
+	}
+	header, stateDB, err := api.headerState(atBlock)
+	if err != nil {
+		return nil, err
+	}
+	results, err := api.verifyContentHashes(ctx, stateDB, header.Number.Uint64(), pairs)
+	if err != nil {
+		return nil, err
+	}
+	return &ContentHashVerification{Block: hexutil.Uint64(header.Number.Uint64()), Results: results}, nil
+}
+
+// ContentHashVerification subscribes to the verification of the pairs, like
+// VerifyContentHashes but without a limit on the number of pairs. The results are sent
+// in order, up to maxContentHashPairs per notification, all of them against the state
+// of the block resolved at the subscription.
+func (api *arkivAPI) ContentHashVerification(ctx context.Context, pairs []ContentHashPair, atBlock *hexutil.Uint64) (_ *rpc.Subscription, err error) {
+	defer func() { err = arkivRPCError(err) }()
+
+	if err := api.methods.check("contentHashVerification"); err != nil {
+		return nil, err
+	}
+
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	header, stateDB, err := api.headerState(atBlock)
+	if err != nil {
+		return nil, err
+	}
+
+	sub := notifier.CreateSubscription()
+	block := header.Number.Uint64()
+	go func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			select {
+			case <-sub.Err():
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		for offset := 0; offset < len(pairs); offset += maxContentHashPairs {
+			chunk := pairs[offset:min(offset+maxContentHashPairs, len(pairs))]
+			results, err := api.verifyContentHashes(ctx, stateDB, block, chunk)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Warn("Failed to verify the Arkiv content hashes of a subscription", "block", block, "offset", offset, "err", err)
+				}
+				return
+			}
+			verification := &ContentHashVerification{Block: hexutil.Uint64(block), Offset: uint64(offset), Results: results}
+			if err := notifier.Notify(sub.ID, verification); err != nil {
+				return
+			}
+		}
+	}()
+	return sub, nil
+}
+
+// headerState returns the header of the block, the current block if atBlock is
+// nil, and its state.
+func (api *arkivAPI) headerState(atBlock *hexutil.Uint64) (*types.Header, *state.StateDB, error) {
+	header := api.eth.blockchain.CurrentBlock()
+	if atBlock != nil {
+		if uint64(*atBlock) > header.Number.Uint64() {
+			return nil, nil, invalidRequest("block is in the future: head is %d", header.Number.Uint64())
+		}
+		header = api.eth.blockchain.GetHeaderByNumber(uint64(*atBlock))
+		if header == nil {
+			return nil, nil, fmt.Errorf("block %d not found", uint64(*atBlock))
+		}
+	}
+	stateDB, err := api.eth.BlockChain().StateAt(header.Root)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get state: %w", err)
+	}
+	return header, stateDB, nil
+}
+
+// verifyContentHashes checks the pairs against the entities live in the state of the
+// block, whose payloads are read at the block in a single pass.
+func (api *arkivAPI) verifyContentHashes(ctx context.Context, stateDB *state.StateDB, block uint64, pairs []ContentHashPair) ([]ContentHashResult, error) {
+	results := make([]ContentHashResult, len(pairs))
+	var live []common.Hash
+	for i, pair := range pairs {
+		results[i].Key = pair.Key
+		if !entity.Exists(stateDB, pair.Key) {
+			results[i].Status = ContentHashEntityMissing
+			continue
+		}
+		live = append(live, pair.Key)
+	}
+
+	entities, err := api.readEntitiesAt(ctx, live, block)
+	if err != nil {
+		return nil, err
+	}
+	for i, pair := range pairs {
+		if results[i].Status != "" {
+			continue
+		}
+		content, ok := entities[pair.Key]
+		if !ok {
+			results[i].Status = ContentHashUnknown
+			continue
+		}
+		if hash := crypto.Keccak256Hash(content.payload); hash != pair.Hash {
+			results[i].Status = ContentHashMismatch
+			results[i].ContentHash = &hash
+			continue
+		}
+		results[i].Status = ContentHashMatch
+	}
+	return results, nil
+}
@@ -0,0 +1,168 @@
+package eth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"testing"
+	"time"
+
+	sqlitestore "github.com/Arkiv-Network/sqlite-bitmap-store"
+	"github.com/ethereum/go-ethereum/arkiv/rpctypes"
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/require"
+)
+
+// hidingStore is a store missing an entity.
+type hidingStore struct {
+	arkivStore
+	hidden common.Hash
+}
+
+func (s *hidingStore) QueryEntities(ctx context.Context, query string, options *sqlitestore.Options) (*sqlitestore.QueryResponse, error) {
+	if query == fmt.Sprintf("$key = %s", s.hidden.Hex()) {
+		return &sqlitestore.QueryResponse{}, nil
+	}
+	return s.arkivStore.QueryEntities(ctx, query, options)
+}
+
+// newContentHashesAPI returns an API over a chain where e0 and e1 are created at block
+// 1, e0 is updated at block 2 and e1 deleted at block 3, with the first indexed blocks
+// indexed, and the keys of e0 and e1.
+func newContentHashesAPI(t *testing.T, indexed int) (*arkivAPI, common.Hash, common.Hash) {
+	t.Helper()
+
+	key, _ := crypto.GenerateKey()
+	var created []common.Hash
+	steps := []arkivTestStep{
+		func([]common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			return key, &storagetx.ArkivTransaction{Create: []storagetx.ArkivCreate{
+				{BTL: 100, ContentType: "text/plain", Payload: []byte("e0")},
+				{BTL: 100, ContentType: "text/plain", Payload: []byte("e1")},
+			}}
+		},
+		func(keys []common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			return key, &storagetx.ArkivTransaction{Update: []storagetx.ArkivUpdate{
+				{EntityKey: keys[0], BTL: 100, ContentType: "text/plain", Payload: []byte("e0 v2")},
+			}}
+		},
+		func(keys []common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			created = keys
+			return key, &storagetx.ArkivTransaction{Delete: []common.Hash{keys[1]}}
+		},
+	}
+	api, _ := newArkivTestAPI(t, key, key, steps, indexed)
+	return api, created[0], created[1]
+}
+
+func TestArkivAPI_VerifyContentHashes(t *testing.T) {
+	hash := func(payload string) common.Hash {
+		return crypto.Keccak256Hash([]byte(payload))
+	}
+	at := func(block uint64) *hexutil.Uint64 {
+		return (*hexutil.Uint64)(&block)
+	}
+	ctx := context.Background()
+
+	for _, indexed := range []int{3, 1} {
+		api, e0, e1 := newContentHashesAPI(t, indexed)
+		missing := common.HexToHash("0x01")
+
+		verification, err := api.VerifyContentHashes(ctx, []ContentHashPair{
+			{Key: e0, Hash: hash("e0 v2")},
+			{Key: e0, Hash: hash("e0")},
+			{Key: e1, Hash: hash("e1")},
+			{Key: missing, Hash: hash("e0 v2")},
+		}, nil)
+		require.NoError(t, err, "%d indexed", indexed)
+		current := hash("e0 v2")
+		require.Equal(t, &ContentHashVerification{
+			Block: 3,
+			Results: []ContentHashResult{
+				{Key: e0, Status: ContentHashMatch},
+				{Key: e0, Status: ContentHashMismatch, ContentHash: &current},
+				{Key: e1, Status: ContentHashEntityMissing},
+				{Key: missing, Status: ContentHashEntityMissing},
+			},
+		}, verification, "%d indexed", indexed)
+
+		// Before the update and the deletion
+		verification, err = api.VerifyContentHashes(ctx, []ContentHashPair{
+			{Key: e0, Hash: hash("e0")},
+			{Key: e1, Hash: hash("e1")},
+		}, at(1))
+		require.NoError(t, err, "%d indexed", indexed)
+		require.Equal(t, &ContentHashVerification{
+			Block: 1,
+			Results: []ContentHashResult{
+				{Key: e0, Status: ContentHashMatch},
+				{Key: e1, Status: ContentHashMatch},
+			},
+		}, verification, "%d indexed", indexed)
+	}
+
+	t.Run("unknown hash", func(t *testing.T) {
+		api, e0, _ := newContentHashesAPI(t, 3)
+		api.store = &hidingStore{arkivStore: api.store, hidden: e0}
+		verification, err := api.VerifyContentHashes(ctx, []ContentHashPair{{Key: e0, Hash: hash("e0 v2")}}, nil)
+		require.NoError(t, err)
+		require.Equal(t, []ContentHashResult{{Key: e0, Status: ContentHashUnknown}}, verification.Results)
+	})
+
+	t.Run("limits", func(t *testing.T) {
+		api, _, _ := newContentHashesAPI(t, 3)
+		_, err := api.VerifyContentHashes(ctx, make([]ContentHashPair, maxContentHashPairs+1), nil)
+		var rpcErr rpc.Error
+		require.ErrorAs(t, err, &rpcErr)
+		require.Equal(t, rpctypes.ErrCodeValidation, rpcErr.ErrorCode())
+
+		_, err = api.VerifyContentHashes(ctx, nil, at(4))
+		require.EqualError(t, err, "block is in the future: head is 3")
+	})
+}
+
+func TestArkivAPI_ContentHashVerification(t *testing.T) {
+	api, e0, _ := newContentHashesAPI(t, 3)
+	server := rpc.NewServer()
+	t.Cleanup(server.Stop)
+	require.NoError(t, server.RegisterName("arkiv", api))
+	client := rpc.DialInProc(server)
+	t.Cleanup(client.Close)
+
+	// More pairs than fit in a notification, verified at block 2
+	pairs := make([]ContentHashPair, maxContentHashPairs+2)
+	for i := range pairs {
+		pairs[i] = ContentHashPair{Key: common.BigToHash(common.Big1), Hash: common.Hash{}}
+	}
+	pairs[0] = ContentHashPair{Key: e0, Hash: crypto.Keccak256Hash([]byte("e0 v2"))}
+	pairs[maxContentHashPairs+1] = ContentHashPair{Key: e0, Hash: crypto.Keccak256Hash([]byte("e0"))}
+	block := hexutil.Uint64(2)
+
+	ch := make(chan *ContentHashVerification, 2)
+	sub, err := client.Subscribe(context.Background(), "arkiv", ch, "contentHashVerification", pairs, &block)
+	require.NoError(t, err)
+	defer sub.Unsubscribe()
+
+	var results []ContentHashResult
+	for offset := 0; offset < len(pairs); offset += maxContentHashPairs {
+		select {
+		case verification := <-ch:
+			require.Equal(t, block, verification.Block)
+			require.Equal(t, uint64(offset), verification.Offset)
+			results = append(results, verification.Results...)
+		case err := <-sub.Err():
+			t.Fatal(err)
+		case <-time.After(10 * time.Second):
+			t.Fatal("no verification received")
+		}
+	}
+	require.Len(t, results, len(pairs))
+	current := crypto.Keccak256Hash([]byte("e0 v2"))
+	require.Equal(t, ContentHashResult{Key: e0, Status: ContentHashMatch}, results[0])
+	require.Equal(t, ContentHashEntityMissing, results[1].Status)
+	require.Equal(t, ContentHashResult{Key: e0, Status: ContentHashMismatch, ContentHash: &current}, results[len(pairs)-1])
+}
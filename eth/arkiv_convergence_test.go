@@ -0,0 +1,397 @@
+package eth
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"math/big"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	sqlitestore "github.com/Arkiv-Network/sqlite-bitmap-store"
+	arkivaddress "github.com/ethereum/go-ethereum/arkiv/address"
+	"github.com/ethereum/go-ethereum/arkiv/compression"
+	"github.com/ethereum/go-ethereum/arkiv/dbevents"
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/beacon"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip1559"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/eth/protocols/eth"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/stretchr/testify/require"
+)
+
+// arkivTestNode is a node running the Arkiv processor and the events pipeline
+// feeding the query store.
+type arkivTestNode struct {
+	db        ethdb.Database
+	chain     *core.BlockChain
+	handler   *handler
+	txpool    *testTxPool
+	store     *sqlitestore.SQLiteStore
+	storePath string
+}
+
+// newArkivTestNode opens a node on the database and the store file, the events
+// pipeline resumes from the last block of the store.
+func newArkivTestNode(t *testing.T, gspec *core.Genesis, db ethdb.Database, storePath string) *arkivTestNode {
+	t.Helper()
+
+	store, err := sqlitestore.NewSQLiteStore(slog.New(slog.NewTextHandler(io.Discard, nil)), storePath, 1)
+	require.NoError(t, err)
+
+	lastBlock, err := store.GetLastBlock(context.Background())
+	require.NoError(t, err)
+
+	hooks := dbevents.NewHooks(db, 0)
+	iterator, _ := dbevents.NewChainBatchIterator(db, hooks, lastBlock, false)
+	go store.FollowEvents(context.Background(), iterator.Events())
+
+	// Keep the state of every block, the nodes are compared block by block
+	options := core.DefaultConfig().WithArchive(true).WithStateScheme(rawdb.HashScheme)
+	chain, err := core.NewBlockChainWithHooks(db, gspec, beacon.New(ethash.NewFaker()), options, hooks)
+	require.NoError(t, err)
+
+	txpool := newTestTxPool()
+	handler, err := newHandler(&handlerConfig{
+		Database:   db,
+		Chain:      chain,
+		TxPool:     txpool,
+		Network:    1,
+		Sync:       ethconfig.FullSync,
+		BloomCache: 1,
+	})
+	require.NoError(t, err)
+	handler.Start(1000)
+
+	return &arkivTestNode{
+		db:        db,
+		chain:     chain,
+		handler:   handler,
+		txpool:    txpool,
+		store:     store,
+		storePath: storePath,
+	}
+}
+
+func (n *arkivTestNode) close() {
+	n.handler.Stop()
+	n.chain.Stop()
+	n.store.Close()
+}
+
+// connect connects the nodes with an eth protocol pipe and returns a function
+// disconnecting them.
+func (n *arkivTestNode) connect(other *arkivTestNode) func() {
+	caps := []p2p.Cap{{Name: "eth", Version: eth.ETH68}}
+
+	pipe, otherPipe := p2p.MsgPipe()
+	peer := eth.NewPeer(eth.ETH68, p2p.NewPeer(enode.ID{1}, "", caps), pipe, n.txpool)
+	otherPeer := eth.NewPeer(eth.ETH68, p2p.NewPeer(enode.ID{2}, "", caps), otherPipe, other.txpool)
+
+	go n.handler.runEthPeer(peer, func(peer *eth.Peer) error {
+		return eth.Handle((*ethHandler)(n.handler), peer)
+	})
+	go other.handler.runEthPeer(otherPeer, func(peer *eth.Peer) error {
+		return eth.Handle((*ethHandler)(other.handler), peer)
+	})
+
+	return func() {
+		peer.Close()
+		otherPeer.Close()
+		pipe.Close()
+		otherPipe.Close()
+	}
+}
+
+// syncFrom imports the chain of the other node through the downloader.
+func (n *arkivTestNode) syncFrom(t *testing.T, other *arkivTestNode) {
+	t.Helper()
+
+	disconnect := n.connect(other)
+	defer disconnect()
+
+	head := other.chain.CurrentBlock()
+	require.Eventually(t, func() bool {
+		return n.handler.peers.len() == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, n.handler.downloader.BeaconSync(ethconfig.FullSync, head, nil))
+	require.Eventually(t, func() bool {
+		return n.chain.CurrentBlock().Hash() == head.Hash()
+	}, 30*time.Second, 50*time.Millisecond)
+}
+
+// storeContents returns the entities of the store once it processed the block.
+func (n *arkivTestNode) storeContents(t *testing.T, block uint64) []json.RawMessage {
+	t.Helper()
+
+	require.Eventually(t, func() bool {
+		last, err := n.store.GetLastBlock(context.Background())
+		return err == nil && last >= block
+	}, 10*time.Second, 10*time.Millisecond)
+
+	includeAll := &sqlitestore.IncludeData{
+		Key:                         true,
+		Attributes:                  true,
+		SyntheticAttributes:         true,
+		Payload:                     true,
+		ContentType:                 true,
+		Expiration:                  true,
+		Owner:                       true,
+		CreatedAtBlock:              true,
+		LastModifiedAtBlock:         true,
+		TransactionIndexInBlock:     true,
+		OperationIndexInTransaction: true,
+	}
+
+	var entities []json.RawMessage
+	cursor := ""
+	for {
+		res, err := n.store.QueryEntities(context.Background(), "$all", &sqlitestore.Options{
+			AtBlock:     &block,
+			IncludeData: includeAll,
+			Cursor:      cursor,
+		})
+		require.NoError(t, err)
+		entities = append(entities, res.Data...)
+		if res.Cursor == nil {
+			break
+		}
+		cursor = *res.Cursor
+	}
+
+	sort.Slice(entities, func(i, j int) bool { return string(entities[i]) < string(entities[j]) })
+	return entities
+}
+
+// arkivConvergenceConfig returns the test chain config with the Arkiv tombstones and
+// the BTL reductions active, stopping at Isthmus if jovian is false.
+func arkivConvergenceConfig(jovian bool) *params.ChainConfig {
+	config := *params.OptimismTestConfig
+	if !jovian {
+		config.JovianTime = nil
+	}
+	config.ArkivTombstonesTime = new(uint64)
+	config.ArkivTombstoneRetention = 4
+	config.ArkivGasScheduleTime = new(uint64)
+	config.ArkivReduceBTLTime = new(uint64)
+	return &config
+}
+
+// l1InfoDeposit returns the L1 attributes deposit opening the block, which also
+// runs the Arkiv housekeeping.
+func l1InfoDeposit(config *params.ChainConfig, number uint64, time uint64) *types.Transaction {
+	var data []byte
+	if config.IsJovian(time) {
+		data = make([]byte, types.JovianL1AttributesLen)
+		copy(data, types.JovianL1AttributesSelector)
+	} else {
+		data = make([]byte, types.IsthmusL1AttributesLen)
+		copy(data, types.IsthmusL1AttributesSelector)
+	}
+
+	var sourceHash common.Hash
+	binary.BigEndian.PutUint64(sourceHash[24:], number)
+	to := common.HexToAddress("0x4200000000000000000000000000000000000015")
+	return types.NewTx(&types.DepositTx{
+		SourceHash: sourceHash,
+		From:       common.HexToAddress("0xdeaddeaddeaddeaddeaddeaddeaddeaddead0001"),
+		To:         &to,
+		Gas:        1_000_000,
+		Data:       data,
+	})
+}
+
+// arkivConvergenceChain generates a chain creating, updating, extending, changing
+// the owner of and deleting entities, some of which expire along the way.
+func arkivConvergenceChain(t *testing.T, config *params.ChainConfig, blocks int) (*core.Genesis, []*types.Block, []common.Hash) {
+	t.Helper()
+
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	extra := eip1559.EncodeOptimismExtraData(config, 0, 250, 6, new(uint64))
+
+	gspec := &core.Genesis{
+		Config:    config,
+		ExtraData: extra,
+		GasLimit:  60_000_000,
+		BaseFee:   big.NewInt(params.InitialBaseFee),
+		Alloc:     types.GenesisAlloc{from: {Balance: new(big.Int).Mul(big.NewInt(params.Ether), big.NewInt(1000))}},
+	}
+
+	var keys []common.Hash
+	signer := types.LatestSigner(config)
+	_, chain, receipts := core.GenerateChainWithGenesis(gspec, beacon.New(ethash.NewFaker()), blocks, func(i int, b *core.BlockGen) {
+		b.SetExtra(extra)
+		b.AddTx(l1InfoDeposit(config, b.Number().Uint64(), b.Timestamp()))
+
+		var atx *storagetx.ArkivTransaction
+		switch {
+		case i < 4:
+			atx = &storagetx.ArkivTransaction{
+				Create: []storagetx.ArkivCreate{
+					{
+						BTL:               uint64(3 + i),
+						ContentType:       "text/plain",
+						Payload:           []byte("short lived"),
+						StringAnnotations: []storagetx.StringAnnotation{{Key: "kind", Value: "short"}},
+					},
+					{
+						BTL:                uint64(100 + i),
+						ContentType:        "application/json",
+						Payload:            []byte(`{"long":"lived"}`),
+						NumericAnnotations: []storagetx.NumericAnnotation{{Key: "index", Value: uint64(i)}},
+					},
+				},
+			}
+		case i == 4:
+			atx = &storagetx.ArkivTransaction{
+				Update: []storagetx.ArkivUpdate{{
+					EntityKey:         keys[1],
+					ContentType:       "text/plain",
+					BTL:               50,
+					Payload:           []byte("updated"),
+					StringAnnotations: []storagetx.StringAnnotation{{Key: "kind", Value: "updated"}},
+				}},
+				Extend:      []storagetx.ExtendBTL{{EntityKey: keys[3], NumberOfBlocks: 10}},
+				ChangeOwner: []storagetx.ArkivChangeOwner{{EntityKey: keys[5], NewOwner: common.HexToAddress("0x1234")}},
+				Delete:      []common.Hash{keys[7]},
+			}
+		case i == blocks/2+1:
+			atx = &storagetx.ArkivTransaction{
+				Create: []storagetx.ArkivCreate{{BTL: 2, ContentType: "text/plain", Payload: []byte("after restart")}},
+				Delete: []common.Hash{keys[1]},
+			}
+		}
+		if atx == nil {
+			return
+		}
+
+		data, err := rlp.EncodeToBytes(atx)
+		require.NoError(t, err)
+		tx, err := types.SignNewTx(key, signer, &types.DynamicFeeTx{
+			ChainID:   config.ChainID,
+			Nonce:     b.TxNonce(from),
+			To:        &arkivaddress.ArkivProcessorAddress,
+			Gas:       5_000_000,
+			GasFeeCap: new(big.Int).Mul(b.BaseFee(), big.NewInt(2)),
+			GasTipCap: big.NewInt(1),
+			Data:      compression.MustBrotliCompress(data),
+		})
+		require.NoError(t, err)
+		b.AddTx(tx)
+
+		// The keys of the created entities, as derived by the processor
+		for i, create := range atx.Create {
+			keys = append(keys, crypto.Keccak256Hash(tx.Hash().Bytes(), create.Payload, common.LeftPadBytes(big.NewInt(int64(i)).Bytes(), 32)))
+		}
+	})
+
+	for i, blockReceipts := range receipts {
+		for _, receipt := range blockReceipts {
+			require.Equal(t, types.ReceiptStatusSuccessful, receipt.Status, "block %d", i+1)
+		}
+	}
+	return gspec, chain, keys
+}
+
+func TestArkivStateConvergence(t *testing.T) {
+	t.Run("isthmus", func(t *testing.T) { testArkivStateConvergence(t, false) })
+	t.Run("jovian", func(t *testing.T) { testArkivStateConvergence(t, true) })
+}
+
+// testArkivStateConvergence imports the blocks of a producer into a second node
+// over the eth protocol, restarting the importer halfway, and checks that both
+// nodes end up with the same Arkiv state and query store.
+func testArkivStateConvergence(t *testing.T, jovian bool) {
+	const blocks = 24
+
+	config := arkivConvergenceConfig(jovian)
+	gspec, chain, keys := arkivConvergenceChain(t, config, blocks)
+	require.NotEmpty(t, keys)
+
+	dir := t.TempDir()
+	producer := newArkivTestNode(t, gspec, rawdb.NewMemoryDatabase(), filepath.Join(dir, "producer.db"))
+	defer producer.close()
+
+	importerDB := rawdb.NewMemoryDatabase()
+	importer := newArkivTestNode(t, gspec, importerDB, filepath.Join(dir, "importer.db"))
+
+	// Import the first half and restart the importer, the events pipeline resumes
+	// from the checkpoint of its store
+	_, err := producer.chain.InsertChain(chain[:blocks/2])
+	require.NoError(t, err)
+	importer.syncFrom(t, producer)
+	importer.storeContents(t, blocks/2)
+	importer.close()
+
+	importer = newArkivTestNode(t, gspec, importerDB, importer.storePath)
+	defer importer.close()
+	require.EqualValues(t, blocks/2, importer.chain.CurrentBlock().Number.Uint64())
+
+	_, err = producer.chain.InsertChain(chain[blocks/2:])
+	require.NoError(t, err)
+	importer.syncFrom(t, producer)
+
+	statuses := map[string]bool{}
+	for number := uint64(1); number <= blocks; number++ {
+		produced, imported := producer.chain.GetHeaderByNumber(number), importer.chain.GetHeaderByNumber(number)
+		require.Equal(t, produced.Hash(), imported.Hash(), "block %d", number)
+
+		producedState, err := producer.chain.StateAt(produced.Root)
+		require.NoError(t, err)
+		importedState, err := importer.chain.StateAt(imported.Root)
+		require.NoError(t, err)
+		require.Equal(t,
+			producedState.GetStorageRoot(arkivaddress.ArkivProcessorAddress),
+			importedState.GetStorageRoot(arkivaddress.ArkivProcessorAddress),
+			"processor storage root at block %d", number,
+		)
+
+		for _, key := range keys {
+			metaData := entityMetaData(importedState, key)
+			require.Equal(t, entityMetaData(producedState, key), metaData, "entity %s at block %d", key, number)
+			statuses[metaData.Status] = true
+		}
+	}
+
+	// The entities went through every status, and the tombstone of the entity
+	// deleted in block 5 was swept
+	for _, status := range []string{EntityStatusLive, EntityStatusDeleted, EntityStatusExpired} {
+		require.True(t, statuses[status], "no %s entity", status)
+	}
+	head, err := importer.chain.StateAt(importer.chain.CurrentBlock().Root)
+	require.NoError(t, err)
+	require.Equal(t, EntityStatusUnknown, entityMetaData(head, keys[7]).Status)
+
+	producedEntities := producer.storeContents(t, blocks)
+	require.NotEmpty(t, producedEntities)
+	require.Equal(t, producedEntities, importer.storeContents(t, blocks))
+
+	// Both nodes draw the same samples
+	producerAPI := &arkivAPI{eth: &Ethereum{blockchain: producer.chain}, store: producer.store}
+	importerAPI := &arkivAPI{eth: &Ethereum{blockchain: importer.chain}, store: importer.store}
+	for number := uint64(1); number <= blocks; number++ {
+		options := &SampleOptions{AtBlock: (*hexutil.Uint64)(&number), IncludeMetaData: true}
+		produced, err := producerAPI.SampleEntities(context.Background(), 3, hexutil.Bytes("seed"), options)
+		require.NoError(t, err, "block %d", number)
+		imported, err := importerAPI.SampleEntities(context.Background(), 3, hexutil.Bytes("seed"), options)
+		require.NoError(t, err, "block %d", number)
+		require.Equal(t, produced, imported, "block %d", number)
+	}
+}
@@ -0,0 +1,74 @@
+package eth
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/arkiv/limits"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity/entityexpiration"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// maxEntitiesToExpireBlocks is the largest range of blocks GetEntitiesToExpire walks.
+const maxEntitiesToExpireBlocks = limits.MaxEntitiesToExpireBlocks
+
+// GetEntitiesToExpire returns the keys of the entities expiring from fromBlock to
+// toBlock, both included, grouped by the block they expire at so their BTL can be
+// extended in batches. The sets of the entities expiring at each block are read from
+// the state of the current block: the blocks up to the current one have been swept
+// already and have none. If owner is set, only its entities are returned.
+func (api *arkivAPI) GetEntitiesToExpire(ctx context.Context, fromBlock hexutil.Uint64, toBlock hexutil.Uint64, owner *common.Address) (_ *EntitiesToExpire, err error) {
+	defer func() { err = arkivRPCError(err) }()
+
+	if err := api.methods.check("getEntitiesToExpire"); err != nil {
+		return nil, err
+	}
+
+	from, to := uint64(fromBlock), uint64(toBlock)
+	if from > to {
+		return nil, invalidRequest("fromBlock %d is after toBlock %d", from, to)
+	}
+	if to-from >= maxEntitiesToExpireBlocks {
+		return nil, invalidRequest("range of %d blocks exceeds the limit of %d blocks", to-from+1, maxEntitiesToExpireBlocks)
+	}
+	header, stateDB, err := api.headerState(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &EntitiesToExpire{
+		Block:     hexutil.Uint64(header.Number.Uint64()),
+		FromBlock: fromBlock,
+		ToBlock:   toBlock,
+		Owner:     owner,
+		Blocks:    []ExpiringBlock{},
+	}
+	for block := from; ; block++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		var keys []common.Hash
+		for key := range entityexpiration.IteratorOfEntitiesToExpireAtBlock(stateDB, block) {
+			if owner != nil {
+				md, err := entity.GetEntityMetaData(stateDB, key)
+				if err != nil {
+					return nil, fmt.Errorf("entity %s expiring at block %d: %w", key.Hex(), block, err)
+				}
+				if md.Owner != *owner {
+					continue
+				}
+			}
+			keys = append(keys, key)
+		}
+		if len(keys) > 0 {
+			result.Blocks = append(result.Blocks, ExpiringBlock{Block: hexutil.Uint64(block), Keys: keys})
+		}
+		// The range may end at the largest block number
+		if block == to {
+			break
+		}
+	}
+	return result, nil
+}
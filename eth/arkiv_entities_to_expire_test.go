@@ -0,0 +1,80 @@
+package eth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/arkiv/rpctypes"
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArkivAPI_GetEntitiesToExpire(t *testing.T) {
+	keyA, _ := crypto.GenerateKey()
+	keyB, _ := crypto.GenerateKey()
+	a := crypto.PubkeyToAddress(keyA.PublicKey)
+	create := func(payload string, btl uint64) storagetx.ArkivCreate {
+		return storagetx.ArkivCreate{BTL: btl, ContentType: "text/plain", Payload: []byte(payload)}
+	}
+	var created []common.Hash
+	steps := []arkivTestStep{
+		// Block 1: A creates e0 and e1 expiring at block 11 and e2 at block 21
+		func([]common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			return keyA, &storagetx.ArkivTransaction{Create: []storagetx.ArkivCreate{create("e0", 10), create("e1", 10), create("e2", 20)}}
+		},
+		// Block 2: B creates e3 expiring at block 12
+		func([]common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			return keyB, &storagetx.ArkivTransaction{Create: []storagetx.ArkivCreate{create("e3", 10)}}
+		},
+		// Block 3: nothing
+		func(keys []common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			created = keys
+			return nil, nil
+		},
+	}
+	api, _ := newArkivTestAPI(t, keyA, keyB, steps, len(steps))
+
+	result, err := api.GetEntitiesToExpire(context.Background(), 1, 30, nil)
+	require.NoError(t, err)
+	require.Equal(t, &EntitiesToExpire{
+		Block:     3,
+		FromBlock: 1,
+		ToBlock:   30,
+		Blocks: []ExpiringBlock{
+			{Block: 11, Keys: []common.Hash{created[0], created[1]}},
+			{Block: 12, Keys: []common.Hash{created[3]}},
+			{Block: 21, Keys: []common.Hash{created[2]}},
+		},
+	}, result)
+
+	t.Run("owner", func(t *testing.T) {
+		result, err := api.GetEntitiesToExpire(context.Background(), 11, 20, &a)
+		require.NoError(t, err)
+		require.Equal(t, &a, result.Owner)
+		require.Equal(t, []ExpiringBlock{{Block: 11, Keys: []common.Hash{created[0], created[1]}}}, result.Blocks)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		result, err := api.GetEntitiesToExpire(context.Background(), 13, 20, nil)
+		require.NoError(t, err)
+		require.Empty(t, result.Blocks)
+	})
+
+	t.Run("limits", func(t *testing.T) {
+		var rpcErr rpc.Error
+		_, err := api.GetEntitiesToExpire(context.Background(), 1, maxEntitiesToExpireBlocks+1, nil)
+		require.ErrorAs(t, err, &rpcErr)
+		require.Equal(t, rpctypes.ErrCodeValidation, rpcErr.ErrorCode())
+
+		_, err = api.GetEntitiesToExpire(context.Background(), 1, maxEntitiesToExpireBlocks, nil)
+		require.NoError(t, err)
+
+		_, err = api.GetEntitiesToExpire(context.Background(), 2, 1, nil)
+		require.ErrorAs(t, err, &rpcErr)
+		require.Equal(t, rpctypes.ErrCodeValidation, rpcErr.ErrorCode())
+	})
+}
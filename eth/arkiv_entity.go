@@ -0,0 +1,165 @@
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	sqlitestore "github.com/Arkiv-Network/sqlite-bitmap-store"
+	"github.com/ethereum/go-ethereum/arkiv/rpctypes"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// EntityNotFoundError is returned for a key that doesn't hold a live entity at the
+// block. The data of the error is the status of the key, like arkiv_getEntityMetaData.
+type EntityNotFoundError struct {
+	Key    common.Hash
+	Block  uint64
+	Status *EntityMetaData
+}
+
+func (e *EntityNotFoundError) Error() string {
+	switch e.Status.Status {
+	case EntityStatusDeleted, EntityStatusExpired:
+		return fmt.Sprintf("entity %s not found at block %d: %s at block %d", e.Key.Hex(), e.Block, e.Status.Status, uint64(*e.Status.Block))
+	}
+	return fmt.Sprintf("entity %s not found at block %d", e.Key.Hex(), e.Block)
+}
+
+func (e *EntityNotFoundError) ErrorCode() int { return rpctypes.ErrCodeNotFound }
+
+func (e *EntityNotFoundError) ErrorData() interface{} { return e.Status }
+
+// GetEntity returns the entity with the key at the block, the current block if atBlock
+// is nil. The owner and the expiry are read from the state at the block, the content
+// from the store, or rebuilt from the operations of the chain when the store doesn't
+// hold the entity as it was at the block. It returns an EntityNotFoundError if the
+// entity isn't live at the block.
+func (api *arkivAPI) GetEntity(ctx context.Context, key common.Hash, atBlock *hexutil.Uint64) (_ *Entity, err error) {
+	defer func() { err = arkivRPCError(err) }()
+
+	if err := api.methods.check("getEntity"); err != nil {
+		return nil, err
+	}
+
+	header := api.eth.blockchain.CurrentBlock()
+	if atBlock != nil {
+		if uint64(*atBlock) > header.Number.Uint64() {
+			return nil, invalidRequest("block is in the future: head is %d", header.Number.Uint64())
+		}
+		header = api.eth.blockchain.GetHeaderByNumber(uint64(*atBlock))
+		if header == nil {
+			return nil, fmt.Errorf("block %d not found", uint64(*atBlock))
+		}
+	}
+	block := header.Number.Uint64()
+	stateDB, err := api.eth.BlockChain().StateAt(header.Root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get state: %w", err)
+	}
+	md, err := entity.GetEntityMetaData(stateDB, key)
+	if errors.Is(err, entity.ErrEntityNotFound) {
+		return nil, &EntityNotFoundError{Key: key, Block: block, Status: entityMetaData(stateDB, key)}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := api.entityAt(ctx, key, block)
+	if err != nil {
+		return nil, err
+	}
+	if content == nil {
+		return nil, fmt.Errorf("entity %s is live at block %d but missing from the store", key.Hex(), block)
+	}
+	attributes := sqlitestore.IncludeData{Attributes: true}
+	return &Entity{
+		Key:               key,
+		Owner:             md.Owner,
+		ExpiresAtBlock:    hexutil.Uint64(md.ExpiresAtBlock),
+		ContentType:       content.contentType,
+		StringAttributes:  filterEntityAttributes(content.strs, attributes),
+		NumericAttributes: filterEntityAttributes(content.nums, attributes),
+		Payload:           content.payload,
+		Block:             hexutil.Uint64(block),
+	}, nil
+}
+
+// entityAt returns the entity with the key as it was at the block, nil if it didn't
+// exist, see readEntitiesAt.
+func (api *arkivAPI) entityAt(ctx context.Context, key common.Hash, block uint64) (*overlayEntity, error) {
+	entities, err := api.readEntitiesAt(ctx, []common.Hash{key}, block)
+	if err != nil {
+		return nil, err
+	}
+	return entities[key], nil
+}
+
+// readEntitiesAt returns the entities with the keys as they were at the block, without
+// the ones that didn't exist. The store holds the entities as of its last indexed
+// block: the operations of the blocks it hasn't indexed yet are applied on top of it,
+// and the entities changed since the block are rebuilt from the chain. The blocks in
+// between are decoded once for all the keys.
+func (api *arkivAPI) readEntitiesAt(ctx context.Context, keys []common.Hash, block uint64) (map[common.Hash]*overlayEntity, error) {
+	lastIndexed, err := api.store.GetLastBlock(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last block from store: %w", err)
+	}
+	from, to := min(block, lastIndexed)+1, max(block, lastIndexed)
+	if to-min(block, lastIndexed) > arkivMaxRewindBlocks {
+		return nil, notIndexed(block, lastIndexed, "block %d is more than %d blocks away from the last indexed block %d", block, arkivMaxRewindBlocks, lastIndexed)
+	}
+
+	overlay := &arkivOverlay{
+		indexedBlock: lastIndexed,
+		entities:     map[common.Hash]*overlayEntity{},
+	}
+	overlay.base = func(key common.Hash) (*overlayEntity, error) {
+		return storeEntity(ctx, api.store, api.compressedPayloads, key, lastIndexed)
+	}
+	wanted := make(map[common.Hash]struct{}, len(keys))
+	for _, key := range keys {
+		wanted[key] = struct{}{}
+	}
+	changed := map[common.Hash]struct{}{}
+	for number := from; number <= to; number++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		decoded, err := api.blockOperations(number)
+		if err != nil {
+			return nil, err
+		}
+		if block < lastIndexed {
+			for _, operation := range decoded.Operations {
+				if _, ok := wanted[operationKey(operation)]; ok {
+					changed[operationKey(operation)] = struct{}{}
+				}
+			}
+			continue
+		}
+		if err := overlay.apply(number, decoded.Operations, EntityProvenanceHeadOverlay); err != nil {
+			return nil, err
+		}
+	}
+
+	entities, err := api.entitiesAt(ctx, changed, block)
+	if err != nil {
+		return nil, err
+	}
+	for key := range wanted {
+		if _, ok := changed[key]; ok {
+			continue
+		}
+		entity, err := overlay.lookup(key)
+		if err != nil {
+			return nil, err
+		}
+		if entity != nil {
+			entities[key] = entity
+		}
+	}
+	return entities, nil
+}
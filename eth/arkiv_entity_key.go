@@ -0,0 +1,26 @@
+package eth
+
+import (
+	"github.com/ethereum/go-ethereum/arkiv/limits"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// ComputeEntityKey returns the key of the entity created by the create at opIndex of
+// the Create operations of the transaction txHash, with the payload, the way the
+// processor derives it, see entity.DeriveEntityKey. The hash of a signed transaction is
+// known before it's sent, so the keys of its entities are known before its receipt.
+// The key doesn't depend on the state: a deduplicated create, see Idempotency Keys,
+// doesn't create the entity of the key.
+func (api *arkivAPI) ComputeEntityKey(txHash common.Hash, opIndex hexutil.Uint64, payload hexutil.Bytes) (_ common.Hash, err error) {
+	defer func() { err = arkivRPCError(err) }()
+
+	if err := api.methods.check("computeEntityKey"); err != nil {
+		return common.Hash{}, err
+	}
+	if opIndex >= limits.MaxOperations {
+		return common.Hash{}, invalidRequest("operation index %d is out of range, a transaction has at most %d operations", opIndex, limits.MaxOperations)
+	}
+	return entity.DeriveEntityKey(txHash, int(opIndex), payload), nil
+}
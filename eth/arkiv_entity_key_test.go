@@ -0,0 +1,41 @@
+package eth
+
+import (
+	"crypto/ecdsa"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/arkiv/logs"
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArkivAPI_ComputeEntityKey(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	creates := []storagetx.ArkivCreate{
+		{BTL: 100, ContentType: "text/plain", Payload: []byte("first")},
+		{BTL: 100, ContentType: "text/plain", Payload: []byte("second")},
+		{BTL: 100, ContentType: "text/plain"},
+	}
+	_, chainBlocks, receipts := newArkivTestChain(t, key, key, []arkivTestStep{
+		func([]common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			return key, &storagetx.ArkivTransaction{Create: creates}
+		},
+	})
+	api := &arkivAPI{}
+
+	// The keys match the ArkivEntityCreated logs of the entities the chain created
+	tx, receipt := chainBlocks[0].Transactions()[1], receipts[0][1]
+	require.Len(t, receipt.Logs, len(creates))
+	for i, create := range creates {
+		require.Equal(t, logs.ArkivEntityCreated, receipt.Logs[i].Topics[0])
+		computed, err := api.ComputeEntityKey(tx.Hash(), hexutil.Uint64(i), create.Payload)
+		require.NoError(t, err)
+		require.Equal(t, receipt.Logs[i].Topics[1], computed)
+	}
+
+	_, err := api.ComputeEntityKey(tx.Hash(), 1000, nil)
+	require.ErrorContains(t, err, "out of range")
+}
@@ -0,0 +1,141 @@
+package eth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"testing"
+
+	sqlitestore "github.com/Arkiv-Network/sqlite-bitmap-store"
+	"github.com/ethereum/go-ethereum/arkiv/rpctypes"
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArkivAPI_GetEntity(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	owner := crypto.PubkeyToAddress(key.PublicKey)
+
+	kind := func(value string) []storagetx.StringAnnotation {
+		return []storagetx.StringAnnotation{{Key: "kind", Value: value}}
+	}
+	var created []common.Hash
+	steps := []arkivTestStep{
+		// Block 1: e0 and e1 are created
+		func([]common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			return key, &storagetx.ArkivTransaction{Create: []storagetx.ArkivCreate{
+				{BTL: 100, ContentType: "text/plain", Payload: []byte("e0"), StringAnnotations: kind("x"), NumericAnnotations: []storagetx.NumericAnnotation{{Key: "size", Value: 1}}},
+				{BTL: 100, ContentType: "text/plain", Payload: []byte("e1"), StringAnnotations: kind("x")},
+			}}
+		},
+		// Block 2: e0 is updated
+		func(keys []common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			return key, &storagetx.ArkivTransaction{Update: []storagetx.ArkivUpdate{
+				{EntityKey: keys[0], BTL: 50, ContentType: "application/json", Payload: []byte(`{"e0":2}`), StringAnnotations: kind("y")},
+			}}
+		},
+		// Block 3: e1 is deleted
+		func(keys []common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			created = keys
+			return key, &storagetx.ArkivTransaction{Delete: []common.Hash{keys[1]}}
+		},
+	}
+
+	for _, indexed := range []int{len(steps), 1} {
+		api, _ := newArkivTestAPI(t, key, key, steps, indexed)
+		e0, e1 := created[0], created[1]
+		ctx := context.Background()
+		at := func(block uint64) *hexutil.Uint64 {
+			return (*hexutil.Uint64)(&block)
+		}
+
+		entity, err := api.GetEntity(ctx, e0, nil)
+		require.NoError(t, err, "%d indexed", indexed)
+		require.Equal(t, &Entity{
+			Key:               e0,
+			Owner:             owner,
+			ExpiresAtBlock:    52,
+			ContentType:       "application/json",
+			StringAttributes:  map[string]string{"kind": "y"},
+			NumericAttributes: map[string]uint64{},
+			Payload:           []byte(`{"e0":2}`),
+			Block:             3,
+		}, entity, "%d indexed", indexed)
+
+		// Before the update
+		entity, err = api.GetEntity(ctx, e0, at(1))
+		require.NoError(t, err)
+		require.Equal(t, &Entity{
+			Key:               e0,
+			Owner:             owner,
+			ExpiresAtBlock:    101,
+			ContentType:       "text/plain",
+			StringAttributes:  map[string]string{"kind": "x"},
+			NumericAttributes: map[string]uint64{"size": 1},
+			Payload:           []byte("e0"),
+			Block:             1,
+		}, entity, "%d indexed", indexed)
+
+		entity, err = api.GetEntity(ctx, e1, at(2))
+		require.NoError(t, err)
+		require.Equal(t, []byte("e1"), []byte(entity.Payload))
+
+		_, err = api.GetEntity(ctx, e1, nil)
+		var notFound *EntityNotFoundError
+		require.ErrorAs(t, err, &notFound)
+		require.Equal(t, rpctypes.ErrCodeNotFound, notFound.ErrorCode())
+		require.Equal(t, EntityStatusDeleted, notFound.Status.Status)
+		require.EqualError(t, err, "entity "+e1.Hex()+" not found at block 3: deleted at block 3")
+
+		_, err = api.GetEntity(ctx, common.HexToHash("0x01"), nil)
+		require.EqualError(t, err, "entity "+common.HexToHash("0x01").Hex()+" not found at block 3")
+
+		_, err = api.GetEntity(ctx, e0, at(4))
+		require.EqualError(t, err, "block is in the future: head is 3")
+	}
+}
+
+func TestArkivStore_ReducedThenExtendedExpiry(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+
+	var created []common.Hash
+	steps := []arkivTestStep{
+		// Block 1: e0 is created, expiring at block 101
+		func([]common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			return key, &storagetx.ArkivTransaction{Create: []storagetx.ArkivCreate{{BTL: 100, ContentType: "text/plain", Payload: []byte("e0")}}}
+		},
+		// Block 2: e0 is brought forward to block 41
+		func(keys []common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			return key, &storagetx.ArkivTransaction{
+				Version:   storagetx.TransactionVersionReduceBTL,
+				ReduceBTL: []storagetx.ArkivReduceBTL{{EntityKey: keys[0], NumberOfBlocks: 60}},
+			}
+		},
+		// Block 3: e0 is extended to block 61
+		func(keys []common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			created = keys
+			return key, &storagetx.ArkivTransaction{Extend: []storagetx.ExtendBTL{{EntityKey: keys[0], NumberOfBlocks: 20}}}
+		},
+	}
+	api, _ := newArkivTestAPI(t, key, key, steps, len(steps))
+
+	// The store has the expiry of the chain after every block
+	for block, want := range map[uint64]uint64{1: 101, 2: 41, 3: 61} {
+		response, err := api.store.QueryEntities(context.Background(), "$key = "+created[0].Hex(), &sqlitestore.Options{
+			AtBlock:     &block,
+			IncludeData: &sqlitestore.IncludeData{Expiration: true},
+		})
+		require.NoError(t, err)
+		require.Len(t, response.Data, 1, "block %d", block)
+		var ed sqlitestore.EntityData
+		require.NoError(t, json.Unmarshal(response.Data[0], &ed))
+		require.Equal(t, want, *ed.ExpiresAt, "block %d", block)
+
+		entity, err := api.GetEntity(context.Background(), created[0], (*hexutil.Uint64)(&block))
+		require.NoError(t, err)
+		require.Equal(t, hexutil.Uint64(want), entity.ExpiresAtBlock, "block %d", block)
+	}
+}
@@ -0,0 +1,88 @@
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/arkiv/rpctypes"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// validationError is a request of the arkiv namespace rejected as invalid.
+type validationError struct{ err error }
+
+func (e *validationError) Error() string { return e.err.Error() }
+
+func (e *validationError) Unwrap() error { return e.err }
+
+// invalidRequest returns a validationError with the formatted message.
+func invalidRequest(format string, args ...any) error {
+	return &validationError{fmt.Errorf(format, args...)}
+}
+
+// notIndexedError is a request for a block the store can't answer for.
+type notIndexedError struct {
+	block       uint64
+	lastIndexed uint64
+	err         error
+}
+
+func (e *notIndexedError) Error() string { return e.err.Error() }
+
+// notIndexed returns a notIndexedError for the block with the formatted message.
+func notIndexed(block, lastIndexed uint64, format string, args ...any) error {
+	return &notIndexedError{block: block, lastIndexed: lastIndexed, err: fmt.Errorf(format, args...)}
+}
+
+// arkivError is an error of the arkiv namespace with its JSON-RPC code and data.
+type arkivError struct {
+	code int
+	err  error
+	data interface{}
+}
+
+func (e *arkivError) Error() string { return e.err.Error() }
+
+func (e *arkivError) Unwrap() error { return e.err }
+
+func (e *arkivError) ErrorCode() int { return e.code }
+
+func (e *arkivError) ErrorData() interface{} { return e.data }
+
+// arkivRPCError returns the error of an arkiv method with the code of its failure, see
+// rpctypes.ErrCodeNotFound and the codes after it. The message is the message of the
+// whole error, which it wraps. The errors clients can't act on are returned unchanged,
+// with the generic code.
+func arkivRPCError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var (
+		notFound  *EntityNotFoundError
+		noAlias   *aliasNotFoundError
+		unindexed *notIndexedError
+		invalid   *validationError
+	)
+	switch {
+	case errors.As(err, &notFound):
+		return &arkivError{code: rpctypes.ErrCodeNotFound, err: err, data: notFound.Status}
+	case errors.As(err, &noAlias), errors.Is(err, entity.ErrEntityNotFound):
+		return &arkivError{code: rpctypes.ErrCodeNotFound, err: err}
+	case errors.As(err, &unindexed):
+		return &arkivError{code: rpctypes.ErrCodeNotIndexed, err: err, data: &rpctypes.NotIndexedErrorData{
+			Block:            hexutil.Uint64(unindexed.block),
+			LastIndexedBlock: hexutil.Uint64(unindexed.lastIndexed),
+		}}
+	case errors.As(err, &invalid):
+		return &arkivError{code: rpctypes.ErrCodeValidation, err: err}
+	case errors.Is(err, errMethodDisabled):
+		return &arkivError{code: rpctypes.ErrCodeMethodDisabled, err: err}
+	case errors.Is(err, errQueryMemoryBudget):
+		return &arkivError{code: rpctypes.ErrCodeConcurrencyLimit, err: err}
+	case errors.Is(err, context.DeadlineExceeded):
+		return &arkivError{code: rpctypes.ErrCodeStoreBusy, err: err}
+	}
+	return err
+}
@@ -0,0 +1,125 @@
+package eth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	sqlitestore "github.com/Arkiv-Network/sqlite-bitmap-store"
+	"github.com/ethereum/go-ethereum/arkiv/dbevents"
+	"github.com/ethereum/go-ethereum/arkiv/rpctypes"
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArkivAPI_ErrorCodes(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	owner := crypto.PubkeyToAddress(key.PublicKey)
+	steps := []arkivTestStep{
+		// Block 1: e0 is created
+		func([]common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			return key, &storagetx.ArkivTransaction{Create: []storagetx.ArkivCreate{{BTL: 100, ContentType: "text/plain", Payload: []byte("e0")}}}
+		},
+		func([]common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) { return nil, nil },
+		func([]common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) { return nil, nil },
+	}
+	// The store indexed block 1 only
+	api, _ := newArkivTestAPI(t, key, key, steps, 1)
+	ctx := context.Background()
+
+	// The code is read by the RPC server from the returned error itself
+	requireCode := func(t *testing.T, err error, code int) rpc.DataError {
+		t.Helper()
+		require.Error(t, err)
+		rpcErr, ok := err.(rpc.Error)
+		require.True(t, ok, "%T is not an rpc.Error", err)
+		require.Equal(t, code, rpcErr.ErrorCode(), err.Error())
+		dataErr, _ := err.(rpc.DataError)
+		return dataErr
+	}
+
+	t.Run("NotFound", func(t *testing.T) {
+		_, err := api.GetEntity(ctx, common.HexToHash("0x01"), nil)
+		data := requireCode(t, err, rpctypes.ErrCodeNotFound)
+		require.Equal(t, &EntityMetaData{Status: EntityStatusUnknown}, data.ErrorData())
+		var notFound *EntityNotFoundError
+		require.ErrorAs(t, err, &notFound)
+
+		// The missing entities of the state keep the message of the error
+		wrapped := fmt.Errorf("entity %s expiring at block 5: %w", common.HexToHash("0x01").Hex(), entity.ErrEntityNotFound)
+		err = arkivRPCError(wrapped)
+		requireCode(t, err, rpctypes.ErrCodeNotFound)
+		require.ErrorIs(t, err, entity.ErrEntityNotFound)
+		require.Equal(t, wrapped.Error(), err.Error())
+	})
+
+	t.Run("NotIndexed", func(t *testing.T) {
+		_, err := api.GetOwnerUsageReport(ctx, owner, 3, 3)
+		data := requireCode(t, err, rpctypes.ErrCodeNotIndexed)
+		require.Equal(t, &rpctypes.NotIndexedErrorData{Block: 2, LastIndexedBlock: 1}, data.ErrorData())
+		encoded, err := json.Marshal(data.ErrorData())
+		require.NoError(t, err)
+		require.JSONEq(t, `{"block":"0x2","lastIndexedBlock":"0x1"}`, string(encoded))
+	})
+
+	t.Run("StoreBusy", func(t *testing.T) {
+		expired, cancel := context.WithDeadline(ctx, time.Now().Add(-time.Second))
+		defer cancel()
+		_, err := api.Query(expired, "$all", &QueryOptions{})
+		requireCode(t, err, rpctypes.ErrCodeStoreBusy)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("ConcurrencyLimit", func(t *testing.T) {
+		// Another query holds all the budget but a byte
+		api := *api
+		api.memory = newArkivQueryMemoryBudget(1<<20, 0)
+		release, err := api.memory.reserve(ctx, 1<<20-1)
+		require.NoError(t, err)
+		defer release()
+
+		_, err = api.Query(ctx, "$all", &QueryOptions{AllowFullScan: true})
+		requireCode(t, err, rpctypes.ErrCodeConcurrencyLimit)
+		require.ErrorIs(t, err, errQueryMemoryBudget)
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		_, err := api.Query(ctx, `kind = `, &QueryOptions{})
+		requireCode(t, err, rpctypes.ErrCodeValidation)
+
+		_, err = api.Query(ctx, "$all", &QueryOptions{Options: sqlitestore.Options{Cursor: "0x01"}})
+		requireCode(t, err, rpctypes.ErrCodeValidation)
+
+		_, err = api.QueryDiff(ctx, "$all", 2, 1, nil)
+		requireCode(t, err, rpctypes.ErrCodeValidation)
+
+		future := hexutil.Uint64(10)
+		_, err = api.GetEntity(ctx, common.HexToHash("0x01"), &future)
+		requireCode(t, err, rpctypes.ErrCodeValidation)
+
+		_, err = api.GetProcessorLogs(ctx, 0, 1, &ProcessorLogsOptions{Kinds: []string{"unknown"}})
+		requireCode(t, err, rpctypes.ErrCodeValidation)
+
+		ingester, err := dbevents.NewIngester(dbevents.IngesterConfig{}, nil, nil, &dbevents.SyncStatusTracker{})
+		require.NoError(t, err)
+		admin := &arkivAdminAPI{ingester: ingester}
+		_, err = admin.ReinjectDeadLetter(ctx, "../blocks-1-1.json")
+		requireCode(t, err, rpctypes.ErrCodeValidation)
+		require.ErrorIs(t, err, dbevents.ErrDeadLetterNotFound)
+	})
+
+	t.Run("Internal", func(t *testing.T) {
+		// The other errors keep the generic code
+		require.Nil(t, arkivRPCError(nil))
+		_, ok := arkivRPCError(context.Canceled).(rpc.Error)
+		require.False(t, ok)
+	})
+}
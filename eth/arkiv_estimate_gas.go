@@ -0,0 +1,98 @@
+package eth
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/arkiv/compression"
+	"github.com/ethereum/go-ethereum/arkiv/statediff"
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// EstimateStorageGas compresses the Arkiv transaction with brotli and runs it on top of
+// the state of the current block, like SimulateTransaction, to report the gas it uses.
+// The gas is the intrinsic gas of the calldata and the Arkiv gas of the operations,
+// the processor charges nothing for the slots it writes, which are counted for the
+// storage they hold. A failing transaction still reports its gas, the gas it would
+// use with a failed receipt, and its error, an operation on an entity that doesn't
+// exist or isn't owned by the sender among others.
+func (api *arkivAPI) EstimateStorageGas(ctx context.Context, args EstimateStorageGasArgs) (_ *StorageGasEstimate, err error) {
+	defer func() { err = arkivRPCError(err) }()
+
+	if err := api.methods.check("estimateStorageGas"); err != nil {
+		return nil, err
+	}
+
+	encoded, err := rlp.EncodeToBytes(&args.Transaction)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode the transaction: %w", err)
+	}
+	data, err := compression.BrotliCompress(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compress the transaction: %w", err)
+	}
+
+	header, stateDB, err := api.headerState(nil)
+	if err != nil {
+		return nil, err
+	}
+	config := api.eth.blockchain.Config()
+	rules := config.Rules(header.Number, header.Difficulty.Sign() == 0, header.Time)
+	schedule := storagetx.GasScheduleAt(config, header.Time)
+
+	intrinsicGas, floorDataGas, arkivGas, err := arkivTransactionGas(rules, schedule, data, &args.Transaction)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &StorageGasEstimate{
+		Block:        hexutil.Uint64(header.Number.Uint64()),
+		Gas:          hexutil.Uint64(max(intrinsicGas+arkivGas, floorDataGas)),
+		CalldataSize: hexutil.Uint64(len(data)),
+		IntrinsicGas: hexutil.Uint64(intrinsicGas),
+		FloorDataGas: hexutil.Uint64(floorDataGas),
+		ArkivGas:     hexutil.Uint64(arkivGas),
+		Operations:   args.Transaction.OperationsGas(schedule),
+	}
+
+	recorder := statediff.NewRecorder(stateDB)
+	if _, err := api.executeNext(header, recorder, args.From, data); err != nil {
+		result.Error = err.Error()
+		return result, nil
+	}
+	result.Success = true
+	for _, slot := range recorder.Slots() {
+		switch {
+		case slot.Old == (common.Hash{}):
+			result.CreatedSlots++
+		case slot.New == (common.Hash{}):
+			result.ClearedSlots++
+		default:
+			result.ModifiedSlots++
+		}
+	}
+	return result, nil
+}
+
+// arkivTransactionGas returns the intrinsic gas of the calldata of the Arkiv transaction,
+// its floor data gas, 0 before Prague, and its Arkiv gas under the schedule. The
+// transaction uses the sum of the intrinsic and the Arkiv gas, at least the floor data
+// gas.
+func arkivTransactionGas(rules params.Rules, schedule storagetx.GasSchedule, data []byte, tx *storagetx.ArkivTransaction) (intrinsicGas uint64, floorDataGas uint64, arkivGas uint64, err error) {
+	intrinsicGas, err = core.IntrinsicGas(data, nil, nil, false, rules.IsHomestead, rules.IsIstanbul, rules.IsShanghai)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	if rules.IsPrague {
+		floorDataGas, err = core.FloorDataGas(data)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+	}
+	return intrinsicGas, floorDataGas, tx.Gas(schedule), nil
+}
@@ -0,0 +1,78 @@
+package eth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArkivAPI_EstimateStorageGas(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	other, _ := crypto.GenerateKey()
+
+	create := &storagetx.ArkivTransaction{Create: []storagetx.ArkivCreate{{
+		BTL:               100,
+		ContentType:       "text/plain",
+		Payload:           []byte("e0"),
+		StringAnnotations: []storagetx.StringAnnotation{{Key: "a", Value: strings.Repeat("a", int(params.DefaultArkivAnnotationValueGasThreshold)+10)}},
+	}}}
+	var created []common.Hash
+	steps := []arkivTestStep{
+		// Block 1: an entity of the sender is created
+		func([]common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			return key, create
+		},
+		func(keys []common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			created = keys
+			return nil, nil
+		},
+	}
+	api, gasUsed := newArkivTestAPI(t, key, other, steps, len(steps))
+	ctx := context.Background()
+
+	estimate := func(from common.Address, atx *storagetx.ArkivTransaction) *StorageGasEstimate {
+		t.Helper()
+		result, err := api.EstimateStorageGas(ctx, EstimateStorageGasArgs{From: from, Transaction: *atx})
+		require.NoError(t, err)
+		return result
+	}
+
+	// The estimate of the create is the gas the mined one used
+	result := estimate(sender, create)
+	require.True(t, result.Success, result.Error)
+	require.Equal(t, hexutil.Uint64(2), result.Block)
+	require.Equal(t, hexutil.Uint64(gasUsed[1]), result.Gas)
+	require.Equal(t, hexutil.Uint64(10*params.DefaultArkivAnnotationValueGasPerByte), result.ArkivGas)
+	require.Equal(t, []storagetx.OperationGas{{Operation: "create", Index: 0, Gas: 10 * params.DefaultArkivAnnotationValueGasPerByte}}, result.Operations)
+	require.NotZero(t, result.CalldataSize)
+	require.NotZero(t, result.CreatedSlots)
+
+	// An extension moves the entity to another expiration set
+	extend := &storagetx.ArkivTransaction{Extend: []storagetx.ExtendBTL{{EntityKey: created[0], NumberOfBlocks: 50}}}
+	result = estimate(sender, extend)
+	require.True(t, result.Success, result.Error)
+	require.Zero(t, result.ArkivGas)
+	require.NotZero(t, result.ModifiedSlots)
+	require.NotZero(t, result.ClearedSlots)
+
+	// The checks of the processor run against the state of the current block
+	update := &storagetx.ArkivTransaction{Update: []storagetx.ArkivUpdate{{EntityKey: common.Hash{1}, BTL: 100, ContentType: "text/plain"}}}
+	result = estimate(sender, update)
+	require.False(t, result.Success)
+	require.NotEmpty(t, result.Error)
+	require.NotZero(t, result.Gas)
+	require.Zero(t, result.CreatedSlots)
+
+	result = estimate(crypto.PubkeyToAddress(other.PublicKey), &storagetx.ArkivTransaction{Delete: []common.Hash{created[0]}})
+	require.False(t, result.Success)
+	require.Contains(t, result.Error, "is not the owner")
+}
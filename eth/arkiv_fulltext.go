@@ -0,0 +1,116 @@
+package eth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"strings"
+
+	sqlitestore "github.com/Arkiv-Network/sqlite-bitmap-store"
+	"github.com/ethereum/go-ethereum/arkiv/fulltext"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// arkivFullTextPageSize is the number of entities read from the store per query when
+// the full-text index is rebuilt.
+const arkivFullTextPageSize = 200
+
+// openArkivFullText opens the full-text index of the store and rebuilds it from the
+// content of the store if the indexing policy changed or it's out of sync.
+// It must be called before the store starts following the chain.
+func openArkivFullText(ctx context.Context, store arkivStore, compressedPayloads bool, path string, config fulltext.Config) (*fulltext.Index, error) {
+	index, err := fulltext.Open(path, config)
+	if err != nil {
+		return nil, err
+	}
+
+	lastBlock, err := store.GetLastBlock(ctx)
+	if err != nil {
+		index.Close()
+		return nil, fmt.Errorf("failed to get last block from store: %w", err)
+	}
+
+	needsReindex, err := index.NeedsReindex(ctx, uint64(lastBlock))
+	if err != nil {
+		index.Close()
+		return nil, err
+	}
+
+	if needsReindex {
+		log.Info("Rebuilding Arkiv full-text index", "block", uint64(lastBlock))
+		err = index.Reindex(ctx, arkivStoreEntities(ctx, store, compressedPayloads, uint64(lastBlock)), uint64(lastBlock))
+		if err != nil {
+			index.Close()
+			return nil, fmt.Errorf("failed to rebuild full-text index: %w", err)
+		}
+	}
+
+	return index, nil
+}
+
+// arkivStoreEntities iterates over the payloads of all the entities of the store at the block.
+func arkivStoreEntities(ctx context.Context, store arkivStore, compressedPayloads bool, atBlock uint64) iter.Seq2[fulltext.Entity, error] {
+	return func(yield func(fulltext.Entity, error) bool) {
+		pageSize := uint64(arkivFullTextPageSize)
+		options := &sqlitestore.Options{
+			AtBlock: &atBlock,
+			IncludeData: &sqlitestore.IncludeData{
+				Key:         true,
+				Payload:     true,
+				ContentType: true,
+			},
+			ResultsPerPage: &pageSize,
+		}
+
+		for {
+			response, err := store.QueryEntities(ctx, "$all", options)
+			if err != nil {
+				yield(fulltext.Entity{}, fmt.Errorf("failed to query entities: %w", err))
+				return
+			}
+
+			for _, d := range response.Data {
+				ed := sqlitestore.EntityData{}
+				err := json.Unmarshal(d, &ed)
+				if err != nil {
+					yield(fulltext.Entity{}, fmt.Errorf("failed to unmarshal entity data: %w", err))
+					return
+				}
+				if ed.Key == nil || ed.ContentType == nil {
+					continue
+				}
+				payload, err := storePayload(compressedPayloads, ed.Value)
+				if err != nil {
+					yield(fulltext.Entity{}, fmt.Errorf("failed to read the payload of %s: %w", ed.Key.Hex(), err))
+					return
+				}
+				if !yield(fulltext.Entity{Key: *ed.Key, ContentType: *ed.ContentType, Payload: payload}, nil) {
+					return
+				}
+			}
+
+			if response.Cursor == nil || *response.Cursor == "" {
+				return
+			}
+			options.Cursor = *response.Cursor
+		}
+	}
+}
+
+// withKeyMatch restricts the query to the keys of the entities.
+func withKeyMatch(query string, keys []common.Hash) string {
+	hexKeys := make([]string, len(keys))
+	for i, key := range keys {
+		hexKeys[i] = key.Hex()
+	}
+	keyClause := fmt.Sprintf("$key IN (%s)", strings.Join(hexKeys, " "))
+
+	switch strings.TrimSpace(query) {
+	case "", "$all", "*":
+		return keyClause
+	default:
+		return fmt.Sprintf("(%s) && %s", query, keyClause)
+	}
+}
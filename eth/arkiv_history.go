@@ -0,0 +1,218 @@
+package eth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/Arkiv-Network/arkiv-events/events"
+	"github.com/ethereum/go-ethereum/arkiv/dbevents"
+	"github.com/ethereum/go-ethereum/arkiv/genesis"
+	"github.com/ethereum/go-ethereum/arkiv/limits"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+)
+
+// arkivMaxRewindBlocks is how far before a block the last update or the creation of an
+// entity is looked for to rebuild the entity at the block, a day of 2s blocks.
+const arkivMaxRewindBlocks = limits.MaxRewindBlocks
+
+// blockOperations decodes the Arkiv operations of the canonical block. The operations
+// of block 1 start with the creates of the entities of the genesis, which the store
+// indexes with block 1.
+func (api *arkivAPI) blockOperations(number uint64) (*events.Block, error) {
+	block := api.eth.blockchain.GetBlockByNumber(number)
+	if block == nil {
+		return nil, fmt.Errorf("block %d not found", number)
+	}
+	receipts := api.eth.blockchain.GetReceiptsByHash(block.Hash())
+	if len(receipts) != len(block.Transactions()) {
+		return nil, fmt.Errorf("receipts of block %d not found, the node may have pruned them", number)
+	}
+	decoded, _, err := dbevents.BlockToEvents(block, receipts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode block %d: %w", number, err)
+	}
+	if number == 1 {
+		genesis, err := api.genesisOperations()
+		if err != nil {
+			return nil, err
+		}
+		decoded.Operations = append(genesis, decoded.Operations...)
+	}
+	return decoded, nil
+}
+
+// genesisOperations returns the creates of the entities declared by the genesis
+// manifest, see dbevents.GenesisOperations. The manifest was checked against the
+// genesis allocation when the store indexed it.
+func (api *arkivAPI) genesisOperations() ([]events.Operation, error) {
+	db := api.eth.ChainDb()
+	blob := rawdb.ReadArkivGenesisManifest(db, rawdb.ReadCanonicalHash(db, 0))
+	if blob == nil {
+		return nil, nil
+	}
+	manifest := &genesis.Manifest{}
+	if err := json.Unmarshal(blob, manifest); err != nil {
+		return nil, fmt.Errorf("failed to decode Arkiv genesis manifest: %w", err)
+	}
+	return dbevents.GenesisOperations(manifest.Block()), nil
+}
+
+// operationKey returns the key of the entity the operation applies to.
+func operationKey(operation events.Operation) common.Hash {
+	switch {
+	case operation.Create != nil:
+		return operation.Create.Key
+	case operation.Update != nil:
+		return operation.Update.Key
+	case operation.Delete != nil:
+		return common.Hash(*operation.Delete)
+	case operation.Expire != nil:
+		return common.Hash(*operation.Expire)
+	case operation.ExtendBTL != nil:
+		return operation.ExtendBTL.Key
+	case operation.ChangeOwner != nil:
+		return operation.ChangeOwner.Key
+	}
+	return common.Hash{}
+}
+
+// entitiesAt rebuilds the entities with the keys as they were at the block. All the
+// entities must exist at the block.
+//
+// The store only holds the current entities. The ones it holds unchanged since the
+// block are read from it, with the owner and the expiration of the state of the block.
+// The others are rebuilt from the chain, replaying their operations from their last
+// update before the block on top of the attributes the store holds since their
+// creation, or from their creation if the store no longer holds them. The update or
+// the creation must be at most arkivMaxRewindBlocks before the block.
+func (api *arkivAPI) entitiesAt(ctx context.Context, keys map[common.Hash]struct{}, block uint64) (map[common.Hash]*overlayEntity, error) {
+	entities := make(map[common.Hash]*overlayEntity, len(keys))
+	if len(keys) == 0 {
+		return entities, nil
+	}
+
+	lastIndexed, err := api.store.GetLastBlock(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last block from store: %w", err)
+	}
+	current := make(map[common.Hash]*overlayEntity, len(keys))
+	var unchanged []common.Hash
+	pending := make(map[common.Hash]struct{}, len(keys))
+	for key := range keys {
+		stored, err := storeEntity(ctx, api.store, api.compressedPayloads, key, lastIndexed)
+		if err != nil {
+			return nil, err
+		}
+		current[key] = stored
+		if stored != nil && block <= lastIndexed && stored.nums["$lastModifiedAtBlock"] <= block {
+			unchanged = append(unchanged, key)
+		} else {
+			pending[key] = struct{}{}
+		}
+	}
+	if len(unchanged) > 0 {
+		if err := api.stateEntitiesAt(block, unchanged, current, entities); err != nil {
+			return nil, err
+		}
+		// Without the state of the block they are rebuilt from the chain too
+		for _, key := range unchanged {
+			if _, ok := entities[key]; !ok {
+				pending[key] = struct{}{}
+			}
+		}
+	}
+	if len(pending) == 0 {
+		return entities, nil
+	}
+
+	// The operations on the entities, newest block first
+	var history []*events.Block
+	for number := block; len(pending) > 0; number-- {
+		if number == 0 || block-number >= arkivMaxRewindBlocks {
+			return nil, fmt.Errorf("entity %s was not updated or created in the %d blocks before block %d", sortedKeys(pending)[0].Hex(), arkivMaxRewindBlocks, block)
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		decoded, err := api.blockOperations(number)
+		if err != nil {
+			return nil, err
+		}
+		filtered := &events.Block{Number: number}
+		var found []common.Hash
+		for _, operation := range decoded.Operations {
+			key := operationKey(operation)
+			if _, ok := pending[key]; !ok {
+				continue
+			}
+			filtered.Operations = append(filtered.Operations, operation)
+			if operation.Create != nil || operation.Update != nil && current[key] != nil {
+				found = append(found, key)
+			}
+		}
+		for _, key := range found {
+			delete(pending, key)
+		}
+		if len(filtered.Operations) > 0 {
+			history = append(history, filtered)
+		}
+	}
+
+	// An update replaces everything but the attributes set at the creation, which are
+	// the ones of the store
+	overlay := &arkivOverlay{
+		entities: map[common.Hash]*overlayEntity{},
+		base: func(key common.Hash) (*overlayEntity, error) {
+			return current[key], nil
+		},
+	}
+	for _, decoded := range slices.Backward(history) {
+		if err := overlay.apply(decoded.Number, decoded.Operations, EntityProvenanceIndexed); err != nil {
+			return nil, err
+		}
+	}
+	for key := range keys {
+		if _, ok := entities[key]; ok {
+			continue
+		}
+		rebuilt, ok := overlay.entities[key]
+		if !ok || rebuilt.deleted {
+			return nil, fmt.Errorf("entity %s doesn't exist at block %d", key.Hex(), block)
+		}
+		entities[key] = rebuilt
+	}
+	return entities, nil
+}
+
+// stateEntitiesAt sets the entities of the keys, which the store holds unchanged since
+// the block, with the owner and the expiration of the state of the block. It leaves
+// them all out if the node no longer holds the state.
+func (api *arkivAPI) stateEntitiesAt(block uint64, keys []common.Hash, current, entities map[common.Hash]*overlayEntity) error {
+	header := api.eth.blockchain.GetHeaderByNumber(block)
+	if header == nil {
+		return fmt.Errorf("block %d not found", block)
+	}
+	stateDB, err := api.eth.BlockChain().StateAt(header.Root)
+	if err != nil {
+		return nil
+	}
+	mds, errs := entity.GetEntityMetaDataBatch(stateDB, keys)
+	for i, key := range keys {
+		if errs[i] != nil {
+			return fmt.Errorf("entity %s doesn't exist at block %d", key.Hex(), block)
+		}
+		at := *current[key]
+		at.strs = maps.Clone(at.strs)
+		at.strs["$owner"] = strings.ToLower(mds[i].Owner.Hex())
+		at.nums = maps.Clone(at.nums)
+		at.nums["$expiration"] = mds[i].ExpiresAtBlock
+		entities[key] = &at
+	}
+	return nil
+}
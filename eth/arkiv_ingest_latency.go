@@ -0,0 +1,189 @@
+package eth
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Arkiv-Network/arkiv-events/events"
+	"github.com/ethereum/go-ethereum/arkiv/address"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+const (
+	// arkivIngestLatencyCapacity is the largest number of transactions whose latency is
+	// tracked, the oldest are forgotten first.
+	arkivIngestLatencyCapacity = 10_000
+
+	// arkivIngestLatencyTTL is how long the latency of a transaction is kept after its
+	// submission.
+	arkivIngestLatencyTTL = time.Hour
+)
+
+var (
+	// The time from the submission of a transaction to the import of its block, and
+	// from the import to the store committing the block.
+	arkivIngestInclusionTimer = metrics.NewRegisteredTimer("arkiv/ingest/latency/inclusion", nil)
+	arkivIngestIndexedTimer   = metrics.NewRegisteredTimer("arkiv/ingest/latency/indexed", nil)
+)
+
+// ingestTiming is when a transaction was submitted, included in a block and indexed
+// by the store, the zero time for the steps not reached yet.
+type ingestTiming struct {
+	submitted time.Time
+	included  time.Time
+	indexed   time.Time
+	block     uint64
+}
+
+// arkivIngestLatency follows the local transactions to the processor from their
+// submission to the store, so that the time until a write is visible to the queries
+// can be measured. It tracks at most capacity transactions, for ttl at most.
+type arkivIngestLatency struct {
+	capacity int
+	ttl      time.Duration
+	now      func() time.Time
+
+	mu  sync.Mutex
+	txs map[common.Hash]*ingestTiming
+	// order holds the tracked transactions in the order of their submission.
+	order []common.Hash
+}
+
+func newArkivIngestLatency(capacity int, ttl time.Duration) *arkivIngestLatency {
+	return &arkivIngestLatency{
+		capacity: capacity,
+		ttl:      ttl,
+		now:      time.Now,
+		txs:      make(map[common.Hash]*ingestTiming),
+	}
+}
+
+// submitted starts tracking the transaction if it's sent to the processor.
+func (l *arkivIngestLatency) submitted(tx *types.Transaction) {
+	if to := tx.To(); to == nil || *to != address.ArkivProcessorAddress {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.txs[tx.Hash()]; ok {
+		return
+	}
+	now := l.now()
+	l.txs[tx.Hash()] = &ingestTiming{submitted: now}
+	l.order = append(l.order, tx.Hash())
+	l.evict(now)
+}
+
+// evict forgets the transactions past the capacity or the TTL, the oldest first.
+func (l *arkivIngestLatency) evict(now time.Time) {
+	evicted := 0
+	for _, hash := range l.order {
+		timing, ok := l.txs[hash]
+		if ok && len(l.txs) <= l.capacity && now.Sub(timing.submitted) < l.ttl {
+			break
+		}
+		delete(l.txs, hash)
+		evicted++
+	}
+	l.order = l.order[evicted:]
+}
+
+// included records the import of the block holding tracked transactions, it's a block
+// hook.
+func (l *arkivIngestLatency) included(block *types.Block, _ *events.Block) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	for _, tx := range block.Transactions() {
+		timing, ok := l.txs[tx.Hash()]
+		if !ok {
+			continue
+		}
+		timing.included, timing.indexed, timing.block = now, time.Time{}, block.NumberU64()
+		arkivIngestInclusionTimer.Update(now.Sub(timing.submitted))
+	}
+	return nil
+}
+
+// removed forgets the inclusion of the tracked transactions of a block a reorg removed
+// from the canonical chain, it's a reorg hook.
+func (l *arkivIngestLatency) removed(block *types.Block, _ *events.Block) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, tx := range block.Transactions() {
+		if timing, ok := l.txs[tx.Hash()]; ok && timing.block == block.NumberU64() {
+			timing.included, timing.indexed, timing.block = time.Time{}, time.Time{}, 0
+		}
+	}
+	return nil
+}
+
+// indexed records the store committing the blocks up to lastBlock.
+func (l *arkivIngestLatency) indexed(lastBlock uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	for _, timing := range l.txs {
+		if timing.included.IsZero() || !timing.indexed.IsZero() || timing.block > lastBlock {
+			continue
+		}
+		timing.indexed = now
+		arkivIngestIndexedTimer.Update(now.Sub(timing.included))
+	}
+}
+
+// latency returns the latency of the transaction, nil if it isn't tracked.
+func (l *arkivIngestLatency) latency(hash common.Hash) *IngestLatency {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	timing, ok := l.txs[hash]
+	if !ok {
+		return nil
+	}
+	latency := &IngestLatency{
+		TxHash:      hash,
+		SubmittedAt: hexutil.Uint64(timing.submitted.UnixMilli()),
+	}
+	if !timing.included.IsZero() {
+		block := hexutil.Uint64(timing.block)
+		latency.Block = &block
+		latency.IncludedAt = millis(timing.included.UnixMilli())
+		latency.SubmitToInclusion = millis(timing.included.Sub(timing.submitted).Milliseconds())
+	}
+	if !timing.indexed.IsZero() {
+		latency.IndexedAt = millis(timing.indexed.UnixMilli())
+		latency.InclusionToIndexed = millis(timing.indexed.Sub(timing.included).Milliseconds())
+	}
+	return latency
+}
+
+func millis(ms int64) *hexutil.Uint64 {
+	value := hexutil.Uint64(max(ms, 0))
+	return &value
+}
+
+// GetIngestLatency returns how long the transaction submitted to the node took to be
+// included in a block and indexed by the store, null if the node doesn't track it:
+// only the transactions to the processor submitted to the node in the last hour are
+// tracked, the latest 10000 at most.
+func (api *arkivAPI) GetIngestLatency(ctx context.Context, txHash common.Hash) (_ *IngestLatency, err error) {
+	defer func() { err = arkivRPCError(err) }()
+
+	if err := api.methods.check("getIngestLatency"); err != nil {
+		return nil, err
+	}
+	if api.eth.arkivIngestLatency == nil {
+		return nil, nil
+	}
+	return api.eth.arkivIngestLatency.latency(txHash), nil
+}
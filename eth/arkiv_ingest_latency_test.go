@@ -0,0 +1,101 @@
+package eth
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/arkiv/address"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+func processorTx(nonce uint64) *types.Transaction {
+	return types.NewTransaction(nonce, address.ArkivProcessorAddress, new(big.Int), 100_000, new(big.Int), nil)
+}
+
+func latencyBlock(number int64, txs ...*types.Transaction) *types.Block {
+	return types.NewBlockWithHeader(&types.Header{Number: big.NewInt(number)}).WithBody(types.Body{Transactions: txs})
+}
+
+func TestIngestLatency(t *testing.T) {
+	start := time.UnixMilli(1_000_000)
+	now := start
+	l := newArkivIngestLatency(10, time.Hour)
+	l.now = func() time.Time { return now }
+
+	tx := processorTx(0)
+	l.submitted(tx)
+
+	// The transactions to other addresses aren't tracked
+	other := types.NewTransaction(1, common.Address{0x01}, new(big.Int), 21_000, new(big.Int), nil)
+	l.submitted(other)
+	require.Nil(t, l.latency(other.Hash()))
+
+	latency := l.latency(tx.Hash())
+	require.Equal(t, hexutil.Uint64(1_000_000), latency.SubmittedAt)
+	require.Nil(t, latency.IncludedAt)
+
+	now = start.Add(2 * time.Second)
+	require.NoError(t, l.included(latencyBlock(5, tx), nil))
+
+	// The store hasn't committed the block yet
+	l.indexed(4)
+	latency = l.latency(tx.Hash())
+	require.Equal(t, hexutil.Uint64(5), *latency.Block)
+	require.Equal(t, hexutil.Uint64(2000), *latency.SubmitToInclusion)
+	require.Nil(t, latency.IndexedAt)
+
+	now = start.Add(2500 * time.Millisecond)
+	l.indexed(5)
+	latency = l.latency(tx.Hash())
+	require.Equal(t, hexutil.Uint64(1_002_500), *latency.IndexedAt)
+	require.Equal(t, hexutil.Uint64(500), *latency.InclusionToIndexed)
+}
+
+func TestIngestLatencyReorg(t *testing.T) {
+	l := newArkivIngestLatency(10, time.Hour)
+	tx := processorTx(0)
+	l.submitted(tx)
+
+	require.NoError(t, l.included(latencyBlock(5, tx), nil))
+	l.indexed(5)
+	require.NoError(t, l.removed(latencyBlock(5, tx), nil))
+
+	latency := l.latency(tx.Hash())
+	require.Nil(t, latency.Block)
+	require.Nil(t, latency.IncludedAt)
+	require.Nil(t, latency.IndexedAt)
+
+	// The inclusion in the new chain is recorded
+	require.NoError(t, l.included(latencyBlock(6, tx), nil))
+	require.Equal(t, hexutil.Uint64(6), *l.latency(tx.Hash()).Block)
+}
+
+func TestIngestLatencyBounds(t *testing.T) {
+	start := time.Unix(1_000, 0)
+	now := start
+	l := newArkivIngestLatency(2, time.Minute)
+	l.now = func() time.Time { return now }
+
+	txs := []*types.Transaction{processorTx(0), processorTx(1), processorTx(2)}
+	for _, tx := range txs {
+		l.submitted(tx)
+	}
+
+	// The oldest transaction is forgotten past the capacity
+	require.Nil(t, l.latency(txs[0].Hash()))
+	require.NotNil(t, l.latency(txs[1].Hash()))
+	require.NotNil(t, l.latency(txs[2].Hash()))
+
+	// And all of them past the TTL
+	now = start.Add(time.Minute)
+	tx := processorTx(3)
+	l.submitted(tx)
+	require.Nil(t, l.latency(txs[1].Hash()))
+	require.Nil(t, l.latency(txs[2].Hash()))
+	require.NotNil(t, l.latency(tx.Hash()))
+	require.Len(t, l.order, 1)
+}
@@ -0,0 +1,122 @@
+package eth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	sqlitestore "github.com/Arkiv-Network/sqlite-bitmap-store"
+	"github.com/Arkiv-Network/sqlite-bitmap-store/store"
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// arkivKeyPrefixBatchSize is the number of entities whose keys are read from the
+// store at once when matching a key prefix.
+const arkivKeyPrefixBatchSize = 200
+
+// parseKeyPrefix returns the annotation key prefix of the keyPrefix query option,
+// which can be written with or without a trailing .*: invoice.customer.* and
+// invoice.customer select the same entities.
+func parseKeyPrefix(prefix string) (string, error) {
+	prefix = strings.TrimSuffix(prefix, entity.AnnotationKeySeparator+"*")
+	if err := entity.ValidateAnnotationKey(prefix); err != nil {
+		return "", invalidRequest("invalid key prefix: %w", err)
+	}
+	return prefix, nil
+}
+
+// matchKeyPrefix returns the keys of the entities with an annotation key under the
+// prefix. The prefixes of the annotation keys are indexed when the entities are
+// stored, so the entities are read from a single bitmap of every store.
+func matchKeyPrefix(ctx context.Context, s arkivStore, prefix string) ([]common.Hash, error) {
+	keys := []common.Hash{}
+	for _, shard := range arkivStores(s, "$all") {
+		if err := matchStoreKeyPrefix(ctx, shard, prefix, &keys); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+// matchStoreKeyPrefix appends the keys of the entities of the store with an annotation
+// key under the prefix.
+func matchStoreKeyPrefix(ctx context.Context, s *sqlitestore.SQLiteStore, prefix string, keys *[]common.Hash) error {
+	err := s.ReadTransaction(ctx, func(q *store.Queries) error {
+		bitmap, err := q.EvaluateStringAttributeValueEqual(ctx, store.EvaluateStringAttributeValueEqualParams{
+			Name:  storagetx.AnnotationKeyPrefixAttributePrefix + prefix,
+			Value: storagetx.AnnotationKeyPrefixAttributeValue,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		for ids := range slices.Chunk(bitmap.ToArray(), arkivKeyPrefixBatchSize) {
+			rows, err := q.RetrievePayloads(ctx, ids)
+			if err != nil {
+				return err
+			}
+			for _, row := range rows {
+				*keys = append(*keys, common.BytesToHash(row.EntityKey))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error matching key prefix: %w", err)
+	}
+	return nil
+}
+
+// matchKeys returns the keys of the entities matching both the text and the key
+// prefix of the query options, whichever are set.
+func (api *arkivAPI) matchKeys(ctx context.Context, op *QueryOptions) ([]common.Hash, error) {
+	var keys []common.Hash
+	if op.Text != "" {
+		if api.fullText == nil {
+			return nil, invalidRequest("full-text search is disabled, enable it with --arkiv.fulltext")
+		}
+		matched, err := api.fullText.Match(ctx, op.Text)
+		if err != nil {
+			return nil, fmt.Errorf("error matching text: %w", err)
+		}
+		keys = matched
+	}
+	if op.KeyPrefix != "" {
+		prefix, err := parseKeyPrefix(op.KeyPrefix)
+		if err != nil {
+			return nil, err
+		}
+		matched, err := matchKeyPrefix(ctx, api.store, prefix)
+		if err != nil {
+			return nil, err
+		}
+		if op.Text == "" {
+			return matched, nil
+		}
+		keys = intersectKeys(keys, matched)
+	}
+	return keys, nil
+}
+
+// intersectKeys returns the keys of a that are also in b.
+func intersectKeys(a, b []common.Hash) []common.Hash {
+	inB := make(map[common.Hash]struct{}, len(b))
+	for _, key := range b {
+		inB[key] = struct{}{}
+	}
+	keys := []common.Hash{}
+	for _, key := range a {
+		if _, ok := inB[key]; ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
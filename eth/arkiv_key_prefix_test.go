@@ -0,0 +1,111 @@
+package eth
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	arkivevents "github.com/Arkiv-Network/arkiv-events"
+	"github.com/Arkiv-Network/arkiv-events/events"
+	sqlitestore "github.com/Arkiv-Network/sqlite-bitmap-store"
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+// seedKeyPrefixStore creates a store with an entity for every annotation key, indexed
+// the way the events pipeline indexes hierarchical keys.
+func seedKeyPrefixStore(t *testing.T, keys ...string) *sqlitestore.SQLiteStore {
+	t.Helper()
+
+	store, err := sqlitestore.NewSQLiteStore(slog.New(slog.DiscardHandler), filepath.Join(t.TempDir(), "arkiv.db"), 1)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		store.Close()
+	})
+
+	block := events.Block{Number: 1}
+	for i, key := range keys {
+		attributes := map[string]string{key: "value"}
+		for j := range len(key) {
+			if key[j] == '.' {
+				attributes[storagetx.AnnotationKeyPrefixAttributePrefix+key[:j]] = storagetx.AnnotationKeyPrefixAttributeValue
+			}
+		}
+		block.Operations = append(block.Operations, events.Operation{
+			Create: &events.OPCreate{
+				Key:               common.BytesToHash([]byte{byte(i + 1)}),
+				ContentType:       "text/plain",
+				BTL:               1000,
+				Content:           []byte("payload"),
+				StringAttributes:  attributes,
+				NumericAttributes: map[string]uint64{},
+			},
+			OpIndex: uint64(i),
+		})
+	}
+
+	iterator := func(yield func(arkivevents.BatchOrError) bool) {
+		yield(arkivevents.BatchOrError{Batch: events.BlockBatch{Blocks: []events.Block{block}}})
+	}
+	require.NoError(t, store.FollowEvents(context.Background(), iterator))
+
+	return store
+}
+
+func queryKeys(t *testing.T, response *QueryResponse) []common.Hash {
+	t.Helper()
+
+	keys := []common.Hash{}
+	for _, data := range response.Data {
+		var ed sqlitestore.EntityData
+		require.NoError(t, json.Unmarshal(data, &ed))
+		keys = append(keys, *ed.Key)
+	}
+	return keys
+}
+
+func TestArkivAPI_QueryKeyPrefix(t *testing.T) {
+	api := &arkivAPI{
+		store: seedKeyPrefixStore(t,
+			"invoice.customer.region",
+			"invoice.customer.name",
+			"invoice.customers.count",
+			"invoice.customer",
+			"invoice",
+		),
+	}
+	atBlock := uint64(1)
+	options := func(prefix string) *QueryOptions {
+		return &QueryOptions{
+			Options: sqlitestore.Options{
+				AtBlock:     &atBlock,
+				IncludeData: &sqlitestore.IncludeData{Key: true},
+			},
+			KeyPrefix: prefix,
+		}
+	}
+
+	// invoice.customers shares only a partial segment with invoice.customer, and the
+	// key equal to the prefix is not under it
+	for _, prefix := range []string{"invoice.customer.*", "invoice.customer"} {
+		response, err := api.Query(context.Background(), "$all", options(prefix))
+		require.NoError(t, err, prefix)
+		require.ElementsMatch(t, []common.Hash{common.BytesToHash([]byte{1}), common.BytesToHash([]byte{2})}, queryKeys(t, response), prefix)
+	}
+
+	response, err := api.Query(context.Background(), "$all", options("invoice.*"))
+	require.NoError(t, err)
+	require.Len(t, response.Data, 4)
+
+	response, err = api.Query(context.Background(), "$all", options("order.*"))
+	require.NoError(t, err)
+	require.Empty(t, response.Data)
+
+	for _, prefix := range []string{"invoice.$owner", "invoice..customer", "*"} {
+		_, err = api.Query(context.Background(), "$all", options(prefix))
+		require.ErrorContains(t, err, "invalid key prefix", prefix)
+	}
+}
@@ -0,0 +1,61 @@
+package eth
+
+import (
+	"github.com/ethereum/go-ethereum/arkiv/limits"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// GetLimits returns the limits enforced on Arkiv transactions and requests at the
+// head: the consensus limits, enforced in the transaction pool and during execution,
+// and the policy of this node. The limits set by the configuration of the node are
+// read from the policies enforcing them, the disabled ones are left out. It's one of
+// the arkivReportingMethods, which the operator can't disable.
+func (api *arkivAPI) GetLimits() *Limits {
+	header := api.eth.blockchain.CurrentBlock()
+	config := api.eth.blockchain.Config()
+
+	result := &Limits{
+		Version:                     limits.Version,
+		Block:                       hexutil.Uint64(header.Number.Uint64()),
+		Time:                        hexutil.Uint64(header.Time),
+		Consensus:                   []Limit{},
+		Node:                        []Limit{},
+		MaxAnnotationValueSize:      config.ArkivMaxAnnotationValueSizeAt(header.Time),
+		AnnotationValueGasThreshold: config.ArkivAnnotationValueGasThresholdAt(header.Time),
+		AnnotationValueGasPerByte:   hexutil.Uint64(config.ArkivAnnotationValueGasPerByteAt(header.Time)),
+	}
+	for _, limit := range limits.ConsensusLimits(config, header.Time) {
+		result.Consensus = append(result.Consensus, newLimit(config, limit))
+	}
+
+	node := limits.NodeLimits()
+	node = append(node, api.memory.limits()...)
+	if api.eth.APIBackend != nil {
+		node = append(node, api.eth.APIBackend.daBackpressure.limits()...)
+	}
+	if api.fullText != nil {
+		node = append(node, limits.Limit{Name: "maxIndexedPayloadSize", Scope: limits.Node, Unit: "bytes", Value: api.fullText.MaxPayloadSize()})
+	}
+	for _, limit := range node {
+		result.Node = append(result.Node, newLimit(config, limit))
+	}
+	return result
+}
+
+// newLimit returns the limit of the registry as reported by arkiv_getLimits.
+func newLimit(config *params.ChainConfig, limit limits.Limit) Limit {
+	result := Limit{Name: limit.Name, Unit: limit.Unit, Value: limit.Value}
+	if limit.Feature == nil {
+		return result
+	}
+	result.ForkGated = true
+	if feature, ok := params.ArkivFeatureByID(*limit.Feature); ok {
+		result.Feature = feature.Name
+		if activation := feature.Activation(config); activation != nil {
+			time := hexutil.Uint64(*activation)
+			result.ActivationTime = &time
+		}
+	}
+	return result
+}
@@ -0,0 +1,114 @@
+package eth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/arkiv/compression"
+	"github.com/ethereum/go-ethereum/arkiv/limits"
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/beacon"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArkivAPI_GetLimits(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	empty := func([]common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) { return nil, nil }
+	gspec, blocks, _ := newArkivTestChain(t, key, key, []arkivTestStep{empty, empty})
+
+	// The two-step transfer activates after the head with its own window
+	ownershipTime := blocks[1].Time() + 100
+	gspec.Config.ArkivOwnershipTime = &ownershipTime
+	gspec.Config.ArkivOwnershipTransferWindow = 100
+	gspec.Config.ArkivDottedKeysTime = new(uint64)
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), gspec, beacon.New(ethash.NewFaker()), nil)
+	require.NoError(t, err)
+	t.Cleanup(chain.Stop)
+	_, err = chain.InsertChain(blocks)
+	require.NoError(t, err)
+	api := &arkivAPI{eth: &Ethereum{blockchain: chain}, memory: newArkivQueryMemoryBudget(1<<20, 0)}
+
+	result := api.GetLimits()
+	require.Equal(t, uint64(limits.Version), result.Version)
+	require.Equal(t, hexutil.Uint64(2), result.Block)
+	limit := func(list []Limit, name string) Limit {
+		t.Helper()
+		for _, limit := range list {
+			if limit.Name == name {
+				return limit
+			}
+		}
+		t.Fatalf("limit %s not found", name)
+		return Limit{}
+	}
+
+	window := limit(result.Consensus, "ownershipTransferWindow")
+	require.Equal(t, uint64(100), window.Value)
+	require.True(t, window.ForkGated)
+	require.Equal(t, "arkiv.twoStepTransfer", window.Feature)
+	require.Equal(t, hexutil.Uint64(ownershipTime), *window.ActivationTime)
+	require.False(t, limit(result.Consensus, "maxContentTypeLength").ForkGated)
+	valueSizeLimit := limit(result.Consensus, "maxAnnotationValueSize")
+	require.Equal(t, "arkiv.gasSchedule", valueSizeLimit.Feature)
+	require.Equal(t, result.MaxAnnotationValueSize, valueSizeLimit.Value)
+	require.Equal(t, uint64(1<<20), limit(result.Node, "queryMemoryBudget").Value)
+
+	// The reported limits are the enforced ones: a transaction at the limit passes, one
+	// over it is rejected
+	unpack := func(tx *storagetx.ArkivTransaction) error {
+		data, err := rlp.EncodeToBytes(tx)
+		require.NoError(t, err)
+		unpacked, err := storagetx.UnpackArkivTransactionWithLimits(compression.MustBrotliCompress(data), storagetx.UnpackLimitsAt(chain.Config(), uint64(result.Time)))
+		if err != nil {
+			return err
+		}
+		return unpacked.Validate()
+	}
+	create := func(contentType string, annotationKey string, annotationValue string) *storagetx.ArkivTransaction {
+		return &storagetx.ArkivTransaction{
+			Version: storagetx.CurrentTransactionVersion,
+			Create: []storagetx.ArkivCreate{{
+				BTL:               10,
+				ContentType:       contentType,
+				StringAnnotations: []storagetx.StringAnnotation{{Key: annotationKey, Value: annotationValue}},
+			}},
+		}
+	}
+
+	valueSize := int(limit(result.Consensus, "maxAnnotationValueSize").Value)
+	require.NoError(t, unpack(create("text/plain", "a", strings.Repeat("a", valueSize))))
+	require.ErrorContains(t, unpack(create("text/plain", "a", strings.Repeat("a", valueSize+1))), "too long")
+
+	contentTypeLength := int(limit(result.Consensus, "maxContentTypeLength").Value)
+	require.NoError(t, unpack(create("text/"+strings.Repeat("a", contentTypeLength-5), "a", "")))
+	require.ErrorContains(t, unpack(create("text/"+strings.Repeat("a", contentTypeLength-4), "a", "")), "too long")
+
+	keyDepth := int(limit(result.Consensus, "maxAnnotationKeyDepth").Value)
+	require.NoError(t, unpack(create("text/plain", strings.Repeat("a.", keyDepth-1)+"a", "")))
+	require.ErrorContains(t, unpack(create("text/plain", strings.Repeat("a.", keyDepth)+"a", "")), "segments")
+
+	operations := int(limit(result.Consensus, "maxOperations").Value)
+	deletes := &storagetx.ArkivTransaction{}
+	for i := range operations {
+		deletes.Delete = append(deletes.Delete, common.BigToHash(big.NewInt(int64(i+1))))
+	}
+	require.NoError(t, unpack(deletes))
+	deletes.Delete = append(deletes.Delete, common.HexToHash("0xffff"))
+	require.ErrorContains(t, unpack(deletes), "number of operations")
+
+	usageReportBlocks := limit(result.Node, "maxUsageReportBlocks").Value
+	_, err = api.GetOwnerUsageReport(context.Background(), common.Address{}, 0, hexutil.Uint64(usageReportBlocks))
+	require.ErrorContains(t, err, "exceeds the limit")
+	_, err = api.GetOwnerUsageReport(context.Background(), common.Address{}, 0, hexutil.Uint64(usageReportBlocks-1))
+	require.NotContains(t, err.Error(), "exceeds the limit")
+}
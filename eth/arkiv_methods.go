@@ -0,0 +1,124 @@
+package eth
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// errMethodDisabled is returned by the methods of the arkiv namespace the operator
+// disabled, see --arkiv.rpc.enable and --arkiv.rpc.disable.
+var errMethodDisabled = errors.New("method disabled by operator")
+
+// arkivMethods are the methods of the arkiv namespace the operator can disable, by
+// the name they are served under, the subscriptions by the name passed to
+// arkiv_subscribe.
+var arkivMethods = []string{
+	"computeEntityKey",
+	"contentHashVerification",
+	"entityEvents",
+	"estimateStorageGas",
+	"getBlockTiming",
+	"getEntitiesByOwnerClass",
+	"getEntitiesOfOwner",
+	"getEntitiesToExpire",
+	"getEntity",
+	"getEntityCount",
+	"getEntityExpiry",
+	"getEntityMetaData",
+	"getIngestLatency",
+	"getNumberOfUsedSlots",
+	"getOwnerEntitiesByExpiry",
+	"getOwnerUsageReport",
+	"getProcessorLogs",
+	"getUsedSlotsByOwner",
+	"query",
+	"queryCount",
+	"queryDiff",
+	"resolveAlias",
+	"sampleEntities",
+	"sendSignedOperation",
+	"simulateTransaction",
+	"storageChallenge",
+	"verifyContentHashes",
+}
+
+// arkivReportingMethods are the methods reporting the configuration and the health of
+// the node. They can't be disabled, so clients can always find out what the node
+// serves and load balancers can probe it, and they don't call arkivMethodFilter.check.
+// None of them runs a query or reads entities, so serving them costs nothing an
+// operator would want to shed. Every other method of the namespace is in arkivMethods
+// and checks the filter first, TestArkivMethodFilter fails on a method in neither
+// list.
+var arkivReportingMethods = []string{
+	"capabilities",
+	"getLimits",
+	"selfCheck",
+	"shadowEnforcementStats",
+	"syncStatus",
+}
+
+// arkivMethodFilter is the set of the methods of the arkiv namespace the operator
+// disabled.
+type arkivMethodFilter struct {
+	disabled map[string]struct{}
+}
+
+// newArkivMethodFilter returns the filter serving the enabled methods but the
+// disabled ones, all of them if none is enabled. The names may carry the arkiv_
+// prefix, unknown names and the reporting methods are rejected.
+func newArkivMethodFilter(enabled, disabled []string) (arkivMethodFilter, error) {
+	parse := func(names []string) (map[string]struct{}, error) {
+		methods := map[string]struct{}{}
+		for _, name := range names {
+			method := strings.TrimPrefix(strings.TrimSpace(name), "arkiv_")
+			if slices.Contains(arkivReportingMethods, method) {
+				return nil, fmt.Errorf("arkiv method %q can't be disabled", name)
+			}
+			if !slices.Contains(arkivMethods, method) {
+				return nil, fmt.Errorf("unknown arkiv method %q", name)
+			}
+			methods[method] = struct{}{}
+		}
+		return methods, nil
+	}
+
+	allowed, err := parse(enabled)
+	if err != nil {
+		return arkivMethodFilter{}, err
+	}
+	denied, err := parse(disabled)
+	if err != nil {
+		return arkivMethodFilter{}, err
+	}
+
+	filter := arkivMethodFilter{disabled: map[string]struct{}{}}
+	for _, method := range arkivMethods {
+		_, isAllowed := allowed[method]
+		_, isDenied := denied[method]
+		if isDenied || (len(allowed) > 0 && !isAllowed) {
+			filter.disabled[method] = struct{}{}
+		}
+	}
+	return filter, nil
+}
+
+// check returns errMethodDisabled if the method is disabled.
+func (f arkivMethodFilter) check(method string) error {
+	if _, ok := f.disabled[method]; ok {
+		return fmt.Errorf("arkiv_%s: %w", method, errMethodDisabled)
+	}
+	return nil
+}
+
+// disabledMethods returns the names of the disabled methods, sorted.
+func (f arkivMethodFilter) disabledMethods() []string {
+	methods := []string{}
+	for _, method := range arkivMethods {
+		if _, ok := f.disabled[method]; ok {
+			methods = append(methods, method)
+		}
+	}
+	return methods
+}
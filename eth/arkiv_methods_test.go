@@ -0,0 +1,122 @@
+package eth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/arkiv/rpctypes"
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArkivMethodFilter(t *testing.T) {
+	// Every method of the namespace can be disabled or reports the node
+	var served []string
+	api := reflect.TypeOf(&arkivAPI{})
+	for i := range api.NumMethod() {
+		name := api.Method(i).Name
+		served = append(served, strings.ToLower(name[:1])+name[1:])
+	}
+	slices.Sort(served)
+	require.Equal(t, served, slices.Sorted(slices.Values(append(slices.Clone(arkivMethods), arkivReportingMethods...))))
+
+	t.Run("default", func(t *testing.T) {
+		filter, err := newArkivMethodFilter(nil, nil)
+		require.NoError(t, err)
+		require.Empty(t, filter.disabledMethods())
+		for _, method := range arkivMethods {
+			require.NoError(t, filter.check(method))
+		}
+	})
+
+	t.Run("allowlist", func(t *testing.T) {
+		filter, err := newArkivMethodFilter([]string{"arkiv_getEntity", "getEntityMetaData", "getEntityCount"}, nil)
+		require.NoError(t, err)
+		require.NoError(t, filter.check("getEntity"))
+		require.NoError(t, filter.check("getEntityMetaData"))
+		require.ErrorIs(t, filter.check("query"), errMethodDisabled)
+		require.Len(t, filter.disabledMethods(), len(arkivMethods)-3)
+		require.NotContains(t, filter.disabledMethods(), "getEntity")
+	})
+
+	t.Run("denylist", func(t *testing.T) {
+		filter, err := newArkivMethodFilter(nil, []string{"arkiv_query", "queryDiff"})
+		require.NoError(t, err)
+		require.Equal(t, []string{"query", "queryDiff"}, filter.disabledMethods())
+		require.ErrorIs(t, filter.check("query"), errMethodDisabled)
+		require.NoError(t, filter.check("queryCount"))
+	})
+
+	t.Run("both", func(t *testing.T) {
+		// The denylist wins over the allowlist
+		filter, err := newArkivMethodFilter([]string{"query", "getEntity"}, []string{"query"})
+		require.NoError(t, err)
+		require.ErrorIs(t, filter.check("query"), errMethodDisabled)
+		require.NoError(t, filter.check("getEntity"))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := newArkivMethodFilter([]string{"arkiv_unknown"}, nil)
+		require.ErrorContains(t, err, "unknown arkiv method")
+		_, err = newArkivMethodFilter(nil, []string{"eth_call"})
+		require.ErrorContains(t, err, "unknown arkiv method")
+		_, err = newArkivMethodFilter(nil, []string{"arkiv_capabilities"})
+		require.ErrorContains(t, err, "can't be disabled")
+		_, err = newArkivMethodFilter([]string{"syncStatus"}, nil)
+		require.ErrorContains(t, err, "can't be disabled")
+	})
+}
+
+func TestArkivAPI_MethodDisabled(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	steps := []arkivTestStep{
+		func([]common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			return key, &storagetx.ArkivTransaction{Create: []storagetx.ArkivCreate{{BTL: 100, ContentType: "text/plain", Payload: []byte("e0")}}}
+		},
+	}
+	api, _ := newArkivTestAPI(t, key, key, steps, len(steps))
+	ctx := context.Background()
+
+	filter, err := newArkivMethodFilter(nil, []string{"query", "queryDiff"})
+	require.NoError(t, err)
+	api.methods = filter
+
+	_, err = api.Query(ctx, "$all", &QueryOptions{AllowFullScan: true})
+	require.ErrorIs(t, err, errMethodDisabled)
+	rpcErr, ok := err.(rpc.Error)
+	require.True(t, ok, "%T is not an rpc.Error", err)
+	require.Equal(t, rpctypes.ErrCodeMethodDisabled, rpcErr.ErrorCode())
+	require.Contains(t, err.Error(), "arkiv_query")
+
+	_, err = api.QueryDiff(ctx, "$all", 0, 1, nil)
+	require.ErrorIs(t, err, errMethodDisabled)
+
+	count, err := api.GetEntityCount(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), count)
+
+	capabilities, err := api.Capabilities(nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"query", "queryDiff"}, capabilities.DisabledMethods)
+
+	// Without a store the capabilities are still reported, and the disabled methods
+	// fail before reaching the store
+	noStore := &arkivAPI{eth: api.eth, methods: filter}
+	capabilities, err = noStore.Capabilities(nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"query", "queryDiff"}, capabilities.DisabledMethods)
+	_, err = noStore.Query(ctx, "$all", &QueryOptions{AllowFullScan: true})
+	require.ErrorIs(t, err, errMethodDisabled)
+
+	// Nothing is disabled by default
+	capabilities, err = (&arkivAPI{eth: api.eth}).Capabilities(nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{}, capabilities.DisabledMethods)
+}
@@ -0,0 +1,224 @@
+package eth
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/arkiv/fulltext"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// arkivMetricsInterval is the interval at which the size of the Arkiv store and the
+// ingest lag are collected.
+const arkivMetricsInterval = 3 * time.Second
+
+var (
+	arkivStoreSizeGauge        = metrics.NewRegisteredGauge("arkiv/store/size", nil)
+	arkivStoreEntitiesGauge    = metrics.NewRegisteredGauge("arkiv/store/entities", nil)
+	arkivIngestLagBlocksGauge  = metrics.NewRegisteredGauge("arkiv/ingest/lag/blocks", nil)
+	arkivIngestLagSecondsGauge = metrics.NewRegisteredGauge("arkiv/ingest/lag/seconds", nil)
+
+	arkivFullTextSizeGauge     = metrics.NewRegisteredGauge("arkiv/fulltext/size", nil)
+	arkivFullTextEntitiesGauge = metrics.NewRegisteredGauge("arkiv/fulltext/entities", nil)
+
+	// Query latency partitioned by the coarse shape of the query
+	arkivQueryByOwnerTimer      = metrics.NewRegisteredTimer("arkiv/query/latency/byowner", nil)
+	arkivQueryByAnnotationTimer = metrics.NewRegisteredTimer("arkiv/query/latency/byannotation", nil)
+	arkivQueryFullScanTimer     = metrics.NewRegisteredTimer("arkiv/query/latency/fullscan", nil)
+)
+
+// arkivQueryTimer returns the latency timer for the shape of the query.
+func arkivQueryTimer(query string) *metrics.Timer {
+	switch {
+	case strings.Contains(query, "$owner"):
+		return arkivQueryByOwnerTimer
+	case strings.TrimSpace(query) == "" || strings.Contains(query, "$all"):
+		return arkivQueryFullScanTimer
+	default:
+		return arkivQueryByAnnotationTimer
+	}
+}
+
+// arkivMetricsCollector periodically collects the size of the Arkiv store and how far
+// its ingestion lags behind the chain head.
+type arkivMetricsCollector struct {
+	store arkivStore
+	path  string
+	chain *core.BlockChain
+
+	// fullText is the full-text index of the store, nil if it's disabled.
+	fullText *fulltext.Index
+
+	// db is a read-only connection to the store file used to count the rows of its tables.
+	// It's nil if the sqlite driver is not available or the store is in memory.
+	db *sql.DB
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newArkivMetricsCollector(store arkivStore, path string, chain *core.BlockChain, fullText *fulltext.Index) *arkivMetricsCollector {
+	c := &arkivMetricsCollector{
+		store:    store,
+		path:     path,
+		chain:    chain,
+		fullText: fullText,
+		quit:     make(chan struct{}),
+	}
+
+	if path != ":memory:" {
+		db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?mode=ro", path))
+		if err != nil {
+			log.Warn("Arkiv row count metrics are not available", "error", err)
+		} else {
+			c.db = db
+		}
+	}
+
+	return c
+}
+
+func (c *arkivMetricsCollector) start() {
+	if !metrics.Enabled() {
+		return
+	}
+	c.wg.Add(1)
+	go c.loop()
+}
+
+func (c *arkivMetricsCollector) stop() {
+	close(c.quit)
+	c.wg.Wait()
+	if c.db != nil {
+		c.db.Close()
+	}
+}
+
+func (c *arkivMetricsCollector) loop() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(arkivMetricsInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.collect()
+		case <-c.quit:
+			return
+		}
+	}
+}
+
+func (c *arkivMetricsCollector) collect() {
+	ctx, cancel := context.WithTimeout(context.Background(), arkivMetricsInterval)
+	defer cancel()
+
+	arkivStoreSizeGauge.Update(c.fileSize())
+
+	entities, err := c.store.GetNumberOfEntities(ctx)
+	if err != nil {
+		log.Debug("Failed to collect Arkiv entity count", "error", err)
+	} else {
+		arkivStoreEntitiesGauge.Update(int64(entities))
+	}
+
+	err = c.collectRowCounts(ctx)
+	if err != nil {
+		log.Debug("Failed to collect Arkiv row counts", "error", err)
+	}
+
+	err = c.collectIngestLag(ctx)
+	if err != nil {
+		log.Debug("Failed to collect Arkiv ingest lag", "error", err)
+	}
+
+	if c.fullText != nil {
+		stats, err := c.fullText.Stats(ctx)
+		if err != nil {
+			log.Debug("Failed to collect Arkiv full-text index stats", "error", err)
+		} else {
+			arkivFullTextSizeGauge.Update(stats.Size)
+			arkivFullTextEntitiesGauge.Update(stats.Entities)
+		}
+	}
+}
+
+// fileSize returns the size of the store file including its write-ahead log.
+func (c *arkivMetricsCollector) fileSize() int64 {
+	size := int64(0)
+	for _, file := range []string{c.path, c.path + "-wal"} {
+		info, err := os.Stat(file)
+		if err == nil {
+			size += info.Size()
+		}
+	}
+	return size
+}
+
+// collectRowCounts updates the row count gauge of every table of the store.
+func (c *arkivMetricsCollector) collectRowCounts(ctx context.Context) error {
+	if c.db == nil {
+		return nil
+	}
+
+	rows, err := c.db.QueryContext(ctx, "SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'")
+	if err != nil {
+		return fmt.Errorf("failed to list tables: %w", err)
+	}
+
+	tables := []string{}
+	for rows.Next() {
+		var table string
+		err = rows.Scan(&table)
+		if err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan table name: %w", err)
+		}
+		tables = append(tables, table)
+	}
+	rows.Close()
+
+	for _, table := range tables {
+		var count int64
+		err = c.db.QueryRowContext(ctx, fmt.Sprintf(`SELECT COUNT(*) FROM "%s"`, strings.ReplaceAll(table, `"`, `""`))).Scan(&count)
+		if err != nil {
+			return fmt.Errorf("failed to count rows of %s: %w", table, err)
+		}
+		metrics.GetOrRegisterGauge("arkiv/store/rows/"+table, nil).Update(count)
+	}
+
+	return nil
+}
+
+// collectIngestLag updates how many blocks and seconds the store lags behind the chain head.
+func (c *arkivMetricsCollector) collectIngestLag(ctx context.Context) error {
+	lastBlock, err := c.store.GetLastBlock(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get last block from store: %w", err)
+	}
+
+	head := c.chain.CurrentHeader()
+	last := uint64(lastBlock)
+	if head == nil || head.Number.Uint64() <= last {
+		arkivIngestLagBlocksGauge.Update(0)
+		arkivIngestLagSecondsGauge.Update(0)
+		return nil
+	}
+
+	arkivIngestLagBlocksGauge.Update(int64(head.Number.Uint64() - last))
+
+	lastHeader := c.chain.GetHeaderByNumber(last)
+	if lastHeader != nil && head.Time >= lastHeader.Time {
+		arkivIngestLagSecondsGauge.Update(int64(head.Time - lastHeader.Time))
+	}
+
+	return nil
+}
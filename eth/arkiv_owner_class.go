@@ -0,0 +1,249 @@
+package eth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	sqlitestore "github.com/Arkiv-Network/sqlite-bitmap-store"
+	arkivaddress "github.com/ethereum/go-ethereum/arkiv/address"
+	"github.com/ethereum/go-ethereum/arkiv/limits"
+	arkivlogs "github.com/ethereum/go-ethereum/arkiv/logs"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// maxOwnerClassReads is the largest number of owners whose code a call to
+// GetEntitiesByOwnerClass reads.
+const maxOwnerClassReads = limits.MaxOwnerClassReads
+
+// ownerClass returns the class of the owner in the state: the accounts delegating
+// their code with EIP-7702 can still sign, they are EOAs.
+func ownerClass(stateDB *state.StateDB, owner common.Address) string {
+	if owner == (common.Address{}) {
+		return OwnerClassZeroAddress
+	}
+	code := stateDB.GetCode(owner)
+	if len(code) == 0 {
+		return OwnerClassEOA
+	}
+	if _, ok := types.ParseDelegation(code); ok {
+		return OwnerClassEOA
+	}
+	return OwnerClassContract
+}
+
+// GetEntitiesByOwnerClass returns a page of the entities live at the block, the current
+// block if the options don't set one, whose owner is of the class: the zero address,
+// a contract or an EOA. The owners are walked in the order of their address and
+// classified by their code in the state of the block, at most maxOwnerClassReads of
+// them per call; their entities are the ones of GetEntitiesOfOwner. The cursor pins
+// the block, so the pages of a listing are consistent.
+func (api *arkivAPI) GetEntitiesByOwnerClass(ctx context.Context, class string, opts *OwnerClassOptions) (_ *OwnerClassEntities, err error) {
+	defer func() { err = arkivRPCError(err) }()
+
+	if err := api.methods.check("getEntitiesByOwnerClass"); err != nil {
+		return nil, err
+	}
+
+	switch class {
+	case OwnerClassZeroAddress, OwnerClassContract, OwnerClassEOA:
+	default:
+		return nil, invalidRequest("unknown owner class %q: expected %s, %s or %s", class, OwnerClassZeroAddress, OwnerClassContract, OwnerClassEOA)
+	}
+	if opts == nil {
+		opts = &OwnerClassOptions{}
+	}
+	perPage := opts.ResultsPerPage
+	if perPage == 0 {
+		perPage = maxOwnerEntitiesPerPage
+	}
+	if perPage > maxOwnerEntitiesPerPage {
+		return nil, invalidRequest("page of %d entities, more than the limit of %d", perPage, maxOwnerEntitiesPerPage)
+	}
+	atBlock := opts.AtBlock
+	if opts.Cursor != nil {
+		atBlock = &opts.Cursor.Block
+	}
+	header, stateDB, err := api.headerState(atBlock)
+	if err != nil {
+		return nil, err
+	}
+	block := header.Number.Uint64()
+	if err := waitIndexed(ctx, api.store, block); err != nil {
+		return nil, err
+	}
+
+	owners := []common.Address{{}}
+	if class != OwnerClassZeroAddress {
+		owners, err = api.entityOwners(ctx, block)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if opts.Cursor != nil {
+		start, _ := slices.BinarySearchFunc(owners, opts.Cursor.Owner, func(owner, cursor common.Address) int {
+			if c := bytes.Compare(owner[:], cursor[:]); c < 0 || c == 0 && opts.Cursor.Key == nil {
+				return -1
+			}
+			return 1
+		})
+		owners = owners[start:]
+	}
+
+	page := &OwnerClassEntities{
+		Class:    class,
+		Block:    hexutil.Uint64(block),
+		Entities: []OwnerClassEntity{},
+	}
+	// done is the last owner whose entities are all listed
+	var done common.Address
+	reads := 0
+	for _, owner := range owners {
+		if uint64(len(page.Entities)) == perPage {
+			page.Cursor = &OwnerClassCursor{Block: hexutil.Uint64(block), Owner: done}
+			return page, nil
+		}
+		if class != OwnerClassZeroAddress && owner != (common.Address{}) {
+			if reads == maxOwnerClassReads {
+				page.Cursor = &OwnerClassCursor{Block: hexutil.Uint64(block), Owner: done}
+				return page, nil
+			}
+			reads++
+		}
+		if ownerClass(stateDB, owner) != class {
+			done = owner
+			continue
+		}
+
+		keys, err := api.ownerEntities(ctx, owner, block)
+		if err != nil {
+			return nil, err
+		}
+		slices.SortFunc(keys, func(a, b common.Hash) int {
+			return bytes.Compare(a[:], b[:])
+		})
+		if opts.Cursor != nil && opts.Cursor.Key != nil && owner == opts.Cursor.Owner {
+			start, _ := slices.BinarySearchFunc(keys, *opts.Cursor.Key, func(key, cursor common.Hash) int {
+				if bytes.Compare(key[:], cursor[:]) <= 0 {
+					return -1
+				}
+				return 1
+			})
+			keys = keys[start:]
+		}
+
+		left := perPage - uint64(len(page.Entities))
+		pageKeys := keys[:min(uint64(len(keys)), left)]
+		mds, errs := entity.GetEntityMetaDataBatch(stateDB, pageKeys)
+		for i, key := range pageKeys {
+			if errs[i] != nil {
+				return nil, fmt.Errorf("entity %s of %s isn't live at block %d: %w", key.Hex(), owner.Hex(), block, errs[i])
+			}
+			page.Entities = append(page.Entities, OwnerClassEntity{Key: key, Owner: owner, ExpiresAtBlock: hexutil.Uint64(mds[i].ExpiresAtBlock)})
+		}
+		if uint64(len(keys)) > left {
+			cursor := pageKeys[len(pageKeys)-1]
+			page.Cursor = &OwnerClassCursor{Block: hexutil.Uint64(block), Owner: owner, Key: &cursor}
+			return page, nil
+		}
+		done = owner
+	}
+	return page, nil
+}
+
+// entityOwners returns the owners of the entities live at the block, ordered by
+// address. They are read from the store, and from the logs of the blocks after the
+// block for the entities changed since, like ownerEntities; the list can hold owners
+// without entities at the block.
+func (api *arkivAPI) entityOwners(ctx context.Context, atBlock uint64) ([]common.Address, error) {
+	for {
+		lastBlock, err := api.store.GetLastBlock(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get last block from store: %w", err)
+		}
+		if atBlock > lastBlock {
+			return nil, notIndexed(atBlock, lastBlock, "store has not indexed block %d yet: last indexed block is %d", atBlock, lastBlock)
+		}
+		if lastBlock-atBlock > arkivMaxRewindBlocks {
+			return nil, notIndexed(atBlock, lastBlock, "block %d is more than %d blocks before the last indexed block %d", atBlock, arkivMaxRewindBlocks, lastBlock)
+		}
+
+		owners, err := storeOwners(ctx, api.store, lastBlock)
+		if err != nil {
+			return nil, err
+		}
+		// The store moved on while being queried, start over at its new block
+		if latest, err := api.store.GetLastBlock(ctx); err != nil {
+			return nil, fmt.Errorf("failed to get last block from store: %w", err)
+		} else if latest != lastBlock {
+			continue
+		}
+
+		// The owners of the entities changed since the block before the operations
+		for number := atBlock + 1; number <= lastBlock; number++ {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			block := api.eth.blockchain.GetBlockByNumber(number)
+			if block == nil {
+				return nil, fmt.Errorf("block %d not found", number)
+			}
+			for _, receipt := range api.eth.blockchain.GetReceiptsByHash(block.Hash()) {
+				for _, l := range receipt.Logs {
+					if l.Address != arkivaddress.ArkivProcessorAddress || len(l.Topics) < 3 {
+						continue
+					}
+					switch l.Topics[0] {
+					case arkivlogs.ArkivEntityCreated, arkivlogs.ArkivEntityOwnershipTransferLapsed:
+					default:
+						owners[common.BytesToAddress(l.Topics[2].Bytes())] = struct{}{}
+					}
+				}
+			}
+		}
+
+		sorted := make([]common.Address, 0, len(owners))
+		for owner := range owners {
+			sorted = append(sorted, owner)
+		}
+		slices.SortFunc(sorted, func(a, b common.Address) int {
+			return bytes.Compare(a[:], b[:])
+		})
+		return sorted, nil
+	}
+}
+
+// storeOwners returns the set of the owners of the entities of the store, which has
+// indexed the block.
+func storeOwners(ctx context.Context, s arkivStore, block uint64) (map[common.Address]struct{}, error) {
+	options := &sqlitestore.Options{
+		AtBlock:     &block,
+		IncludeData: &sqlitestore.IncludeData{Owner: true},
+	}
+	owners := map[common.Address]struct{}{}
+	for {
+		response, err := s.QueryEntities(ctx, "$all", options)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query the entities: %w", err)
+		}
+		for _, d := range response.Data {
+			ed := sqlitestore.EntityData{}
+			if err := json.Unmarshal(d, &ed); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal entity data: %w", err)
+			}
+			if ed.Owner != nil {
+				owners[*ed.Owner] = struct{}{}
+			}
+		}
+		if response.Cursor == nil || *response.Cursor == "" {
+			return owners, nil
+		}
+		options.Cursor = *response.Cursor
+	}
+}
@@ -0,0 +1,149 @@
+package eth
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"slices"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/arkiv/rpctypes"
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/beacon"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip1559"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/require"
+)
+
+// stopContractCode deploys a contract whose code is a single STOP.
+var stopContractCode = common.FromHex("0x600060005360016000f3")
+
+func TestArkivAPI_GetEntitiesByOwnerClass(t *testing.T) {
+	keyA, _ := crypto.GenerateKey()
+	keyB, _ := crypto.GenerateKey()
+	a, b := crypto.PubkeyToAddress(keyA.PublicKey), crypto.PubkeyToAddress(keyB.PublicKey)
+	contract := crypto.CreateAddress(a, 0)
+	create := func(payload string) storagetx.ArkivCreate {
+		return storagetx.ArkivCreate{BTL: 100, ContentType: "text/plain", Payload: []byte(payload)}
+	}
+
+	config := arkivConvergenceConfig(true)
+	extra := eip1559.EncodeOptimismExtraData(config, 0, 250, 6, new(uint64))
+	funds := new(big.Int).Mul(big.NewInt(params.Ether), big.NewInt(1000))
+	gspec := &core.Genesis{
+		Config:    config,
+		ExtraData: extra,
+		GasLimit:  60_000_000,
+		BaseFee:   big.NewInt(params.InitialBaseFee),
+		Alloc:     types.GenesisAlloc{a: {Balance: funds}, b: {Balance: funds}},
+	}
+	signer := types.LatestSigner(config)
+	var keys []common.Hash
+	_, chainBlocks, receipts := core.GenerateChainWithGenesis(gspec, beacon.New(ethash.NewFaker()), 3, func(i int, gen *core.BlockGen) {
+		gen.SetExtra(extra)
+		gen.AddTx(l1InfoDeposit(config, gen.Number().Uint64(), gen.Timestamp()))
+
+		switch i {
+		case 0:
+			// Block 1: A deploys the contract
+			tx, err := types.SignNewTx(keyA, signer, &types.DynamicFeeTx{
+				ChainID:   config.ChainID,
+				Nonce:     gen.TxNonce(a),
+				Gas:       100_000,
+				GasFeeCap: new(big.Int).Mul(gen.BaseFee(), big.NewInt(2)),
+				GasTipCap: big.NewInt(1),
+				Data:      stopContractCode,
+			})
+			require.NoError(t, err)
+			gen.AddTx(tx)
+		case 1:
+			// Block 2: A creates e0, e1 and e2, B creates e3
+			keys = append(keys, addArkivTx(t, gen, signer, keyA, &storagetx.ArkivTransaction{Create: []storagetx.ArkivCreate{create("e0"), create("e1"), create("e2")}})...)
+			keys = append(keys, addArkivTx(t, gen, signer, keyB, &storagetx.ArkivTransaction{Create: []storagetx.ArkivCreate{create("e3")}})...)
+		case 2:
+			// Block 3: A gives e1 and e2 to the contract
+			addArkivTx(t, gen, signer, keyA, &storagetx.ArkivTransaction{ChangeOwner: []storagetx.ArkivChangeOwner{
+				{EntityKey: keys[1], NewOwner: contract},
+				{EntityKey: keys[2], NewOwner: contract},
+			}})
+		}
+	})
+	for i, blockReceipts := range receipts {
+		for _, receipt := range blockReceipts {
+			require.Equal(t, types.ReceiptStatusSuccessful, receipt.Status, "block %d", i+1)
+		}
+	}
+	api := newIndexedAPI(t, gspec, chainBlocks, len(chainBlocks))
+
+	// The entities of the owners, ordered by owner and key
+	expected := func(owners ...common.Address) []OwnerClassEntity {
+		slices.SortFunc(owners, func(x, y common.Address) int { return bytes.Compare(x[:], y[:]) })
+		held := map[common.Address][]common.Hash{a: {keys[0]}, b: {keys[3]}, contract: {keys[1], keys[2]}}
+		entities := []OwnerClassEntity{}
+		for _, owner := range owners {
+			owned := slices.Clone(held[owner])
+			slices.SortFunc(owned, func(x, y common.Hash) int { return bytes.Compare(x[:], y[:]) })
+			for _, key := range owned {
+				entities = append(entities, OwnerClassEntity{Key: key, Owner: owner, ExpiresAtBlock: 102})
+			}
+		}
+		return entities
+	}
+	list := func(t *testing.T, class string, options *OwnerClassOptions) []OwnerClassEntity {
+		t.Helper()
+		page, err := api.GetEntitiesByOwnerClass(context.Background(), class, options)
+		require.NoError(t, err)
+		require.Equal(t, class, page.Class)
+		require.Nil(t, page.Cursor)
+		return page.Entities
+	}
+
+	require.Equal(t, expected(contract), list(t, OwnerClassContract, nil))
+	require.Equal(t, expected(a, b), list(t, OwnerClassEOA, nil))
+	require.Empty(t, list(t, OwnerClassZeroAddress, nil))
+
+	t.Run("before the change of owner", func(t *testing.T) {
+		block := hexutil.Uint64(2)
+		entities := list(t, OwnerClassEOA, &OwnerClassOptions{AtBlock: &block})
+		require.Len(t, entities, 4)
+		for _, entity := range entities {
+			require.NotEqual(t, contract, entity.Owner)
+		}
+		require.Empty(t, list(t, OwnerClassContract, &OwnerClassOptions{AtBlock: &block}))
+	})
+
+	t.Run("pages", func(t *testing.T) {
+		options := &OwnerClassOptions{ResultsPerPage: 1}
+		var entities []OwnerClassEntity
+		for {
+			page, err := api.GetEntitiesByOwnerClass(context.Background(), OwnerClassContract, options)
+			require.NoError(t, err)
+			entities = append(entities, page.Entities...)
+			if page.Cursor == nil {
+				break
+			}
+			require.Equal(t, hexutil.Uint64(3), page.Cursor.Block)
+			options.Cursor = page.Cursor
+		}
+		require.Equal(t, expected(contract), entities)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := api.GetEntitiesByOwnerClass(context.Background(), "multisig", nil)
+		require.ErrorContains(t, err, `unknown owner class "multisig"`)
+		var rpcErr rpc.Error
+		require.ErrorAs(t, err, &rpcErr)
+		require.Equal(t, rpctypes.ErrCodeValidation, rpcErr.ErrorCode())
+
+		_, err = api.GetEntitiesByOwnerClass(context.Background(), OwnerClassEOA, &OwnerClassOptions{ResultsPerPage: maxOwnerEntitiesPerPage + 1})
+		require.ErrorAs(t, err, &rpcErr)
+		require.Equal(t, rpctypes.ErrCodeValidation, rpcErr.ErrorCode())
+	})
+}
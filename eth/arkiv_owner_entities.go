@@ -0,0 +1,166 @@
+package eth
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/ethereum/go-ethereum/arkiv/limits"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// maxOwnerEntitiesPerPage is the largest page of GetEntitiesOfOwner.
+const maxOwnerEntitiesPerPage = limits.MaxOwnerEntitiesPerPage
+
+// GetEntitiesOfOwner returns a page of the keys of the entities of the owner at the
+// block, the current block if the options don't set one, with the blocks they expire
+// at. The entities are read from the owner index of the store, which follows the
+// changes of owner, and the ones changed since the block from the logs of the blocks
+// after it, see ownerEntities. Like Query, it waits for the store to index the block.
+func (api *arkivAPI) GetEntitiesOfOwner(ctx context.Context, owner common.Address, opts *OwnerEntitiesOptions) (_ *OwnerEntities, err error) {
+	defer func() { err = arkivRPCError(err) }()
+
+	if err := api.methods.check("getEntitiesOfOwner"); err != nil {
+		return nil, err
+	}
+
+	if opts == nil {
+		opts = &OwnerEntitiesOptions{}
+	}
+	perPage := opts.ResultsPerPage
+	if perPage == 0 {
+		perPage = maxOwnerEntitiesPerPage
+	}
+	if perPage > maxOwnerEntitiesPerPage {
+		return nil, invalidRequest("page of %d entities, more than the limit of %d", perPage, maxOwnerEntitiesPerPage)
+	}
+	header, stateDB, err := api.headerState(opts.AtBlock)
+	if err != nil {
+		return nil, err
+	}
+	block := header.Number.Uint64()
+	if err := waitIndexed(ctx, api.store, block); err != nil {
+		return nil, err
+	}
+
+	keys, err := api.ownerEntities(ctx, owner, block)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(keys, func(a, b common.Hash) int {
+		return bytes.Compare(a[:], b[:])
+	})
+	if opts.Cursor != nil {
+		start, _ := slices.BinarySearchFunc(keys, *opts.Cursor, func(key, cursor common.Hash) int {
+			if bytes.Compare(key[:], cursor[:]) <= 0 {
+				return -1
+			}
+			return 1
+		})
+		keys = keys[start:]
+	}
+
+	page := &OwnerEntities{
+		Owner:    owner,
+		Block:    hexutil.Uint64(block),
+		Entities: make([]OwnerEntity, 0, min(uint64(len(keys)), perPage)),
+	}
+	pageKeys := keys[:min(uint64(len(keys)), perPage)]
+	mds, errs := entity.GetEntityMetaDataBatch(stateDB, pageKeys)
+	for i, key := range pageKeys {
+		if errs[i] != nil {
+			return nil, fmt.Errorf("entity %s of %s isn't live at block %d: %w", key.Hex(), owner.Hex(), block, errs[i])
+		}
+		page.Entities = append(page.Entities, OwnerEntity{Key: key, ExpiresAtBlock: hexutil.Uint64(mds[i].ExpiresAtBlock)})
+	}
+	if uint64(len(keys)) > perPage {
+		cursor := keys[perPage-1]
+		page.Cursor = &cursor
+	}
+	return page, nil
+}
+
+// compareExpiry orders the entities by the block they expire at, and the entities
+// expiring at the same block by key, so the order is the same on every call.
+func compareExpiry(a, b ExpiryCursor) int {
+	if a.ExpiresAtBlock != b.ExpiresAtBlock {
+		return cmp.Compare(a.ExpiresAtBlock, b.ExpiresAtBlock)
+	}
+	return bytes.Compare(a.Key[:], b.Key[:])
+}
+
+// GetOwnerEntitiesByExpiry returns a page of the entities of the owner at the block,
+// the current block if the options don't set one, the soonest expiring first, with the
+// number of blocks left until they expire. The keys are the ones of GetEntitiesOfOwner,
+// joined with their metadata in the state of the block to order them.
+func (api *arkivAPI) GetOwnerEntitiesByExpiry(ctx context.Context, owner common.Address, opts *OwnerEntitiesByExpiryOptions) (_ *OwnerEntitiesByExpiry, err error) {
+	defer func() { err = arkivRPCError(err) }()
+
+	if err := api.methods.check("getOwnerEntitiesByExpiry"); err != nil {
+		return nil, err
+	}
+
+	if opts == nil {
+		opts = &OwnerEntitiesByExpiryOptions{}
+	}
+	perPage := opts.ResultsPerPage
+	if perPage == 0 {
+		perPage = maxOwnerEntitiesPerPage
+	}
+	if perPage > maxOwnerEntitiesPerPage {
+		return nil, invalidRequest("page of %d entities, more than the limit of %d", perPage, maxOwnerEntitiesPerPage)
+	}
+	header, stateDB, err := api.headerState(opts.AtBlock)
+	if err != nil {
+		return nil, err
+	}
+	block := header.Number.Uint64()
+	if err := waitIndexed(ctx, api.store, block); err != nil {
+		return nil, err
+	}
+
+	keys, err := api.ownerEntities(ctx, owner, block)
+	if err != nil {
+		return nil, err
+	}
+	entities := make([]ExpiryCursor, 0, len(keys))
+	mds, errs := entity.GetEntityMetaDataBatch(stateDB, keys)
+	for i, key := range keys {
+		if errs[i] != nil {
+			return nil, fmt.Errorf("entity %s of %s isn't live at block %d: %w", key.Hex(), owner.Hex(), block, errs[i])
+		}
+		entities = append(entities, ExpiryCursor{ExpiresAtBlock: hexutil.Uint64(mds[i].ExpiresAtBlock), Key: key})
+	}
+	slices.SortFunc(entities, compareExpiry)
+	if opts.Cursor != nil {
+		start, _ := slices.BinarySearchFunc(entities, *opts.Cursor, func(e, cursor ExpiryCursor) int {
+			if compareExpiry(e, cursor) <= 0 {
+				return -1
+			}
+			return 1
+		})
+		entities = entities[start:]
+	}
+
+	page := &OwnerEntitiesByExpiry{
+		Owner:    owner,
+		Block:    hexutil.Uint64(block),
+		Entities: make([]ExpiringEntity, 0, min(uint64(len(entities)), perPage)),
+	}
+	for _, e := range entities[:min(uint64(len(entities)), perPage)] {
+		page.Entities = append(page.Entities, ExpiringEntity{
+			Key:             e.Key,
+			ExpiresAtBlock:  e.ExpiresAtBlock,
+			BlocksRemaining: hexutil.Uint64(uint64(e.ExpiresAtBlock) - block),
+		})
+	}
+	if uint64(len(entities)) > perPage {
+		cursor := entities[perPage-1]
+		page.Cursor = &cursor
+	}
+	return page, nil
+}
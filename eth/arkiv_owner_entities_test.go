@@ -0,0 +1,195 @@
+package eth
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"slices"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/arkiv/rpctypes"
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArkivAPI_GetEntitiesOfOwner(t *testing.T) {
+	keyA, _ := crypto.GenerateKey()
+	keyB, _ := crypto.GenerateKey()
+	a, b := crypto.PubkeyToAddress(keyA.PublicKey), crypto.PubkeyToAddress(keyB.PublicKey)
+	create := func(payload string) storagetx.ArkivCreate {
+		return storagetx.ArkivCreate{BTL: 100, ContentType: "text/plain", Payload: []byte(payload)}
+	}
+	var created []common.Hash
+	steps := []arkivTestStep{
+		// Block 1: A creates e0, e1 and e2
+		func([]common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			return keyA, &storagetx.ArkivTransaction{Create: []storagetx.ArkivCreate{create("e0"), create("e1"), create("e2")}}
+		},
+		// Block 2: A gives e1 to B
+		func(keys []common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			return keyA, &storagetx.ArkivTransaction{ChangeOwner: []storagetx.ArkivChangeOwner{{EntityKey: keys[1], NewOwner: b}}}
+		},
+		// Block 3: A deletes e2
+		func(keys []common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			return keyA, &storagetx.ArkivTransaction{Delete: []common.Hash{keys[2]}}
+		},
+		// Block 4: B creates e3
+		func([]common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			return keyB, &storagetx.ArkivTransaction{Create: []storagetx.ArkivCreate{create("e3")}}
+		},
+		// Block 5: nothing
+		func(keys []common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			created = keys
+			return nil, nil
+		},
+	}
+	at := func(block uint64) *OwnerEntitiesOptions {
+		return &OwnerEntitiesOptions{AtBlock: (*hexutil.Uint64)(&block)}
+	}
+	keysOf := func(t *testing.T, api *arkivAPI, owner common.Address, options *OwnerEntitiesOptions) []common.Hash {
+		t.Helper()
+		page, err := api.GetEntitiesOfOwner(context.Background(), owner, options)
+		require.NoError(t, err)
+		require.Equal(t, owner, page.Owner)
+		require.Nil(t, page.Cursor)
+		keys := []common.Hash{}
+		for _, entity := range page.Entities {
+			keys = append(keys, entity.Key)
+		}
+		return keys
+	}
+	sorted := func(keys ...common.Hash) []common.Hash {
+		keys = append([]common.Hash{}, keys...)
+		slices.SortFunc(keys, func(a, b common.Hash) int { return bytes.Compare(a[:], b[:]) })
+		return keys
+	}
+
+	api, _ := newArkivTestAPI(t, keyA, keyB, steps, len(steps))
+
+	// e1 moved to B with the change of owner
+	require.Equal(t, []common.Hash{created[0]}, keysOf(t, api, a, nil))
+	require.Equal(t, sorted(created[1], created[3]), keysOf(t, api, b, nil))
+
+	// Before the change of owner and the deletion
+	for _, indexed := range []int{len(steps), 3} {
+		api, _ := newArkivTestAPI(t, keyA, keyB, steps, indexed)
+		require.Equal(t, sorted(created[:3]...), keysOf(t, api, a, at(1)), "%d indexed", indexed)
+		require.Equal(t, []common.Hash{}, keysOf(t, api, b, at(1)), "%d indexed", indexed)
+		require.Equal(t, sorted(created[0], created[2]), keysOf(t, api, a, at(2)), "%d indexed", indexed)
+		require.Equal(t, []common.Hash{created[1]}, keysOf(t, api, b, at(2)), "%d indexed", indexed)
+	}
+
+	t.Run("expiry", func(t *testing.T) {
+		page, err := api.GetEntitiesOfOwner(context.Background(), b, at(4))
+		require.NoError(t, err)
+		expiries := map[common.Hash]hexutil.Uint64{}
+		for _, entity := range page.Entities {
+			expiries[entity.Key] = entity.ExpiresAtBlock
+		}
+		require.Equal(t, map[common.Hash]hexutil.Uint64{created[1]: 101, created[3]: 104}, expiries)
+	})
+
+	t.Run("pages", func(t *testing.T) {
+		options := at(1)
+		options.ResultsPerPage = 2
+		page, err := api.GetEntitiesOfOwner(context.Background(), a, options)
+		require.NoError(t, err)
+		all := sorted(created[:3]...)
+		require.Len(t, page.Entities, 2)
+		require.Equal(t, all[1], *page.Cursor)
+
+		options.Cursor = page.Cursor
+		require.Equal(t, all[2:], keysOf(t, api, a, options))
+	})
+
+	t.Run("limits", func(t *testing.T) {
+		_, err := api.GetEntitiesOfOwner(context.Background(), a, &OwnerEntitiesOptions{ResultsPerPage: maxOwnerEntitiesPerPage + 1})
+		var rpcErr rpc.Error
+		require.ErrorAs(t, err, &rpcErr)
+		require.Equal(t, rpctypes.ErrCodeValidation, rpcErr.ErrorCode())
+
+		// The store hasn't indexed the head
+		behind, _ := newArkivTestAPI(t, keyA, keyB, steps, 3)
+		_, err = behind.GetEntitiesOfOwner(context.Background(), a, nil)
+		require.ErrorAs(t, err, &rpcErr)
+		require.Equal(t, rpctypes.ErrCodeNotIndexed, rpcErr.ErrorCode())
+	})
+}
+
+func TestArkivAPI_GetOwnerEntitiesByExpiry(t *testing.T) {
+	keyA, _ := crypto.GenerateKey()
+	keyB, _ := crypto.GenerateKey()
+	a := crypto.PubkeyToAddress(keyA.PublicKey)
+	create := func(payload string, btl uint64) storagetx.ArkivCreate {
+		return storagetx.ArkivCreate{BTL: btl, ContentType: "text/plain", Payload: []byte(payload)}
+	}
+	var created []common.Hash
+	steps := []arkivTestStep{
+		// Block 1: A creates e0, e2 and e3 expiring at block 101 and e1 at block 51
+		func([]common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			return keyA, &storagetx.ArkivTransaction{Create: []storagetx.ArkivCreate{
+				create("e0", 100), create("e1", 50), create("e2", 100), create("e3", 100),
+			}}
+		},
+		// Block 2: nothing
+		func(keys []common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			created = keys
+			return nil, nil
+		},
+	}
+	api, _ := newArkivTestAPI(t, keyA, keyB, steps, len(steps))
+
+	// The entities expiring at the same block are ordered by key
+	tied := []common.Hash{created[0], created[2], created[3]}
+	slices.SortFunc(tied, func(a, b common.Hash) int { return bytes.Compare(a[:], b[:]) })
+	want := []ExpiringEntity{{Key: created[1], ExpiresAtBlock: 51, BlocksRemaining: 49}}
+	for _, key := range tied {
+		want = append(want, ExpiringEntity{Key: key, ExpiresAtBlock: 101, BlocksRemaining: 99})
+	}
+
+	page, err := api.GetOwnerEntitiesByExpiry(context.Background(), a, nil)
+	require.NoError(t, err)
+	require.Equal(t, a, page.Owner)
+	require.Equal(t, hexutil.Uint64(2), page.Block)
+	require.Equal(t, want, page.Entities)
+	require.Nil(t, page.Cursor)
+
+	t.Run("pages", func(t *testing.T) {
+		// Every page size walks the entities in the same order, across the tie
+		for _, perPage := range []uint64{1, 2, 3} {
+			options := &OwnerEntitiesByExpiryOptions{ResultsPerPage: perPage}
+			var got []ExpiringEntity
+			for {
+				page, err := api.GetOwnerEntitiesByExpiry(context.Background(), a, options)
+				require.NoError(t, err)
+				got = append(got, page.Entities...)
+				if page.Cursor == nil {
+					break
+				}
+				last := page.Entities[len(page.Entities)-1]
+				require.Equal(t, ExpiryCursor{ExpiresAtBlock: last.ExpiresAtBlock, Key: last.Key}, *page.Cursor)
+				options.Cursor = page.Cursor
+			}
+			require.Equal(t, want, got, "%d per page", perPage)
+		}
+	})
+
+	t.Run("atBlock", func(t *testing.T) {
+		block := uint64(1)
+		page, err := api.GetOwnerEntitiesByExpiry(context.Background(), a, &OwnerEntitiesByExpiryOptions{AtBlock: (*hexutil.Uint64)(&block)})
+		require.NoError(t, err)
+		require.Len(t, page.Entities, 4)
+		require.Equal(t, hexutil.Uint64(50), page.Entities[0].BlocksRemaining)
+	})
+
+	t.Run("limits", func(t *testing.T) {
+		_, err := api.GetOwnerEntitiesByExpiry(context.Background(), a, &OwnerEntitiesByExpiryOptions{ResultsPerPage: maxOwnerEntitiesPerPage + 1})
+		var rpcErr rpc.Error
+		require.ErrorAs(t, err, &rpcErr)
+		require.Equal(t, rpctypes.ErrCodeValidation, rpcErr.ErrorCode())
+	})
+}
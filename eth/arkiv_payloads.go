@@ -0,0 +1,109 @@
+package eth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	arkivevents "github.com/Arkiv-Network/arkiv-events"
+	"github.com/ethereum/go-ethereum/arkiv/compression"
+	"github.com/ethereum/go-ethereum/arkiv/dbevents"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/node"
+)
+
+// arkivContentResolveBackoff is the wait between the fetches of a payload stored off
+// chain.
+const arkivContentResolveBackoff = time.Second
+
+// checkArkivPayloadStorage checks the store keeps its payloads the way the node is
+// configured to. A store that hasn't indexed any block yet takes the configured mode.
+func checkArkivPayloadStorage(db ethdb.KeyValueStore, lastBlock uint64, compressed bool) error {
+	if lastBlock == 0 {
+		rawdb.WriteArkivStoreCompressedPayloads(db, compressed)
+		return nil
+	}
+	if rawdb.ReadArkivStoreCompressedPayloads(db) == compressed {
+		return nil
+	}
+	if compressed {
+		return fmt.Errorf("the Arkiv store keeps its payloads uncompressed, remove it to compress them")
+	}
+	return fmt.Errorf("the Arkiv store keeps its payloads compressed, remove it or set --arkiv.store.compress")
+}
+
+// resolveArkivContent fetches the payloads stored off chain of the batches from the
+// data availability service of the node before they're indexed, they stay pending on
+// a node without one. The fetches stop with the context of the pipeline.
+func resolveArkivContent(ctx context.Context, nodeConfig *node.Config, iterator arkivevents.BatchIterator) (arkivevents.BatchIterator, error) {
+	if nodeConfig.ArkivContentResolverURL == "" {
+		return iterator, nil
+	}
+	resolver, err := dbevents.NewHTTPContentResolver(nodeConfig.ArkivContentResolverURL, nodeConfig.ArkivContentResolveTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return dbevents.ResolveExternalContent(ctx, iterator, resolver, nodeConfig.ArkivContentResolveAttempts, nodeConfig.ArkivContentResolveTimeout, arkivContentResolveBackoff), nil
+}
+
+// acceptedCodecs returns the ids of the codecs named by the AcceptEncoding option.
+func acceptedCodecs(names []string) (map[byte]bool, error) {
+	accepted := map[byte]bool{}
+	for _, name := range names {
+		found := false
+		for codec, codecName := range compression.CodecNames {
+			if codecName == name {
+				accepted[codec] = true
+				found = true
+			}
+		}
+		if !found {
+			return nil, invalidRequest("unsupported payload encoding %q", name)
+		}
+	}
+	return accepted, nil
+}
+
+// storePayload returns the payload of an entity read from the store as it was written.
+func storePayload(compressed bool, value []byte) ([]byte, error) {
+	if !compressed || value == nil {
+		return value, nil
+	}
+	return compression.DecompressPayload(value)
+}
+
+// decodeStorePayloads decompresses the payloads of the entities returned by a store
+// keeping them compressed. The payloads compressed with an accepted codec are served
+// as they are stored instead, with the name of their codec.
+func decodeStorePayloads(data []json.RawMessage, accepted map[byte]bool) error {
+	for i, d := range data {
+		var ed EntityData
+		if err := json.Unmarshal(d, &ed); err != nil {
+			return fmt.Errorf("failed to unmarshal entity data: %w", err)
+		}
+		if ed.Value == nil {
+			continue
+		}
+		codec, compressed, err := compression.DecodePayload(ed.Value)
+		if err != nil {
+			return err
+		}
+		switch {
+		case codec == compression.CodecIdentity:
+			ed.Value = compressed
+		case accepted[codec]:
+			ed.Value = compressed
+			ed.ValueEncoding = compression.CodecNames[codec]
+		default:
+			if ed.Value, err = compression.DecompressPayload(ed.Value); err != nil {
+				return err
+			}
+		}
+		if data[i], err = json.Marshal(&ed); err != nil {
+			return fmt.Errorf("error marshalling entity data: %w", err)
+		}
+	}
+	return nil
+}
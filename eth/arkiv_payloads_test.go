@@ -0,0 +1,159 @@
+package eth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	arkivevents "github.com/Arkiv-Network/arkiv-events"
+	"github.com/Arkiv-Network/arkiv-events/events"
+	sqlitestore "github.com/Arkiv-Network/sqlite-bitmap-store"
+	"github.com/ethereum/go-ethereum/arkiv/compression"
+	"github.com/ethereum/go-ethereum/arkiv/dbevents"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	compressiblePayload   = bytes.Repeat([]byte(`{"city":"prague","country":"czechia"}`), 20)
+	incompressiblePayload = []byte("x")
+)
+
+// newPayloadsArkivAPI returns an API over a store of an entity with a compressible
+// payload and one with an incompressible payload, compressed if compressed is set.
+func newPayloadsArkivAPI(t *testing.T, compressed bool) (*arkivAPI, []common.Hash) {
+	t.Helper()
+
+	store, err := sqlitestore.NewSQLiteStore(slog.New(slog.DiscardHandler), filepath.Join(t.TempDir(), "arkiv.db"), 1)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		store.Close()
+	})
+
+	keys := []common.Hash{common.HexToHash("0x01"), common.HexToHash("0x02")}
+	create := func(key common.Hash, payload []byte) events.Operation {
+		return events.Operation{Create: &events.OPCreate{
+			Key:               key,
+			ContentType:       "application/json",
+			BTL:               100,
+			Content:           payload,
+			StringAttributes:  map[string]string{},
+			NumericAttributes: map[string]uint64{},
+		}}
+	}
+	block := events.Block{Number: 1, Operations: []events.Operation{
+		create(keys[0], compressiblePayload),
+		create(keys[1], incompressiblePayload),
+	}}
+	block.Operations[1].OpIndex = 1
+	var iterator arkivevents.BatchIterator = func(yield func(arkivevents.BatchOrError) bool) {
+		yield(arkivevents.BatchOrError{Batch: events.BlockBatch{Blocks: []events.Block{block}}})
+	}
+	if compressed {
+		iterator = dbevents.CompressPayloads(iterator)
+	}
+	require.NoError(t, store.FollowEvents(context.Background(), iterator))
+
+	return &arkivAPI{store: store, compressedPayloads: compressed}, keys
+}
+
+func queryPayloads(t *testing.T, api *arkivAPI, acceptEncoding ...string) []EntityData {
+	t.Helper()
+
+	atBlock := uint64(1)
+	response, err := api.Query(context.Background(), "$all", &QueryOptions{
+		Options: sqlitestore.Options{
+			AtBlock:     &atBlock,
+			IncludeData: &sqlitestore.IncludeData{Key: true, Payload: true},
+		},
+		AllowFullScan:  true,
+		AcceptEncoding: acceptEncoding,
+	})
+	require.NoError(t, err)
+
+	entities := []EntityData{}
+	for _, data := range response.Data {
+		var ed EntityData
+		require.NoError(t, json.Unmarshal(data, &ed))
+		entities = append(entities, ed)
+	}
+	require.Len(t, entities, 2)
+	if *entities[0].Key != common.HexToHash("0x01") {
+		entities[0], entities[1] = entities[1], entities[0]
+	}
+	return entities
+}
+
+func TestArkivAPI_QueryCompressedPayloads(t *testing.T) {
+	api, _ := newPayloadsArkivAPI(t, true)
+
+	// The payloads are decompressed unless the client accepts their codec
+	entities := queryPayloads(t, api)
+	require.Equal(t, compressiblePayload, []byte(entities[0].Value))
+	require.Empty(t, entities[0].ValueEncoding)
+	require.Equal(t, incompressiblePayload, []byte(entities[1].Value))
+	require.Empty(t, entities[1].ValueEncoding)
+
+	entities = queryPayloads(t, api, "br")
+	require.Equal(t, "br", entities[0].ValueEncoding)
+	require.Less(t, len(entities[0].Value), len(compressiblePayload))
+	// The content hash is the hash of the decompressed payload
+	payload, err := compression.BrotliDecompress(entities[0].Value)
+	require.NoError(t, err)
+	require.Equal(t, crypto.Keccak256Hash(compressiblePayload), crypto.Keccak256Hash(payload))
+	// Payloads that don't compress are kept as they are
+	require.Equal(t, incompressiblePayload, []byte(entities[1].Value))
+	require.Empty(t, entities[1].ValueEncoding)
+
+	atBlock := uint64(1)
+	_, err = api.Query(context.Background(), "$all", &QueryOptions{
+		Options:        sqlitestore.Options{AtBlock: &atBlock},
+		AcceptEncoding: []string{"gzip"},
+	})
+	require.ErrorContains(t, err, `unsupported payload encoding "gzip"`)
+}
+
+func TestArkivAPI_QueryUncompressedPayloads(t *testing.T) {
+	api, _ := newPayloadsArkivAPI(t, false)
+
+	// A store keeping the payloads uncompressed serves them as they are
+	entities := queryPayloads(t, api, "br")
+	require.Equal(t, compressiblePayload, []byte(entities[0].Value))
+	require.Empty(t, entities[0].ValueEncoding)
+}
+
+func TestArkivCompressedPayloadsReaders(t *testing.T) {
+	api, keys := newPayloadsArkivAPI(t, true)
+	ctx := context.Background()
+
+	entity, err := storeEntity(ctx, api.store, true, keys[0], 1)
+	require.NoError(t, err)
+	require.Equal(t, compressiblePayload, entity.payload)
+
+	payloads := map[common.Hash][]byte{}
+	for entity, err := range arkivStoreEntities(ctx, api.store, true, 1) {
+		require.NoError(t, err)
+		payloads[entity.Key] = entity.Payload
+	}
+	require.Equal(t, map[common.Hash][]byte{keys[0]: compressiblePayload, keys[1]: incompressiblePayload}, payloads)
+}
+
+func TestCheckArkivPayloadStorage(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+
+	// A new store takes the configured mode, and keeps it
+	require.NoError(t, checkArkivPayloadStorage(db, 0, true))
+	require.NoError(t, checkArkivPayloadStorage(db, 10, true))
+	require.ErrorContains(t, checkArkivPayloadStorage(db, 10, false), "keeps its payloads compressed")
+
+	require.NoError(t, checkArkivPayloadStorage(db, 0, false))
+	require.ErrorContains(t, checkArkivPayloadStorage(db, 10, true), "keeps its payloads uncompressed")
+
+	// Stores created before the mode was recorded keep their payloads uncompressed
+	require.NoError(t, checkArkivPayloadStorage(rawdb.NewMemoryDatabase(), 10, false))
+}
@@ -0,0 +1,411 @@
+package eth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/Arkiv-Network/arkiv-events/events"
+	sqlitestore "github.com/Arkiv-Network/sqlite-bitmap-store"
+	"github.com/Arkiv-Network/sqlite-bitmap-store/query"
+	"github.com/ethereum/go-ethereum/arkiv/dbevents"
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+const (
+	// arkivPendingViewMaxBlocks is the number of blocks past the last block indexed by
+	// the store a pending view overlays, the most recent ones if the store lags further.
+	arkivPendingViewMaxBlocks = 16
+
+	// arkivPendingViewMaxTxs is the number of pooled transactions of the sender a
+	// pending view overlays.
+	arkivPendingViewMaxTxs = 64
+)
+
+// overlayEntity is an entity as changed by operations the store hasn't indexed.
+type overlayEntity struct {
+	contentType string
+	payload     []byte
+	strs        map[string]string
+	nums        map[string]uint64
+	deleted     bool
+	provenance  EntityProvenance
+}
+
+// arkivOverlay holds the entities changed by the operations of a pending view.
+type arkivOverlay struct {
+	// indexedBlock is the block the store is queried at, the operations of the later
+	// blocks are overlaid.
+	indexedBlock uint64
+
+	entities map[common.Hash]*overlayEntity
+	// order holds the keys of the entities in the order they were last changed.
+	order []common.Hash
+
+	// base reads an entity from the store.
+	base func(key common.Hash) (*overlayEntity, error)
+}
+
+// pendingOverlay decodes the operations of the blocks up to atBlock the store hasn't
+// indexed yet, and of the pooled transactions of the sender of the view.
+func (api *arkivAPI) pendingOverlay(ctx context.Context, view *PendingView, atBlock uint64) (*arkivOverlay, error) {
+	lastIndexed, err := api.store.GetLastBlock(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last block from store: %w", err)
+	}
+
+	overlay := &arkivOverlay{
+		indexedBlock: min(atBlock, lastIndexed),
+		entities:     map[common.Hash]*overlayEntity{},
+	}
+	overlay.base = func(key common.Hash) (*overlayEntity, error) {
+		return storeEntity(ctx, api.store, api.compressedPayloads, key, overlay.indexedBlock)
+	}
+
+	from := max(lastIndexed+1, atBlock-min(atBlock, arkivPendingViewMaxBlocks-1))
+	for number := from; number <= atBlock; number++ {
+		block := api.eth.blockchain.GetBlockByNumber(number)
+		if block == nil {
+			break
+		}
+		decoded, _, err := dbevents.BlockToEvents(block, api.eth.blockchain.GetReceiptsByHash(block.Hash()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode block %d: %w", number, err)
+		}
+		if err := overlay.apply(decoded.Number, decoded.Operations, EntityProvenanceHeadOverlay); err != nil {
+			return nil, err
+		}
+	}
+
+	if view.Sender != nil {
+		head := api.eth.blockchain.CurrentHeader()
+		twoStepTransfers := api.eth.blockchain.Config().ArkivOwnershipTransferWindowAt(head.Time) > 0
+		// The extensions of the pending transactions count from the expiry the
+		// transactions before them leave
+		var lookupErr error
+		expiresAt := func(key common.Hash) (uint64, error) {
+			entity, err := overlay.lookup(key)
+			if err != nil {
+				lookupErr = err
+				return 0, err
+			}
+			if entity == nil {
+				return 0, fmt.Errorf("entity %s not found", key.Hex())
+			}
+			return entity.nums["$expiration"], nil
+		}
+		pending, _ := api.eth.txPool.ContentFrom(*view.Sender)
+		for i, tx := range pending[:min(len(pending), arkivPendingViewMaxTxs)] {
+			operations, err := dbevents.PendingTransactionToEvents(tx, uint64(i), *view.Sender, twoStepTransfers, head.Number.Uint64()+1, expiresAt)
+			if lookupErr != nil {
+				return nil, lookupErr
+			}
+			if err != nil {
+				// The transaction fails when it's executed
+				continue
+			}
+			if err := overlay.apply(head.Number.Uint64()+1, operations, EntityProvenancePending); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return overlay, nil
+}
+
+// storeEntity reads an entity with all its attributes from the store, nil if the
+// store doesn't hold it.
+func storeEntity(ctx context.Context, store arkivStore, compressedPayloads bool, key common.Hash, atBlock uint64) (*overlayEntity, error) {
+	response, err := store.QueryEntities(ctx, fmt.Sprintf("$key = %s", key.Hex()), &sqlitestore.Options{
+		AtBlock: &atBlock,
+		IncludeData: &sqlitestore.IncludeData{
+			Payload:             true,
+			ContentType:         true,
+			Attributes:          true,
+			SyntheticAttributes: true,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read entity %s: %w", key.Hex(), err)
+	}
+	if len(response.Data) == 0 {
+		return nil, nil
+	}
+
+	var ed sqlitestore.EntityData
+	if err := json.Unmarshal(response.Data[0], &ed); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal entity data: %w", err)
+	}
+	payload, err := storePayload(compressedPayloads, ed.Value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the payload of %s: %w", key.Hex(), err)
+	}
+	entity := &overlayEntity{
+		payload:    payload,
+		strs:       ed.StringAttributes,
+		nums:       ed.NumericAttributes,
+		provenance: EntityProvenanceIndexed,
+	}
+	if ed.ContentType != nil {
+		entity.contentType = *ed.ContentType
+	}
+	return entity, nil
+}
+
+// lookup returns the entity as changed by the operations applied so far.
+func (o *arkivOverlay) lookup(key common.Hash) (*overlayEntity, error) {
+	if entity, ok := o.entities[key]; ok {
+		if entity.deleted {
+			return nil, nil
+		}
+		return entity, nil
+	}
+	return o.base(key)
+}
+
+func (o *arkivOverlay) set(key common.Hash, entity *overlayEntity) {
+	o.order = slices.DeleteFunc(o.order, func(k common.Hash) bool { return k == key })
+	o.order = append(o.order, key)
+	o.entities[key] = entity
+}
+
+// apply applies the operations of a block to the overlay the way the store applies
+// them, skipping the operations on entities it can't find.
+func (o *arkivOverlay) apply(blockNumber uint64, operations []events.Operation, provenance EntityProvenance) error {
+	for _, operation := range operations {
+		switch {
+		case operation.Create != nil:
+			create := operation.Create
+			strs := maps.Clone(create.StringAttributes)
+			strs["$owner"] = strings.ToLower(create.Owner.Hex())
+			strs["$creator"] = strings.ToLower(create.Owner.Hex())
+			strs["$key"] = strings.ToLower(create.Key.Hex())
+
+			nums := maps.Clone(create.NumericAttributes)
+			nums["$expiration"] = blockNumber + create.BTL
+			nums["$createdAtBlock"] = blockNumber
+			nums["$lastModifiedAtBlock"] = blockNumber
+			nums["$sequence"] = blockNumber<<32 | operation.TxIndex<<16 | operation.OpIndex
+			nums["$txIndex"] = operation.TxIndex
+			nums["$opIndex"] = operation.OpIndex
+
+			o.set(create.Key, &overlayEntity{
+				contentType: create.ContentType,
+				payload:     create.Content,
+				strs:        strs,
+				nums:        nums,
+				provenance:  provenance,
+			})
+
+		case operation.Update != nil:
+			update := operation.Update
+			old, err := o.lookup(update.Key)
+			if err != nil {
+				return err
+			}
+			if old == nil {
+				continue
+			}
+			strs := maps.Clone(update.StringAttributes)
+			strs["$owner"] = strings.ToLower(update.Owner.Hex())
+			strs["$creator"] = old.strs["$creator"]
+			strs["$key"] = strings.ToLower(update.Key.Hex())
+
+			nums := maps.Clone(update.NumericAttributes)
+			nums["$expiration"] = blockNumber + update.BTL
+			nums["$lastModifiedAtBlock"] = blockNumber
+			for _, name := range []string{"$createdAtBlock", "$sequence", "$txIndex", "$opIndex"} {
+				nums[name] = old.nums[name]
+			}
+
+			o.set(update.Key, &overlayEntity{
+				contentType: update.ContentType,
+				payload:     update.Content,
+				strs:        strs,
+				nums:        nums,
+				provenance:  provenance,
+			})
+
+		case operation.ExtendBTL != nil:
+			old, err := o.lookup(operation.ExtendBTL.Key)
+			if err != nil {
+				return err
+			}
+			if old == nil {
+				continue
+			}
+			extended := *old
+			extended.nums = maps.Clone(old.nums)
+			extended.nums["$expiration"] = blockNumber + operation.ExtendBTL.BTL
+			extended.provenance = provenance
+			o.set(operation.ExtendBTL.Key, &extended)
+
+		case operation.ChangeOwner != nil:
+			old, err := o.lookup(operation.ChangeOwner.Key)
+			if err != nil {
+				return err
+			}
+			if old == nil {
+				continue
+			}
+			changed := *old
+			changed.strs = maps.Clone(old.strs)
+			changed.strs["$owner"] = strings.ToLower(operation.ChangeOwner.Owner.Hex())
+			changed.provenance = provenance
+			o.set(operation.ChangeOwner.Key, &changed)
+
+		case operation.Delete != nil:
+			o.set(common.Hash(*operation.Delete), &overlayEntity{deleted: true, provenance: provenance})
+
+		case operation.Expire != nil:
+			o.set(common.Hash(*operation.Expire), &overlayEntity{deleted: true, provenance: provenance})
+		}
+	}
+	return nil
+}
+
+// merge overlays the entities of the view on the response of the store to the query,
+// and sets the provenance of the returned entities. The entities changed by the
+// overlay replace their indexed version, and the ones matching the query are added on
+// top of the first page, the most recently changed first. Entities only match the
+// text option if their indexed payload matched it.
+func (o *arkivOverlay) merge(req string, op *QueryOptions, textMatches []common.Hash, response *QueryResponse) error {
+	include := op.GetIncludeData()
+
+	indexed := response.Data
+	response.Data = []json.RawMessage{}
+	response.Provenance = []EntityProvenance{}
+
+	if op.Cursor == "" {
+		matches, err := overlayMatcher(req, op, textMatches)
+		if err != nil {
+			return err
+		}
+
+		for _, key := range slices.Backward(o.order) {
+			entity := o.entities[key]
+			if entity.deleted || !matches(key, entity) {
+				continue
+			}
+			data, err := json.Marshal(entity.data(key, include))
+			if err != nil {
+				return fmt.Errorf("error marshalling entity data: %w", err)
+			}
+			response.Data = append(response.Data, data)
+			response.Provenance = append(response.Provenance, entity.provenance)
+		}
+	}
+
+	for _, data := range indexed {
+		var ed EntityData
+		if err := json.Unmarshal(data, &ed); err != nil {
+			return fmt.Errorf("failed to unmarshal entity data: %w", err)
+		}
+		if ed.Key != nil {
+			if _, changed := o.entities[*ed.Key]; changed {
+				continue
+			}
+		}
+		if !include.Key {
+			// The key was only requested to find the changed entities
+			ed.Key = nil
+			var err error
+			if data, err = json.Marshal(&ed); err != nil {
+				return fmt.Errorf("error marshalling entity data: %w", err)
+			}
+		}
+		response.Data = append(response.Data, data)
+		response.Provenance = append(response.Provenance, EntityProvenanceIndexed)
+	}
+	return nil
+}
+
+// overlayMatcher returns whether an entity rebuilt from the operations of the chain
+// matches the query and its options. Entities only match the text option if their
+// indexed payload matched it.
+func overlayMatcher(req string, op *QueryOptions, textMatches []common.Hash) (func(common.Hash, *overlayEntity) bool, error) {
+	ast, err := query.Parse(req)
+	if err != nil {
+		return nil, invalidRequest("error parsing query: %w", err)
+	}
+	var prefixAttribute string
+	if op.KeyPrefix != "" {
+		prefix, err := parseKeyPrefix(op.KeyPrefix)
+		if err != nil {
+			return nil, err
+		}
+		prefixAttribute = storagetx.AnnotationKeyPrefixAttributePrefix + prefix
+	}
+	return func(key common.Hash, entity *overlayEntity) bool {
+		switch {
+		case !matchQuery(ast, entity.strs, entity.nums):
+			return false
+		case prefixAttribute != "" && entity.strs[prefixAttribute] == "":
+			return false
+		case op.Text != "" && !slices.Contains(textMatches, key):
+			return false
+		}
+		return true
+	}, nil
+}
+
+// data returns the fields of the entity requested by the query, the way the store
+// returns them.
+func (e *overlayEntity) data(key common.Hash, include sqlitestore.IncludeData) *sqlitestore.EntityData {
+	ed := &sqlitestore.EntityData{}
+	if include.Key {
+		ed.Key = &key
+	}
+	if include.Payload {
+		ed.Value = hexutil.Bytes(e.payload)
+	}
+	if include.ContentType {
+		ed.ContentType = &e.contentType
+	}
+	if include.Attributes || include.SyntheticAttributes {
+		ed.StringAttributes = filterEntityAttributes(e.strs, include)
+		ed.NumericAttributes = filterEntityAttributes(e.nums, include)
+	}
+	numeric := func(name string) *uint64 {
+		value := e.nums[name]
+		return &value
+	}
+	if include.Expiration {
+		ed.ExpiresAt = numeric("$expiration")
+	}
+	if include.Owner {
+		owner := common.HexToAddress(e.strs["$owner"])
+		ed.Owner = &owner
+	}
+	if include.CreatedAtBlock {
+		ed.CreatedAtBlock = numeric("$createdAtBlock")
+	}
+	if include.LastModifiedAtBlock {
+		ed.LastModifiedAtBlock = numeric("$lastModifiedAtBlock")
+	}
+	if include.TransactionIndexInBlock {
+		ed.TransactionIndexInBlock = numeric("$txIndex")
+	}
+	if include.OperationIndexInTransaction {
+		ed.OperationIndexInTransaction = numeric("$opIndex")
+	}
+	return ed
+}
+
+// filterEntityAttributes returns the attributes requested by the query, the synthetic
+// ones starting with $.
+func filterEntityAttributes[T any](attributes map[string]T, include sqlitestore.IncludeData) map[string]T {
+	filtered := map[string]T{}
+	for name, value := range attributes {
+		synthetic := strings.HasPrefix(name, "$")
+		if (synthetic && include.SyntheticAttributes) || (!synthetic && include.Attributes) {
+			filtered[name] = value
+		}
+	}
+	return filtered
+}
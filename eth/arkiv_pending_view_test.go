@@ -0,0 +1,133 @@
+package eth
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	arkivevents "github.com/Arkiv-Network/arkiv-events"
+	"github.com/Arkiv-Network/arkiv-events/events"
+	sqlitestore "github.com/Arkiv-Network/sqlite-bitmap-store"
+	"github.com/ethereum/go-ethereum/arkiv/dbevents"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/beacon"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/stretchr/testify/require"
+)
+
+// newLaggingArkivAPI returns an API over a chain of the blocks whose store only
+// indexed the first indexed blocks.
+func newLaggingArkivAPI(t *testing.T, blocks int, indexed int) (*arkivAPI, []common.Hash) {
+	t.Helper()
+
+	gspec, chainBlocks, keys := arkivConvergenceChain(t, arkivConvergenceConfig(true), blocks)
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), gspec, beacon.New(ethash.NewFaker()), nil)
+	require.NoError(t, err)
+	t.Cleanup(chain.Stop)
+	_, err = chain.InsertChain(chainBlocks)
+	require.NoError(t, err)
+
+	store, err := sqlitestore.NewSQLiteStore(slog.New(slog.DiscardHandler), filepath.Join(t.TempDir(), "arkiv.db"), 1)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		store.Close()
+	})
+
+	batch := events.BlockBatch{}
+	for _, block := range chainBlocks[:indexed] {
+		decoded, _, err := dbevents.BlockToEvents(block, chain.GetReceiptsByHash(block.Hash()))
+		require.NoError(t, err)
+		batch.Blocks = append(batch.Blocks, *decoded)
+	}
+	iterator := func(yield func(arkivevents.BatchOrError) bool) {
+		yield(arkivevents.BatchOrError{Batch: batch})
+	}
+	require.NoError(t, store.FollowEvents(context.Background(), iterator))
+
+	return &arkivAPI{eth: &Ethereum{blockchain: chain}, store: store}, keys
+}
+
+func queryEntities(t *testing.T, response *QueryResponse) []sqlitestore.EntityData {
+	t.Helper()
+
+	entities := []sqlitestore.EntityData{}
+	for _, data := range response.Data {
+		var ed sqlitestore.EntityData
+		require.NoError(t, json.Unmarshal(data, &ed))
+		entities = append(entities, ed)
+	}
+	return entities
+}
+
+func TestArkivAPI_QueryPendingView(t *testing.T) {
+	api, keys := newLaggingArkivAPI(t, 5, 2)
+	pendingView := func(query string) *QueryResponse {
+		response, err := api.Query(context.Background(), query, &QueryOptions{PendingView: &PendingView{}})
+		require.NoError(t, err, query)
+		require.Len(t, response.Provenance, len(response.Data), query)
+		return response
+	}
+
+	// The entity created at block 4 is only visible through the overlay
+	created := "$key = " + keys[6].Hex()
+	atBlock := uint64(2)
+	response, err := api.Query(context.Background(), created, &QueryOptions{Options: sqlitestore.Options{AtBlock: &atBlock}})
+	require.NoError(t, err)
+	require.Empty(t, response.Data)
+	require.Nil(t, response.Provenance)
+
+	response = pendingView(created)
+	entities := queryEntities(t, response)
+	require.Len(t, entities, 1)
+	require.Equal(t, keys[6], *entities[0].Key)
+	require.Equal(t, []EntityProvenance{EntityProvenanceHeadOverlay}, response.Provenance)
+
+	// The overlay replaces the indexed version of the entities it changes
+	response = pendingView(`kind = "updated"`)
+	entities = queryEntities(t, response)
+	require.Len(t, entities, 1)
+	require.Equal(t, keys[1], *entities[0].Key)
+	require.Equal(t, "updated", string(entities[0].Value))
+	require.Equal(t, []EntityProvenance{EntityProvenanceHeadOverlay}, response.Provenance)
+
+	// Deleted and expired entities are gone
+	require.Empty(t, pendingView("$key = "+keys[7].Hex()).Data)
+	require.Empty(t, pendingView("$key = "+keys[0].Hex()).Data)
+
+	response = pendingView("$all")
+	provenance := map[common.Hash]EntityProvenance{}
+	for i, entity := range queryEntities(t, response) {
+		provenance[*entity.Key] = response.Provenance[i]
+	}
+	require.Equal(t, map[common.Hash]EntityProvenance{
+		keys[1]: EntityProvenanceHeadOverlay,
+		keys[2]: EntityProvenanceIndexed,
+		keys[3]: EntityProvenanceHeadOverlay,
+		keys[4]: EntityProvenanceHeadOverlay,
+		keys[5]: EntityProvenanceHeadOverlay,
+		keys[6]: EntityProvenanceHeadOverlay,
+	}, provenance)
+
+	// The overlay is never written to the store
+	lastBlock, err := api.store.GetLastBlock(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), lastBlock)
+}
+
+func TestArkivAPI_QueryPendingViewKeyOnlyRequested(t *testing.T) {
+	api, keys := newLaggingArkivAPI(t, 5, 2)
+
+	// The keys used to merge the overlay are not returned unless requested
+	response, err := api.Query(context.Background(), "$key = "+keys[2].Hex(), &QueryOptions{
+		Options:     sqlitestore.Options{IncludeData: &sqlitestore.IncludeData{ContentType: true}},
+		PendingView: &PendingView{},
+	})
+	require.NoError(t, err)
+	require.Len(t, response.Data, 1)
+	require.JSONEq(t, `{"contentType":"text/plain"}`, string(response.Data[0]))
+	require.Equal(t, []EntityProvenance{EntityProvenanceIndexed}, response.Provenance)
+}
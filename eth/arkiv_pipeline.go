@@ -0,0 +1,78 @@
+package eth
+
+import (
+	"context"
+	"time"
+
+	arkivevents "github.com/Arkiv-Network/arkiv-events"
+	"github.com/ethereum/go-ethereum/arkiv/dbevents"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// arkivDrainTimeout is how long the shutdown waits for the store to ingest the batch
+// it's on, its ingestion is canceled and rolled back past it.
+const arkivDrainTimeout = 30 * time.Second
+
+// arkivPipeline follows the Arkiv events of the chain into the store. It's a lifecycle
+// of the node registered after the Ethereum service, so that it's stopped before the
+// chain database and the store are closed.
+type arkivPipeline struct {
+	timeout time.Duration
+
+	// stopping ends the iterator once the batch being ingested is done, aborting cancels
+	// the ingestion of the batch.
+	stopping context.Context
+	stop     context.CancelFunc
+	aborting context.Context
+	abort    context.CancelFunc
+	done     chan struct{}
+}
+
+// newArkivPipeline returns a pipeline waiting up to timeout for the batch being
+// ingested on shutdown. The iterator it follows must end once stopping is canceled.
+func newArkivPipeline(timeout time.Duration) *arkivPipeline {
+	p := &arkivPipeline{timeout: timeout, done: make(chan struct{})}
+	p.stopping, p.stop = context.WithCancel(context.Background())
+	p.aborting, p.abort = context.WithCancel(context.Background())
+	return p
+}
+
+// follow starts ingesting the batches of the iterator with the ingester. The ingestion
+// starts with the Ethereum service rather than in Start, the startup self-check sends a
+// tick through it.
+func (p *arkivPipeline) follow(ingester *dbevents.Ingester, iterator arkivevents.BatchIterator) {
+	go func() {
+		defer close(p.done)
+		if err := ingester.Follow(p.aborting, iterator); err != nil && p.aborting.Err() == nil {
+			log.Error("failed to follow events", "error", err)
+		}
+	}()
+}
+
+// watch runs the watchdog of the iterator until the pipeline is stopped.
+func (p *arkivPipeline) watch(watchdog *dbevents.Watchdog) {
+	go watchdog.Run(p.stopping)
+}
+
+// Start implements node.Lifecycle, the ingestion is already running.
+func (p *arkivPipeline) Start() error {
+	return nil
+}
+
+// Stop lets the store finish the batch it's on, committing it along with its
+// checkpoint, and stops the ingestion. A batch not ingested within the timeout is
+// canceled and rolled back, the next start ingests it again.
+func (p *arkivPipeline) Stop() error {
+	p.stop()
+	timer := time.NewTimer(p.timeout)
+	defer timer.Stop()
+	select {
+	case <-p.done:
+		return nil
+	case <-timer.C:
+	}
+	log.Warn("Arkiv store didn't finish its batch before the shutdown timeout, canceling it", "timeout", p.timeout)
+	p.abort()
+	<-p.done
+	return nil
+}
@@ -0,0 +1,154 @@
+package eth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Arkiv-Network/arkiv-events/events"
+	sqlitestore "github.com/Arkiv-Network/sqlite-bitmap-store"
+	"github.com/ethereum/go-ethereum/arkiv/dbevents"
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/beacon"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/stretchr/testify/require"
+)
+
+// newArkivPipelineChain returns the database of a chain with an entity created in each
+// of its 3 blocks, its blocks and a store.
+func newArkivPipelineChain(t *testing.T) (ethdb.Database, []*types.Block, *sqlitestore.SQLiteStore) {
+	t.Helper()
+
+	key, _ := crypto.GenerateKey()
+	step := func([]common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+		return key, &storagetx.ArkivTransaction{Create: []storagetx.ArkivCreate{{BTL: 100, ContentType: "text/plain", Payload: []byte("e")}}}
+	}
+	gspec, blocks, _ := newArkivTestChain(t, key, key, []arkivTestStep{step, step, step})
+	db := rawdb.NewMemoryDatabase()
+	chain, err := core.NewBlockChain(db, gspec, beacon.New(ethash.NewFaker()), nil)
+	require.NoError(t, err)
+	t.Cleanup(chain.Stop)
+	_, err = chain.InsertChain(blocks)
+	require.NoError(t, err)
+
+	store, err := sqlitestore.NewSQLiteStore(slog.New(slog.DiscardHandler), filepath.Join(t.TempDir(), "arkiv.db"), 1)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		store.Close()
+	})
+	return db, blocks, store
+}
+
+// startArkivPipeline starts a pipeline ingesting the chain into the store from its last
+// block, 2 blocks per batch, with ingest, and notifies it about the head. It returns the
+// pipeline and the hooks notifying it.
+func startArkivPipeline(t *testing.T, db ethdb.Database, head *types.Block, store *sqlitestore.SQLiteStore, timeout time.Duration, ingest dbevents.IngestFunc) (*arkivPipeline, *dbevents.Hooks) {
+	t.Helper()
+
+	lastBlock := func() (uint64, error) {
+		return store.GetLastBlock(context.Background())
+	}
+	last, err := lastBlock()
+	require.NoError(t, err)
+
+	pipeline := newArkivPipeline(timeout)
+	hooks := dbevents.NewHooks(db, 0)
+	iterator, tracker := dbevents.NewChainBatchIterator(db, hooks, last, false,
+		dbevents.WithBatchSize(2),
+		dbevents.WithContext(pipeline.stopping),
+		dbevents.WithCheckpoint(),
+	)
+	ingester, err := dbevents.NewIngester(dbevents.IngesterConfig{}, ingest, lastBlock, tracker)
+	require.NoError(t, err)
+	pipeline.follow(ingester, dbevents.VerifyContinuity(iterator.Events(), lastBlock, tracker))
+	require.NoError(t, hooks.OnNewBlock(arkivConvergenceConfig(true), head))
+	return pipeline, hooks
+}
+
+func TestArkivPipeline_Shutdown(t *testing.T) {
+	follow := func(store *sqlitestore.SQLiteStore) dbevents.IngestFunc {
+		return func(ctx context.Context, batch events.BlockBatch) error {
+			return store.FollowEvents(ctx, dbevents.SingleBatch(batch))
+		}
+	}
+	restart := func(t *testing.T, db ethdb.Database, blocks []*types.Block, store *sqlitestore.SQLiteStore) {
+		t.Helper()
+
+		// The next run continues from the checkpoint, the continuity check passes
+		head := blocks[len(blocks)-1]
+		pipeline, hooks := startArkivPipeline(t, db, head, store, time.Minute, follow(store))
+		require.Eventually(t, func() bool {
+			last, err := store.GetLastBlock(context.Background())
+			if err == nil && last < 3 {
+				// A head per batch
+				require.NoError(t, hooks.OnNewBlock(arkivConvergenceConfig(true), head))
+			}
+			return err == nil && last == 3
+		}, 5*time.Second, 10*time.Millisecond)
+		require.NoError(t, pipeline.Stop())
+		require.Equal(t, &rawdb.ArkivEventsCheckpoint{Number: 3, Hash: blocks[2].Hash()}, rawdb.ReadArkivEventsCheckpoint(db))
+		entities, err := store.GetNumberOfEntities(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, uint64(3), entities)
+	}
+
+	t.Run("drains the batch", func(t *testing.T) {
+		db, blocks, store := newArkivPipelineChain(t)
+		started, release := make(chan struct{}), make(chan struct{})
+		pipeline, _ := startArkivPipeline(t, db, blocks[2], store, time.Minute, func(ctx context.Context, batch events.BlockBatch) error {
+			close(started)
+			<-release
+			return follow(store)(ctx, batch)
+		})
+
+		// The node shuts down in the middle of the first batch
+		<-started
+		stopped := make(chan error)
+		go func() {
+			stopped <- pipeline.Stop()
+		}()
+		select {
+		case <-stopped:
+			t.Fatal("the pipeline stopped before its batch was ingested")
+		case <-time.After(50 * time.Millisecond):
+		}
+		close(release)
+		require.NoError(t, <-stopped)
+
+		last, err := store.GetLastBlock(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, uint64(2), last)
+		require.Equal(t, &rawdb.ArkivEventsCheckpoint{Number: 2, Hash: blocks[1].Hash()}, rawdb.ReadArkivEventsCheckpoint(db))
+
+		restart(t, db, blocks, store)
+	})
+
+	t.Run("rolls back past the timeout", func(t *testing.T) {
+		db, blocks, store := newArkivPipelineChain(t)
+		started := make(chan struct{})
+		pipeline, _ := startArkivPipeline(t, db, blocks[2], store, 50*time.Millisecond, func(ctx context.Context, batch events.BlockBatch) error {
+			close(started)
+			<-ctx.Done()
+			return ctx.Err()
+		})
+
+		<-started
+		require.NoError(t, pipeline.Stop())
+
+		last, err := store.GetLastBlock(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, uint64(0), last)
+		require.Nil(t, rawdb.ReadArkivEventsCheckpoint(db))
+
+		restart(t, db, blocks, store)
+	})
+}
@@ -0,0 +1,353 @@
+package eth
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"maps"
+	"slices"
+
+	arkivaddress "github.com/ethereum/go-ethereum/arkiv/address"
+	"github.com/ethereum/go-ethereum/arkiv/limits"
+	arkivlogs "github.com/ethereum/go-ethereum/arkiv/logs"
+	"github.com/ethereum/go-ethereum/arkiv/webhook"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/filtermaps"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const (
+	// maxProcessorLogsScanBlocks is the largest number of blocks a call to
+	// GetProcessorLogs scans, a day of 2s blocks. Longer ranges are paginated.
+	maxProcessorLogsScanBlocks = limits.MaxProcessorLogsScanBlocks
+
+	// defaultProcessorLogsLimit and maxProcessorLogsLimit are the default and largest
+	// numbers of logs GetProcessorLogs returns.
+	defaultProcessorLogsLimit = 1_000
+	maxProcessorLogsLimit     = limits.MaxProcessorLogsLimit
+
+	// The ways GetProcessorLogs finds the logs: with the log index of the node, or by
+	// reading the receipts of the blocks whose bloom filter matches.
+	processorLogsPathIndex = "index"
+	processorLogsPathBloom = "bloom"
+)
+
+// processorLogKinds are the topics of the logs of the processor by kind, the kinds of
+// the webhook events, the steps of the ownership transfers, the changes of the aliases,
+// the deduplicated creates and the BTL reductions.
+var processorLogKinds = map[string]common.Hash{
+	webhook.KindCreated:      arkivlogs.ArkivEntityCreated,
+	webhook.KindUpdated:      arkivlogs.ArkivEntityUpdated,
+	webhook.KindDeleted:      arkivlogs.ArkivEntityDeleted,
+	webhook.KindExpired:      arkivlogs.ArkivEntityExpired,
+	webhook.KindExtended:     arkivlogs.ArkivEntityBTLExtended,
+	webhook.KindOwnerChanged: arkivlogs.ArkivEntityOwnerChanged,
+	"transferProposed":       arkivlogs.ArkivEntityOwnershipTransferProposed,
+	"transferAccepted":       arkivlogs.ArkivEntityOwnershipTransferAccepted,
+	"transferLapsed":         arkivlogs.ArkivEntityOwnershipTransferLapsed,
+	"aliasRegistered":        arkivlogs.ArkivAliasRegistered,
+	"aliasTransferred":       arkivlogs.ArkivAliasTransferred,
+	"aliasExpired":           arkivlogs.ArkivAliasExpired,
+	"createDeduplicated":     arkivlogs.ArkivCreateDeduplicated,
+	"btlReduced":             arkivlogs.ArkivEntityBTLReduced,
+}
+
+// processorLogsCursor is the position of the next log to return.
+type processorLogsCursor struct {
+	block, txIndex, logIndex uint64
+}
+
+func (c processorLogsCursor) encode() string {
+	b := make([]byte, 24)
+	binary.BigEndian.PutUint64(b, c.block)
+	binary.BigEndian.PutUint64(b[8:], c.txIndex)
+	binary.BigEndian.PutUint64(b[16:], c.logIndex)
+	return hexutil.Encode(b)
+}
+
+func decodeProcessorLogsCursor(s string) (processorLogsCursor, error) {
+	b, err := hexutil.Decode(s)
+	if err != nil || len(b) != 24 {
+		return processorLogsCursor{}, invalidRequest("invalid cursor %q", s)
+	}
+	return processorLogsCursor{
+		block:    binary.BigEndian.Uint64(b),
+		txIndex:  binary.BigEndian.Uint64(b[8:]),
+		logIndex: binary.BigEndian.Uint64(b[16:]),
+	}, nil
+}
+
+// before returns whether the log comes before the position.
+func (c processorLogsCursor) before(l *types.Log) bool {
+	if l.BlockNumber != c.block {
+		return l.BlockNumber < c.block
+	}
+	if uint64(l.TxIndex) != c.txIndex {
+		return uint64(l.TxIndex) < c.txIndex
+	}
+	return uint64(l.Index) < c.logIndex
+}
+
+// GetProcessorLogs returns the decoded logs of the processor from fromBlock to toBlock,
+// oldest first, optionally filtered by owner and kind, or their counts by kind in
+// buckets of blocks. A call scans 43200 blocks at most and returns a cursor to resume
+// from when the range has more logs. The logs are found with the log index of the
+// node when it covers the blocks scanned, and by reading the receipts of the blocks
+// whose bloom filter matches otherwise. The response tells which.
+func (api *arkivAPI) GetProcessorLogs(ctx context.Context, fromBlock hexutil.Uint64, toBlock hexutil.Uint64, options *ProcessorLogsOptions) (_ *ProcessorLogs, err error) {
+	defer func() { err = arkivRPCError(err) }()
+
+	if err := api.methods.check("getProcessorLogs"); err != nil {
+		return nil, err
+	}
+
+	if options == nil {
+		options = &ProcessorLogsOptions{}
+	}
+	from, to := uint64(fromBlock), uint64(toBlock)
+	if from > to {
+		return nil, invalidRequest("fromBlock %d is after toBlock %d", from, to)
+	}
+	head := api.eth.blockchain.CurrentHeader().Number.Uint64()
+	if to > head {
+		return nil, invalidRequest("block is in the future: head is %d", head)
+	}
+
+	limit := uint64(defaultProcessorLogsLimit)
+	if options.Limit != 0 {
+		limit = min(options.Limit, maxProcessorLogsLimit)
+	}
+
+	kindTopics := []common.Hash{}
+	for _, kind := range options.Kinds {
+		topic, ok := processorLogKinds[kind]
+		if !ok {
+			return nil, invalidRequest("unknown log kind %q", kind)
+		}
+		kindTopics = append(kindTopics, topic)
+	}
+	if len(kindTopics) == 0 {
+		for _, topic := range processorLogKinds {
+			kindTopics = append(kindTopics, topic)
+		}
+	}
+	topics := [][]common.Hash{kindTopics, nil}
+	if options.Owner != nil {
+		topics = append(topics, []common.Hash{common.BytesToHash(options.Owner.Bytes())})
+	}
+
+	cursor := processorLogsCursor{block: from}
+	if options.Cursor != "" {
+		var err error
+		if cursor, err = decodeProcessorLogsCursor(options.Cursor); err != nil {
+			return nil, err
+		}
+		if cursor.block < from || cursor.block > to {
+			return nil, invalidRequest("cursor at block %d is out of the range", cursor.block)
+		}
+	}
+
+	last := min(to, cursor.block+maxProcessorLogsScanBlocks-1)
+	matches, path, err := api.processorLogs(ctx, cursor.block, last, topics)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &ProcessorLogs{
+		Path:          path,
+		BlocksScanned: last - cursor.block + 1,
+	}
+	var buckets map[uint64]*ProcessorLogBucket
+	if options.BucketSize != 0 {
+		buckets = map[uint64]*ProcessorLogBucket{}
+	}
+	for _, l := range matches {
+		if cursor.before(l) {
+			continue
+		}
+		decoded := decodeProcessorLog(l)
+		if buckets != nil {
+			start := from + (l.BlockNumber-from)/options.BucketSize*options.BucketSize
+			bucket := buckets[start]
+			if bucket == nil {
+				bucket = &ProcessorLogBucket{
+					FromBlock: hexutil.Uint64(start),
+					ToBlock:   hexutil.Uint64(min(start+options.BucketSize-1, to)),
+					Counts:    map[string]uint64{},
+				}
+				buckets[start] = bucket
+			}
+			bucket.Counts[decoded.Kind]++
+			continue
+		}
+		if uint64(len(response.Logs)) == limit {
+			next := processorLogsCursor{block: l.BlockNumber, txIndex: uint64(l.TxIndex), logIndex: uint64(l.Index)}.encode()
+			response.Cursor = &next
+			response.BlocksScanned = l.BlockNumber - cursor.block + 1
+			return response, nil
+		}
+		response.Logs = append(response.Logs, decoded)
+	}
+	for _, start := range slices.Sorted(maps.Keys(buckets)) {
+		response.Buckets = append(response.Buckets, *buckets[start])
+	}
+	if last < to {
+		next := processorLogsCursor{block: last + 1}.encode()
+		response.Cursor = &next
+	}
+	return response, nil
+}
+
+// processorLogs returns the logs of the processor matching the topics in the blocks,
+// in chain order, and the path used to find them.
+func (api *arkivAPI) processorLogs(ctx context.Context, first, last uint64, topics [][]common.Hash) ([]*types.Log, string, error) {
+	addresses := []common.Address{arkivaddress.ArkivProcessorAddress}
+	if api.eth.filterMaps != nil {
+		matches, ok, err := api.indexedProcessorLogs(ctx, api.eth.filterMaps.NewMatcherBackend(), first, last, addresses, topics)
+		if err != nil {
+			return nil, "", err
+		}
+		if ok {
+			return matches, processorLogsPathIndex, nil
+		}
+	}
+
+	var matches []*types.Log
+	for number := first; number <= last; number++ {
+		if err := ctx.Err(); err != nil {
+			return nil, "", err
+		}
+		header := api.eth.blockchain.GetHeaderByNumber(number)
+		if header == nil {
+			return nil, "", fmt.Errorf("block %d not found", number)
+		}
+		if !bloomMatches(header.Bloom, addresses, topics) {
+			continue
+		}
+		for _, receipt := range api.eth.blockchain.GetReceiptsByHash(header.Hash()) {
+			for _, l := range receipt.Logs {
+				if logMatches(l, addresses, topics) {
+					matches = append(matches, l)
+				}
+			}
+		}
+	}
+	return matches, processorLogsPathBloom, nil
+}
+
+// indexedProcessorLogs searches the log index, if it covers the blocks and doesn't
+// change during the search.
+func (api *arkivAPI) indexedProcessorLogs(ctx context.Context, backend filtermaps.MatcherBackend, first, last uint64, addresses []common.Address, topics [][]common.Hash) ([]*types.Log, bool, error) {
+	defer backend.Close()
+
+	blocks := common.NewRange(first, last-first+1)
+	sync, err := backend.SyncLogIndex(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	if sync.IndexedBlocks.Intersection(blocks) != blocks {
+		return nil, false, nil
+	}
+	potential, err := filtermaps.GetPotentialMatches(ctx, backend, first, last, addresses, topics)
+	if err != nil {
+		return nil, false, err
+	}
+	if sync, err = backend.SyncLogIndex(ctx); err != nil {
+		return nil, false, err
+	}
+	if sync.ValidBlocks.Intersection(blocks) != blocks {
+		return nil, false, nil
+	}
+
+	var matches []*types.Log
+	for _, l := range potential {
+		if logMatches(l, addresses, topics) {
+			// The logs may be shared with the caches of the index
+			copied := *l
+			matches = append(matches, &copied)
+		}
+	}
+	if err := api.fillLogTxHashes(matches); err != nil {
+		return nil, false, err
+	}
+	return matches, true, nil
+}
+
+// fillLogTxHashes sets the transaction hashes the log index doesn't keep.
+func (api *arkivAPI) fillLogTxHashes(logs []*types.Log) error {
+	var block *types.Block
+	for _, l := range logs {
+		if l.TxHash != (common.Hash{}) {
+			continue
+		}
+		if block == nil || block.Hash() != l.BlockHash {
+			if block = api.eth.blockchain.GetBlockByHash(l.BlockHash); block == nil {
+				return fmt.Errorf("block %s not found", l.BlockHash.Hex())
+			}
+		}
+		if int(l.TxIndex) >= len(block.Transactions()) {
+			return fmt.Errorf("transaction %d of block %d not found", l.TxIndex, l.BlockNumber)
+		}
+		l.TxHash = block.Transactions()[l.TxIndex].Hash()
+	}
+	return nil
+}
+
+// logMatches returns whether the log is emitted by one of the addresses and matches
+// the topics, a nil or empty position matching any topic.
+func logMatches(l *types.Log, addresses []common.Address, topics [][]common.Hash) bool {
+	if len(addresses) > 0 && !slices.Contains(addresses, l.Address) {
+		return false
+	}
+	if len(topics) > len(l.Topics) {
+		return false
+	}
+	for i, sub := range topics {
+		if len(sub) > 0 && !slices.Contains(sub, l.Topics[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// bloomMatches returns whether the bloom filter may hold a log matching the addresses
+// and topics.
+func bloomMatches(bloom types.Bloom, addresses []common.Address, topics [][]common.Hash) bool {
+	if len(addresses) > 0 && !slices.ContainsFunc(addresses, func(a common.Address) bool {
+		return types.BloomLookup(bloom, a)
+	}) {
+		return false
+	}
+	for _, sub := range topics {
+		if len(sub) > 0 && !slices.ContainsFunc(sub, func(topic common.Hash) bool {
+			return types.BloomLookup(bloom, topic)
+		}) {
+			return false
+		}
+	}
+	return true
+}
+
+// decodeProcessorLog decodes a log matching the kinds of processorLogKinds.
+func decodeProcessorLog(l *types.Log) ProcessorLog {
+	decoded := ProcessorLog{
+		BlockNumber: hexutil.Uint64(l.BlockNumber),
+		TxHash:      l.TxHash,
+		TxIndex:     hexutil.Uint(l.TxIndex),
+		LogIndex:    hexutil.Uint(l.Index),
+		Key:         l.Topics[1],
+		Owner:       common.BytesToAddress(l.Topics[2].Bytes()),
+		Data:        l.Data,
+	}
+	for kind, topic := range processorLogKinds {
+		if topic == l.Topics[0] {
+			decoded.Kind = kind
+		}
+	}
+	if len(l.Topics) > 3 {
+		newOwner := common.BytesToAddress(l.Topics[3].Bytes())
+		decoded.NewOwner = &newOwner
+	}
+	return decoded
+}
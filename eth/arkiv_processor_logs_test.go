@@ -0,0 +1,131 @@
+package eth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetProcessorLogs(t *testing.T) {
+	keyA, _ := crypto.GenerateKey()
+	keyB, _ := crypto.GenerateKey()
+	a, b := crypto.PubkeyToAddress(keyA.PublicKey), crypto.PubkeyToAddress(keyB.PublicKey)
+
+	create := func(payload string, btl uint64) storagetx.ArkivCreate {
+		return storagetx.ArkivCreate{BTL: btl, ContentType: "text/plain", Payload: []byte(payload)}
+	}
+	steps := []arkivTestStep{
+		// Block 1: A creates e0, e1 expiring at block 3, and e2
+		func([]common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			return keyA, &storagetx.ArkivTransaction{Create: []storagetx.ArkivCreate{create("e0", 100), create("e1", 2), create("e2", 100)}}
+		},
+		// Block 2: B creates e3
+		func([]common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			return keyB, &storagetx.ArkivTransaction{Create: []storagetx.ArkivCreate{create("e3", 100)}}
+		},
+		// Block 3: e1 expires, nothing else
+		func([]common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) { return nil, nil },
+		// Block 4: A gives e0 to B and deletes e2
+		func(keys []common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			return keyA, &storagetx.ArkivTransaction{
+				ChangeOwner: []storagetx.ArkivChangeOwner{{EntityKey: keys[0], NewOwner: b}},
+				Delete:      []common.Hash{keys[2]},
+			}
+		},
+	}
+	api, _ := newArkivTestAPI(t, keyA, keyB, steps, len(steps))
+	ctx := context.Background()
+
+	kinds := func(logs []ProcessorLog) []string {
+		var kinds []string
+		for _, l := range logs {
+			kinds = append(kinds, l.Kind)
+		}
+		return kinds
+	}
+
+	all, err := api.GetProcessorLogs(ctx, 0, 4, nil)
+	require.NoError(t, err)
+	require.Equal(t, processorLogsPathBloom, all.Path)
+	require.Equal(t, uint64(5), all.BlocksScanned)
+	require.Nil(t, all.Cursor)
+	require.Equal(t, []string{"created", "created", "created", "created", "expired", "deleted", "ownerChanged"}, kinds(all.Logs))
+	for i := 1; i < len(all.Logs); i++ {
+		require.NotEqual(t, common.Hash{}, all.Logs[i].TxHash)
+	}
+	changed := all.Logs[6]
+	require.Equal(t, a, changed.Owner)
+	require.Equal(t, &b, changed.NewOwner)
+
+	// The owner is the third topic, the previous owner of e0 for its change
+	owned, err := api.GetProcessorLogs(ctx, 0, 4, &ProcessorLogsOptions{Owner: &b})
+	require.NoError(t, err)
+	require.Equal(t, []string{"created"}, kinds(owned.Logs))
+
+	filtered, err := api.GetProcessorLogs(ctx, 0, 4, &ProcessorLogsOptions{Owner: &a, Kinds: []string{"created", "deleted"}})
+	require.NoError(t, err)
+	require.Equal(t, []string{"created", "created", "created", "deleted"}, kinds(filtered.Logs))
+
+	// Pages of 3 logs resume where the previous one stopped
+	var paged []ProcessorLog
+	options := &ProcessorLogsOptions{Limit: 3}
+	for {
+		page, err := api.GetProcessorLogs(ctx, 0, 4, options)
+		require.NoError(t, err)
+		paged = append(paged, page.Logs...)
+		if page.Cursor == nil {
+			break
+		}
+		options.Cursor = *page.Cursor
+	}
+	require.Equal(t, all.Logs, paged)
+
+	counts, err := api.GetProcessorLogs(ctx, 1, 4, &ProcessorLogsOptions{BucketSize: 2})
+	require.NoError(t, err)
+	require.Empty(t, counts.Logs)
+	require.Equal(t, []ProcessorLogBucket{
+		{FromBlock: 1, ToBlock: 2, Counts: map[string]uint64{"created": 4}},
+		{FromBlock: 3, ToBlock: 4, Counts: map[string]uint64{"expired": 1, "deleted": 1, "ownerChanged": 1}},
+	}, counts.Buckets)
+}
+
+func TestGetProcessorLogsErrors(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	nothing := func([]common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) { return nil, nil }
+	api, _ := newArkivTestAPI(t, key, key, []arkivTestStep{nothing, nothing, nothing}, 3)
+	ctx := context.Background()
+
+	_, err := api.GetProcessorLogs(ctx, 2, 1, nil)
+	require.ErrorContains(t, err, "fromBlock 2 is after toBlock 1")
+
+	_, err = api.GetProcessorLogs(ctx, 0, 4, nil)
+	require.ErrorContains(t, err, "block is in the future: head is 3")
+
+	_, err = api.GetProcessorLogs(ctx, 0, 3, &ProcessorLogsOptions{Kinds: []string{"renamed"}})
+	require.ErrorContains(t, err, `unknown log kind "renamed"`)
+
+	_, err = api.GetProcessorLogs(ctx, 0, 3, &ProcessorLogsOptions{Cursor: "0x01"})
+	require.ErrorContains(t, err, "invalid cursor")
+
+	cursor := processorLogsCursor{block: 5}.encode()
+	_, err = api.GetProcessorLogs(ctx, 0, 3, &ProcessorLogsOptions{Cursor: cursor})
+	require.ErrorContains(t, err, "cursor at block 5 is out of the range")
+
+	logs, err := api.GetProcessorLogs(ctx, 0, 3, nil)
+	require.NoError(t, err)
+	require.Empty(t, logs.Logs)
+	require.Equal(t, uint64(4), logs.BlocksScanned)
+}
+
+func TestProcessorLogsCursor(t *testing.T) {
+	cursor := processorLogsCursor{block: 1 << 40, txIndex: 3, logIndex: 7}
+	decoded, err := decodeProcessorLogsCursor(cursor.encode())
+	require.NoError(t, err)
+	require.Equal(t, cursor, decoded)
+	require.Equal(t, "0x"+"0000010000000000"+"0000000000000003"+"0000000000000007", cursor.encode())
+}
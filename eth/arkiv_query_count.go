@@ -0,0 +1,93 @@
+package eth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sqlitestore "github.com/Arkiv-Network/sqlite-bitmap-store"
+	"github.com/Arkiv-Network/sqlite-bitmap-store/query"
+	"github.com/Arkiv-Network/sqlite-bitmap-store/store"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// arkivIndexWait is how long a count waits for the store to index its block, like the
+// store does for the queries.
+const arkivIndexWait = 3 * time.Second
+
+// QueryCount returns the number of entities matching the query, without reading them.
+// The query is evaluated to a bitmap in every store it's routed to, whose cardinalities
+// are summed. Like Query, it waits for the store to index the block of the options,
+// the current block by default, and counts the entities of the store once it has.
+func (api *arkivAPI) QueryCount(ctx context.Context, req string, op *sqlitestore.Options) (_ *QueryCount, err error) {
+	defer func() { err = arkivRPCError(err) }()
+
+	if err := api.methods.check("queryCount"); err != nil {
+		return nil, err
+	}
+
+	startTime := time.Now()
+
+	atBlock := api.eth.blockchain.CurrentHeader().Number.Uint64()
+	if op != nil && op.AtBlock != nil {
+		atBlock = *op.AtBlock
+	}
+	ast, err := query.Parse(req)
+	if err != nil {
+		return nil, invalidRequest("error parsing query: %w", err)
+	}
+	if err := waitIndexed(ctx, api.store, atBlock); err != nil {
+		return nil, err
+	}
+
+	result := &QueryCount{}
+	for i, shard := range arkivStores(api.store, req) {
+		err := shard.ReadTransaction(ctx, func(q *store.Queries) error {
+			bitmap, err := ast.Evaluate(ctx, q)
+			if err != nil {
+				return err
+			}
+			lastBlock, err := q.GetLastBlock(ctx)
+			if err != nil {
+				return err
+			}
+			result.Count += bitmap.GetCardinality()
+			// The count is as of the shard the least ahead
+			if i == 0 || hexutil.Uint64(lastBlock) < result.Block {
+				result.Block = hexutil.Uint64(lastBlock)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error counting query: %w", err)
+		}
+	}
+
+	elapsed := time.Since(startTime)
+	log.Info("arkiv api", "count", req, "block", atBlock, "matches", result.Count, "elapsed_ms", elapsed.Milliseconds())
+
+	return result, nil
+}
+
+// waitIndexed waits up to arkivIndexWait for the store to index the block.
+func waitIndexed(ctx context.Context, s arkivStore, block uint64) error {
+	deadline := time.NewTimer(arkivIndexWait)
+	defer deadline.Stop()
+	for {
+		lastBlock, err := s.GetLastBlock(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get last block from store: %w", err)
+		}
+		if lastBlock >= block {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-deadline.C:
+			return notIndexed(block, lastBlock, "store has not indexed block %d yet: last indexed block is %d", block, lastBlock)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
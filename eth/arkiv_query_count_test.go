@@ -0,0 +1,76 @@
+package eth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"testing"
+
+	sqlitestore "github.com/Arkiv-Network/sqlite-bitmap-store"
+	"github.com/ethereum/go-ethereum/arkiv/rpctypes"
+	"github.com/ethereum/go-ethereum/arkiv/shards"
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArkivAPI_QueryCount(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	create := func(kind string, namespace string) storagetx.ArkivCreate {
+		annotations := []storagetx.StringAnnotation{{Key: "kind", Value: kind}}
+		if namespace != "" {
+			annotations = append(annotations, storagetx.StringAnnotation{Key: shards.NamespaceAttribute, Value: namespace})
+		}
+		return storagetx.ArkivCreate{BTL: 100, ContentType: "text/plain", Payload: []byte(kind), StringAnnotations: annotations}
+	}
+	steps := []arkivTestStep{
+		// Block 1: 4 entities of kind x, 2 of them in namespace a
+		func([]common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			return key, &storagetx.ArkivTransaction{Create: []storagetx.ArkivCreate{create("x", "a"), create("x", "a"), create("x", ""), create("x", "")}}
+		},
+		// Block 2: 2 entities of kind y
+		func([]common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			return key, &storagetx.ArkivTransaction{Create: []storagetx.ArkivCreate{create("y", ""), create("y", "a")}}
+		},
+		// Block 3: an entity of kind x is deleted
+		func(keys []common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			return key, &storagetx.ArkivTransaction{Delete: keys[:1]}
+		},
+	}
+	api, _ := newArkivTestAPI(t, key, key, steps, len(steps))
+	ctx := context.Background()
+
+	for name, api := range map[string]*arkivAPI{
+		"single store": api,
+		"sharded":      newShardedArkivAPI(t, api, "a=namespace:a"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			for req, count := range map[string]uint64{
+				`kind = "x"`:               3,
+				`kind = "y"`:               2,
+				`kind = "x" || kind = "y"`: 5,
+				`kind = "z"`:               0,
+				`$all`:                     5,
+				`kind != "y"`:              3,
+			} {
+				result, err := api.QueryCount(ctx, req, nil)
+				require.NoError(t, err, req)
+				require.Equal(t, &QueryCount{Count: count, Block: 3}, result, req)
+			}
+		})
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := api.QueryCount(ctx, `kind = `, nil)
+		var rpcErr rpc.Error
+		require.ErrorAs(t, err, &rpcErr)
+		require.Equal(t, rpctypes.ErrCodeValidation, rpcErr.ErrorCode())
+
+		// The store doesn't index the block in time
+		atBlock := uint64(4)
+		_, err = api.QueryCount(ctx, `kind = "x"`, &sqlitestore.Options{AtBlock: &atBlock})
+		require.ErrorAs(t, err, &rpcErr)
+		require.Equal(t, rpctypes.ErrCodeNotIndexed, rpcErr.ErrorCode())
+	})
+}
@@ -0,0 +1,349 @@
+package eth
+
+import (
+	"cmp"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	sqlitestore "github.com/Arkiv-Network/sqlite-bitmap-store"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// errQueryCursorMismatch is returned for a cursor created for another query.
+var errQueryCursorMismatch error = &validationError{errors.New("cursor was created for a different query")}
+
+// queryCursor is the position of the next page of a query, bound to the query and the
+// block it was created for.
+//
+// The store returns the entities in the order they were created, newest first, and its
+// cursor is the position after the last entity returned, which keeps the same order. The
+// entities changed after the block are rebuilt and slotted in by their $sequence, so
+// the cursor also holds the $sequence of the last entity of the page.
+type queryCursor struct {
+	query    common.Hash
+	block    uint64
+	sequence uint64
+	store    string // empty for the first page of the store
+}
+
+func (c queryCursor) encode() string {
+	b := make([]byte, 48, 48+len(c.store))
+	copy(b, c.query[:])
+	binary.BigEndian.PutUint64(b[32:], c.block)
+	binary.BigEndian.PutUint64(b[40:], c.sequence)
+	return hexutil.Encode(append(b, c.store...))
+}
+
+func decodeQueryCursor(s string) (queryCursor, error) {
+	b, err := hexutil.Decode(s)
+	if err != nil || len(b) < 48 {
+		return queryCursor{}, invalidRequest("invalid cursor %q", s)
+	}
+	return queryCursor{
+		query:    common.BytesToHash(b[:32]),
+		block:    binary.BigEndian.Uint64(b[32:]),
+		sequence: binary.BigEndian.Uint64(b[40:]),
+		store:    string(b[48:]),
+	}, nil
+}
+
+// queryCursorHash identifies a query and the options that select and order its
+// entities.
+func queryCursorHash(req string, op *QueryOptions) common.Hash {
+	parts := [][]byte{[]byte(req), {0}, []byte(op.Text), {0}, []byte(op.KeyPrefix)}
+	if op.OrderBy != nil {
+		parts = append(parts, []byte{0}, []byte(op.OrderBy.Key), []byte{0}, []byte(op.OrderBy.Direction))
+	}
+	return crypto.Keccak256Hash(parts...)
+}
+
+// checkQueryCursor decodes the cursor of the options and checks it was created for
+// the query. A query with a cursor and without a block runs at the block of the cursor.
+func checkQueryCursor(req string, op *QueryOptions) (*queryCursor, error) {
+	if op.Cursor == "" {
+		return nil, nil
+	}
+	cursor, err := decodeQueryCursor(op.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	if cursor.query != queryCursorHash(req, op) {
+		return nil, errQueryCursorMismatch
+	}
+	if op.AtBlock == nil {
+		op.AtBlock = &cursor.block
+	} else if *op.AtBlock != cursor.block {
+		return nil, invalidRequest("cursor was created for block %d, not block %d", cursor.block, *op.AtBlock)
+	}
+	return &cursor, nil
+}
+
+// queryDiffCursor is the position of the next page of a QueryDiff, bound to the query
+// and the blocks it compares. The keys of a diff are listed in order, the next page
+// starts after the last key of the page.
+type queryDiffCursor struct {
+	query  common.Hash
+	blockA uint64
+	blockB uint64
+	key    common.Hash
+}
+
+func newQueryDiffCursor(req string, blockA, blockB uint64) queryDiffCursor {
+	return queryDiffCursor{query: crypto.Keccak256Hash([]byte(req)), blockA: blockA, blockB: blockB}
+}
+
+func (c queryDiffCursor) encode() string {
+	b := make([]byte, 80)
+	copy(b, c.query[:])
+	binary.BigEndian.PutUint64(b[32:], c.blockA)
+	binary.BigEndian.PutUint64(b[40:], c.blockB)
+	copy(b[48:], c.key[:])
+	return hexutil.Encode(b)
+}
+
+// checkQueryDiffCursor decodes the cursor and checks it was created for the query and
+// the blocks.
+func checkQueryDiffCursor(s string, req string, blockA, blockB uint64) (*queryDiffCursor, error) {
+	b, err := hexutil.Decode(s)
+	if err != nil || len(b) != 80 {
+		return nil, invalidRequest("invalid cursor %q", s)
+	}
+	cursor := &queryDiffCursor{
+		query:  common.BytesToHash(b[:32]),
+		blockA: binary.BigEndian.Uint64(b[32:]),
+		blockB: binary.BigEndian.Uint64(b[40:]),
+		key:    common.BytesToHash(b[48:]),
+	}
+	if cursor.query != newQueryDiffCursor(req, blockA, blockB).query {
+		return nil, errQueryCursorMismatch
+	}
+	if cursor.blockA != blockA || cursor.blockB != blockB {
+		return nil, invalidRequest("cursor was created for blocks %d to %d, not blocks %d to %d", cursor.blockA, cursor.blockB, blockA, blockB)
+	}
+	return cursor, nil
+}
+
+// pageQuery turns the page the store returned for a query into the page of the query
+// at its block: the entities changed after the block are shown as they were at the
+// block, see arkivRewind, and the entities of the overlay of a pending view on top of
+// the first page. The cursor of the next page is bound to the query and its block. The
+// queries ordered by an annotation are paged by pageOrderedQuery.
+func (api *arkivAPI) pageQuery(
+	ctx context.Context,
+	req string,
+	op *QueryOptions,
+	textMatches []common.Hash,
+	previous *queryCursor,
+	overlay *arkivOverlay,
+	response *QueryResponse,
+) error {
+	if op.OrderBy != nil {
+		return api.pageOrderedQuery(ctx, req, op, textMatches, previous, response)
+	}
+	rewind, err := api.rewindQuery(ctx, *op.AtBlock)
+	if err != nil {
+		return err
+	}
+	matches := func(common.Hash, *overlayEntity) bool { return false }
+	if len(rewind.entities) > 0 {
+		if matches, err = overlayMatcher(req, op, textMatches); err != nil {
+			return err
+		}
+	}
+	include := pagedIncludeData(op.GetIncludeData())
+	if err := rewind.page(response, include, matches, op.ResultsPerPage, queryCursorHash(req, op), *op.AtBlock, previous); err != nil {
+		return err
+	}
+	if overlay != nil {
+		if err := overlay.merge(req, op, textMatches, response); err != nil {
+			return err
+		}
+	}
+	return stripEntityData(response.Data, op.GetIncludeData())
+}
+
+// entitySequence is the $sequence of an entity returned by the store, which orders the
+// entities like the store does.
+func entitySequence(ed *EntityData) uint64 {
+	if ed.CreatedAtBlock == nil || ed.TransactionIndexInBlock == nil || ed.OperationIndexInTransaction == nil {
+		return 0
+	}
+	return *ed.CreatedAtBlock<<32 | *ed.TransactionIndexInBlock<<16 | *ed.OperationIndexInTransaction
+}
+
+// pagedIncludeData returns the fields the store is asked for to page through a query:
+// the requested ones, and the key and the $sequence of the entities.
+func pagedIncludeData(include sqlitestore.IncludeData) sqlitestore.IncludeData {
+	include.Key = true
+	include.CreatedAtBlock = true
+	include.TransactionIndexInBlock = true
+	include.OperationIndexInTransaction = true
+	return include
+}
+
+// stripEntityData removes the fields that weren't requested from the entities.
+func stripEntityData(data []json.RawMessage, include sqlitestore.IncludeData) error {
+	if pagedIncludeData(include) == include {
+		return nil
+	}
+	for i, d := range data {
+		var ed EntityData
+		if err := json.Unmarshal(d, &ed); err != nil {
+			return fmt.Errorf("failed to unmarshal entity data: %w", err)
+		}
+		if !include.Key {
+			ed.Key = nil
+		}
+		if !include.CreatedAtBlock {
+			ed.CreatedAtBlock = nil
+		}
+		if !include.TransactionIndexInBlock {
+			ed.TransactionIndexInBlock = nil
+		}
+		if !include.OperationIndexInTransaction {
+			ed.OperationIndexInTransaction = nil
+		}
+		var err error
+		if data[i], err = json.Marshal(&ed); err != nil {
+			return fmt.Errorf("error marshalling entity data: %w", err)
+		}
+	}
+	return nil
+}
+
+// arkivRewind holds the entities changed between the block of a query and the last
+// block of the store, as they were at the block of the query.
+type arkivRewind struct {
+	// changed holds the keys of the entities changed after the block.
+	changed map[common.Hash]struct{}
+	// entities holds the changed entities that existed at the block.
+	entities map[common.Hash]*overlayEntity
+}
+
+// rewindQuery rebuilds the entities changed between the block and the last block the
+// store indexed, which must be at most arkivMaxRewindBlocks after the block.
+func (api *arkivAPI) rewindQuery(ctx context.Context, block uint64) (*arkivRewind, error) {
+	lastIndexed, err := api.store.GetLastBlock(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last block from store: %w", err)
+	}
+	rewind := &arkivRewind{changed: map[common.Hash]struct{}{}}
+	if lastIndexed <= block {
+		return rewind, nil
+	}
+	if lastIndexed-block > arkivMaxRewindBlocks {
+		return nil, notIndexed(block, lastIndexed, "block %d is more than %d blocks before the last indexed block %d", block, arkivMaxRewindBlocks, lastIndexed)
+	}
+
+	existing := map[common.Hash]struct{}{}
+	for number := block + 1; number <= lastIndexed; number++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		decoded, err := api.blockOperations(number)
+		if err != nil {
+			return nil, err
+		}
+		for _, operation := range decoded.Operations {
+			key := operationKey(operation)
+			if _, ok := rewind.changed[key]; ok {
+				continue
+			}
+			rewind.changed[key] = struct{}{}
+			if operation.Create == nil {
+				existing[key] = struct{}{}
+			}
+		}
+	}
+	if rewind.entities, err = api.entitiesAt(ctx, existing, block); err != nil {
+		return nil, err
+	}
+	return rewind, nil
+}
+
+// page replaces the entities of a page of the store changed after the block of the
+// query with the ones that matched at the block. The page covers the entities whose
+// $sequence is below the one of the previous cursor, all of them for the first page,
+// down to the last entity of the page of the store, all of them if it's its last page.
+// The cursor of the store is replaced with the cursor of the next page.
+//
+// A page holding more than perPage entities is cut, and its next page starts again
+// from the same page of the store, below the last entity returned.
+func (r *arkivRewind) page(
+	response *QueryResponse,
+	include sqlitestore.IncludeData,
+	matches func(common.Hash, *overlayEntity) bool,
+	perPage *uint64,
+	query common.Hash,
+	block uint64,
+	previous *queryCursor,
+) error {
+	type pagedEntity struct {
+		sequence uint64
+		data     json.RawMessage
+	}
+	var paged []pagedEntity
+	lower := uint64(0)
+	for _, d := range response.Data {
+		var ed EntityData
+		if err := json.Unmarshal(d, &ed); err != nil {
+			return fmt.Errorf("failed to unmarshal entity data: %w", err)
+		}
+		lower = entitySequence(&ed)
+		if previous != nil && lower >= previous.sequence {
+			continue
+		}
+		if ed.Key != nil {
+			if _, changed := r.changed[*ed.Key]; changed {
+				continue
+			}
+		}
+		paged = append(paged, pagedEntity{sequence: lower, data: d})
+	}
+
+	var next *queryCursor
+	if response.Cursor != nil && *response.Cursor != "" {
+		next = &queryCursor{query: query, block: block, store: *response.Cursor, sequence: lower}
+	} else {
+		lower = 0
+	}
+
+	for key, entity := range r.entities {
+		sequence := entity.nums["$sequence"]
+		if sequence < lower || (previous != nil && sequence >= previous.sequence) || !matches(key, entity) {
+			continue
+		}
+		data, err := json.Marshal(entity.data(key, include))
+		if err != nil {
+			return fmt.Errorf("error marshalling entity data: %w", err)
+		}
+		paged = append(paged, pagedEntity{sequence: sequence, data: data})
+	}
+	slices.SortStableFunc(paged, func(a, b pagedEntity) int {
+		return cmp.Compare(b.sequence, a.sequence)
+	})
+	if perPage != nil && *perPage > 0 && uint64(len(paged)) > *perPage {
+		paged = paged[:*perPage]
+		next = &queryCursor{query: query, block: block, sequence: paged[len(paged)-1].sequence}
+		if previous != nil {
+			next.store = previous.store
+		}
+	}
+
+	response.Data = make([]json.RawMessage, len(paged))
+	for i, entity := range paged {
+		response.Data[i] = entity.data
+	}
+	response.Cursor = nil
+	if next != nil {
+		encoded := next.encode()
+		response.Cursor = &encoded
+	}
+	return nil
+}
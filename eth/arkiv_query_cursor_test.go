@@ -0,0 +1,125 @@
+package eth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"testing"
+
+	sqlitestore "github.com/Arkiv-Network/sqlite-bitmap-store"
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryCursorEncoding(t *testing.T) {
+	cursor := queryCursor{query: common.HexToHash("0x01"), block: 7, sequence: 7<<32 | 1<<16 | 2, store: "0x2a"}
+	decoded, err := decodeQueryCursor(cursor.encode())
+	require.NoError(t, err)
+	require.Equal(t, cursor, decoded)
+	cursor.store = ""
+	decoded, err = decodeQueryCursor(cursor.encode())
+	require.NoError(t, err)
+	require.Equal(t, cursor, decoded)
+
+	_, err = decodeQueryCursor("0x2a")
+	require.ErrorContains(t, err, `invalid cursor "0x2a"`)
+	_, err = decodeQueryCursor("cursor")
+	require.ErrorContains(t, err, `invalid cursor "cursor"`)
+}
+
+func TestArkivAPI_QueryCursor(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+
+	kind := func(value string) []storagetx.StringAnnotation {
+		return []storagetx.StringAnnotation{{Key: "kind", Value: value}}
+	}
+	create := func(payload string) storagetx.ArkivCreate {
+		return storagetx.ArkivCreate{BTL: 100, ContentType: "text/plain", Payload: []byte(payload), StringAnnotations: kind("x")}
+	}
+	var created []common.Hash
+	steps := []arkivTestStep{
+		// Block 1: e0 to e4 are created
+		func([]common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			return key, &storagetx.ArkivTransaction{Create: []storagetx.ArkivCreate{create("e0"), create("e1"), create("e2"), create("e3"), create("e4")}}
+		},
+		// Block 2: e5 is created
+		func([]common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			return key, &storagetx.ArkivTransaction{Create: []storagetx.ArkivCreate{create("e5")}}
+		},
+		// Block 3: e1 no longer matches kind = "x"
+		func(keys []common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			return key, &storagetx.ArkivTransaction{Update: []storagetx.ArkivUpdate{
+				{EntityKey: keys[1], BTL: 100, ContentType: "text/plain", Payload: []byte("e1"), StringAnnotations: kind("y")},
+			}}
+		},
+		// Block 4: e3 is deleted
+		func(keys []common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			return key, &storagetx.ArkivTransaction{Delete: []common.Hash{keys[3]}}
+		},
+		// Block 5: e6 is created
+		func([]common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			return key, &storagetx.ArkivTransaction{Create: []storagetx.ArkivCreate{create("e6")}}
+		},
+		// Block 6: nothing
+		func(keys []common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			created = keys
+			return nil, nil
+		},
+	}
+	api, _ := newArkivTestAPI(t, key, key, steps, len(steps))
+	ctx := context.Background()
+
+	page := func(req string, atBlock *uint64, perPage uint64, cursor string) *QueryResponse {
+		t.Helper()
+		response, err := api.Query(ctx, req, &QueryOptions{Options: sqlitestore.Options{
+			AtBlock:        atBlock,
+			ResultsPerPage: &perPage,
+			Cursor:         cursor,
+			IncludeData:    &sqlitestore.IncludeData{Key: true, Payload: true},
+		}})
+		require.NoError(t, err)
+		return response
+	}
+	all := func(req string, atBlock *uint64, perPage uint64) []common.Hash {
+		t.Helper()
+		var keys []common.Hash
+		cursor := ""
+		for {
+			response := page(req, atBlock, perPage, cursor)
+			require.LessOrEqual(t, len(response.Data), int(perPage))
+			for _, entity := range queryEntities(t, response) {
+				require.Nil(t, entity.CreatedAtBlock, "fields that weren't requested are removed")
+				keys = append(keys, *entity.Key)
+			}
+			if response.Cursor == nil {
+				return keys
+			}
+			cursor = *response.Cursor
+		}
+	}
+
+	// At block 2 the entities changed after it are shown as they were, newest first
+	block := uint64(2)
+	expected := []common.Hash{created[5], created[4], created[3], created[2], created[1], created[0]}
+	for _, perPage := range []uint64{1, 2, 4, 100} {
+		require.Equal(t, expected, all(`kind = "x"`, &block, perPage), "%d per page", perPage)
+	}
+
+	// At the head the same query doesn't see the changed entities
+	head := uint64(6)
+	require.Equal(t, []common.Hash{created[6], created[5], created[4], created[2], created[0]}, all(`kind = "x"`, &head, 2))
+
+	// A cursor without a block continues at the block of the cursor
+	first := page(`kind = "x"`, &block, 2, "")
+	require.NotNil(t, first.Cursor)
+	require.Equal(t, page(`kind = "x"`, &block, 2, *first.Cursor), page(`kind = "x"`, nil, 2, *first.Cursor))
+
+	perPage := uint64(2)
+	_, err := api.Query(ctx, `kind = "y"`, &QueryOptions{Options: sqlitestore.Options{ResultsPerPage: &perPage, Cursor: *first.Cursor}})
+	require.ErrorIs(t, err, errQueryCursorMismatch)
+	_, err = api.Query(ctx, `kind = "x"`, &QueryOptions{Options: sqlitestore.Options{AtBlock: &head, ResultsPerPage: &perPage, Cursor: *first.Cursor}})
+	require.ErrorContains(t, err, "cursor was created for block 2, not block 6")
+	_, err = api.Query(ctx, `kind = "x"`, &QueryOptions{Options: sqlitestore.Options{Cursor: "0x2a"}})
+	require.ErrorContains(t, err, "invalid cursor")
+}
@@ -0,0 +1,106 @@
+package eth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"testing"
+
+	sqlitestore "github.com/Arkiv-Network/sqlite-bitmap-store"
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryDiff(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+
+	kind := func(value string) []storagetx.StringAnnotation {
+		return []storagetx.StringAnnotation{{Key: "kind", Value: value}}
+	}
+	create := func(payload, k string, btl uint64) storagetx.ArkivCreate {
+		return storagetx.ArkivCreate{BTL: btl, ContentType: "text/plain", Payload: []byte(payload), StringAnnotations: kind(k)}
+	}
+	update := func(entityKey common.Hash, payload, k string) storagetx.ArkivUpdate {
+		return storagetx.ArkivUpdate{EntityKey: entityKey, BTL: 100, ContentType: "text/plain", Payload: []byte(payload), StringAnnotations: kind(k)}
+	}
+	var created []common.Hash
+	steps := []arkivTestStep{
+		// Block 1: e0, e1 expiring at block 4 and e2 are created
+		func([]common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			return key, &storagetx.ArkivTransaction{Create: []storagetx.ArkivCreate{create("e0", "x", 100), create("e1", "x", 3), create("e2", "x", 100)}}
+		},
+		// Block 2: nothing
+		func([]common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) { return nil, nil },
+		// Block 3: the payload of e0 and the annotation of e2 are updated
+		func(keys []common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			return key, &storagetx.ArkivTransaction{Update: []storagetx.ArkivUpdate{update(keys[0], "e0 updated", "x"), update(keys[2], "e2", "y")}}
+		},
+		// Block 4: e1 expires, e3 is created
+		func([]common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			return key, &storagetx.ArkivTransaction{Create: []storagetx.ArkivCreate{create("e3", "x", 100)}}
+		},
+		// Block 5: e0 is extended, which doesn't change its content
+		func(keys []common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			created = keys
+			return key, &storagetx.ArkivTransaction{Extend: []storagetx.ExtendBTL{{EntityKey: keys[0], NumberOfBlocks: 10}}}
+		},
+	}
+	// The store holds the entities at the head, the diff doesn't depend on it
+	api, _ := newArkivTestAPI(t, key, key, steps, len(steps))
+
+	e0, e1, e2, e3 := created[0], created[1], created[2], created[3]
+
+	diff, err := api.QueryDiff(context.Background(), "$all", 2, 5, nil)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []common.Hash{e3}, diff.Added)
+	require.ElementsMatch(t, []common.Hash{e1}, diff.Removed)
+	require.ElementsMatch(t, []common.Hash{e0, e2}, diff.Changed)
+	require.False(t, diff.Truncated)
+
+	diff, err = api.QueryDiff(context.Background(), `kind = "x"`, 2, 5, nil)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []common.Hash{e3}, diff.Added)
+	require.ElementsMatch(t, []common.Hash{e1, e2}, diff.Removed)
+	require.ElementsMatch(t, []common.Hash{e0}, diff.Changed)
+
+	// Only the extension in between
+	diff, err = api.QueryDiff(context.Background(), "$all", 4, 5, nil)
+	require.NoError(t, err)
+	require.Empty(t, diff.Added)
+	require.Empty(t, diff.Removed)
+	require.Empty(t, diff.Changed)
+
+	diff, err = api.QueryDiff(context.Background(), "$all", 0, 1, nil)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []common.Hash{e0, e1, e2}, diff.Added)
+
+	perPage := uint64(2)
+	diff, err = api.QueryDiff(context.Background(), "$all", 2, 5, &sqlitestore.Options{ResultsPerPage: &perPage})
+	require.NoError(t, err)
+	first := append(append(diff.Added, diff.Removed...), diff.Changed...)
+	require.Len(t, first, 2)
+	require.True(t, diff.Truncated)
+	require.NotEmpty(t, diff.Cursor)
+
+	// The cursor returns the next keys, bound to the query and the blocks
+	diff, err = api.QueryDiff(context.Background(), "$all", 2, 5, &sqlitestore.Options{ResultsPerPage: &perPage, Cursor: diff.Cursor})
+	require.NoError(t, err)
+	second := append(append(diff.Added, diff.Removed...), diff.Changed...)
+	require.ElementsMatch(t, []common.Hash{e0, e1, e2, e3}, append(first, second...))
+	require.False(t, diff.Truncated)
+	require.Empty(t, diff.Cursor)
+
+	diff, err = api.QueryDiff(context.Background(), "$all", 2, 5, &sqlitestore.Options{ResultsPerPage: &perPage})
+	require.NoError(t, err)
+	_, err = api.QueryDiff(context.Background(), `kind = "x"`, 2, 5, &sqlitestore.Options{Cursor: diff.Cursor})
+	require.ErrorIs(t, err, errQueryCursorMismatch)
+	_, err = api.QueryDiff(context.Background(), "$all", 3, 5, &sqlitestore.Options{Cursor: diff.Cursor})
+	require.ErrorContains(t, err, "cursor was created for blocks 2 to 5, not blocks 3 to 5")
+
+	_, err = api.QueryDiff(context.Background(), "$all", 5, 2, nil)
+	require.ErrorContains(t, err, "blockA 5 is after blockB 2")
+
+	_, err = api.QueryDiff(context.Background(), "$all", 2, 6, nil)
+	require.ErrorContains(t, err, "block is in the future: head is 5")
+}
@@ -0,0 +1,72 @@
+package eth
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+const (
+	// DefaultArkivQueryTimeout is the default time a query can run in the store.
+	DefaultArkivQueryTimeout = 10 * time.Second
+	// DefaultArkivQueryMaxRows is the default number of rows a query can return.
+	DefaultArkivQueryMaxRows = 10_000
+	// DefaultArkivQueryMaxBytes is the default size in bytes of the rows a query can
+	// return.
+	DefaultArkivQueryMaxBytes = 64 * 1024 * 1024
+)
+
+// arkivQueryLimits bound the time a query runs in the store and the size of its
+// results. A zero limit disables it.
+type arkivQueryLimits struct {
+	timeout  time.Duration
+	maxRows  uint64
+	maxBytes uint64
+}
+
+// timeoutOf returns the time the query can run in the store: the timeout of the node,
+// or the one of the request if it's shorter.
+func (l arkivQueryLimits) timeoutOf(op *QueryOptions) time.Duration {
+	requested := time.Duration(op.TimeoutMs) * time.Millisecond
+	if requested > 0 && (l.timeout == 0 || requested < l.timeout) {
+		return requested
+	}
+	return l.timeout
+}
+
+// withTimeout returns the context the query runs in the store with.
+func (l arkivQueryLimits) withTimeout(ctx context.Context, op *QueryOptions) (context.Context, context.CancelFunc) {
+	timeout := l.timeoutOf(op)
+	if timeout == 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// checkPage fails the queries asking for a page larger than the rows a query can
+// return, before they run.
+func (l arkivQueryLimits) checkPage(op *QueryOptions) error {
+	if l.maxRows > 0 && op.ResultsPerPage != nil && *op.ResultsPerPage > l.maxRows {
+		return invalidRequest("result too large, use pagination: page of %d rows, more than the limit of %d", *op.ResultsPerPage, l.maxRows)
+	}
+	return nil
+}
+
+// checkResults fails the results with more rows or bytes than a query can return,
+// instead of truncating them.
+func (l arkivQueryLimits) checkResults(data []json.RawMessage) error {
+	if l.maxRows > 0 && uint64(len(data)) > l.maxRows {
+		return invalidRequest("result too large, use pagination: %d rows, more than the limit of %d", len(data), l.maxRows)
+	}
+	if l.maxBytes == 0 {
+		return nil
+	}
+	size := uint64(0)
+	for _, row := range data {
+		size += uint64(len(row))
+	}
+	if size > l.maxBytes {
+		return invalidRequest("result too large, use pagination: %d bytes, more than the limit of %d", size, l.maxBytes)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package eth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sqlitestore "github.com/Arkiv-Network/sqlite-bitmap-store"
+	"github.com/ethereum/go-ethereum/arkiv/rpctypes"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/require"
+)
+
+// stalledStore is a store whose queries run until their context is done.
+type stalledStore struct {
+	arkivStore
+}
+
+func (s stalledStore) QueryEntities(ctx context.Context, query string, options *sqlitestore.Options) (*sqlitestore.QueryResponse, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestQueryLimitsTimeout(t *testing.T) {
+	limits := arkivQueryLimits{timeout: time.Second}
+
+	require.Equal(t, time.Second, limits.timeoutOf(&QueryOptions{}))
+	require.Equal(t, 10*time.Millisecond, limits.timeoutOf(&QueryOptions{TimeoutMs: 10}))
+	// The requests can't extend the timeout of the node
+	require.Equal(t, time.Second, limits.timeoutOf(&QueryOptions{TimeoutMs: 5000}))
+
+	disabled := arkivQueryLimits{}
+	require.Zero(t, disabled.timeoutOf(&QueryOptions{}))
+	require.Equal(t, 5*time.Second, disabled.timeoutOf(&QueryOptions{TimeoutMs: 5000}))
+}
+
+func TestArkivAPI_QueryTimeout(t *testing.T) {
+	api := &arkivAPI{
+		store:       stalledStore{seedArkivStore(t)},
+		queryLimits: arkivQueryLimits{timeout: time.Minute},
+	}
+	atBlock := uint64(1)
+
+	start := time.Now()
+	_, err := api.Query(context.Background(), `tag = "rare"`, &QueryOptions{
+		Options:   sqlitestore.Options{AtBlock: &atBlock},
+		TimeoutMs: 10,
+	})
+	require.Less(t, time.Since(start), time.Minute)
+	require.ErrorContains(t, err, "query timed out after 10ms")
+	var rpcErr rpc.Error
+	require.ErrorAs(t, err, &rpcErr)
+	require.Equal(t, rpctypes.ErrCodeStoreBusy, rpcErr.ErrorCode())
+}
+
+func TestArkivAPI_QueryResultLimits(t *testing.T) {
+	atBlock := uint64(1)
+	query := func(t *testing.T, limits arkivQueryLimits, resultsPerPage *uint64) (*QueryResponse, error) {
+		t.Helper()
+		api := &arkivAPI{store: seedArkivStore(t), queryLimits: limits}
+		return api.Query(context.Background(), `tag = "rare"`, &QueryOptions{
+			Options: sqlitestore.Options{AtBlock: &atBlock, ResultsPerPage: resultsPerPage},
+		})
+	}
+	requireTooLarge := func(t *testing.T, err error) {
+		t.Helper()
+		require.ErrorContains(t, err, "result too large, use pagination")
+		var rpcErr rpc.Error
+		require.ErrorAs(t, err, &rpcErr)
+		require.Equal(t, rpctypes.ErrCodeValidation, rpcErr.ErrorCode())
+	}
+
+	response, err := query(t, arkivQueryLimits{maxRows: 10, maxBytes: 1 << 20}, nil)
+	require.NoError(t, err)
+	require.Len(t, response.Data, 10)
+
+	// The results aren't truncated
+	_, err = query(t, arkivQueryLimits{maxRows: 5}, nil)
+	requireTooLarge(t, err)
+	_, err = query(t, arkivQueryLimits{maxBytes: 100}, nil)
+	requireTooLarge(t, err)
+
+	// A page larger than the limit fails before the query runs
+	perPage := uint64(6)
+	_, err = query(t, arkivQueryLimits{maxRows: 5}, &perPage)
+	requireTooLarge(t, err)
+
+	perPage = 5
+	response, err = query(t, arkivQueryLimits{maxRows: 5}, &perPage)
+	require.NoError(t, err)
+	require.Len(t, response.Data, 5)
+}
@@ -0,0 +1,112 @@
+package eth
+
+import (
+	"cmp"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/Arkiv-Network/sqlite-bitmap-store/query"
+)
+
+// matchQuery reports whether an entity with the attributes matches the normalized
+// query, evaluating the query the way the store evaluates it against its bitmaps: a
+// predicate on an attribute the entity doesn't have never matches, not even a
+// negated one.
+func matchQuery(ast *query.AST, strs map[string]string, nums map[string]uint64) bool {
+	if ast.Expr == nil {
+		return true
+	}
+	for _, and := range ast.Expr.Or.Terms {
+		matched := true
+		for i := range and.Terms {
+			if !matchTerm(&and.Terms[i], strs, nums) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+func matchTerm(term *query.ASTTerm, strs map[string]string, nums map[string]uint64) bool {
+	switch {
+	case term.Assign != nil:
+		return matchValue(term.Assign.Var, term.Assign.Value, strs, nums, func(c int) bool {
+			return (c == 0) != term.Assign.IsNot
+		})
+	case term.Inclusion != nil:
+		in := term.Inclusion
+		if in.Values.Strings != nil {
+			value, ok := strs[in.Var]
+			return ok && slices.Contains(in.Values.Strings, value) != in.IsNot
+		}
+		value, ok := nums[in.Var]
+		return ok && slices.Contains(in.Values.Numbers, value) != in.IsNot
+	case term.LessThan != nil:
+		return matchValue(term.LessThan.Var, term.LessThan.Value, strs, nums, func(c int) bool { return c < 0 })
+	case term.LessOrEqualThan != nil:
+		return matchValue(term.LessOrEqualThan.Var, term.LessOrEqualThan.Value, strs, nums, func(c int) bool { return c <= 0 })
+	case term.GreaterThan != nil:
+		return matchValue(term.GreaterThan.Var, term.GreaterThan.Value, strs, nums, func(c int) bool { return c > 0 })
+	case term.GreaterOrEqualThan != nil:
+		return matchValue(term.GreaterOrEqualThan.Var, term.GreaterOrEqualThan.Value, strs, nums, func(c int) bool { return c >= 0 })
+	case term.Glob != nil:
+		value, ok := strs[term.Glob.Var]
+		if !ok {
+			return false
+		}
+		glob, err := globRegexp(term.Glob.Value)
+		return err == nil && glob.MatchString(value) != term.Glob.IsNot
+	default:
+		return false
+	}
+}
+
+// matchValue compares the attribute of the entity with the value of a predicate.
+func matchValue(name string, v query.Value, strs map[string]string, nums map[string]uint64, match func(int) bool) bool {
+	if v.String != nil {
+		value, ok := strs[name]
+		return ok && match(strings.Compare(value, *v.String))
+	}
+	value, ok := nums[name]
+	return ok && match(cmp.Compare(value, *v.Number))
+}
+
+// globRegexp translates a SQLite GLOB pattern: * matches any sequence of characters, ?
+// any character and [...] a character class, negated by a leading ^.
+func globRegexp(pattern string) (*regexp.Regexp, error) {
+	var expr strings.Builder
+	expr.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			expr.WriteString("(?s:.*)")
+		case '?':
+			expr.WriteString("(?s:.)")
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				expr.WriteString(regexp.QuoteMeta(pattern[i:]))
+				i = len(pattern)
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			expr.WriteString("[")
+			if strings.HasPrefix(class, "^") {
+				expr.WriteString("^")
+				class = class[1:]
+			}
+			expr.WriteString(strings.NewReplacer(`\`, `\\`, `[`, `\[`).Replace(class))
+			expr.WriteString("]")
+			i += end + 1
+		default:
+			expr.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	expr.WriteString("$")
+	return regexp.Compile(expr.String())
+}
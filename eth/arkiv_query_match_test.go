@@ -0,0 +1,39 @@
+package eth
+
+import (
+	"testing"
+
+	"github.com/Arkiv-Network/sqlite-bitmap-store/query"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchQuery(t *testing.T) {
+	strs := map[string]string{"kind": "doc", "name": "report-2024.pdf", "$owner": "0x000000000000000000000000000000000000000a"}
+	nums := map[string]uint64{"size": 10}
+
+	for _, tc := range []struct {
+		query string
+		match bool
+	}{
+		{query: `$all`, match: true},
+		{query: `kind = "doc"`, match: true},
+		{query: `kind != "doc"`},
+		{query: `kind = "doc" && size > 10`},
+		{query: `kind = "other" || size >= 10`, match: true},
+		{query: `size < 11 && size <= 10`, match: true},
+		{query: `kind IN ("doc" "sheet")`, match: true},
+		{query: `size NOT IN (1 2)`, match: true},
+		{query: `name ~ "report-*.pdf"`, match: true},
+		{query: `name ~ "report-20[0-2]?.pdf"`, match: true},
+		{query: `name ~ "report-[^2]*"`},
+		{query: `name !~ "*.pdf"`},
+		{query: `$owner = 0x000000000000000000000000000000000000000A`, match: true},
+		// Predicates on a missing attribute never match, not even negated ones
+		{query: `missing != "doc"`},
+		{query: `!(missing = "doc")`},
+	} {
+		ast, err := query.Parse(tc.query)
+		require.NoError(t, err, tc.query)
+		require.Equal(t, tc.match, matchQuery(ast, strs, nums), tc.query)
+	}
+}
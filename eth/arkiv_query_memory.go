@@ -0,0 +1,168 @@
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Arkiv-Network/arkiv-events/events"
+	sqlitestore "github.com/Arkiv-Network/sqlite-bitmap-store"
+	"github.com/ethereum/go-ethereum/arkiv/limits"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+const (
+	// DefaultArkivQueryMemoryBudget is the default number of bytes the running queries
+	// can hold at once.
+	DefaultArkivQueryMemoryBudget = 256 * 1024 * 1024
+
+	// arkivQueryEntityBytes is the memory a query holds for every matching entity while
+	// it combines the bitmaps of its terms.
+	arkivQueryEntityBytes = 64
+	// arkivQueryRowBytes and arkivQueryPayloadRowBytes are the memory a query holds for
+	// every returned row, without and with the payloads.
+	arkivQueryRowBytes        = 1024
+	arkivQueryPayloadRowBytes = 16 * 1024
+)
+
+// errQueryMemoryBudget is returned for the queries that don't fit in the memory budget.
+var errQueryMemoryBudget = errors.New("query memory budget exceeded")
+
+var arkivQueryMemoryHistogram = metrics.NewRegisteredHistogram("arkiv/query/memory", nil, metrics.NewExpDecaySample(1028, 0.015))
+
+// estimateQueryMemory returns the working set of a query selecting the number of
+// entities: the bitmaps of the matching entities and the rows of the returned page.
+func estimateQueryMemory(entities uint64, options *sqlitestore.Options) uint64 {
+	rows := min(entities, options.GetResultsPerPage())
+	rowBytes := uint64(arkivQueryRowBytes)
+	if options.GetIncludeData().Payload {
+		rowBytes = arkivQueryPayloadRowBytes
+	}
+	return entities*arkivQueryEntityBytes + rows*rowBytes
+}
+
+// peakQueryMemory returns the working set of a query selecting the number of entities
+// once its results are materialized.
+func peakQueryMemory(entities uint64, response *sqlitestore.QueryResponse) uint64 {
+	peak := entities * arkivQueryEntityBytes
+	for _, data := range response.Data {
+		peak += uint64(len(data))
+	}
+	return peak
+}
+
+// operationsMemory returns the memory held by the decoded operations of a block: a row
+// for every operation, with the payloads and the annotations it writes.
+func operationsMemory(block *events.Block) uint64 {
+	size := uint64(0)
+	for _, operation := range block.Operations {
+		size += arkivQueryRowBytes
+		switch {
+		case operation.Create != nil:
+			size += uint64(len(operation.Create.Content)) + attributesMemory(operation.Create.StringAttributes, operation.Create.NumericAttributes)
+		case operation.Update != nil:
+			size += uint64(len(operation.Update.Content)) + attributesMemory(operation.Update.StringAttributes, operation.Update.NumericAttributes)
+		}
+	}
+	return size
+}
+
+func attributesMemory(strs map[string]string, nums map[string]uint64) uint64 {
+	size := uint64(0)
+	for name, value := range strs {
+		size += uint64(len(name) + len(value))
+	}
+	for name := range nums {
+		size += uint64(len(name) + 8)
+	}
+	return size
+}
+
+// arkivQueryMemoryBudget bounds the memory held by the running queries. Each query
+// reserves its estimated working set before it runs and releases it once it's done,
+// the queries that don't fit wait for the others to finish, up to wait, or fail.
+type arkivQueryMemoryBudget struct {
+	total uint64
+	wait  time.Duration
+
+	mu       sync.Mutex
+	used     uint64
+	released chan struct{} // closed and replaced whenever memory is released
+}
+
+// newArkivQueryMemoryBudget returns a budget of total bytes, nil if total is 0.
+func newArkivQueryMemoryBudget(total uint64, wait time.Duration) *arkivQueryMemoryBudget {
+	if total == 0 {
+		return nil
+	}
+	return &arkivQueryMemoryBudget{
+		total:    total,
+		wait:     wait,
+		released: make(chan struct{}),
+	}
+}
+
+// limits returns the limit of the budget, none if the budget is disabled.
+func (b *arkivQueryMemoryBudget) limits() []limits.Limit {
+	if b == nil {
+		return nil
+	}
+	return []limits.Limit{{Name: "queryMemoryBudget", Scope: limits.Node, Unit: "bytes", Value: b.total}}
+}
+
+// reserve reserves size bytes, the returned function releases them. A nil budget
+// doesn't limit the queries.
+func (b *arkivQueryMemoryBudget) reserve(ctx context.Context, size uint64) (func(), error) {
+	if b == nil {
+		return func() {}, nil
+	}
+	if size > b.total {
+		return nil, invalidRequest("%w: query needs about %d bytes, the budget is %d bytes", errQueryMemoryBudget, size, b.total)
+	}
+
+	var timeout <-chan time.Time
+	if b.wait > 0 {
+		timer := time.NewTimer(b.wait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	for {
+		b.mu.Lock()
+		if b.used+size <= b.total {
+			b.used += size
+			b.mu.Unlock()
+			return func() { b.release(size) }, nil
+		}
+		released := b.released
+		b.mu.Unlock()
+
+		if timeout == nil {
+			return nil, fmt.Errorf("%w: query needs about %d bytes, %d of %d bytes are in use", errQueryMemoryBudget, size, b.inUse(), b.total)
+		}
+		select {
+		case <-released:
+		case <-timeout:
+			return nil, fmt.Errorf("%w: query needs about %d bytes, %d of %d bytes are still in use after %v", errQueryMemoryBudget, size, b.inUse(), b.total, b.wait)
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (b *arkivQueryMemoryBudget) release(size uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.used -= size
+	close(b.released)
+	b.released = make(chan struct{})
+}
+
+func (b *arkivQueryMemoryBudget) inUse() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.used
+}
@@ -0,0 +1,83 @@
+package eth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryMemoryBudget(t *testing.T) {
+	budget := newArkivQueryMemoryBudget(100, 0)
+
+	release, err := budget.reserve(context.Background(), 60)
+	require.NoError(t, err)
+	require.Equal(t, uint64(60), budget.inUse())
+
+	// Without a wait the queries that don't fit fail right away
+	_, err = budget.reserve(context.Background(), 50)
+	require.ErrorIs(t, err, errQueryMemoryBudget)
+
+	// Queries larger than the budget never fit
+	_, err = budget.reserve(context.Background(), 101)
+	require.ErrorIs(t, err, errQueryMemoryBudget)
+
+	release()
+	require.Zero(t, budget.inUse())
+	release, err = budget.reserve(context.Background(), 100)
+	require.NoError(t, err)
+	release()
+}
+
+func TestQueryMemoryBudgetWait(t *testing.T) {
+	budget := newArkivQueryMemoryBudget(100, time.Minute)
+
+	release, err := budget.reserve(context.Background(), 60)
+	require.NoError(t, err)
+
+	reserved := make(chan error, 1)
+	go func() {
+		release, err := budget.reserve(context.Background(), 50)
+		if err == nil {
+			release()
+		}
+		reserved <- err
+	}()
+	select {
+	case err := <-reserved:
+		t.Fatalf("reserved before the memory was released: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	release()
+	require.NoError(t, <-reserved)
+	require.Zero(t, budget.inUse())
+}
+
+func TestQueryMemoryBudgetTimeout(t *testing.T) {
+	budget := newArkivQueryMemoryBudget(100, 10*time.Millisecond)
+
+	release, err := budget.reserve(context.Background(), 60)
+	require.NoError(t, err)
+	defer release()
+
+	_, err = budget.reserve(context.Background(), 50)
+	require.ErrorIs(t, err, errQueryMemoryBudget)
+
+	// The context of the request bounds the wait too
+	budget.wait = time.Minute
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = budget.reserve(ctx, 50)
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestQueryMemoryBudgetDisabled(t *testing.T) {
+	budget := newArkivQueryMemoryBudget(0, 0)
+	require.Nil(t, budget)
+
+	release, err := budget.reserve(context.Background(), 1<<40)
+	require.NoError(t, err)
+	release()
+}
@@ -0,0 +1,214 @@
+package eth
+
+import (
+	"cmp"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	sqlitestore "github.com/Arkiv-Network/sqlite-bitmap-store"
+	"github.com/ethereum/go-ethereum/arkiv/limits"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// maxOrderedQueryEntities is the largest number of entities a query ordered by an
+// annotation can select.
+const maxOrderedQueryEntities = limits.MaxOrderedQueryEntities
+
+// checkQueryOrder checks the order of the options and sets its default direction. The
+// key must be a valid annotation key, like the keys of the transactions.
+func checkQueryOrder(op *QueryOptions) error {
+	if op.OrderBy == nil {
+		return nil
+	}
+	if err := entity.ValidateAnnotationKey(op.OrderBy.Key); err != nil {
+		return invalidRequest("invalid orderBy: %w", err)
+	}
+	switch op.OrderBy.Direction {
+	case "":
+		op.OrderBy.Direction = QueryOrderAscending
+	case QueryOrderAscending, QueryOrderDescending:
+	default:
+		return invalidRequest("invalid orderBy direction %q, must be %q or %q", op.OrderBy.Direction, QueryOrderAscending, QueryOrderDescending)
+	}
+	if op.PendingView != nil {
+		return invalidRequest("orderBy can't be combined with pendingView")
+	}
+	return nil
+}
+
+// queryAllEntities returns all the entities of the store matching a query ordered by
+// an annotation, going through the pages of the store, with their annotations.
+func (api *arkivAPI) queryAllEntities(ctx context.Context, query string, options *sqlitestore.Options) (*sqlitestore.QueryResponse, error) {
+	pageOptions := *options
+	include := *options.IncludeData
+	include.Attributes = true
+	pageOptions.IncludeData = &include
+	pageOptions.ResultsPerPage = nil
+	pageOptions.Cursor = ""
+
+	var all *sqlitestore.QueryResponse
+	for {
+		response, err := api.store.QueryEntities(ctx, query, &pageOptions)
+		if err != nil {
+			return nil, err
+		}
+		if all == nil {
+			all = response
+		} else {
+			all.Data = append(all.Data, response.Data...)
+		}
+		if len(all.Data) > maxOrderedQueryEntities {
+			return nil, invalidRequest("query selects more than %d entities, narrow it down to order it by an annotation", maxOrderedQueryEntities)
+		}
+		if response.Cursor == nil || *response.Cursor == "" {
+			break
+		}
+		pageOptions.Cursor = *response.Cursor
+	}
+	all.Cursor = nil
+	return all, nil
+}
+
+// orderedEntity is an entity of a query ordered by an annotation, with the value of
+// the annotation, nil if the entity doesn't have it.
+type orderedEntity struct {
+	value    *uint64
+	sequence uint64
+	data     json.RawMessage
+}
+
+// compareOrderedEntities orders the entities with the annotation by its value in the
+// direction, then the entities without it, and the entities with the same value
+// newest first like the store does.
+func compareOrderedEntities(direction string, a, b orderedEntity) int {
+	switch {
+	case a.value != nil && b.value == nil:
+		return -1
+	case a.value == nil && b.value != nil:
+		return 1
+	case a.value != nil && *a.value != *b.value:
+		if direction == QueryOrderDescending {
+			return cmp.Compare(*b.value, *a.value)
+		}
+		return cmp.Compare(*a.value, *b.value)
+	}
+	return cmp.Compare(b.sequence, a.sequence)
+}
+
+// orderedCursor returns the cursor of the page after the entity. The cursor of an
+// ordered query holds the value of the annotation of the entity in place of the cursor
+// of the store, empty if the entity doesn't have it.
+func orderedCursor(query common.Hash, block uint64, last orderedEntity) queryCursor {
+	cursor := queryCursor{query: query, block: block, sequence: last.sequence}
+	if last.value != nil {
+		cursor.store = string(binary.BigEndian.AppendUint64(nil, *last.value))
+	}
+	return cursor
+}
+
+// orderedPosition returns the position of the last entity of the page of the cursor.
+func orderedPosition(cursor *queryCursor) (orderedEntity, error) {
+	position := orderedEntity{sequence: cursor.sequence}
+	switch len(cursor.store) {
+	case 0:
+	case 8:
+		value := binary.BigEndian.Uint64([]byte(cursor.store))
+		position.value = &value
+	default:
+		return orderedEntity{}, invalidRequest("invalid cursor of an ordered query")
+	}
+	return position, nil
+}
+
+// pageOrderedQuery turns all the entities of the store matching a query ordered by an
+// annotation, see queryAllEntities, into the page of the query at its block: the
+// entities changed after the block are shown as they were at the block, see
+// arkivRewind, the entities are sorted, and the page starts after the last entity of
+// the page of the previous cursor.
+func (api *arkivAPI) pageOrderedQuery(
+	ctx context.Context,
+	req string,
+	op *QueryOptions,
+	textMatches []common.Hash,
+	previous *queryCursor,
+	response *QueryResponse,
+) error {
+	rewind, err := api.rewindQuery(ctx, *op.AtBlock)
+	if err != nil {
+		return err
+	}
+	matches := func(common.Hash, *overlayEntity) bool { return false }
+	if len(rewind.entities) > 0 {
+		if matches, err = overlayMatcher(req, op, textMatches); err != nil {
+			return err
+		}
+	}
+	requested := op.GetIncludeData()
+	include := pagedIncludeData(requested)
+	include.Attributes = true
+	query := queryCursorHash(req, op)
+	if err := rewind.page(response, include, matches, nil, query, *op.AtBlock, nil); err != nil {
+		return err
+	}
+
+	entities := make([]orderedEntity, len(response.Data))
+	for i, d := range response.Data {
+		var ed EntityData
+		if err := json.Unmarshal(d, &ed); err != nil {
+			return fmt.Errorf("failed to unmarshal entity data: %w", err)
+		}
+		entities[i] = orderedEntity{sequence: entitySequence(&ed), data: d}
+		if value, ok := ed.NumericAttributes[op.OrderBy.Key]; ok {
+			entities[i].value = &value
+		}
+		if requested.Attributes {
+			continue
+		}
+		// The annotations were only fetched to sort the entities
+		if requested.SyntheticAttributes {
+			ed.StringAttributes = filterEntityAttributes(ed.StringAttributes, requested)
+			ed.NumericAttributes = filterEntityAttributes(ed.NumericAttributes, requested)
+		} else {
+			ed.StringAttributes, ed.NumericAttributes = nil, nil
+		}
+		if entities[i].data, err = json.Marshal(&ed); err != nil {
+			return fmt.Errorf("error marshalling entity data: %w", err)
+		}
+	}
+	compare := func(a, b orderedEntity) int {
+		return compareOrderedEntities(op.OrderBy.Direction, a, b)
+	}
+	slices.SortFunc(entities, compare)
+
+	start := 0
+	if previous != nil {
+		position, err := orderedPosition(previous)
+		if err != nil {
+			return err
+		}
+		var found bool
+		if start, found = slices.BinarySearchFunc(entities, position, compare); found {
+			start++
+		}
+	}
+	end := len(entities)
+	response.Cursor = nil
+	if perPage := op.ResultsPerPage; perPage != nil && *perPage > 0 && uint64(end-start) > *perPage {
+		end = start + int(*perPage)
+		encoded := orderedCursor(query, *op.AtBlock, entities[end-1]).encode()
+		response.Cursor = &encoded
+	}
+
+	response.Data = make([]json.RawMessage, 0, end-start)
+	for _, ordered := range entities[start:end] {
+		response.Data = append(response.Data, ordered.data)
+	}
+	if err := stripEntityData(response.Data, requested); err != nil {
+		return err
+	}
+	return api.queryLimits.checkResults(response.Data)
+}
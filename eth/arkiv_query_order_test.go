@@ -0,0 +1,113 @@
+package eth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"testing"
+
+	sqlitestore "github.com/Arkiv-Network/sqlite-bitmap-store"
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArkivAPI_QueryOrderBy(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+
+	create := func(payload string, score ...uint64) storagetx.ArkivCreate {
+		create := storagetx.ArkivCreate{
+			BTL:               100,
+			ContentType:       "text/plain",
+			Payload:           []byte(payload),
+			StringAnnotations: []storagetx.StringAnnotation{{Key: "kind", Value: "x"}},
+		}
+		for _, value := range score {
+			create.NumericAnnotations = append(create.NumericAnnotations, storagetx.NumericAnnotation{Key: "score", Value: value})
+		}
+		return create
+	}
+	var created []common.Hash
+	steps := []arkivTestStep{
+		// Block 1: e0 to e4 are created, e2 without a score
+		func([]common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			return key, &storagetx.ArkivTransaction{Create: []storagetx.ArkivCreate{
+				create("e0", 30), create("e1", 10), create("e2"), create("e3", 20), create("e4", 10),
+			}}
+		},
+		// Block 2: the score of e3 drops to 5
+		func(keys []common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			created = keys
+			update := create("e3", 5)
+			return key, &storagetx.ArkivTransaction{Update: []storagetx.ArkivUpdate{{
+				EntityKey:          keys[3],
+				BTL:                update.BTL,
+				ContentType:        update.ContentType,
+				Payload:            update.Payload,
+				StringAnnotations:  update.StringAnnotations,
+				NumericAnnotations: update.NumericAnnotations,
+			}}}
+		},
+	}
+	api, _ := newArkivTestAPI(t, key, key, steps, len(steps))
+	ctx := context.Background()
+
+	query := func(atBlock uint64, order *QueryOrder, perPage uint64, cursor string) (*QueryResponse, error) {
+		return api.Query(ctx, `kind = "x"`, &QueryOptions{
+			Options: sqlitestore.Options{
+				AtBlock:        &atBlock,
+				ResultsPerPage: &perPage,
+				Cursor:         cursor,
+				IncludeData:    &sqlitestore.IncludeData{Key: true},
+			},
+			OrderBy: order,
+		})
+	}
+	all := func(atBlock uint64, order *QueryOrder, perPage uint64) []common.Hash {
+		t.Helper()
+		var keys []common.Hash
+		cursor := ""
+		for {
+			response, err := query(atBlock, order, perPage, cursor)
+			require.NoError(t, err)
+			require.LessOrEqual(t, len(response.Data), int(perPage))
+			for _, entity := range queryEntities(t, response) {
+				require.Nil(t, entity.NumericAttributes, "annotations that weren't requested are removed")
+				keys = append(keys, *entity.Key)
+			}
+			if response.Cursor == nil {
+				return keys
+			}
+			cursor = *response.Cursor
+		}
+	}
+
+	// The entities without a score come last, the ones with the same score newest first
+	ascending := []common.Hash{created[4], created[1], created[3], created[0], created[2]}
+	descending := []common.Hash{created[0], created[3], created[4], created[1], created[2]}
+	for _, perPage := range []uint64{1, 2, 3, 100} {
+		require.Equal(t, ascending, all(1, &QueryOrder{Key: "score"}, perPage), "%d per page", perPage)
+		require.Equal(t, descending, all(1, &QueryOrder{Key: "score", Direction: QueryOrderDescending}, perPage), "%d per page", perPage)
+	}
+
+	// At the head e3 is ordered by its new score
+	require.Equal(t, []common.Hash{created[3], created[4], created[1], created[0], created[2]}, all(2, &QueryOrder{Key: "score", Direction: QueryOrderAscending}, 2))
+
+	// The cursor is bound to the order
+	first, err := query(1, &QueryOrder{Key: "score"}, 2, "")
+	require.NoError(t, err)
+	require.NotNil(t, first.Cursor)
+	_, err = query(1, &QueryOrder{Key: "score", Direction: QueryOrderDescending}, 2, *first.Cursor)
+	require.ErrorIs(t, err, errQueryCursorMismatch)
+	_, err = query(1, nil, 2, *first.Cursor)
+	require.ErrorIs(t, err, errQueryCursorMismatch)
+
+	_, err = query(1, &QueryOrder{Key: "high score"}, 2, "")
+	require.ErrorContains(t, err, "invalid orderBy")
+	_, err = query(1, &QueryOrder{Key: "$sequence"}, 2, "")
+	require.ErrorContains(t, err, "invalid orderBy")
+	_, err = query(1, &QueryOrder{Key: "score", Direction: "up"}, 2, "")
+	require.ErrorContains(t, err, `invalid orderBy direction "up"`)
+	_, err = api.Query(ctx, `kind = "x"`, &QueryOptions{OrderBy: &QueryOrder{Key: "score"}, PendingView: &PendingView{}})
+	require.ErrorContains(t, err, "orderBy can't be combined with pendingView")
+}
@@ -0,0 +1,103 @@
+package eth
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Arkiv-Network/sqlite-bitmap-store/query"
+	"github.com/Arkiv-Network/sqlite-bitmap-store/store"
+)
+
+const (
+	// DefaultArkivQueryMaxScanFraction is the default fraction of the live entities a
+	// query can select without allowing a full scan.
+	DefaultArkivQueryMaxScanFraction = 0.9
+
+	// arkivQueryMinScanEntities is the number of entities a query can always select,
+	// so that queries on small stores are never rejected.
+	arkivQueryMinScanEntities = 10_000
+)
+
+// arkivQueryPlanner rejects the queries that select most of the live entities, which
+// degenerate into a scan of the whole store.
+type arkivQueryPlanner struct {
+	// maxScanFraction is the fraction of the live entities a query can select, 0
+	// disables the limit.
+	maxScanFraction float64
+	// minScanEntities is the number of entities a query can always select.
+	minScanEntities uint64
+}
+
+// fullScan reports whether a query selecting the number of entities is a full scan.
+func (p arkivQueryPlanner) fullScan(entities uint64, liveEntities uint64) bool {
+	if p.maxScanFraction <= 0 || entities <= p.minScanEntities {
+		return false
+	}
+	return float64(entities) > p.maxScanFraction*float64(liveEntities)
+}
+
+// estimate estimates the number of entities matching the query from the cardinalities
+// of the bitmaps of its predicates, in every store the query is routed to.
+func (p arkivQueryPlanner) estimate(ctx context.Context, s arkivStore, req string) (*QueryEstimate, error) {
+	ast, err := query.Parse(req)
+	if err != nil {
+		return nil, invalidRequest("error parsing query: %w", err)
+	}
+
+	liveEntities, err := s.GetNumberOfEntities(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get entity count: %w", err)
+	}
+
+	var entities uint64
+	for _, shard := range arkivStores(s, req) {
+		shardEntities, err := shard.GetNumberOfEntities(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get entity count: %w", err)
+		}
+		err = shard.ReadTransaction(ctx, func(q *store.Queries) error {
+			matched, err := estimateEntities(ast, shardEntities, func(term *query.ASTTerm) (uint64, error) {
+				bitmap, err := term.Evaluate(ctx, q)
+				if err != nil {
+					return 0, err
+				}
+				return bitmap.GetCardinality(), nil
+			})
+			entities += matched
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error estimating query: %w", err)
+		}
+	}
+
+	return &QueryEstimate{
+		Entities:     entities,
+		LiveEntities: liveEntities,
+		FullScan:     p.fullScan(entities, liveEntities),
+	}, nil
+}
+
+// estimateEntities returns an upper bound of the number of entities matching the
+// normalized query: a conjunction matches at most as many entities as its most
+// selective predicate and a disjunction at most the sum of its alternatives.
+func estimateEntities(ast *query.AST, liveEntities uint64, cardinality func(*query.ASTTerm) (uint64, error)) (uint64, error) {
+	if ast.Expr == nil {
+		return liveEntities, nil
+	}
+
+	total := uint64(0)
+	for _, and := range ast.Expr.Or.Terms {
+		bound := liveEntities
+		for i := range and.Terms {
+			c, err := cardinality(&and.Terms[i])
+			if err != nil {
+				return 0, err
+			}
+			bound = min(bound, c)
+		}
+		total += bound
+	}
+
+	return min(total, liveEntities), nil
+}
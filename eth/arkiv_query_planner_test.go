@@ -0,0 +1,112 @@
+package eth
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	arkivevents "github.com/Arkiv-Network/arkiv-events"
+	"github.com/Arkiv-Network/arkiv-events/events"
+	sqlitestore "github.com/Arkiv-Network/sqlite-bitmap-store"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+// seedArkivStore creates a store with 100 entities of kind "doc", ten of which are
+// tagged "rare".
+func seedArkivStore(t *testing.T) *sqlitestore.SQLiteStore {
+	t.Helper()
+
+	store, err := sqlitestore.NewSQLiteStore(slog.New(slog.DiscardHandler), filepath.Join(t.TempDir(), "arkiv.db"), 1)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		store.Close()
+	})
+
+	block := events.Block{Number: 1}
+	for i := range 100 {
+		create := &events.OPCreate{
+			Key:               common.BytesToHash([]byte{byte(i + 1)}),
+			ContentType:       "text/plain",
+			BTL:               1000,
+			Content:           []byte("payload"),
+			StringAttributes:  map[string]string{"kind": "doc"},
+			NumericAttributes: map[string]uint64{"index": uint64(i)},
+		}
+		if i%10 == 0 {
+			create.StringAttributes["tag"] = "rare"
+		}
+		block.Operations = append(block.Operations, events.Operation{Create: create, OpIndex: uint64(i)})
+	}
+
+	iterator := func(yield func(arkivevents.BatchOrError) bool) {
+		yield(arkivevents.BatchOrError{Batch: events.BlockBatch{Blocks: []events.Block{block}}})
+	}
+	require.NoError(t, store.FollowEvents(context.Background(), iterator))
+
+	return store
+}
+
+func TestArkivQueryPlanner_Estimate(t *testing.T) {
+	store := seedArkivStore(t)
+	planner := arkivQueryPlanner{maxScanFraction: 0.5, minScanEntities: 20}
+
+	for _, tc := range []struct {
+		query    string
+		entities uint64
+		fullScan bool
+	}{
+		{query: `tag = "rare"`, entities: 10},
+		{query: `index < 30`, entities: 30},
+		{query: `kind = "doc" && tag = "rare"`, entities: 10},
+		{query: `index < 60`, entities: 60, fullScan: true},
+		{query: `kind = "doc"`, entities: 100, fullScan: true},
+		{query: `kind != "other"`, entities: 100, fullScan: true},
+		{query: `tag = "rare" || kind = "doc"`, entities: 100, fullScan: true},
+		{query: `tag = "rare" || index >= 95`, entities: 15},
+		{query: `$all`, entities: 100, fullScan: true},
+	} {
+		estimate, err := planner.estimate(context.Background(), store, tc.query)
+		require.NoError(t, err, tc.query)
+		require.Equal(t, &QueryEstimate{Entities: tc.entities, LiveEntities: 100, FullScan: tc.fullScan}, estimate, tc.query)
+	}
+}
+
+func TestArkivQueryPlanner_FullScan(t *testing.T) {
+	planner := arkivQueryPlanner{maxScanFraction: 0.5, minScanEntities: 20}
+	require.False(t, planner.fullScan(50, 100))
+	require.True(t, planner.fullScan(51, 100))
+
+	// Queries on small stores are never full scans
+	require.False(t, planner.fullScan(20, 20))
+
+	// A zero fraction disables the limit
+	planner.maxScanFraction = 0
+	require.False(t, planner.fullScan(100, 100))
+}
+
+func TestArkivAPI_QueryRejectsFullScan(t *testing.T) {
+	api := &arkivAPI{
+		store:   seedArkivStore(t),
+		planner: arkivQueryPlanner{maxScanFraction: 0.5, minScanEntities: 20},
+	}
+	atBlock := uint64(1)
+
+	_, err := api.Query(context.Background(), `kind = "doc"`, &QueryOptions{Options: sqlitestore.Options{AtBlock: &atBlock}})
+	require.ErrorContains(t, err, "query selects about 100 of 100 entities")
+
+	response, err := api.Query(context.Background(), `kind = "doc"`, &QueryOptions{
+		Options:       sqlitestore.Options{AtBlock: &atBlock},
+		AllowFullScan: true,
+		IncludeStats:  true,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, response.Data)
+	require.Equal(t, &QueryEstimate{Entities: 100, LiveEntities: 100, FullScan: true}, response.Stats.Estimate)
+
+	response, err = api.Query(context.Background(), `tag = "rare"`, &QueryOptions{Options: sqlitestore.Options{AtBlock: &atBlock}})
+	require.NoError(t, err)
+	require.Len(t, response.Data, 10)
+	require.Nil(t, response.Stats)
+}
@@ -0,0 +1,26 @@
+package eth
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// ErrReadOnly is returned for the transactions submitted to a read replica that has
+// no upstream to forward them to.
+var ErrReadOnly = errors.New("read-only node")
+
+// sendReadOnlyTx forwards a transaction submitted to a read replica to the upstream
+// sequencer, it is never added to the local pool.
+func (b *EthAPIBackend) sendReadOnlyTx(ctx context.Context, signedTx *types.Transaction) error {
+	if b.eth.seqRPCService == nil {
+		return ErrReadOnly
+	}
+	data, err := signedTx.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return b.eth.seqRPCService.CallContext(ctx, nil, "eth_sendRawTransaction", hexutil.Encode(data))
+}
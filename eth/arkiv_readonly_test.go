@@ -0,0 +1,63 @@
+package eth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadOnlySendTxRejected(t *testing.T) {
+	b := initBackend(false)
+	b.readOnly = true
+
+	tx := makeTx(0, nil, nil, key)
+	err := b.SendTx(context.Background(), tx)
+	require.ErrorIs(t, err, ErrReadOnly)
+	require.EqualError(t, err, "read-only node")
+	require.Nil(t, b.eth.txPool.Get(tx.Hash()))
+}
+
+func TestReadOnlySendTxForwarded(t *testing.T) {
+	type request struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+		Params []string        `json:"params"`
+	}
+	requests := make(chan request, 1)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		requests <- req
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"jsonrpc": "2.0", "id": req.ID, "result": "0x"})
+	}))
+	defer upstream.Close()
+
+	b := initBackend(false)
+	b.readOnly = true
+	client, err := rpc.DialHTTP(upstream.URL)
+	require.NoError(t, err)
+	defer client.Close()
+	b.eth.seqRPCService = client
+
+	tx := makeTx(0, nil, nil, key)
+	require.NoError(t, b.SendTx(context.Background(), tx))
+
+	data, err := tx.MarshalBinary()
+	require.NoError(t, err)
+	req := <-requests
+	require.Equal(t, "eth_sendRawTransaction", req.Method)
+	require.Equal(t, []string{hexutil.Encode(data)}, req.Params)
+
+	// The forwarded transaction is not pooled locally
+	require.Nil(t, b.eth.txPool.Get(tx.Hash()))
+}
@@ -0,0 +1,98 @@
+package eth
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/arkiv/dbevents"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+const (
+	// arkivReceiptPruneInterval is the interval at which the receipts are pruned.
+	arkivReceiptPruneInterval = time.Minute
+	// arkivReceiptPruneBlocks is the largest number of blocks a pruning pass goes
+	// through.
+	arkivReceiptPruneBlocks = 10_000
+)
+
+// errArkivPrunedReceipts is returned for the receipts and the logs of the blocks pruned
+// by the Arkiv retention policy.
+var errArkivPrunedReceipts = errors.New("receipts pruned by arkiv retention policy")
+
+// arkivPrunedReceiptsError returns errArkivPrunedReceipts if the receipts of the block
+// were pruned by the Arkiv retention policy, nil otherwise.
+func arkivPrunedReceiptsError(db ethdb.KeyValueReader, hash common.Hash, number uint64) error {
+	if rawdb.HasArkivPrunedReceipts(db, hash, number) {
+		return errArkivPrunedReceipts
+	}
+	return nil
+}
+
+// arkivReceiptPruner drops the receipts of the blocks without Arkiv logs once the
+// events pipeline is retention blocks past them, see dbevents.PruneReceipts.
+type arkivReceiptPruner struct {
+	db        ethdb.Database
+	retention uint64
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// newArkivReceiptPruner returns a pruner keeping the receipts for retention blocks
+// after the events checkpoint, nil if retention is 0.
+func newArkivReceiptPruner(db ethdb.Database, retention uint64) *arkivReceiptPruner {
+	if retention == 0 {
+		return nil
+	}
+	if retention >= params.FullImmutabilityThreshold {
+		log.Warn("Arkiv receipts are frozen before their retention ends, they won't be pruned", "retention", retention, "freezeAfter", params.FullImmutabilityThreshold)
+	}
+	return &arkivReceiptPruner{
+		db:        db,
+		retention: retention,
+		quit:      make(chan struct{}),
+	}
+}
+
+func (p *arkivReceiptPruner) start() {
+	p.wg.Add(1)
+	go p.loop()
+}
+
+func (p *arkivReceiptPruner) stop() {
+	close(p.quit)
+	p.wg.Wait()
+}
+
+func (p *arkivReceiptPruner) loop() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(arkivReceiptPruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.prune()
+		case <-p.quit:
+			return
+		}
+	}
+}
+
+func (p *arkivReceiptPruner) prune() {
+	pruned, err := dbevents.PruneReceipts(p.db, p.retention, arkivReceiptPruneBlocks)
+	if err != nil {
+		log.Error("Failed to prune Arkiv receipts", "err", err)
+		return
+	}
+	if pruned != nil && pruned.Blocks > 0 {
+		log.Info("Pruned receipts without Arkiv logs", "from", pruned.From, "to", pruned.To, "blocks", pruned.Blocks, "bytes", pruned.Bytes)
+	}
+}
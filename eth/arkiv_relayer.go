@@ -0,0 +1,156 @@
+package eth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	arkivaddress "github.com/ethereum/go-ethereum/arkiv/address"
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// arkivRelayMaxDeadline is how far past the time of the current block the deadline
+// of a relayed operation can be, which bounds how long the node remembers it.
+const arkivRelayMaxDeadline = 3600
+
+// errNoRelayer is returned by SendSignedOperation on a node without a relayer account,
+// see --arkiv.relayer.key.
+var errNoRelayer = errors.New("no Arkiv relayer account configured")
+
+// arkivRelayer is the account of the node sending the Arkiv operations signed as
+// typed data. It remembers the operations it relayed until their deadline, so an
+// operation is relayed once.
+type arkivRelayer struct {
+	key     *ecdsa.PrivateKey
+	address common.Address
+
+	mu      sync.Mutex
+	relayed map[common.Hash]uint64 // hash of the operation -> deadline
+}
+
+func newArkivRelayer(key *ecdsa.PrivateKey) *arkivRelayer {
+	return &arkivRelayer{
+		key:     key,
+		address: crypto.PubkeyToAddress(key.PublicKey),
+		relayed: map[common.Hash]uint64{},
+	}
+}
+
+// SendSignedOperation verifies an Arkiv operation signed as EIP-712 typed data, see
+// storagetx.ArkivOperation, and sends its transaction from the relayer account of the
+// node, in a dynamic fee transaction to the processor paying the gas the transaction
+// uses. The relayer is the sender of the transaction: it owns the entities the
+// operation creates and must own the entities it changes, the signer of the operation
+// only authorizes it.
+//
+// The operation is run on top of the state of the current block first and rejected
+// if it fails, so the relayer doesn't pay for failing transactions, and it is relayed
+// once: its deadline must be within an hour of the time of the current block.
+func (api *arkivAPI) SendSignedOperation(ctx context.Context, args SendSignedOperationArgs) (_ *RelayedOperation, err error) {
+	defer func() { err = arkivRPCError(err) }()
+
+	if err := api.methods.check("sendSignedOperation"); err != nil {
+		return nil, err
+	}
+	relayer := api.relayer
+	if relayer == nil {
+		return nil, errNoRelayer
+	}
+
+	config := api.eth.blockchain.Config()
+	op, signer, err := storagetx.VerifySignedOperation(args.TypedData, args.Signature, config.ChainID)
+	if err != nil {
+		return nil, invalidRequest("invalid signed operation: %v", err)
+	}
+	hash, err := op.Hash(config.ChainID)
+	if err != nil {
+		return nil, err
+	}
+	data, err := op.Calldata()
+	if err != nil {
+		return nil, invalidRequest("invalid operation: %v", err)
+	}
+
+	header, stateDB, err := api.headerState(nil)
+	if err != nil {
+		return nil, err
+	}
+	if op.Deadline <= header.Time {
+		return nil, invalidRequest("operation expired at %d, current block time is %d", op.Deadline, header.Time)
+	}
+	if op.Deadline > header.Time+arkivRelayMaxDeadline {
+		return nil, invalidRequest("operation deadline %d is more than %d seconds after the current block time %d", op.Deadline, arkivRelayMaxDeadline, header.Time)
+	}
+	if _, err := storagetx.UnpackArkivTransactionWithLimits(data, storagetx.UnpackLimitsAt(config, header.Time)); err != nil {
+		return nil, invalidRequest("invalid operation: %v", err)
+	}
+
+	rules := config.Rules(header.Number, header.Difficulty.Sign() == 0, header.Time)
+	intrinsicGas, floorDataGas, arkivGas, err := arkivTransactionGas(rules, storagetx.GasScheduleAt(config, header.Time), data, &op.Transaction)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := api.executeNext(header, stateDB, relayer.address, data); err != nil {
+		return nil, invalidRequest("operation fails when sent by the relayer %s: %v", relayer.address, err)
+	}
+
+	// The lock keeps the operations relayed once and the nonces of the relayer in order
+	relayer.mu.Lock()
+	defer relayer.mu.Unlock()
+
+	for relayed, deadline := range relayer.relayed {
+		if deadline <= header.Time {
+			delete(relayer.relayed, relayed)
+		}
+	}
+	if _, ok := relayer.relayed[hash]; ok {
+		return nil, invalidRequest("operation %s was already relayed", hash)
+	}
+
+	nonce, err := api.eth.APIBackend.GetPoolNonce(ctx, relayer.address)
+	if err != nil {
+		return nil, err
+	}
+	tip, err := api.eth.APIBackend.SuggestGasTipCap(ctx)
+	if err != nil {
+		return nil, err
+	}
+	feeCap := new(big.Int).Set(tip)
+	if header.BaseFee != nil {
+		feeCap.Add(feeCap, new(big.Int).Mul(header.BaseFee, big.NewInt(2)))
+	}
+	to := arkivaddress.ArkivProcessorAddress
+	tx, err := types.SignNewTx(relayer.key, types.LatestSigner(config), &types.DynamicFeeTx{
+		ChainID:   config.ChainID,
+		Nonce:     nonce,
+		GasTipCap: tip,
+		GasFeeCap: feeCap,
+		Gas:       max(intrinsicGas+arkivGas, floorDataGas),
+		To:        &to,
+		Data:      data,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign the relayed transaction: %w", err)
+	}
+	if err := api.eth.APIBackend.SendTx(ctx, tx); err != nil {
+		return nil, err
+	}
+	relayer.relayed[hash] = op.Deadline
+	log.Debug("Relayed Arkiv operation", "hash", hash, "signer", signer, "tx", tx.Hash(), "nonce", nonce)
+
+	return &RelayedOperation{
+		Hash:            hash,
+		Signer:          signer,
+		Relayer:         relayer.address,
+		TransactionHash: tx.Hash(),
+		Nonce:           hexutil.Uint64(nonce),
+	}, nil
+}
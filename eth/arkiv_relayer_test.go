@@ -0,0 +1,103 @@
+package eth
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	arkivaddress "github.com/ethereum/go-ethereum/arkiv/address"
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/eth/gasprice"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArkivAPI_SendSignedOperation(t *testing.T) {
+	b := initBackend(false)
+	b.eth.APIBackend = b
+	b.gpo = gasprice.NewOracle(b, ethconfig.Defaults.GPO, big.NewInt(params.GWei))
+	api := &arkivAPI{eth: b.eth}
+	chainID := gspec.Config.ChainID
+	ctx := context.Background()
+
+	signerKey, _ := crypto.GenerateKey()
+	sign := func(op *storagetx.ArkivOperation) SendSignedOperationArgs {
+		t.Helper()
+		typedData := op.TypedData(chainID)
+		hash, _, err := apitypes.TypedDataAndHash(typedData)
+		require.NoError(t, err)
+		signature, err := crypto.Sign(hash, signerKey)
+		require.NoError(t, err)
+		signature[crypto.RecoveryIDOffset] += 27
+		return SendSignedOperationArgs{TypedData: typedData, Signature: signature}
+	}
+	op := &storagetx.ArkivOperation{
+		Transaction: storagetx.ArkivTransaction{Create: []storagetx.ArkivCreate{{
+			BTL:         100,
+			ContentType: "text/plain",
+			Payload:     []byte("relayed"),
+		}}},
+		Nonce:    1,
+		Deadline: 600,
+	}
+
+	// Without a relayer account the operations aren't relayed
+	_, err := api.SendSignedOperation(ctx, sign(op))
+	require.ErrorIs(t, err, errNoRelayer)
+
+	api.relayer = newArkivRelayer(key)
+	relayed, err := api.SendSignedOperation(ctx, sign(op))
+	require.NoError(t, err)
+	require.Equal(t, crypto.PubkeyToAddress(signerKey.PublicKey), relayed.Signer)
+	require.Equal(t, address, relayed.Relayer)
+	hash, err := op.Hash(chainID)
+	require.NoError(t, err)
+	require.Equal(t, hash, relayed.Hash)
+
+	// The pool holds the transaction of the relayer to the processor
+	tx := b.eth.txPool.Get(relayed.TransactionHash)
+	require.NotNil(t, tx)
+	require.Equal(t, arkivaddress.ArkivProcessorAddress, *tx.To())
+	data, err := op.Calldata()
+	require.NoError(t, err)
+	require.Equal(t, data, tx.Data())
+	from, err := signer.Sender(tx)
+	require.NoError(t, err)
+	require.Equal(t, address, from)
+
+	// An operation is relayed once
+	_, err = api.SendSignedOperation(ctx, sign(op))
+	require.ErrorContains(t, err, "already relayed")
+
+	// Another nonce makes another operation, sent with the next nonce of the relayer
+	op.Nonce = 2
+	relayed, err = api.SendSignedOperation(ctx, sign(op))
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), uint64(relayed.Nonce))
+
+	// The deadline must be ahead, by an hour at most
+	op.Deadline = 0
+	_, err = api.SendSignedOperation(ctx, sign(op))
+	require.ErrorContains(t, err, "expired")
+	op.Deadline = arkivRelayMaxDeadline + 1
+	_, err = api.SendSignedOperation(ctx, sign(op))
+	require.ErrorContains(t, err, "more than")
+
+	// The operations failing when sent by the relayer are rejected
+	failing := &storagetx.ArkivOperation{
+		Transaction: storagetx.ArkivTransaction{Delete: []common.Hash{{1}}},
+		Deadline:    600,
+	}
+	_, err = api.SendSignedOperation(ctx, sign(failing))
+	require.ErrorContains(t, err, "operation fails")
+
+	// A signature of other typed data is rejected
+	args := sign(op)
+	args.TypedData.Domain.ChainId = nil
+	_, err = api.SendSignedOperation(ctx, args)
+	require.ErrorContains(t, err, "invalid signed operation")
+}
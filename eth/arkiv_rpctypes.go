@@ -0,0 +1,112 @@
+package eth
+
+import "github.com/ethereum/go-ethereum/arkiv/rpctypes"
+
+// The request and response types of the arkiv namespace are shared with its clients,
+// see the rpctypes package.
+type (
+	QueryOptions                 = rpctypes.QueryOptions
+	PendingView                  = rpctypes.PendingView
+	QueryOrder                   = rpctypes.QueryOrder
+	EntityProvenance             = rpctypes.EntityProvenance
+	QueryResponse                = rpctypes.QueryResponse
+	QueryStats                   = rpctypes.QueryStats
+	QueryEstimate                = rpctypes.QueryEstimate
+	QueryMemory                  = rpctypes.QueryMemory
+	EntityData                   = rpctypes.EntityData
+	QueryDiff                    = rpctypes.QueryDiff
+	EntityMetaData               = rpctypes.EntityMetaData
+	PendingOwner                 = rpctypes.PendingOwner
+	ExpiryEstimate               = rpctypes.ExpiryEstimate
+	EntityExpiry                 = rpctypes.EntityExpiry
+	PrunedGap                    = rpctypes.PrunedGap
+	IngestLatency                = rpctypes.IngestLatency
+	SyncStatus                   = rpctypes.SyncStatus
+	Limits                       = rpctypes.Limits
+	Limit                        = rpctypes.Limit
+	ProcessorLogsOptions         = rpctypes.ProcessorLogsOptions
+	ProcessorLog                 = rpctypes.ProcessorLog
+	ProcessorLogBucket           = rpctypes.ProcessorLogBucket
+	ProcessorLogs                = rpctypes.ProcessorLogs
+	OwnerUsageReport             = rpctypes.OwnerUsageReport
+	EventsCheckpoint             = rpctypes.EventsCheckpoint
+	DeadLetter                   = rpctypes.DeadLetter
+	WarmupQuery                  = rpctypes.WarmupQuery
+	WarmupReport                 = rpctypes.WarmupReport
+	SelfCheckFinding             = rpctypes.SelfCheckFinding
+	SelfCheck                    = rpctypes.SelfCheck
+	Entity                       = rpctypes.Entity
+	EntityEventFilter            = rpctypes.EntityEventFilter
+	EntityEvent                  = rpctypes.EntityEvent
+	Capability                   = rpctypes.Capability
+	Capabilities                 = rpctypes.Capabilities
+	ShadowRule                   = rpctypes.ShadowRule
+	ShadowViolation              = rpctypes.ShadowViolation
+	ShadowEnforcementStats       = rpctypes.ShadowEnforcementStats
+	ContentHashPair              = rpctypes.ContentHashPair
+	ContentHashResult            = rpctypes.ContentHashResult
+	ContentHashVerification      = rpctypes.ContentHashVerification
+	StorageChallengeResponse     = rpctypes.StorageChallengeResponse
+	StorageChallenge             = rpctypes.StorageChallenge
+	AliasResolution              = rpctypes.AliasResolution
+	SampleOptions                = rpctypes.SampleOptions
+	SampledEntity                = rpctypes.SampledEntity
+	EntitySample                 = rpctypes.EntitySample
+	QueryCount                   = rpctypes.QueryCount
+	OwnerEntitiesOptions         = rpctypes.OwnerEntitiesOptions
+	OwnerEntity                  = rpctypes.OwnerEntity
+	OwnerEntities                = rpctypes.OwnerEntities
+	OwnerEntitiesByExpiryOptions = rpctypes.OwnerEntitiesByExpiryOptions
+	ExpiryCursor                 = rpctypes.ExpiryCursor
+	ExpiringEntity               = rpctypes.ExpiringEntity
+	OwnerEntitiesByExpiry        = rpctypes.OwnerEntitiesByExpiry
+	OwnerClassOptions            = rpctypes.OwnerClassOptions
+	OwnerClassCursor             = rpctypes.OwnerClassCursor
+	OwnerClassEntity             = rpctypes.OwnerClassEntity
+	OwnerClassEntities           = rpctypes.OwnerClassEntities
+	ExpiringBlock                = rpctypes.ExpiringBlock
+	EntitiesToExpire             = rpctypes.EntitiesToExpire
+	SimulateTransactionArgs      = rpctypes.SimulateTransactionArgs
+	SimulationResult             = rpctypes.SimulationResult
+	EstimateStorageGasArgs       = rpctypes.EstimateStorageGasArgs
+	StorageGasEstimate           = rpctypes.StorageGasEstimate
+	SendSignedOperationArgs      = rpctypes.SendSignedOperationArgs
+	RelayedOperation             = rpctypes.RelayedOperation
+)
+
+const (
+	EntityProvenanceIndexed     = rpctypes.EntityProvenanceIndexed
+	EntityProvenanceHeadOverlay = rpctypes.EntityProvenanceHeadOverlay
+	EntityProvenancePending     = rpctypes.EntityProvenancePending
+
+	QueryOrderAscending  = rpctypes.QueryOrderAscending
+	QueryOrderDescending = rpctypes.QueryOrderDescending
+
+	EntityStatusLive    = rpctypes.EntityStatusLive
+	EntityStatusDeleted = rpctypes.EntityStatusDeleted
+	EntityStatusExpired = rpctypes.EntityStatusExpired
+	EntityStatusUnknown = rpctypes.EntityStatusUnknown
+
+	SelfCheckOK      = rpctypes.SelfCheckOK
+	SelfCheckWarning = rpctypes.SelfCheckWarning
+	SelfCheckError   = rpctypes.SelfCheckError
+
+	EntityEventCreated      = rpctypes.EntityEventCreated
+	EntityEventUpdated      = rpctypes.EntityEventUpdated
+	EntityEventDeleted      = rpctypes.EntityEventDeleted
+	EntityEventExpired      = rpctypes.EntityEventExpired
+	EntityEventExtended     = rpctypes.EntityEventExtended
+	EntityEventOwnerChanged = rpctypes.EntityEventOwnerChanged
+
+	ContentHashMatch         = rpctypes.ContentHashMatch
+	ContentHashMismatch      = rpctypes.ContentHashMismatch
+	ContentHashUnknown       = rpctypes.ContentHashUnknown
+	ContentHashEntityMissing = rpctypes.ContentHashEntityMissing
+
+	OwnerClassZeroAddress = rpctypes.OwnerClassZeroAddress
+	OwnerClassContract    = rpctypes.OwnerClassContract
+	OwnerClassEOA         = rpctypes.OwnerClassEOA
+
+	WarmupSourceConfig  = rpctypes.WarmupSourceConfig
+	WarmupSourcePopular = rpctypes.WarmupSourcePopular
+)
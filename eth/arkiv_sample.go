@@ -0,0 +1,192 @@
+package eth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	sqlitestore "github.com/Arkiv-Network/sqlite-bitmap-store"
+	"github.com/ethereum/go-ethereum/arkiv/limits"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// maxSampleEntities is the largest sample SampleEntities draws.
+const maxSampleEntities = limits.MaxSampleEntities
+
+// SampleEntities draws a sample of n of the entities live at the block, the current
+// block if the options don't set one. The sample is the n entities with the lowest
+// rank, the keccak256 hash of the seed followed by the key of the entity: it's uniform
+// over the live entities and the same on every node for a seed and a block.
+func (api *arkivAPI) SampleEntities(ctx context.Context, n uint64, seed hexutil.Bytes, opts *SampleOptions) (_ *EntitySample, err error) {
+	defer func() { err = arkivRPCError(err) }()
+
+	if err := api.methods.check("sampleEntities"); err != nil {
+		return nil, err
+	}
+
+	if n > maxSampleEntities {
+		return nil, invalidRequest("sample of %d entities, more than the limit of %d", n, maxSampleEntities)
+	}
+	if opts == nil {
+		opts = &SampleOptions{}
+	}
+	header, stateDB, err := api.headerState(opts.AtBlock)
+	if err != nil {
+		return nil, err
+	}
+	block := header.Number.Uint64()
+	keys, population, err := api.sampleKeys(ctx, stateDB, block, seed, n)
+	if err != nil {
+		return nil, err
+	}
+
+	sample := &EntitySample{
+		Block:      hexutil.Uint64(block),
+		Seed:       seed,
+		Population: population,
+		Entities:   make([]SampledEntity, len(keys)),
+	}
+	for i, key := range keys {
+		sample.Entities[i].Key = key
+		if opts.IncludeMetaData {
+			sample.Entities[i].MetaData = entityMetaData(stateDB, key)
+		}
+	}
+	return sample, nil
+}
+
+// entitySample keeps the n keys with the lowest ranks of the keys added to it.
+type entitySample struct {
+	seed []byte
+	n    int
+	keys []rankedKey
+}
+
+type rankedKey struct {
+	rank common.Hash
+	key  common.Hash
+}
+
+func newEntitySample(seed []byte, n uint64) *entitySample {
+	return &entitySample{seed: seed, n: int(n)}
+}
+
+func (s *entitySample) add(key common.Hash) {
+	s.keys = append(s.keys, rankedKey{rank: crypto.Keccak256Hash(s.seed, key[:]), key: key})
+	// The keys beyond the n lowest are dropped once there are n of them too many
+	if len(s.keys) >= 2*s.n+1 {
+		s.truncate()
+	}
+}
+
+func (s *entitySample) truncate() {
+	slices.SortFunc(s.keys, func(a, b rankedKey) int {
+		if c := bytes.Compare(a.rank[:], b.rank[:]); c != 0 {
+			return c
+		}
+		return bytes.Compare(a.key[:], b.key[:])
+	})
+	s.keys = s.keys[:min(len(s.keys), s.n)]
+}
+
+// sorted returns the keys of the sample, the lowest rank first.
+func (s *entitySample) sorted() []common.Hash {
+	s.truncate()
+	keys := make([]common.Hash, len(s.keys))
+	for i, ranked := range s.keys {
+		keys[i] = ranked.key
+	}
+	return keys
+}
+
+// sampleKeys returns the keys of the sample of n entities live at the block and the
+// number of entities live at the block. The keys of the entities of the store are
+// read page by page, without the rest of the entities, and only the lowest ranks are
+// kept. The entities the blocks between the block and the last block indexed by the
+// store operated on, at most arkivMaxRewindBlocks, are checked against the state of
+// the block instead.
+func (api *arkivAPI) sampleKeys(ctx context.Context, stateDB *state.StateDB, block uint64, seed []byte, n uint64) ([]common.Hash, uint64, error) {
+	for {
+		lastIndexed, err := api.store.GetLastBlock(ctx)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to get last block from store: %w", err)
+		}
+		from, to := min(block, lastIndexed)+1, max(block, lastIndexed)
+		if to-min(block, lastIndexed) > arkivMaxRewindBlocks {
+			return nil, 0, notIndexed(block, lastIndexed, "block %d is more than %d blocks away from the last indexed block %d", block, arkivMaxRewindBlocks, lastIndexed)
+		}
+		changed := map[common.Hash]struct{}{}
+		for number := from; number <= to; number++ {
+			if err := ctx.Err(); err != nil {
+				return nil, 0, err
+			}
+			decoded, err := api.blockOperations(number)
+			if err != nil {
+				return nil, 0, err
+			}
+			for _, operation := range decoded.Operations {
+				changed[operationKey(operation)] = struct{}{}
+			}
+		}
+
+		sample := newEntitySample(seed, n)
+		var population uint64
+		err = storeKeys(ctx, api.store, lastIndexed, func(key common.Hash) {
+			if _, ok := changed[key]; !ok {
+				sample.add(key)
+				population++
+			}
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+		// The store moved on while being read, start over at its new block
+		if latest, err := api.store.GetLastBlock(ctx); err != nil {
+			return nil, 0, fmt.Errorf("failed to get last block from store: %w", err)
+		} else if latest != lastIndexed {
+			continue
+		}
+
+		for key := range changed {
+			if entity.Exists(stateDB, key) {
+				sample.add(key)
+				population++
+			}
+		}
+		return sample.sorted(), population, nil
+	}
+}
+
+// storeKeys calls fn with the key of every entity of the store, which has indexed the
+// block.
+func storeKeys(ctx context.Context, s arkivStore, block uint64, fn func(common.Hash)) error {
+	options := &sqlitestore.Options{
+		AtBlock:     &block,
+		IncludeData: &sqlitestore.IncludeData{Key: true},
+	}
+	for {
+		response, err := s.QueryEntities(ctx, "$all", options)
+		if err != nil {
+			return fmt.Errorf("failed to query the entities: %w", err)
+		}
+		for _, d := range response.Data {
+			ed := sqlitestore.EntityData{}
+			if err := json.Unmarshal(d, &ed); err != nil {
+				return fmt.Errorf("failed to unmarshal entity data: %w", err)
+			}
+			if ed.Key != nil {
+				fn(*ed.Key)
+			}
+		}
+		if response.Cursor == nil || *response.Cursor == "" {
+			return nil
+		}
+		options.Cursor = *response.Cursor
+	}
+}
@@ -0,0 +1,143 @@
+package eth
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/arkiv/rpctypes"
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/require"
+)
+
+// newSampleAPI returns an API over a chain where 10 entities are created at block 1,
+// the first 3 of them deleted at block 2 and the fourth extended at block 3, with the
+// first indexed blocks indexed, and the keys of the entities.
+func newSampleAPI(t *testing.T, indexed int) (*arkivAPI, []common.Hash) {
+	t.Helper()
+
+	key, _ := crypto.GenerateKey()
+	var created []common.Hash
+	steps := []arkivTestStep{
+		func([]common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			atx := &storagetx.ArkivTransaction{}
+			for i := range 10 {
+				atx.Create = append(atx.Create, storagetx.ArkivCreate{BTL: 100, ContentType: "text/plain", Payload: []byte(fmt.Sprintf("e%d", i))})
+			}
+			return key, atx
+		},
+		func(keys []common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			return key, &storagetx.ArkivTransaction{Delete: keys[:3]}
+		},
+		func(keys []common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			created = keys
+			return key, &storagetx.ArkivTransaction{Extend: []storagetx.ExtendBTL{{EntityKey: keys[3], NumberOfBlocks: 10}}}
+		},
+	}
+	api, _ := newArkivTestAPI(t, key, key, steps, indexed)
+	return api, created
+}
+
+// lowestRanks returns the n keys with the lowest ranks with the seed.
+func lowestRanks(seed []byte, keys []common.Hash, n int) []common.Hash {
+	keys = slices.Clone(keys)
+	slices.SortFunc(keys, func(a, b common.Hash) int {
+		return bytes.Compare(crypto.Keccak256(seed, a[:]), crypto.Keccak256(seed, b[:]))
+	})
+	return keys[:min(n, len(keys))]
+}
+
+func sampleKeysOf(sample *EntitySample) []common.Hash {
+	keys := make([]common.Hash, len(sample.Entities))
+	for i, entity := range sample.Entities {
+		keys[i] = entity.Key
+	}
+	return keys
+}
+
+func TestArkivAPI_SampleEntities(t *testing.T) {
+	at := func(block uint64) *hexutil.Uint64 {
+		return (*hexutil.Uint64)(&block)
+	}
+	ctx := context.Background()
+	seed := hexutil.Bytes("audit")
+
+	// The store indexed the head, or is behind the block sampled at
+	for _, indexed := range []int{3, 1} {
+		api, keys := newSampleAPI(t, indexed)
+
+		sample, err := api.SampleEntities(ctx, 4, seed, nil)
+		require.NoError(t, err, "%d indexed", indexed)
+		require.Equal(t, hexutil.Uint64(3), sample.Block)
+		require.Equal(t, uint64(7), sample.Population, "%d indexed", indexed)
+		require.Equal(t, lowestRanks(seed, keys[3:], 4), sampleKeysOf(sample), "%d indexed", indexed)
+		require.Nil(t, sample.Entities[0].MetaData)
+
+		// Before the deletions
+		sample, err = api.SampleEntities(ctx, 4, seed, &SampleOptions{AtBlock: at(1)})
+		require.NoError(t, err, "%d indexed", indexed)
+		require.Equal(t, uint64(10), sample.Population, "%d indexed", indexed)
+		require.Equal(t, lowestRanks(seed, keys, 4), sampleKeysOf(sample), "%d indexed", indexed)
+	}
+
+	t.Run("seeds", func(t *testing.T) {
+		api, keys := newSampleAPI(t, 3)
+
+		// A sample larger than the population holds all the entities
+		sample, err := api.SampleEntities(ctx, 100, seed, nil)
+		require.NoError(t, err)
+		require.Equal(t, lowestRanks(seed, keys[3:], 100), sampleKeysOf(sample))
+
+		// The same seed draws the same sample, another seed ranks the entities differently
+		again, err := api.SampleEntities(ctx, 100, seed, nil)
+		require.NoError(t, err)
+		require.Equal(t, sample, again)
+		other, err := api.SampleEntities(ctx, 100, hexutil.Bytes("other"), nil)
+		require.NoError(t, err)
+		require.ElementsMatch(t, sampleKeysOf(sample), sampleKeysOf(other))
+		require.NotEqual(t, sampleKeysOf(sample), sampleKeysOf(other))
+	})
+
+	t.Run("metadata", func(t *testing.T) {
+		api, _ := newSampleAPI(t, 3)
+		sample, err := api.SampleEntities(ctx, 2, seed, &SampleOptions{IncludeMetaData: true})
+		require.NoError(t, err)
+		for _, entity := range sample.Entities {
+			require.NotNil(t, entity.MetaData)
+			require.Equal(t, EntityStatusLive, entity.MetaData.Status)
+		}
+	})
+
+	t.Run("limits", func(t *testing.T) {
+		api, _ := newSampleAPI(t, 3)
+		_, err := api.SampleEntities(ctx, maxSampleEntities+1, seed, nil)
+		var rpcErr rpc.Error
+		require.ErrorAs(t, err, &rpcErr)
+		require.Equal(t, rpctypes.ErrCodeValidation, rpcErr.ErrorCode())
+
+		_, err = api.SampleEntities(ctx, 1, seed, &SampleOptions{AtBlock: at(4)})
+		require.EqualError(t, err, "block is in the future: head is 3")
+	})
+}
+
+func TestEntitySample_Bounded(t *testing.T) {
+	seed := []byte{1}
+	var keys []common.Hash
+	sample := newEntitySample(seed, 5)
+	for i := range 1000 {
+		key := common.BigToHash(common.Big1)
+		key[0] = byte(i)
+		key[1] = byte(i >> 8)
+		keys = append(keys, key)
+		sample.add(key)
+		require.LessOrEqual(t, len(sample.keys), 10)
+	}
+	require.Equal(t, lowestRanks(seed, keys, 5), sample.sorted())
+}
@@ -0,0 +1,240 @@
+package eth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+	"time"
+
+	storeschema "github.com/Arkiv-Network/sqlite-bitmap-store/store"
+	arkivaddress "github.com/ethereum/go-ethereum/arkiv/address"
+	"github.com/ethereum/go-ethereum/arkiv/dbevents"
+	"github.com/ethereum/go-ethereum/arkiv/shards"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// arkivSelfCheckTickTimeout is how long the self-check waits for the store to process a
+// tick sent through the events pipeline.
+const arkivSelfCheckTickTimeout = 5 * time.Second
+
+// arkivSelfChecker checks the Arkiv subsystem is wired correctly, when the node starts
+// and with arkiv_selfCheck.
+type arkivSelfChecker struct {
+	store     arkivStore
+	storePath string
+	chain     *core.BlockChain
+	ticker    *dbevents.Ticker
+}
+
+// run runs all the checks, a failing check doesn't stop the next ones.
+func (c *arkivSelfChecker) run(ctx context.Context) *SelfCheck {
+	report := &SelfCheck{OK: true}
+	for _, check := range []struct {
+		name string
+		run  func(context.Context) (string, error)
+	}{
+		{"store", c.checkStore},
+		{"checkpoint", c.checkCheckpoint},
+		{"processor", c.checkProcessor},
+		{"forks", c.checkForks},
+		{"events", c.checkEvents},
+	} {
+		finding := SelfCheckFinding{Check: check.name, Status: SelfCheckOK}
+		message, err := check.run(ctx)
+		var warning *selfCheckWarning
+		switch {
+		case errors.As(err, &warning):
+			finding.Status, finding.Message = SelfCheckWarning, warning.message
+		case err != nil:
+			finding.Status, finding.Message = SelfCheckError, err.Error()
+			report.OK = false
+		default:
+			finding.Message = message
+		}
+		report.Findings = append(report.Findings, finding)
+	}
+	return report
+}
+
+// selfCheckWarning is returned by a check that found a problem the node runs with.
+type selfCheckWarning struct {
+	message string
+}
+
+func (w *selfCheckWarning) Error() string {
+	return w.message
+}
+
+// checkStore checks the store can be read and its schema, and the one of every shard,
+// is the one of the store release the node is built with.
+func (c *arkivSelfChecker) checkStore(ctx context.Context) (string, error) {
+	if c.storePath == ":memory:" {
+		// Every connection to an in-memory database opens its own database
+		return "", &selfCheckWarning{"the store is in memory, its queries don't see the ingested entities"}
+	}
+	entities, err := c.store.GetNumberOfEntities(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the store: %w", err)
+	}
+
+	latest, err := latestStoreSchemaVersion()
+	if err != nil {
+		return "", err
+	}
+	router, ok := c.store.(*shards.Router)
+	if !ok || len(router.Shards()) == 1 {
+		if err := checkStoreSchema(ctx, c.storePath, latest); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%d entities, schema version %d", entities, latest), nil
+	}
+	for _, shard := range router.Shards() {
+		if err := checkStoreSchema(ctx, shard.Path, latest); err != nil {
+			return "", fmt.Errorf("shard %s: %w", shard.Name, err)
+		}
+	}
+	return fmt.Sprintf("%d entities in %d shards, schema version %d", entities, len(router.Shards()), latest), nil
+}
+
+// checkStoreSchema checks the schema of the store file is at the version.
+func checkStoreSchema(ctx context.Context, path string, latest uint64) error {
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?mode=ro", path))
+	if err != nil {
+		return fmt.Errorf("failed to open the store: %w", err)
+	}
+	defer db.Close()
+	var (
+		version uint64
+		dirty   bool
+	)
+	if err := db.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations").Scan(&version, &dirty); err != nil {
+		return fmt.Errorf("failed to read the schema version of the store: %w", err)
+	}
+	if dirty {
+		return fmt.Errorf("the migration of the store schema to version %d didn't complete", version)
+	}
+	if version != latest {
+		return fmt.Errorf("the store schema is at version %d, expected version %d", version, latest)
+	}
+	return nil
+}
+
+// latestStoreSchemaVersion returns the version of the last migration of the store.
+func latestStoreSchemaVersion() (uint64, error) {
+	migrations, err := fs.Glob(storeschema.Migrations, "schema/*.up.sql")
+	if err != nil {
+		return 0, err
+	}
+	var latest uint64
+	for _, migration := range migrations {
+		name := strings.TrimPrefix(migration, "schema/")
+		version, err := strconv.ParseUint(name[:strings.IndexByte(name, '_')], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid store migration %q: %w", name, err)
+		}
+		latest = max(latest, version)
+	}
+	return latest, nil
+}
+
+// checkCheckpoint checks the last block ingested by the store is on the chain.
+func (c *arkivSelfChecker) checkCheckpoint(ctx context.Context) (string, error) {
+	lastBlock, err := c.store.GetLastBlock(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get last block from store: %w", err)
+	}
+	head := c.chain.CurrentBlock().Number.Uint64()
+	if lastBlock > head {
+		return "", fmt.Errorf("the store is at block %d, after the head %d of the chain: rebuild the Arkiv store or move its checkpoint with arkiv_setEventsCheckpoint", lastBlock, head)
+	}
+	return fmt.Sprintf("block %d of %d", lastBlock, head), nil
+}
+
+// checkProcessor checks the address of the Arkiv processor has no code, a contract of
+// the genesis deployed there would run instead of the Arkiv transactions.
+func (c *arkivSelfChecker) checkProcessor(context.Context) (string, error) {
+	state, err := c.chain.State()
+	if err != nil {
+		return "", fmt.Errorf("failed to open the state of the head: %w", err)
+	}
+	if size := state.GetCodeSize(arkivaddress.ArkivProcessorAddress); size > 0 {
+		return "", fmt.Errorf("the Arkiv processor address %s has %d bytes of code", arkivaddress.ArkivProcessorAddress, size)
+	}
+	return "", nil
+}
+
+// checkForks checks the settings of the Arkiv forks of the chain config take effect.
+func (c *arkivSelfChecker) checkForks(context.Context) (string, error) {
+	config := c.chain.Config()
+	var warnings []string
+	if !config.IsOptimism() && config.ArkivHousekeepingOrderTime != nil {
+		warnings = append(warnings, "the housekeeping order fork is set on a chain without deposit transactions")
+	}
+	if (config.ArkivMaxAnnotationValueSize != 0 || config.ArkivAnnotationValueGasThreshold != 0 || config.ArkivAnnotationValueGasPerByte != 0) && config.ArkivGasScheduleTime == nil {
+		warnings = append(warnings, "the annotation value limits are set without arkivGasScheduleTime")
+	}
+	if config.ArkivTombstoneRetention != 0 && config.ArkivTombstonesTime == nil {
+		warnings = append(warnings, "arkivTombstoneRetention is set without arkivTombstonesTime")
+	}
+	if config.ArkivOwnershipTransferWindow != 0 && config.ArkivOwnershipTime == nil {
+		warnings = append(warnings, "arkivOwnershipTransferWindow is set without arkivOwnershipTime")
+	}
+	if len(warnings) > 0 {
+		return "", &selfCheckWarning{strings.Join(warnings, ", ")}
+	}
+	return "", nil
+}
+
+// checkEvents checks the store reads the events pipeline by sending a tick through it.
+// A store busy ingesting a large batch only makes the check a warning.
+func (c *arkivSelfChecker) checkEvents(ctx context.Context) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, arkivSelfCheckTickTimeout)
+	defer cancel()
+	start := time.Now()
+	err := c.ticker.Tick(ctx)
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return "", &selfCheckWarning{fmt.Sprintf("the store didn't process a tick within %s", arkivSelfCheckTickTimeout)}
+	case err != nil:
+		return "", err
+	}
+	return fmt.Sprintf("tick processed in %s", time.Since(start).Round(time.Millisecond)), nil
+}
+
+// logArkivSelfCheck logs the findings of a self-check in a single record.
+func logArkivSelfCheck(report *SelfCheck) {
+	logFn := log.Info
+	var ctx []any
+	for _, finding := range report.Findings {
+		value := finding.Status
+		if finding.Message != "" {
+			value += ": " + finding.Message
+		}
+		ctx = append(ctx, finding.Check, value)
+		switch finding.Status {
+		case SelfCheckError:
+			logFn = log.Error
+		case SelfCheckWarning:
+			if report.OK {
+				logFn = log.Warn
+			}
+		}
+	}
+	logFn("Arkiv self-check", ctx...)
+}
+
+// arkivSelfCheckError returns the error of the failed checks of a self-check.
+func arkivSelfCheckError(report *SelfCheck) error {
+	var failed []string
+	for _, finding := range report.Findings {
+		if finding.Status == SelfCheckError {
+			failed = append(failed, fmt.Sprintf("%s: %s", finding.Check, finding.Message))
+		}
+	}
+	return fmt.Errorf("the Arkiv self-check failed, %s", strings.Join(failed, "; "))
+}
@@ -0,0 +1,192 @@
+package eth
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	arkivevents "github.com/Arkiv-Network/arkiv-events"
+	"github.com/Arkiv-Network/arkiv-events/events"
+	sqlitestore "github.com/Arkiv-Network/sqlite-bitmap-store"
+	arkivaddress "github.com/ethereum/go-ethereum/arkiv/address"
+	"github.com/ethereum/go-ethereum/arkiv/dbevents"
+	"github.com/ethereum/go-ethereum/consensus/beacon"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+// newArkivSelfChecker returns a self-checker over a chain of the genesis alone and a
+// store in a file.
+func newArkivSelfChecker(t *testing.T, config *params.ChainConfig, alloc types.GenesisAlloc) *arkivSelfChecker {
+	t.Helper()
+
+	gspec := &core.Genesis{Config: config, Alloc: alloc, BaseFee: big.NewInt(params.InitialBaseFee)}
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), gspec, beacon.New(ethash.NewFaker()), nil)
+	require.NoError(t, err)
+	t.Cleanup(chain.Stop)
+
+	path := filepath.Join(t.TempDir(), "arkiv.db")
+	store, err := sqlitestore.NewSQLiteStore(slog.New(slog.DiscardHandler), path, 1)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		store.Close()
+	})
+
+	return &arkivSelfChecker{store: store, storePath: path, chain: chain, ticker: dbevents.NewTicker()}
+}
+
+// followEvents starts reading the pipeline of the self-checker until the test ends.
+func (c *arkivSelfChecker) followEvents(t *testing.T) {
+	quit := make(chan struct{})
+	t.Cleanup(func() { close(quit) })
+	iterator := func(yield func(arkivevents.BatchOrError) bool) {
+		<-quit
+	}
+	go c.store.(*sqlitestore.SQLiteStore).FollowEvents(context.Background(), c.ticker.Wrap(iterator, func() (uint64, error) {
+		return c.store.GetLastBlock(context.Background())
+	}))
+}
+
+func selfCheckFinding(t *testing.T, report *SelfCheck, check string) SelfCheckFinding {
+	t.Helper()
+	for _, finding := range report.Findings {
+		if finding.Check == check {
+			return finding
+		}
+	}
+	t.Fatalf("no %s finding in %+v", check, report.Findings)
+	return SelfCheckFinding{}
+}
+
+func TestArkivSelfCheck(t *testing.T) {
+	checker := newArkivSelfChecker(t, arkivConvergenceConfig(true), nil)
+	checker.followEvents(t)
+
+	report := checker.run(context.Background())
+	require.True(t, report.OK, "%+v", report.Findings)
+	for _, finding := range report.Findings {
+		require.Equal(t, SelfCheckOK, finding.Status, finding.Check)
+	}
+	latest, err := latestStoreSchemaVersion()
+	require.NoError(t, err)
+	require.Equal(t, fmt.Sprintf("0 entities, schema version %d", latest), selfCheckFinding(t, report, "store").Message)
+	require.Equal(t, "block 0 of 0", selfCheckFinding(t, report, "checkpoint").Message)
+
+	// The tick leaves the checkpoint where it was
+	lastBlock, err := checker.store.GetLastBlock(context.Background())
+	require.NoError(t, err)
+	require.Zero(t, lastBlock)
+}
+
+func TestArkivSelfCheck_StoreSchema(t *testing.T) {
+	checker := newArkivSelfChecker(t, arkivConvergenceConfig(true), nil)
+	checker.followEvents(t)
+
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s", checker.storePath))
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec("UPDATE schema_migrations SET version = 99")
+	require.NoError(t, err)
+	report := checker.run(context.Background())
+	require.False(t, report.OK)
+	finding := selfCheckFinding(t, report, "store")
+	require.Equal(t, SelfCheckError, finding.Status)
+	require.Contains(t, finding.Message, "the store schema is at version 99")
+
+	_, err = db.Exec("UPDATE schema_migrations SET dirty = 1")
+	require.NoError(t, err)
+	report = checker.run(context.Background())
+	require.Equal(t, "the migration of the store schema to version 99 didn't complete", selfCheckFinding(t, report, "store").Message)
+
+	// The other checks still run
+	require.Equal(t, SelfCheckOK, selfCheckFinding(t, report, "events").Status)
+
+	checker.storePath = ":memory:"
+	report = checker.run(context.Background())
+	require.True(t, report.OK)
+	require.Equal(t, SelfCheckWarning, selfCheckFinding(t, report, "store").Status)
+}
+
+func TestArkivSelfCheck_CheckpointAfterHead(t *testing.T) {
+	checker := newArkivSelfChecker(t, arkivConvergenceConfig(true), nil)
+	iterator := func(yield func(arkivevents.BatchOrError) bool) {
+		yield(arkivevents.BatchOrError{Batch: events.BlockBatch{Blocks: []events.Block{{Number: 10}}}})
+	}
+	require.NoError(t, checker.store.(*sqlitestore.SQLiteStore).FollowEvents(context.Background(), iterator))
+	checker.followEvents(t)
+
+	report := checker.run(context.Background())
+	require.False(t, report.OK)
+	finding := selfCheckFinding(t, report, "checkpoint")
+	require.Equal(t, SelfCheckError, finding.Status)
+	require.Contains(t, finding.Message, "the store is at block 10, after the head 0 of the chain")
+}
+
+func TestArkivSelfCheck_ProcessorCode(t *testing.T) {
+	checker := newArkivSelfChecker(t, arkivConvergenceConfig(true), types.GenesisAlloc{
+		arkivaddress.ArkivProcessorAddress: {Code: []byte{0x60, 0x00}, Balance: new(big.Int)},
+	})
+	checker.followEvents(t)
+
+	report := checker.run(context.Background())
+	require.False(t, report.OK)
+	finding := selfCheckFinding(t, report, "processor")
+	require.Equal(t, SelfCheckError, finding.Status)
+	require.Equal(t, fmt.Sprintf("the Arkiv processor address %s has 2 bytes of code", arkivaddress.ArkivProcessorAddress), finding.Message)
+}
+
+func TestArkivSelfCheck_Forks(t *testing.T) {
+	config := arkivConvergenceConfig(true)
+	config.ArkivGasScheduleTime = nil
+	config.ArkivMaxAnnotationValueSize = 1024
+	config.ArkivTombstonesTime = nil
+	config.ArkivOwnershipTransferWindow = 10
+	checker := newArkivSelfChecker(t, config, nil)
+	checker.followEvents(t)
+
+	// Settings without effect don't fail the check
+	report := checker.run(context.Background())
+	require.True(t, report.OK)
+	finding := selfCheckFinding(t, report, "forks")
+	require.Equal(t, SelfCheckWarning, finding.Status)
+	require.Equal(t, "the annotation value limits are set without arkivGasScheduleTime, arkivTombstoneRetention is set without arkivTombstonesTime, arkivOwnershipTransferWindow is set without arkivOwnershipTime", finding.Message)
+
+	nonOptimism := *params.TestChainConfig
+	nonOptimism.ArkivHousekeepingOrderTime = new(uint64)
+	checker = newArkivSelfChecker(t, &nonOptimism, nil)
+	checker.followEvents(t)
+	finding = selfCheckFinding(t, checker.run(context.Background()), "forks")
+	require.Equal(t, "the housekeeping order fork is set on a chain without deposit transactions", finding.Message)
+}
+
+func TestArkivSelfCheck_Events(t *testing.T) {
+	checker := newArkivSelfChecker(t, arkivConvergenceConfig(true), nil)
+
+	// The store never reads the pipeline
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	report := checker.run(ctx)
+	require.False(t, report.OK)
+	finding := selfCheckFinding(t, report, "events")
+	require.Equal(t, SelfCheckError, finding.Status)
+	require.Equal(t, dbevents.ErrEventsNotStarted.Error(), finding.Message)
+
+	// The ingestion halts
+	iterator := func(yield func(arkivevents.BatchOrError) bool) {
+		yield(arkivevents.BatchOrError{Error: fmt.Errorf("halted")})
+	}
+	require.Error(t, checker.store.(*sqlitestore.SQLiteStore).FollowEvents(context.Background(), checker.ticker.Wrap(iterator, func() (uint64, error) { return 0, nil })))
+	finding = selfCheckFinding(t, checker.run(context.Background()), "events")
+	require.Equal(t, SelfCheckError, finding.Status)
+	require.Equal(t, dbevents.ErrEventsStopped.Error(), finding.Message)
+}
@@ -0,0 +1,42 @@
+package eth
+
+import (
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// ShadowEnforcementStats returns the checks of the rules of the processor before their
+// forks activate, not enabled unless the node runs with --arkiv.shadow-enforcement.
+// It's one of the arkivReportingMethods, which the operator can't disable.
+func (api *arkivAPI) ShadowEnforcementStats() *ShadowEnforcementStats {
+	recorder := api.eth.arkivShadow
+	result := &ShadowEnforcementStats{
+		Enabled: recorder != nil,
+		Rules:   []ShadowRule{},
+		Recent:  []ShadowViolation{},
+	}
+	if recorder == nil {
+		return result
+	}
+	name := func(id params.ArkivFeature) string {
+		feature, _ := params.ArkivFeatureByID(id)
+		return feature.Name
+	}
+	for _, stats := range recorder.Stats() {
+		result.Rules = append(result.Rules, ShadowRule{
+			ID:         stats.Feature[:],
+			Name:       name(stats.Feature),
+			Evaluated:  stats.Evaluated,
+			Violations: stats.Violations,
+		})
+	}
+	for _, violation := range recorder.Recent() {
+		result.Recent = append(result.Recent, ShadowViolation{
+			Rule:   name(violation.Feature),
+			Block:  hexutil.Uint64(violation.Block),
+			TxHash: violation.TxHash,
+			Error:  violation.Error,
+		})
+	}
+	return result
+}
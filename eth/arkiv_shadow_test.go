@@ -0,0 +1,45 @@
+package eth
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/arkiv/shadow"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShadowEnforcementStats(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		api := &arkivAPI{eth: &Ethereum{}}
+		require.Equal(t, &ShadowEnforcementStats{Rules: []ShadowRule{}, Recent: []ShadowViolation{}}, api.ShadowEnforcementStats())
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		recorder := shadow.NewRecorder(shadow.DefaultRecentViolations)
+		activation := uint64(100)
+		config := &params.ChainConfig{ArkivGasScheduleTime: &activation}
+		for i, err := range []error{nil, errors.New("create[0] string annotation a value is too long: 9000 bytes (max 8192)")} {
+			require.NoError(t, shadow.Enforce(recorder, config, params.ArkivFeatureGasSchedule, 50, uint64(11+i), common.Hash{byte(i)}, func() error { return err }))
+		}
+
+		api := &arkivAPI{eth: &Ethereum{arkivShadow: recorder}}
+		require.Equal(t, &ShadowEnforcementStats{
+			Enabled: true,
+			Rules: []ShadowRule{{
+				ID:         params.ArkivFeatureGasSchedule[:],
+				Name:       "arkiv.gasSchedule",
+				Evaluated:  2,
+				Violations: 1,
+			}},
+			Recent: []ShadowViolation{{
+				Rule:   "arkiv.gasSchedule",
+				Block:  hexutil.Uint64(12),
+				TxHash: common.Hash{1},
+				Error:  "create[0] string annotation a value is too long: 9000 bytes (max 8192)",
+			}},
+		}, api.ShadowEnforcementStats())
+	})
+}
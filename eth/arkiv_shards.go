@@ -0,0 +1,25 @@
+package eth
+
+import (
+	"context"
+
+	sqlitestore "github.com/Arkiv-Network/sqlite-bitmap-store"
+	"github.com/ethereum/go-ethereum/arkiv/shards"
+)
+
+// arkivStore is what the Arkiv API reads the entities from: the store, or the router of
+// its shards when it's sharded.
+type arkivStore interface {
+	QueryEntities(ctx context.Context, query string, options *sqlitestore.Options) (*sqlitestore.QueryResponse, error)
+	GetLastBlock(ctx context.Context) (uint64, error)
+	GetNumberOfEntities(ctx context.Context) (uint64, error)
+}
+
+// arkivStores returns the stores holding the entities the query can select, for the
+// reads that go to the stores directly.
+func arkivStores(s arkivStore, req string) []*sqlitestore.SQLiteStore {
+	if router, ok := s.(*shards.Router); ok {
+		return router.Stores(req)
+	}
+	return []*sqlitestore.SQLiteStore{s.(*sqlitestore.SQLiteStore)}
+}
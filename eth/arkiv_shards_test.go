@@ -0,0 +1,156 @@
+package eth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	arkivevents "github.com/Arkiv-Network/arkiv-events"
+	"github.com/Arkiv-Network/arkiv-events/events"
+	sqlitestore "github.com/Arkiv-Network/sqlite-bitmap-store"
+	"github.com/ethereum/go-ethereum/arkiv/dbevents"
+	"github.com/ethereum/go-ethereum/arkiv/shards"
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+// newShardedArkivAPI returns an API over the chain of the API whose entities are split
+// over shards by the rules, the shards indexed the whole chain.
+func newShardedArkivAPI(t *testing.T, api *arkivAPI, specs ...string) *arkivAPI {
+	t.Helper()
+
+	rules, err := shards.ParseRules(specs)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "arkiv.db")
+	open := func(path string) (*sqlitestore.SQLiteStore, error) {
+		return sqlitestore.NewSQLiteStore(slog.New(slog.DiscardHandler), path, 1)
+	}
+	store, err := open(path)
+	require.NoError(t, err)
+	router, err := shards.New(store, path, rules, open)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		router.Close()
+		store.Close()
+	})
+
+	chain := api.eth.blockchain
+	batch := events.BlockBatch{}
+	for number := uint64(1); number <= chain.CurrentBlock().Number.Uint64(); number++ {
+		block := chain.GetBlockByNumber(number)
+		decoded, _, err := dbevents.BlockToEvents(block, chain.GetReceiptsByHash(block.Hash()))
+		require.NoError(t, err)
+		batch.Blocks = append(batch.Blocks, *decoded)
+	}
+	iterator := func(yield func(arkivevents.BatchOrError) bool) {
+		yield(arkivevents.BatchOrError{Batch: batch})
+	}
+	require.NoError(t, router.FollowEvents(context.Background(), iterator))
+
+	return &arkivAPI{eth: api.eth, store: router}
+}
+
+func TestArkivAPI_Shards(t *testing.T) {
+	keyA, _ := crypto.GenerateKey()
+	keyB, _ := crypto.GenerateKey()
+
+	annotations := func(namespace string, kind string) []storagetx.StringAnnotation {
+		annotations := []storagetx.StringAnnotation{{Key: "kind", Value: kind}}
+		if namespace != "" {
+			annotations = append(annotations, storagetx.StringAnnotation{Key: shards.NamespaceAttribute, Value: namespace})
+		}
+		return annotations
+	}
+	create := func(payload string, namespace string) storagetx.ArkivCreate {
+		return storagetx.ArkivCreate{BTL: 100, ContentType: "text/plain", Payload: []byte(payload), StringAnnotations: annotations(namespace, "x")}
+	}
+	var created []common.Hash
+	steps := []arkivTestStep{
+		// Block 1: e0 and e3 in namespace a, e1 in namespace b, e2 without namespace
+		func([]common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			return keyA, &storagetx.ArkivTransaction{Create: []storagetx.ArkivCreate{create("e0", "a"), create("e1", "b"), create("e2", ""), create("e3", "a")}}
+		},
+		// Block 2: e4 in namespace a and e5 in namespace b, created by B
+		func([]common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			return keyB, &storagetx.ArkivTransaction{Create: []storagetx.ArkivCreate{create("e4", "a"), create("e5", "b")}}
+		},
+		// Block 3: e0 no longer matches kind = "x", e1 is deleted
+		func(keys []common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			return keyA, &storagetx.ArkivTransaction{
+				Update: []storagetx.ArkivUpdate{{EntityKey: keys[0], BTL: 100, ContentType: "text/plain", Payload: []byte("e0"), StringAnnotations: annotations("a", "y")}},
+				Delete: []common.Hash{keys[1]},
+			}
+		},
+		// Block 4: nothing
+		func(keys []common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			created = keys
+			return nil, nil
+		},
+	}
+	api, _ := newArkivTestAPI(t, keyA, keyB, steps, len(steps))
+	creatorB := crypto.PubkeyToAddress(keyB.PublicKey)
+	sharded := newShardedArkivAPI(t, api, "a=namespace:a", "b=owner:"+creatorB.Hex()[:10])
+	ctx := context.Background()
+
+	router := sharded.store.(*shards.Router)
+	shardKeys := func(shard *shards.Shard) []common.Hash {
+		t.Helper()
+		response, err := shard.Store.QueryEntities(ctx, "$all", &sqlitestore.Options{IncludeData: &sqlitestore.IncludeData{Key: true}})
+		require.NoError(t, err)
+		var keys []common.Hash
+		for _, entity := range queryEntities(t, &QueryResponse{QueryResponse: response}) {
+			keys = append(keys, *entity.Key)
+		}
+		return keys
+	}
+	require.Equal(t, []common.Hash{created[2]}, shardKeys(router.Shards()[0]))
+	require.Equal(t, []common.Hash{created[4], created[3], created[0]}, shardKeys(router.Shards()[1]), "e4 matches the namespace rule first")
+	require.Equal(t, []common.Hash{created[5]}, shardKeys(router.Shards()[2]))
+
+	all := func(api *arkivAPI, req string, atBlock uint64, perPage uint64) []common.Hash {
+		t.Helper()
+		var keys []common.Hash
+		cursor := ""
+		for {
+			response, err := api.Query(ctx, req, &QueryOptions{Options: sqlitestore.Options{
+				AtBlock:        &atBlock,
+				ResultsPerPage: &perPage,
+				Cursor:         cursor,
+				IncludeData:    &sqlitestore.IncludeData{Key: true},
+			}})
+			require.NoError(t, err)
+			require.LessOrEqual(t, len(response.Data), int(perPage))
+			for _, entity := range queryEntities(t, response) {
+				keys = append(keys, *entity.Key)
+			}
+			if response.Cursor == nil {
+				return keys
+			}
+			cursor = *response.Cursor
+		}
+	}
+
+	// The sharded store answers like the store, at the head and before the changes
+	for _, req := range []string{`namespace = "a"`, `kind = "x"`, `$all`, `namespace = "b" || namespace = "a"`} {
+		for _, block := range []uint64{2, 4} {
+			expected := all(api, req, block, 100)
+			require.NotEmpty(t, expected, req)
+			for _, perPage := range []uint64{1, 2, 100} {
+				require.Equal(t, expected, all(sharded, req, block, perPage), "%s at block %d, %d per page", req, block, perPage)
+			}
+		}
+	}
+	require.Equal(t, []common.Hash{created[4], created[3]}, all(sharded, `namespace = "a" && kind = "x"`, 4, 1))
+
+	count, err := sharded.GetEntityCount(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(5), count)
+
+	entity, err := sharded.GetEntity(ctx, created[0], nil)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"kind": "y", shards.NamespaceAttribute: "a"}, entity.StringAttributes)
+}
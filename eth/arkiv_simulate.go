@@ -0,0 +1,163 @@
+package eth
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/ethereum/go-ethereum/arkiv/housekeepingtx"
+	"github.com/ethereum/go-ethereum/arkiv/limits"
+	arkivlogs "github.com/ethereum/go-ethereum/arkiv/logs"
+	"github.com/ethereum/go-ethereum/arkiv/statediff"
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// maxSimulationOffset is the largest TargetBlockOffset of SimulateTransaction.
+const maxSimulationOffset = limits.MaxSimulationOffset
+
+// SimulateTransaction runs the Arkiv transaction on top of the state of the current
+// block, as the first transaction of the next block, with the rules of the current
+// block, and discards its changes. From the housekeeping order fork the housekeeping
+// of the next block runs before all its transactions, so it is run first, before the
+// fork it isn't run. The transaction has no hash yet: the keys of the entities it
+// creates are derived from the hash of the sender and the calldata, and differ from the
+// ones of the mined transaction.
+//
+// With a TargetBlockOffset of n the transaction lands n blocks after the next one
+// instead: the housekeeping of the blocks in between is run first, with the rules of
+// the current block, along with the one of the block the transaction lands in from
+// the housekeeping order fork. ExpiredEntities lists the entities it expires that the
+// transaction refers to.
+//
+// With IncludeStateDiff the result holds the slots of the processor the transaction
+// changes, decoded into the structures they belong to, see statediff.Decode. The
+// slots changed by the housekeeping are left out.
+func (api *arkivAPI) SimulateTransaction(ctx context.Context, args SimulateTransactionArgs) (_ *SimulationResult, err error) {
+	defer func() { err = arkivRPCError(err) }()
+
+	if err := api.methods.check("simulateTransaction"); err != nil {
+		return nil, err
+	}
+
+	offset := uint64(args.TargetBlockOffset)
+	if offset > maxSimulationOffset {
+		return nil, invalidRequest("targetBlockOffset %d exceeds the limit of %d blocks", offset, maxSimulationOffset)
+	}
+
+	header, stateDB, err := api.headerState(nil)
+	if err != nil {
+		return nil, err
+	}
+	config := api.eth.blockchain.Config()
+	block := header.Number.Uint64() + 1 + offset
+	tombstoneRetention := config.ArkivTombstoneRetentionAt(header.Time)
+	transferWindow := config.ArkivOwnershipTransferWindowAt(header.Time)
+	idempotencyTTL := config.ArkivIdempotencyTTLAt(header.Time)
+
+	expired, err := api.runHousekeeping(ctx, header, stateDB, block)
+	if err != nil {
+		return nil, err
+	}
+
+	recorder := statediff.NewRecorder(stateDB)
+	logs, err := api.executeAt(header, block, recorder, args.From, args.Data)
+	result := &SimulationResult{
+		Block:       hexutil.Uint64(header.Number.Uint64()),
+		TargetBlock: hexutil.Uint64(block),
+		Success:     err == nil,
+		Logs:        []*types.Log{},
+	}
+	if len(expired) > 0 {
+		// The entities expired in the interim matter to the transaction if it refers
+		// to them, whether it fails on them or not
+		if tx, err := storagetx.UnpackArkivTransactionWithLimits(args.Data, storagetx.UnpackLimitsAt(config, header.Time)); err == nil {
+			for _, key := range tx.ReferencedEntityKeys() {
+				if _, ok := expired[key]; ok && !slices.Contains(result.ExpiredEntities, key) {
+					result.ExpiredEntities = append(result.ExpiredEntities, key)
+				}
+			}
+		}
+	}
+	if err != nil {
+		result.Error = err.Error()
+		return result, nil
+	}
+	result.Logs = logs
+
+	if args.IncludeStateDiff {
+		hints := statediff.Hints{
+			Owners: []common.Address{args.From},
+			Blocks: []uint64{block, block + tombstoneRetention, block + transferWindow, block + idempotencyTTL},
+		}
+		for _, log := range logs {
+			if len(log.Topics) > 1 {
+				hints.Keys = append(hints.Keys, log.Topics[1])
+			}
+		}
+		// The slots of the idempotency keys are derived from the keys and the sender
+		if tx, err := storagetx.UnpackArkivTransactionWithLimits(args.Data, storagetx.UnpackLimitsAt(config, header.Time)); err == nil {
+			for _, create := range tx.Create {
+				if create.IdempotencyKey != nil {
+					hints.Keys = append(hints.Keys, entity.IdempotencyHash(args.From, *create.IdempotencyKey))
+				}
+			}
+		}
+		result.StateDiff = statediff.Decode(recorder.Slots(), hints)
+	}
+	return result, nil
+}
+
+// executeNext runs the calldata of an Arkiv transaction sent by from on the state, as
+// the first transaction of the block after header, with the rules of header. The hash
+// of the transaction is derived from the sender and the calldata.
+func (api *arkivAPI) executeNext(header *types.Header, access storageutil.StateAccess, from common.Address, data []byte) ([]*types.Log, error) {
+	return api.executeAt(header, header.Number.Uint64()+1, access, from, data)
+}
+
+// executeAt runs the calldata like executeNext, as the first transaction of block.
+func (api *arkivAPI) executeAt(header *types.Header, block uint64, access storageutil.StateAccess, from common.Address, data []byte) ([]*types.Log, error) {
+	config := api.eth.blockchain.Config()
+	return storagetx.ExecuteArkivTransaction(
+		data,
+		storagetx.RulesAt(config, header.Time),
+		block,
+		crypto.Keccak256Hash(from[:], data),
+		0,
+		from,
+		access,
+	)
+}
+
+// runHousekeeping runs the housekeeping of the blocks after header that precedes a
+// transaction in block on the state, with the rules of header, and returns the entities
+// it expires. The housekeeping of block itself precedes the transaction from the
+// housekeeping order fork, which runs it first in the block.
+func (api *arkivAPI) runHousekeeping(ctx context.Context, header *types.Header, stateDB *state.StateDB, block uint64) (map[common.Hash]struct{}, error) {
+	rules := housekeepingtx.RulesAt(api.eth.blockchain.Config(), header.Time)
+	last := block - 1
+	if rules.Ordered {
+		last = block
+	}
+	expired := map[common.Hash]struct{}{}
+	logs := housekeepingtx.LogAppenderFunc(func(log *types.Log) {
+		if len(log.Topics) > 1 && log.Topics[0] == arkivlogs.ArkivEntityExpired {
+			expired[log.Topics[1]] = struct{}{}
+		}
+	})
+	for number := header.Number.Uint64() + 1; number <= last; number++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := housekeepingtx.ExecuteTransaction(number, common.Hash{}, rules, stateDB, logs); err != nil {
+			return nil, fmt.Errorf("failed to run the housekeeping of block %d: %w", number, err)
+		}
+	}
+	return expired, nil
+}
@@ -0,0 +1,142 @@
+package eth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/arkiv/compression"
+	"github.com/ethereum/go-ethereum/arkiv/statediff"
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArkivAPI_SimulateTransaction(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+
+	var created []common.Hash
+	steps := []arkivTestStep{
+		// Block 1: an entity expiring at block 101 is created
+		func([]common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			return key, &storagetx.ArkivTransaction{Create: []storagetx.ArkivCreate{{BTL: 100, ContentType: "text/plain", Payload: []byte("e0")}}}
+		},
+		func(keys []common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			created = keys
+			return nil, nil
+		},
+	}
+	gspec, blocks, _ := newArkivTestChain(t, key, key, steps)
+	unordered := newIndexedAPI(t, gspec, blocks, len(blocks))
+	// The blocks only carry the L1 attributes deposit, they run the same housekeeping
+	// with the housekeeping order fork active
+	config := *gspec.Config
+	config.ArkivHousekeepingOrderTime = new(uint64)
+	gspec.Config = &config
+	api := newIndexedAPI(t, gspec, blocks, len(blocks))
+	ctx := context.Background()
+
+	simulate := func(api *arkivAPI, atx *storagetx.ArkivTransaction, offset uint64, includeStateDiff bool) (*SimulationResult, error) {
+		t.Helper()
+		data, err := rlp.EncodeToBytes(atx)
+		require.NoError(t, err)
+		return api.SimulateTransaction(ctx, SimulateTransactionArgs{
+			From:              sender,
+			Data:              compression.MustBrotliCompress(data),
+			TargetBlockOffset: hexutil.Uint64(offset),
+			IncludeStateDiff:  includeStateDiff,
+		})
+	}
+
+	extend := &storagetx.ArkivTransaction{Extend: []storagetx.ExtendBTL{{EntityKey: created[0], NumberOfBlocks: 50}}}
+	result, err := simulate(api, extend, 0, false)
+	require.NoError(t, err)
+	require.True(t, result.Success)
+	require.Equal(t, hexutil.Uint64(2), result.Block)
+	require.Equal(t, hexutil.Uint64(3), result.TargetBlock)
+	require.Len(t, result.Logs, 1)
+	require.Nil(t, result.StateDiff)
+
+	// The entity moves from the set of block 101 to the one of block 151
+	result, err = simulate(api, extend, 0, true)
+	require.NoError(t, err)
+	require.True(t, result.Success)
+	var metaData *statediff.DecodedSlot
+	sets := map[hexutil.Uint64]int{}
+	for i, slot := range result.StateDiff {
+		require.NotEqual(t, statediff.KindUnknown, slot.Kind, "slot %s", slot.Slot.Slot)
+		switch slot.Kind {
+		case statediff.KindEntityMetaData:
+			metaData = &result.StateDiff[i]
+		case statediff.KindEntitiesToExpire:
+			sets[*slot.Block]++
+		}
+	}
+	require.NotNil(t, metaData)
+	require.Equal(t, created[0], *metaData.Key)
+	require.Equal(t, &statediff.MetaData{Owner: sender, ExpiresAtBlock: 101}, metaData.OldValue)
+	require.Equal(t, &statediff.MetaData{Owner: sender, ExpiresAtBlock: 151}, metaData.NewValue)
+	require.Equal(t, map[hexutil.Uint64]int{101: 3, 151: 3}, sets)
+
+	// Head is block 2: with an offset of 97 the transaction lands in block 100 and the
+	// entity is still live
+	result, err = simulate(api, extend, 97, false)
+	require.NoError(t, err)
+	require.True(t, result.Success)
+	require.Equal(t, hexutil.Uint64(100), result.TargetBlock)
+	require.Empty(t, result.ExpiredEntities)
+
+	// One block later the housekeeping of block 101 runs before the transaction and
+	// expires it first
+	result, err = simulate(api, extend, 98, true)
+	require.NoError(t, err)
+	require.False(t, result.Success)
+	require.NotEmpty(t, result.Error)
+	require.Empty(t, result.Logs)
+	require.Equal(t, hexutil.Uint64(101), result.TargetBlock)
+	require.Equal(t, []common.Hash{created[0]}, result.ExpiredEntities)
+	require.Nil(t, result.StateDiff)
+
+	// Before the fork the housekeeping of block 101 isn't run first
+	result, err = simulate(unordered, extend, 98, false)
+	require.NoError(t, err)
+	require.True(t, result.Success)
+	require.Equal(t, hexutil.Uint64(101), result.TargetBlock)
+	require.Empty(t, result.ExpiredEntities)
+
+	// A create doesn't refer to the entities expired in the interim
+	create := &storagetx.ArkivTransaction{Create: []storagetx.ArkivCreate{{BTL: 10, ContentType: "text/plain", Payload: []byte("e1")}}}
+	result, err = simulate(api, create, 98, true)
+	require.NoError(t, err)
+	require.True(t, result.Success)
+	require.Empty(t, result.ExpiredEntities)
+	for _, slot := range result.StateDiff {
+		// The slots changed by the housekeeping are left out
+		if slot.Kind == statediff.KindEntitiesToExpire {
+			require.NotEqual(t, hexutil.Uint64(101), *slot.Block)
+		}
+	}
+
+	// Nothing is written to the state
+	_, stateDB, err := api.headerState(nil)
+	require.NoError(t, err)
+	md, err := entity.GetEntityMetaData(stateDB, created[0])
+	require.NoError(t, err)
+	require.Equal(t, uint64(101), md.ExpiresAtBlock)
+
+	// A failing transaction reports its error
+	result, err = simulate(api, &storagetx.ArkivTransaction{Delete: []common.Hash{{1}}}, 0, true)
+	require.NoError(t, err)
+	require.False(t, result.Success)
+	require.NotEmpty(t, result.Error)
+	require.Empty(t, result.Logs)
+	require.Nil(t, result.StateDiff)
+
+	_, err = simulate(api, extend, maxSimulationOffset+1, false)
+	require.ErrorContains(t, err, "targetBlockOffset")
+}
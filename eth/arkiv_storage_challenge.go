@@ -0,0 +1,92 @@
+package eth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	sqlitestore "github.com/Arkiv-Network/sqlite-bitmap-store"
+	"github.com/ethereum/go-ethereum/arkiv/compression"
+	"github.com/ethereum/go-ethereum/arkiv/limits"
+	"github.com/ethereum/go-ethereum/arkiv/storagechallenge"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// maxChallengeKeys is the largest number of keys StorageChallenge answers for.
+const maxChallengeKeys = limits.MaxChallengeKeys
+
+// StorageChallenge answers a storage challenge with the payloads of the entities as the
+// store holds them at its last indexed block: the response for a key is the keccak256
+// hash of the nonce followed by the payload, nil if the store doesn't hold the entity.
+// Unlike GetEntity, nothing is rebuilt from the chain, so the responses prove what the
+// store of the node holds. The stored payloads are streamed through the hash, the
+// compressed ones are decompressed as they are hashed.
+func (api *arkivAPI) StorageChallenge(ctx context.Context, keys []common.Hash, nonce hexutil.Bytes) (_ *StorageChallenge, err error) {
+	defer func() { err = arkivRPCError(err) }()
+
+	if err := api.methods.check("storageChallenge"); err != nil {
+		return nil, err
+	}
+
+	if len(keys) > maxChallengeKeys {
+		return nil, invalidRequest("%d keys, more than the limit of %d", len(keys), maxChallengeKeys)
+	}
+	if err := storagechallenge.CheckNonce(nonce); err != nil {
+		return nil, invalidRequest("%w", err)
+	}
+	block, err := api.store.GetLastBlock(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last block from store: %w", err)
+	}
+
+	challenge := &StorageChallenge{
+		Block:     hexutil.Uint64(block),
+		Nonce:     nonce,
+		Responses: make([]StorageChallengeResponse, len(keys)),
+	}
+	for i, key := range keys {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		response, err := api.challengeResponse(ctx, key, block, nonce)
+		if err != nil {
+			return nil, err
+		}
+		challenge.Responses[i] = StorageChallengeResponse{Key: key, Response: response}
+	}
+	return challenge, nil
+}
+
+// challengeResponse returns the response to the challenge with the nonce for the
+// payload of the entity stored at the block, nil if the store doesn't hold it.
+func (api *arkivAPI) challengeResponse(ctx context.Context, key common.Hash, block uint64, nonce []byte) (*common.Hash, error) {
+	response, err := api.store.QueryEntities(ctx, fmt.Sprintf("$key = %s", key.Hex()), &sqlitestore.Options{
+		AtBlock:     &block,
+		IncludeData: &sqlitestore.IncludeData{Payload: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read entity %s: %w", key.Hex(), err)
+	}
+	if len(response.Data) == 0 {
+		return nil, nil
+	}
+
+	var ed sqlitestore.EntityData
+	if err := json.Unmarshal(response.Data[0], &ed); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal entity data: %w", err)
+	}
+	var payload io.Reader = bytes.NewReader(ed.Value)
+	if api.compressedPayloads && ed.Value != nil {
+		if payload, err = compression.NewPayloadReader(ed.Value); err != nil {
+			return nil, fmt.Errorf("failed to read the payload of %s: %w", key.Hex(), err)
+		}
+	}
+	hash, err := storagechallenge.Response(nonce, payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash the payload of %s: %w", key.Hex(), err)
+	}
+	return &hash, nil
+}
@@ -0,0 +1,94 @@
+package eth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	sqlitestore "github.com/Arkiv-Network/sqlite-bitmap-store"
+	"github.com/ethereum/go-ethereum/arkiv/rpctypes"
+	"github.com/ethereum/go-ethereum/arkiv/storagechallenge"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/require"
+)
+
+// corruptingStore is a store returning another payload for an entity.
+type corruptingStore struct {
+	arkivStore
+	corrupted common.Hash
+}
+
+func (s *corruptingStore) QueryEntities(ctx context.Context, query string, options *sqlitestore.Options) (*sqlitestore.QueryResponse, error) {
+	response, err := s.arkivStore.QueryEntities(ctx, query, options)
+	if err != nil || query != fmt.Sprintf("$key = %s", s.corrupted.Hex()) || len(response.Data) == 0 {
+		return response, err
+	}
+	var ed sqlitestore.EntityData
+	if err := json.Unmarshal(response.Data[0], &ed); err != nil {
+		return nil, err
+	}
+	ed.Value = append(bytes.Clone(ed.Value), '!')
+	if response.Data[0], err = json.Marshal(&ed); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func TestArkivAPI_StorageChallenge(t *testing.T) {
+	ctx := context.Background()
+	// e0 is updated at block 2 and e1 deleted at block 3
+	api, e0, e1 := newContentHashesAPI(t, 3)
+	missing := common.HexToHash("0x01")
+	keys := []common.Hash{e0, e1, missing}
+
+	nonce, err := storagechallenge.NewNonce()
+	require.NoError(t, err)
+	response := storagechallenge.PayloadResponse(nonce, []byte("e0 v2"))
+	challenge, err := api.StorageChallenge(ctx, keys, nonce)
+	require.NoError(t, err)
+	require.Equal(t, &StorageChallenge{
+		Block: 3,
+		Nonce: nonce,
+		Responses: []StorageChallengeResponse{
+			{Key: e0, Response: &response},
+			{Key: e1},
+			{Key: missing},
+		},
+	}, challenge)
+
+	// The verifier holding the payload of e0 detects a replica corrupting it
+	expected := []*common.Hash{&response, nil, nil}
+	replica := &arkivAPI{eth: api.eth, store: &corruptingStore{arkivStore: api.store, corrupted: e0}}
+	verify := func(api *arkivAPI) []storagechallenge.Result {
+		t.Helper()
+		challenge, err := api.StorageChallenge(ctx, keys, nonce)
+		require.NoError(t, err)
+		responses := make([]*common.Hash, len(challenge.Responses))
+		for i, r := range challenge.Responses {
+			responses[i] = r.Response
+		}
+		results, err := storagechallenge.Verify(keys, expected, responses)
+		require.NoError(t, err)
+		return results
+	}
+	require.Equal(t, storagechallenge.StatusOK, verify(api)[0].Status)
+	require.Equal(t, storagechallenge.StatusCorrupted, verify(replica)[0].Status)
+
+	// And a replica missing it
+	replica.store = &hidingStore{arkivStore: api.store, hidden: e0}
+	require.Equal(t, storagechallenge.StatusMissing, verify(replica)[0].Status)
+
+	t.Run("limits", func(t *testing.T) {
+		var rpcErr rpc.Error
+		_, err := api.StorageChallenge(ctx, make([]common.Hash, maxChallengeKeys+1), nonce)
+		require.ErrorAs(t, err, &rpcErr)
+		require.Equal(t, rpctypes.ErrCodeValidation, rpcErr.ErrorCode())
+
+		_, err = api.StorageChallenge(ctx, keys, nonce[:storagechallenge.MinNonceLength-1])
+		require.ErrorAs(t, err, &rpcErr)
+		require.Equal(t, rpctypes.ErrCodeValidation, rpcErr.ErrorCode())
+	})
+}
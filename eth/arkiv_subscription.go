@@ -0,0 +1,185 @@
+package eth
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/ethereum/go-ethereum/arkiv/dbevents"
+	"github.com/ethereum/go-ethereum/arkiv/storageutil/entity"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// EntityEvents subscribes to the lifecycle events of the entities matching the filter,
+// sent for every block appended to the canonical chain after the subscription. The
+// blocks skipped by a head moving several blocks at once are sent in order, the blocks
+// replacing others in a reorg are sent again.
+func (api *arkivAPI) EntityEvents(ctx context.Context, filter *EntityEventFilter) (_ *rpc.Subscription, err error) {
+	defer func() { err = arkivRPCError(err) }()
+
+	if err := api.methods.check("entityEvents"); err != nil {
+		return nil, err
+	}
+
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	if filter == nil {
+		filter = &EntityEventFilter{}
+	}
+
+	sub := notifier.CreateSubscription()
+	heads := make(chan core.ChainHeadEvent, chainHeadChanSize)
+	headSub := api.eth.blockchain.SubscribeChainHeadEvent(heads)
+	current := api.eth.blockchain.CurrentBlock()
+	last, lastHash := current.Number.Uint64(), current.Hash()
+
+	go func() {
+		defer headSub.Unsubscribe()
+		for {
+			select {
+			case head := <-heads:
+				number, hash := head.Header.Number.Uint64(), head.Header.Hash()
+				if hash == lastHash {
+					continue
+				}
+				// The head moved back or sideways in a reorg, its block replaced ours
+				from := min(last+1, number)
+				for n := from; n <= number; n++ {
+					events, err := api.entityEvents(n, filter)
+					if err != nil {
+						log.Warn("Failed to read the Arkiv entity events of a subscription", "block", n, "err", err)
+						return
+					}
+					for _, event := range events {
+						if err := notifier.Notify(sub.ID, event); err != nil {
+							return
+						}
+					}
+				}
+				last, lastHash = number, hash
+			case <-sub.Err():
+				return
+			}
+		}
+	}()
+	return sub, nil
+}
+
+// entityEvents returns the events of the operations of the canonical block matching
+// the filter. The previous owners of the ownership changes are read from their logs,
+// the owners of the entities the other operations don't carry from the state before
+// the block.
+func (api *arkivAPI) entityEvents(number uint64, filter *EntityEventFilter) ([]*EntityEvent, error) {
+	block := api.eth.blockchain.GetBlockByNumber(number)
+	if block == nil {
+		return nil, fmt.Errorf("block %d not found", number)
+	}
+	receipts := api.eth.blockchain.GetReceiptsByHash(block.Hash())
+	decoded, _, err := dbevents.BlockToEvents(block, receipts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode block %d: %w", number, err)
+	}
+	if len(decoded.Operations) == 0 {
+		return nil, nil
+	}
+	header := block.Header()
+	type opPosition struct{ txIndex, opIndex uint64 }
+	previousOwners := make(map[opPosition]common.Address)
+	for _, change := range dbevents.BlockOwnerChanges(block, receipts) {
+		previousOwners[opPosition{change.TxIndex, change.OpIndex}] = change.PreviousOwner
+	}
+
+	var parentState *state.StateDB
+	owners := make(map[common.Hash]common.Address)
+	owner := func(key common.Hash) (common.Address, error) {
+		if owner, ok := owners[key]; ok {
+			return owner, nil
+		}
+		if parentState == nil {
+			parent := api.eth.blockchain.GetHeaderByHash(header.ParentHash)
+			if parent == nil {
+				return common.Address{}, fmt.Errorf("parent of block %d not found", number)
+			}
+			if parentState, err = api.eth.blockchain.StateAt(parent.Root); err != nil {
+				return common.Address{}, fmt.Errorf("failed to get state: %w", err)
+			}
+		}
+		md, err := entity.GetEntityMetaData(parentState, key)
+		if err != nil {
+			return common.Address{}, fmt.Errorf("failed to get the owner of entity %s: %w", key.Hex(), err)
+		}
+		owners[key] = md.Owner
+		return md.Owner, nil
+	}
+	expiresAt := func(btl uint64) *hexutil.Uint64 {
+		block := hexutil.Uint64(number + btl)
+		return &block
+	}
+
+	var result []*EntityEvent
+	for _, operation := range decoded.Operations {
+		event := &EntityEvent{
+			Key:         operationKey(operation),
+			BlockNumber: hexutil.Uint64(number),
+			BlockHash:   header.Hash(),
+			TxIndex:     hexutil.Uint64(operation.TxIndex),
+			OpIndex:     hexutil.Uint64(operation.OpIndex),
+		}
+		switch {
+		case operation.Create != nil:
+			event.Kind = EntityEventCreated
+			event.Owner = operation.Create.Owner
+			event.ExpiresAtBlock = expiresAt(operation.Create.BTL)
+			owners[event.Key] = event.Owner
+		case operation.Update != nil:
+			event.Kind = EntityEventUpdated
+			event.Owner = operation.Update.Owner
+			event.ExpiresAtBlock = expiresAt(operation.Update.BTL)
+			owners[event.Key] = event.Owner
+		case operation.ChangeOwner != nil:
+			previous := previousOwners[opPosition{operation.TxIndex, operation.OpIndex}]
+			event.Kind = EntityEventOwnerChanged
+			event.Owner = operation.ChangeOwner.Owner
+			event.PreviousOwner = &previous
+			owners[event.Key] = event.Owner
+		case operation.ExtendBTL != nil:
+			event.Kind = EntityEventExtended
+			event.Owner, err = owner(event.Key)
+			event.ExpiresAtBlock = expiresAt(operation.ExtendBTL.BTL)
+		case operation.Delete != nil:
+			event.Kind = EntityEventDeleted
+			event.Owner, err = owner(event.Key)
+		case operation.Expire != nil:
+			event.Kind = EntityEventExpired
+			event.Owner, err = owner(event.Key)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if matchEntityEvent(filter, event) {
+			result = append(result, event)
+		}
+	}
+	return result, nil
+}
+
+// matchEntityEvent reports whether the event matches the filter.
+func matchEntityEvent(filter *EntityEventFilter, event *EntityEvent) bool {
+	if len(filter.Keys) > 0 && !slices.Contains(filter.Keys, event.Key) {
+		return false
+	}
+	if len(filter.Owners) > 0 && !slices.Contains(filter.Owners, event.Owner) &&
+		(event.PreviousOwner == nil || !slices.Contains(filter.Owners, *event.PreviousOwner)) {
+		return false
+	}
+	return true
+}
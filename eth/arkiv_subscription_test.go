@@ -0,0 +1,136 @@
+package eth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/beacon"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArkivAPI_EntityEvents(t *testing.T) {
+	keyA, _ := crypto.GenerateKey()
+	keyB, _ := crypto.GenerateKey()
+	a, b := crypto.PubkeyToAddress(keyA.PublicKey), crypto.PubkeyToAddress(keyB.PublicKey)
+
+	create := func(payload string, btl uint64) storagetx.ArkivCreate {
+		return storagetx.ArkivCreate{BTL: btl, ContentType: "text/plain", Payload: []byte(payload)}
+	}
+	var keys []common.Hash
+	steps := []arkivTestStep{
+		// Block 1: A creates e0, e1 expiring at block 3, and e2
+		func([]common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			return keyA, &storagetx.ArkivTransaction{Create: []storagetx.ArkivCreate{create("e0", 100), create("e1", 2), create("e2", 100)}}
+		},
+		// Block 2: B creates e3
+		func([]common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			return keyB, &storagetx.ArkivTransaction{Create: []storagetx.ArkivCreate{create("e3", 100)}}
+		},
+		// Block 3: e1 expires, A extends e0
+		func(keys []common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			return keyA, &storagetx.ArkivTransaction{Extend: []storagetx.ExtendBTL{{EntityKey: keys[0], NumberOfBlocks: 50}}}
+		},
+		// Block 4: A gives e0 to B and deletes e2
+		func(created []common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			keys = created
+			return keyA, &storagetx.ArkivTransaction{
+				ChangeOwner: []storagetx.ArkivChangeOwner{{EntityKey: created[0], NewOwner: b}},
+				Delete:      []common.Hash{created[2]},
+			}
+		},
+		// Block 5: B creates e4
+		func([]common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			return keyB, &storagetx.ArkivTransaction{Create: []storagetx.ArkivCreate{create("e4", 100)}}
+		},
+	}
+	gspec, blocks, _ := newArkivTestChain(t, keyA, keyB, steps)
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), gspec, beacon.New(ethash.NewFaker()), nil)
+	require.NoError(t, err)
+	t.Cleanup(chain.Stop)
+	_, err = chain.InsertChain(blocks[:1])
+	require.NoError(t, err)
+
+	server := rpc.NewServer()
+	t.Cleanup(server.Stop)
+	require.NoError(t, server.RegisterName("arkiv", &arkivAPI{eth: &Ethereum{blockchain: chain}}))
+	client := rpc.DialInProc(server)
+	t.Cleanup(client.Close)
+	ctx := context.Background()
+
+	subscribe := func(filter *EntityEventFilter) (chan *EntityEvent, *rpc.ClientSubscription) {
+		t.Helper()
+		ch := make(chan *EntityEvent, 16)
+		sub, err := client.Subscribe(ctx, "arkiv", ch, "entityEvents", filter)
+		require.NoError(t, err)
+		return ch, sub
+	}
+	receive := func(ch chan *EntityEvent, n int) []*EntityEvent {
+		t.Helper()
+		var events []*EntityEvent
+		for range n {
+			select {
+			case event := <-ch:
+				events = append(events, event)
+			case <-time.After(5 * time.Second):
+				t.Fatalf("received %d events, expected %d", len(events), n)
+			}
+		}
+		return events
+	}
+
+	all, allSub := subscribe(nil)
+	owned, ownedSub := subscribe(&EntityEventFilter{Owners: []common.Address{b}})
+	keyed, keyedSub := subscribe(&EntityEventFilter{Keys: []common.Hash{keys[0]}})
+
+	// The head moves from block 1 to 4 at once, the blocks in between are sent too
+	_, err = chain.InsertChain(blocks[1:4])
+	require.NoError(t, err)
+
+	expiresAt := func(block uint64) *hexutil.Uint64 { return (*hexutil.Uint64)(&block) }
+	event := func(kind string, key common.Hash, owner common.Address, block uint64, txIndex, opIndex uint64) *EntityEvent {
+		return &EntityEvent{
+			Kind:        kind,
+			Key:         key,
+			Owner:       owner,
+			BlockNumber: hexutil.Uint64(block),
+			BlockHash:   blocks[block-1].Hash(),
+			TxIndex:     hexutil.Uint64(txIndex),
+			OpIndex:     hexutil.Uint64(opIndex),
+		}
+	}
+	created := event(EntityEventCreated, keys[3], b, 2, 1, 0)
+	created.ExpiresAtBlock = expiresAt(102)
+	expired := event(EntityEventExpired, keys[1], a, 3, 0, 0)
+	extended := event(EntityEventExtended, keys[0], a, 3, 1, 0)
+	extended.ExpiresAtBlock = expiresAt(151)
+	deleted := event(EntityEventDeleted, keys[2], a, 4, 1, 0)
+	changed := event(EntityEventOwnerChanged, keys[0], b, 4, 1, 1)
+	changed.PreviousOwner = &a
+
+	require.Equal(t, []*EntityEvent{created, expired, extended, deleted, changed}, receive(all, 5))
+	require.Equal(t, []*EntityEvent{created, changed}, receive(owned, 2), "the previous owner of e0 matches too")
+	require.Equal(t, []*EntityEvent{extended, changed}, receive(keyed, 2))
+
+	// Nothing is sent after the unsubscription
+	allSub.Unsubscribe()
+	keyedSub.Unsubscribe()
+	_, err = chain.InsertChain(blocks[4:])
+	require.NoError(t, err)
+	require.Equal(t, EntityEventCreated, receive(owned, 1)[0].Kind)
+	ownedSub.Unsubscribe()
+	select {
+	case event := <-all:
+		t.Fatalf("unexpected event %+v", event)
+	default:
+	}
+}
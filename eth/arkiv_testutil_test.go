@@ -0,0 +1,144 @@
+package eth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"log/slog"
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	arkivevents "github.com/Arkiv-Network/arkiv-events"
+	"github.com/Arkiv-Network/arkiv-events/events"
+	sqlitestore "github.com/Arkiv-Network/sqlite-bitmap-store"
+	arkivaddress "github.com/ethereum/go-ethereum/arkiv/address"
+	"github.com/ethereum/go-ethereum/arkiv/compression"
+	"github.com/ethereum/go-ethereum/arkiv/dbevents"
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/beacon"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip1559"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/stretchr/testify/require"
+)
+
+// arkivTestStep returns the Arkiv transaction of a block and the key signing it,
+// given the keys of the entities created so far.
+type arkivTestStep func(keys []common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction)
+
+// newArkivTestAPI returns an API over a chain running the steps, one block each,
+// whose store indexed the first indexed blocks, and the gas used by the Arkiv
+// transaction of each block.
+func newArkivTestAPI(t *testing.T, keyA, keyB *ecdsa.PrivateKey, steps []arkivTestStep, indexed int) (*arkivAPI, []uint64) {
+	t.Helper()
+
+	gspec, chainBlocks, receipts := newArkivTestChain(t, keyA, keyB, steps)
+
+	// The gas used by the Arkiv transaction of every block, the deposit aside
+	gasUsed := make([]uint64, len(receipts)+1)
+	for i, blockReceipts := range receipts {
+		for _, receipt := range blockReceipts[1:] {
+			require.Equal(t, types.ReceiptStatusSuccessful, receipt.Status, "block %d", i+1)
+			gasUsed[i+1] += receipt.GasUsed
+		}
+	}
+
+	return newIndexedAPI(t, gspec, chainBlocks, indexed), gasUsed
+}
+
+// newIndexedAPI returns an API over the chain of the blocks, whose store indexed the
+// first indexed blocks.
+func newIndexedAPI(t *testing.T, gspec *core.Genesis, chainBlocks []*types.Block, indexed int) *arkivAPI {
+	t.Helper()
+
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), gspec, beacon.New(ethash.NewFaker()), nil)
+	require.NoError(t, err)
+	t.Cleanup(chain.Stop)
+	_, err = chain.InsertChain(chainBlocks)
+	require.NoError(t, err)
+
+	store, err := sqlitestore.NewSQLiteStore(slog.New(slog.DiscardHandler), filepath.Join(t.TempDir(), "arkiv.db"), 1)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		store.Close()
+	})
+
+	batch := events.BlockBatch{}
+	for _, block := range chainBlocks[:indexed] {
+		decoded, _, err := dbevents.BlockToEvents(block, chain.GetReceiptsByHash(block.Hash()))
+		require.NoError(t, err)
+		batch.Blocks = append(batch.Blocks, *decoded)
+	}
+	iterator := func(yield func(arkivevents.BatchOrError) bool) {
+		yield(arkivevents.BatchOrError{Batch: batch})
+	}
+	require.NoError(t, store.FollowEvents(context.Background(), iterator))
+
+	return &arkivAPI{eth: &Ethereum{blockchain: chain}, store: store}
+}
+
+// newArkivTestChain generates the blocks running the steps, one block each, on the
+// genesis funding both keys.
+func newArkivTestChain(t *testing.T, keyA, keyB *ecdsa.PrivateKey, steps []arkivTestStep) (*core.Genesis, []*types.Block, []types.Receipts) {
+	t.Helper()
+
+	config := arkivConvergenceConfig(true)
+	a, b := crypto.PubkeyToAddress(keyA.PublicKey), crypto.PubkeyToAddress(keyB.PublicKey)
+	extra := eip1559.EncodeOptimismExtraData(config, 0, 250, 6, new(uint64))
+	funds := new(big.Int).Mul(big.NewInt(params.Ether), big.NewInt(1000))
+
+	gspec := &core.Genesis{
+		Config:    config,
+		ExtraData: extra,
+		GasLimit:  60_000_000,
+		BaseFee:   big.NewInt(params.InitialBaseFee),
+		Alloc:     types.GenesisAlloc{a: {Balance: funds}, b: {Balance: funds}},
+	}
+
+	var keys []common.Hash
+	signer := types.LatestSigner(config)
+	_, chainBlocks, receipts := core.GenerateChainWithGenesis(gspec, beacon.New(ethash.NewFaker()), len(steps), func(i int, gen *core.BlockGen) {
+		gen.SetExtra(extra)
+		gen.AddTx(l1InfoDeposit(config, gen.Number().Uint64(), gen.Timestamp()))
+
+		key, atx := steps[i](keys)
+		if atx == nil {
+			return
+		}
+		keys = append(keys, addArkivTx(t, gen, signer, key, atx)...)
+	})
+
+	return gspec, chainBlocks, receipts
+}
+
+// addArkivTx adds the Arkiv transaction signed with the key to the block and returns
+// the keys of the entities it creates.
+func addArkivTx(t *testing.T, gen *core.BlockGen, signer types.Signer, key *ecdsa.PrivateKey, atx *storagetx.ArkivTransaction) []common.Hash {
+	t.Helper()
+
+	data, err := rlp.EncodeToBytes(atx)
+	require.NoError(t, err)
+	tx, err := types.SignNewTx(key, signer, &types.DynamicFeeTx{
+		ChainID:   signer.ChainID(),
+		Nonce:     gen.TxNonce(crypto.PubkeyToAddress(key.PublicKey)),
+		To:        &arkivaddress.ArkivProcessorAddress,
+		Gas:       5_000_000,
+		GasFeeCap: new(big.Int).Mul(gen.BaseFee(), big.NewInt(2)),
+		GasTipCap: big.NewInt(1),
+		Data:      compression.MustBrotliCompress(data),
+	})
+	require.NoError(t, err)
+	gen.AddTx(tx)
+
+	var keys []common.Hash
+	for i, create := range atx.Create {
+		keys = append(keys, crypto.Keccak256Hash(tx.Hash().Bytes(), create.Payload, common.LeftPadBytes(big.NewInt(int64(i)).Bytes(), 32)))
+	}
+	return keys
+}
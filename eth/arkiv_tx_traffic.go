@@ -0,0 +1,39 @@
+package eth
+
+import (
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/protocols/eth"
+)
+
+// txTrafficInfo is the transaction gossip exchanged with a peer since it connected,
+// in bytes, split by whether the transactions are sent to the full broadcast
+// recipients.
+type txTrafficInfo struct {
+	HotIn    uint64 `json:"hotBytesIn"`
+	HotOut   uint64 `json:"hotBytesOut"`
+	OtherIn  uint64 `json:"otherBytesIn"`
+	OtherOut uint64 `json:"otherBytesOut"`
+}
+
+// hotTx reports whether the transaction is sent to one of the full broadcast
+// recipients, whatever its size.
+func (h *handler) hotTx(tx *types.Transaction) bool {
+	if tx.To() == nil {
+		return false
+	}
+	_, ok := h.fullBroadcastTo[*tx.To()]
+	return ok
+}
+
+// newTxTrafficInfo returns the accounting of the peer, nil if it has none.
+func newTxTrafficInfo(traffic *eth.TxTraffic) *txTrafficInfo {
+	if traffic == nil {
+		return nil
+	}
+	return &txTrafficInfo{
+		HotIn:    traffic.HotIn.Load(),
+		HotOut:   traffic.HotOut.Load(),
+		OtherIn:  traffic.OtherIn.Load(),
+		OtherOut: traffic.OtherOut.Load(),
+	}
+}
@@ -0,0 +1,247 @@
+package eth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	sqlitestore "github.com/Arkiv-Network/sqlite-bitmap-store"
+	arkivaddress "github.com/ethereum/go-ethereum/arkiv/address"
+	"github.com/ethereum/go-ethereum/arkiv/dbevents"
+	"github.com/ethereum/go-ethereum/arkiv/limits"
+	arkivlogs "github.com/ethereum/go-ethereum/arkiv/logs"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const (
+	// maxOwnerUsageReportBlocks is the largest range of blocks a usage report covers,
+	// a day of 2s blocks. Longer periods are reported in several ranges.
+	maxOwnerUsageReportBlocks = limits.MaxUsageReportBlocks
+
+	// arkivSlotsPerEntity is the number of state slots a live entity holds: its
+	// metadata, and its element and index in the set of entities expiring at its
+	// expiry block.
+	arkivSlotsPerEntity = 3
+)
+
+// GetOwnerUsageReport returns the usage of the Arkiv storage by the owner from
+// fromBlock to toBlock, for billing. The entities of the owner at the start of the
+// range are rebuilt from the store, which must have indexed the block before it, and
+// the logs since, and the operations of the range are read from the blocks and their
+// receipts, decoded like the events pipeline does.
+func (api *arkivAPI) GetOwnerUsageReport(ctx context.Context, owner common.Address, fromBlock hexutil.Uint64, toBlock hexutil.Uint64) (_ *OwnerUsageReport, err error) {
+	defer func() { err = arkivRPCError(err) }()
+
+	if err := api.methods.check("getOwnerUsageReport"); err != nil {
+		return nil, err
+	}
+
+	from, to := uint64(fromBlock), uint64(toBlock)
+	if from > to {
+		return nil, invalidRequest("fromBlock %d is after toBlock %d", from, to)
+	}
+	if to-from >= maxOwnerUsageReportBlocks {
+		return nil, invalidRequest("range of %d blocks exceeds the limit of %d blocks", to-from+1, maxOwnerUsageReportBlocks)
+	}
+	head := api.eth.blockchain.CurrentHeader().Number.Uint64()
+	if to > head {
+		return nil, invalidRequest("block is in the future: head is %d", head)
+	}
+
+	// The entities held at the start of the range, since the block they are held from
+	held := map[common.Hash]uint64{}
+	if from > 0 {
+		keys, err := api.ownerEntities(ctx, owner, from-1)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			held[key] = from
+		}
+	}
+
+	report := &OwnerUsageReport{
+		Owner:     owner,
+		FromBlock: fromBlock,
+		ToBlock:   toBlock,
+	}
+	release := func(key common.Hash, block uint64) {
+		if since, ok := held[key]; ok {
+			report.SlotBlocks += hexutil.Uint64((block - since) * arkivSlotsPerEntity)
+			delete(held, key)
+		}
+	}
+
+	signer := types.LatestSigner(api.eth.blockchain.Config())
+	for number := from; number <= to; number++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		block := api.eth.blockchain.GetBlockByNumber(number)
+		if block == nil {
+			return nil, fmt.Errorf("block %d not found", number)
+		}
+		receipts := api.eth.blockchain.GetReceiptsByHash(block.Hash())
+
+		for i, tx := range block.Transactions() {
+			if tx.IsDepositTx() || tx.To() == nil || *tx.To() != arkivaddress.ArkivProcessorAddress || i >= len(receipts) {
+				continue
+			}
+			if sender, err := signer.Sender(tx); err == nil && sender == owner {
+				report.GasUsed += hexutil.Uint64(receipts[i].GasUsed)
+			}
+		}
+
+		decoded, _, err := dbevents.BlockToEvents(block, receipts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode block %d: %w", number, err)
+		}
+		for _, op := range decoded.Operations {
+			switch {
+			case op.Create != nil:
+				if op.Create.Owner == owner {
+					report.Creates++
+					report.PayloadBytes += uint64(len(op.Create.Content))
+					held[op.Create.Key] = number
+				}
+			case op.Update != nil:
+				if op.Update.Owner == owner {
+					report.Updates++
+					report.PayloadBytes += uint64(len(op.Update.Content))
+					if _, ok := held[op.Update.Key]; !ok {
+						held[op.Update.Key] = number
+					}
+				}
+			case op.Delete != nil:
+				key := common.Hash(*op.Delete)
+				if _, ok := held[key]; ok {
+					report.Deletes++
+					release(key, number)
+				}
+			case op.Expire != nil:
+				release(common.Hash(*op.Expire), number)
+			case op.ChangeOwner != nil:
+				_, ok := held[op.ChangeOwner.Key]
+				switch {
+				case ok && op.ChangeOwner.Owner != owner:
+					release(op.ChangeOwner.Key, number)
+				case !ok && op.ChangeOwner.Owner == owner:
+					held[op.ChangeOwner.Key] = number
+				}
+			}
+		}
+	}
+
+	// The entities still held at the end of the range
+	for key := range held {
+		release(key, to+1)
+	}
+	return report, nil
+}
+
+// ownerEntities returns the keys of the entities of the owner at the block. The store
+// only holds the current entities, so the ownership of the entities changed since the
+// block is taken from the first Arkiv log of each of them in the receipts of the blocks
+// the store indexed after it, at most arkivMaxRewindBlocks.
+func (api *arkivAPI) ownerEntities(ctx context.Context, owner common.Address, atBlock uint64) ([]common.Hash, error) {
+	for {
+		lastBlock, err := api.store.GetLastBlock(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get last block from store: %w", err)
+		}
+		if atBlock > lastBlock {
+			return nil, notIndexed(atBlock, lastBlock, "store has not indexed block %d yet: last indexed block is %d", atBlock, lastBlock)
+		}
+		if lastBlock-atBlock > arkivMaxRewindBlocks {
+			return nil, notIndexed(atBlock, lastBlock, "block %d is more than %d blocks before the last indexed block %d", atBlock, arkivMaxRewindBlocks, lastBlock)
+		}
+
+		current, err := api.storeOwnerEntities(ctx, owner, lastBlock)
+		if err != nil {
+			return nil, err
+		}
+		// The store moved on while being queried, start over at its new block
+		if latest, err := api.store.GetLastBlock(ctx); err != nil {
+			return nil, fmt.Errorf("failed to get last block from store: %w", err)
+		} else if latest != lastBlock {
+			continue
+		}
+
+		// Whether the entities changed since the block were held by the owner at it
+		held := map[common.Hash]bool{}
+		for number := atBlock + 1; number <= lastBlock; number++ {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			block := api.eth.blockchain.GetBlockByNumber(number)
+			if block == nil {
+				return nil, fmt.Errorf("block %d not found", number)
+			}
+			for _, receipt := range api.eth.blockchain.GetReceiptsByHash(block.Hash()) {
+				for _, l := range receipt.Logs {
+					if l.Address != arkivaddress.ArkivProcessorAddress || len(l.Topics) < 3 {
+						continue
+					}
+					key := l.Topics[1]
+					if _, ok := held[key]; ok {
+						continue
+					}
+					switch l.Topics[0] {
+					case arkivlogs.ArkivEntityCreated:
+						// The entity didn't exist yet
+						held[key] = false
+					case arkivlogs.ArkivEntityOwnershipTransferLapsed:
+						// Its topic is the owner the entity wasn't given to
+					default:
+						// The other logs carry the owner before the operation
+						held[key] = common.BytesToAddress(l.Topics[2].Bytes()) == owner
+					}
+				}
+			}
+		}
+
+		var keys []common.Hash
+		for _, key := range current {
+			if _, ok := held[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		for _, key := range sortedKeys(held) {
+			if held[key] {
+				keys = append(keys, key)
+			}
+		}
+		return keys, nil
+	}
+}
+
+// storeOwnerEntities returns the keys of the entities of the owner in the store, which
+// has indexed the block.
+func (api *arkivAPI) storeOwnerEntities(ctx context.Context, owner common.Address, atBlock uint64) ([]common.Hash, error) {
+	options := &sqlitestore.Options{
+		AtBlock:     &atBlock,
+		IncludeData: &sqlitestore.IncludeData{Key: true},
+	}
+	var keys []common.Hash
+	for {
+		response, err := api.store.QueryEntities(ctx, fmt.Sprintf("$owner = %s", owner.Hex()), options)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query entities of %s: %w", owner.Hex(), err)
+		}
+		for _, d := range response.Data {
+			ed := sqlitestore.EntityData{}
+			if err := json.Unmarshal(d, &ed); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal entity data: %w", err)
+			}
+			if ed.Key != nil {
+				keys = append(keys, *ed.Key)
+			}
+		}
+		if response.Cursor == nil || *response.Cursor == "" {
+			return keys, nil
+		}
+		options.Cursor = *response.Cursor
+	}
+}
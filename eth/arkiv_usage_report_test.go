@@ -0,0 +1,113 @@
+package eth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/arkiv/storagetx"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetOwnerUsageReport(t *testing.T) {
+	keyA, _ := crypto.GenerateKey()
+	keyB, _ := crypto.GenerateKey()
+	a, b := crypto.PubkeyToAddress(keyA.PublicKey), crypto.PubkeyToAddress(keyB.PublicKey)
+
+	create := func(payload string, btl uint64) storagetx.ArkivCreate {
+		return storagetx.ArkivCreate{BTL: btl, ContentType: "text/plain", Payload: []byte(payload)}
+	}
+	steps := []arkivTestStep{
+		// Block 1: A creates e0, and e1 expiring at block 4
+		func([]common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			return keyA, &storagetx.ArkivTransaction{Create: []storagetx.ArkivCreate{create("0123456789", 100), create("01234", 3)}}
+		},
+		// Block 2: A updates e0
+		func(keys []common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			return keyA, &storagetx.ArkivTransaction{Update: []storagetx.ArkivUpdate{{
+				EntityKey:   keys[0],
+				ContentType: "text/plain",
+				BTL:         100,
+				Payload:     []byte("01234567890123456789"),
+			}}}
+		},
+		// Block 3: A creates e2
+		func([]common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			return keyA, &storagetx.ArkivTransaction{Create: []storagetx.ArkivCreate{create("0123", 100)}}
+		},
+		// Block 4: e1 expires, B creates e3
+		func([]common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			return keyB, &storagetx.ArkivTransaction{Create: []storagetx.ArkivCreate{create("0123456", 100)}}
+		},
+		// Block 5: A gives e0 to B
+		func(keys []common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			return keyA, &storagetx.ArkivTransaction{ChangeOwner: []storagetx.ArkivChangeOwner{{EntityKey: keys[0], NewOwner: b}}}
+		},
+		// Block 6: A deletes e2
+		func(keys []common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) {
+			return keyA, &storagetx.ArkivTransaction{Delete: []common.Hash{keys[2]}}
+		},
+		// Block 7: nothing
+		func([]common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) { return nil, nil },
+	}
+	// The store holds the entities at the head, e0 belongs to B there and e2 is gone
+	api, gasUsed := newArkivTestAPI(t, keyA, keyB, steps, len(steps))
+
+	report, err := api.GetOwnerUsageReport(context.Background(), a, 2, 7)
+	require.NoError(t, err)
+	// e1 is held at blocks 2-3, e0 at blocks 2-4 and e2 at blocks 3-5
+	require.Equal(t, &OwnerUsageReport{
+		Owner:        a,
+		FromBlock:    2,
+		ToBlock:      7,
+		Creates:      1,
+		Updates:      1,
+		Deletes:      1,
+		PayloadBytes: 20 + 4,
+		SlotBlocks:   (2 + 3 + 3) * arkivSlotsPerEntity,
+		GasUsed:      hexutil.Uint64(gasUsed[2] + gasUsed[3] + gasUsed[5] + gasUsed[6]),
+	}, report)
+
+	report, err = api.GetOwnerUsageReport(context.Background(), b, 2, 7)
+	require.NoError(t, err)
+	// e3 is held at blocks 4-7 and e0 at blocks 5-7
+	require.Equal(t, &OwnerUsageReport{
+		Owner:        b,
+		FromBlock:    2,
+		ToBlock:      7,
+		Creates:      1,
+		PayloadBytes: 7,
+		SlotBlocks:   (4 + 3) * arkivSlotsPerEntity,
+		GasUsed:      hexutil.Uint64(gasUsed[4]),
+	}, report)
+
+	// From genesis nothing is read from the store
+	report, err = api.GetOwnerUsageReport(context.Background(), a, 0, 1)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), report.Creates)
+	require.Equal(t, uint64(10+5), report.PayloadBytes)
+	require.Equal(t, hexutil.Uint64(2*arkivSlotsPerEntity), report.SlotBlocks)
+}
+
+func TestGetOwnerUsageReportRange(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	owner := crypto.PubkeyToAddress(key.PublicKey)
+	nothing := func([]common.Hash) (*ecdsa.PrivateKey, *storagetx.ArkivTransaction) { return nil, nil }
+	api, _ := newArkivTestAPI(t, key, key, []arkivTestStep{nothing, nothing, nothing}, 1)
+
+	_, err := api.GetOwnerUsageReport(context.Background(), owner, 2, 1)
+	require.ErrorContains(t, err, "fromBlock 2 is after toBlock 1")
+
+	_, err = api.GetOwnerUsageReport(context.Background(), owner, 1, maxOwnerUsageReportBlocks+1)
+	require.ErrorContains(t, err, "exceeds the limit")
+
+	_, err = api.GetOwnerUsageReport(context.Background(), owner, 1, 4)
+	require.ErrorContains(t, err, "block is in the future: head is 3")
+
+	// The store must have indexed the block before the range
+	_, err = api.GetOwnerUsageReport(context.Background(), owner, 3, 3)
+	require.ErrorContains(t, err, "store has not indexed block 2 yet")
+}
@@ -0,0 +1,327 @@
+package eth
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	// DefaultArkivWarmupPopular is the default number of the most popular queries
+	// persisted across restarts and run by the warmup.
+	DefaultArkivWarmupPopular = 20
+
+	// arkivPopularQueriesTracked bounds the number of distinct queries counted, the
+	// least popular one is forgotten to count a new one.
+	arkivPopularQueriesTracked = 1000
+
+	// arkivWarmupPollInterval is how often the startup warmup checks whether the
+	// store indexed the head.
+	arkivWarmupPollInterval = time.Second
+)
+
+// errWarmupRunning is returned when a warmup is requested while one is running.
+var errWarmupRunning = errors.New("a warmup is already running")
+
+// arkivWarmupQuery is a query listed in the warmup file.
+type arkivWarmupQuery struct {
+	Query   string        `json:"query"`
+	Options *QueryOptions `json:"options,omitempty"`
+}
+
+// loadArkivWarmupQueries reads the queries of the warmup file, a JSON array of
+// queries with their options.
+func loadArkivWarmupQueries(path string) ([]arkivWarmupQuery, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read warmup file: %w", err)
+	}
+	var queries []arkivWarmupQuery
+	if err := json.Unmarshal(data, &queries); err != nil {
+		return nil, fmt.Errorf("failed to decode warmup file %s: %w", path, err)
+	}
+	for i, query := range queries {
+		if query.Query == "" {
+			return nil, fmt.Errorf("warmup file %s: query %d is empty", path, i)
+		}
+	}
+	return queries, nil
+}
+
+// popularQuery is a query and the number of times it was run, as persisted.
+type popularQuery struct {
+	Query string `json:"query"`
+	Count uint64 `json:"count"`
+}
+
+// arkivPopularQueries counts the queries served by the node and persists the most
+// popular ones, so that the warmup of the next start runs them. The counts of the
+// persisted queries carry over, the others start over.
+type arkivPopularQueries struct {
+	path string
+	size int
+
+	mu     sync.Mutex
+	counts map[string]uint64
+}
+
+// newArkivPopularQueries returns the counter persisting the size most popular queries
+// to the file, loading the ones persisted by the previous run. It returns nil if size
+// is 0.
+func newArkivPopularQueries(path string, size int) (*arkivPopularQueries, error) {
+	if size <= 0 {
+		return nil, nil
+	}
+	p := &arkivPopularQueries{path: path, size: size, counts: map[string]uint64{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return p, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read popular queries: %w", err)
+	}
+	var persisted []popularQuery
+	if err := json.Unmarshal(data, &persisted); err != nil {
+		return nil, fmt.Errorf("failed to decode popular queries %s: %w", path, err)
+	}
+	for _, query := range persisted {
+		p.counts[query.Query] = query.Count
+	}
+	return p, nil
+}
+
+// record counts a run of the query. A nil counter doesn't count anything.
+func (p *arkivPopularQueries) record(query string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.counts[query]; !ok && len(p.counts) >= arkivPopularQueriesTracked {
+		// Forget the least popular query to count the new one
+		var least string
+		for q, count := range p.counts {
+			if least == "" || count < p.counts[least] || count == p.counts[least] && q > least {
+				least = q
+			}
+		}
+		delete(p.counts, least)
+	}
+	p.counts[query]++
+}
+
+// top returns the most popular queries with their counts, the most popular first.
+func (p *arkivPopularQueries) top() []popularQuery {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	queries := make([]popularQuery, 0, len(p.counts))
+	for query, count := range p.counts {
+		queries = append(queries, popularQuery{Query: query, Count: count})
+	}
+	p.mu.Unlock()
+
+	slices.SortFunc(queries, func(a, b popularQuery) int {
+		if c := cmp.Compare(b.Count, a.Count); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Query, b.Query)
+	})
+	return queries[:min(len(queries), p.size)]
+}
+
+// save writes the most popular queries to the file, replacing the previous content
+// atomically.
+func (p *arkivPopularQueries) save() error {
+	if p == nil {
+		return nil
+	}
+	data, err := json.Marshal(p.top())
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(p.path), filepath.Base(p.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write popular queries: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write popular queries: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write popular queries: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), p.path); err != nil {
+		return fmt.Errorf("failed to write popular queries: %w", err)
+	}
+	return nil
+}
+
+// arkivWarmupKey marks the context of the queries run by a warmup, which aren't
+// counted as popular.
+type arkivWarmupKey struct{}
+
+// arkivWarmup runs the queries of the warmup file and the most popular queries
+// against the head, discarding their results, so that the page caches of the store
+// are warm before the clients query it. The queries go through Query like the ones of
+// the clients, so they hold the memory budget of the queries while they run.
+type arkivWarmup struct {
+	api     *arkivAPI
+	head    func() uint64 // returns the number of the current head
+	queries []arkivWarmupQuery
+	popular *arkivPopularQueries
+	gate    bool
+
+	mu      sync.Mutex
+	running bool
+	done    bool // whether a warmup completed
+	last    *WarmupReport
+}
+
+// run runs a warmup against the current head, one query at a time. The failures of
+// the queries are reported, they don't stop the warmup.
+func (w *arkivWarmup) run(ctx context.Context) (*WarmupReport, error) {
+	w.mu.Lock()
+	if w.running {
+		w.mu.Unlock()
+		return nil, errWarmupRunning
+	}
+	w.running = true
+	block := w.head()
+	report := &WarmupReport{Block: hexutil.Uint64(block), Queries: []WarmupQuery{}}
+	w.last = report
+	w.mu.Unlock()
+
+	queries := slices.Clone(w.queries)
+	sources := make([]string, len(queries))
+	for i := range sources {
+		sources[i] = WarmupSourceConfig
+	}
+	for _, popular := range w.popular.top() {
+		if !slices.ContainsFunc(queries, func(q arkivWarmupQuery) bool { return q.Query == popular.Query && q.Options == nil }) {
+			queries = append(queries, arkivWarmupQuery{Query: popular.Query})
+			sources = append(sources, WarmupSourcePopular)
+		}
+	}
+
+	ctx = context.WithValue(ctx, arkivWarmupKey{}, true)
+	results := make([]WarmupQuery, 0, len(queries))
+	for i, query := range queries {
+		if err := ctx.Err(); err != nil {
+			w.finish(report, results, false)
+			return nil, err
+		}
+		options := &QueryOptions{}
+		if query.Options != nil {
+			copied := *query.Options
+			options = &copied
+		}
+		options.AtBlock = &block
+
+		start := time.Now()
+		_, err := w.api.Query(ctx, query.Query, options)
+		result := WarmupQuery{Query: query.Query, Source: sources[i], DurationMs: uint64(time.Since(start).Milliseconds())}
+		if err != nil {
+			result.Error = err.Error()
+			log.Warn("Arkiv warmup query failed", "query", query.Query, "err", err)
+		} else {
+			log.Info("Warmed up Arkiv query", "query", query.Query, "elapsed", time.Since(start))
+		}
+		results = append(results, result)
+	}
+	return w.finish(report, results, true), nil
+}
+
+// finish records the results of the warmup.
+func (w *arkivWarmup) finish(report *WarmupReport, results []WarmupQuery, done bool) *WarmupReport {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	report.Queries = results
+	report.Done = done
+	w.running = false
+	w.done = w.done || done
+	return report
+}
+
+// startup waits for the store to index the head, then runs a warmup. It gives up
+// once ctx is canceled.
+func (w *arkivWarmup) startup(ctx context.Context) {
+	head := w.head()
+	for {
+		lastBlock, err := w.api.store.GetLastBlock(ctx)
+		if err == nil && lastBlock >= head {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(arkivWarmupPollInterval):
+		}
+	}
+	start := time.Now()
+	report, err := w.run(ctx)
+	if err != nil {
+		log.Warn("Arkiv warmup interrupted", "err", err)
+		return
+	}
+	log.Info("Arkiv warmup done", "block", uint64(report.Block), "queries", len(report.Queries), "elapsed", time.Since(start))
+}
+
+// ready reports whether the node serves queries: always unless the warmup gates it,
+// then once a warmup completed.
+func (w *arkivWarmup) ready() bool {
+	if w == nil || !w.gate {
+		return true
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.done
+}
+
+// report returns the last warmup, nil if none ran.
+func (w *arkivWarmup) report() *WarmupReport {
+	if w == nil {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.last == nil {
+		return nil
+	}
+	report := *w.last
+	report.Queries = slices.Clone(report.Queries)
+	return &report
+}
+
+// Warmup runs the queries of the warmup file and the most popular queries against the
+// current head, discarding their results, and returns how long each one took.
+func (api *arkivAdminAPI) Warmup(ctx context.Context) (_ *WarmupReport, err error) {
+	defer func() { err = arkivRPCError(err) }()
+
+	if api.warmup == nil {
+		return nil, invalidRequest("no warmup queries: set --arkiv.warmup.file or --arkiv.warmup.popular")
+	}
+	report, err := api.warmup.run(ctx)
+	if errors.Is(err, errWarmupRunning) {
+		return nil, &validationError{err}
+	}
+	return report, err
+}
@@ -0,0 +1,141 @@
+package eth
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPopularQueriesPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "arkiv-popular-queries.json")
+
+	popular, err := newArkivPopularQueries(path, 2)
+	require.NoError(t, err)
+	for _, query := range []string{`a = "1"`, `b = "2"`, `b = "2"`, `c = "3"`, `c = "3"`, `c = "3"`} {
+		popular.record(query)
+	}
+	require.Equal(t, []popularQuery{{Query: `c = "3"`, Count: 3}, {Query: `b = "2"`, Count: 2}}, popular.top())
+	require.NoError(t, popular.save())
+
+	// The most popular queries carry over to the next run with their counts
+	reloaded, err := newArkivPopularQueries(path, 2)
+	require.NoError(t, err)
+	require.Equal(t, popular.top(), reloaded.top())
+	reloaded.record(`b = "2"`)
+	reloaded.record(`b = "2"`)
+	require.Equal(t, []popularQuery{{Query: `b = "2"`, Count: 4}, {Query: `c = "3"`, Count: 3}}, reloaded.top())
+
+	// Without popular queries nothing is counted nor persisted
+	disabled, err := newArkivPopularQueries(filepath.Join(t.TempDir(), "none.json"), 0)
+	require.NoError(t, err)
+	require.Nil(t, disabled)
+	disabled.record(`a = "1"`)
+	require.NoError(t, disabled.save())
+
+	// A corrupted file fails the start
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0o600))
+	_, err = newArkivPopularQueries(path, 2)
+	require.ErrorContains(t, err, "failed to decode popular queries")
+}
+
+func TestPopularQueriesTracked(t *testing.T) {
+	popular, err := newArkivPopularQueries(filepath.Join(t.TempDir(), "popular.json"), 1)
+	require.NoError(t, err)
+	popular.record("popular")
+	popular.record("popular")
+	for i := range arkivPopularQueriesTracked {
+		popular.record(fmt.Sprintf("q%d", i))
+	}
+
+	// The least popular queries are forgotten to count the new ones
+	require.Len(t, popular.counts, arkivPopularQueriesTracked)
+	require.Equal(t, []popularQuery{{Query: "popular", Count: 2}}, popular.top())
+}
+
+func TestLoadWarmupQueries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "warmup.json")
+	require.NoError(t, os.WriteFile(path, []byte(`[{"query": "type = \"note\""}, {"query": "$all", "options": {"resultsPerPage": 10}}]`), 0o600))
+
+	queries, err := loadArkivWarmupQueries(path)
+	require.NoError(t, err)
+	require.Len(t, queries, 2)
+	require.Equal(t, `type = "note"`, queries[0].Query)
+	require.Nil(t, queries[0].Options)
+	require.Equal(t, uint64(10), *queries[1].Options.ResultsPerPage)
+
+	require.NoError(t, os.WriteFile(path, []byte(`[{"options": {}}]`), 0o600))
+	_, err = loadArkivWarmupQueries(path)
+	require.ErrorContains(t, err, "query 0 is empty")
+}
+
+func TestWarmup(t *testing.T) {
+	api := &arkivAPI{store: seedKeyPrefixStore(t, "type", "kind")}
+	popular, err := newArkivPopularQueries(filepath.Join(t.TempDir(), "popular.json"), 5)
+	require.NoError(t, err)
+	api.popular = popular
+	api.warmup = &arkivWarmup{
+		api:     api,
+		head:    func() uint64 { return 1 },
+		queries: []arkivWarmupQuery{{Query: `type = "value"`}},
+		popular: popular,
+		gate:    true,
+	}
+	require.False(t, api.SyncStatus().Ready)
+	require.Nil(t, api.SyncStatus().Warmup)
+
+	// The queries of the clients are counted, the ones of the warmup aren't
+	atBlock := uint64(1)
+	_, err = api.Query(context.Background(), `kind = "value"`, &QueryOptions{AtBlock: &atBlock})
+	require.NoError(t, err)
+
+	report, err := (&arkivAdminAPI{warmup: api.warmup}).Warmup(context.Background())
+	require.NoError(t, err)
+	require.True(t, report.Done)
+	require.Equal(t, uint64(1), uint64(report.Block))
+	require.Len(t, report.Queries, 2)
+	require.Equal(t, WarmupQuery{Query: `type = "value"`, Source: WarmupSourceConfig, DurationMs: report.Queries[0].DurationMs}, report.Queries[0])
+	require.Equal(t, WarmupQuery{Query: `kind = "value"`, Source: WarmupSourcePopular, DurationMs: report.Queries[1].DurationMs}, report.Queries[1])
+	require.Equal(t, []popularQuery{{Query: `kind = "value"`, Count: 1}}, popular.top())
+
+	status := api.SyncStatus()
+	require.True(t, status.Ready)
+	require.Equal(t, report, status.Warmup)
+}
+
+func TestWarmupMemoryBudget(t *testing.T) {
+	api := &arkivAPI{
+		store:  seedKeyPrefixStore(t, "type"),
+		memory: newArkivQueryMemoryBudget(arkivQueryEntityBytes+arkivQueryRowBytes, 0),
+	}
+	api.warmup = &arkivWarmup{
+		api:     api,
+		head:    func() uint64 { return 1 },
+		queries: []arkivWarmupQuery{{Query: `type = "value"`}},
+		gate:    true,
+	}
+
+	// The warmup queries hold the memory budget like the others, they fail while the
+	// budget is in use
+	release, err := api.memory.reserve(context.Background(), 1)
+	require.NoError(t, err)
+	report, err := api.warmup.run(context.Background())
+	require.NoError(t, err)
+	require.Len(t, report.Queries, 1)
+	require.Contains(t, report.Queries[0].Error, errQueryMemoryBudget.Error())
+	require.True(t, report.Done)
+	require.True(t, api.warmup.ready())
+
+	release()
+	report, err = api.warmup.run(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, report.Queries[0].Error)
+	require.Zero(t, api.memory.inUse())
+
+	// Without warmup queries there is nothing to run
+	_, err = (&arkivAdminAPI{}).Warmup(context.Background())
+	require.ErrorContains(t, err, "no warmup queries")
+}
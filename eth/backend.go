@@ -21,7 +21,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log/slog"
 	"math"
 	"math/big"
 	"runtime"
@@ -32,10 +31,16 @@ import (
 	"github.com/holiman/uint256"
 	"golang.org/x/time/rate"
 
+	"github.com/Arkiv-Network/arkiv-events/events"
 	sqlitestore "github.com/Arkiv-Network/sqlite-bitmap-store"
 
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/arkiv/dbevents"
+	"github.com/ethereum/go-ethereum/arkiv/fulltext"
+	"github.com/ethereum/go-ethereum/arkiv/shadow"
+	"github.com/ethereum/go-ethereum/arkiv/shards"
+	"github.com/ethereum/go-ethereum/arkiv/storageaccounting"
+	"github.com/ethereum/go-ethereum/arkiv/webhook"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/consensus"
@@ -49,6 +54,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/txpool/locals"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/eth/ethconfig"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
@@ -142,6 +148,19 @@ type Ethereum struct {
 	interopRPC           *interop.InteropClient
 	supervisorFailsafe   atomic.Bool
 
+	arkivMetrics       *arkivMetricsCollector
+	arkivFullText      *fulltext.Index
+	arkivWebhooks      *webhook.Dispatcher
+	arkivHooks         *dbevents.Hooks
+	arkivSelfCheck     *arkivSelfChecker
+	arkivShadow        *shadow.Recorder
+	arkivPipeline      *arkivPipeline
+	arkivStore         *sqlitestore.SQLiteStore
+	arkivShards        *shards.Router
+	arkivReceipts      *arkivReceiptPruner
+	arkivIngestLatency *arkivIngestLatency
+	arkivWarmup        *arkivWarmup
+
 	nodeCloser func() error
 }
 
@@ -241,6 +260,11 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 			rawdb.WriteDatabaseVersion(chainDb, core.BlockChainVersion)
 		}
 	}
+	var arkivShadow *shadow.Recorder
+	if stack.Config().ArkivShadowEnforcement {
+		arkivShadow = shadow.NewRecorder(shadow.DefaultRecentViolations)
+	}
+	eth.arkivShadow = arkivShadow
 	var (
 		options = &core.BlockChainConfig{
 			TrieCleanLimit:   config.TrieCleanCache,
@@ -258,6 +282,7 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 				EnablePreimageRecording: config.EnablePreimageRecording,
 				EnableWitnessStats:      config.EnableWitnessStats,
 				StatelessSelfValidation: config.StatelessSelfValidation,
+				ArkivShadow:             arkivShadow,
 			},
 			// Enables file journaling for the trie database. The journal files will be stored
 			// within the data directory. The corresponding paths will be either:
@@ -321,40 +346,142 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	options.Overrides = &overrides
 
 	// eth.blockchain, err = core.NewBlockChain(chainDb, config.Genesis, eth.engine, options)
-	log.Info("Creating SQLStore", "path", stack.Config().GolemBaseSQLStateFile)
+	store, router, err := openArkivStore(stack.Config())
+	if err != nil {
+		return nil, err
+	}
 	sqlStateFile := stack.Config().GolemBaseSQLStateFile
-
 	if sqlStateFile == "" {
 		sqlStateFile = ":memory:"
 	}
 
-	store, err := sqlitestore.NewSQLiteStore(
-		slog.New(log.Root().Handler()),
-		sqlStateFile,
-		7,
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create sql store: %w", err)
-	}
-
-	lastBlock, err := store.GetLastBlock(context.Background())
+	// The shards are fed from the last block ingested by all of them
+	lastBlock, err := router.GetLastBlock(context.Background())
 	if err != nil {
 		return nil, fmt.Errorf("failed to get last block from store: %w", err)
 	}
+	compressedPayloads := stack.Config().ArkivStoreCompress
+	if err := checkArkivPayloadStorage(chainDb, uint64(lastBlock), compressedPayloads); err != nil {
+		return nil, err
+	}
 
-	batchIterator, onNewHead := dbevents.NewChainBatchIterator(chainDb, uint64(lastBlock))
+	eth.arkivStore, eth.arkivShards = store, router
+
+	eth.arkivHooks = dbevents.NewHooks(chainDb, stack.Config().ArkivHookBudget)
+	eth.arkivHooks.SetPerKindOpIndexes(stack.Config().ArkivPerKindOpIndex)
+	eth.arkivIngestLatency = newArkivIngestLatency(arkivIngestLatencyCapacity, arkivIngestLatencyTTL)
+	eth.arkivHooks.RegisterBlockHook("ingestLatency", eth.arkivIngestLatency.included)
+	eth.arkivHooks.RegisterReorgHook("ingestLatency", eth.arkivIngestLatency.removed)
+	eth.arkivPipeline = newArkivPipeline(arkivDrainTimeout)
+	chainIterator, arkivSyncStatus := dbevents.NewChainBatchIterator(
+		chainDb,
+		eth.arkivHooks,
+		uint64(lastBlock),
+		stack.Config().ArkivSkipPruned,
+		dbevents.WithBatchSize(stack.Config().ArkivEventsBatchSize),
+		dbevents.WithConcurrency(stack.Config().ArkivEventsConcurrency),
+		dbevents.WithContext(eth.arkivPipeline.stopping),
+		dbevents.WithCheckpoint(),
+		// The chain is created below, before the iterator reads a block
+		dbevents.WithUsedSlots(func(header *types.Header) (uint64, error) {
+			statedb, err := eth.blockchain.StateAt(header.Root)
+			if err != nil {
+				return 0, err
+			}
+			return storageaccounting.GetNumberOfUsedSlots(statedb).Uint64(), nil
+		}),
+	)
+	batchIterator := chainIterator.Events()
+	if lastBlock == 0 {
+		// A new store starts with the entities seeded by the genesis
+		batchIterator = dbevents.WithGenesis(batchIterator, func() (*events.Block, error) {
+			return dbevents.GenesisBlock(chainDb)
+		})
+	}
+	batchIterator = dbevents.VerifyContinuity(batchIterator, func() (uint64, error) {
+		return router.GetLastBlock(context.Background())
+	}, arkivSyncStatus)
+	if batchIterator, err = resolveArkivContent(eth.arkivPipeline.stopping, stack.Config(), batchIterator); err != nil {
+		return nil, err
+	}
 
-	go func() {
-		err := store.FollowEvents(context.Background(), batchIterator)
+	var arkivFullText *fulltext.Index
+	if stack.Config().ArkivFullText {
+		fullTextFile := ":memory:"
+		if sqlStateFile != ":memory:" {
+			fullTextFile = sqlStateFile + "-fulltext"
+		}
+		arkivFullText, err = openArkivFullText(context.Background(), router, compressedPayloads, fullTextFile, fulltext.Config{
+			ContentTypes:   stack.Config().ArkivFullTextContentTypes,
+			MaxPayloadSize: stack.Config().ArkivFullTextMaxPayloadSize,
+		})
 		if err != nil {
-			log.Error("failed to follow events", "error", err)
+			return nil, fmt.Errorf("failed to open full-text index: %w", err)
 		}
-	}()
+		eth.arkivFullText = arkivFullText
+		batchIterator = arkivFullText.Wrap(batchIterator)
+	}
+	if stack.Config().ArkivPerKindOpIndex {
+		batchIterator = dbevents.PerKindOpIndexes(batchIterator)
+	}
+	if compressedPayloads {
+		// The full-text index reads the payloads before they're compressed
+		batchIterator = dbevents.CompressPayloads(batchIterator)
+	}
+	ticker := dbevents.NewTicker()
+	batchIterator = ticker.Wrap(batchIterator, func() (uint64, error) {
+		return router.GetLastBlock(context.Background())
+	})
+
+	// A batch the store fails to ingest is retried, then written to the dead-letter queue
+	ingester, err := dbevents.NewIngester(dbevents.IngesterConfig{
+		Dir:        stack.ResolvePath("arkiv-deadletter"),
+		SkipPoison: stack.Config().ArkivSkipPoison,
+	}, func(ctx context.Context, batch events.BlockBatch) error {
+		if err := router.FollowEvents(ctx, dbevents.SingleBatch(batch)); err != nil {
+			return err
+		}
+		eth.arkivIngestLatency.indexed(batch.Blocks[len(batch.Blocks)-1].Number)
+		return nil
+	}, func() (uint64, error) {
+		return router.GetLastBlock(context.Background())
+	}, arkivSyncStatus)
+	if err != nil {
+		return nil, err
+	}
+	eth.arkivPipeline.follow(ingester, batchIterator)
+	eth.arkivPipeline.watch(dbevents.NewWatchdog(arkivSyncStatus, stack.Config().ArkivEventsStuckHeads, dbevents.DefaultWatchdogInterval))
 
-	eth.blockchain, err = core.NewBlockChainWithOnNewBlock(chainDb, config.Genesis, eth.engine, options, onNewHead)
+	eth.blockchain, err = core.NewBlockChainWithHooks(chainDb, config.Genesis, eth.engine, options, eth.arkivHooks)
 	if err != nil {
 		return nil, err
 	}
+	eth.arkivMetrics = newArkivMetricsCollector(router, sqlStateFile, eth.blockchain, arkivFullText)
+
+	eth.arkivSelfCheck = &arkivSelfChecker{store: router, storePath: sqlStateFile, chain: eth.blockchain, ticker: ticker}
+	selfCheck := eth.arkivSelfCheck.run(context.Background())
+	logArkivSelfCheck(selfCheck)
+	if !selfCheck.OK && !stack.Config().ArkivSelfCheckWarnOnly {
+		return nil, arkivSelfCheckError(selfCheck)
+	}
+
+	eth.arkivReceipts = newArkivReceiptPruner(chainDb, stack.Config().ArkivReceiptRetention)
+
+	if nodeConfig := stack.Config(); len(nodeConfig.ArkivWebhookURLs) > 0 {
+		eth.arkivWebhooks, err = webhook.New(webhook.Config{
+			URLs:          nodeConfig.ArkivWebhookURLs,
+			Secret:        nodeConfig.ArkivWebhookSecret,
+			Owners:        nodeConfig.ArkivWebhookOwners,
+			Keys:          nodeConfig.ArkivWebhookKeys,
+			Kinds:         nodeConfig.ArkivWebhookKinds,
+			Confirmations: nodeConfig.ArkivWebhookConfirmations,
+			QueuePath:     stack.ResolvePath("arkiv-webhooks.json"),
+			MaxQueue:      nodeConfig.ArkivWebhookMaxQueue,
+		}, eth.blockchain)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Arkiv webhooks: %w", err)
+		}
+	}
 
 	if chainConfig := eth.blockchain.Config(); chainConfig.Optimism != nil { // config.Genesis.Config.ChainID cannot be used because it's based on CLI flags only, thus default to mainnet L1
 		config.NetworkId = chainConfig.ChainID.Uint64() // optimism defaults eth network ID to chain ID
@@ -447,6 +574,11 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 		NoTxGossip:               config.RollupDisableTxPoolGossip,
 		TxGossipNetRestrict:      txGossipNetRestrict,
 		TxGossipTrustedPeersOnly: config.RollupTxPoolTrustedPeersOnly,
+
+		// Arkiv additions
+		FullBroadcastTo:      stack.Config().ArkivTxBroadcastTo,
+		FullBroadcastMaxSize: stack.Config().ArkivTxBroadcastMaxSize,
+		ReadOnly:             stack.Config().ArkivReadOnly,
 	}); err != nil {
 		return nil, err
 	}
@@ -456,16 +588,30 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	eth.miner = miner.New(eth, config.Miner, eth.engine)
 	eth.miner.SetExtra(makeExtraData(config.Miner.ExtraData))
 	eth.miner.SetPrioAddresses(config.TxPool.Locals)
+	if stack.Config().ArkivReadOnly {
+		log.Info("Running as a read-only Arkiv replica", "upstream", stack.Config().ArkivReadOnlyUpstream)
+		eth.miner.Disable()
+	}
 
-	eth.APIBackend = &EthAPIBackend{stack.Config().ExtRPCEnabled(), stack.Config().AllowUnprotectedTxs, config.RollupDisableTxPoolAdmission, eth, nil}
+	daBackpressure := newArkivDABackpressure(
+		stack.Config().ArkivDABackpressureBlocks,
+		stack.Config().ArkivDABackpressureMinSize,
+		stack.Config().ArkivDABackpressureBlockBudget,
+		config.TxPool.Locals,
+	)
+	eth.APIBackend = &EthAPIBackend{stack.Config().ExtRPCEnabled(), stack.Config().AllowUnprotectedTxs, config.RollupDisableTxPoolAdmission, stack.Config().ArkivReadOnly, daBackpressure, eth, nil}
 	if eth.APIBackend.allowUnprotectedTxs {
 		log.Info("Unprotected transactions allowed")
 	}
 	eth.APIBackend.gpo = gasprice.NewOracle(eth.APIBackend, config.GPO, config.Miner.GasPrice)
 
-	if config.RollupSequencerHTTP != "" {
+	sequencerHTTP := config.RollupSequencerHTTP
+	if stack.Config().ArkivReadOnlyUpstream != "" {
+		sequencerHTTP = stack.Config().ArkivReadOnlyUpstream
+	}
+	if sequencerHTTP != "" {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		client, err := rpc.DialContext(ctx, config.RollupSequencerHTTP)
+		client, err := rpc.DialContext(ctx, sequencerHTTP)
 		cancel()
 		if err != nil {
 			return nil, err
@@ -490,20 +636,70 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	// Start the RPC service
 	eth.netRPCService = ethapi.NewNetAPI(eth.p2pServer, networkID)
 
-	arkivAPI, err := NewArkivAPI(eth, store)
+	arkivAPI, err := NewArkivAPI(eth, router, arkivSyncStatus, arkivFullText, ArkivAPIConfig{
+		QueryMaxScanFraction: stack.Config().ArkivQueryMaxScanFraction,
+		QueryMemoryBudget:    stack.Config().ArkivQueryMemoryBudget,
+		QueryMemoryWait:      stack.Config().ArkivQueryMemoryWait,
+		QueryTimeout:         stack.Config().ArkivQueryTimeout,
+		QueryMaxRows:         stack.Config().ArkivQueryMaxRows,
+		QueryMaxBytes:        stack.Config().ArkivQueryMaxBytes,
+		LegacyJSON:           stack.Config().ArkivLegacyJSON,
+		ReadOnly:             stack.Config().ArkivReadOnly,
+		CompressedPayloads:   compressedPayloads,
+		EnabledMethods:       stack.Config().ArkivRPCEnable,
+		DisabledMethods:      stack.Config().ArkivRPCDisable,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("error creating Arkiv API: %w", err)
 	}
+	arkivAPI.popular, err = newArkivPopularQueries(stack.ResolvePath("arkiv-popular-queries.json"), stack.Config().ArkivWarmupPopular)
+	if err != nil {
+		return nil, err
+	}
+	var warmupQueries []arkivWarmupQuery
+	if file := stack.Config().ArkivWarmupFile; file != "" {
+		if warmupQueries, err = loadArkivWarmupQueries(file); err != nil {
+			return nil, err
+		}
+	}
+	if len(warmupQueries) > 0 || arkivAPI.popular != nil {
+		arkivAPI.warmup = &arkivWarmup{
+			api:     arkivAPI,
+			head:    func() uint64 { return eth.blockchain.CurrentHeader().Number.Uint64() },
+			queries: warmupQueries,
+			popular: arkivAPI.popular,
+			gate:    stack.Config().ArkivWarmupGate,
+		}
+	}
+	eth.arkivWarmup = arkivAPI.warmup
+	if file := stack.Config().ArkivRelayerKeyFile; file != "" {
+		key, err := crypto.LoadECDSA(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the Arkiv relayer key: %w", err)
+		}
+		arkivAPI.relayer = newArkivRelayer(key)
+		log.Info("Relaying Arkiv operations signed as typed data", "relayer", arkivAPI.relayer.address)
+	}
 	// Register the backend on the node
 	stack.RegisterAPIs([]rpc.API{
 		{
 			Namespace: "arkiv",
 			Service:   arkivAPI,
 		},
+		{
+			Namespace:     "arkiv",
+			Service:       &arkivAdminAPI{store: router, ingester: ingester, warmup: arkivAPI.warmup},
+			Authenticated: true,
+		},
+	})
+	stack.SetArkivGatewayHead(func() common.Hash {
+		return eth.blockchain.CurrentHeader().Hash()
 	})
 	stack.RegisterAPIs(eth.APIs())
 	stack.RegisterProtocols(eth.Protocols())
 	stack.RegisterLifecycle(eth)
+	// The Arkiv pipeline stops first, before the chain database is closed
+	stack.RegisterLifecycle(eth.arkivPipeline)
 
 	// Successful startup; push a marker and check previous unclean shutdowns.
 	eth.shutdownTracker.MarkStartup()
@@ -579,6 +775,19 @@ func (s *Ethereum) Synced() bool                       { return s.handler.synced
 func (s *Ethereum) SetSynced()                         { s.handler.enableSyncedFeatures() }
 func (s *Ethereum) ArchiveMode() bool                  { return s.config.NoPruning }
 
+// RegisterArkivBlockHook registers a hook called with every block becoming canonical
+// and its Arkiv operations. The import of the block waits for the hooks for the budget
+// at most, and a hook failing, panicking or exceeding the budget doesn't fail it.
+func (s *Ethereum) RegisterArkivBlockHook(name string, hook dbevents.BlockHook) {
+	s.arkivHooks.RegisterBlockHook(name, hook)
+}
+
+// RegisterArkivReorgHook registers a hook called with every block a reorg removes from
+// the canonical chain, newest first, under the same budget as the block hooks.
+func (s *Ethereum) RegisterArkivReorgHook(name string, hook dbevents.BlockHook) {
+	s.arkivHooks.RegisterReorgHook(name, hook)
+}
+
 // Protocols returns all the currently configured
 // network protocols to start.
 func (s *Ethereum) Protocols() []p2p.Protocol {
@@ -608,6 +817,17 @@ func (s *Ethereum) Start() error {
 	// start log indexer
 	s.filterMaps.Start()
 	go s.updateFilterMapsHeads()
+
+	s.arkivMetrics.start()
+	if s.arkivReceipts != nil {
+		s.arkivReceipts.start()
+	}
+	if s.arkivWebhooks != nil {
+		s.arkivWebhooks.Start()
+	}
+	if s.arkivWarmup != nil {
+		go s.arkivWarmup.startup(s.arkivPipeline.stopping)
+	}
 	return nil
 }
 
@@ -735,8 +955,30 @@ func (s *Ethereum) Stop() error {
 	s.closeFilterMaps <- ch
 	<-ch
 	s.filterMaps.Stop()
+	s.arkivMetrics.stop()
+	if s.arkivReceipts != nil {
+		s.arkivReceipts.stop()
+	}
+	if s.arkivWebhooks != nil {
+		s.arkivWebhooks.Stop()
+	}
+	if s.arkivWarmup != nil {
+		if err := s.arkivWarmup.popular.save(); err != nil {
+			log.Error("Failed to save the popular Arkiv queries", "err", err)
+		}
+	}
 	s.txPool.Close()
 	s.blockchain.Stop()
+	if s.arkivFullText != nil {
+		s.arkivFullText.Close()
+	}
+	// The pipeline is stopped, nothing writes to the store anymore
+	if err := s.arkivShards.Close(); err != nil {
+		log.Error("Failed to close the Arkiv store shards", "err", err)
+	}
+	if err := s.arkivStore.Close(); err != nil {
+		log.Error("Failed to close the Arkiv store", "err", err)
+	}
 	s.engine.Close()
 	if s.seqRPCService != nil {
 		s.seqRPCService.Close()
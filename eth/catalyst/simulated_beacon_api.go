@@ -18,15 +18,23 @@ package catalyst
 
 import (
 	"context"
+	"errors"
+	"math/big"
+	"sync/atomic"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
 )
 
 // simulatedBeaconAPI provides a RPC API for SimulatedBeacon.
 type simulatedBeaconAPI struct {
 	sim *SimulatedBeacon
+
+	// faucetIndex is the index of the next withdrawal funding an account
+	faucetIndex atomic.Uint64
 }
 
 // newSimulatedBeaconAPI returns an instance of simulatedBeaconAPI with a
@@ -108,3 +116,23 @@ func (a *simulatedBeaconAPI) AddWithdrawal(ctx context.Context, withdrawal *type
 func (a *simulatedBeaconAPI) SetFeeRecipient(ctx context.Context, feeRecipient common.Address) {
 	a.sim.setFeeRecipient(feeRecipient)
 }
+
+// FundAccount credits the account with the amount of wei, which must be a whole
+// number of gwei. The funds are paid by a withdrawal included in the next block.
+func (a *simulatedBeaconAPI) FundAccount(ctx context.Context, account common.Address, amount *hexutil.Big) error {
+	if amount == nil || amount.ToInt().Sign() <= 0 {
+		return errors.New("amount must be positive")
+	}
+	gwei, rem := new(big.Int).QuoRem(amount.ToInt(), big.NewInt(params.GWei), new(big.Int))
+	if rem.Sign() != 0 {
+		return errors.New("amount must be a whole number of gwei")
+	}
+	if !gwei.IsUint64() {
+		return errors.New("amount too large")
+	}
+	return a.sim.withdrawals.add(&types.Withdrawal{
+		Index:   a.faucetIndex.Add(1) - 1,
+		Address: account,
+		Amount:  gwei.Uint64(),
+	})
+}
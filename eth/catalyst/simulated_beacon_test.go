@@ -24,6 +24,7 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -209,3 +210,37 @@ func TestOnDemandSpam(t *testing.T) {
 		}
 	}
 }
+
+func TestFundAccount(t *testing.T) {
+	var (
+		testAddr        = common.Address{0x01}
+		account         = common.Address{0xfa}
+		genesis         = core.DeveloperGenesisBlock(10_000_000, &testAddr)
+		node, eth, mock = startSimulatedBeaconEthService(t, genesis, 0)
+		api             = &simulatedBeaconAPI{sim: mock}
+	)
+	defer node.Close()
+
+	for _, amount := range []*big.Int{big.NewInt(0), big.NewInt(params.GWei + 1), new(big.Int).Lsh(big.NewInt(1), 128)} {
+		if err := api.FundAccount(context.Background(), account, (*hexutil.Big)(amount)); err == nil {
+			t.Fatalf("funding %v wei succeeded", amount)
+		}
+	}
+
+	amount := new(big.Int).Mul(big.NewInt(3), big.NewInt(params.Ether))
+	for range 2 {
+		if err := api.FundAccount(context.Background(), account, (*hexutil.Big)(amount)); err != nil {
+			t.Fatal("FundAccount failed", err)
+		}
+	}
+	mock.Commit()
+
+	block := eth.BlockChain().CurrentBlock()
+	state, err := eth.BlockChain().StateAt(block.Root)
+	if err != nil {
+		t.Fatal("can't read state", err)
+	}
+	if want := new(big.Int).Mul(amount, big.NewInt(2)); state.GetBalance(account).ToBig().Cmp(want) != 0 {
+		t.Fatalf("balance mismatch: have %v, want %v", state.GetBalance(account), want)
+	}
+}
@@ -112,6 +112,11 @@ type handlerConfig struct {
 	NoTxGossip               bool             // Disable P2P transaction gossip
 	TxGossipNetRestrict      *netutil.Netlist // Restrict tx gossip to specific IP networks
 	TxGossipTrustedPeersOnly bool             // Restrict tx gossip to trusted peers only
+
+	// Arkiv additions
+	FullBroadcastTo      []common.Address // Recipients whose small transactions are sent in full to all peers
+	FullBroadcastMaxSize uint64           // Encoded size up to which transactions are sent in full, 0 disables it
+	ReadOnly             bool             // Ignore the transactions of peers and disable tx gossip, for read replicas
 }
 
 type handler struct {
@@ -130,6 +135,10 @@ type handler struct {
 	txGossipNetRestrict      *netutil.Netlist
 	txGossipTrustedPeersOnly bool
 
+	fullBroadcastTo      map[common.Address]struct{}
+	fullBroadcastMaxSize uint64
+	readOnly             bool
+
 	downloader     *downloader.Downloader
 	txFetcher      *fetcher.TxFetcher
 	peers          *peerSet
@@ -172,9 +181,14 @@ func newHandler(config *handlerConfig) (*handler, error) {
 		handlerStartCh: make(chan struct{}),
 
 		// OP Stack additions
-		noTxGossip:               config.NoTxGossip,
+		noTxGossip:               config.NoTxGossip || config.ReadOnly,
 		txGossipNetRestrict:      config.TxGossipNetRestrict,
 		txGossipTrustedPeersOnly: config.TxGossipTrustedPeersOnly,
+
+		// Arkiv additions
+		fullBroadcastTo:      newFullBroadcastSet(config.FullBroadcastTo),
+		fullBroadcastMaxSize: config.FullBroadcastMaxSize,
+		readOnly:             config.ReadOnly,
 	}
 	if config.Sync == ethconfig.FullSync {
 		// The database seems empty as the current block is the genesis. Yet the snap
@@ -301,6 +315,9 @@ func (h *handler) runEthPeer(peer *eth.Peer, handler eth.Handler) error {
 	}
 	peer.Log().Debug("Ethereum peer connected", "name", peer.Name())
 
+	// Account the transaction gossip of the connection from scratch
+	peer.SetTxTraffic(eth.NewTxTraffic(h.hotTx))
+
 	// Register the peer locally
 	if err := h.peers.registerPeer(peer, snap); err != nil {
 		peer.Log().Error("Ethereum peer registration failed", "err", err)
@@ -492,6 +509,9 @@ func (h *handler) BroadcastTransactions(txs types.Transactions) {
 	var (
 		blobTxs  int // Number of blob transactions to announce only
 		largeTxs int // Number of large transactions to announce only
+		fullTxs  int // Number of small transactions sent directly to all peers
+
+		fullCount int // Number of direct sends of the small transactions
 
 		directCount int // Number of transactions sent directly to peers (duplicates included)
 		annCount    int // Number of transactions announced across all peers (duplicates included)
@@ -502,15 +522,27 @@ func (h *handler) BroadcastTransactions(txs types.Transactions) {
 		signer = types.LatestSigner(h.chain.Config())
 		choice = newBroadcastChoice(h.nodeID, h.txBroadcastKey)
 		peers  = h.peers.all()
+
+		fullSet map[*ethPeer]struct{} // Gossip-allowed peers, computed on first use
 	)
 
 	for _, tx := range txs {
-		var directSet map[*ethPeer]struct{}
+		var (
+			directSet map[*ethPeer]struct{}
+			full      bool
+		)
 		switch {
 		case tx.Type() == types.BlobTxType:
 			blobTxs++
 		case tx.Size() > txMaxBroadcastSize:
 			largeTxs++
+		case h.fullBroadcast(tx):
+			if fullSet == nil {
+				fullSet = h.fullBroadcastPeers(peers)
+			}
+			fullTxs++
+			full = true
+			directSet = fullSet
 		default:
 			// Get transaction sender address. Here we can ignore any error
 			// since we're just interested in any value.
@@ -525,6 +557,9 @@ func (h *handler) BroadcastTransactions(txs types.Transactions) {
 			if _, ok := directSet[peer]; ok {
 				// Send direct.
 				txset[peer] = append(txset[peer], tx.Hash())
+				if full {
+					fullCount++
+				}
 			} else {
 				// Send announcement.
 				annos[peer] = append(annos[peer], tx.Hash())
@@ -536,11 +571,15 @@ func (h *handler) BroadcastTransactions(txs types.Transactions) {
 		directCount += len(hashes)
 		peer.AsyncSendTransactions(hashes)
 	}
+	if fullTxs > 0 {
+		fullBroadcastTxMeter.Mark(int64(fullTxs))
+		fullBroadcastSendMeter.Mark(int64(fullCount))
+	}
 	for peer, hashes := range annos {
 		annCount += len(hashes)
 		peer.AsyncSendPooledTransactionHashes(hashes)
 	}
-	log.Debug("Distributed transactions", "plaintxs", len(txs)-blobTxs-largeTxs, "blobtxs", blobTxs, "largetxs", largeTxs,
+	log.Debug("Distributed transactions", "plaintxs", len(txs)-blobTxs-largeTxs-fullTxs, "blobtxs", blobTxs, "largetxs", largeTxs, "fulltxs", fullTxs,
 		"bcastpeers", len(txset), "bcastcount", directCount, "annpeers", len(annos), "anncount", annCount)
 }
 
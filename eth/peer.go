@@ -27,6 +27,8 @@ import (
 type ethPeerInfo struct {
 	Version uint `json:"version"` // Ethereum protocol version negotiated
 	*peerBlockRange
+
+	TxTraffic *txTrafficInfo `json:"arkivTxGossip,omitempty"` // Transaction gossip exchanged since connecting
 }
 
 type peerBlockRange struct {
@@ -43,7 +45,7 @@ type ethPeer struct {
 
 // info gathers and returns some `eth` protocol metadata known about a peer.
 func (p *ethPeer) info() *ethPeerInfo {
-	info := &ethPeerInfo{Version: p.Version()}
+	info := &ethPeerInfo{Version: p.Version(), TxTraffic: newTxTrafficInfo(p.TxTraffic())}
 	if br := p.BlockRange(); br != nil {
 		info.peerBlockRange = &peerBlockRange{
 			Earliest:   br.EarliestBlock,
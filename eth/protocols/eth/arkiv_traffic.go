@@ -0,0 +1,79 @@
+package eth
+
+import (
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+var (
+	// The bytes of transaction gossip of all the peers, split by whether the
+	// transactions are sent to the hot addresses.
+	hotTxInMeter    = metrics.NewRegisteredMeter("eth/protocols/eth/arkiv/txgossip/hot/in", nil)
+	hotTxOutMeter   = metrics.NewRegisteredMeter("eth/protocols/eth/arkiv/txgossip/hot/out", nil)
+	otherTxInMeter  = metrics.NewRegisteredMeter("eth/protocols/eth/arkiv/txgossip/other/in", nil)
+	otherTxOutMeter = metrics.NewRegisteredMeter("eth/protocols/eth/arkiv/txgossip/other/out", nil)
+)
+
+// TxTraffic accounts the bytes of the transactions exchanged with a peer, sent in
+// full by the broadcasts or fetched from the pool, split by whether the transactions
+// are sent to one of the hot addresses. The counters live as long as the connection,
+// a reconnecting peer starts over.
+type TxTraffic struct {
+	hot func(tx *types.Transaction) bool
+
+	HotIn    atomic.Uint64
+	HotOut   atomic.Uint64
+	OtherIn  atomic.Uint64
+	OtherOut atomic.Uint64
+}
+
+// NewTxTraffic returns the accounting of a peer, hot tells the transactions to the
+// hot addresses apart.
+func NewTxTraffic(hot func(tx *types.Transaction) bool) *TxTraffic {
+	return &TxTraffic{hot: hot}
+}
+
+// account adds the transaction of the given encoded size to the counters. The
+// transactions are the ones the handlers already decoded or took from the pool, they
+// are never decoded again.
+func (t *TxTraffic) account(tx *types.Transaction, size uint64, inbound bool) {
+	switch hot := t.hot(tx); {
+	case hot && inbound:
+		t.HotIn.Add(size)
+		hotTxInMeter.Mark(int64(size))
+	case hot:
+		t.HotOut.Add(size)
+		hotTxOutMeter.Mark(int64(size))
+	case inbound:
+		t.OtherIn.Add(size)
+		otherTxInMeter.Mark(int64(size))
+	default:
+		t.OtherOut.Add(size)
+		otherTxOutMeter.Mark(int64(size))
+	}
+}
+
+// SetTxTraffic sets the accounting of the transactions exchanged with the peer, the
+// transactions aren't accounted without one.
+func (p *Peer) SetTxTraffic(traffic *TxTraffic) {
+	p.txTraffic.Store(traffic)
+}
+
+// TxTraffic returns the accounting of the transactions exchanged with the peer, nil
+// if it's not set.
+func (p *Peer) TxTraffic() *TxTraffic {
+	return p.txTraffic.Load()
+}
+
+// accountTxs accounts the decoded transactions of a message.
+func (p *Peer) accountTxs(txs []*types.Transaction, inbound bool) {
+	traffic := p.txTraffic.Load()
+	if traffic == nil {
+		return
+	}
+	for _, tx := range txs {
+		traffic.account(tx, tx.Size(), inbound)
+	}
+}
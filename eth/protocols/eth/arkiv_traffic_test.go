@@ -0,0 +1,113 @@
+package eth
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// waitTraffic waits for the counters of the peer to reach the expected bytes, in the
+// order hot in, hot out, other in, other out.
+func waitTraffic(t *testing.T, traffic *TxTraffic, expected [4]uint64) {
+	t.Helper()
+	var counters [4]uint64
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		counters = [4]uint64{traffic.HotIn.Load(), traffic.HotOut.Load(), traffic.OtherIn.Load(), traffic.OtherOut.Load()}
+		if counters == expected {
+			return
+		}
+	}
+	t.Fatalf("traffic mismatch: have %v, want %v", counters, expected)
+}
+
+func TestTxTraffic(t *testing.T) {
+	backend := newTestBackendWithGenerator(0, true, true, nil)
+	defer backend.close()
+
+	peer, _ := newTestPeer("peer", ETH68, backend)
+	defer peer.close()
+
+	hotAddr := common.Address{0xaa}
+	traffic := NewTxTraffic(func(tx *types.Transaction) bool {
+		return tx.To() != nil && *tx.To() == hotAddr
+	})
+	peer.SetTxTraffic(traffic)
+
+	signer := types.NewCancunSigner(params.TestChainConfig.ChainID)
+	sign := func(nonce uint64, to common.Address, data []byte) *types.Transaction {
+		tx, err := types.SignTx(types.NewTransaction(nonce, to, big.NewInt(10_000), 100_000, big.NewInt(1_000_000_000), data), signer, testKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tx
+	}
+	hot, other := sign(0, hotAddr, []byte("hot")), sign(1, testAddr, nil)
+	hotSize, otherSize := hot.Size(), other.Size()
+
+	// Broadcasts received from the peer
+	if err := p2p.Send(peer.app, TransactionsMsg, TransactionsPacket{hot, other}); err != nil {
+		t.Fatal(err)
+	}
+	waitTraffic(t, traffic, [4]uint64{hotSize, 0, otherSize, 0})
+
+	// Pooled transactions fetched from the peer
+	if err := p2p.Send(peer.app, PooledTransactionsMsg, PooledTransactionsPacket{RequestId: 1, PooledTransactionsResponse: PooledTransactionsResponse{hot}}); err != nil {
+		t.Fatal(err)
+	}
+	waitTraffic(t, traffic, [4]uint64{2 * hotSize, 0, otherSize, 0})
+
+	// Pooled transactions the peer fetches
+	for _, err := range backend.txpool.Add([]*types.Transaction{hot, other}, true) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := p2p.Send(peer.app, GetPooledTransactionsMsg, GetPooledTransactionsPacket{RequestId: 2, GetPooledTransactionsRequest: []common.Hash{hot.Hash(), other.Hash()}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p2p.ExpectMsg(peer.app, PooledTransactionsMsg, PooledTransactionsPacket{RequestId: 2, PooledTransactionsResponse: PooledTransactionsResponse{hot, other}}); err != nil {
+		t.Fatal(err)
+	}
+	waitTraffic(t, traffic, [4]uint64{2 * hotSize, hotSize, otherSize, otherSize})
+
+	// Broadcasts sent to the peer
+	errc := make(chan error, 1)
+	go func() { errc <- peer.SendTransactions(types.Transactions{other}) }()
+	if err := p2p.ExpectMsg(peer.app, TransactionsMsg, TransactionsPacket{other}); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	waitTraffic(t, traffic, [4]uint64{2 * hotSize, hotSize, otherSize, 2 * otherSize})
+}
+
+func TestTxTrafficUnset(t *testing.T) {
+	backend := newTestBackendWithGenerator(0, true, true, nil)
+	defer backend.close()
+
+	peer, _ := newTestPeer("peer", ETH68, backend)
+	defer peer.close()
+
+	// Without an accounting the transactions go through unaccounted
+	if peer.TxTraffic() != nil {
+		t.Fatal("unexpected traffic accounting")
+	}
+	tx, err := types.SignTx(types.NewTransaction(0, testAddr, big.NewInt(10_000), params.TxGas, big.NewInt(1_000_000_000), nil), types.NewCancunSigner(params.TestChainConfig.ChainID), testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	errc := make(chan error, 1)
+	go func() { errc <- peer.SendTransactions(types.Transactions{tx}) }()
+	if err := p2p.ExpectMsg(peer.app, TransactionsMsg, TransactionsPacket{tx}); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+}
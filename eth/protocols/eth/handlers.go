@@ -464,22 +464,31 @@ func handleGetPooledTransactions(backend Backend, msg Decoder, peer *Peer) error
 func answerGetPooledTransactions(backend Backend, query GetPooledTransactionsRequest, peer *Peer) ([]common.Hash, []rlp.RawValue) {
 	// Gather transactions until the fetch or network limits is reached
 	var (
-		bytes  int
-		hashes []common.Hash
-		txs    []rlp.RawValue
+		bytes   int
+		hashes  []common.Hash
+		txs     []rlp.RawValue
+		traffic = peer.TxTraffic()
 	)
 	for _, hash := range query {
 		if bytes >= softResponseLimit {
 			break
 		}
 		// Retrieve the requested transaction, skipping if unknown to us
-		encoded := backend.TxPool(peer.Peer).GetRLP(hash)
+		pool := backend.TxPool(peer.Peer)
+		encoded := pool.GetRLP(hash)
 		if len(encoded) == 0 {
 			continue
 		}
 		hashes = append(hashes, hash)
 		txs = append(txs, encoded)
 		bytes += len(encoded)
+
+		// The pool holds the transaction decoded, classify that one
+		if traffic != nil {
+			if tx := pool.Get(hash); tx != nil {
+				traffic.account(tx, uint64(len(encoded)), false)
+			}
+		}
 	}
 	return hashes, txs
 }
@@ -508,6 +517,7 @@ func handleTransactions(backend Backend, msg Decoder, peer *Peer) error {
 		seen[hash] = struct{}{}
 		peer.markTransaction(hash)
 	}
+	peer.accountTxs(txs, true)
 	return backend.Handle(peer, &txs)
 }
 
@@ -536,6 +546,7 @@ func handlePooledTransactions(backend Backend, msg Decoder, peer *Peer) error {
 		peer.markTransaction(hash)
 	}
 	requestTracker.Fulfil(peer.id, peer.version, PooledTransactionsMsg, txs.RequestId)
+	peer.accountTxs(txs.PooledTransactionsResponse, true)
 
 	return backend.Handle(peer, &txs.PooledTransactionsResponse)
 }
@@ -59,6 +59,8 @@ type Peer struct {
 	reqCancel   chan *cancel   // Dispatch channel to cancel pending requests and untrack them
 	resDispatch chan *response // Dispatch channel to fulfil pending requests and untrack them
 
+	txTraffic atomic.Pointer[TxTraffic] // Accounting of the transaction gossip, if set
+
 	term chan struct{} // Termination channel to stop the broadcasters
 }
 
@@ -136,7 +138,11 @@ func (p *Peer) SendTransactions(txs types.Transactions) error {
 	for _, tx := range txs {
 		p.knownTxs.Add(tx.Hash())
 	}
-	return p2p.Send(p.rw, TransactionsMsg, txs)
+	if err := p2p.Send(p.rw, TransactionsMsg, txs); err != nil {
+		return err
+	}
+	p.accountTxs(txs, false)
+	return nil
 }
 
 // AsyncSendTransactions queues a list of transactions (by hash) to eventually
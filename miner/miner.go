@@ -19,9 +19,11 @@ package miner
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/big"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -38,6 +40,10 @@ import (
 	"github.com/ethereum/go-ethereum/params"
 )
 
+// ErrMinerDisabled is returned when building a payload on a node whose miner is
+// disabled, like a read replica.
+var ErrMinerDisabled = errors.New("payload building is disabled on a read-only node")
+
 var (
 	maxDATxSizeGauge    = metrics.NewRegisteredGauge("miner/maxDATxSize", nil)
 	maxDABlockSizeGauge = metrics.NewRegisteredGauge("miner/maxDABlockSize", nil)
@@ -109,6 +115,8 @@ type Miner struct {
 
 	backend Backend
 
+	disabled atomic.Bool // Whether payload building is refused
+
 	lifeCtxCancel context.CancelFunc
 	lifeCtx       context.Context
 }
@@ -246,8 +254,24 @@ func (miner *Miner) SetMaxDASize(maxTxSize, maxBlockSize *big.Int) {
 	maxDABlockSizeGauge.Update(convertNilToZero(maxBlockSize))
 }
 
+// MaxDABlockSize returns the maximum data availability size of a block, nil if there
+// is no maximum.
+func (miner *Miner) MaxDABlockSize() *big.Int {
+	miner.confMu.RLock()
+	defer miner.confMu.RUnlock()
+	return miner.config.MaxDABlockSize
+}
+
+// Disable makes the miner refuse to build payloads.
+func (miner *Miner) Disable() {
+	miner.disabled.Store(true)
+}
+
 // BuildPayload builds the payload according to the provided parameters.
 func (miner *Miner) BuildPayload(args *BuildPayloadArgs, witness bool) (*Payload, error) {
+	if miner.disabled.Load() {
+		return nil, ErrMinerDisabled
+	}
 	return miner.buildPayload(args, witness)
 }
 
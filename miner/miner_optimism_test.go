@@ -43,10 +43,7 @@ func TestDAFootprintMining(t *testing.T) {
 
 		for i, receipt := range receipts {
 			txGas += receipt.GasUsed
-			if txs[i].IsDepositTx() {
-				continue
-			}
-			daFootprint += txs[i].RollupCostData().EstimatedDASize().Uint64() * testDAFootprintGasScalar
+			daFootprint += types.DAFootprintGas(txs[i], testDAFootprintGasScalar)
 		}
 		require.Equal(t, txGas, block.GasUsed(), "total tx gas used should be equal to block gas used")
 		require.Greater(t, daFootprint, block.GasUsed(), "total DA footprint used should be greater than block gas used")
@@ -62,7 +59,7 @@ func TestDAFootprintMining(t *testing.T) {
 			require.NoError(t, err, "failed to calculate DA footprint")
 			require.Equal(t, daFootprint, *block.Header().BlobGasUsed,
 				"header blob gas used should match calculated DA footprint")
-			require.Equal(t, testDAFootprintGasScalar*types.MinTransactionSize.Uint64(), daFootprint,
+			require.Equal(t, types.DAFootprintGasOfSize(types.MinTransactionSize.Uint64(), testDAFootprintGasScalar), daFootprint,
 				"simple pending transaction should lead to min DA footprint")
 		})
 	})
@@ -97,6 +94,26 @@ func TestDAFootprintMining(t *testing.T) {
 	})
 }
 
+// TestDAFootprintAccounting checks that the DA footprint the miner sets in the header of
+// a Jovian block is the sum of the DA footprints derived for its receipts, both agreeing
+// with the shared helper. The import of the block checks it against CalcDAFootprint.
+func TestDAFootprintAccounting(t *testing.T) {
+	cfg := jovianConfig()
+	testMineAndExecute(t, 0, cfg, func(t *testing.T, _ *core.BlockChain, block *types.Block, receipts []*types.Receipt) {
+		require.NoError(t, types.Receipts(receipts).DeriveFields(cfg, block.Hash(), block.NumberU64(), block.Time(), block.BaseFee(), nil, block.Transactions()))
+
+		footprint := types.NewDAFootprintAccumulator(testDAFootprintGasScalar)
+		var receiptsFootprint uint64
+		for i, tx := range block.Transactions() {
+			require.Equal(t, footprint.Add(tx), receipts[i].BlobGasUsed, "DA footprint of receipt %d", i)
+			receiptsFootprint += receipts[i].BlobGasUsed
+		}
+		require.NotZero(t, footprint.Total(), "block should have a DA footprint")
+		require.Equal(t, footprint.Total(), *block.Header().BlobGasUsed, "header DA footprint")
+		require.Equal(t, footprint.Total(), receiptsFootprint, "sum of the receipts DA footprints")
+	})
+}
+
 func testMineAndExecute(t *testing.T, numTxs uint64, cfg *params.ChainConfig, assertFn func(*testing.T, *core.BlockChain, *types.Block, []*types.Receipt)) {
 	db := rawdb.NewMemoryDatabase()
 	w, b := newTestWorker(t, cfg, beacon.New(ethash.NewFaker()), db, 0)
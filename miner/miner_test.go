@@ -19,6 +19,7 @@ package miner
 
 import (
 	"context"
+	"errors"
 	"math/big"
 	"sync"
 	"testing"
@@ -138,6 +139,25 @@ func TestBuildPendingBlocks(t *testing.T) {
 	wg.Wait()
 }
 
+func TestDisabledMiner(t *testing.T) {
+	miner := createMiner(t)
+	miner.Disable()
+	if _, err := miner.BuildPayload(&BuildPayloadArgs{}, false); !errors.Is(err, ErrMinerDisabled) {
+		t.Fatalf("unexpected error building a payload: %v", err)
+	}
+}
+
+func TestMaxDABlockSize(t *testing.T) {
+	miner := createMiner(t)
+	if size := miner.MaxDABlockSize(); size != nil {
+		t.Fatalf("unexpected DA block size without a limit: %v", size)
+	}
+	miner.SetMaxDASize(big.NewInt(100), big.NewInt(1000))
+	if size := miner.MaxDABlockSize(); size == nil || size.Uint64() != 1000 {
+		t.Fatalf("unexpected DA block size: %v", size)
+	}
+}
+
 func minerTestGenesisBlock(period uint64, gasLimit uint64, faucet common.Address) *core.Genesis {
 	config := *params.AllCliqueProtocolChanges
 	config.Clique = &params.CliqueConfig{
@@ -24,6 +24,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/ethereum/go-ethereum/arkiv/housekeepingtx"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus/misc"
 	"github.com/ethereum/go-ethereum/consensus/misc/eip1559"
@@ -181,7 +182,7 @@ func (miner *Miner) generateWork(genParam *generateParams, witness bool) *newPay
 		genParam.txs = types.Transactions{
 			types.NewTx(&types.DepositTx{
 				// System address
-				From:  common.HexToAddress("0xDeaDDEaDDeAdDeAdDEAdDEaddeAddEAdDEAd0001"),
+				From:  housekeepingtx.L1AttributesDepositor,
 				To:    &types.L1BlockAddr,
 				Value: big.NewInt(0),
 				Gas:   1000000,
@@ -527,7 +528,7 @@ func (miner *Miner) commitTransactions(env *environment, plainTxs, blobTxs *tran
 	// OP-Stack additions: throttling and DA footprint limit
 	blockDABytes := new(big.Int)
 	isJovian := miner.chainConfig.IsJovian(env.header.Time)
-	minTransactionDAFootprint := types.MinTransactionSize.Uint64() * uint64(env.daFootprintGasScalar)
+	minTransactionDAFootprint := types.DAFootprintGasOfSize(types.MinTransactionSize.Uint64(), env.daFootprintGasScalar)
 
 	for {
 		// Check interruption signal and abort building if it's fired.
@@ -606,7 +607,7 @@ func (miner *Miner) commitTransactions(env *environment, plainTxs, blobTxs *tran
 		// Note that commitTransaction is only called after deposit transactions have already been committed,
 		// so we don't need to resolve the transaction here and exclude deposits.
 		if isJovian {
-			txDAFootprint = ltx.DABytes.Uint64() * uint64(env.daFootprintGasScalar)
+			txDAFootprint = types.DAFootprintGasOfSize(ltx.DABytes.Uint64(), env.daFootprintGasScalar)
 			if daFootprintLeft < txDAFootprint {
 				log.Debug("Not enough DA space left for transaction", "hash", ltx.Hash, "left", daFootprintLeft, "needed", txDAFootprint)
 				txs.Pop()
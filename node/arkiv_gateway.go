@@ -0,0 +1,314 @@
+package node
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// DefaultArkivGatewayPort is the default port of the Arkiv query gateway.
+	DefaultArkivGatewayPort = 8555
+
+	// ArkivGatewayKeyHeader carries the API key of a request to the gateway, the key
+	// can also be sent as a bearer token.
+	ArkivGatewayKeyHeader = "X-Api-Key"
+	// ArkivGatewayBlockHashHeader and ArkivGatewaySignatureHeader carry the hash of
+	// the head block when the response was signed and the signature of the response.
+	ArkivGatewayBlockHashHeader = "Arkiv-Block-Hash"
+	ArkivGatewaySignatureHeader = "Arkiv-Signature"
+
+	// arkivGatewayBodyLimit is the size of the requests the gateway reads, the limit
+	// of the RPC server.
+	arkivGatewayBodyLimit = 5 * 1024 * 1024
+	// arkivGatewayReloadInterval is how often the gateway checks the keys file for
+	// changes.
+	arkivGatewayReloadInterval = time.Second
+	// arkivGatewayIdleClient is how long the rate limiter of an IP is kept after its
+	// last request.
+	arkivGatewayIdleClient = 10 * time.Minute
+)
+
+// arkivGatewayModules are the namespaces served by the gateway.
+var arkivGatewayModules = []string{"arkiv"}
+
+// ArkivGatewayKey is an API key of the gateway, as listed in the keys file. The
+// requests with the key are limited to Rate per second with bursts of Burst, the
+// rate limit of the gateway if Rate is 0.
+type ArkivGatewayKey struct {
+	Key   string  `json:"key"`
+	Name  string  `json:"name"`
+	Rate  float64 `json:"rate,omitempty"`
+	Burst int     `json:"burst,omitempty"`
+}
+
+// arkivGatewayKeys is the content of the keys file.
+type arkivGatewayKeys struct {
+	Keys []ArkivGatewayKey `json:"keys"`
+}
+
+// arkivGatewayClient is the rate limiter of an IP or an API key.
+type arkivGatewayClient struct {
+	name    string
+	limiter *rate.Limiter
+	seen    time.Time
+}
+
+// arkivGateway serves the arkiv namespace to the public: it authenticates the
+// requests with an API key when a keys file is set, rate limits them per key or per
+// IP and signs the responses with the node key.
+type arkivGateway struct {
+	next  http.Handler
+	rate  rate.Limit
+	burst int
+	key   *ecdsa.PrivateKey
+	head  func() common.Hash
+
+	keysFile string
+
+	mu          sync.Mutex
+	keys        map[string]*arkivGatewayClient
+	keysStat    os.FileInfo
+	keysChecked time.Time
+	ips         map[string]*arkivGatewayClient
+	pruned      time.Time
+}
+
+// initArkivGateway configures the Arkiv query gateway serving the arkiv namespace of
+// the APIs.
+func (n *Node) initArkivGateway(apis []rpc.API, rpcConfig rpcEndpointConfig) error {
+	var key *ecdsa.PrivateKey
+	if n.config.ArkivGatewaySign {
+		key = n.server.PrivateKey
+	}
+	gateway, err := newArkivGateway(nil, n.config.ArkivGatewayRateLimit, n.config.ArkivGatewayBurst, n.config.ArkivGatewayKeysFile, key, n.arkivGatewayHead)
+	if err != nil {
+		return err
+	}
+	if err := n.arkivGateway.setListenAddr(n.config.ArkivGatewayHost, n.config.ArkivGatewayPort); err != nil {
+		return err
+	}
+	return n.arkivGateway.enableRPC(apis, httpConfig{
+		CorsAllowedOrigins: n.config.ArkivGatewayCors,
+		Vhosts:             n.config.ArkivGatewayVirtualHosts,
+		Modules:            arkivGatewayModules,
+		rpcEndpointConfig:  rpcConfig,
+		wrap: func(next http.Handler) http.Handler {
+			gateway.next = next
+			return gateway
+		},
+	})
+}
+
+// newArkivGateway returns the gateway serving next. A rate of 0 disables the rate
+// limits, a nil key the signatures, and an empty keys file the API keys.
+func newArkivGateway(next http.Handler, limit float64, burst int, keysFile string, key *ecdsa.PrivateKey, head func() common.Hash) (*arkivGateway, error) {
+	g := &arkivGateway{
+		next:     next,
+		rate:     rate.Limit(limit),
+		burst:    max(burst, 1),
+		key:      key,
+		head:     head,
+		keysFile: keysFile,
+		ips:      map[string]*arkivGatewayClient{},
+	}
+	if g.head == nil {
+		g.head = func() common.Hash { return common.Hash{} }
+	}
+	if keysFile != "" {
+		if err := g.loadKeys(time.Now()); err != nil {
+			return nil, err
+		}
+	}
+	return g, nil
+}
+
+// loadKeys reads the keys file, keeping the limiters of the keys whose quota didn't
+// change. The caller must hold g.mu, unless the gateway isn't serving yet.
+func (g *arkivGateway) loadKeys(now time.Time) error {
+	stat, err := os.Stat(g.keysFile)
+	if err != nil {
+		return fmt.Errorf("failed to read the Arkiv gateway keys: %w", err)
+	}
+	data, err := os.ReadFile(g.keysFile)
+	if err != nil {
+		return fmt.Errorf("failed to read the Arkiv gateway keys: %w", err)
+	}
+	var file arkivGatewayKeys
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to parse the Arkiv gateway keys %s: %w", g.keysFile, err)
+	}
+
+	keys := make(map[string]*arkivGatewayClient, len(file.Keys))
+	for _, key := range file.Keys {
+		if key.Key == "" {
+			return fmt.Errorf("Arkiv gateway key %q of %s is empty", key.Name, g.keysFile)
+		}
+		limit, burst := g.rate, g.burst
+		if key.Rate > 0 {
+			limit, burst = rate.Limit(key.Rate), max(key.Burst, 1)
+		}
+		if client, ok := g.keys[key.Key]; ok && client.limiter.Limit() == limit && client.limiter.Burst() == burst {
+			client.name = key.Name
+			keys[key.Key] = client
+			continue
+		}
+		keys[key.Key] = &arkivGatewayClient{name: key.Name, limiter: rate.NewLimiter(limit, burst)}
+	}
+	g.keys, g.keysStat, g.keysChecked = keys, stat, now
+	return nil
+}
+
+// reloadKeys reloads the keys file if it changed since it was last read, at most
+// every arkivGatewayReloadInterval. A file that fails to load leaves the keys as they
+// are. The caller must hold g.mu.
+func (g *arkivGateway) reloadKeys(now time.Time) {
+	if now.Sub(g.keysChecked) < arkivGatewayReloadInterval {
+		return
+	}
+	g.keysChecked = now
+	stat, err := os.Stat(g.keysFile)
+	if err != nil {
+		log.Warn("Failed to check the Arkiv gateway keys", "err", err)
+		return
+	}
+	if stat.ModTime().Equal(g.keysStat.ModTime()) && stat.Size() == g.keysStat.Size() {
+		return
+	}
+	if err := g.loadKeys(now); err != nil {
+		log.Warn("Failed to reload the Arkiv gateway keys", "err", err)
+		return
+	}
+	log.Info("Reloaded the Arkiv gateway keys", "keys", len(g.keys))
+}
+
+// client returns the limiter of the request, nil if the API key is missing or
+// unknown.
+func (g *arkivGateway) client(r *http.Request, now time.Time) *arkivGatewayClient {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.keysFile != "" {
+		g.reloadKeys(now)
+		key := r.Header.Get(ArkivGatewayKeyHeader)
+		if key == "" {
+			key, _ = strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		}
+		return g.keys[key]
+	}
+
+	if now.Sub(g.pruned) > arkivGatewayIdleClient {
+		for ip, client := range g.ips {
+			if now.Sub(client.seen) > arkivGatewayIdleClient {
+				delete(g.ips, ip)
+			}
+		}
+		g.pruned = now
+	}
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	client, ok := g.ips[ip]
+	if !ok {
+		client = &arkivGatewayClient{name: ip, limiter: rate.NewLimiter(g.rate, g.burst)}
+		g.ips[ip] = client
+	}
+	client.seen = now
+	return client
+}
+
+// ServeHTTP charges the calls of the request to the limiter of its key or its IP
+// and signs the response.
+func (g *arkivGateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, arkivGatewayBodyLimit))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	now := time.Now()
+	client := g.client(r, now)
+	if client == nil {
+		http.Error(w, "missing or invalid API key", http.StatusUnauthorized)
+		return
+	}
+	if g.rate > 0 && !client.limiter.AllowN(now, arkivGatewayCalls(body)) {
+		http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+		return
+	}
+
+	if g.key == nil {
+		g.next.ServeHTTP(w, r)
+		return
+	}
+	response := &arkivGatewayResponse{header: w.Header(), status: http.StatusOK}
+	g.next.ServeHTTP(response, r)
+	blockHash := g.head()
+	signature, err := crypto.Sign(ArkivGatewayDigest(response.body.Bytes(), blockHash), g.key)
+	if err != nil {
+		http.Error(w, "failed to sign the response", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set(ArkivGatewayBlockHashHeader, blockHash.Hex())
+	w.Header().Set(ArkivGatewaySignatureHeader, common.Bytes2Hex(signature))
+	w.Header().Del("Content-Length")
+	w.WriteHeader(response.status)
+	w.Write(response.body.Bytes())
+}
+
+// arkivGatewayCalls returns the number of calls of a JSON-RPC request, the length of
+// a batch.
+func arkivGatewayCalls(body []byte) int {
+	if trimmed := bytes.TrimSpace(body); len(trimmed) == 0 || trimmed[0] != '[' {
+		return 1
+	}
+	var batch []json.RawMessage
+	if err := json.Unmarshal(body, &batch); err != nil || len(batch) == 0 {
+		return 1
+	}
+	return len(batch)
+}
+
+// arkivGatewayResponse buffers the response to sign it.
+type arkivGatewayResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *arkivGatewayResponse) Header() http.Header         { return r.header }
+func (r *arkivGatewayResponse) WriteHeader(status int)      { r.status = status }
+func (r *arkivGatewayResponse) Write(b []byte) (int, error) { return r.body.Write(b) }
+
+// ArkivGatewayDigest returns the hash signed by the gateway: the hash of the hash of
+// the response body and of the hash of the head block.
+func ArkivGatewayDigest(body []byte, blockHash common.Hash) []byte {
+	return crypto.Keccak256(crypto.Keccak256(body), blockHash[:])
+}
+
+// VerifyArkivGatewayResponse checks the signature of a response of the gateway with
+// the hash of the head block it carries, and returns the node key that signed it.
+// The caller compares the key with the one of the node it trusts, from its enode.
+func VerifyArkivGatewayResponse(body []byte, blockHash common.Hash, signature []byte) (*ecdsa.PublicKey, error) {
+	if len(signature) != crypto.SignatureLength {
+		return nil, errors.New("invalid Arkiv gateway signature length")
+	}
+	return crypto.SigToPub(ArkivGatewayDigest(body, blockHash), signature)
+}
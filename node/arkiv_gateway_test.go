@@ -0,0 +1,166 @@
+package node
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/require"
+)
+
+type arkivGatewayTestAPI struct{}
+
+func (arkivGatewayTestAPI) Ping() string { return "pong" }
+
+// startArkivGateway starts a node serving a ping method in the arkiv and eth
+// namespaces, with the gateway configured by configure.
+func startArkivGateway(t *testing.T, configure func(*Config)) *Node {
+	t.Helper()
+
+	conf := testNodeConfig()
+	conf.ArkivGatewayHost = "127.0.0.1"
+	configure(conf)
+	stack, err := New(conf)
+	require.NoError(t, err)
+	stack.RegisterAPIs([]rpc.API{
+		{Namespace: "arkiv", Service: arkivGatewayTestAPI{}},
+		{Namespace: "eth", Service: arkivGatewayTestAPI{}},
+	})
+	stack.SetArkivGatewayHead(func() common.Hash { return common.HexToHash("0x1234") })
+	require.NoError(t, stack.Start())
+	t.Cleanup(func() { stack.Close() })
+	return stack
+}
+
+// postArkivGateway sends the JSON-RPC request to the gateway with the API key, if
+// any, and returns the response and its body.
+func postArkivGateway(t *testing.T, stack *Node, key string, body string) (*http.Response, []byte) {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodPost, stack.ArkivGatewayEndpoint(), strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set(ArkivGatewayKeyHeader, key)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp, data
+}
+
+const arkivGatewayPing = `{"jsonrpc":"2.0","id":1,"method":"arkiv_ping"}`
+
+func TestArkivGateway_RateLimit(t *testing.T) {
+	stack := startArkivGateway(t, func(conf *Config) {
+		conf.ArkivGatewayRateLimit = 0.001
+		conf.ArkivGatewayBurst = 3
+	})
+
+	resp, body := postArkivGateway(t, stack, "", arkivGatewayPing)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Contains(t, string(body), `"result":"pong"`)
+
+	// A batch is charged for each of its calls, the burst holds two more
+	resp, _ = postArkivGateway(t, stack, "", "["+arkivGatewayPing+","+arkivGatewayPing+","+arkivGatewayPing+"]")
+	require.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	resp, _ = postArkivGateway(t, stack, "", "["+arkivGatewayPing+","+arkivGatewayPing+"]")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp, _ = postArkivGateway(t, stack, "", arkivGatewayPing)
+	require.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+}
+
+func TestArkivGateway_APIKeys(t *testing.T) {
+	keysFile := filepath.Join(t.TempDir(), "keys.json")
+	writeKeys := func(keys ...ArkivGatewayKey) {
+		data, err := json.Marshal(arkivGatewayKeys{Keys: keys})
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(keysFile, data, 0600))
+	}
+	writeKeys(ArkivGatewayKey{Key: "alpha", Name: "alpha", Rate: 0.001, Burst: 1})
+
+	stack := startArkivGateway(t, func(conf *Config) {
+		conf.ArkivGatewayRateLimit = 100
+		conf.ArkivGatewayBurst = 100
+		conf.ArkivGatewayKeysFile = keysFile
+	})
+
+	resp, _ := postArkivGateway(t, stack, "", arkivGatewayPing)
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	resp, _ = postArkivGateway(t, stack, "beta", arkivGatewayPing)
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// The quota of the key applies
+	resp, _ = postArkivGateway(t, stack, "alpha", arkivGatewayPing)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp, _ = postArkivGateway(t, stack, "alpha", arkivGatewayPing)
+	require.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+
+	// The keys are reloaded when the file changes, the key without a quota gets the
+	// rate limit of the gateway
+	writeKeys(ArkivGatewayKey{Key: "alpha", Name: "alpha", Rate: 0.001, Burst: 1}, ArkivGatewayKey{Key: "beta", Name: "beta"})
+	require.Eventually(t, func() bool {
+		resp, _ := postArkivGateway(t, stack, "beta", arkivGatewayPing)
+		return resp.StatusCode == http.StatusOK
+	}, 5*arkivGatewayReloadInterval, 50*time.Millisecond)
+	for range 3 {
+		resp, _ = postArkivGateway(t, stack, "beta", arkivGatewayPing)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+	resp, _ = postArkivGateway(t, stack, "alpha", arkivGatewayPing)
+	require.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+}
+
+func TestArkivGateway_RejectsOtherNamespaces(t *testing.T) {
+	stack := startArkivGateway(t, func(*Config) {})
+
+	resp, body := postArkivGateway(t, stack, "", `{"jsonrpc":"2.0","id":1,"method":"eth_ping"}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var result struct {
+		Error *struct {
+			Code int `json:"code"`
+		} `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal(body, &result))
+	require.NotNil(t, result.Error)
+	require.Equal(t, -32601, result.Error.Code)
+}
+
+func TestArkivGateway_Signature(t *testing.T) {
+	stack := startArkivGateway(t, func(conf *Config) {
+		conf.ArkivGatewaySign = true
+	})
+
+	resp, body := postArkivGateway(t, stack, "", arkivGatewayPing)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	blockHash := common.HexToHash(resp.Header.Get(ArkivGatewayBlockHashHeader))
+	require.Equal(t, common.HexToHash("0x1234"), blockHash)
+	signature := common.FromHex(resp.Header.Get(ArkivGatewaySignatureHeader))
+
+	signer, err := VerifyArkivGatewayResponse(body, blockHash, signature)
+	require.NoError(t, err)
+	require.Equal(t, crypto.PubkeyToAddress(testNodeKey.PublicKey), crypto.PubkeyToAddress(*signer))
+
+	// Another body or block isn't signed by the node
+	signer, err = VerifyArkivGatewayResponse([]byte(`{"jsonrpc":"2.0","id":1,"result":"pang"}`), blockHash, signature)
+	require.NoError(t, err)
+	require.NotEqual(t, crypto.PubkeyToAddress(testNodeKey.PublicKey), crypto.PubkeyToAddress(*signer))
+	signer, err = VerifyArkivGatewayResponse(body, common.Hash{}, signature)
+	require.NoError(t, err)
+	require.NotEqual(t, crypto.PubkeyToAddress(testNodeKey.PublicKey), crypto.PubkeyToAddress(*signer))
+
+	// The responses aren't signed unless enabled
+	unsigned := startArkivGateway(t, func(*Config) {})
+	resp, _ = postArkivGateway(t, unsigned, "", arkivGatewayPing)
+	require.Empty(t, resp.Header.Get(ArkivGatewaySignatureHeader))
+}
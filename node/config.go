@@ -24,6 +24,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -218,6 +219,186 @@ type Config struct {
 	ArkivHistoricBlocksFlag uint64 `toml:",omitempty"`
 
 	ArkivDatabaseDisabled bool `toml:",omitempty"`
+
+	// ArkivSkipPruned makes the Arkiv indexer skip blocks whose receipts were pruned
+	// instead of stalling at the pruned boundary.
+	ArkivSkipPruned bool `toml:",omitempty"`
+
+	// ArkivReceiptRetention is the number of blocks the receipts of the blocks without
+	// Arkiv logs are kept for once the Arkiv indexer processed them, 0 keeps them.
+	ArkivReceiptRetention uint64 `toml:",omitempty"`
+
+	// ArkivSkipPoison makes the Arkiv indexer skip a batch the store failed to ingest
+	// once it's written to the dead-letter queue, instead of halting until the batch
+	// is re-injected.
+	ArkivSkipPoison bool `toml:",omitempty"`
+
+	// ArkivShadowEnforcement checks the Arkiv consensus rules before their forks
+	// activate and records the transactions that would violate them, see arkiv/shadow.
+	ArkivShadowEnforcement bool `toml:",omitempty"`
+
+	// ArkivStoreCompress keeps the payloads of the Arkiv entities compressed in the
+	// store. The mode is set when the store is created, the node doesn't start with a
+	// store kept in the other mode.
+	ArkivStoreCompress bool `toml:",omitempty"`
+
+	// ArkivShards are the rules splitting the Arkiv entities over several stores, in
+	// the form <shard>=namespace:<value> or <shard>=owner:<0x prefix>. The entities no
+	// rule matches stay in the store, all of them if it's empty.
+	ArkivShards []string `toml:",omitempty"`
+
+	// ArkivPerKindOpIndex numbers the Arkiv operations of a transaction per kind, like
+	// the releases before the canonical order, for the consumers that haven't migrated.
+	ArkivPerKindOpIndex bool `toml:",omitempty"`
+
+	// ArkivEventsBatchSize is the maximum number of blocks the Arkiv store ingests at
+	// once, 0 uses the default.
+	ArkivEventsBatchSize uint64 `toml:",omitempty"`
+
+	// ArkivEventsConcurrency is the number of blocks the Arkiv store reads from the chain
+	// at once, 0 uses the default.
+	ArkivEventsConcurrency int `toml:",omitempty"`
+
+	// ArkivEventsStuckHeads is the number of heads the Arkiv events iterator can be
+	// notified about without making progress before it's reported stuck, 0 uses the
+	// default.
+	ArkivEventsStuckHeads uint64 `toml:",omitempty"`
+
+	// ArkivSelfCheckWarnOnly starts the node even when the Arkiv self-check run at
+	// startup fails, the failed checks are only logged.
+	ArkivSelfCheckWarnOnly bool `toml:",omitempty"`
+
+	// ArkivHookBudget is how long the import of a block waits for the Arkiv block
+	// hooks, 0 uses the default.
+	ArkivHookBudget time.Duration `toml:",omitempty"`
+
+	// ArkivFullText enables the full-text index of the payloads of the Arkiv entities.
+	ArkivFullText bool `toml:",omitempty"`
+
+	// ArkivFullTextContentTypes are the content types whose payloads are indexed.
+	ArkivFullTextContentTypes []string `toml:",omitempty"`
+
+	// ArkivFullTextMaxPayloadSize is the size limit of the indexed payloads in bytes.
+	ArkivFullTextMaxPayloadSize uint64 `toml:",omitempty"`
+
+	// ArkivQueryMaxScanFraction is the fraction of the live Arkiv entities a query can
+	// select without allowing a full scan, 0 disables the limit.
+	ArkivQueryMaxScanFraction float64 `toml:",omitempty"`
+
+	// ArkivQueryMemoryBudget is the number of bytes the running Arkiv queries can hold
+	// at once, 0 disables the limit. The queries that don't fit wait up to
+	// ArkivQueryMemoryWait for the others to finish, or fail right away if it's 0.
+	ArkivQueryMemoryBudget uint64        `toml:",omitempty"`
+	ArkivQueryMemoryWait   time.Duration `toml:",omitempty"`
+
+	// ArkivQueryTimeout is how long an Arkiv query can run in the store, 0 disables
+	// the limit. The requests can only shorten it.
+	ArkivQueryTimeout time.Duration `toml:",omitempty"`
+
+	// ArkivQueryMaxRows and ArkivQueryMaxBytes are the number of rows and their size
+	// in bytes an Arkiv query can return, 0 disables the limit. Larger results fail
+	// instead of being truncated.
+	ArkivQueryMaxRows  uint64 `toml:",omitempty"`
+	ArkivQueryMaxBytes uint64 `toml:",omitempty"`
+
+	// ArkivLegacyJSON adds the fields of the previous encoding to the arkiv RPC
+	// responses whose encoding changed.
+	ArkivLegacyJSON bool `toml:",omitempty"`
+
+	// ArkivRPCEnable and ArkivRPCDisable select the methods of the arkiv namespace the
+	// node serves: the enabled ones, all of them if none is, but the disabled ones.
+	ArkivRPCEnable  []string `toml:",omitempty"`
+	ArkivRPCDisable []string `toml:",omitempty"`
+
+	// ArkivWebhookURLs are the endpoints the Arkiv entity events are posted to, the
+	// webhooks are disabled if it's empty.
+	ArkivWebhookURLs []string `toml:",omitempty"`
+
+	// ArkivWebhookSecret is the shared secret signing the webhook requests.
+	ArkivWebhookSecret string `toml:",omitempty"`
+
+	// ArkivWebhookOwners, ArkivWebhookKeys and ArkivWebhookKinds filter the posted
+	// events, an empty list matches everything.
+	ArkivWebhookOwners []common.Address `toml:",omitempty"`
+	ArkivWebhookKeys   []common.Hash    `toml:",omitempty"`
+	ArkivWebhookKinds  []string         `toml:",omitempty"`
+
+	// ArkivWebhookConfirmations is the number of confirmations before the events of
+	// a block are posted, 0 waits for the block to be finalized.
+	ArkivWebhookConfirmations uint64 `toml:",omitempty"`
+
+	// ArkivWebhookMaxQueue is the number of undelivered webhook requests kept.
+	ArkivWebhookMaxQueue int `toml:",omitempty"`
+
+	// ArkivTxBroadcastTo are the recipients whose transactions up to
+	// ArkivTxBroadcastMaxSize bytes are sent in full to all peers instead of being
+	// announced to most of them. ArkivTxBroadcastMaxSize 0 disables it.
+	ArkivTxBroadcastTo      []common.Address `toml:",omitempty"`
+	ArkivTxBroadcastMaxSize uint64           `toml:",omitempty"`
+
+	// ArkivReadOnly runs the node as a read replica: it serves the Arkiv API but
+	// doesn't gossip transactions nor build blocks, and rejects the transactions
+	// submitted over RPC unless ArkivReadOnlyUpstream is set, in which case they are
+	// forwarded to it.
+	ArkivReadOnly         bool   `toml:",omitempty"`
+	ArkivReadOnlyUpstream string `toml:",omitempty"`
+
+	// ArkivDABackpressureBlocks is the DA backlog of the pending pool, in blocks, beyond
+	// which the sequencer rejects the transactions submitted over RPC with at least
+	// ArkivDABackpressureMinSize bytes of calldata, 0 disables it. The DA budget of a
+	// block is the one set by the batcher, ArkivDABackpressureBlockBudget otherwise.
+	ArkivDABackpressureBlocks      uint64 `toml:",omitempty"`
+	ArkivDABackpressureMinSize     uint64 `toml:",omitempty"`
+	ArkivDABackpressureBlockBudget uint64 `toml:",omitempty"`
+
+	// ArkivWarmupFile is a JSON file listing the queries the warmup of the query
+	// caches runs at startup, and ArkivWarmupPopular the number of the most popular
+	// queries of the node persisted across restarts and run too, 0 disables them.
+	// ArkivWarmupGate keeps the node not ready until the warmup completes.
+	ArkivWarmupFile    string `toml:",omitempty"`
+	ArkivWarmupPopular int    `toml:",omitempty"`
+	ArkivWarmupGate    bool   `toml:",omitempty"`
+
+	// ArkivGatewayHost and ArkivGatewayPort are the interface and the port of the
+	// Arkiv query gateway, serving the arkiv namespace alone to the public, disabled if
+	// the host is empty. ArkivGatewayCors and ArkivGatewayVirtualHosts are its CORS
+	// domains and virtual hosts.
+	ArkivGatewayHost         string   `toml:",omitempty"`
+	ArkivGatewayPort         int      `toml:",omitempty"`
+	ArkivGatewayCors         []string `toml:",omitempty"`
+	ArkivGatewayVirtualHosts []string `toml:",omitempty"`
+
+	// ArkivGatewayRateLimit is the number of calls per second the gateway serves to
+	// an IP, or to an API key without a quota, with bursts of ArkivGatewayBurst
+	// calls, 0 disables the limit.
+	ArkivGatewayRateLimit float64 `toml:",omitempty"`
+	ArkivGatewayBurst     int     `toml:",omitempty"`
+
+	// ArkivGatewayKeysFile is a JSON file listing the API keys the requests to the
+	// gateway must carry and their quotas, reloaded when it changes. The gateway is
+	// open to all if it's empty.
+	ArkivGatewayKeysFile string `toml:",omitempty"`
+
+	// ArkivGatewaySign signs the responses of the gateway with the node key.
+	ArkivGatewaySign bool `toml:",omitempty"`
+
+	// ArkivRelayerKeyFile is the file of the private key of the account relaying the
+	// Arkiv operations signed as typed data, arkiv_sendSignedOperation is unavailable
+	// if it's empty.
+	ArkivRelayerKeyFile string `toml:",omitempty"`
+
+	// ArkivContentResolverURL is the base URL of the data availability service the
+	// payloads of the Arkiv entities stored off chain are fetched from, they stay
+	// pending if it's empty.
+	ArkivContentResolverURL string `toml:",omitempty"`
+
+	// ArkivContentResolveAttempts is how many times a payload stored off chain is
+	// fetched before it's left pending, 0 uses the default.
+	ArkivContentResolveAttempts int `toml:",omitempty"`
+
+	// ArkivContentResolveTimeout is how long a fetch of a payload stored off chain can
+	// take, 0 uses the default.
+	ArkivContentResolveTimeout time.Duration `toml:",omitempty"`
 }
 
 // IPCEndpoint resolves an IPC endpoint based on a configured value, taking into
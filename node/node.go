@@ -63,9 +63,12 @@ type Node struct {
 	ws            *httpServer //
 	httpAuth      *httpServer //
 	wsAuth        *httpServer //
+	arkivGateway  *httpServer // Serves the arkiv namespace to the public, see arkivGateway
 	ipc           *ipcServer  // Stores information about the ipc http server
 	inprocHandler *rpc.Server // In-process RPC request handler to process the API requests
 
+	arkivGatewayHead func() common.Hash // Hash of the head block signed by the Arkiv gateway
+
 	databases map[*closeTrackingDB]struct{} // All open databases
 }
 
@@ -154,6 +157,7 @@ func New(conf *Config) (*Node, error) {
 	node.httpAuth = newHTTPServer(node.log, conf.HTTPTimeouts)
 	node.ws = newHTTPServer(node.log, rpc.DefaultHTTPTimeouts)
 	node.wsAuth = newHTTPServer(node.log, rpc.DefaultHTTPTimeouts)
+	node.arkivGateway = newHTTPServer(node.log, conf.HTTPTimeouts)
 	node.ipc = newIPCServer(node.log, conf.IPCEndpoint())
 
 	return node, nil
@@ -502,6 +506,13 @@ func (n *Node) startRPC() error {
 			return err
 		}
 	}
+	// Configure the Arkiv query gateway
+	if n.config.ArkivGatewayHost != "" {
+		if err := n.initArkivGateway(openAPIs, rpcConfig); err != nil {
+			return err
+		}
+		servers = append(servers, n.arkivGateway)
+	}
 	// Start the servers
 	for _, server := range servers {
 		if err := server.start(); err != nil {
@@ -527,6 +538,7 @@ func (n *Node) stopRPC() {
 	n.ws.stop()
 	n.httpAuth.stop()
 	n.wsAuth.stop()
+	n.arkivGateway.stop()
 	n.ipc.stop()
 	n.stopInProc()
 }
@@ -587,6 +599,18 @@ func (n *Node) RegisterAPIs(apis []rpc.API) {
 	n.rpcAPIs = append(n.rpcAPIs, apis...)
 }
 
+// SetArkivGatewayHead sets the function returning the hash of the head block the
+// Arkiv query gateway signs its responses with.
+func (n *Node) SetArkivGatewayHead(head func() common.Hash) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if n.state != initializingState {
+		panic("can't set the Arkiv gateway head on running/stopped node")
+	}
+	n.arkivGatewayHead = head
+}
+
 // getAPIs return two sets of APIs, both the ones that do not require
 // authentication, and the complete set
 func (n *Node) getAPIs() (unauthenticated, all []rpc.API) {
@@ -677,6 +701,11 @@ func (n *Node) HTTPEndpoint() string {
 	return "http://" + n.http.listenAddr() //nolint:all
 }
 
+// ArkivGatewayEndpoint returns the URL of the Arkiv query gateway.
+func (n *Node) ArkivGatewayEndpoint() string {
+	return "http://" + n.arkivGateway.listenAddr()
+}
+
 // WSEndpoint returns the current JSON-RPC over WebSocket endpoint.
 func (n *Node) WSEndpoint() string {
 	if n.http.wsAllowed() {
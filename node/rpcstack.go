@@ -43,6 +43,8 @@ type httpConfig struct {
 	Vhosts             []string
 	prefix             string // path prefix on which to mount http handler
 	rpcEndpointConfig
+
+	wrap func(http.Handler) http.Handler // optional handler wrapping the RPC server
 }
 
 // wsConfig is the JSON-RPC/Websocket configuration
@@ -310,9 +312,13 @@ func (h *httpServer) enableRPC(apis []rpc.API, config httpConfig) error {
 	if err := RegisterApis(apis, config.Modules, srv); err != nil {
 		return err
 	}
+	var handler http.Handler = srv
+	if config.wrap != nil {
+		handler = config.wrap(srv)
+	}
 	h.httpConfig = config
 	h.httpHandler.Store(&rpcHandler{
-		Handler: NewHTTPHandlerStack(srv, config.CorsAllowedOrigins, config.Vhosts, config.jwtSecret),
+		Handler: NewHTTPHandlerStack(handler, config.CorsAllowedOrigins, config.Vhosts, config.jwtSecret),
 		prefix:  config.prefix,
 		server:  srv,
 	})
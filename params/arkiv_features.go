@@ -0,0 +1,158 @@
+package params
+
+import "slices"
+
+// ArkivFeature is the id of a feature of the Arkiv processor, the first 4 bytes of the
+// keccak256 hash of its name, like the interface ids of ERC-165.
+type ArkivFeature [4]byte
+
+var (
+	// ArkivFeatureGasSchedule is the cap on the size of the string annotation values and
+	// the gas charged on top of the intrinsic gas for the large ones.
+	ArkivFeatureGasSchedule = ArkivFeature{0x05, 0x04, 0x99, 0xec} // arkiv.gasSchedule
+	// ArkivFeatureTypedNumerics is the typed numeric annotations, the signed, decimal
+	// and timestamp values of the versioned transactions.
+	ArkivFeatureTypedNumerics = ArkivFeature{0xd0, 0x97, 0xfe, 0x76} // arkiv.typedNumerics
+	// ArkivFeatureEncryption is the encryption envelope of the payload of a create or
+	// an update.
+	ArkivFeatureEncryption = ArkivFeature{0x75, 0xba, 0x56, 0x4f} // arkiv.encryption
+	// ArkivFeatureTombstones is the recording of the tombstones of the removed entities.
+	ArkivFeatureTombstones = ArkivFeature{0xb6, 0x64, 0x6e, 0x64} // arkiv.tombstones
+	// ArkivFeatureTwoStepTransfer is the ownership transfer accepted by the new owner.
+	ArkivFeatureTwoStepTransfer = ArkivFeature{0xbf, 0xb2, 0xed, 0x11} // arkiv.twoStepTransfer
+	// ArkivFeatureDottedKeys is the dotted annotation keys, the namespaced keys made of
+	// segments separated by dots.
+	ArkivFeatureDottedKeys = ArkivFeature{0x20, 0xfd, 0x74, 0x66} // arkiv.dottedKeys
+	// ArkivFeatureHousekeepingOrder is the housekeeping run in the L1 attributes
+	// deposit only.
+	ArkivFeatureHousekeepingOrder = ArkivFeature{0x76, 0x2a, 0xae, 0x8d} // arkiv.housekeepingOrder
+	// ArkivFeatureCapabilities is the capabilities precompile itself.
+	ArkivFeatureCapabilities = ArkivFeature{0xb8, 0x38, 0xc3, 0x54} // arkiv.capabilities
+	// ArkivFeatureUpsert is reserved for the creation of an entity by its update, it
+	// isn't supported yet.
+	ArkivFeatureUpsert = ArkivFeature{0xfa, 0xa6, 0xf9, 0x89} // arkiv.upsert
+	// ArkivFeatureNamespaces is reserved for the entity namespaces of the processor, it
+	// isn't supported yet.
+	ArkivFeatureNamespaces = ArkivFeature{0xa6, 0xc6, 0x36, 0xf2} // arkiv.namespaces
+	// ArkivFeatureOwnerSlots is the counting of the slots used by the entities of each
+	// owner.
+	ArkivFeatureOwnerSlots = ArkivFeature{0x55, 0x3a, 0x26, 0xd0} // arkiv.ownerSlots
+	// ArkivFeatureContentHash is the content hash of the payload stored next to the
+	// metadata of the entities.
+	ArkivFeatureContentHash = ArkivFeature{0x60, 0x81, 0xd9, 0xf7} // arkiv.contentHash
+	// ArkivFeatureContentHashRead is the content hash precompile, reading the content
+	// hash of an entity from a contract.
+	ArkivFeatureContentHashRead = ArkivFeature{0x25, 0x1a, 0xff, 0xcc} // arkiv.contentHashRead
+	// ArkivFeatureAliases is the registry of the names pointing to entities.
+	ArkivFeatureAliases = ArkivFeature{0xab, 0xcc, 0xd6, 0x41} // arkiv.aliases
+	// ArkivFeatureMaxBTL is the cap on the distance between the current block and the
+	// expiry of the entities and the aliases.
+	ArkivFeatureMaxBTL = ArkivFeature{0x90, 0x4b, 0x47, 0xbc} // arkiv.maxBTL
+	// ArkivFeatureIdempotency is the deduplication of the creates carrying the same
+	// idempotency key.
+	ArkivFeatureIdempotency = ArkivFeature{0x86, 0xcf, 0x73, 0x17} // arkiv.idempotency
+	// ArkivFeatureReduceBTL is the operation bringing the expiry of an entity forward.
+	ArkivFeatureReduceBTL = ArkivFeature{0x90, 0x4c, 0xf2, 0x50} // arkiv.reduceBTL
+	// ArkivFeatureChangeOwnerBatch is the operation transferring several entities to a
+	// new owner.
+	ArkivFeatureChangeOwnerBatch = ArkivFeature{0xc8, 0x6a, 0xc3, 0xc6} // arkiv.changeOwnerBatch
+	// ArkivFeaturePayloadLimits is the cap on the size of the payloads and the chain
+	// parameters of the limits of the decoding of the transactions.
+	ArkivFeaturePayloadLimits = ArkivFeature{0x3c, 0xee, 0xac, 0xfc} // arkiv.payloadLimits
+	// ArkivFeatureCodecs is the prefix naming the codec of the calldata of the
+	// transactions, brotli or zstd.
+	ArkivFeatureCodecs = ArkivFeature{0xc9, 0x32, 0x50, 0x68} // arkiv.codecs
+	// ArkivFeatureExternalContent is the commitment to a payload stored off chain, in
+	// place of the payload of a create or an update.
+	ArkivFeatureExternalContent = ArkivFeature{0x07, 0xe2, 0x03, 0x99} // arkiv.externalContent
+	// ArkivFeatureConditions is the preconditions on the entities of the creates,
+	// updates and extensions, reverting the transaction when they don't hold.
+	ArkivFeatureConditions = ArkivFeature{0x59, 0x2d, 0x95, 0x5e} // arkiv.conditions
+)
+
+// ArkivFeatureSpec is the entry of a feature in the registry of the Arkiv features.
+type ArkivFeatureSpec struct {
+	ID   ArkivFeature
+	Name string
+
+	// activation returns the time the feature activates at in the chain config, nil if
+	// it never does.
+	activation func(c *ChainConfig) *uint64
+}
+
+// Activation returns the time the feature activates at in the chain config, nil if it
+// never does.
+func (f ArkivFeatureSpec) Activation(c *ChainConfig) *uint64 {
+	return f.activation(c)
+}
+
+var (
+	arkivNever = func(*ChainConfig) *uint64 { return nil }
+)
+
+// arkivFeatures is the registry of the Arkiv features, the single source of the fork
+// gating of the processor and of the answers of the capabilities precompile and of
+// arkiv_capabilities. New features are appended.
+var arkivFeatures = []ArkivFeatureSpec{
+	{ArkivFeatureGasSchedule, "arkiv.gasSchedule", func(c *ChainConfig) *uint64 { return c.ArkivGasScheduleTime }},
+	{ArkivFeatureTypedNumerics, "arkiv.typedNumerics", func(c *ChainConfig) *uint64 { return c.ArkivTypedNumericsTime }},
+	{ArkivFeatureEncryption, "arkiv.encryption", func(c *ChainConfig) *uint64 { return c.ArkivEncryptionTime }},
+	{ArkivFeatureTombstones, "arkiv.tombstones", func(c *ChainConfig) *uint64 { return c.ArkivTombstonesTime }},
+	{ArkivFeatureTwoStepTransfer, "arkiv.twoStepTransfer", func(c *ChainConfig) *uint64 { return c.ArkivOwnershipTime }},
+	{ArkivFeatureDottedKeys, "arkiv.dottedKeys", func(c *ChainConfig) *uint64 { return c.ArkivDottedKeysTime }},
+	{ArkivFeatureHousekeepingOrder, "arkiv.housekeepingOrder", func(c *ChainConfig) *uint64 { return c.ArkivHousekeepingOrderTime }},
+	{ArkivFeatureCapabilities, "arkiv.capabilities", func(c *ChainConfig) *uint64 { return c.ArkivCapabilitiesTime }},
+	{ArkivFeatureUpsert, "arkiv.upsert", arkivNever},
+	{ArkivFeatureNamespaces, "arkiv.namespaces", arkivNever},
+	{ArkivFeatureOwnerSlots, "arkiv.ownerSlots", func(c *ChainConfig) *uint64 { return c.ArkivOwnerSlotsTime }},
+	{ArkivFeatureContentHash, "arkiv.contentHash", func(c *ChainConfig) *uint64 { return c.ArkivContentHashTime }},
+	{ArkivFeatureContentHashRead, "arkiv.contentHashRead", func(c *ChainConfig) *uint64 { return c.ArkivContentHashReadTime }},
+	{ArkivFeatureAliases, "arkiv.aliases", func(c *ChainConfig) *uint64 { return c.ArkivAliasesTime }},
+	{ArkivFeatureMaxBTL, "arkiv.maxBTL", func(c *ChainConfig) *uint64 { return c.ArkivMaxBTLTime }},
+	{ArkivFeatureIdempotency, "arkiv.idempotency", func(c *ChainConfig) *uint64 { return c.ArkivIdempotencyTime }},
+	{ArkivFeatureReduceBTL, "arkiv.reduceBTL", func(c *ChainConfig) *uint64 { return c.ArkivReduceBTLTime }},
+	{ArkivFeatureChangeOwnerBatch, "arkiv.changeOwnerBatch", func(c *ChainConfig) *uint64 { return c.ArkivChangeOwnerBatchTime }},
+	{ArkivFeaturePayloadLimits, "arkiv.payloadLimits", func(c *ChainConfig) *uint64 { return c.ArkivPayloadLimitsTime }},
+	{ArkivFeatureCodecs, "arkiv.codecs", func(c *ChainConfig) *uint64 { return c.ArkivCodecsTime }},
+	{ArkivFeatureExternalContent, "arkiv.externalContent", func(c *ChainConfig) *uint64 { return c.ArkivExternalContentTime }},
+	{ArkivFeatureConditions, "arkiv.conditions", func(c *ChainConfig) *uint64 { return c.ArkivConditionsTime }},
+}
+
+// ArkivFeatures returns the registry of the Arkiv features.
+func ArkivFeatures() []ArkivFeatureSpec {
+	return slices.Clone(arkivFeatures)
+}
+
+// ArkivFeatureByID returns the entry of the feature in the registry, false for the
+// unknown features.
+func ArkivFeatureByID(id ArkivFeature) (ArkivFeatureSpec, bool) {
+	for _, feature := range arkivFeatures {
+		if feature.ID == id {
+			return feature, true
+		}
+	}
+	return ArkivFeatureSpec{}, false
+}
+
+// IsArkivFeature returns whether the Arkiv feature is active at time, false for the
+// unknown features.
+func (c *ChainConfig) IsArkivFeature(id ArkivFeature, time uint64) bool {
+	feature, ok := ArkivFeatureByID(id)
+	return ok && isTimestampForked(feature.activation(c), time)
+}
+
+// ArkivFeatureSet is the set of the Arkiv features active at a time.
+type ArkivFeatureSet struct {
+	config *ChainConfig
+	time   uint64
+}
+
+// ArkivFeaturesAt returns the set of the Arkiv features active at time.
+func (c *ChainConfig) ArkivFeaturesAt(time uint64) ArkivFeatureSet {
+	return ArkivFeatureSet{config: c, time: time}
+}
+
+// Supports returns whether the feature is in the set.
+func (s ArkivFeatureSet) Supports(id ArkivFeature) bool {
+	return s.config != nil && s.config.IsArkivFeature(id, s.time)
+}
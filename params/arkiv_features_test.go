@@ -0,0 +1,110 @@
+package params
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArkivFeatures_IDs(t *testing.T) {
+	seen := make(map[ArkivFeature]bool)
+	for _, feature := range ArkivFeatures() {
+		require.Equal(t, crypto.Keccak256([]byte(feature.Name))[:4], feature.ID[:], feature.Name)
+		require.False(t, seen[feature.ID], feature.Name)
+		seen[feature.ID] = true
+	}
+}
+
+func TestArkivFeatures_Activation(t *testing.T) {
+	config := &ChainConfig{
+		ArkivGasScheduleTime:       newUint64(100),
+		ArkivTypedNumericsTime:     newUint64(100),
+		ArkivEncryptionTime:        newUint64(100),
+		ArkivDottedKeysTime:        newUint64(100),
+		ArkivTombstonesTime:        newUint64(100),
+		ArkivOwnershipTime:         newUint64(100),
+		ArkivHousekeepingOrderTime: newUint64(100),
+		ArkivCapabilitiesTime:      newUint64(100),
+		ArkivOwnerSlotsTime:        newUint64(100),
+		ArkivContentHashTime:       newUint64(100),
+		ArkivContentHashReadTime:   newUint64(100),
+		ArkivAliasesTime:           newUint64(100),
+		ArkivMaxBTLTime:            newUint64(100),
+		ArkivIdempotencyTime:       newUint64(100),
+		ArkivReduceBTLTime:         newUint64(100),
+		ArkivChangeOwnerBatchTime:  newUint64(100),
+		ArkivPayloadLimitsTime:     newUint64(100),
+		ArkivCodecsTime:            newUint64(100),
+		ArkivExternalContentTime:   newUint64(100),
+		ArkivConditionsTime:        newUint64(100),
+	}
+
+	// The fork gating of the processor reads the registry
+	gates := map[ArkivFeature]func(uint64) bool{
+		ArkivFeatureGasSchedule:       config.IsArkivGasSchedule,
+		ArkivFeatureTypedNumerics:     config.IsArkivTypedNumerics,
+		ArkivFeatureEncryption:        config.IsArkivEncryption,
+		ArkivFeatureDottedKeys:        config.IsArkivDottedKeys,
+		ArkivFeatureTombstones:        config.IsArkivTombstones,
+		ArkivFeatureTwoStepTransfer:   config.IsArkivOwnership,
+		ArkivFeatureHousekeepingOrder: config.IsArkivHousekeepingOrder,
+		ArkivFeatureCapabilities:      config.IsArkivCapabilities,
+		ArkivFeatureOwnerSlots:        config.IsArkivOwnerSlots,
+		ArkivFeatureContentHash:       config.IsArkivContentHash,
+		ArkivFeatureContentHashRead:   config.IsArkivContentHashRead,
+		ArkivFeatureAliases:           config.IsArkivAliases,
+		ArkivFeatureMaxBTL:            config.IsArkivMaxBTL,
+		ArkivFeatureIdempotency:       config.IsArkivIdempotency,
+		ArkivFeatureReduceBTL:         config.IsArkivReduceBTL,
+		ArkivFeatureChangeOwnerBatch:  config.IsArkivChangeOwnerBatch,
+		ArkivFeaturePayloadLimits:     config.IsArkivPayloadLimits,
+		ArkivFeatureCodecs:            config.IsArkivCodecs,
+		ArkivFeatureExternalContent:   config.IsArkivExternalContent,
+		ArkivFeatureConditions:        config.IsArkivConditions,
+	}
+	for id, gate := range gates {
+		require.False(t, config.IsArkivFeature(id, 99))
+		require.False(t, config.ArkivFeaturesAt(99).Supports(id))
+		require.False(t, gate(99))
+
+		require.True(t, config.IsArkivFeature(id, 100))
+		require.True(t, config.ArkivFeaturesAt(100).Supports(id))
+		require.True(t, gate(100))
+	}
+	require.Zero(t, config.ArkivOwnershipTransferWindowAt(99))
+	require.Equal(t, DefaultArkivOwnershipTransferWindow, config.ArkivOwnershipTransferWindowAt(100))
+	require.Zero(t, config.ArkivTombstoneRetentionAt(99))
+	require.Equal(t, DefaultArkivTombstoneRetention, config.ArkivTombstoneRetentionAt(100))
+	require.Zero(t, config.ArkivMaxAnnotationValueSizeAt(99))
+	require.Equal(t, DefaultArkivMaxAnnotationValueSize, config.ArkivMaxAnnotationValueSizeAt(100))
+	require.Zero(t, config.ArkivAnnotationValueGasThresholdAt(99))
+	require.Equal(t, DefaultArkivAnnotationValueGasThreshold, config.ArkivAnnotationValueGasThresholdAt(100))
+	require.Zero(t, config.ArkivAnnotationValueGasPerByteAt(99))
+	require.Equal(t, DefaultArkivAnnotationValueGasPerByte, config.ArkivAnnotationValueGasPerByteAt(100))
+	require.Zero(t, config.ArkivMaxBTLAt(99))
+	require.Equal(t, DefaultArkivMaxBTL, config.ArkivMaxBTLAt(100))
+	require.Zero(t, config.ArkivIdempotencyTTLAt(99))
+	require.Equal(t, DefaultArkivIdempotencyTTL, config.ArkivIdempotencyTTLAt(100))
+	require.Zero(t, config.ArkivMaxPayloadSizeAt(99))
+	require.Equal(t, DefaultArkivMaxPayloadSize, config.ArkivMaxPayloadSizeAt(100))
+	require.Zero(t, config.ArkivMaxDecompressedSizeAt(99))
+	require.Equal(t, DefaultArkivMaxDecompressedSize, config.ArkivMaxDecompressedSizeAt(100))
+	require.Zero(t, config.ArkivMaxOperationsAt(99))
+	require.Equal(t, DefaultArkivMaxOperations, config.ArkivMaxOperationsAt(100))
+
+	// The chain can lower the limits of the decoding, not raise them
+	config.ArkivMaxDecompressedSize, config.ArkivMaxOperations = 1024, 10*DefaultArkivMaxOperations
+	require.Equal(t, uint64(1024), config.ArkivMaxDecompressedSizeAt(100))
+	require.Equal(t, DefaultArkivMaxOperations, config.ArkivMaxOperationsAt(100))
+
+	require.False(t, config.Rules(new(big.Int), false, 99).IsArkivCapabilities)
+	require.True(t, config.Rules(new(big.Int), false, 100).IsArkivCapabilities)
+
+	// The reserved features are never active
+	require.False(t, config.IsArkivFeature(ArkivFeatureUpsert, 1<<60))
+	require.False(t, config.IsArkivFeature(ArkivFeatureNamespaces, 1<<60))
+	require.False(t, config.IsArkivFeature(ArkivFeature{0x01, 0xff, 0xc9, 0xa7}, 1<<60))
+	require.False(t, ArkivFeatureSet{}.Supports(ArkivFeatureGasSchedule))
+}
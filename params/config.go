@@ -242,6 +242,11 @@ var (
 			Cancun: DefaultCancunBlobConfig,
 			Prague: DefaultPragueBlobConfig,
 		},
+		// The dev chain keeps the Arkiv features that were active before they got a fork
+		ArkivGasScheduleTime:   newUint64(0),
+		ArkivTypedNumericsTime: newUint64(0),
+		ArkivEncryptionTime:    newUint64(0),
+		ArkivDottedKeysTime:    newUint64(0),
 	}
 
 	// AllCliqueProtocolChanges contains every protocol change (EIPs) introduced
@@ -515,6 +520,68 @@ type ChainConfig struct {
 
 	InteropTime *uint64 `json:"interopTime,omitempty"` // Interop switch time (nil = no fork, 0 = already on optimism interop)
 
+	ArkivGasScheduleTime       *uint64 `json:"arkivGasScheduleTime,omitempty"`       // Arkiv gas schedule switch time (nil = no fork, 0 = already active)
+	ArkivTypedNumericsTime     *uint64 `json:"arkivTypedNumericsTime,omitempty"`     // Arkiv typed numeric annotations switch time (nil = no fork, 0 = already active)
+	ArkivEncryptionTime        *uint64 `json:"arkivEncryptionTime,omitempty"`        // Arkiv payload encryption switch time (nil = no fork, 0 = already active)
+	ArkivTombstonesTime        *uint64 `json:"arkivTombstonesTime,omitempty"`        // Arkiv entity tombstones switch time (nil = no fork, 0 = already active)
+	ArkivOwnershipTime         *uint64 `json:"arkivOwnershipTime,omitempty"`         // Arkiv two-step ownership transfer switch time (nil = no fork, 0 = already active)
+	ArkivDottedKeysTime        *uint64 `json:"arkivDottedKeysTime,omitempty"`        // Arkiv dotted annotation keys switch time (nil = no fork, 0 = already active)
+	ArkivHousekeepingOrderTime *uint64 `json:"arkivHousekeepingOrderTime,omitempty"` // Arkiv housekeeping in the L1 attributes deposit only switch time (nil = no fork, 0 = already active)
+	ArkivCapabilitiesTime      *uint64 `json:"arkivCapabilitiesTime,omitempty"`      // Arkiv capabilities precompile switch time (nil = no fork, 0 = already active)
+	ArkivOwnerSlotsTime        *uint64 `json:"arkivOwnerSlotsTime,omitempty"`        // Arkiv per-owner used slots counters switch time (nil = no fork, 0 = already active)
+	ArkivContentHashTime       *uint64 `json:"arkivContentHashTime,omitempty"`       // Arkiv entity content hashes switch time (nil = no fork, 0 = already active)
+	ArkivContentHashReadTime   *uint64 `json:"arkivContentHashReadTime,omitempty"`   // Arkiv content hash precompile switch time (nil = no fork, 0 = already active)
+	ArkivAliasesTime           *uint64 `json:"arkivAliasesTime,omitempty"`           // Arkiv alias registry switch time (nil = no fork, 0 = already active)
+	ArkivMaxBTLTime            *uint64 `json:"arkivMaxBTLTime,omitempty"`            // Arkiv maximum BTL switch time (nil = no fork, 0 = already active)
+	ArkivIdempotencyTime       *uint64 `json:"arkivIdempotencyTime,omitempty"`       // Arkiv idempotency keys switch time (nil = no fork, 0 = already active)
+	ArkivReduceBTLTime         *uint64 `json:"arkivReduceBTLTime,omitempty"`         // Arkiv BTL reductions switch time (nil = no fork, 0 = already active)
+	ArkivChangeOwnerBatchTime  *uint64 `json:"arkivChangeOwnerBatchTime,omitempty"`  // Arkiv batched ownership changes switch time (nil = no fork, 0 = already active)
+	ArkivPayloadLimitsTime     *uint64 `json:"arkivPayloadLimitsTime,omitempty"`     // Arkiv payload size limits switch time (nil = no fork, 0 = already active)
+	ArkivCodecsTime            *uint64 `json:"arkivCodecsTime,omitempty"`            // Arkiv calldata codecs switch time (nil = no fork, 0 = already active)
+	ArkivExternalContentTime   *uint64 `json:"arkivExternalContentTime,omitempty"`   // Arkiv external content switch time (nil = no fork, 0 = already active)
+	ArkivConditionsTime        *uint64 `json:"arkivConditionsTime,omitempty"`        // Arkiv conditional operations switch time (nil = no fork, 0 = already active)
+
+	// ArkivTombstoneRetention is the number of blocks the tombstone of a removed Arkiv
+	// entity is kept, 0 means DefaultArkivTombstoneRetention.
+	ArkivTombstoneRetention uint64 `json:"arkivTombstoneRetention,omitempty"`
+
+	// ArkivMaxAnnotationValueSize is the largest string annotation value of an Arkiv
+	// create or update in bytes, 0 means DefaultArkivMaxAnnotationValueSize.
+	ArkivMaxAnnotationValueSize uint64 `json:"arkivMaxAnnotationValueSize,omitempty"`
+
+	// ArkivAnnotationValueGasThreshold is the size of a string annotation value in bytes
+	// above which every byte is charged, 0 means DefaultArkivAnnotationValueGasThreshold.
+	ArkivAnnotationValueGasThreshold uint64 `json:"arkivAnnotationValueGasThreshold,omitempty"`
+
+	// ArkivAnnotationValueGasPerByte is the gas charged for every byte of a string
+	// annotation value above the threshold, 0 means DefaultArkivAnnotationValueGasPerByte.
+	ArkivAnnotationValueGasPerByte uint64 `json:"arkivAnnotationValueGasPerByte,omitempty"`
+
+	// ArkivOwnershipTransferWindow is the number of blocks the new owner of an Arkiv
+	// entity has to accept a transfer, 0 means DefaultArkivOwnershipTransferWindow.
+	ArkivOwnershipTransferWindow uint64 `json:"arkivOwnershipTransferWindow,omitempty"`
+
+	// ArkivMaxBTL is the largest number of blocks between the current block and the
+	// expiry of an Arkiv entity or alias, 0 means DefaultArkivMaxBTL.
+	ArkivMaxBTL uint64 `json:"arkivMaxBTL,omitempty"`
+
+	// ArkivIdempotencyTTL is the number of blocks the idempotency key of a create is
+	// remembered, 0 means DefaultArkivIdempotencyTTL.
+	ArkivIdempotencyTTL uint64 `json:"arkivIdempotencyTTL,omitempty"`
+
+	// ArkivMaxPayloadSize is the largest payload of an Arkiv create or update in bytes,
+	// 0 means DefaultArkivMaxPayloadSize.
+	ArkivMaxPayloadSize uint64 `json:"arkivMaxPayloadSize,omitempty"`
+
+	// ArkivMaxDecompressedSize is the largest size of the decompressed calldata of an
+	// Arkiv transaction in bytes, 0 or a larger value means
+	// DefaultArkivMaxDecompressedSize.
+	ArkivMaxDecompressedSize uint64 `json:"arkivMaxDecompressedSize,omitempty"`
+
+	// ArkivMaxOperations is the largest number of operations of an Arkiv transaction, 0
+	// or a larger value means DefaultArkivMaxOperations.
+	ArkivMaxOperations uint64 `json:"arkivMaxOperations,omitempty"`
+
 	// TerminalTotalDifficulty is the amount of total difficulty reached by
 	// the network that triggers the consensus upgrade.
 	TerminalTotalDifficulty *big.Int `json:"terminalTotalDifficulty,omitempty"`
@@ -675,6 +742,66 @@ func (c *ChainConfig) String() string {
 	if c.InteropTime != nil {
 		result += fmt.Sprintf(", Interop: %v", *c.InteropTime)
 	}
+	if c.ArkivGasScheduleTime != nil {
+		result += fmt.Sprintf(", ArkivGasSchedule: %v", *c.ArkivGasScheduleTime)
+	}
+	if c.ArkivTypedNumericsTime != nil {
+		result += fmt.Sprintf(", ArkivTypedNumerics: %v", *c.ArkivTypedNumericsTime)
+	}
+	if c.ArkivEncryptionTime != nil {
+		result += fmt.Sprintf(", ArkivEncryption: %v", *c.ArkivEncryptionTime)
+	}
+	if c.ArkivTombstonesTime != nil {
+		result += fmt.Sprintf(", ArkivTombstones: %v", *c.ArkivTombstonesTime)
+	}
+	if c.ArkivOwnershipTime != nil {
+		result += fmt.Sprintf(", ArkivOwnership: %v", *c.ArkivOwnershipTime)
+	}
+	if c.ArkivDottedKeysTime != nil {
+		result += fmt.Sprintf(", ArkivDottedKeys: %v", *c.ArkivDottedKeysTime)
+	}
+	if c.ArkivHousekeepingOrderTime != nil {
+		result += fmt.Sprintf(", ArkivHousekeepingOrder: %v", *c.ArkivHousekeepingOrderTime)
+	}
+	if c.ArkivCapabilitiesTime != nil {
+		result += fmt.Sprintf(", ArkivCapabilities: %v", *c.ArkivCapabilitiesTime)
+	}
+	if c.ArkivOwnerSlotsTime != nil {
+		result += fmt.Sprintf(", ArkivOwnerSlots: %v", *c.ArkivOwnerSlotsTime)
+	}
+	if c.ArkivContentHashTime != nil {
+		result += fmt.Sprintf(", ArkivContentHash: %v", *c.ArkivContentHashTime)
+	}
+	if c.ArkivContentHashReadTime != nil {
+		result += fmt.Sprintf(", ArkivContentHashRead: %v", *c.ArkivContentHashReadTime)
+	}
+	if c.ArkivAliasesTime != nil {
+		result += fmt.Sprintf(", ArkivAliases: %v", *c.ArkivAliasesTime)
+	}
+	if c.ArkivMaxBTLTime != nil {
+		result += fmt.Sprintf(", ArkivMaxBTL: %v", *c.ArkivMaxBTLTime)
+	}
+	if c.ArkivIdempotencyTime != nil {
+		result += fmt.Sprintf(", ArkivIdempotency: %v", *c.ArkivIdempotencyTime)
+	}
+	if c.ArkivReduceBTLTime != nil {
+		result += fmt.Sprintf(", ArkivReduceBTL: %v", *c.ArkivReduceBTLTime)
+	}
+	if c.ArkivChangeOwnerBatchTime != nil {
+		result += fmt.Sprintf(", ArkivChangeOwnerBatch: %v", *c.ArkivChangeOwnerBatchTime)
+	}
+	if c.ArkivPayloadLimitsTime != nil {
+		result += fmt.Sprintf(", ArkivPayloadLimits: %v", *c.ArkivPayloadLimitsTime)
+	}
+	if c.ArkivCodecsTime != nil {
+		result += fmt.Sprintf(", ArkivCodecs: %v", *c.ArkivCodecsTime)
+	}
+	if c.ArkivExternalContentTime != nil {
+		result += fmt.Sprintf(", ArkivExternalContent: %v", *c.ArkivExternalContentTime)
+	}
+	if c.ArkivConditionsTime != nil {
+		result += fmt.Sprintf(", ArkivConditions: %v", *c.ArkivConditionsTime)
+	}
 	result += "}"
 	return result
 }
@@ -1020,6 +1147,274 @@ func (c *ChainConfig) IsInterop(time uint64) bool {
 	return isTimestampForked(c.InteropTime, time)
 }
 
+// IsArkivGasSchedule returns whether time is either equal to the Arkiv gas schedule fork
+// time or greater. From the fork the string annotation values are capped, and the bytes
+// of a value above the threshold are charged on top of the intrinsic gas.
+func (c *ChainConfig) IsArkivGasSchedule(time uint64) bool {
+	return c.IsArkivFeature(ArkivFeatureGasSchedule, time)
+}
+
+// ArkivMaxAnnotationValueSizeAt returns the largest string annotation value of the
+// Arkiv creates and updates applied at time in bytes, 0 if the values aren't capped
+// yet.
+func (c *ChainConfig) ArkivMaxAnnotationValueSizeAt(time uint64) uint64 {
+	if !c.IsArkivGasSchedule(time) {
+		return 0
+	}
+	return c.ArkivAnnotationValueCap()
+}
+
+// ArkivAnnotationValueCap returns the largest string annotation value in bytes of the
+// Arkiv creates and updates applied from the gas schedule fork, whether the fork is
+// active or not.
+func (c *ChainConfig) ArkivAnnotationValueCap() uint64 {
+	if c.ArkivMaxAnnotationValueSize == 0 {
+		return DefaultArkivMaxAnnotationValueSize
+	}
+	return c.ArkivMaxAnnotationValueSize
+}
+
+// ArkivAnnotationValueGasThresholdAt returns the size of a string annotation value in
+// bytes above which every byte is charged at time, 0 before the gas schedule fork.
+func (c *ChainConfig) ArkivAnnotationValueGasThresholdAt(time uint64) uint64 {
+	if !c.IsArkivGasSchedule(time) {
+		return 0
+	}
+	if c.ArkivAnnotationValueGasThreshold == 0 {
+		return DefaultArkivAnnotationValueGasThreshold
+	}
+	return c.ArkivAnnotationValueGasThreshold
+}
+
+// ArkivAnnotationValueGasPerByteAt returns the gas charged for every byte of a string
+// annotation value above the threshold at time, 0 before the gas schedule fork, when
+// the values are free.
+func (c *ChainConfig) ArkivAnnotationValueGasPerByteAt(time uint64) uint64 {
+	if !c.IsArkivGasSchedule(time) {
+		return 0
+	}
+	if c.ArkivAnnotationValueGasPerByte == 0 {
+		return DefaultArkivAnnotationValueGasPerByte
+	}
+	return c.ArkivAnnotationValueGasPerByte
+}
+
+// IsArkivTypedNumerics returns whether time is either equal to the Arkiv typed numeric
+// annotations fork time or greater. From the fork transactions can carry a version and
+// typed numeric annotations.
+func (c *ChainConfig) IsArkivTypedNumerics(time uint64) bool {
+	return c.IsArkivFeature(ArkivFeatureTypedNumerics, time)
+}
+
+// IsArkivEncryption returns whether time is either equal to the Arkiv payload
+// encryption fork time or greater. From the fork creates and updates can carry the
+// encryption info of their payload.
+func (c *ChainConfig) IsArkivEncryption(time uint64) bool {
+	return c.IsArkivFeature(ArkivFeatureEncryption, time)
+}
+
+// IsArkivTombstones returns whether time is either equal to the Arkiv tombstones fork
+// time or greater.
+func (c *ChainConfig) IsArkivTombstones(time uint64) bool {
+	return c.IsArkivFeature(ArkivFeatureTombstones, time)
+}
+
+// ArkivTombstoneRetentionAt returns the number of blocks the tombstones of the Arkiv
+// entities removed at time are kept, 0 if tombstones are not recorded yet.
+func (c *ChainConfig) ArkivTombstoneRetentionAt(time uint64) uint64 {
+	if !c.IsArkivTombstones(time) {
+		return 0
+	}
+	if c.ArkivTombstoneRetention == 0 {
+		return DefaultArkivTombstoneRetention
+	}
+	return c.ArkivTombstoneRetention
+}
+
+// IsArkivOwnership returns whether time is either equal to the Arkiv two-step
+// ownership transfer fork time or greater.
+func (c *ChainConfig) IsArkivOwnership(time uint64) bool {
+	return c.IsArkivFeature(ArkivFeatureTwoStepTransfer, time)
+}
+
+// ArkivOwnershipTransferWindowAt returns the number of blocks the new owner of an
+// Arkiv entity has to accept a transfer proposed at time, 0 if ownership changes
+// immediately.
+func (c *ChainConfig) ArkivOwnershipTransferWindowAt(time uint64) uint64 {
+	if !c.IsArkivOwnership(time) {
+		return 0
+	}
+	if c.ArkivOwnershipTransferWindow == 0 {
+		return DefaultArkivOwnershipTransferWindow
+	}
+	return c.ArkivOwnershipTransferWindow
+}
+
+// IsArkivDottedKeys returns whether time is either equal to the Arkiv dotted annotation
+// keys fork time or greater. From the fork annotation keys can be dotted paths.
+func (c *ChainConfig) IsArkivDottedKeys(time uint64) bool {
+	return c.IsArkivFeature(ArkivFeatureDottedKeys, time)
+}
+
+// IsArkivHousekeepingOrder returns whether time is either equal to the Arkiv
+// housekeeping order fork time or greater. From the fork the housekeeping runs in the
+// L1 attributes deposit only, before all the other transactions of the block.
+func (c *ChainConfig) IsArkivHousekeepingOrder(time uint64) bool {
+	return c.IsArkivFeature(ArkivFeatureHousekeepingOrder, time)
+}
+
+// IsArkivCapabilities returns whether time is either equal to the Arkiv capabilities
+// fork time or greater. From the fork contracts read the active Arkiv features from
+// the capabilities precompile.
+func (c *ChainConfig) IsArkivCapabilities(time uint64) bool {
+	return c.IsArkivFeature(ArkivFeatureCapabilities, time)
+}
+
+// IsArkivOwnerSlots returns whether time is either equal to the Arkiv owner slots fork
+// time or greater. From the fork the processor counts the slots used by the entities
+// of each owner, starting from zero.
+func (c *ChainConfig) IsArkivOwnerSlots(time uint64) bool {
+	return c.IsArkivFeature(ArkivFeatureOwnerSlots, time)
+}
+
+// IsArkivContentHash returns whether time is either equal to the Arkiv content hash
+// fork time or greater. From the fork the processor stores the keccak256 hash of the
+// payload of the entities it stores next to their metadata.
+func (c *ChainConfig) IsArkivContentHash(time uint64) bool {
+	return c.IsArkivFeature(ArkivFeatureContentHash, time)
+}
+
+// IsArkivContentHashRead returns whether time is either equal to the Arkiv content hash
+// read fork time or greater. From the fork contracts read the content hash of the
+// entities from the content hash precompile.
+func (c *ChainConfig) IsArkivContentHashRead(time uint64) bool {
+	return c.IsArkivFeature(ArkivFeatureContentHashRead, time)
+}
+
+// IsArkivAliases returns whether time is either equal to the Arkiv aliases fork time or
+// greater. From the fork the transactions can register names pointing to entities,
+// which expire with their own BTL.
+func (c *ChainConfig) IsArkivAliases(time uint64) bool {
+	return c.IsArkivFeature(ArkivFeatureAliases, time)
+}
+
+// IsArkivMaxBTL returns whether time is either equal to the Arkiv maximum BTL fork time
+// or greater. From the fork the expiries past the maximum BTL, or past the largest
+// block number, are rejected instead of being scheduled.
+func (c *ChainConfig) IsArkivMaxBTL(time uint64) bool {
+	return c.IsArkivFeature(ArkivFeatureMaxBTL, time)
+}
+
+// ArkivMaxBTLAt returns the largest number of blocks between the current block and the
+// expiry of the Arkiv entities and aliases stored at time, 0 if the BTL isn't capped yet.
+func (c *ChainConfig) ArkivMaxBTLAt(time uint64) uint64 {
+	if !c.IsArkivMaxBTL(time) {
+		return 0
+	}
+	if c.ArkivMaxBTL == 0 {
+		return DefaultArkivMaxBTL
+	}
+	return c.ArkivMaxBTL
+}
+
+// IsArkivIdempotency returns whether time is either equal to the Arkiv idempotency fork
+// time or greater. From the fork a create carrying the idempotency key of a previous
+// create of the same sender is skipped.
+func (c *ChainConfig) IsArkivIdempotency(time uint64) bool {
+	return c.IsArkivFeature(ArkivFeatureIdempotency, time)
+}
+
+// ArkivIdempotencyTTLAt returns the number of blocks the idempotency keys of the Arkiv
+// creates applied at time are remembered, 0 if creates can't carry them yet.
+func (c *ChainConfig) ArkivIdempotencyTTLAt(time uint64) uint64 {
+	if !c.IsArkivIdempotency(time) {
+		return 0
+	}
+	if c.ArkivIdempotencyTTL == 0 {
+		return DefaultArkivIdempotencyTTL
+	}
+	return c.ArkivIdempotencyTTL
+}
+
+// IsArkivReduceBTL returns whether time is either equal to the Arkiv BTL reduction fork
+// time or greater. From the fork the owner of an entity can bring its expiry forward.
+func (c *ChainConfig) IsArkivReduceBTL(time uint64) bool {
+	return c.IsArkivFeature(ArkivFeatureReduceBTL, time)
+}
+
+// IsArkivChangeOwnerBatch returns whether time is either equal to the Arkiv batched
+// ownership changes fork time or greater. From the fork a single operation can
+// transfer several entities to a new owner.
+func (c *ChainConfig) IsArkivChangeOwnerBatch(time uint64) bool {
+	return c.IsArkivFeature(ArkivFeatureChangeOwnerBatch, time)
+}
+
+// IsArkivPayloadLimits returns whether time is either equal to the Arkiv payload limits
+// fork time or greater. From the fork the payloads are capped, and the transactions
+// decompressing past their limit fail instead of being truncated.
+func (c *ChainConfig) IsArkivPayloadLimits(time uint64) bool {
+	return c.IsArkivFeature(ArkivFeaturePayloadLimits, time)
+}
+
+// ArkivMaxPayloadSizeAt returns the largest payload of the Arkiv creates and updates
+// applied at time in bytes, 0 if the payloads aren't capped yet.
+func (c *ChainConfig) ArkivMaxPayloadSizeAt(time uint64) uint64 {
+	if !c.IsArkivPayloadLimits(time) {
+		return 0
+	}
+	if c.ArkivMaxPayloadSize == 0 {
+		return DefaultArkivMaxPayloadSize
+	}
+	return c.ArkivMaxPayloadSize
+}
+
+// ArkivMaxDecompressedSizeAt returns the largest decompressed calldata of the Arkiv
+// transactions applied at time in bytes, 0 before the payload limits fork, when the
+// calldata is truncated at DefaultArkivMaxDecompressedSize instead.
+func (c *ChainConfig) ArkivMaxDecompressedSizeAt(time uint64) uint64 {
+	if !c.IsArkivPayloadLimits(time) {
+		return 0
+	}
+	if c.ArkivMaxDecompressedSize == 0 {
+		return DefaultArkivMaxDecompressedSize
+	}
+	return min(c.ArkivMaxDecompressedSize, DefaultArkivMaxDecompressedSize)
+}
+
+// ArkivMaxOperationsAt returns the largest number of operations of the Arkiv
+// transactions applied at time, 0 before the payload limits fork, when only
+// DefaultArkivMaxOperations applies.
+func (c *ChainConfig) ArkivMaxOperationsAt(time uint64) uint64 {
+	if !c.IsArkivPayloadLimits(time) {
+		return 0
+	}
+	if c.ArkivMaxOperations == 0 {
+		return DefaultArkivMaxOperations
+	}
+	return min(c.ArkivMaxOperations, DefaultArkivMaxOperations)
+}
+
+// IsArkivCodecs returns whether time is either equal to the Arkiv calldata codecs fork
+// time or greater. From the fork the calldata can be prefixed with the id of its codec,
+// brotli or zstd, before it's raw brotli only.
+func (c *ChainConfig) IsArkivCodecs(time uint64) bool {
+	return c.IsArkivFeature(ArkivFeatureCodecs, time)
+}
+
+// IsArkivExternalContent returns whether time is either equal to the Arkiv external
+// content fork time or greater. From the fork creates and updates can carry the
+// commitment to a payload stored off chain instead of the payload.
+func (c *ChainConfig) IsArkivExternalContent(time uint64) bool {
+	return c.IsArkivFeature(ArkivFeatureExternalContent, time)
+}
+
+// IsArkivConditions returns whether time is either equal to the Arkiv conditional
+// operations fork time or greater. From the fork creates, updates and extensions can
+// carry preconditions on the entities they touch.
+func (c *ChainConfig) IsArkivConditions(time uint64) bool {
+	return c.IsArkivFeature(ArkivFeatureConditions, time)
+}
+
 // IsOptimism returns whether the node is an optimism node or not.
 func (c *ChainConfig) IsOptimism() bool {
 	return c.Optimism != nil
@@ -1598,6 +1993,8 @@ type Rules struct {
 	IsOptimismCanyon, IsOptimismFjord                       bool
 	IsOptimismGranite, IsOptimismHolocene                   bool
 	IsOptimismIsthmus, IsOptimismJovian                     bool
+	IsArkivCapabilities, IsArkivContentHashRead             bool
+	ArkivFeatures                                           ArkivFeatureSet
 }
 
 // Rules ensures c's ChainID is not nil.
@@ -1639,6 +2036,10 @@ func (c *ChainConfig) Rules(num *big.Int, isMerge bool, timestamp uint64) Rules
 		IsOptimismHolocene: isMerge && c.IsOptimismHolocene(timestamp),
 		IsOptimismIsthmus:  isMerge && c.IsOptimismIsthmus(timestamp),
 		IsOptimismJovian:   isMerge && c.IsOptimismJovian(timestamp),
+		// Arkiv
+		IsArkivCapabilities:    c.IsArkivCapabilities(timestamp),
+		IsArkivContentHashRead: c.IsArkivContentHashRead(timestamp),
+		ArkivFeatures:          c.ArkivFeaturesAt(timestamp),
 	}
 }
 
@@ -36,6 +36,97 @@ func (c *ChainConfig) opCheckCompatible(newcfg *ChainConfig, headNumber *big.Int
 	if isForkTimestampIncompatible(c.InteropTime, newcfg.InteropTime, headTimestamp, genesisTimestamp) {
 		return newTimestampCompatError("Interop fork timestamp", c.InteropTime, newcfg.InteropTime)
 	}
+	if isForkTimestampIncompatible(c.ArkivGasScheduleTime, newcfg.ArkivGasScheduleTime, headTimestamp, genesisTimestamp) {
+		return newTimestampCompatError("Arkiv gas schedule fork timestamp", c.ArkivGasScheduleTime, newcfg.ArkivGasScheduleTime)
+	}
+	if isForkTimestampIncompatible(c.ArkivTypedNumericsTime, newcfg.ArkivTypedNumericsTime, headTimestamp, genesisTimestamp) {
+		return newTimestampCompatError("Arkiv typed numerics fork timestamp", c.ArkivTypedNumericsTime, newcfg.ArkivTypedNumericsTime)
+	}
+	if isForkTimestampIncompatible(c.ArkivEncryptionTime, newcfg.ArkivEncryptionTime, headTimestamp, genesisTimestamp) {
+		return newTimestampCompatError("Arkiv encryption fork timestamp", c.ArkivEncryptionTime, newcfg.ArkivEncryptionTime)
+	}
+	// The gas schedule decides which transactions fail, it can't change once it is
+	// enforced.
+	if c.IsArkivGasSchedule(headTimestamp) && (c.ArkivMaxAnnotationValueSizeAt(headTimestamp) != newcfg.ArkivMaxAnnotationValueSizeAt(headTimestamp) ||
+		c.ArkivAnnotationValueGasThresholdAt(headTimestamp) != newcfg.ArkivAnnotationValueGasThresholdAt(headTimestamp) ||
+		c.ArkivAnnotationValueGasPerByteAt(headTimestamp) != newcfg.ArkivAnnotationValueGasPerByteAt(headTimestamp)) {
+		return newTimestampCompatError("Arkiv gas schedule", c.ArkivGasScheduleTime, newcfg.ArkivGasScheduleTime)
+	}
+	if isForkTimestampIncompatible(c.ArkivTombstonesTime, newcfg.ArkivTombstonesTime, headTimestamp, genesisTimestamp) {
+		return newTimestampCompatError("Arkiv tombstones fork timestamp", c.ArkivTombstonesTime, newcfg.ArkivTombstonesTime)
+	}
+	// The retention schedules the sweeping of the tombstones, it can't change once
+	// tombstones are recorded.
+	if c.IsArkivTombstones(headTimestamp) && c.ArkivTombstoneRetentionAt(headTimestamp) != newcfg.ArkivTombstoneRetentionAt(headTimestamp) {
+		return newTimestampCompatError("Arkiv tombstone retention", c.ArkivTombstonesTime, newcfg.ArkivTombstonesTime)
+	}
+	if isForkTimestampIncompatible(c.ArkivOwnershipTime, newcfg.ArkivOwnershipTime, headTimestamp, genesisTimestamp) {
+		return newTimestampCompatError("Arkiv ownership fork timestamp", c.ArkivOwnershipTime, newcfg.ArkivOwnershipTime)
+	}
+	if c.IsArkivOwnership(headTimestamp) && c.ArkivOwnershipTransferWindowAt(headTimestamp) != newcfg.ArkivOwnershipTransferWindowAt(headTimestamp) {
+		return newTimestampCompatError("Arkiv ownership transfer window", c.ArkivOwnershipTime, newcfg.ArkivOwnershipTime)
+	}
+	if isForkTimestampIncompatible(c.ArkivDottedKeysTime, newcfg.ArkivDottedKeysTime, headTimestamp, genesisTimestamp) {
+		return newTimestampCompatError("Arkiv dotted keys fork timestamp", c.ArkivDottedKeysTime, newcfg.ArkivDottedKeysTime)
+	}
+	if isForkTimestampIncompatible(c.ArkivHousekeepingOrderTime, newcfg.ArkivHousekeepingOrderTime, headTimestamp, genesisTimestamp) {
+		return newTimestampCompatError("Arkiv housekeeping order fork timestamp", c.ArkivHousekeepingOrderTime, newcfg.ArkivHousekeepingOrderTime)
+	}
+	if isForkTimestampIncompatible(c.ArkivCapabilitiesTime, newcfg.ArkivCapabilitiesTime, headTimestamp, genesisTimestamp) {
+		return newTimestampCompatError("Arkiv capabilities fork timestamp", c.ArkivCapabilitiesTime, newcfg.ArkivCapabilitiesTime)
+	}
+	if isForkTimestampIncompatible(c.ArkivOwnerSlotsTime, newcfg.ArkivOwnerSlotsTime, headTimestamp, genesisTimestamp) {
+		return newTimestampCompatError("Arkiv owner slots fork timestamp", c.ArkivOwnerSlotsTime, newcfg.ArkivOwnerSlotsTime)
+	}
+	if isForkTimestampIncompatible(c.ArkivContentHashTime, newcfg.ArkivContentHashTime, headTimestamp, genesisTimestamp) {
+		return newTimestampCompatError("Arkiv content hash fork timestamp", c.ArkivContentHashTime, newcfg.ArkivContentHashTime)
+	}
+	if isForkTimestampIncompatible(c.ArkivContentHashReadTime, newcfg.ArkivContentHashReadTime, headTimestamp, genesisTimestamp) {
+		return newTimestampCompatError("Arkiv content hash read fork timestamp", c.ArkivContentHashReadTime, newcfg.ArkivContentHashReadTime)
+	}
+	if isForkTimestampIncompatible(c.ArkivAliasesTime, newcfg.ArkivAliasesTime, headTimestamp, genesisTimestamp) {
+		return newTimestampCompatError("Arkiv aliases fork timestamp", c.ArkivAliasesTime, newcfg.ArkivAliasesTime)
+	}
+	if isForkTimestampIncompatible(c.ArkivMaxBTLTime, newcfg.ArkivMaxBTLTime, headTimestamp, genesisTimestamp) {
+		return newTimestampCompatError("Arkiv max BTL fork timestamp", c.ArkivMaxBTLTime, newcfg.ArkivMaxBTLTime)
+	}
+	// The cap decides which transactions fail, it can't change once it is enforced.
+	if c.IsArkivMaxBTL(headTimestamp) && c.ArkivMaxBTLAt(headTimestamp) != newcfg.ArkivMaxBTLAt(headTimestamp) {
+		return newTimestampCompatError("Arkiv max BTL", c.ArkivMaxBTLTime, newcfg.ArkivMaxBTLTime)
+	}
+	if isForkTimestampIncompatible(c.ArkivIdempotencyTime, newcfg.ArkivIdempotencyTime, headTimestamp, genesisTimestamp) {
+		return newTimestampCompatError("Arkiv idempotency fork timestamp", c.ArkivIdempotencyTime, newcfg.ArkivIdempotencyTime)
+	}
+	// The TTL schedules the sweeping of the idempotency keys, it can't change once they
+	// are recorded.
+	if c.IsArkivIdempotency(headTimestamp) && c.ArkivIdempotencyTTLAt(headTimestamp) != newcfg.ArkivIdempotencyTTLAt(headTimestamp) {
+		return newTimestampCompatError("Arkiv idempotency TTL", c.ArkivIdempotencyTime, newcfg.ArkivIdempotencyTime)
+	}
+	if isForkTimestampIncompatible(c.ArkivReduceBTLTime, newcfg.ArkivReduceBTLTime, headTimestamp, genesisTimestamp) {
+		return newTimestampCompatError("Arkiv reduce BTL fork timestamp", c.ArkivReduceBTLTime, newcfg.ArkivReduceBTLTime)
+	}
+	if isForkTimestampIncompatible(c.ArkivChangeOwnerBatchTime, newcfg.ArkivChangeOwnerBatchTime, headTimestamp, genesisTimestamp) {
+		return newTimestampCompatError("Arkiv change owner batch fork timestamp", c.ArkivChangeOwnerBatchTime, newcfg.ArkivChangeOwnerBatchTime)
+	}
+	if isForkTimestampIncompatible(c.ArkivPayloadLimitsTime, newcfg.ArkivPayloadLimitsTime, headTimestamp, genesisTimestamp) {
+		return newTimestampCompatError("Arkiv payload limits fork timestamp", c.ArkivPayloadLimitsTime, newcfg.ArkivPayloadLimitsTime)
+	}
+	if isForkTimestampIncompatible(c.ArkivCodecsTime, newcfg.ArkivCodecsTime, headTimestamp, genesisTimestamp) {
+		return newTimestampCompatError("Arkiv codecs fork timestamp", c.ArkivCodecsTime, newcfg.ArkivCodecsTime)
+	}
+	if isForkTimestampIncompatible(c.ArkivExternalContentTime, newcfg.ArkivExternalContentTime, headTimestamp, genesisTimestamp) {
+		return newTimestampCompatError("Arkiv external content fork timestamp", c.ArkivExternalContentTime, newcfg.ArkivExternalContentTime)
+	}
+	if isForkTimestampIncompatible(c.ArkivConditionsTime, newcfg.ArkivConditionsTime, headTimestamp, genesisTimestamp) {
+		return newTimestampCompatError("Arkiv conditions fork timestamp", c.ArkivConditionsTime, newcfg.ArkivConditionsTime)
+	}
+	// The limits decide which transactions fail, they can't change once they are
+	// enforced.
+	if c.IsArkivPayloadLimits(headTimestamp) && (c.ArkivMaxPayloadSizeAt(headTimestamp) != newcfg.ArkivMaxPayloadSizeAt(headTimestamp) ||
+		c.ArkivMaxDecompressedSizeAt(headTimestamp) != newcfg.ArkivMaxDecompressedSizeAt(headTimestamp) ||
+		c.ArkivMaxOperationsAt(headTimestamp) != newcfg.ArkivMaxOperationsAt(headTimestamp)) {
+		return newTimestampCompatError("Arkiv payload limits", c.ArkivPayloadLimitsTime, newcfg.ArkivPayloadLimitsTime)
+	}
 	return nil
 }
 
@@ -68,6 +159,68 @@ func (c *ChainConfig) opDescription() string {
 	if c.InteropTime != nil {
 		banner += fmt.Sprintf(" - Interop:                     @%-10v\n", *c.InteropTime)
 	}
+	if c.ArkivGasScheduleTime != nil {
+		at := *c.ArkivGasScheduleTime
+		banner += fmt.Sprintf(" - Arkiv Gas Schedule:          @%-10v (annotation values %d bytes, %d gas per byte above %d bytes)\n", at, c.ArkivMaxAnnotationValueSizeAt(at), c.ArkivAnnotationValueGasPerByteAt(at), c.ArkivAnnotationValueGasThresholdAt(at))
+	}
+	if c.ArkivTypedNumericsTime != nil {
+		banner += fmt.Sprintf(" - Arkiv Typed Numerics:        @%-10v\n", *c.ArkivTypedNumericsTime)
+	}
+	if c.ArkivEncryptionTime != nil {
+		banner += fmt.Sprintf(" - Arkiv Encryption:            @%-10v\n", *c.ArkivEncryptionTime)
+	}
+	if c.ArkivTombstonesTime != nil {
+		banner += fmt.Sprintf(" - Arkiv Tombstones:            @%-10v (retention %d blocks)\n", *c.ArkivTombstonesTime, c.ArkivTombstoneRetentionAt(*c.ArkivTombstonesTime))
+	}
+	if c.ArkivOwnershipTime != nil {
+		banner += fmt.Sprintf(" - Arkiv Ownership:             @%-10v (transfer window %d blocks)\n", *c.ArkivOwnershipTime, c.ArkivOwnershipTransferWindowAt(*c.ArkivOwnershipTime))
+	}
+	if c.ArkivDottedKeysTime != nil {
+		banner += fmt.Sprintf(" - Arkiv Dotted Keys:           @%-10v\n", *c.ArkivDottedKeysTime)
+	}
+	if c.ArkivHousekeepingOrderTime != nil {
+		banner += fmt.Sprintf(" - Arkiv Housekeeping Order:    @%-10v\n", *c.ArkivHousekeepingOrderTime)
+	}
+	if c.ArkivCapabilitiesTime != nil {
+		banner += fmt.Sprintf(" - Arkiv Capabilities:          @%-10v\n", *c.ArkivCapabilitiesTime)
+	}
+	if c.ArkivOwnerSlotsTime != nil {
+		banner += fmt.Sprintf(" - Arkiv Owner Slots:           @%-10v\n", *c.ArkivOwnerSlotsTime)
+	}
+	if c.ArkivContentHashTime != nil {
+		banner += fmt.Sprintf(" - Arkiv Content Hash:          @%-10v\n", *c.ArkivContentHashTime)
+	}
+	if c.ArkivContentHashReadTime != nil {
+		banner += fmt.Sprintf(" - Arkiv Content Hash Read:     @%-10v\n", *c.ArkivContentHashReadTime)
+	}
+	if c.ArkivAliasesTime != nil {
+		banner += fmt.Sprintf(" - Arkiv Aliases:               @%-10v\n", *c.ArkivAliasesTime)
+	}
+	if c.ArkivMaxBTLTime != nil {
+		banner += fmt.Sprintf(" - Arkiv Max BTL:               @%-10v (max BTL %d blocks)\n", *c.ArkivMaxBTLTime, c.ArkivMaxBTLAt(*c.ArkivMaxBTLTime))
+	}
+	if c.ArkivIdempotencyTime != nil {
+		banner += fmt.Sprintf(" - Arkiv Idempotency:           @%-10v (TTL %d blocks)\n", *c.ArkivIdempotencyTime, c.ArkivIdempotencyTTLAt(*c.ArkivIdempotencyTime))
+	}
+	if c.ArkivReduceBTLTime != nil {
+		banner += fmt.Sprintf(" - Arkiv Reduce BTL:            @%-10v\n", *c.ArkivReduceBTLTime)
+	}
+	if c.ArkivChangeOwnerBatchTime != nil {
+		banner += fmt.Sprintf(" - Arkiv Change Owner Batch:    @%-10v\n", *c.ArkivChangeOwnerBatchTime)
+	}
+	if c.ArkivPayloadLimitsTime != nil {
+		at := *c.ArkivPayloadLimitsTime
+		banner += fmt.Sprintf(" - Arkiv Payload Limits:        @%-10v (payload %d bytes, calldata %d bytes, %d operations)\n", at, c.ArkivMaxPayloadSizeAt(at), c.ArkivMaxDecompressedSizeAt(at), c.ArkivMaxOperationsAt(at))
+	}
+	if c.ArkivCodecsTime != nil {
+		banner += fmt.Sprintf(" - Arkiv Codecs:                @%-10v\n", *c.ArkivCodecsTime)
+	}
+	if c.ArkivExternalContentTime != nil {
+		banner += fmt.Sprintf(" - Arkiv External Content:      @%-10v\n", *c.ArkivExternalContentTime)
+	}
+	if c.ArkivConditionsTime != nil {
+		banner += fmt.Sprintf(" - Arkiv Conditions:            @%-10v\n", *c.ArkivConditionsTime)
+	}
 	banner += "\nAll op fork specifications can be found at https://specs.optimism.io/\n"
 	return banner
 }
@@ -210,6 +210,25 @@ const (
 	HistoryServeWindow = 8191 // Number of blocks to serve historical block hashes for, EIP-2935.
 
 	MaxBlockSize = 8_388_608 // maximum size of an RLP-encoded block
+
+	DefaultArkivMaxAnnotationValueSize      uint64 = 8 * 1024 // Largest string annotation value of an Arkiv create or update in bytes
+	DefaultArkivAnnotationValueGasThreshold uint64 = 512      // Size of a string annotation value in bytes above which every byte is charged
+	DefaultArkivAnnotationValueGasPerByte   uint64 = 64       // Gas charged for every byte of a string annotation value above the threshold
+
+	DefaultArkivTombstoneRetention uint64 = 302_400 // Number of blocks the tombstone of a removed Arkiv entity is kept (a week of 2s blocks)
+
+	DefaultArkivOwnershipTransferWindow uint64 = 43_200 // Number of blocks the new owner of an Arkiv entity has to accept a transfer (a day of 2s blocks)
+
+	DefaultArkivMaxBTL uint64 = 15_768_000 // Largest number of blocks between the current block and the expiry of an Arkiv entity (a year of 2s blocks)
+
+	DefaultArkivIdempotencyTTL uint64 = 43_200 // Number of blocks the idempotency key of an Arkiv create is remembered (a day of 2s blocks)
+
+	DefaultArkivMaxPayloadSize      uint64 = 1024 * 1024      // Largest payload of an Arkiv create or update in bytes
+	DefaultArkivMaxDecompressedSize uint64 = 20 * 1024 * 1024 // Largest decompressed calldata of an Arkiv transaction in bytes, limits.MaxDecompressedSize
+	DefaultArkivMaxOperations       uint64 = 1000             // Largest number of operations of an Arkiv transaction, limits.MaxOperations
+
+	ArkivCapabilitiesGas uint64 = 100  // Gas price for the Arkiv capabilities precompile
+	ArkivContentHashGas  uint64 = 4200 // Gas price for the Arkiv content hash precompile, two cold storage reads
 )
 
 // Bls12381G1MultiExpDiscountTable is the gas discount table for BLS12-381 G1 multi exponentiation operation